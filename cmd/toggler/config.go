@@ -0,0 +1,140 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"strconv"
+	"syscall"
+
+	"github.com/toggler-io/toggler/external/config"
+	"github.com/toggler-io/toggler/external/interface/httpintf/httputils"
+	"github.com/toggler-io/toggler/external/logging"
+)
+
+// logLevel is the level of the default logger, which is changed when the config is reloaded.
+var logLevel slog.LevelVar
+
+// configLoader assembles the configuration from the config file, the environment variables and the command line flags.
+// The flags have the highest precedence, then the environment variables, then the config file, and finally the defaults.
+type configLoader struct {
+	path     string
+	flagSets []*flag.FlagSet
+}
+
+func (l *configLoader) Load() (config.Config, error) {
+	c, err := config.Load(l.path, os.LookupEnv)
+	if err != nil {
+		return c, err
+	}
+	for _, fs := range l.flagSets {
+		if err := applyFlags(fs, &c); err != nil {
+			return c, err
+		}
+	}
+	return c, c.Validate()
+}
+
+// applyFlags overrides the config with the flags which were explicitly given on the command line.
+func applyFlags(fs *flag.FlagSet, c *config.Config) error {
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if err != nil {
+			return
+		}
+		v := f.Value.String()
+		switch f.Name {
+		case `database-url`:
+			c.Storage.URL = v
+		case `cache-url`:
+			c.Cache.URL = v
+		case `cache-ttl`:
+			err = c.Cache.TTL.Set(v)
+		case `tracing-exporter`:
+			c.Telemetry.Tracing.Exporter = v
+		case `otlp-endpoint`:
+			c.Telemetry.Tracing.OTLPEndpoint = v
+		case `otlp-insecure`:
+			c.Telemetry.Tracing.OTLPInsecure, err = strconv.ParseBool(v)
+		case `log-level`:
+			c.Logging.Level = v
+		case `log-format`:
+			c.Logging.Format = v
+		case `port`:
+			c.HTTP.Port, err = strconv.Atoi(v)
+		case `metrics-token`:
+			c.Auth.MetricsToken = v
+		}
+		if err != nil {
+			err = fmt.Errorf(`invalid -%s flag: %w`, f.Name, err)
+		}
+	})
+	return err
+}
+
+func configCMD(args []string, loader *configLoader) {
+	if len(args) < 2 || args[1] != `validate` {
+		fmt.Println(`please provide on of the config commands`)
+		fmt.Printf("\t%s\n", `validate`)
+		os.Exit(1)
+	}
+
+	if _, err := loader.Load(); err != nil {
+		fmt.Fprintln(os.Stderr, `invalid configuration:`)
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	fmt.Println(`configuration is valid`)
+}
+
+// reloadableSettings are the parts of the http server which can be changed without a restart.
+type reloadableSettings struct {
+	cors             *httputils.CORSPolicy
+	clientIdentities *httputils.ClientCertificateIdentities
+	bodyLimit        *httputils.BodyLimit
+}
+
+func (s reloadableSettings) apply(c config.Config) {
+	if level, err := logging.ParseLevel(c.Logging.Level); err == nil {
+		logLevel.Set(level)
+	}
+	s.cors.SetAllowedOrigins(c.HTTP.CORS.AllowedOrigins)
+	s.clientIdentities.SetIdentities(c.HTTP.TLS.ClientIdentities)
+	s.bodyLimit.SetMaxBytes(c.HTTP.MaxBodyBytes)
+}
+
+// reloadOnSIGHUP loads the configuration again when the process receives a SIGHUP.
+// Only the log level, the CORS origins, the client certificate identities and the body limit are applied,
+// the rest of the changes are reported, and take effect after a restart.
+// The running config is the one the server started with.
+func reloadOnSIGHUP(loader *configLoader, running config.Config, settings reloadableSettings) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	for range hup {
+		next, err := loader.Load()
+		if err != nil {
+			slog.Error(`config reload failed, the current configuration is kept`, slog.String(`error`, err.Error()))
+			continue
+		}
+
+		settings.apply(next)
+		if !reflect.DeepEqual(withoutReloadable(running), withoutReloadable(next)) {
+			slog.Warn(`config reloaded, but some of the changes require a restart`)
+		} else {
+			slog.Info(`config reloaded`)
+		}
+	}
+}
+
+func withoutReloadable(c config.Config) config.Config {
+	c.Logging.Level = ``
+	c.HTTP.CORS = config.CORS{}
+	c.HTTP.TLS.ClientIdentities = nil
+	c.HTTP.MaxBodyBytes = 0
+	return c
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+
+	"github.com/toggler-io/toggler/external/resource/storages"
+)
+
+func copyDataCMD(args []string) error {
+	flagSet := flag.NewFlagSet(`copy-data`, flag.ExitOnError)
+	from := flagSet.String(`from`, ``, `connection url of the storage to copy the data from.`)
+	to := flagSet.String(`to`, ``, `connection url of the storage to copy the data to.`)
+	dryRun := flagSet.Bool(`dry-run`, false, `only print what would be copied, without writing the destination storage.`)
+
+	if err := flagSet.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if *from == `` || *to == `` {
+		return errors.New(`source and destination storage urls are required (-from, -to)`)
+	}
+
+	src, err := storages.New(*from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := storages.New(*to)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	report, err := storages.Copy(context.Background(), src, dst, storages.CopyOptions{DryRun: *dryRun})
+	fmt.Print(report.String())
+	if err != nil {
+		slog.Info(`the copy can be resumed by running the same command again`)
+		return err
+	}
+	if *dryRun {
+		fmt.Println(`dry run, no data was copied`)
+	}
+	return nil
+}
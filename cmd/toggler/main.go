@@ -2,25 +2,30 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
-	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/toggler-io/toggler/external/resource/caches"
 	"github.com/toggler-io/toggler/external/resource/storages"
 
-	"github.com/unrolled/logger"
-
 	"github.com/toggler-io/toggler/domains/release"
 	"github.com/toggler-io/toggler/domains/security"
 	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/external/config"
 	"github.com/toggler-io/toggler/external/interface/httpintf"
+	"github.com/toggler-io/toggler/external/interface/httpintf/health"
+	"github.com/toggler-io/toggler/external/interface/httpintf/httptls"
+	"github.com/toggler-io/toggler/external/interface/httpintf/httputils"
+	"github.com/toggler-io/toggler/external/interface/metrics"
+	"github.com/toggler-io/toggler/external/interface/tracing"
+	"github.com/toggler-io/toggler/external/logging"
 )
 
 const commandsHelpDescription = `
@@ -31,112 +36,223 @@ Commands:
     - create token for admin user
   * fixtures
     - create fixtures for local development purpose
+  * export
+    - print the release configuration as a YAML/JSON manifest
+  * apply
+    - apply a YAML/JSON manifest to the release configuration
+  * copy-data
+    - copy every entity from one storage to an other, like from sqlite to postgres
+  * config validate
+    - check the configuration assembled from the config file, the environment and the flags
+
+Admin commands of a remote toggler server:
+  * flags list|create|delete
+    - manage the release flags
+  * envs list|create|delete
+    - manage the deployment environments
+  * rollouts list|set|delete
+    - manage the rollouts, like "rollouts set -env prod -percentage 25 my-flag"
+  * pilots list|enroll|unenroll
+    - manage the manual pilot enrollments, like "pilots enroll -env prod -flag my-flag pilot-id"
+  * eval
+    - explain the flag states of a pilot, like "eval -pilot pilot-id -env prod my-flag"
 `
 
 func main() {
+	if err := run(); err != nil {
+		fatal(err)
+	}
+}
+
+// run executes the command given in the arguments.
+// Its errors are returned instead of exiting right away,
+// so the deferred functions close the storage and the cache, and flush the traces before the process exits.
+func run() error {
 	flagSet := flag.NewFlagSet(`toggler`, flag.ContinueOnError)
-	dbURL := flagSet.String(`database-url`, ``, `define what url should be used for the db connection. Default value used from ENV[DATABASE_URL].`)
-	cacheURL := flagSet.String(`cache-url`, ``, `define what url should be used for the cache connection. default value is taken from ENV[CACHE_URL].`)
+	configPath := flagSet.String(`config`, os.Getenv(`TOGGLER_CONFIG`), `define the path of the YAML/JSON config file. default value is taken from ENV[TOGGLER_CONFIG].`)
+	flagSet.String(`database-url`, ``, `define what url should be used for the db connection. Default value used from ENV[DATABASE_URL].`)
+	flagSet.String(`cache-url`, ``, `define what url should be used for the cache connection. default value is taken from ENV[CACHE_URL].`)
+	flagSet.Duration(`cache-ttl`, 0, `define how long a cached entry can be served. default value is taken from ENV[CACHE_TTL] or 5m.`)
+	flagSet.String(`tracing-exporter`, ``, `define where the traces are exported: none, stdout or otlp. default value is taken from ENV[TRACING_EXPORTER].`)
+	flagSet.String(`otlp-endpoint`, ``, `define the host:port of the OTLP collector. default value is taken from ENV[OTEL_EXPORTER_OTLP_ENDPOINT].`)
+	flagSet.Bool(`otlp-insecure`, false, `connect to the OTLP collector without TLS. default value is taken from ENV[OTEL_EXPORTER_OTLP_INSECURE].`)
+	flagSet.String(`log-level`, ``, `define the minimum level of the logs: debug, info, warn or error. default value is taken from ENV[LOG_LEVEL] or info.`)
+	flagSet.String(`log-format`, ``, `define the format of the logs: json or text. default value is taken from ENV[LOG_FORMAT] or json.`)
 
 	if err := flagSet.Parse(os.Args[1:]); err != nil {
 		if err == flag.ErrHelp {
 			fmt.Print(commandsHelpDescription)
 			fmt.Println()
 		} else {
-			log.Print(err.Error())
+			slog.Error(`invalid command line flags`, slog.String(`error`, err.Error()))
 		}
-		os.Exit(0)
+		return nil
+	}
+
+	// the admin commands only talk to a remote server, so they don't need the configuration of the server.
+	if cmd, ok := remoteCommands[flagSet.Arg(0)]; ok {
+		cmd(flagSet.Args())
+		return nil
+	}
+
+	loader := &configLoader{path: *configPath, flagSets: []*flag.FlagSet{flagSet}}
+
+	if flagSet.Arg(0) == `config` {
+		configCMD(flagSet.Args(), loader)
+		return nil
 	}
 
-	setupDatabaseURL(dbURL)
-	setupCacheURL(cacheURL)
+	cfg, err := loader.Load()
+	if err != nil {
+		return err
+	}
+
+	logger, err := logging.New(logging.Config{Level: cfg.Logging.Level, Format: cfg.Logging.Format, LevelVar: &logLevel})
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+
+	// copy-data works with its own source and destination storages.
+	if flagSet.Arg(0) == `copy-data` {
+		return copyDataCMD(flagSet.Args())
+	}
+
+	if err := setupDatabaseURL(cfg.Storage.URL); err != nil {
+		return err
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter: cfg.Telemetry.Tracing.Exporter,
+		Endpoint: cfg.Telemetry.Tracing.OTLPEndpoint,
+		Insecure: cfg.Telemetry.Tracing.OTLPInsecure,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error(`unable to flush the traces`, slog.String(`error`, err.Error()))
+		}
+	}()
 
 	storage, err := storages.NewFromEnv()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	defer storage.Close()
 
-	cache, err := caches.New(*cacheURL, storage)
+	cache, err := caches.New(cfg.Cache.URL, tracing.Storage(storage, `storage`), caches.Config{
+		TTL:        cfg.Cache.TTL.Duration(),
+		MaxEntries: cfg.Cache.MaxEntries,
+		Eviction:   cfg.Cache.Eviction,
+	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	defer cache.Close()
 
 	switch flagSet.Arg(0) {
 	case `create-token`:
-		createTokenCMD(flagSet.Args(), storage)
+		return createTokenCMD(flagSet.Args(), storage)
 
 	case `fixtures`:
-		fixturesCMD(flagSet.Args(), storage)
+		return fixturesCMD(flagSet.Args(), storage)
 
 	case `http-server`, `server`, `s`:
-		httpServerCMD(flagSet.Args(), cache)
+		return httpServerCMD(flagSet.Args(), loader, cache, healthChecks(storage, cache)...)
+
+	case `export`:
+		return exportCMD(flagSet.Args(), storage)
+
+	case `apply`:
+		return applyCMD(flagSet.Args(), storage)
 
 	default:
 		fmt.Println(`please provide on of the commands`)
 		fmt.Printf("\t%s\n", `http-server`)
 		fmt.Printf("\t%s\n", `create-token`)
+		fmt.Printf("\t%s\n", `export`)
+		fmt.Printf("\t%s\n", `apply`)
+		fmt.Printf("\t%s\n", `copy-data`)
+		fmt.Printf("\t%s\n", `config validate`)
+		fmt.Printf("\t%s\n", `flags`)
+		fmt.Printf("\t%s\n", `envs`)
+		fmt.Printf("\t%s\n", `rollouts`)
+		fmt.Printf("\t%s\n", `pilots`)
+		fmt.Printf("\t%s\n", `eval`)
 	}
-
+	return nil
 }
 
-func httpServerCMD(args []string, s toggler.Storage) {
+func httpServerCMD(args []string, loader *configLoader, s toggler.Storage, checks ...health.Check) error {
 	flagSet := flag.NewFlagSet(`http-server`, flag.ExitOnError)
-	portConfValue := flagSet.String(`port`, os.Getenv(`PORT`), `set http server port else the env variable "PORT" value will be used.`)
+	flagSet.String(`port`, ``, `set http server port else the env variable "PORT" value will be used.`)
+	flagSet.String(`metrics-token`, ``, `protect the /metrics endpoint with a bearer token, else the env variable "METRICS_TOKEN" value will be used.`)
 
 	if err := flagSet.Parse(args[1:]); err != nil {
-		log.Println(err)
+		slog.Error(`invalid command line flags`, slog.String(`error`, err.Error()))
 	}
 
-	httpServer(getPort(*portConfValue), s)
-}
+	loader.flagSets = append(loader.flagSets, flagSet)
+	cfg, err := loader.Load()
+	if err != nil {
+		return err
+	}
+	if cfg.HTTP.Port == 0 {
+		return errors.New(`http server port is not given (--port/$PORT/http.port)`)
+	}
 
-func httpServer(port int, storage toggler.Storage) {
-	s := makeHTTPServer(storage, port)
-	withGracefulShutdown(func() {
-		if err := s.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatal(err)
-		}
-	}, func(ctx context.Context) error {
-		return s.Shutdown(ctx)
-	})
+	return httpServer(cfg, loader, s, checks...)
 }
 
-func getPort(portConfValue string) int {
-	if portConfValue == `` {
-		log.Fatal(`http server port is not given (--port/$PORT)`)
-	}
-	p, err := strconv.Atoi(portConfValue)
+func httpServer(cfg config.Config, loader *configLoader, storage toggler.Storage, checks ...health.Check) error {
+	s, settings, err := makeHTTPServer(storage, cfg, checks...)
 	if err != nil {
-		log.Fatal(`http server port is not a number`)
+		return err
 	}
-	return p
-}
+	go reloadOnSIGHUP(loader, cfg, settings)
 
-func setupDatabaseURL(dbURL *string) {
-	if *dbURL == `` {
-		return
+	if cfg.HTTP.TLS.Enabled() {
+		certs, err := httptls.New(cfg.HTTP.TLS.HTTPTLSConfig(), cfg.HTTP.TLS.WatchInterval.Duration())
+		if err != nil {
+			return err
+		}
+		defer certs.Close()
+		s.TLSConfig = certs.TLSConfig()
 	}
 
-	if err := os.Setenv(`DATABASE_URL`, *dbURL); err != nil {
-		log.Fatal(err)
-	}
+	return withGracefulShutdown(cfg.HTTP.ShutdownTimeout.Duration(), func() error {
+		var err error
+		if s.TLSConfig != nil {
+			// the certificates are served by the TLSConfig, so they can be reloaded.
+			err = s.ListenAndServeTLS(``, ``)
+		} else {
+			err = s.ListenAndServe()
+		}
+		if err == http.ErrServerClosed {
+			return nil
+		}
+		return err
+	}, func(ctx context.Context) error {
+		return s.Shutdown(ctx)
+	})
 }
 
-func setupCacheURL(cacheURL *string) {
-	if *cacheURL == `` {
-		*cacheURL = os.Getenv(`CACHE_URL`)
+func setupDatabaseURL(dbURL string) error {
+	if dbURL == `` {
+		return nil
 	}
+	return os.Setenv(`DATABASE_URL`, dbURL)
 }
 
-func fixturesCMD(args []string, s toggler.Storage) {
+func fixturesCMD(args []string, s toggler.Storage) error {
 	flagSet := flag.NewFlagSet(`fixtures`, flag.ExitOnError)
 	fixtures := flagSet.Bool(`create-fixtures`, false, `create default fixtures for development purpose.`)
 	localDevelopmentToken := flagSet.String(`create-unsafe-token`, ``, `create token for local development purpose (don't use in prod)`)
 
 	if err := flagSet.Parse(args[1:]); err != nil {
-		log.Println(err)
+		slog.Error(`invalid command line flags`, slog.String(`error`, err.Error()))
 	}
 
 	if *fixtures {
@@ -146,6 +262,7 @@ func fixturesCMD(args []string, s toggler.Storage) {
 	if *localDevelopmentToken != `` {
 		createDevelopmentToken(s, *localDevelopmentToken)
 	}
+	return nil
 }
 
 func createFixtures(s toggler.Storage) {
@@ -159,54 +276,125 @@ func createFixtures(s toggler.Storage) {
 	_ = uc.RolloutManager.SetPilotEnrollmentForFeature(context.Background(), ff.ID, devEnv.ID, `test-public-pilot-id-2`, false)
 }
 
-func makeHTTPServer(storage toggler.Storage, port int) *http.Server {
-	useCases := toggler.NewUseCases(storage)
-	mux, err := httpintf.NewServeMux(useCases)
+func makeHTTPServer(storage toggler.Storage, cfg config.Config, checks ...health.Check) (*http.Server, reloadableSettings, error) {
+	m := metrics.New()
+	if cache, ok := storage.(metrics.Cache); ok {
+		m.ObserveCache(cache)
+		storage = tracing.Storage(storage, `cache`)
+	}
+
+	useCases := toggler.NewUseCases(m.Storage(storage))
+	useCases.RolloutManager.Observer = m
+	useCases.Webhooks.ErrorHandler = func(ctx context.Context, err error) {
+		logging.Error(ctx, `webhook delivery failed`, err)
+	}
+	if err := useCases.Webhooks.Start(context.Background()); err != nil {
+		return nil, reloadableSettings{}, err
+	}
+	useCases.Scheduler.ErrorHandler = func(ctx context.Context, err error) {
+		logging.Error(ctx, `scheduled change run failed`, err)
+	}
+	if err := useCases.Scheduler.Start(context.Background()); err != nil {
+		return nil, reloadableSettings{}, err
+	}
+	useCases.Guardrails.ErrorHandler = func(ctx context.Context, err error) {
+		logging.Error(ctx, `guardrail evaluation failed`, err)
+	}
+	if err := useCases.Guardrails.Start(context.Background()); err != nil {
+		return nil, reloadableSettings{}, err
+	}
+	mux, err := httpintf.NewServeMux(useCases, checks...)
 	if err != nil {
-		log.Fatal(err)
+		return nil, reloadableSettings{}, err
 	}
+	mux.Handle(`/metrics`, m.Handler(cfg.Auth.MetricsToken))
 
-	loggerMW := logger.New()
-	app := loggerMW.Handler(mux)
+	settings := reloadableSettings{cors: mux.CORS, clientIdentities: mux.ClientIdentities, bodyLimit: &httputils.BodyLimit{}}
+	settings.apply(cfg)
+
+	app := logging.Middleware(slog.Default(), tracing.Middleware(m.Middleware(settings.bodyLimit.Handler(mux), mux.Route), mux.Route), mux.Route)
 
 	server := &http.Server{
-		Addr:    fmt.Sprintf(`:%d`, port),
-		Handler: app,
+		Addr:              fmt.Sprintf(`:%d`, cfg.HTTP.Port),
+		Handler:           app,
+		ReadTimeout:       cfg.HTTP.ReadTimeout.Duration(),
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout.Duration(),
+		WriteTimeout:      cfg.HTTP.WriteTimeout.Duration(),
+		IdleTimeout:       cfg.HTTP.IdleTimeout.Duration(),
 	}
+	// the deliveries in progress are stopped with the server, and they stay pending in the delivery log.
+	server.RegisterOnShutdown(func() { _ = useCases.Webhooks.Close() })
+	server.RegisterOnShutdown(func() { _ = useCases.Scheduler.Close() })
+	server.RegisterOnShutdown(func() { _ = useCases.Guardrails.Close() })
 
-	return server
+	return server, settings, nil
+}
+
+// healthChecks collects the dependencies of the http server which can be checked.
+// The cache is optional, because the storage can serve the requests without it.
+func healthChecks(storage, cache toggler.Storage) []health.Check {
+	var checks []health.Check
+	if p, ok := storage.(health.Pinger); ok {
+		checks = append(checks, health.Check{Name: `storage`, Check: p.Ping})
+	}
+	if m, ok := storage.(interface{ CheckMigrations(context.Context) error }); ok {
+		checks = append(checks, health.Check{Name: `migrations`, Check: m.CheckMigrations})
+	}
+	if p, ok := cache.(health.Pinger); ok {
+		checks = append(checks, health.Check{Name: `cache`, Optional: true, Check: p.Ping})
+	}
+	return checks
 }
 
-func createTokenCMD(args []string, s toggler.Storage) {
+func createTokenCMD(args []string, s toggler.Storage) error {
 	flagSet := flag.NewFlagSet(`create-token`, flag.ExitOnError)
+	env := flagSet.String(`env`, ``, `scope the token to the deployment environment (ID or name), so the OFREP clients evaluate the flags in it`)
 
 	flagSet.Usage = func() {
-		const format = "Usage of %s: [TOKEN_OWNER_UID]\n"
+		const format = "Usage of %s: [-env ENV] [TOKEN_OWNER_UID]\n"
 		_, _ = fmt.Fprintf(flagSet.Output(), format, args[0])
 		flagSet.PrintDefaults()
 	}
 
 	if err := flagSet.Parse(args[1:]); err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	createToken(s, flagSet.Arg(0))
+	return createToken(s, flagSet.Arg(0), *env)
 }
 
-func createToken(s toggler.Storage, ownerUID string) {
+func createToken(s toggler.Storage, ownerUID, envAlias string) error {
 	if ownerUID == `` {
-		log.Fatal(`owner uid required to create a token`)
+		return errors.New(`owner uid required to create a token`)
 	}
 
+	ctx := context.Background()
 	issuer := security.Issuer{Storage: s}
 
-	tStr, _, err := issuer.CreateNewToken(context.Background(), ownerUID, nil, nil)
+	var (
+		tStr string
+		err  error
+	)
+	if envAlias == `` {
+		tStr, _, err = issuer.CreateNewToken(ctx, ownerUID, nil, nil)
+	} else {
+		var env release.Environment
+		found, ferr := s.ReleaseEnvironment(ctx).FindByAlias(ctx, envAlias, &env)
+		if ferr != nil {
+			return ferr
+		}
+		if !found {
+			return fmt.Errorf(`deployment environment not found: %s`, envAlias)
+		}
+		tStr, _, err = issuer.CreateNewEnvironmentToken(ctx, ownerUID, env.ID, nil, nil)
+	}
 
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	fmt.Println(`token:`, tStr)
+	return nil
 }
 
 func createDevelopmentToken(s toggler.Storage, tokenSTR string) {
@@ -248,23 +436,39 @@ func createDevelopmentToken(s toggler.Storage, tokenSTR string) {
 	}
 }
 
-func withGracefulShutdown(wrk func(), shutdown func(context.Context) error) {
+func withGracefulShutdown(timeout time.Duration, wrk func() error, shutdown func(context.Context) error) error {
 
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
-	go wrk()
-	<-done
+	failed := make(chan error, 1)
+	go func() { failed <- wrk() }()
+
+	select {
+	case <-done:
+	case err := <-failed:
+		// the work stopped on its own, like when the port is taken,
+		// but the background jobs registered for the shutdown still have to stop.
+		_ = shutdown(context.Background())
+		return err
+	}
 
-	log.Println(`toggler graceful shutdown - BEGIN`)
+	slog.Info(`graceful shutdown started`)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 25*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
 	if err := shutdown(ctx); err != nil {
-		log.Fatalln(err)
+		return err
 	}
 
-	log.Println(`toggler graceful shutdown - FINISH`)
+	slog.Info(`graceful shutdown finished`)
+	return nil
+}
 
+// fatal logs the error that stops the command, and exits with a non-zero status code.
+// The deferred functions don't run after it, so the commands which hold resources return their errors to main instead.
+func fatal(err error) {
+	slog.Error(`command failed`, slog.String(`error`, err.Error()))
+	os.Exit(1)
 }
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/toggler"
+)
+
+func exportCMD(args []string, s toggler.Storage) error {
+	flagSet := flag.NewFlagSet(`export`, flag.ExitOnError)
+	format := flagSet.String(`format`, `yaml`, `manifest format, either yaml or json.`)
+	pilots := flagSet.Bool(`pilots`, false, `include the manual pilot enrollments in the manifest.`)
+
+	if err := flagSet.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	uc := toggler.NewUseCases(s)
+	m, err := uc.RolloutManager.ExportManifest(context.Background(), release.ManifestOptions{Pilots: *pilots})
+	if err != nil {
+		return err
+	}
+
+	var out []byte
+	switch *format {
+	case `yaml`:
+		out, err = yaml.Marshal(m)
+	case `json`:
+		out, err = json.MarshalIndent(m, ``, `  `)
+		out = append(out, '\n')
+	default:
+		return fmt.Errorf(`unknown manifest format: %s`, *format)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(out)
+	return err
+}
+
+func applyCMD(args []string, s toggler.Storage) error {
+	flagSet := flag.NewFlagSet(`apply`, flag.ExitOnError)
+	file := flagSet.String(`f`, ``, `path to the YAML/JSON manifest file, use "-" to read from stdin.`)
+	dryRun := flagSet.Bool(`dry-run`, false, `only print the plan, without applying it.`)
+	prune := flagSet.Bool(`prune`, false, `delete the entities that are not declared in the manifest.`)
+	pilots := flagSet.Bool(`pilots`, false, `reconcile the manual pilot enrollments as well.`)
+
+	if err := flagSet.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if *file == `` {
+		return errors.New(`manifest file is not given (-f)`)
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	if *file == `-` {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(*file)
+	}
+	if err != nil {
+		return err
+	}
+
+	var m release.Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return err
+	}
+
+	var (
+		ctx  = context.Background()
+		uc   = toggler.NewUseCases(s)
+		opts = release.ManifestOptions{Pilots: *pilots, Prune: *prune}
+		plan release.ManifestPlan
+	)
+	if *dryRun {
+		plan, err = uc.RolloutManager.PlanManifest(ctx, m, opts)
+	} else {
+		plan, err = uc.RolloutManager.ApplyManifest(ctx, m, opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(plan.String())
+	if *dryRun && !plan.IsEmpty() {
+		fmt.Println(`dry run, no changes were applied`)
+	}
+	return nil
+}
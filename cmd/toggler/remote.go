@@ -0,0 +1,527 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/external/config"
+	"github.com/toggler-io/toggler/external/interface/httpintf/apiclient"
+)
+
+// remoteCommands are the admin commands, which manage a remote toggler through its HTTP API.
+// They don't need a storage, only the url and a token of the server.
+var remoteCommands = map[string]func(args []string){
+	`flags`:    flagsCMD,
+	`envs`:     envsCMD,
+	`rollouts`: rolloutsCMD,
+	`pilots`:   pilotsCMD,
+	`eval`:     evalCMD,
+}
+
+const (
+	outputTable = `table`
+	outputJSON  = `json`
+)
+
+// remoteFlags are the flags which every admin command has.
+type remoteFlags struct {
+	url     *string
+	token   *string
+	profile *string
+	output  *string
+}
+
+func newRemoteFlagSet(name string) (*flag.FlagSet, remoteFlags) {
+	flagSet := flag.NewFlagSet(name, flag.ExitOnError)
+	return flagSet, remoteFlags{
+		url:     flagSet.String(`url`, ``, `the url of the toggler server. default value is taken from ENV[TOGGLER_URL] or the profile.`),
+		token:   flagSet.String(`token`, ``, `the security token for the server. default value is taken from ENV[TOGGLER_TOKEN] or the profile.`),
+		profile: flagSet.String(`profile`, ``, `the name of the profile in the profiles file (ENV[TOGGLER_PROFILES] or ~/.config/toggler/profiles.yaml). default value is taken from ENV[TOGGLER_PROFILE].`),
+		output:  flagSet.String(`o`, outputTable, `output format, either table or json.`),
+	}
+}
+
+// client connects to the server with the settings from the flags, the environment or the profile, in this order.
+func (f remoteFlags) client() apiclient.Client {
+	if *f.output != outputTable && *f.output != outputJSON {
+		fatal(fmt.Errorf(`unknown output format: %s`, *f.output))
+	}
+
+	path, ok := os.LookupEnv(`TOGGLER_PROFILES`)
+	if !ok {
+		var err error
+		if path, err = config.DefaultProfilesPath(); err != nil {
+			fatal(err)
+		}
+	}
+	profiles, err := config.LoadProfiles(path)
+	if err != nil {
+		fatal(err)
+	}
+	name := *f.profile
+	if name == `` {
+		name = os.Getenv(`TOGGLER_PROFILE`)
+	}
+	profile, err := profiles.Lookup(name)
+	if err != nil {
+		fatal(err)
+	}
+
+	c := apiclient.Client{
+		BaseURL: firstNonEmpty(*f.url, os.Getenv(`TOGGLER_URL`), profile.URL),
+		Token:   firstNonEmpty(*f.token, os.Getenv(`TOGGLER_TOKEN`), profile.Token),
+	}
+	if c.BaseURL == `` {
+		fatal(errors.New(`toggler server url is not given (-url/$TOGGLER_URL/profile)`))
+	}
+	return c
+}
+
+// print writes the value as JSON, or as a table with the given header and rows.
+func (f remoteFlags) print(v interface{}, header []string, rows [][]string) {
+	if *f.output == outputJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent(``, `  `)
+		if err := enc.Encode(v); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	printTable(os.Stdout, header, rows)
+}
+
+func printTable(out io.Writer, header []string, rows [][]string) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		_, _ = fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	if err := w.Flush(); err != nil {
+		fatal(err)
+	}
+}
+
+func firstNonEmpty(vs ...string) string {
+	for _, v := range vs {
+		if v != `` {
+			return v
+		}
+	}
+	return ``
+}
+
+// subcommand splits the args of a command like "flags list -o json" into the subcommand and its args.
+func subcommand(args []string, usage string) (string, []string) {
+	if len(args) < 2 {
+		fatal(fmt.Errorf(`usage: toggler %s`, usage))
+	}
+	return args[1], args[1:]
+}
+
+func flagsCMD(args []string) {
+	const usage = `flags list|create|delete`
+	sub, args := subcommand(args, usage)
+	ctx := context.Background()
+
+	switch sub {
+	case `list`:
+		flagSet, rf := newRemoteFlagSet(`flags list`)
+		prefix := flagSet.String(`prefix`, ``, `list only the flags with this name prefix.`)
+		_ = flagSet.Parse(args[1:])
+		flags, err := rf.client().ListFlags(ctx, *prefix)
+		if err != nil {
+			fatal(err)
+		}
+		var rows [][]string
+		for _, f := range flags {
+			rows = append(rows, []string{f.ID, f.Name, strconv.Itoa(f.Revision)})
+		}
+		rf.print(flags, []string{`ID`, `NAME`, `REVISION`}, rows)
+
+	case `create`:
+		flagSet, rf := newRemoteFlagSet(`flags create`)
+		_ = flagSet.Parse(args[1:])
+		c := rf.client()
+		var created []release.Flag
+		for _, name := range requireArgs(flagSet, `flag name`) {
+			f, err := c.CreateFlag(ctx, release.Flag{Name: name})
+			if err != nil {
+				fatal(err)
+			}
+			created = append(created, f)
+		}
+		var rows [][]string
+		for _, f := range created {
+			rows = append(rows, []string{f.ID, f.Name})
+		}
+		rf.print(created, []string{`ID`, `NAME`}, rows)
+
+	case `delete`:
+		flagSet, rf := newRemoteFlagSet(`flags delete`)
+		_ = flagSet.Parse(args[1:])
+		c := rf.client()
+		for _, nameOrID := range requireArgs(flagSet, `flag name`) {
+			f, err := c.FindFlag(ctx, nameOrID)
+			if err != nil {
+				fatal(err)
+			}
+			if err := c.DeleteFlag(ctx, f.ID); err != nil {
+				fatal(err)
+			}
+		}
+
+	default:
+		fatal(fmt.Errorf(`usage: toggler %s`, usage))
+	}
+}
+
+func envsCMD(args []string) {
+	const usage = `envs list|create|delete`
+	sub, args := subcommand(args, usage)
+	ctx := context.Background()
+
+	switch sub {
+	case `list`:
+		flagSet, rf := newRemoteFlagSet(`envs list`)
+		prefix := flagSet.String(`prefix`, ``, `list only the environments with this name prefix.`)
+		_ = flagSet.Parse(args[1:])
+		envs, err := rf.client().ListEnvironments(ctx, *prefix)
+		if err != nil {
+			fatal(err)
+		}
+		var rows [][]string
+		for _, env := range envs {
+			rows = append(rows, []string{env.ID, env.Name, strconv.Itoa(env.Revision)})
+		}
+		rf.print(envs, []string{`ID`, `NAME`, `REVISION`}, rows)
+
+	case `create`:
+		flagSet, rf := newRemoteFlagSet(`envs create`)
+		_ = flagSet.Parse(args[1:])
+		c := rf.client()
+		var created []release.Environment
+		for _, name := range requireArgs(flagSet, `environment name`) {
+			env, err := c.CreateEnvironment(ctx, release.Environment{Name: name})
+			if err != nil {
+				fatal(err)
+			}
+			created = append(created, env)
+		}
+		var rows [][]string
+		for _, env := range created {
+			rows = append(rows, []string{env.ID, env.Name})
+		}
+		rf.print(created, []string{`ID`, `NAME`}, rows)
+
+	case `delete`:
+		flagSet, rf := newRemoteFlagSet(`envs delete`)
+		_ = flagSet.Parse(args[1:])
+		c := rf.client()
+		for _, nameOrID := range requireArgs(flagSet, `environment name`) {
+			env, err := c.FindEnvironment(ctx, nameOrID)
+			if err != nil {
+				fatal(err)
+			}
+			if err := c.DeleteEnvironment(ctx, env.ID); err != nil {
+				fatal(err)
+			}
+		}
+
+	default:
+		fatal(fmt.Errorf(`usage: toggler %s`, usage))
+	}
+}
+
+func rolloutsCMD(args []string) {
+	const usage = `rollouts list|set|delete`
+	sub, args := subcommand(args, usage)
+	ctx := context.Background()
+
+	switch sub {
+	case `list`:
+		flagSet, rf := newRemoteFlagSet(`rollouts list`)
+		flagName := flagSet.String(`flag`, ``, `list only the rollouts of this release flag.`)
+		envName := flagSet.String(`env`, ``, `list only the rollouts in this deployment environment.`)
+		_ = flagSet.Parse(args[1:])
+		c := rf.client()
+		flagNames, envNames := names(ctx, c)
+		rollouts, err := c.ListRollouts(ctx, lookupFlagID(ctx, c, *flagName), lookupEnvID(ctx, c, *envName))
+		if err != nil {
+			fatal(err)
+		}
+		var rows [][]string
+		for _, r := range rollouts {
+			rows = append(rows, []string{flagNames[r.FlagID], envNames[r.EnvironmentID], planSummary(r.Plan), strconv.Itoa(r.Revision)})
+		}
+		rf.print(rollouts, []string{`FLAG`, `ENV`, `PLAN`, `REVISION`}, rows)
+
+	case `set`:
+		flagSet, rf := newRemoteFlagSet(`rollouts set`)
+		envName := flagSet.String(`env`, ``, `the deployment environment of the rollout.`)
+		percentage := flagSet.Int(`percentage`, -1, `enroll this percentage of the pilots.`)
+		global := flagSet.String(`global`, ``, `turn the flag on or off for every pilot.`)
+		_ = flagSet.Parse(args[1:])
+		flagNames := requireArgs(flagSet, `flag name`)
+		if *envName == `` {
+			fatal(errors.New(`deployment environment is not given (-env)`))
+		}
+		plan := rolloutPlanFromFlags(*percentage, *global)
+
+		c := rf.client()
+		env, err := c.FindEnvironment(ctx, *envName)
+		if err != nil {
+			fatal(err)
+		}
+		var rows [][]string
+		var rollouts []release.Rollout
+		for _, name := range flagNames {
+			f, err := c.FindFlag(ctx, name)
+			if err != nil {
+				fatal(err)
+			}
+			r, err := c.SetRolloutPlan(ctx, f.ID, env.ID, plan)
+			if err != nil {
+				fatal(err)
+			}
+			rollouts = append(rollouts, r)
+			rows = append(rows, []string{f.Name, env.Name, planSummary(r.Plan)})
+		}
+		rf.print(rollouts, []string{`FLAG`, `ENV`, `PLAN`}, rows)
+
+	case `delete`:
+		flagSet, rf := newRemoteFlagSet(`rollouts delete`)
+		envName := flagSet.String(`env`, ``, `the deployment environment of the rollout.`)
+		_ = flagSet.Parse(args[1:])
+		flagNames := requireArgs(flagSet, `flag name`)
+		if *envName == `` {
+			fatal(errors.New(`deployment environment is not given (-env)`))
+		}
+		c := rf.client()
+		env, err := c.FindEnvironment(ctx, *envName)
+		if err != nil {
+			fatal(err)
+		}
+		for _, name := range flagNames {
+			f, err := c.FindFlag(ctx, name)
+			if err != nil {
+				fatal(err)
+			}
+			if err := c.DeleteRollout(ctx, f.ID, env.ID); err != nil {
+				fatal(err)
+			}
+		}
+
+	default:
+		fatal(fmt.Errorf(`usage: toggler %s`, usage))
+	}
+}
+
+// rolloutPlanFromFlags returns the plan which is set by the rollouts set command.
+// A percentage plan keeps the seed of the current percentage plan,
+// so the already enrolled pilots stay enrolled when the percentage is increased.
+func rolloutPlanFromFlags(percentage int, global string) func(release.RolloutPlan) release.RolloutPlan {
+	switch {
+	case 0 <= percentage && global == ``:
+		return func(current release.RolloutPlan) release.RolloutPlan {
+			plan, ok := current.(release.RolloutDecisionByPercentage)
+			if !ok {
+				plan = release.NewRolloutDecisionByPercentage()
+			}
+			plan.Percentage = percentage
+			return plan
+		}
+	case percentage < 0 && global != ``:
+		state, err := parseOnOff(global)
+		if err != nil {
+			fatal(err)
+		}
+		return func(release.RolloutPlan) release.RolloutPlan {
+			return release.RolloutDecisionByGlobal{State: state}
+		}
+	default:
+		fatal(errors.New(`either the -percentage or the -global option is required`))
+		return nil
+	}
+}
+
+func parseOnOff(v string) (bool, error) {
+	switch v {
+	case `on`:
+		return true, nil
+	case `off`:
+		return false, nil
+	default:
+		return strconv.ParseBool(v)
+	}
+}
+
+func planSummary(plan release.RolloutPlan) string {
+	switch p := plan.(type) {
+	case release.RolloutDecisionByGlobal:
+		if p.State {
+			return `global on`
+		}
+		return `global off`
+	case release.RolloutDecisionByPercentage:
+		return fmt.Sprintf(`percentage %d%%`, p.Percentage)
+	case release.RolloutDecisionByAPI:
+		if p.URL == nil {
+			return `api`
+		}
+		return `api ` + p.URL.String()
+	case release.RolloutDecisionAND:
+		return fmt.Sprintf(`(%s) and (%s)`, planSummary(p.Left), planSummary(p.Right))
+	case release.RolloutDecisionOR:
+		return fmt.Sprintf(`(%s) or (%s)`, planSummary(p.Left), planSummary(p.Right))
+	case release.RolloutDecisionNOT:
+		return fmt.Sprintf(`not (%s)`, planSummary(p.Definition))
+	default:
+		return fmt.Sprintf(`%T`, plan)
+	}
+}
+
+func pilotsCMD(args []string) {
+	const usage = `pilots list|enroll|unenroll`
+	sub, args := subcommand(args, usage)
+	ctx := context.Background()
+
+	switch sub {
+	case `list`:
+		flagSet, rf := newRemoteFlagSet(`pilots list`)
+		flagName := flagSet.String(`flag`, ``, `list only the pilots of this release flag.`)
+		envName := flagSet.String(`env`, ``, `list only the pilots in this deployment environment.`)
+		_ = flagSet.Parse(args[1:])
+		c := rf.client()
+		flagNames, envNames := names(ctx, c)
+		pilots, err := c.ListPilots(ctx, lookupFlagID(ctx, c, *flagName), lookupEnvID(ctx, c, *envName))
+		if err != nil {
+			fatal(err)
+		}
+		var rows [][]string
+		for _, p := range pilots {
+			rows = append(rows, []string{p.PublicID, flagNames[p.FlagID], envNames[p.EnvironmentID], strconv.FormatBool(p.IsParticipating)})
+		}
+		rf.print(pilots, []string{`PILOT`, `FLAG`, `ENV`, `PARTICIPATING`}, rows)
+
+	case `enroll`, `unenroll`:
+		flagSet, rf := newRemoteFlagSet(`pilots ` + sub)
+		flagName := flagSet.String(`flag`, ``, `the release flag of the enrollment.`)
+		envName := flagSet.String(`env`, ``, `the deployment environment of the enrollment.`)
+		_ = flagSet.Parse(args[1:])
+		pilotIDs := requireArgs(flagSet, `pilot id`)
+		if *flagName == `` || *envName == `` {
+			fatal(errors.New(`release flag and deployment environment are required (-flag, -env)`))
+		}
+		c := rf.client()
+		f, err := c.FindFlag(ctx, *flagName)
+		if err != nil {
+			fatal(err)
+		}
+		env, err := c.FindEnvironment(ctx, *envName)
+		if err != nil {
+			fatal(err)
+		}
+		var pilots []release.Pilot
+		var rows [][]string
+		for _, pilotID := range pilotIDs {
+			p, err := c.SetPilotEnrollment(ctx, f.ID, env.ID, pilotID, sub == `enroll`)
+			if err != nil {
+				fatal(err)
+			}
+			pilots = append(pilots, p)
+			rows = append(rows, []string{p.PublicID, f.Name, env.Name, strconv.FormatBool(p.IsParticipating)})
+		}
+		rf.print(pilots, []string{`PILOT`, `FLAG`, `ENV`, `PARTICIPATING`}, rows)
+
+	default:
+		fatal(fmt.Errorf(`usage: toggler %s`, usage))
+	}
+}
+
+func evalCMD(args []string) {
+	flagSet, rf := newRemoteFlagSet(`eval`)
+	pilotID := flagSet.String(`pilot`, ``, `the public id of the pilot.`)
+	envName := flagSet.String(`env`, ``, `the deployment environment of the evaluation.`)
+	asOf := flagSet.String(`as-of`, ``, `evaluate the flags as they were at this RFC3339 time.`)
+	_ = flagSet.Parse(args[1:])
+	if *pilotID == `` || *envName == `` {
+		fatal(errors.New(`pilot and deployment environment are required (-pilot, -env)`))
+	}
+
+	var at time.Time
+	if *asOf != `` {
+		var err error
+		if at, err = time.Parse(time.RFC3339, *asOf); err != nil {
+			fatal(err)
+		}
+	}
+
+	evaluations, err := rf.client().Evaluate(context.Background(), *pilotID, *envName, at, flagSet.Args()...)
+	if err != nil {
+		fatal(err)
+	}
+	var rows [][]string
+	for _, e := range evaluations {
+		rows = append(rows, []string{e.FlagName, strconv.FormatBool(e.State), string(e.Reason)})
+	}
+	rf.print(evaluations, []string{`FLAG`, `STATE`, `REASON`}, rows)
+}
+
+func requireArgs(flagSet *flag.FlagSet, name string) []string {
+	if flagSet.NArg() == 0 {
+		fatal(fmt.Errorf(`%s is not given`, name))
+	}
+	return flagSet.Args()
+}
+
+// names maps the IDs of the flags and the environments to their names, so the tables can show the names.
+func names(ctx context.Context, c apiclient.Client) (flags, envs map[string]string) {
+	flags, envs = map[string]string{}, map[string]string{}
+	fs, err := c.ListFlags(ctx, ``)
+	if err != nil {
+		fatal(err)
+	}
+	for _, f := range fs {
+		flags[f.ID] = f.Name
+	}
+	es, err := c.ListEnvironments(ctx, ``)
+	if err != nil {
+		fatal(err)
+	}
+	for _, env := range es {
+		envs[env.ID] = env.Name
+	}
+	return flags, envs
+}
+
+func lookupFlagID(ctx context.Context, c apiclient.Client, nameOrID string) string {
+	if nameOrID == `` {
+		return ``
+	}
+	f, err := c.FindFlag(ctx, nameOrID)
+	if err != nil {
+		fatal(err)
+	}
+	return f.ID
+}
+
+func lookupEnvID(ctx context.Context, c apiclient.Client, nameOrID string) string {
+	if nameOrID == `` {
+		return ``
+	}
+	env, err := c.FindEnvironment(ctx, nameOrID)
+	if err != nil {
+		fatal(err)
+	}
+	return env.ID
+}
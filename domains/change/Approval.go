@@ -0,0 +1,29 @@
+package change
+
+import "time"
+
+// Approval is the consent of a token owner to apply a change request.
+// A token owner can approve a change request only once, and not the one proposed by themselves.
+type Approval struct {
+	ID        string    `ext:"ID" json:"id"`
+	RequestID string    `json:"change_request_id"`
+	OwnerUID  string    `json:"owner_uid"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ApprovalQuery selects the approvals of a change request.
+type ApprovalQuery struct {
+	RequestID string
+}
+
+func (q ApprovalQuery) Match(a Approval) bool {
+	return q.RequestID == `` || q.RequestID == a.RequestID
+}
+
+// Less tells the order of the approvals, which is the order they were given.
+func (q ApprovalQuery) Less(a, b Approval) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
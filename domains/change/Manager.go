@@ -0,0 +1,227 @@
+package change
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/extid"
+	"github.com/adamluzsi/frameless/iterators"
+
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/security"
+)
+
+func NewManager(s Storage) *Manager {
+	return &Manager{Storage: s}
+}
+
+// Manager takes the change requests through their review.
+// The storage is expected to be guarded by the Protection,
+// so the changes of the protected environments are only made by Apply.
+type Manager struct {
+	Storage Storage
+}
+
+// Review is a change request along with what its reviewer needs to know about it.
+type Review struct {
+	Request Request
+	// Environment is the protected environment, which tells how many approvals the change request needs.
+	Environment release.Environment
+	Approvals   []Approval
+	// Changes is the field level difference between the base and the proposed state of the entity.
+	Changes []release.VersionChange
+}
+
+// IsApproved tells if the change request has the approvals required by its environment.
+func (r Review) IsApproved() bool {
+	return r.Environment.RequiredApprovals <= len(r.Approvals)
+}
+
+// List returns the change requests that match the query, the latest first.
+func (m *Manager) List(ctx context.Context, q RequestQuery) ([]Request, error) {
+	requests := make([]Request, 0)
+	err := iterators.Collect(m.Storage.ChangeRequest(ctx).FindByQuery(ctx, q), &requests)
+	return requests, err
+}
+
+// Review looks up a change request, along with its approvals and changes.
+func (m *Manager) Review(ctx context.Context, requestID string) (Review, error) {
+	var review Review
+	found, err := m.Storage.ChangeRequest(ctx).FindByID(ctx, &review.Request, requestID)
+	if err != nil {
+		return Review{}, err
+	}
+	if !found {
+		return Review{}, ErrRequestNotFound
+	}
+
+	if _, err := m.Storage.ReleaseEnvironment(ctx).FindByID(ctx, &review.Environment, review.Request.EnvironmentID); err != nil {
+		return Review{}, err
+	}
+
+	review.Approvals = make([]Approval, 0)
+	if err := iterators.Collect(m.Storage.ChangeApproval(ctx).FindByQuery(ctx, ApprovalQuery{RequestID: requestID}), &review.Approvals); err != nil {
+		return Review{}, err
+	}
+
+	review.Changes, err = review.Request.Changes()
+	return review, err
+}
+
+// Approve records the approval of the token owner of the context.
+// The token owner who proposed the change can't approve it.
+func (m *Manager) Approve(ctx context.Context, requestID string) (rErr error) {
+	owner, ok := security.LookupOwner(ctx)
+	if !ok {
+		return ErrOwnerUnknown
+	}
+
+	ctx, err := m.Storage.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer frameless.FinishOnePhaseCommit(&rErr, m.Storage, ctx)
+
+	request, err := m.pending(ctx, requestID)
+	if err != nil {
+		return err
+	}
+	if request.ProposedBy == owner {
+		return ErrSelfApproval
+	}
+
+	return m.Storage.ChangeApproval(ctx).Create(ctx, &Approval{
+		RequestID: request.ID,
+		OwnerUID:  owner,
+		CreatedAt: time.Now().UTC(),
+	})
+}
+
+// Reject closes the change request without making the change.
+func (m *Manager) Reject(ctx context.Context, requestID, reason string) (rErr error) {
+	owner, ok := security.LookupOwner(ctx)
+	if !ok {
+		return ErrOwnerUnknown
+	}
+
+	ctx, err := m.Storage.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer frameless.FinishOnePhaseCommit(&rErr, m.Storage, ctx)
+
+	request, err := m.pending(ctx, requestID)
+	if err != nil {
+		return err
+	}
+
+	request.Status = StatusRejected
+	request.ResolvedBy = owner
+	request.Reason = reason
+	request.UpdatedAt = time.Now().UTC()
+	return m.Storage.ChangeRequest(ctx).Update(ctx, &request)
+}
+
+// Apply makes the proposed change, once the change request has the approvals required by its environment.
+// The change fails with release.ErrRevisionConflict when the entity was changed since the proposal,
+// and the change request stays pending.
+func (m *Manager) Apply(ctx context.Context, requestID string) (rErr error) {
+	owner, ok := security.LookupOwner(ctx)
+	if !ok {
+		return ErrOwnerUnknown
+	}
+
+	ctx, err := m.Storage.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer frameless.FinishOnePhaseCommit(&rErr, m.Storage, ctx)
+
+	review, err := m.Review(ctx, requestID)
+	if err != nil {
+		return err
+	}
+	if !review.Request.IsPending() {
+		return ErrRequestNotPending
+	}
+	if review.Environment.ID == `` {
+		return release.ErrEnvironmentNotFound
+	}
+	if !review.IsApproved() {
+		return ErrNotEnoughApprovals
+	}
+
+	request := review.Request
+	request.EntityID, err = m.apply(contextWithApplying(ctx), request)
+	if err != nil {
+		return err
+	}
+	request.Status = StatusApplied
+	request.ResolvedBy = owner
+	request.UpdatedAt = time.Now().UTC()
+	return m.Storage.ChangeRequest(ctx).Update(ctx, &request)
+}
+
+func (m *Manager) pending(ctx context.Context, requestID string) (Request, error) {
+	var request Request
+	found, err := m.Storage.ChangeRequest(ctx).FindByID(ctx, &request, requestID)
+	if err != nil {
+		return Request{}, err
+	}
+	if !found {
+		return Request{}, ErrRequestNotFound
+	}
+	if !request.IsPending() {
+		return Request{}, ErrRequestNotPending
+	}
+	return request, nil
+}
+
+type entityStorage interface {
+	frameless.Creator
+	frameless.Updater
+	frameless.Deleter
+}
+
+// apply makes the change of the request, and returns the ID of the changed entity.
+func (m *Manager) apply(ctx context.Context, request Request) (string, error) {
+	var (
+		s entityStorage
+		T interface{}
+	)
+	switch request.Kind {
+	case KindEnvironment:
+		s, T = m.Storage.ReleaseEnvironment(ctx), release.Environment{}
+	case KindRollout:
+		s, T = m.Storage.ReleaseRollout(ctx), release.Rollout{}
+	case KindPilot:
+		s, T = m.Storage.ReleasePilot(ctx), release.Pilot{}
+	default:
+		return ``, ErrInvalidKind
+	}
+
+	if request.Action == ActionDelete {
+		return request.EntityID, s.DeleteByID(ctx, request.EntityID)
+	}
+
+	ptr := reflect.New(reflect.TypeOf(T)).Interface()
+	if err := request.DecodeProposal(ptr); err != nil {
+		return ``, err
+	}
+
+	switch request.Action {
+	case ActionCreate:
+		if err := s.Create(ctx, ptr); err != nil {
+			return ``, err
+		}
+		id, _ := extid.Lookup(ptr)
+		return fmt.Sprint(id), nil
+	case ActionUpdate:
+		return request.EntityID, s.Update(ctx, ptr)
+	default:
+		return ``, ErrInvalidAction
+	}
+}
@@ -0,0 +1,188 @@
+package change_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adamluzsi/frameless/iterators"
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/change"
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/security"
+	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/external/resource/storages"
+)
+
+func TestManager(t *testing.T) {
+	s := testcase.NewSpec(t)
+	defer s.Finish()
+
+	var (
+		ctx        = context.Background()
+		ctxOf      = func(owner string) context.Context { return security.ContextWithOwner(ctx, owner) }
+		useCases   = s.Let(`use cases`, func(t *testcase.T) interface{} { return toggler.NewUseCases(storages.NewInMemory()) })
+		storageGet = func(t *testcase.T) toggler.Storage { return useCases.Get(t).(*toggler.UseCases).Storage }
+		managerGet = func(t *testcase.T) *change.Manager { return useCases.Get(t).(*toggler.UseCases).Changes }
+		approvals  = s.LetValue(`required approvals`, 1)
+		env        = s.Let(`env`, func(t *testcase.T) interface{} {
+			env := &release.Environment{Name: `production`, RequiredApprovals: approvals.Get(t).(int)}
+			require.Nil(t, storageGet(t).ReleaseEnvironment(ctx).Create(ctx, env))
+			return env
+		})
+		envGet = func(t *testcase.T) *release.Environment { return env.Get(t).(*release.Environment) }
+		flag   = s.Let(`flag`, func(t *testcase.T) interface{} {
+			flag := &release.Flag{Name: `new-checkout`}
+			require.Nil(t, storageGet(t).ReleaseFlag(ctx).Create(ctx, flag))
+			return flag
+		})
+		flagGet    = func(t *testcase.T) *release.Flag { return flag.Get(t).(*release.Flag) }
+		newRollout = func(t *testcase.T) *release.Rollout {
+			return &release.Rollout{
+				FlagID:        flagGet(t).ID,
+				EnvironmentID: envGet(t).ID,
+				Plan:          release.RolloutDecisionByGlobal{State: true},
+			}
+		}
+		rollouts = func(t *testcase.T) []release.Rollout {
+			var rs []release.Rollout
+			require.Nil(t, iterators.Collect(storageGet(t).ReleaseRollout(ctx).FindAll(ctx), &rs))
+			return rs
+		}
+		propose = func(t *testcase.T, owner string, write func(context.Context) error) change.Request {
+			pctx := change.ContextWithProposals(ctxOf(owner))
+			require.Nil(t, write(pctx))
+			proposed := change.Proposed(pctx)
+			require.Len(t, proposed, 1)
+			return proposed[0]
+		}
+	)
+
+	s.Describe(`protection`, func(s *testcase.Spec) {
+		subject := func(t *testcase.T, ctx context.Context) error {
+			return storageGet(t).ReleaseRollout(ctx).Create(ctx, newRollout(t))
+		}
+
+		s.Then(`the direct changes of the protected environment are rejected`, func(t *testcase.T) {
+			require.Equal(t, change.ErrChangeRequestRequired, subject(t, ctxOf(`alice`)))
+			require.Empty(t, rollouts(t))
+		})
+
+		s.Then(`the bulk deletions are rejected while an environment is protected`, func(t *testcase.T) {
+			_ = envGet(t)
+			require.Equal(t, change.ErrChangeRequestRequired, storageGet(t).ReleaseRollout(ctx).DeleteAll(ctx))
+		})
+
+		s.When(`the changes are proposed`, func(s *testcase.Spec) {
+			s.Then(`a pending change request is recorded instead of the change`, func(t *testcase.T) {
+				request := propose(t, `alice`, func(ctx context.Context) error { return subject(t, ctx) })
+				require.Empty(t, rollouts(t))
+				require.Equal(t, change.StatusPending, request.Status)
+				require.Equal(t, change.KindRollout, request.Kind)
+				require.Equal(t, change.ActionCreate, request.Action)
+				require.Equal(t, envGet(t).ID, request.EnvironmentID)
+				require.Equal(t, `alice`, request.ProposedBy)
+
+				requests, err := managerGet(t).List(ctx, change.RequestQuery{EnvironmentID: envGet(t).ID})
+				require.Nil(t, err)
+				require.Equal(t, []change.Request{request}, requests)
+			})
+
+			s.Then(`the token owner must be known`, func(t *testcase.T) {
+				require.Equal(t, change.ErrOwnerUnknown, subject(t, change.ContextWithProposals(ctx)))
+			})
+		})
+
+		s.When(`the environment is not protected`, func(s *testcase.Spec) {
+			approvals.LetValue(s, 0)
+
+			s.Then(`the changes are made directly`, func(t *testcase.T) {
+				ctx := change.ContextWithProposals(ctxOf(`alice`))
+				require.Nil(t, subject(t, ctx))
+				require.Len(t, rollouts(t), 1)
+				require.Empty(t, change.Proposed(ctx))
+			})
+		})
+	})
+
+	s.Describe(`review`, func(s *testcase.Spec) {
+		request := s.Let(`change request`, func(t *testcase.T) interface{} {
+			return propose(t, `alice`, func(ctx context.Context) error {
+				return storageGet(t).ReleaseRollout(ctx).Create(ctx, newRollout(t))
+			})
+		})
+		requestID := func(t *testcase.T) string { return request.Get(t).(change.Request).ID }
+
+		s.Then(`the proposer can't approve the change request`, func(t *testcase.T) {
+			require.Equal(t, change.ErrSelfApproval, managerGet(t).Approve(ctxOf(`alice`), requestID(t)))
+		})
+
+		s.Then(`an other token owner can approve the change request only once`, func(t *testcase.T) {
+			require.Nil(t, managerGet(t).Approve(ctxOf(`bob`), requestID(t)))
+			require.Equal(t, change.ErrAlreadyApproved, managerGet(t).Approve(ctxOf(`bob`), requestID(t)))
+
+			review, err := managerGet(t).Review(ctx, requestID(t))
+			require.Nil(t, err)
+			require.Len(t, review.Approvals, 1)
+			require.Equal(t, `bob`, review.Approvals[0].OwnerUID)
+			require.True(t, review.IsApproved())
+		})
+
+		s.Then(`the change can't be applied without the required approvals`, func(t *testcase.T) {
+			require.Equal(t, change.ErrNotEnoughApprovals, managerGet(t).Apply(ctxOf(`bob`), requestID(t)))
+			require.Empty(t, rollouts(t))
+		})
+
+		s.Then(`the approved change is applied`, func(t *testcase.T) {
+			require.Nil(t, managerGet(t).Approve(ctxOf(`bob`), requestID(t)))
+			require.Nil(t, managerGet(t).Apply(ctxOf(`bob`), requestID(t)))
+
+			rs := rollouts(t)
+			require.Len(t, rs, 1)
+			review, err := managerGet(t).Review(ctx, requestID(t))
+			require.Nil(t, err)
+			require.Equal(t, change.StatusApplied, review.Request.Status)
+			require.Equal(t, `bob`, review.Request.ResolvedBy)
+			require.Equal(t, rs[0].ID, review.Request.EntityID)
+			require.Equal(t, change.ErrRequestNotPending, managerGet(t).Apply(ctxOf(`bob`), requestID(t)))
+		})
+
+		s.Then(`the rejected change request is closed without the change`, func(t *testcase.T) {
+			require.Nil(t, managerGet(t).Reject(ctxOf(`bob`), requestID(t), `not now`))
+
+			review, err := managerGet(t).Review(ctx, requestID(t))
+			require.Nil(t, err)
+			require.Equal(t, change.StatusRejected, review.Request.Status)
+			require.Equal(t, `not now`, review.Request.Reason)
+			require.Equal(t, change.ErrRequestNotPending, managerGet(t).Approve(ctxOf(`carol`), requestID(t)))
+			require.Empty(t, rollouts(t))
+		})
+
+		s.When(`the entity was changed since the proposal`, func(s *testcase.Spec) {
+			approvals.LetValue(s, 0)
+
+			s.Then(`the change request can't be applied`, func(t *testcase.T) {
+				rollout := newRollout(t)
+				require.Nil(t, storageGet(t).ReleaseRollout(ctx).Create(ctx, rollout))
+				env := envGet(t)
+				env.RequiredApprovals = 1
+				require.Nil(t, storageGet(t).ReleaseEnvironment(ctx).Update(ctx, env))
+
+				update := func(percentage int) change.Request {
+					return propose(t, `alice`, func(ctx context.Context) error {
+						r := *rollout
+						r.Plan = release.RolloutDecisionByPercentage{Percentage: percentage}
+						return storageGet(t).ReleaseRollout(ctx).Update(ctx, &r)
+					})
+				}
+				first, second := update(10), update(20)
+				for _, r := range []change.Request{first, second} {
+					require.Nil(t, managerGet(t).Approve(ctxOf(`bob`), r.ID))
+				}
+				require.Nil(t, managerGet(t).Apply(ctxOf(`bob`), first.ID))
+				require.Equal(t, release.ErrRevisionConflict, managerGet(t).Apply(ctxOf(`bob`), second.ID))
+			})
+		})
+	})
+}
@@ -0,0 +1,376 @@
+package change
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/security"
+)
+
+type proposalsContextKey struct{}
+
+type proposals struct {
+	mutex    sync.Mutex
+	requests []Request
+}
+
+// ContextWithProposals returns a context in which the changes of the protected environments
+// are recorded as pending change requests, instead of being rejected.
+func ContextWithProposals(ctx context.Context) context.Context {
+	return context.WithValue(ctx, proposalsContextKey{}, &proposals{})
+}
+
+// Proposed returns the change requests which were recorded in place of the changes made with the context.
+func Proposed(ctx context.Context) []Request {
+	ps, ok := ctx.Value(proposalsContextKey{}).(*proposals)
+	if !ok {
+		return nil
+	}
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	return append([]Request(nil), ps.requests...)
+}
+
+type applyingContextKey struct{}
+
+// contextWithApplying marks the changes of a change request which is being applied,
+// so they are let through the Protection.
+func contextWithApplying(ctx context.Context) context.Context {
+	return context.WithValue(ctx, applyingContextKey{}, true)
+}
+
+// ContextWithRollback marks the automatic rollback of a rollout by its guardrail,
+// which is let through the Protection, since waiting for approvals would defeat the purpose of the kill switch.
+func ContextWithRollback(ctx context.Context) context.Context {
+	return contextWithApplying(ctx)
+}
+
+func isApplying(ctx context.Context) bool {
+	applying, _ := ctx.Value(applyingContextKey{}).(bool)
+	return applying
+}
+
+// Protection guards the protected environments, so they, their rollouts and their pilots
+// are only changed by applying approved change requests.
+// A direct change is rejected with ErrChangeRequestRequired,
+// unless it is made with a context from ContextWithProposals, in which case it is proposed as a change request.
+type Protection struct {
+	Storage Storage
+}
+
+// guarded describes a change of an entity, which might affect a protected environment.
+type guarded struct {
+	Kind     string
+	Action   string
+	EnvIDs   []string
+	EntityID string
+	// Base is the stored entity, or nil on create.
+	Base interface{}
+	// Proposal is the changed entity, or nil on delete.
+	Proposal interface{}
+}
+
+func (p Protection) guard(ctx context.Context, g guarded, change func(context.Context) error) error {
+	if isApplying(ctx) {
+		return change(ctx)
+	}
+
+	env, ok, err := p.protectedEnvironment(ctx, g.EnvIDs...)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return change(ctx)
+	}
+
+	ps, ok := ctx.Value(proposalsContextKey{}).(*proposals)
+	if !ok {
+		return ErrChangeRequestRequired
+	}
+
+	request, err := p.request(ctx, env, g)
+	if err != nil {
+		return err
+	}
+	if err := p.Storage.ChangeRequest(ctx).Create(ctx, &request); err != nil {
+		return err
+	}
+
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	ps.requests = append(ps.requests, request)
+	return nil
+}
+
+func (p Protection) request(ctx context.Context, env release.Environment, g guarded) (Request, error) {
+	owner, ok := security.LookupOwner(ctx)
+	if !ok {
+		return Request{}, ErrOwnerUnknown
+	}
+
+	if v, ok := g.Proposal.(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return Request{}, err
+		}
+	}
+
+	if g.Action == ActionUpdate {
+		// the proposal is applied on the state it was based on,
+		// so the changes made in the meantime are not overwritten silently.
+		revision, _ := release.RevisionOf(g.Proposal)
+		baseRevision, _ := release.RevisionOf(g.Base)
+		if *revision != 0 && *revision != *baseRevision {
+			return Request{}, release.ErrRevisionConflict
+		}
+		*revision = *baseRevision
+	}
+
+	now := time.Now().UTC()
+	request := Request{
+		EnvironmentID: env.ID,
+		Kind:          g.Kind,
+		Action:        g.Action,
+		EntityID:      g.EntityID,
+		Status:        StatusPending,
+		ProposedBy:    owner,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	var err error
+	if g.Base != nil {
+		if request.Base, err = json.Marshal(g.Base); err != nil {
+			return Request{}, err
+		}
+	}
+	if g.Proposal != nil {
+		if request.Proposal, err = json.Marshal(g.Proposal); err != nil {
+			return Request{}, err
+		}
+	}
+	return request, nil
+}
+
+// protectedEnvironment returns the first protected environment among the given ones.
+func (p Protection) protectedEnvironment(ctx context.Context, envIDs ...string) (release.Environment, bool, error) {
+	for _, envID := range envIDs {
+		if envID == `` {
+			continue
+		}
+		var env release.Environment
+		found, err := p.Storage.ReleaseEnvironment(ctx).FindByID(ctx, &env, envID)
+		if err != nil {
+			return release.Environment{}, false, err
+		}
+		if found && env.IsProtected() {
+			return env, true, nil
+		}
+	}
+	return release.Environment{}, false, nil
+}
+
+// guardAll rejects the bulk deletions while any of the environments is protected,
+// as they can't be proposed as change requests.
+func (p Protection) guardAll(ctx context.Context, deleteAll func(context.Context) error) error {
+	if isApplying(ctx) {
+		return deleteAll(ctx)
+	}
+
+	var envs []release.Environment
+	iter := p.Storage.ReleaseEnvironment(ctx).FindAll(ctx)
+	defer iter.Close()
+	for iter.Next() {
+		var env release.Environment
+		if err := iter.Decode(&env); err != nil {
+			return err
+		}
+		envs = append(envs, env)
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+
+	for _, env := range envs {
+		if env.IsProtected() {
+			return ErrChangeRequestRequired
+		}
+	}
+	return deleteAll(ctx)
+}
+
+// stored looks up the current state of the entity, which is the base of the proposed update or delete.
+func stored(ctx context.Context, s interface {
+	FindByID(ctx context.Context, ptr, id interface{}) (bool, error)
+}, T interface{}, id interface{}) (interface{}, bool, error) {
+	ptr := reflect.New(reflect.TypeOf(T)).Interface()
+	found, err := s.FindByID(ctx, ptr, id)
+	return ptr, found, err
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+// ProtectedEnvironmentStorage guards the changes of the protected environments.
+// The creation of an environment is not guarded, as a new environment has no rollouts and pilots yet.
+type ProtectedEnvironmentStorage struct {
+	release.EnvironmentStorage
+	Protection Protection
+}
+
+func (s ProtectedEnvironmentStorage) Update(ctx context.Context, ptr interface{}) error {
+	env := ptr.(*release.Environment)
+	base, found, err := stored(ctx, s.EnvironmentStorage, release.Environment{}, env.ID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return s.EnvironmentStorage.Update(ctx, ptr)
+	}
+	return s.Protection.guard(ctx, guarded{
+		Kind:     KindEnvironment,
+		Action:   ActionUpdate,
+		EnvIDs:   []string{env.ID},
+		EntityID: env.ID,
+		Base:     base,
+		Proposal: env,
+	}, func(ctx context.Context) error { return s.EnvironmentStorage.Update(ctx, ptr) })
+}
+
+func (s ProtectedEnvironmentStorage) DeleteByID(ctx context.Context, id interface{}) error {
+	base, found, err := stored(ctx, s.EnvironmentStorage, release.Environment{}, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return s.EnvironmentStorage.DeleteByID(ctx, id)
+	}
+	env := base.(*release.Environment)
+	return s.Protection.guard(ctx, guarded{
+		Kind:     KindEnvironment,
+		Action:   ActionDelete,
+		EnvIDs:   []string{env.ID},
+		EntityID: env.ID,
+		Base:     base,
+	}, func(ctx context.Context) error { return s.EnvironmentStorage.DeleteByID(ctx, id) })
+}
+
+func (s ProtectedEnvironmentStorage) DeleteAll(ctx context.Context) error {
+	return s.Protection.guardAll(ctx, s.EnvironmentStorage.DeleteAll)
+}
+
+// ProtectedRolloutStorage guards the changes of the rollouts in the protected environments.
+type ProtectedRolloutStorage struct {
+	release.RolloutStorage
+	Protection Protection
+}
+
+func (s ProtectedRolloutStorage) Create(ctx context.Context, ptr interface{}) error {
+	rollout := ptr.(*release.Rollout)
+	return s.Protection.guard(ctx, guarded{
+		Kind:     KindRollout,
+		Action:   ActionCreate,
+		EnvIDs:   []string{rollout.EnvironmentID},
+		Proposal: rollout,
+	}, func(ctx context.Context) error { return s.RolloutStorage.Create(ctx, ptr) })
+}
+
+func (s ProtectedRolloutStorage) Update(ctx context.Context, ptr interface{}) error {
+	rollout := ptr.(*release.Rollout)
+	base, found, err := stored(ctx, s.RolloutStorage, release.Rollout{}, rollout.ID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return s.RolloutStorage.Update(ctx, ptr)
+	}
+	return s.Protection.guard(ctx, guarded{
+		Kind:     KindRollout,
+		Action:   ActionUpdate,
+		EnvIDs:   []string{base.(*release.Rollout).EnvironmentID, rollout.EnvironmentID},
+		EntityID: rollout.ID,
+		Base:     base,
+		Proposal: rollout,
+	}, func(ctx context.Context) error { return s.RolloutStorage.Update(ctx, ptr) })
+}
+
+func (s ProtectedRolloutStorage) DeleteByID(ctx context.Context, id interface{}) error {
+	base, found, err := stored(ctx, s.RolloutStorage, release.Rollout{}, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return s.RolloutStorage.DeleteByID(ctx, id)
+	}
+	rollout := base.(*release.Rollout)
+	return s.Protection.guard(ctx, guarded{
+		Kind:     KindRollout,
+		Action:   ActionDelete,
+		EnvIDs:   []string{rollout.EnvironmentID},
+		EntityID: rollout.ID,
+		Base:     base,
+	}, func(ctx context.Context) error { return s.RolloutStorage.DeleteByID(ctx, id) })
+}
+
+func (s ProtectedRolloutStorage) DeleteAll(ctx context.Context) error {
+	return s.Protection.guardAll(ctx, s.RolloutStorage.DeleteAll)
+}
+
+// ProtectedPilotStorage guards the changes of the pilots in the protected environments.
+type ProtectedPilotStorage struct {
+	release.PilotStorage
+	Protection Protection
+}
+
+func (s ProtectedPilotStorage) Create(ctx context.Context, ptr interface{}) error {
+	pilot := ptr.(*release.Pilot)
+	return s.Protection.guard(ctx, guarded{
+		Kind:     KindPilot,
+		Action:   ActionCreate,
+		EnvIDs:   []string{pilot.EnvironmentID},
+		Proposal: pilot,
+	}, func(ctx context.Context) error { return s.PilotStorage.Create(ctx, ptr) })
+}
+
+func (s ProtectedPilotStorage) Update(ctx context.Context, ptr interface{}) error {
+	pilot := ptr.(*release.Pilot)
+	base, found, err := stored(ctx, s.PilotStorage, release.Pilot{}, pilot.ID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return s.PilotStorage.Update(ctx, ptr)
+	}
+	return s.Protection.guard(ctx, guarded{
+		Kind:     KindPilot,
+		Action:   ActionUpdate,
+		EnvIDs:   []string{base.(*release.Pilot).EnvironmentID, pilot.EnvironmentID},
+		EntityID: pilot.ID,
+		Base:     base,
+		Proposal: pilot,
+	}, func(ctx context.Context) error { return s.PilotStorage.Update(ctx, ptr) })
+}
+
+func (s ProtectedPilotStorage) DeleteByID(ctx context.Context, id interface{}) error {
+	base, found, err := stored(ctx, s.PilotStorage, release.Pilot{}, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return s.PilotStorage.DeleteByID(ctx, id)
+	}
+	pilot := base.(*release.Pilot)
+	return s.Protection.guard(ctx, guarded{
+		Kind:     KindPilot,
+		Action:   ActionDelete,
+		EnvIDs:   []string{pilot.EnvironmentID},
+		EntityID: pilot.ID,
+		Base:     base,
+	}, func(ctx context.Context) error { return s.PilotStorage.DeleteByID(ctx, id) })
+}
+
+func (s ProtectedPilotStorage) DeleteAll(ctx context.Context) error {
+	return s.Protection.guardAll(ctx, s.PilotStorage.DeleteAll)
+}
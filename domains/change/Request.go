@@ -0,0 +1,109 @@
+package change
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/toggler-io/toggler/domains/release"
+)
+
+// The kinds of entities that can be changed with change requests.
+const (
+	KindEnvironment = `environment`
+	KindRollout     = release.VersionKindRollout
+	KindPilot       = release.VersionKindPilot
+)
+
+// The actions a change request can propose.
+const (
+	ActionCreate = release.VersionActionCreate
+	ActionUpdate = release.VersionActionUpdate
+	ActionDelete = release.VersionActionDelete
+)
+
+// The states of a change request.
+const (
+	StatusPending  = `pending`
+	StatusRejected = `rejected`
+	StatusApplied  = `applied`
+)
+
+// Request is a proposed change of a protected environment, its rollout or its pilot.
+// The change is only made when the request is applied, after enough approvals.
+type Request struct {
+	ID string `ext:"ID" json:"id"`
+	// EnvironmentID is the protected environment which is affected by the change.
+	EnvironmentID string `json:"env_id"`
+	// Kind tells the type of the changed entity, e.g. rollout.
+	Kind string `json:"kind"`
+	// Action is the proposed change of the entity.
+	Action string `json:"action"`
+	// EntityID is the ID of the changed entity.
+	// A proposed create has no entity ID until the request is applied.
+	EntityID string `json:"entity_id,omitempty"`
+	// Base is the JSON encoded state of the entity when the change was proposed.
+	// It is empty when the entity is proposed to be created.
+	Base json.RawMessage `json:"base,omitempty"`
+	// Proposal is the JSON encoded state of the entity after the change.
+	// It is empty when the entity is proposed to be deleted.
+	Proposal json.RawMessage `json:"proposal,omitempty"`
+	Status   string          `json:"status"`
+	// ProposedBy is the token owner who proposed the change.
+	ProposedBy string `json:"proposed_by"`
+	// ResolvedBy is the token owner who applied or rejected the change request.
+	ResolvedBy string `json:"resolved_by,omitempty"`
+	// Reason is the explanation of the rejection.
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IsPending tells if the request can be still approved, rejected or applied.
+func (r Request) IsPending() bool {
+	return r.Status == StatusPending
+}
+
+// DecodeProposal unmarshal the proposed state of the entity into the entity pointer.
+func (r Request) DecodeProposal(ptr interface{}) error {
+	return json.Unmarshal(r.Proposal, ptr)
+}
+
+// Changes compares the base and the proposed state of the entity field by field.
+func (r Request) Changes() ([]release.VersionChange, error) {
+	// the states are compared as the versions of the entity,
+	// where a missing state is compared as a deleted version.
+	from := release.Version{Kind: r.Kind, EntityID: r.EntityID, Action: ActionUpdate, Snapshot: r.Base}
+	if r.Action == ActionCreate {
+		from.Action = ActionDelete
+	}
+	to := release.Version{Kind: r.Kind, EntityID: r.EntityID, Action: ActionUpdate, Snapshot: r.Proposal}
+	if r.Action == ActionDelete {
+		to.Action = ActionDelete
+	}
+	return release.DiffVersions(from, to)
+}
+
+// RequestQuery selects the change requests of an environment, or with a status.
+// The empty fields don't filter the requests.
+type RequestQuery struct {
+	EnvironmentID string
+	Status        string
+}
+
+func (q RequestQuery) Match(r Request) bool {
+	if q.EnvironmentID != `` && q.EnvironmentID != r.EnvironmentID {
+		return false
+	}
+	if q.Status != `` && q.Status != r.Status {
+		return false
+	}
+	return true
+}
+
+// Less tells the order of the change requests, which is the latest first, and then by ID.
+func (q RequestQuery) Less(a, b Request) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
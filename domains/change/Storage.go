@@ -0,0 +1,41 @@
+package change
+
+import (
+	"context"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/iterators"
+
+	"github.com/toggler-io/toggler/domains/release"
+)
+
+// Storage holds the change requests, along with the release entities they change.
+type Storage interface {
+	release.Storage
+	ChangeRequest(context.Context) RequestStorage
+	ChangeApproval(context.Context) ApprovalStorage
+}
+
+type (
+	RequestEntries  = iterators.Interface
+	ApprovalEntries = iterators.Interface
+)
+
+type RequestStorage interface {
+	frameless.Creator
+	frameless.Finder
+	frameless.Updater
+	frameless.Deleter
+	// FindByQuery returns the change requests that match the query, the latest first.
+	FindByQuery(ctx context.Context, q RequestQuery) RequestEntries
+}
+
+// ApprovalStorage holds the approvals of the change requests.
+// Its Create fails with ErrAlreadyApproved when the owner already approved the change request.
+type ApprovalStorage interface {
+	frameless.Creator
+	frameless.Finder
+	frameless.Deleter
+	// FindByQuery returns the approvals that match the query, in the order they were given.
+	FindByQuery(ctx context.Context, q ApprovalQuery) ApprovalEntries
+}
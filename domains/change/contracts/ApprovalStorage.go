@@ -0,0 +1,131 @@
+package contracts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/contracts"
+	"github.com/adamluzsi/frameless/iterators"
+	"github.com/adamluzsi/testcase"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/change"
+)
+
+type ApprovalStorage struct {
+	Subject        func(testing.TB) change.ApprovalStorage
+	Context        func(testing.TB) context.Context
+	FixtureFactory func(testing.TB) frameless.FixtureFactory
+}
+
+func (c ApprovalStorage) storage() testcase.Var {
+	return testcase.Var{
+		Name: "change approval storage",
+		Init: func(t *testcase.T) interface{} {
+			return c.Subject(t)
+		},
+	}
+}
+
+func (c ApprovalStorage) storageGet(t *testcase.T) change.ApprovalStorage {
+	return c.storage().Get(t).(change.ApprovalStorage)
+}
+
+func (c ApprovalStorage) String() string {
+	return "ApprovalStorage"
+}
+
+func (c ApprovalStorage) Test(t *testing.T) {
+	c.Spec(testcase.NewSpec(t))
+}
+
+func (c ApprovalStorage) Benchmark(b *testing.B) {
+	c.Spec(testcase.NewSpec(b))
+}
+
+func (c ApprovalStorage) Spec(s *testcase.Spec) {
+	T := change.Approval{}
+	testcase.RunContract(s,
+		contracts.Creator{T: T,
+			Subject:        func(tb testing.TB) contracts.CRD { return c.Subject(tb) },
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		contracts.Finder{T: T,
+			Subject:        func(tb testing.TB) contracts.CRD { return c.Subject(tb) },
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		contracts.Deleter{T: T,
+			Subject:        func(tb testing.TB) contracts.CRD { return c.Subject(tb) },
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+	)
+
+	s.Describe(`approvals`, func(s *testcase.Spec) {
+		var (
+			requestID   = s.Let(`change request id`, func(t *testcase.T) interface{} { return uuid.New().String() })
+			newApproval = func(t *testcase.T, requestID, ownerUID string, createdAt time.Time) *change.Approval {
+				a := c.FixtureFactory(t).Fixture(change.Approval{}, c.Context(t)).(change.Approval)
+				a.RequestID = requestID
+				a.OwnerUID = ownerUID
+				a.CreatedAt = createdAt
+				return &a
+			}
+		)
+
+		s.Before(func(t *testcase.T) {
+			contracts.DeleteAllEntity(t, c.storageGet(t), c.Context(t))
+		})
+
+		s.Describe(`.Create`, func(s *testcase.Spec) {
+			s.When(`the owner already approved the change request`, func(s *testcase.Spec) {
+				s.Before(func(t *testcase.T) {
+					contracts.CreateEntity(t, c.storageGet(t), c.Context(t),
+						newApproval(t, requestID.Get(t).(string), `alice`, time.Now().UTC()))
+				})
+
+				s.Then(`it fails with a conflict`, func(t *testcase.T) {
+					a := newApproval(t, requestID.Get(t).(string), `alice`, time.Now().UTC())
+					require.Equal(t, change.ErrAlreadyApproved, c.storageGet(t).Create(c.Context(t), a))
+				})
+
+				s.Then(`the owner can still approve an other change request`, func(t *testcase.T) {
+					a := newApproval(t, uuid.New().String(), `alice`, time.Now().UTC())
+					contracts.CreateEntity(t, c.storageGet(t), c.Context(t), a)
+				})
+			})
+		})
+
+		s.Describe(`.FindByQuery`, func(s *testcase.Spec) {
+			subject := func(t *testcase.T) []change.Approval {
+				var approvals []change.Approval
+				iter := c.storageGet(t).FindByQuery(c.Context(t), change.ApprovalQuery{RequestID: requestID.Get(t).(string)})
+				require.Nil(t, iterators.Collect(iter, &approvals))
+				return approvals
+			}
+
+			s.Before(func(t *testcase.T) {
+				now := time.Now().UTC().Truncate(time.Second)
+				for _, a := range []*change.Approval{
+					newApproval(t, requestID.Get(t).(string), `bob`, now),
+					newApproval(t, requestID.Get(t).(string), `alice`, now.Add(-time.Hour)),
+					newApproval(t, uuid.New().String(), `alice`, now),
+				} {
+					contracts.CreateEntity(t, c.storageGet(t), c.Context(t), a)
+				}
+			})
+
+			s.Then(`it returns the approvals of the change request, in the order they were given`, func(t *testcase.T) {
+				approvals := subject(t)
+				require.Len(t, approvals, 2)
+				require.Equal(t, `alice`, approvals[0].OwnerUID)
+				require.Equal(t, `bob`, approvals[1].OwnerUID)
+			})
+		})
+	})
+}
@@ -0,0 +1,136 @@
+package contracts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/contracts"
+	"github.com/adamluzsi/frameless/iterators"
+	"github.com/adamluzsi/testcase"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/change"
+)
+
+type RequestStorage struct {
+	Subject        func(testing.TB) change.RequestStorage
+	Context        func(testing.TB) context.Context
+	FixtureFactory func(testing.TB) frameless.FixtureFactory
+}
+
+func (c RequestStorage) storage() testcase.Var {
+	return testcase.Var{
+		Name: "change request storage",
+		Init: func(t *testcase.T) interface{} {
+			return c.Subject(t)
+		},
+	}
+}
+
+func (c RequestStorage) storageGet(t *testcase.T) change.RequestStorage {
+	return c.storage().Get(t).(change.RequestStorage)
+}
+
+func (c RequestStorage) String() string {
+	return "RequestStorage"
+}
+
+func (c RequestStorage) Test(t *testing.T) {
+	c.Spec(testcase.NewSpec(t))
+}
+
+func (c RequestStorage) Benchmark(b *testing.B) {
+	c.Spec(testcase.NewSpec(b))
+}
+
+func (c RequestStorage) Spec(s *testcase.Spec) {
+	T := change.Request{}
+	testcase.RunContract(s,
+		contracts.Creator{T: T,
+			Subject:        func(tb testing.TB) contracts.CRD { return c.Subject(tb) },
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		contracts.Finder{T: T,
+			Subject:        func(tb testing.TB) contracts.CRD { return c.Subject(tb) },
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		contracts.Updater{T: T,
+			Subject:        func(tb testing.TB) contracts.UpdaterSubject { return c.Subject(tb) },
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		contracts.Deleter{T: T,
+			Subject:        func(tb testing.TB) contracts.CRD { return c.Subject(tb) },
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+	)
+
+	s.Describe(`.FindByQuery`, func(s *testcase.Spec) {
+		var (
+			envID      = s.Let(`env id`, func(t *testcase.T) interface{} { return uuid.New().String() })
+			newRequest = func(t *testcase.T, envID, status string, createdAt time.Time) *change.Request {
+				r := c.FixtureFactory(t).Fixture(change.Request{}, c.Context(t)).(change.Request)
+				r.EnvironmentID = envID
+				r.Status = status
+				r.CreatedAt = createdAt
+				return &r
+			}
+			query = s.Let(`query`, func(t *testcase.T) interface{} {
+				return change.RequestQuery{EnvironmentID: envID.Get(t).(string)}
+			})
+			subject = func(t *testcase.T) []change.Request {
+				var requests []change.Request
+				iter := c.storageGet(t).FindByQuery(c.Context(t), query.Get(t).(change.RequestQuery))
+				require.Nil(t, iterators.Collect(iter, &requests))
+				return requests
+			}
+		)
+
+		s.Before(func(t *testcase.T) {
+			contracts.DeleteAllEntity(t, c.storageGet(t), c.Context(t))
+			now := time.Now().UTC().Truncate(time.Second)
+			for _, r := range []*change.Request{
+				newRequest(t, envID.Get(t).(string), change.StatusApplied, now.Add(-time.Hour)),
+				newRequest(t, envID.Get(t).(string), change.StatusPending, now),
+				newRequest(t, uuid.New().String(), change.StatusPending, now),
+			} {
+				contracts.CreateEntity(t, c.storageGet(t), c.Context(t), r)
+			}
+		})
+
+		s.Then(`it returns the change requests of the environment, the latest first`, func(t *testcase.T) {
+			requests := subject(t)
+			require.Len(t, requests, 2)
+			require.Equal(t, change.StatusPending, requests[0].Status)
+			require.Equal(t, change.StatusApplied, requests[1].Status)
+		})
+
+		s.When(`the status is given`, func(s *testcase.Spec) {
+			query.Let(s, func(t *testcase.T) interface{} {
+				return change.RequestQuery{Status: change.StatusPending}
+			})
+
+			s.Then(`it returns the change requests with the status from every environment`, func(t *testcase.T) {
+				requests := subject(t)
+				require.Len(t, requests, 2)
+				for _, r := range requests {
+					require.Equal(t, change.StatusPending, r.Status)
+				}
+			})
+		})
+
+		s.When(`the query is empty`, func(s *testcase.Spec) {
+			query.Let(s, func(t *testcase.T) interface{} { return change.RequestQuery{} })
+
+			s.Then(`it returns every change request`, func(t *testcase.T) {
+				require.Len(t, subject(t), 3)
+			})
+		})
+	})
+}
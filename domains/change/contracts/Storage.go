@@ -0,0 +1,57 @@
+package contracts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/contracts"
+	"github.com/adamluzsi/testcase"
+
+	"github.com/toggler-io/toggler/domains/change"
+)
+
+type Storage struct {
+	Subject        func(testing.TB) change.Storage
+	Context        func(testing.TB) context.Context
+	FixtureFactory func(testing.TB) frameless.FixtureFactory
+}
+
+func (c Storage) String() string {
+	return `change#Storage`
+}
+
+func (c Storage) Test(t *testing.T) {
+	c.Spec(testcase.NewSpec(t))
+}
+
+func (c Storage) Benchmark(b *testing.B) {
+	c.Spec(testcase.NewSpec(b))
+}
+
+func (c Storage) Spec(s *testcase.Spec) {
+	testcase.RunContract(s,
+		RequestStorage{
+			Subject: func(tb testing.TB) change.RequestStorage {
+				return c.Subject(tb).ChangeRequest(c.Context(tb))
+			},
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		ApprovalStorage{
+			Subject: func(tb testing.TB) change.ApprovalStorage {
+				return c.Subject(tb).ChangeApproval(c.Context(tb))
+			},
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		contracts.OnePhaseCommitProtocol{T: change.Request{},
+			Subject: func(tb testing.TB) (frameless.OnePhaseCommitProtocol, contracts.CRD) {
+				storage := c.Subject(tb)
+				return storage, storage.ChangeRequest(c.Context(tb))
+			},
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+	)
+}
@@ -0,0 +1,12 @@
+package contracts_test
+
+import (
+	c "github.com/adamluzsi/frameless/contracts"
+	"github.com/toggler-io/toggler/domains/change/contracts"
+)
+
+var _ = []c.Interface{
+	contracts.Storage{},
+	contracts.RequestStorage{},
+	contracts.ApprovalStorage{},
+}
@@ -0,0 +1,24 @@
+package change
+
+import "github.com/toggler-io/toggler/domains/errs"
+
+var (
+	ErrInvalidKind   = errs.Validation(`change_request_kind_is_invalid`, `kind`, `change request kind is not known`)
+	ErrInvalidAction = errs.Validation(`change_request_action_is_invalid`, `action`, `change request action is not known`)
+)
+
+var (
+	ErrRequestNotFound = errs.NotFound(`change_request_not_found`, `change request not found`)
+)
+
+var (
+	ErrChangeRequestRequired = errs.Conflict(`change_request_required`, `the deployment environment is protected, its changes need an approved change request`)
+	ErrRequestNotPending     = errs.Conflict(`change_request_not_pending`, `change request is already applied or rejected`)
+	ErrAlreadyApproved       = errs.Conflict(`change_request_already_approved`, `change request is already approved by the token owner`)
+	ErrNotEnoughApprovals    = errs.Conflict(`change_request_not_enough_approvals`, `change request doesn't have the approvals required by the deployment environment`)
+)
+
+var (
+	ErrOwnerUnknown = errs.Unauthorized(`token_owner_unknown`, `the token owner of the request is not known`)
+	ErrSelfApproval = errs.Forbidden(`change_request_self_approval`, `change request can't be approved by the token owner who proposed it`)
+)
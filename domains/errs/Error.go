@@ -0,0 +1,71 @@
+// Package errs holds the taxonomy of the domain errors.
+//
+// A domain error has a kind, which tells the interfaces how to present the failure,
+// and a stable code, which the clients can rely on, unlike on the message.
+package errs
+
+import "errors"
+
+type Kind string
+
+const (
+	KindValidation         Kind = `validation`
+	KindNotFound           Kind = `not_found`
+	KindConflict           Kind = `conflict`
+	KindPreconditionFailed Kind = `precondition_failed`
+	KindUnauthorized       Kind = `unauthorized`
+	KindForbidden          Kind = `forbidden`
+	KindUnavailable        Kind = `unavailable`
+)
+
+// Error is a domain error.
+// It is comparable, so it can be declared as a package level error value,
+// and checked with the equality operator or with errors.Is, the same way as a frameless.Error.
+type Error struct {
+	Kind Kind
+	// Code is the stable, machine-readable identifier of the error.
+	Code string
+	// Message describes the error for the developers, and it may change in the future.
+	Message string
+	// Field is the path of the invalid field within the entity or the request, in the case of a validation error.
+	Field string
+}
+
+func (err Error) Error() string {
+	return err.Message
+}
+
+func Validation(code, field, message string) Error {
+	return Error{Kind: KindValidation, Code: code, Field: field, Message: message}
+}
+
+func NotFound(code, message string) Error {
+	return Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+func Conflict(code, message string) Error {
+	return Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+func PreconditionFailed(code, message string) Error {
+	return Error{Kind: KindPreconditionFailed, Code: code, Message: message}
+}
+
+func Unauthorized(code, message string) Error {
+	return Error{Kind: KindUnauthorized, Code: code, Message: message}
+}
+
+func Forbidden(code, message string) Error {
+	return Error{Kind: KindForbidden, Code: code, Message: message}
+}
+
+func Unavailable(code, message string) Error {
+	return Error{Kind: KindUnavailable, Code: code, Message: message}
+}
+
+// Lookup returns the domain error from the chain of the error.
+func Lookup(err error) (Error, bool) {
+	var domainErr Error
+	ok := errors.As(err, &domainErr)
+	return domainErr, ok
+}
@@ -0,0 +1,126 @@
+package guardrail
+
+import (
+	"fmt"
+	"time"
+)
+
+// The actions a guardrail takes with its rollout when it trips.
+const (
+	// ActionZero turns the rollout down to 0%, so no pilot is enrolled by it.
+	ActionZero = `zero`
+	// ActionPreviousVersion reverts the rollout to the version before its latest change.
+	ActionPreviousVersion = `previous_version`
+)
+
+// The states of a guardrail.
+const (
+	// StatusArmed tells that the guardrail watches the health signals of its rollout.
+	StatusArmed = `armed`
+	// StatusTripped tells that the guardrail rolled back its rollout, and it no longer watches it until it is re-armed.
+	StatusTripped = `tripped`
+)
+
+// Guardrail is the automatic kill switch of a rollout.
+// It compares the error rate of the pilots enrolled by the rollout with the error rate of the control group,
+// which is made of the pilots who are not enrolled,
+// and rolls back the rollout when the enrolled pilots see too many errors.
+type Guardrail struct {
+	ID string `ext:"ID" json:"id"`
+	// RolloutID is the guarded rollout.
+	RolloutID string `json:"rollout_id"`
+	// MaxErrorRateIncrease is how many percentage points the error rate of the enrolled pilots
+	// may be above the error rate of the control group, e.g. 2 allows 3% errors when the control has 1%.
+	MaxErrorRateIncrease float64 `json:"max_error_rate_increase"`
+	// WindowSeconds is the length of the window of the recent health signals, which are compared.
+	WindowSeconds int `json:"window_seconds"`
+	// MinRequests is the number of requests both the enrolled pilots and the control group need in the window,
+	// before their error rates are compared.
+	MinRequests int `json:"min_requests"`
+	// Action is the rollback of the rollout, when the guardrail trips.
+	Action string `json:"action"`
+	Status string `json:"status"`
+	// ArmedAt is the time since the health signals are compared,
+	// so the signals from before a re-arm can't trip the guardrail again.
+	ArmedAt time.Time `json:"armed_at"`
+	// TrippedAt is the time of the rollback.
+	TrippedAt time.Time `json:"tripped_at"`
+	// Reason tells why the guardrail tripped, with the compared error rates.
+	Reason string `json:"reason,omitempty"`
+}
+
+func (g Guardrail) Validate() error {
+	if g.RolloutID == `` {
+		return ErrMissingRollout
+	}
+	if g.MaxErrorRateIncrease < 0 || 100 < g.MaxErrorRateIncrease {
+		return ErrInvalidMaxErrorRateIncrease
+	}
+	if g.WindowSeconds <= 0 {
+		return ErrInvalidWindow
+	}
+	if g.MinRequests < 1 {
+		return ErrInvalidMinRequests
+	}
+	switch g.Action {
+	case ActionZero, ActionPreviousVersion:
+	default:
+		return ErrInvalidAction
+	}
+	return nil
+}
+
+// Window is the length of the compared health signal window.
+func (g Guardrail) Window() time.Duration {
+	return time.Duration(g.WindowSeconds) * time.Second
+}
+
+// IsArmed tells if the guardrail watches its rollout.
+func (g Guardrail) IsArmed() bool {
+	return g.Status == StatusArmed
+}
+
+// Check compares the health of the enrolled pilots with the health of the control group.
+// It trips when both of them have enough requests,
+// and the error rate of the enrolled pilots is more than MaxErrorRateIncrease percentage points above the control's.
+// The reason tells the compared error rates.
+func (g Guardrail) Check(enrolled, control Health) (reason string, tripped bool) {
+	if enrolled.Requests() < g.MinRequests || control.Requests() < g.MinRequests {
+		return ``, false
+	}
+
+	increase := (enrolled.ErrorRate() - control.ErrorRate()) * 100
+	if increase <= g.MaxErrorRateIncrease {
+		return ``, false
+	}
+
+	return fmt.Sprintf(`error rate of the enrolled pilots was %.2f%% (%d of %d requests), `+
+		`%.2f percentage points above the %.2f%% (%d of %d requests) of the control group in the last %s, `+
+		`while at most %.2f was allowed`,
+		enrolled.ErrorRate()*100, enrolled.Errors, enrolled.Requests(),
+		increase, control.ErrorRate()*100, control.Errors, control.Requests(), g.Window(),
+		g.MaxErrorRateIncrease), true
+}
+
+// Health is the sum of the health signals of a variant.
+type Health struct {
+	Successes int `json:"successes"`
+	Errors    int `json:"errors"`
+}
+
+func (h Health) Requests() int {
+	return h.Successes + h.Errors
+}
+
+// ErrorRate is the ratio of the failed requests, between 0 and 1.
+func (h Health) ErrorRate() float64 {
+	if h.Requests() == 0 {
+		return 0
+	}
+	return float64(h.Errors) / float64(h.Requests())
+}
+
+func (h *Health) add(s Signal) {
+	h.Successes += s.Successes
+	h.Errors += s.Errors
+}
@@ -0,0 +1,76 @@
+package guardrail_test
+
+import (
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/guardrail"
+)
+
+func TestGuardrail(t *testing.T) {
+	s := testcase.NewSpec(t)
+	defer s.Finish()
+
+	g := s.Let(`guardrail`, func(t *testcase.T) interface{} {
+		return guardrail.Guardrail{
+			RolloutID:            `rollout-id`,
+			MaxErrorRateIncrease: 2,
+			WindowSeconds:        600,
+			MinRequests:          100,
+			Action:               guardrail.ActionZero,
+		}
+	})
+	gGet := func(t *testcase.T) guardrail.Guardrail { return g.Get(t).(guardrail.Guardrail) }
+
+	s.Describe(`.Validate`, func(s *testcase.Spec) {
+		s.Then(`a guardrail with a rollout, thresholds and a known action is valid`, func(t *testcase.T) {
+			require.Nil(t, gGet(t).Validate())
+		})
+
+		s.Then(`the thresholds have to be in range`, func(t *testcase.T) {
+			invalid := gGet(t)
+			invalid.MaxErrorRateIncrease = 101
+			require.Equal(t, guardrail.ErrInvalidMaxErrorRateIncrease, invalid.Validate())
+
+			invalid = gGet(t)
+			invalid.WindowSeconds = 0
+			require.Equal(t, guardrail.ErrInvalidWindow, invalid.Validate())
+
+			invalid = gGet(t)
+			invalid.MinRequests = 0
+			require.Equal(t, guardrail.ErrInvalidMinRequests, invalid.Validate())
+		})
+
+		s.Then(`the action has to be known`, func(t *testcase.T) {
+			invalid := gGet(t)
+			invalid.Action = `delete`
+			require.Equal(t, guardrail.ErrInvalidAction, invalid.Validate())
+		})
+	})
+
+	s.Describe(`.Check`, func(s *testcase.Spec) {
+		control := guardrail.Health{Successes: 990, Errors: 10}
+
+		s.Then(`it trips when the enrolled error rate is above the control's by more than the allowed increase`, func(t *testcase.T) {
+			reason, tripped := gGet(t).Check(guardrail.Health{Successes: 160, Errors: 40}, control)
+			require.True(t, tripped)
+			require.Contains(t, reason, `20.00% (40 of 200 requests)`)
+			require.Contains(t, reason, `19.00 percentage points above the 1.00% (10 of 1000 requests)`)
+		})
+
+		s.Then(`it doesn't trip within the allowed increase`, func(t *testcase.T) {
+			_, tripped := gGet(t).Check(guardrail.Health{Successes: 194, Errors: 6}, control)
+			require.False(t, tripped)
+		})
+
+		s.Then(`it doesn't trip until both variants have enough requests`, func(t *testcase.T) {
+			_, tripped := gGet(t).Check(guardrail.Health{Successes: 9, Errors: 90}, control)
+			require.False(t, tripped)
+
+			_, tripped = gGet(t).Check(guardrail.Health{Successes: 160, Errors: 40}, guardrail.Health{Successes: 99})
+			require.False(t, tripped)
+		})
+	})
+}
@@ -0,0 +1,388 @@
+package guardrail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/iterators"
+
+	"github.com/toggler-io/toggler/domains/change"
+	"github.com/toggler-io/toggler/domains/release"
+)
+
+// LockName is the name of the storage lock, which is held while the guardrails are evaluated.
+const LockName = `guardrails`
+
+// NewMonitor returns a Monitor which is not yet started.
+func NewMonitor(s Storage) *Monitor {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Monitor{
+		Storage:   s,
+		Interval:  30 * time.Second,
+		Retention: 24 * time.Hour,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Monitor evaluates the guardrails of the rollouts against the health signals reported by the services,
+// and rolls back the rollouts whose guardrail trips.
+//
+// The guardrails are evaluated periodically in the background.
+// When more toggler instances share the storage, only one of them evaluates the guardrails at a time,
+// as they are evaluated while the instance holds the LockName lock of the storage.
+// The rollback is made even in a protected environment, without a change request,
+// as it is the kill switch of a rollout which hurts the pilots.
+type Monitor struct {
+	Storage Storage
+	// Interval is the wait between two evaluations of the guardrails.
+	Interval time.Duration
+	// Retention is how long the health signals are kept.
+	// It should be longer than the window of the guardrails.
+	Retention time.Duration
+	// ErrorHandler is called with the errors that happen in the background,
+	// such as a failed rollback.
+	ErrorHandler func(ctx context.Context, err error)
+
+	mutex   sync.Mutex
+	started bool
+	ctx     context.Context
+	cancel  func()
+	wg      sync.WaitGroup
+}
+
+// Start evaluates the guardrails periodically in the background, until the Monitor is closed.
+func (m *Monitor) Start(ctx context.Context) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.started {
+		return nil
+	}
+	m.started = true
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		m.loop(m.ctx)
+	}()
+	return nil
+}
+
+// Close stops the background evaluations, and waits for the rollbacks in progress.
+func (m *Monitor) Close() error {
+	m.cancel()
+	m.wg.Wait()
+	return nil
+}
+
+func (m *Monitor) loop(ctx context.Context) {
+	ticker := time.NewTicker(m.Interval)
+	defer ticker.Stop()
+	for {
+		if err := m.Evaluate(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			m.handleError(ctx, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Report records the health signals of a service.
+// The signals are recorded together, so none of them is recorded when one of them is invalid.
+func (m *Monitor) Report(ctx context.Context, signals ...*Signal) (rErr error) {
+	for _, s := range signals {
+		if err := s.Validate(); err != nil {
+			return err
+		}
+	}
+
+	ctx, err := m.Storage.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer frameless.FinishOnePhaseCommit(&rErr, m.Storage, ctx)
+
+	now := time.Now().UTC()
+	for _, s := range signals {
+		s.ID = ``
+		s.ReportedAt = now
+		if err := m.Storage.GuardrailSignal(ctx).Create(ctx, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns the guardrails of a rollout, or every guardrail when the rollout id is empty.
+func (m *Monitor) List(ctx context.Context, rolloutID string) ([]Guardrail, error) {
+	guardrails := make([]Guardrail, 0)
+	err := iterators.Collect(iterators.Filter(m.Storage.Guardrail(ctx).FindAll(ctx), func(g Guardrail) bool {
+		return rolloutID == `` || g.RolloutID == rolloutID
+	}), &guardrails)
+	return guardrails, err
+}
+
+// Find looks up a guardrail along with the reason of its last trip.
+func (m *Monitor) Find(ctx context.Context, id string) (Guardrail, error) {
+	var g Guardrail
+	found, err := m.Storage.Guardrail(ctx).FindByID(ctx, &g, id)
+	if err != nil {
+		return Guardrail{}, err
+	}
+	if !found {
+		return Guardrail{}, ErrGuardrailNotFound
+	}
+	return g, nil
+}
+
+// Create arms a guardrail for a rollout. A rollout can have only one guardrail.
+func (m *Monitor) Create(ctx context.Context, g *Guardrail) (rErr error) {
+	if err := g.Validate(); err != nil {
+		return err
+	}
+
+	ctx, err := m.Storage.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer frameless.FinishOnePhaseCommit(&rErr, m.Storage, ctx)
+
+	var rollout release.Rollout
+	found, err := m.Storage.ReleaseRollout(ctx).FindByID(ctx, &rollout, g.RolloutID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return release.ErrRolloutNotFound
+	}
+
+	existing, err := m.List(ctx, g.RolloutID)
+	if err != nil {
+		return err
+	}
+	if 0 < len(existing) {
+		return ErrGuardrailAlreadyExist
+	}
+
+	g.ID = ``
+	g.Status = StatusArmed
+	g.ArmedAt = time.Now().UTC()
+	g.TrippedAt = time.Time{}
+	g.Reason = ``
+	return m.Storage.Guardrail(ctx).Create(ctx, g)
+}
+
+// Rearm makes a tripped guardrail watch its rollout again.
+// Only the health signals reported after the re-arm are compared.
+func (m *Monitor) Rearm(ctx context.Context, id string) (rErr error) {
+	ctx, err := m.Storage.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer frameless.FinishOnePhaseCommit(&rErr, m.Storage, ctx)
+
+	g, err := m.Find(ctx, id)
+	if err != nil {
+		return err
+	}
+	if g.IsArmed() {
+		return ErrGuardrailNotTripped
+	}
+
+	g.Status = StatusArmed
+	g.ArmedAt = time.Now().UTC()
+	g.TrippedAt = time.Time{}
+	g.Reason = ``
+	return m.Storage.Guardrail(ctx).Update(ctx, &g)
+}
+
+// Delete removes the guardrail, so its rollout is no longer watched.
+func (m *Monitor) Delete(ctx context.Context, id string) error {
+	if _, err := m.Find(ctx, id); err != nil {
+		return err
+	}
+	return m.Storage.Guardrail(ctx).DeleteByID(ctx, id)
+}
+
+// Health sums the health signals of the guarded rollout in the window of the guardrail, by variant.
+func (m *Monitor) Health(ctx context.Context, g Guardrail, now time.Time) (enrolled, control Health, err error) {
+	var rollout release.Rollout
+	found, err := m.Storage.ReleaseRollout(ctx).FindByID(ctx, &rollout, g.RolloutID)
+	if err != nil {
+		return Health{}, Health{}, err
+	}
+	if !found {
+		return Health{}, Health{}, release.ErrRolloutNotFound
+	}
+	return m.health(ctx, g, rollout, now)
+}
+
+func (m *Monitor) health(ctx context.Context, g Guardrail, rollout release.Rollout, now time.Time) (enrolled, control Health, err error) {
+	since := now.Add(-g.Window())
+	if since.Before(g.ArmedAt) {
+		since = g.ArmedAt
+	}
+
+	q := SignalQuery{FlagID: rollout.FlagID, EnvironmentID: rollout.EnvironmentID, Since: since}
+	err = iterators.ForEach(m.Storage.GuardrailSignal(ctx).FindByQuery(ctx, q), func(s Signal) error {
+		switch s.Variant {
+		case VariantEnrolled:
+			enrolled.add(s)
+		case VariantControl:
+			control.add(s)
+		}
+		return nil
+	})
+	return enrolled, control, err
+}
+
+// Evaluate checks the armed guardrails against the health signals reported by the given time,
+// and rolls back the rollouts whose guardrail trips.
+// It does nothing when an other toggler instance is evaluating the guardrails.
+// A guardrail that can't be evaluated doesn't stop the evaluation of the rest,
+// and its error is returned once every guardrail was evaluated.
+func (m *Monitor) Evaluate(ctx context.Context, now time.Time) (rErr error) {
+	unlock, ok, err := m.Storage.TryLock(ctx, LockName)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	defer func() {
+		if err := unlock(); rErr == nil {
+			rErr = err
+		}
+	}()
+
+	if err := m.prune(ctx, now.Add(-m.Retention)); err != nil {
+		return err
+	}
+
+	var guardrails []Guardrail
+	if err := iterators.Collect(iterators.Filter(m.Storage.Guardrail(ctx).FindAll(ctx), Guardrail.IsArmed), &guardrails); err != nil {
+		return err
+	}
+
+	var failures []error
+	for _, g := range guardrails {
+		err := m.evaluate(ctx, g.ID, now)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			failures = append(failures, fmt.Errorf(`guardrail %s: %w`, g.ID, err))
+		}
+	}
+	return errors.Join(failures...)
+}
+
+// evaluate checks a guardrail, and makes the rollback and records its reason in the same transaction.
+func (m *Monitor) evaluate(ctx context.Context, id string, now time.Time) (rErr error) {
+	ctx, err := m.Storage.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer frameless.FinishOnePhaseCommit(&rErr, m.Storage, ctx)
+
+	g, err := m.Find(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !g.IsArmed() {
+		return nil
+	}
+
+	var rollout release.Rollout
+	found, err := m.Storage.ReleaseRollout(ctx).FindByID(ctx, &rollout, g.RolloutID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		// there is nothing to roll back, so the guardrail waits for the rollout to be reverted.
+		return nil
+	}
+
+	enrolled, control, err := m.health(ctx, g, rollout, now)
+	if err != nil {
+		return err
+	}
+	reason, tripped := g.Check(enrolled, control)
+	if !tripped {
+		return nil
+	}
+
+	rollback, err := m.rollback(change.ContextWithRollback(ctx), g, rollout)
+	if err != nil {
+		return err
+	}
+
+	g.Status = StatusTripped
+	g.TrippedAt = now.UTC()
+	g.Reason = reason + `, so ` + rollback
+	return m.Storage.Guardrail(ctx).Update(ctx, &g)
+}
+
+// rollback makes the action of the guardrail with its rollout, and describes what was done.
+func (m *Monitor) rollback(ctx context.Context, g Guardrail, rollout release.Rollout) (string, error) {
+	if g.Action == ActionPreviousVersion {
+		manager := release.NewRolloutManager(m.Storage)
+		versions, err := manager.ListVersions(ctx, release.VersionKindRollout, rollout.ID)
+		if err != nil {
+			return ``, err
+		}
+		// the last version is the current state of the rollout.
+		if 2 <= len(versions) && !versions[len(versions)-2].IsDeleted() {
+			previous := versions[len(versions)-2]
+			if err := manager.RevertToVersion(ctx, previous.ID); err != nil {
+				return ``, err
+			}
+			return fmt.Sprintf(`the rollout was reverted to its version %d`, previous.Number), nil
+		}
+	}
+
+	switch plan := rollout.Plan.(type) {
+	case release.RolloutDecisionByPercentage:
+		plan.Percentage = 0
+		rollout.Plan = plan
+	default:
+		rollout.Plan = release.RolloutDecisionByGlobal{State: false}
+	}
+	if err := m.Storage.ReleaseRollout(ctx).Update(ctx, &rollout); err != nil {
+		return ``, err
+	}
+	if g.Action == ActionPreviousVersion {
+		return `the rollout had no previous version, so it was turned down to 0%`, nil
+	}
+	return `the rollout was turned down to 0%`, nil
+}
+
+// prune deletes the health signals which were reported before the retention.
+func (m *Monitor) prune(ctx context.Context, before time.Time) error {
+	var ids []string
+	if err := iterators.ForEach(m.Storage.GuardrailSignal(ctx).FindByQuery(ctx, SignalQuery{Before: before}), func(s Signal) error {
+		ids = append(ids, s.ID)
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := m.Storage.GuardrailSignal(ctx).DeleteByID(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Monitor) handleError(ctx context.Context, err error) {
+	if m.ErrorHandler != nil {
+		m.ErrorHandler(ctx, err)
+	}
+}
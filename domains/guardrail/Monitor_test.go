@@ -0,0 +1,219 @@
+package guardrail_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/adamluzsi/frameless/iterators"
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/change"
+	"github.com/toggler-io/toggler/domains/guardrail"
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/external/resource/storages"
+)
+
+func TestMonitor(t *testing.T) {
+	s := testcase.NewSpec(t)
+	defer s.Finish()
+
+	var (
+		ctx        = context.Background()
+		useCases   = s.Let(`use cases`, func(t *testcase.T) interface{} { return toggler.NewUseCases(storages.NewInMemory()) })
+		storageGet = func(t *testcase.T) toggler.Storage { return useCases.Get(t).(*toggler.UseCases).Storage }
+		monitorGet = func(t *testcase.T) *guardrail.Monitor { return useCases.Get(t).(*toggler.UseCases).Guardrails }
+		approvals  = s.LetValue(`required approvals`, 0)
+		rollout    = s.Let(`rollout`, func(t *testcase.T) interface{} {
+			env := release.Environment{Name: `production`}
+			require.Nil(t, storageGet(t).ReleaseEnvironment(ctx).Create(ctx, &env))
+			flag := release.Flag{Name: `checkout-v2`}
+			require.Nil(t, storageGet(t).ReleaseFlag(ctx).Create(ctx, &flag))
+			rollout := &release.Rollout{
+				FlagID:        flag.ID,
+				EnvironmentID: env.ID,
+				Plan:          release.RolloutDecisionByPercentage{PseudoRandPercentageAlgorithm: `FNV1a64`, Percentage: 10},
+			}
+			require.Nil(t, storageGet(t).ReleaseRollout(ctx).Create(ctx, rollout))
+			rollout.Plan = release.RolloutDecisionByPercentage{PseudoRandPercentageAlgorithm: `FNV1a64`, Percentage: 50}
+			require.Nil(t, storageGet(t).ReleaseRollout(ctx).Update(ctx, rollout))
+			// the protection is turned on after the rollout is made
+			env.RequiredApprovals = approvals.Get(t).(int)
+			require.Nil(t, storageGet(t).ReleaseEnvironment(ctx).Update(ctx, &env))
+			return rollout
+		})
+		rolloutGet = func(t *testcase.T) *release.Rollout { return rollout.Get(t).(*release.Rollout) }
+		action     = s.LetValue(`action`, guardrail.ActionZero)
+		gr         = s.Let(`guardrail`, func(t *testcase.T) interface{} {
+			g := &guardrail.Guardrail{
+				RolloutID:            rolloutGet(t).ID,
+				MaxErrorRateIncrease: 2,
+				WindowSeconds:        600,
+				MinRequests:          100,
+				Action:               action.Get(t).(string),
+			}
+			require.Nil(t, monitorGet(t).Create(ctx, g))
+			return g
+		})
+		grGet  = func(t *testcase.T) *guardrail.Guardrail { return gr.Get(t).(*guardrail.Guardrail) }
+		report = func(t *testcase.T, variant string, successes, errors int) {
+			require.Nil(t, monitorGet(t).Report(ctx, &guardrail.Signal{
+				FlagID:        rolloutGet(t).FlagID,
+				EnvironmentID: rolloutGet(t).EnvironmentID,
+				Variant:       variant,
+				Successes:     successes,
+				Errors:        errors,
+			}))
+		}
+		stored = func(t *testcase.T) release.Rollout {
+			var r release.Rollout
+			found, err := storageGet(t).ReleaseRollout(ctx).FindByID(ctx, &r, rolloutGet(t).ID)
+			require.Nil(t, err)
+			require.True(t, found)
+			return r
+		}
+		find = func(t *testcase.T) guardrail.Guardrail {
+			g, err := monitorGet(t).Find(ctx, grGet(t).ID)
+			require.Nil(t, err)
+			return g
+		}
+		percentage = func(t *testcase.T) int {
+			return stored(t).Plan.(release.RolloutDecisionByPercentage).Percentage
+		}
+	)
+
+	s.Describe(`.Create`, func(s *testcase.Spec) {
+		s.Then(`the guardrail is armed`, func(t *testcase.T) {
+			g := find(t)
+			require.Equal(t, guardrail.StatusArmed, g.Status)
+			require.False(t, g.ArmedAt.IsZero())
+		})
+
+		s.Then(`the rollout must exist`, func(t *testcase.T) {
+			g := guardrail.Guardrail{RolloutID: `unknown`, MaxErrorRateIncrease: 2, WindowSeconds: 600, MinRequests: 100, Action: guardrail.ActionZero}
+			require.Equal(t, release.ErrRolloutNotFound, monitorGet(t).Create(ctx, &g))
+		})
+
+		s.Then(`a rollout can have only one guardrail`, func(t *testcase.T) {
+			g := *grGet(t)
+			require.Equal(t, guardrail.ErrGuardrailAlreadyExist, monitorGet(t).Create(ctx, &g))
+		})
+	})
+
+	s.Describe(`.Report`, func(s *testcase.Spec) {
+		s.Then(`the variant has to be known`, func(t *testcase.T) {
+			signal := guardrail.Signal{FlagID: rolloutGet(t).FlagID, EnvironmentID: rolloutGet(t).EnvironmentID, Variant: `on`, Successes: 1}
+			require.Equal(t, guardrail.ErrInvalidVariant, monitorGet(t).Report(ctx, &signal))
+		})
+	})
+
+	s.Describe(`.Evaluate`, func(s *testcase.Spec) {
+		now := s.Let(`now`, func(t *testcase.T) interface{} { return time.Now() })
+		subject := func(t *testcase.T) error {
+			return monitorGet(t).Evaluate(ctx, now.Get(t).(time.Time))
+		}
+		s.Before(func(t *testcase.T) { grGet(t) })
+
+		s.When(`the enrolled pilots see too many errors`, func(s *testcase.Spec) {
+			s.Before(func(t *testcase.T) {
+				report(t, guardrail.VariantControl, 990, 10)
+				report(t, guardrail.VariantEnrolled, 160, 40)
+			})
+
+			s.Then(`the rollout is turned down to 0%, and the reason is recorded`, func(t *testcase.T) {
+				require.Nil(t, subject(t))
+
+				require.Equal(t, 0, percentage(t))
+				g := find(t)
+				require.Equal(t, guardrail.StatusTripped, g.Status)
+				require.False(t, g.TrippedAt.IsZero())
+				require.Contains(t, g.Reason, `20.00% (40 of 200 requests)`)
+				require.Contains(t, g.Reason, `so the rollout was turned down to 0%`)
+			})
+
+			s.Then(`the re-armed guardrail doesn't trip on the signals from before the re-arm`, func(t *testcase.T) {
+				require.Nil(t, subject(t))
+				require.Nil(t, monitorGet(t).Rearm(ctx, grGet(t).ID))
+				require.Equal(t, guardrail.ErrGuardrailNotTripped, monitorGet(t).Rearm(ctx, grGet(t).ID))
+
+				rollout := stored(t)
+				rollout.Plan = release.RolloutDecisionByPercentage{PseudoRandPercentageAlgorithm: `FNV1a64`, Percentage: 50}
+				require.Nil(t, storageGet(t).ReleaseRollout(ctx).Update(ctx, &rollout))
+
+				require.Nil(t, monitorGet(t).Evaluate(ctx, time.Now()))
+				require.Equal(t, 50, percentage(t))
+				require.Equal(t, guardrail.StatusArmed, find(t).Status)
+			})
+
+			s.And(`the signals are older than the window`, func(s *testcase.Spec) {
+				now.Let(s, func(t *testcase.T) interface{} { return time.Now().Add(grGet(t).Window() + time.Minute) })
+
+				s.Then(`the guardrail stays armed`, func(t *testcase.T) {
+					require.Nil(t, subject(t))
+					require.Equal(t, 50, percentage(t))
+					require.Equal(t, guardrail.StatusArmed, find(t).Status)
+				})
+			})
+
+			s.And(`the rollback is to the previous version`, func(s *testcase.Spec) {
+				action.LetValue(s, guardrail.ActionPreviousVersion)
+
+				s.Then(`the rollout is reverted to the version before its latest change`, func(t *testcase.T) {
+					require.Nil(t, subject(t))
+
+					require.Equal(t, 10, percentage(t))
+					require.Contains(t, find(t).Reason, `so the rollout was reverted to its version 1`)
+				})
+			})
+
+			s.And(`the environment is protected`, func(s *testcase.Spec) {
+				approvals.LetValue(s, 1)
+
+				s.Then(`the rollback is made without a change request`, func(t *testcase.T) {
+					require.Nil(t, subject(t))
+
+					require.Equal(t, 0, percentage(t))
+					requests, err := useCases.Get(t).(*toggler.UseCases).Changes.List(ctx, change.RequestQuery{})
+					require.Nil(t, err)
+					require.Empty(t, requests)
+				})
+			})
+
+			s.And(`an other instance holds the lock`, func(s *testcase.Spec) {
+				s.Then(`nothing is evaluated`, func(t *testcase.T) {
+					unlock, ok, err := storageGet(t).TryLock(ctx, guardrail.LockName)
+					require.Nil(t, err)
+					require.True(t, ok)
+					defer unlock()
+
+					require.Nil(t, subject(t))
+					require.Equal(t, 50, percentage(t))
+				})
+			})
+		})
+
+		s.When(`the enrolled pilots are as healthy as the control group`, func(s *testcase.Spec) {
+			s.Before(func(t *testcase.T) {
+				report(t, guardrail.VariantControl, 990, 10)
+				report(t, guardrail.VariantEnrolled, 196, 4)
+			})
+
+			s.Then(`the rollout is kept`, func(t *testcase.T) {
+				require.Nil(t, subject(t))
+				require.Equal(t, 50, percentage(t))
+				require.Equal(t, guardrail.StatusArmed, find(t).Status)
+			})
+		})
+
+		s.Then(`the signals older than the retention are deleted`, func(t *testcase.T) {
+			report(t, guardrail.VariantControl, 1, 0)
+			require.Nil(t, monitorGet(t).Evaluate(ctx, time.Now().Add(monitorGet(t).Retention+time.Minute)))
+
+			var signals []guardrail.Signal
+			require.Nil(t, iterators.Collect(storageGet(t).GuardrailSignal(ctx).FindAll(ctx), &signals))
+			require.Empty(t, signals)
+		})
+	})
+}
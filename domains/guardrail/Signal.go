@@ -0,0 +1,74 @@
+package guardrail
+
+import "time"
+
+// The variants of a release flag, whose health is compared by the guardrails.
+const (
+	// VariantEnrolled is the variant of the pilots for whom the release flag is turned on.
+	VariantEnrolled = `enrolled`
+	// VariantControl is the variant of the pilots for whom the release flag is turned off.
+	VariantControl = `control`
+)
+
+// Signal is a health report of a service, which counts the outcome of its requests made with a variant of a release flag.
+type Signal struct {
+	ID            string `ext:"ID" json:"id"`
+	FlagID        string `json:"flag_id"`
+	EnvironmentID string `json:"env_id"`
+	Variant       string `json:"variant"`
+	// Successes is the number of requests which were served successfully since the previous report.
+	Successes int `json:"successes"`
+	// Errors is the number of requests which failed since the previous report.
+	Errors     int       `json:"errors"`
+	ReportedAt time.Time `json:"reported_at"`
+}
+
+func (s Signal) Validate() error {
+	if s.FlagID == `` || s.EnvironmentID == `` {
+		return ErrInvalidSignal
+	}
+	switch s.Variant {
+	case VariantEnrolled, VariantControl:
+	default:
+		return ErrInvalidVariant
+	}
+	if s.Successes < 0 || s.Errors < 0 {
+		return ErrInvalidSignal
+	}
+	return nil
+}
+
+// SignalQuery selects the health signals of a release flag in a deployment environment, which were reported in a period.
+// The empty fields don't filter the signals.
+type SignalQuery struct {
+	FlagID        string
+	EnvironmentID string
+	// Since selects the signals which were not reported before it.
+	Since time.Time
+	// Before selects the signals which were reported before it.
+	Before time.Time
+}
+
+func (q SignalQuery) Match(s Signal) bool {
+	if q.FlagID != `` && q.FlagID != s.FlagID {
+		return false
+	}
+	if q.EnvironmentID != `` && q.EnvironmentID != s.EnvironmentID {
+		return false
+	}
+	if !q.Since.IsZero() && s.ReportedAt.Before(q.Since) {
+		return false
+	}
+	if !q.Before.IsZero() && !s.ReportedAt.Before(q.Before) {
+		return false
+	}
+	return true
+}
+
+// Less tells the order of the signals, which is by their report time.
+func (q SignalQuery) Less(a, b Signal) bool {
+	if !a.ReportedAt.Equal(b.ReportedAt) {
+		return a.ReportedAt.Before(b.ReportedAt)
+	}
+	return a.ID < b.ID
+}
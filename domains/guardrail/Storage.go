@@ -0,0 +1,36 @@
+package guardrail
+
+import (
+	"context"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/iterators"
+
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/schedule"
+)
+
+// Storage holds the guardrails and the health signals, along with the guarded rollouts.
+type Storage interface {
+	release.Storage
+	schedule.Locker
+	Guardrail(context.Context) GuardrailStorage
+	GuardrailSignal(context.Context) SignalStorage
+}
+
+type SignalEntries = iterators.Interface
+
+type GuardrailStorage interface {
+	frameless.Creator
+	frameless.Finder
+	frameless.Updater
+	frameless.Deleter
+}
+
+type SignalStorage interface {
+	frameless.Creator
+	frameless.Finder
+	frameless.Deleter
+	// FindByQuery returns the health signals that match the query, in the order they were reported.
+	FindByQuery(ctx context.Context, q SignalQuery) SignalEntries
+}
@@ -0,0 +1,56 @@
+package contracts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/contracts"
+	"github.com/adamluzsi/testcase"
+
+	"github.com/toggler-io/toggler/domains/guardrail"
+)
+
+type GuardrailStorage struct {
+	Subject        func(testing.TB) guardrail.GuardrailStorage
+	Context        func(testing.TB) context.Context
+	FixtureFactory func(testing.TB) frameless.FixtureFactory
+}
+
+func (c GuardrailStorage) String() string {
+	return "GuardrailStorage"
+}
+
+func (c GuardrailStorage) Test(t *testing.T) {
+	c.Spec(testcase.NewSpec(t))
+}
+
+func (c GuardrailStorage) Benchmark(b *testing.B) {
+	c.Spec(testcase.NewSpec(b))
+}
+
+func (c GuardrailStorage) Spec(s *testcase.Spec) {
+	T := guardrail.Guardrail{}
+	testcase.RunContract(s,
+		contracts.Creator{T: T,
+			Subject:        func(tb testing.TB) contracts.CRD { return c.Subject(tb) },
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		contracts.Finder{T: T,
+			Subject:        func(tb testing.TB) contracts.CRD { return c.Subject(tb) },
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		contracts.Updater{T: T,
+			Subject:        func(tb testing.TB) contracts.UpdaterSubject { return c.Subject(tb) },
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		contracts.Deleter{T: T,
+			Subject:        func(tb testing.TB) contracts.CRD { return c.Subject(tb) },
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+	)
+}
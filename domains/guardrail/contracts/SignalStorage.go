@@ -0,0 +1,141 @@
+package contracts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/contracts"
+	"github.com/adamluzsi/frameless/iterators"
+	"github.com/adamluzsi/testcase"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/guardrail"
+)
+
+type SignalStorage struct {
+	Subject        func(testing.TB) guardrail.SignalStorage
+	Context        func(testing.TB) context.Context
+	FixtureFactory func(testing.TB) frameless.FixtureFactory
+}
+
+func (c SignalStorage) storage() testcase.Var {
+	return testcase.Var{
+		Name: "guardrail signal storage",
+		Init: func(t *testcase.T) interface{} {
+			return c.Subject(t)
+		},
+	}
+}
+
+func (c SignalStorage) storageGet(t *testcase.T) guardrail.SignalStorage {
+	return c.storage().Get(t).(guardrail.SignalStorage)
+}
+
+func (c SignalStorage) String() string {
+	return "SignalStorage"
+}
+
+func (c SignalStorage) Test(t *testing.T) {
+	c.Spec(testcase.NewSpec(t))
+}
+
+func (c SignalStorage) Benchmark(b *testing.B) {
+	c.Spec(testcase.NewSpec(b))
+}
+
+func (c SignalStorage) Spec(s *testcase.Spec) {
+	T := guardrail.Signal{}
+	testcase.RunContract(s,
+		contracts.Creator{T: T,
+			Subject:        func(tb testing.TB) contracts.CRD { return c.Subject(tb) },
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		contracts.Finder{T: T,
+			Subject:        func(tb testing.TB) contracts.CRD { return c.Subject(tb) },
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		contracts.Deleter{T: T,
+			Subject:        func(tb testing.TB) contracts.CRD { return c.Subject(tb) },
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+	)
+
+	s.Describe(`.FindByQuery`, func(s *testcase.Spec) {
+		var (
+			now       = time.Now().UTC().Truncate(time.Second)
+			flagID    = s.Let(`flag id`, func(t *testcase.T) interface{} { return uuid.New().String() })
+			envID     = s.Let(`env id`, func(t *testcase.T) interface{} { return uuid.New().String() })
+			newSignal = func(t *testcase.T, flagID, envID string, reportedAt time.Time) *guardrail.Signal {
+				signal := c.FixtureFactory(t).Fixture(guardrail.Signal{}, c.Context(t)).(guardrail.Signal)
+				signal.FlagID = flagID
+				signal.EnvironmentID = envID
+				signal.ReportedAt = reportedAt
+				return &signal
+			}
+			query = s.Let(`query`, func(t *testcase.T) interface{} {
+				return guardrail.SignalQuery{FlagID: flagID.Get(t).(string), EnvironmentID: envID.Get(t).(string)}
+			})
+			subject = func(t *testcase.T) []guardrail.Signal {
+				var signals []guardrail.Signal
+				iter := c.storageGet(t).FindByQuery(c.Context(t), query.Get(t).(guardrail.SignalQuery))
+				require.Nil(t, iterators.Collect(iter, &signals))
+				return signals
+			}
+		)
+
+		s.Before(func(t *testcase.T) {
+			contracts.DeleteAllEntity(t, c.storageGet(t), c.Context(t))
+			for _, signal := range []*guardrail.Signal{
+				newSignal(t, flagID.Get(t).(string), envID.Get(t).(string), now),
+				newSignal(t, flagID.Get(t).(string), envID.Get(t).(string), now.Add(-time.Hour)),
+				newSignal(t, flagID.Get(t).(string), uuid.New().String(), now),
+				newSignal(t, uuid.New().String(), envID.Get(t).(string), now),
+			} {
+				contracts.CreateEntity(t, c.storageGet(t), c.Context(t), signal)
+			}
+		})
+
+		s.Then(`it returns the signals of the flag in the environment, in the order they were reported`, func(t *testcase.T) {
+			signals := subject(t)
+			require.Len(t, signals, 2)
+			require.True(t, signals[0].ReportedAt.Equal(now.Add(-time.Hour)))
+			require.True(t, signals[1].ReportedAt.Equal(now))
+		})
+
+		s.When(`the signals of a period are selected`, func(s *testcase.Spec) {
+			query.Let(s, func(t *testcase.T) interface{} {
+				return guardrail.SignalQuery{FlagID: flagID.Get(t).(string), EnvironmentID: envID.Get(t).(string), Since: now.Add(-time.Minute)}
+			})
+
+			s.Then(`it returns the signals which were reported in it`, func(t *testcase.T) {
+				signals := subject(t)
+				require.Len(t, signals, 1)
+				require.True(t, signals[0].ReportedAt.Equal(now))
+			})
+		})
+
+		s.When(`the signals reported before a time are selected`, func(s *testcase.Spec) {
+			query.Let(s, func(t *testcase.T) interface{} { return guardrail.SignalQuery{Before: now} })
+
+			s.Then(`it returns the older signals`, func(t *testcase.T) {
+				signals := subject(t)
+				require.Len(t, signals, 1)
+				require.True(t, signals[0].ReportedAt.Equal(now.Add(-time.Hour)))
+			})
+		})
+
+		s.When(`the query is empty`, func(s *testcase.Spec) {
+			query.Let(s, func(t *testcase.T) interface{} { return guardrail.SignalQuery{} })
+
+			s.Then(`it returns every signal`, func(t *testcase.T) {
+				require.Len(t, subject(t), 4)
+			})
+		})
+	})
+}
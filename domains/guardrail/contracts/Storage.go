@@ -0,0 +1,65 @@
+package contracts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/contracts"
+	"github.com/adamluzsi/testcase"
+
+	"github.com/toggler-io/toggler/domains/guardrail"
+)
+
+type Storage struct {
+	Subject        func(testing.TB) guardrail.Storage
+	Context        func(testing.TB) context.Context
+	FixtureFactory func(testing.TB) frameless.FixtureFactory
+}
+
+func (c Storage) String() string {
+	return `guardrail#Storage`
+}
+
+func (c Storage) Test(t *testing.T) {
+	c.Spec(testcase.NewSpec(t))
+}
+
+func (c Storage) Benchmark(b *testing.B) {
+	c.Spec(testcase.NewSpec(b))
+}
+
+func (c Storage) Spec(s *testcase.Spec) {
+	testcase.RunContract(s,
+		GuardrailStorage{
+			Subject: func(tb testing.TB) guardrail.GuardrailStorage {
+				return c.Subject(tb).Guardrail(c.Context(tb))
+			},
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		SignalStorage{
+			Subject: func(tb testing.TB) guardrail.SignalStorage {
+				return c.Subject(tb).GuardrailSignal(c.Context(tb))
+			},
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		contracts.OnePhaseCommitProtocol{T: guardrail.Guardrail{},
+			Subject: func(tb testing.TB) (frameless.OnePhaseCommitProtocol, contracts.CRD) {
+				storage := c.Subject(tb)
+				return storage, storage.Guardrail(c.Context(tb))
+			},
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		contracts.OnePhaseCommitProtocol{T: guardrail.Signal{},
+			Subject: func(tb testing.TB) (frameless.OnePhaseCommitProtocol, contracts.CRD) {
+				storage := c.Subject(tb)
+				return storage, storage.GuardrailSignal(c.Context(tb))
+			},
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+	)
+}
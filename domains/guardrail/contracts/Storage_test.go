@@ -0,0 +1,12 @@
+package contracts_test
+
+import (
+	c "github.com/adamluzsi/frameless/contracts"
+	"github.com/toggler-io/toggler/domains/guardrail/contracts"
+)
+
+var _ = []c.Interface{
+	contracts.Storage{},
+	contracts.GuardrailStorage{},
+	contracts.SignalStorage{},
+}
@@ -0,0 +1,22 @@
+package guardrail
+
+import "github.com/toggler-io/toggler/domains/errs"
+
+var (
+	ErrMissingRollout              = errs.Validation(`guardrail_rollout_is_missing`, `rollout_id`, `guardrail requires a rollout`)
+	ErrInvalidMaxErrorRateIncrease = errs.Validation(`guardrail_max_error_rate_increase_is_invalid`, `max_error_rate_increase`, `guardrail max error rate increase must be between 0 and 100`)
+	ErrInvalidWindow               = errs.Validation(`guardrail_window_is_invalid`, `window_seconds`, `guardrail window must be positive`)
+	ErrInvalidMinRequests          = errs.Validation(`guardrail_min_requests_is_invalid`, `min_requests`, `guardrail min requests must be at least 1`)
+	ErrInvalidAction               = errs.Validation(`guardrail_action_is_invalid`, `action`, `guardrail action is not known`)
+	ErrInvalidVariant              = errs.Validation(`health_signal_variant_is_invalid`, `variant`, `health signal variant must be enrolled or control`)
+	ErrInvalidSignal               = errs.Validation(`health_signal_is_invalid`, `signal`, `health signal requires a flag, an environment and non-negative counters`)
+)
+
+var (
+	ErrGuardrailNotFound = errs.NotFound(`guardrail_not_found`, `guardrail not found`)
+)
+
+var (
+	ErrGuardrailAlreadyExist = errs.Conflict(`guardrail_already_exist`, `rollout already has a guardrail`)
+	ErrGuardrailNotTripped   = errs.Conflict(`guardrail_not_tripped`, `guardrail is already armed`)
+)
@@ -3,11 +3,25 @@ package release
 type Environment struct {
 	ID   string `ext:"ID" json:"id"`
 	Name string `json:"name"`
+	// RequiredApprovals is the number of approvals a change request needs in the environment.
+	// The environment is protected when it requires approvals,
+	// so its rollouts and pilots can only be changed through approved change requests.
+	RequiredApprovals int `json:"required_approvals,omitempty"`
+	// Revision is incremented by the storage with each update of the environment.
+	Revision int `json:"revision"`
 }
 
 func (env Environment) Validate() error {
 	if env.Name == "" {
 		return ErrEnvironmentNameIsEmpty
 	}
+	if env.RequiredApprovals < 0 {
+		return ErrInvalidRequiredApprovals
+	}
 	return nil
 }
+
+// IsProtected tells if the changes in the environment need approved change requests.
+func (env Environment) IsProtected() bool {
+	return 0 < env.RequiredApprovals
+}
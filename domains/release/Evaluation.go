@@ -0,0 +1,189 @@
+package release
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/adamluzsi/frameless/iterators"
+)
+
+// EvaluationReason explains why a flag evaluated to a given state for a pilot.
+// The values follow the OpenFeature reason vocabulary.
+type EvaluationReason string
+
+const (
+	// EvaluationReasonDefault means the flag has no rollout in the environment, so it is turned off.
+	EvaluationReasonDefault EvaluationReason = `DEFAULT`
+	// EvaluationReasonStatic means the state is the same for every pilot in the environment.
+	EvaluationReasonStatic EvaluationReason = `STATIC`
+	// EvaluationReasonSplit means the pilot fell into a percentage based bucket.
+	EvaluationReasonSplit EvaluationReason = `SPLIT`
+	// EvaluationReasonTargetingMatch means the state was decided for the pilot specifically,
+	// either by manual enrollment or by a pilot dependent rollout plan.
+	EvaluationReasonTargetingMatch EvaluationReason = `TARGETING_MATCH`
+)
+
+// Evaluation is the outcome of a release flag state check for a given pilot.
+type Evaluation struct {
+	FlagID   string
+	FlagName string
+	State    bool
+	Reason   EvaluationReason
+}
+
+// EvaluateFlags checks the release flag states of a pilot in the given environment and explains each result.
+// When no flag name is given, every release flag is evaluated.
+// Unlike GetAllReleaseFlagStatesOfThePilot, flags that don't exist are not part of the result,
+// so the caller can tell them apart from disabled ones.
+// The evaluations are ordered by flag name.
+func (manager *RolloutManager) EvaluateFlags(ctx context.Context, pilotExternalID string, env Environment, flagNames ...string) (_ []Evaluation, rErr error) {
+	ctx, span := startEvaluationSpan(ctx, `EvaluateFlags`, env, flagNames)
+	defer func() { endSpan(span, rErr) }()
+
+	var flagsIter FlagEntries
+	if len(flagNames) == 0 {
+		flagsIter = manager.Storage.ReleaseFlag(ctx).FindAll(ctx)
+	} else {
+		flagsIter = manager.Storage.ReleaseFlag(ctx).FindByNames(ctx, flagNames...)
+	}
+
+	var flags []Flag
+	if err := iterators.Collect(flagsIter, &flags); err != nil {
+		return nil, err
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+
+	pilotsByFlagID := make(map[string]Pilot)
+	pilots := iterators.Filter(manager.Storage.ReleasePilot(ctx).FindByPublicID(ctx, pilotExternalID), func(p Pilot) bool {
+		return p.EnvironmentID == env.ID
+	})
+	if err := iterators.ForEach(pilots, func(p Pilot) error {
+		pilotsByFlagID[p.FlagID] = p
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	evaluations, err := manager.evaluate(ctx, pilotExternalID, flags, pilotsByFlagID, func(flag Flag, rollout *Rollout) (bool, error) {
+		return manager.Storage.ReleaseRollout(ctx).FindByFlagEnvironment(ctx, flag, env, rollout)
+	})
+	if err != nil {
+		manager.observeEvaluation(env, false, err)
+		return nil, err
+	}
+	for _, evaluation := range evaluations {
+		manager.observeEvaluation(env, evaluation.State, nil)
+	}
+	return evaluations, nil
+}
+
+// EvaluateFlagsAsOf evaluates the release flags the same way as EvaluateFlags,
+// but with the flags, rollouts and pilots as they were at the given time.
+// The past state is reconstructed from the versions of the entities,
+// so changes made before the versioning was introduced are not visible.
+// It is meant for post-incident analysis, to tell what a pilot saw at the time of an incident.
+func (manager *RolloutManager) EvaluateFlagsAsOf(ctx context.Context, asOf time.Time, pilotExternalID string, env Environment, flagNames ...string) (_ []Evaluation, rErr error) {
+	ctx, span := startEvaluationSpan(ctx, `EvaluateFlagsAsOf`, env, flagNames)
+	defer func() { endSpan(span, rErr) }()
+
+	names := make(map[string]struct{})
+	for _, name := range flagNames {
+		names[name] = struct{}{}
+	}
+
+	var flags []Flag
+	if err := manager.entitiesAsOf(ctx, VersionKindFlag, asOf, func(v Version) error {
+		var flag Flag
+		if err := v.Decode(&flag); err != nil {
+			return err
+		}
+		if _, ok := names[flag.Name]; ok || len(flagNames) == 0 {
+			flags = append(flags, flag)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+
+	pilotsByFlagID := make(map[string]Pilot)
+	if err := manager.entitiesAsOf(ctx, VersionKindPilot, asOf, func(v Version) error {
+		var pilot Pilot
+		if err := v.Decode(&pilot); err != nil {
+			return err
+		}
+		if pilot.PublicID == pilotExternalID && pilot.EnvironmentID == env.ID {
+			pilotsByFlagID[pilot.FlagID] = pilot
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	rolloutsByFlagID := make(map[string]Rollout)
+	if err := manager.entitiesAsOf(ctx, VersionKindRollout, asOf, func(v Version) error {
+		var rollout Rollout
+		if err := v.Decode(&rollout); err != nil {
+			return err
+		}
+		if rollout.EnvironmentID == env.ID {
+			rolloutsByFlagID[rollout.FlagID] = rollout
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return manager.evaluate(ctx, pilotExternalID, flags, pilotsByFlagID, func(flag Flag, rollout *Rollout) (bool, error) {
+		r, ok := rolloutsByFlagID[flag.ID]
+		*rollout = r
+		return ok, nil
+	})
+}
+
+func (manager *RolloutManager) evaluate(ctx context.Context, pilotExternalID string, flags []Flag, pilotsByFlagID map[string]Pilot,
+	findRollout func(Flag, *Rollout) (bool, error)) ([]Evaluation, error) {
+	evaluations := make([]Evaluation, 0, len(flags))
+	for _, flag := range flags {
+		evaluation := Evaluation{FlagID: flag.ID, FlagName: flag.Name}
+
+		if p, ok := pilotsByFlagID[flag.ID]; ok {
+			evaluation.State = p.IsParticipating
+			evaluation.Reason = EvaluationReasonTargetingMatch
+			evaluations = append(evaluations, evaluation)
+			continue
+		}
+
+		var rollout Rollout
+		found, err := findRollout(flag, &rollout)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			evaluation.Reason = EvaluationReasonDefault
+			evaluations = append(evaluations, evaluation)
+			continue
+		}
+
+		evaluation.State, err = manager.isParticipating(ctx, rollout.Plan, pilotExternalID)
+		if err != nil {
+			return nil, err
+		}
+		evaluation.Reason = evaluationReasonOf(rollout.Plan)
+		evaluations = append(evaluations, evaluation)
+	}
+
+	return evaluations, nil
+}
+
+func evaluationReasonOf(plan RolloutPlan) EvaluationReason {
+	switch plan.(type) {
+	case RolloutDecisionByGlobal:
+		return EvaluationReasonStatic
+	case RolloutDecisionByPercentage:
+		return EvaluationReasonSplit
+	default:
+		return EvaluationReasonTargetingMatch
+	}
+}
@@ -0,0 +1,107 @@
+package release_test
+
+import (
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/release"
+	sh "github.com/toggler-io/toggler/spechelper"
+)
+
+func TestRolloutManager_evaluation(t *testing.T) {
+	s := sh.NewSpec(t)
+
+	s.Let(`manager`, func(t *testcase.T) interface{} {
+		return &release.RolloutManager{Storage: sh.StorageGet(t)}
+	})
+
+	s.Before(func(t *testcase.T) {
+		ctx := sh.ContextGet(t)
+		storage := sh.StorageGet(t)
+		require.Nil(t, storage.ReleasePilot(ctx).DeleteAll(ctx))
+		require.Nil(t, storage.ReleaseRollout(ctx).DeleteAll(ctx))
+		require.Nil(t, storage.ReleaseFlag(ctx).DeleteAll(ctx))
+		require.Nil(t, storage.ReleaseEnvironment(ctx).DeleteAll(ctx))
+	})
+
+	var (
+		env = s.Let(`env`, func(t *testcase.T) interface{} {
+			env := &release.Environment{Name: `production`}
+			require.Nil(t, sh.StorageGet(t).ReleaseEnvironment(sh.ContextGet(t)).Create(sh.ContextGet(t), env))
+			return env
+		})
+		envGet  = func(t *testcase.T) *release.Environment { return env.Get(t).(*release.Environment) }
+		newFlag = func(t *testcase.T, name string) *release.Flag {
+			flag := &release.Flag{Name: name}
+			require.Nil(t, manager(t).CreateFeatureFlag(sh.ContextGet(t), flag))
+			return flag
+		}
+		newRollout = func(t *testcase.T, flag *release.Flag, plan release.RolloutPlan) {
+			rollout := &release.Rollout{FlagID: flag.ID, EnvironmentID: envGet(t).ID, Plan: plan}
+			require.Nil(t, sh.StorageGet(t).ReleaseRollout(sh.ContextGet(t)).Create(sh.ContextGet(t), rollout))
+		}
+		pilotID   = s.LetValue(`pilot public id`, `alice`)
+		flagNames = s.Let(`flag names`, func(t *testcase.T) interface{} { return []string{} })
+	)
+
+	s.Describe(`EvaluateFlags`, func(s *testcase.Spec) {
+		subject := func(t *testcase.T) ([]release.Evaluation, error) {
+			return manager(t).EvaluateFlags(sh.ContextGet(t), pilotID.Get(t).(string), *envGet(t), flagNames.Get(t).([]string)...)
+		}
+
+		s.Before(func(t *testcase.T) {
+			newFlag(t, `no-rollout`)
+			newRollout(t, newFlag(t, `global`), release.RolloutDecisionByGlobal{State: true})
+			newRollout(t, newFlag(t, `percentage`), release.RolloutDecisionByPercentage{Percentage: 0})
+		})
+
+		s.Then(`every flag is evaluated with a reason, ordered by name`, func(t *testcase.T) {
+			evaluations, err := subject(t)
+			require.Nil(t, err)
+			require.Len(t, evaluations, 3)
+
+			require.Equal(t, `global`, evaluations[0].FlagName)
+			require.True(t, evaluations[0].State)
+			require.Equal(t, release.EvaluationReasonStatic, evaluations[0].Reason)
+
+			require.Equal(t, `no-rollout`, evaluations[1].FlagName)
+			require.False(t, evaluations[1].State)
+			require.Equal(t, release.EvaluationReasonDefault, evaluations[1].Reason)
+
+			require.Equal(t, `percentage`, evaluations[2].FlagName)
+			require.False(t, evaluations[2].State)
+			require.Equal(t, release.EvaluationReasonSplit, evaluations[2].Reason)
+		})
+
+		s.When(`flag names are given`, func(s *testcase.Spec) {
+			flagNames.Let(s, func(t *testcase.T) interface{} { return []string{`global`, `unknown`} })
+
+			s.Then(`only the existing requested flags are evaluated`, func(t *testcase.T) {
+				evaluations, err := subject(t)
+				require.Nil(t, err)
+				require.Len(t, evaluations, 1)
+				require.Equal(t, `global`, evaluations[0].FlagName)
+			})
+		})
+
+		s.When(`the pilot is manually enrolled`, func(s *testcase.Spec) {
+			flagNames.Let(s, func(t *testcase.T) interface{} { return []string{`global`} })
+
+			s.Before(func(t *testcase.T) {
+				flag, err := sh.StorageGet(t).ReleaseFlag(sh.ContextGet(t)).FindByName(sh.ContextGet(t), `global`)
+				require.Nil(t, err)
+				require.Nil(t, manager(t).SetPilotEnrollmentForFeature(sh.ContextGet(t), flag.ID, envGet(t).ID, pilotID.Get(t).(string), false))
+			})
+
+			s.Then(`the manual enrollment wins with targeting match`, func(t *testcase.T) {
+				evaluations, err := subject(t)
+				require.Nil(t, err)
+				require.Len(t, evaluations, 1)
+				require.False(t, evaluations[0].State)
+				require.Equal(t, release.EvaluationReasonTargetingMatch, evaluations[0].Reason)
+			})
+		})
+	})
+}
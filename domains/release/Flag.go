@@ -4,6 +4,8 @@ package release
 type Flag struct {
 	ID   string `ext:"ID" json:"id,omitempty"`
 	Name string `json:"name"`
+	// Revision is incremented by the storage with each update of the flag.
+	Revision int `json:"revision"`
 }
 
 func (f Flag) Validate() error {
@@ -0,0 +1,664 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/adamluzsi/frameless/iterators"
+)
+
+// Manifest is the declarative description of the release configuration.
+// Entities reference each other by name instead of ID,
+// so a manifest can be kept in version control and applied to any toggler instance.
+type Manifest struct {
+	Environments []ManifestEnvironment `json:"environments"`
+	Flags        []ManifestFlag        `json:"flags"`
+}
+
+type ManifestEnvironment struct {
+	Name string `json:"name"`
+}
+
+type ManifestFlag struct {
+	Name     string            `json:"name"`
+	Rollouts []ManifestRollout `json:"rollouts,omitempty"`
+	Pilots   []ManifestPilot   `json:"pilots,omitempty"`
+}
+
+type ManifestRollout struct {
+	Environment string          `json:"env"`
+	Plan        RolloutPlanView `json:"plan"`
+}
+
+type ManifestPilot struct {
+	Environment     string `json:"env"`
+	PublicID        string `json:"public_id"`
+	IsParticipating bool   `json:"is_participating"`
+}
+
+type ManifestOptions struct {
+	// Pilots makes the export include the manual pilot enrollments,
+	// and makes the apply reconcile them.
+	Pilots bool
+	// Prune makes the apply delete the entities that are not declared in the manifest.
+	Prune bool
+}
+
+func (m Manifest) Validate() error {
+	envs := make(map[string]struct{})
+	for _, env := range m.Environments {
+		if env.Name == `` {
+			return ErrEnvironmentNameIsEmpty
+		}
+		if _, ok := envs[env.Name]; ok {
+			return fmt.Errorf(`%w: environment %s`, ErrManifestDuplicate, env.Name)
+		}
+		envs[env.Name] = struct{}{}
+	}
+
+	flags := make(map[string]struct{})
+	for _, flag := range m.Flags {
+		if flag.Name == `` {
+			return ErrNameIsEmpty
+		}
+		if _, ok := flags[flag.Name]; ok {
+			return fmt.Errorf(`%w: flag %s`, ErrManifestDuplicate, flag.Name)
+		}
+		flags[flag.Name] = struct{}{}
+
+		rollouts := make(map[string]struct{})
+		for _, rollout := range flag.Rollouts {
+			if rollout.Environment == `` {
+				return ErrMissingEnv
+			}
+			if _, ok := rollouts[rollout.Environment]; ok {
+				return fmt.Errorf(`%w: rollout %s@%s`, ErrManifestDuplicate, flag.Name, rollout.Environment)
+			}
+			rollouts[rollout.Environment] = struct{}{}
+			if rollout.Plan.Plan == nil {
+				return ErrMissingRolloutPlan
+			}
+			if err := rollout.Plan.Plan.Validate(); err != nil {
+				return err
+			}
+		}
+
+		pilots := make(map[string]struct{})
+		for _, pilot := range flag.Pilots {
+			if pilot.Environment == `` {
+				return ErrMissingEnv
+			}
+			key := pilot.Environment + `/` + pilot.PublicID
+			if _, ok := pilots[key]; ok {
+				return fmt.Errorf(`%w: pilot %s@%s`, ErrManifestDuplicate, flag.Name, key)
+			}
+			pilots[key] = struct{}{}
+		}
+	}
+
+	return nil
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+const (
+	ManifestActionCreate = `create`
+	ManifestActionUpdate = `update`
+	ManifestActionDelete = `delete`
+)
+
+const (
+	ManifestKindEnvironment = `environment`
+	ManifestKindFlag        = `flag`
+	ManifestKindRollout     = `rollout`
+	ManifestKindPilot       = `pilot`
+)
+
+// ManifestChange is a single step of a ManifestPlan.
+type ManifestChange struct {
+	Action      string `json:"action"`
+	Kind        string `json:"kind"`
+	Flag        string `json:"flag,omitempty"`
+	Environment string `json:"env,omitempty"`
+	PublicID    string `json:"public_id,omitempty"`
+
+	id              string
+	plan            RolloutPlan
+	isParticipating bool
+}
+
+func (c ManifestChange) String() string {
+	var sign string
+	switch c.Action {
+	case ManifestActionCreate:
+		sign = `+`
+	case ManifestActionUpdate:
+		sign = `~`
+	case ManifestActionDelete:
+		sign = `-`
+	}
+
+	var name string
+	switch c.Kind {
+	case ManifestKindEnvironment:
+		name = c.Environment
+	case ManifestKindFlag:
+		name = c.Flag
+	case ManifestKindRollout:
+		name = c.Flag + `@` + c.Environment
+	case ManifestKindPilot:
+		name = c.Flag + `@` + c.Environment + `/` + c.PublicID
+	}
+
+	return fmt.Sprintf(`%s %s %s`, sign, c.Kind, name)
+}
+
+// ManifestPlan is the ordered list of changes that makes the storage state match a Manifest.
+type ManifestPlan struct {
+	Changes []ManifestChange `json:"changes"`
+}
+
+func (p ManifestPlan) IsEmpty() bool {
+	return len(p.Changes) == 0
+}
+
+func (p ManifestPlan) String() string {
+	if p.IsEmpty() {
+		return `no changes`
+	}
+	var lines []string
+	for _, c := range p.Changes {
+		lines = append(lines, c.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+// ExportManifest describes the current release configuration as a Manifest.
+// The entries are ordered by name, so consecutive exports of the same state are identical.
+func (manager *RolloutManager) ExportManifest(ctx context.Context, opts ManifestOptions) (Manifest, error) {
+	state, err := manager.loadManifestState(ctx)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	m := Manifest{
+		Environments: make([]ManifestEnvironment, 0, len(state.envs)),
+		Flags:        make([]ManifestFlag, 0, len(state.flags)),
+	}
+
+	for _, env := range state.envs {
+		m.Environments = append(m.Environments, ManifestEnvironment{Name: env.Name})
+	}
+
+	for _, flag := range state.flags {
+		mf := ManifestFlag{Name: flag.Name}
+
+		for _, rollout := range state.rollouts {
+			if rollout.FlagID != flag.ID {
+				continue
+			}
+			envName, ok := state.envNameByID[rollout.EnvironmentID]
+			if !ok {
+				continue
+			}
+			mf.Rollouts = append(mf.Rollouts, ManifestRollout{
+				Environment: envName,
+				Plan:        RolloutPlanView{Plan: rollout.Plan},
+			})
+		}
+		sort.Slice(mf.Rollouts, func(i, j int) bool {
+			return mf.Rollouts[i].Environment < mf.Rollouts[j].Environment
+		})
+
+		if opts.Pilots {
+			for _, pilot := range state.pilots {
+				if pilot.FlagID != flag.ID {
+					continue
+				}
+				envName, ok := state.envNameByID[pilot.EnvironmentID]
+				if !ok {
+					continue
+				}
+				mf.Pilots = append(mf.Pilots, ManifestPilot{
+					Environment:     envName,
+					PublicID:        pilot.PublicID,
+					IsParticipating: pilot.IsParticipating,
+				})
+			}
+			sort.Slice(mf.Pilots, func(i, j int) bool {
+				if mf.Pilots[i].Environment != mf.Pilots[j].Environment {
+					return mf.Pilots[i].Environment < mf.Pilots[j].Environment
+				}
+				return mf.Pilots[i].PublicID < mf.Pilots[j].PublicID
+			})
+		}
+
+		m.Flags = append(m.Flags, mf)
+	}
+
+	return m, nil
+}
+
+// PlanManifest computes the changes required to make the storage match the manifest, without applying them.
+func (manager *RolloutManager) PlanManifest(ctx context.Context, m Manifest, opts ManifestOptions) (ManifestPlan, error) {
+	if err := m.Validate(); err != nil {
+		return ManifestPlan{}, err
+	}
+
+	state, err := manager.loadManifestState(ctx)
+	if err != nil {
+		return ManifestPlan{}, err
+	}
+
+	var (
+		pilotDeletes   []ManifestChange
+		rolloutDeletes []ManifestChange
+		envCreates     []ManifestChange
+		flagCreates    []ManifestChange
+		rolloutChanges []ManifestChange
+		pilotChanges   []ManifestChange
+		flagDeletes    []ManifestChange
+		envDeletes     []ManifestChange
+	)
+
+	declaredEnvs := make(map[string]struct{})
+	for _, env := range m.Environments {
+		declaredEnvs[env.Name] = struct{}{}
+		if _, ok := state.envByName[env.Name]; !ok {
+			envCreates = append(envCreates, ManifestChange{
+				Action:      ManifestActionCreate,
+				Kind:        ManifestKindEnvironment,
+				Environment: env.Name,
+			})
+		}
+	}
+
+	// deletedEnvIDs and deletedFlagIDs mark the entities removed by pruning,
+	// so their dependent rollouts and pilots are removed as well.
+	deletedEnvIDs := make(map[string]struct{})
+	deletedFlagIDs := make(map[string]struct{})
+
+	if opts.Prune {
+		for _, env := range state.envs {
+			if _, ok := declaredEnvs[env.Name]; !ok {
+				deletedEnvIDs[env.ID] = struct{}{}
+				envDeletes = append(envDeletes, ManifestChange{
+					Action:      ManifestActionDelete,
+					Kind:        ManifestKindEnvironment,
+					Environment: env.Name,
+					id:          env.ID,
+				})
+			}
+		}
+	}
+
+	isKnownEnv := func(name string) bool {
+		if _, ok := declaredEnvs[name]; ok {
+			return true
+		}
+		env, ok := state.envByName[name]
+		if !ok {
+			return false
+		}
+		_, deleted := deletedEnvIDs[env.ID]
+		return !deleted
+	}
+
+	declaredFlags := make(map[string]ManifestFlag)
+	for _, flag := range m.Flags {
+		declaredFlags[flag.Name] = flag
+		if _, ok := state.flagByName[flag.Name]; !ok {
+			flagCreates = append(flagCreates, ManifestChange{
+				Action: ManifestActionCreate,
+				Kind:   ManifestKindFlag,
+				Flag:   flag.Name,
+			})
+		}
+	}
+
+	if opts.Prune {
+		for _, flag := range state.flags {
+			if _, ok := declaredFlags[flag.Name]; !ok {
+				deletedFlagIDs[flag.ID] = struct{}{}
+				flagDeletes = append(flagDeletes, ManifestChange{
+					Action: ManifestActionDelete,
+					Kind:   ManifestKindFlag,
+					Flag:   flag.Name,
+					id:     flag.ID,
+				})
+			}
+		}
+	}
+
+	isDependentDeleted := func(flagID, envID string) bool {
+		_, flagDeleted := deletedFlagIDs[flagID]
+		_, envDeleted := deletedEnvIDs[envID]
+		return flagDeleted || envDeleted
+	}
+
+	// rollouts
+	currentRollouts := make(map[string]Rollout)
+	for _, rollout := range state.rollouts {
+		flagName, envName := state.flagNameByID[rollout.FlagID], state.envNameByID[rollout.EnvironmentID]
+		if isDependentDeleted(rollout.FlagID, rollout.EnvironmentID) {
+			rolloutDeletes = append(rolloutDeletes, ManifestChange{
+				Action:      ManifestActionDelete,
+				Kind:        ManifestKindRollout,
+				Flag:        flagName,
+				Environment: envName,
+				id:          rollout.ID,
+			})
+			continue
+		}
+		currentRollouts[flagName+`@`+envName] = rollout
+	}
+
+	declaredRollouts := make(map[string]struct{})
+	for _, flag := range m.Flags {
+		for _, mr := range flag.Rollouts {
+			if !isKnownEnv(mr.Environment) {
+				return ManifestPlan{}, fmt.Errorf(`%w: %s`, ErrManifestUnknownEnvironment, mr.Environment)
+			}
+
+			key := flag.Name + `@` + mr.Environment
+			declaredRollouts[key] = struct{}{}
+
+			current, ok := currentRollouts[key]
+			if !ok {
+				rolloutChanges = append(rolloutChanges, ManifestChange{
+					Action:      ManifestActionCreate,
+					Kind:        ManifestKindRollout,
+					Flag:        flag.Name,
+					Environment: mr.Environment,
+					plan:        mr.Plan.Plan,
+				})
+				continue
+			}
+
+			same, err := isSameRolloutPlan(current.Plan, mr.Plan.Plan)
+			if err != nil {
+				return ManifestPlan{}, err
+			}
+			if !same {
+				rolloutChanges = append(rolloutChanges, ManifestChange{
+					Action:      ManifestActionUpdate,
+					Kind:        ManifestKindRollout,
+					Flag:        flag.Name,
+					Environment: mr.Environment,
+					id:          current.ID,
+					plan:        mr.Plan.Plan,
+				})
+			}
+		}
+	}
+
+	if opts.Prune {
+		for key, rollout := range currentRollouts {
+			if _, ok := declaredRollouts[key]; !ok {
+				rolloutDeletes = append(rolloutDeletes, ManifestChange{
+					Action:      ManifestActionDelete,
+					Kind:        ManifestKindRollout,
+					Flag:        state.flagNameByID[rollout.FlagID],
+					Environment: state.envNameByID[rollout.EnvironmentID],
+					id:          rollout.ID,
+				})
+			}
+		}
+	}
+
+	// pilots
+	currentPilots := make(map[string]Pilot)
+	for _, pilot := range state.pilots {
+		flagName, envName := state.flagNameByID[pilot.FlagID], state.envNameByID[pilot.EnvironmentID]
+		if isDependentDeleted(pilot.FlagID, pilot.EnvironmentID) {
+			pilotDeletes = append(pilotDeletes, ManifestChange{
+				Action:      ManifestActionDelete,
+				Kind:        ManifestKindPilot,
+				Flag:        flagName,
+				Environment: envName,
+				PublicID:    pilot.PublicID,
+				id:          pilot.ID,
+			})
+			continue
+		}
+		currentPilots[flagName+`@`+envName+`/`+pilot.PublicID] = pilot
+	}
+
+	if opts.Pilots {
+		declaredPilots := make(map[string]struct{})
+		for _, flag := range m.Flags {
+			for _, mp := range flag.Pilots {
+				if !isKnownEnv(mp.Environment) {
+					return ManifestPlan{}, fmt.Errorf(`%w: %s`, ErrManifestUnknownEnvironment, mp.Environment)
+				}
+
+				key := flag.Name + `@` + mp.Environment + `/` + mp.PublicID
+				declaredPilots[key] = struct{}{}
+
+				change := ManifestChange{
+					Kind:            ManifestKindPilot,
+					Flag:            flag.Name,
+					Environment:     mp.Environment,
+					PublicID:        mp.PublicID,
+					isParticipating: mp.IsParticipating,
+				}
+
+				current, ok := currentPilots[key]
+				switch {
+				case !ok:
+					change.Action = ManifestActionCreate
+					pilotChanges = append(pilotChanges, change)
+				case current.IsParticipating != mp.IsParticipating:
+					change.Action = ManifestActionUpdate
+					change.id = current.ID
+					pilotChanges = append(pilotChanges, change)
+				}
+			}
+		}
+
+		if opts.Prune {
+			for key, pilot := range currentPilots {
+				if _, ok := declaredPilots[key]; !ok {
+					pilotDeletes = append(pilotDeletes, ManifestChange{
+						Action:      ManifestActionDelete,
+						Kind:        ManifestKindPilot,
+						Flag:        state.flagNameByID[pilot.FlagID],
+						Environment: state.envNameByID[pilot.EnvironmentID],
+						PublicID:    pilot.PublicID,
+						id:          pilot.ID,
+					})
+				}
+			}
+		}
+	}
+
+	var plan ManifestPlan
+	for _, group := range [][]ManifestChange{
+		pilotDeletes,
+		rolloutDeletes,
+		envCreates,
+		flagCreates,
+		rolloutChanges,
+		pilotChanges,
+		flagDeletes,
+		envDeletes,
+	} {
+		sort.SliceStable(group, func(i, j int) bool {
+			return group[i].String() < group[j].String()
+		})
+		plan.Changes = append(plan.Changes, group...)
+	}
+	return plan, nil
+}
+
+// ApplyManifest plans and applies the manifest in a single storage transaction.
+// Either every change of the returned plan is applied or none of them.
+func (manager *RolloutManager) ApplyManifest(ctx context.Context, m Manifest, opts ManifestOptions) (_ ManifestPlan, returnErr error) {
+	ctx, err := manager.Storage.BeginTx(ctx)
+	if err != nil {
+		return ManifestPlan{}, err
+	}
+	defer func() {
+		if returnErr != nil {
+			_ = manager.Storage.RollbackTx(ctx)
+			return
+		}
+
+		returnErr = manager.Storage.CommitTx(ctx)
+	}()
+
+	plan, err := manager.PlanManifest(ctx, m, opts)
+	if err != nil {
+		return ManifestPlan{}, err
+	}
+
+	state, err := manager.loadManifestState(ctx)
+	if err != nil {
+		return ManifestPlan{}, err
+	}
+
+	envIDByName := make(map[string]string)
+	for name, env := range state.envByName {
+		envIDByName[name] = env.ID
+	}
+	flagIDByName := make(map[string]string)
+	for name, flag := range state.flagByName {
+		flagIDByName[name] = flag.ID
+	}
+
+	for _, c := range plan.Changes {
+		if err := manager.applyManifestChange(ctx, c, envIDByName, flagIDByName); err != nil {
+			return ManifestPlan{}, fmt.Errorf(`%s: %w`, c.String(), err)
+		}
+	}
+
+	return plan, nil
+}
+
+func (manager *RolloutManager) applyManifestChange(ctx context.Context, c ManifestChange, envIDByName, flagIDByName map[string]string) error {
+	switch c.Kind + `/` + c.Action {
+	case ManifestKindEnvironment + `/` + ManifestActionCreate:
+		env := Environment{Name: c.Environment}
+		if err := manager.Storage.ReleaseEnvironment(ctx).Create(ctx, &env); err != nil {
+			return err
+		}
+		envIDByName[env.Name] = env.ID
+		return nil
+
+	case ManifestKindEnvironment + `/` + ManifestActionDelete:
+		return manager.Storage.ReleaseEnvironment(ctx).DeleteByID(ctx, c.id)
+
+	case ManifestKindFlag + `/` + ManifestActionCreate:
+		flag := Flag{Name: c.Flag}
+		if err := manager.Storage.ReleaseFlag(ctx).Create(ctx, &flag); err != nil {
+			return err
+		}
+		flagIDByName[flag.Name] = flag.ID
+		return nil
+
+	case ManifestKindFlag + `/` + ManifestActionDelete:
+		return manager.Storage.ReleaseFlag(ctx).DeleteByID(ctx, c.id)
+
+	case ManifestKindRollout + `/` + ManifestActionCreate:
+		return manager.Storage.ReleaseRollout(ctx).Create(ctx, &Rollout{
+			FlagID:        flagIDByName[c.Flag],
+			EnvironmentID: envIDByName[c.Environment],
+			Plan:          c.plan,
+		})
+
+	case ManifestKindRollout + `/` + ManifestActionUpdate:
+		return manager.Storage.ReleaseRollout(ctx).Update(ctx, &Rollout{
+			ID:            c.id,
+			FlagID:        flagIDByName[c.Flag],
+			EnvironmentID: envIDByName[c.Environment],
+			Plan:          c.plan,
+		})
+
+	case ManifestKindRollout + `/` + ManifestActionDelete:
+		return manager.Storage.ReleaseRollout(ctx).DeleteByID(ctx, c.id)
+
+	case ManifestKindPilot + `/` + ManifestActionCreate:
+		return manager.Storage.ReleasePilot(ctx).Create(ctx, &Pilot{
+			FlagID:          flagIDByName[c.Flag],
+			EnvironmentID:   envIDByName[c.Environment],
+			PublicID:        c.PublicID,
+			IsParticipating: c.isParticipating,
+		})
+
+	case ManifestKindPilot + `/` + ManifestActionUpdate:
+		return manager.Storage.ReleasePilot(ctx).Update(ctx, &Pilot{
+			ID:              c.id,
+			FlagID:          flagIDByName[c.Flag],
+			EnvironmentID:   envIDByName[c.Environment],
+			PublicID:        c.PublicID,
+			IsParticipating: c.isParticipating,
+		})
+
+	case ManifestKindPilot + `/` + ManifestActionDelete:
+		return manager.Storage.ReleasePilot(ctx).DeleteByID(ctx, c.id)
+
+	default:
+		return ErrInvalidAction
+	}
+}
+
+type manifestState struct {
+	envs         []Environment
+	flags        []Flag
+	rollouts     []Rollout
+	pilots       []Pilot
+	envByName    map[string]Environment
+	flagByName   map[string]Flag
+	envNameByID  map[string]string
+	flagNameByID map[string]string
+}
+
+func (manager *RolloutManager) loadManifestState(ctx context.Context) (manifestState, error) {
+	var state manifestState
+	if err := iterators.Collect(manager.Storage.ReleaseEnvironment(ctx).FindAll(ctx), &state.envs); err != nil {
+		return state, err
+	}
+	if err := iterators.Collect(manager.Storage.ReleaseFlag(ctx).FindAll(ctx), &state.flags); err != nil {
+		return state, err
+	}
+	if err := iterators.Collect(manager.Storage.ReleaseRollout(ctx).FindAll(ctx), &state.rollouts); err != nil {
+		return state, err
+	}
+	if err := iterators.Collect(manager.Storage.ReleasePilot(ctx).FindAll(ctx), &state.pilots); err != nil {
+		return state, err
+	}
+
+	sort.Slice(state.envs, func(i, j int) bool { return state.envs[i].Name < state.envs[j].Name })
+	sort.Slice(state.flags, func(i, j int) bool { return state.flags[i].Name < state.flags[j].Name })
+
+	state.envByName = make(map[string]Environment)
+	state.envNameByID = make(map[string]string)
+	for _, env := range state.envs {
+		state.envByName[env.Name] = env
+		state.envNameByID[env.ID] = env.Name
+	}
+	state.flagByName = make(map[string]Flag)
+	state.flagNameByID = make(map[string]string)
+	for _, flag := range state.flags {
+		state.flagByName[flag.Name] = flag
+		state.flagNameByID[flag.ID] = flag.Name
+	}
+	return state, nil
+}
+
+func isSameRolloutPlan(a, b RolloutPlan) (bool, error) {
+	aJSON, err := json.Marshal(RolloutPlanView{Plan: a})
+	if err != nil {
+		return false, err
+	}
+	bJSON, err := json.Marshal(RolloutPlanView{Plan: b})
+	if err != nil {
+		return false, err
+	}
+	return string(aJSON) == string(bJSON), nil
+}
@@ -0,0 +1,173 @@
+package release_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/adamluzsi/frameless/iterators"
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/release"
+	sh "github.com/toggler-io/toggler/spechelper"
+)
+
+func TestRolloutManager_manifest(t *testing.T) {
+	s := sh.NewSpec(t)
+
+	s.Let(`manager`, func(t *testcase.T) interface{} {
+		return &release.RolloutManager{Storage: sh.StorageGet(t)}
+	})
+
+	s.Before(func(t *testcase.T) {
+		ctx := sh.ContextGet(t)
+		storage := sh.StorageGet(t)
+		require.Nil(t, storage.ReleasePilot(ctx).DeleteAll(ctx))
+		require.Nil(t, storage.ReleaseRollout(ctx).DeleteAll(ctx))
+		require.Nil(t, storage.ReleaseFlag(ctx).DeleteAll(ctx))
+		require.Nil(t, storage.ReleaseEnvironment(ctx).DeleteAll(ctx))
+	})
+
+	var (
+		manifest = s.Let(`manifest`, func(t *testcase.T) interface{} {
+			var m release.Manifest
+			require.Nil(t, json.Unmarshal([]byte(`{
+				"environments": [{"name": "production"}, {"name": "staging"}],
+				"flags": [{
+					"name": "checkout",
+					"rollouts": [
+						{"env": "production", "plan": {"type": "percentage", "percentage": 10, "seed": 42}},
+						{"env": "staging", "plan": {"type": "global", "state": true}}
+					],
+					"pilots": [{"env": "staging", "public_id": "alice", "is_participating": true}]
+				}]
+			}`), &m))
+			return m
+		})
+		manifestGet = func(t *testcase.T) release.Manifest { return manifest.Get(t).(release.Manifest) }
+		opts        = s.Let(`opts`, func(t *testcase.T) interface{} { return release.ManifestOptions{} })
+		optsGet     = func(t *testcase.T) release.ManifestOptions { return opts.Get(t).(release.ManifestOptions) }
+		countFlags  = func(t *testcase.T) int {
+			n, err := iterators.Count(sh.StorageGet(t).ReleaseFlag(sh.ContextGet(t)).FindAll(sh.ContextGet(t)))
+			require.Nil(t, err)
+			return n
+		}
+	)
+
+	s.Describe(`PlanManifest`, func(s *testcase.Spec) {
+		subject := func(t *testcase.T) (release.ManifestPlan, error) {
+			return manager(t).PlanManifest(sh.ContextGet(t), manifestGet(t), optsGet(t))
+		}
+
+		s.Then(`it plans the creation of every declared entity without applying them`, func(t *testcase.T) {
+			plan, err := subject(t)
+			require.Nil(t, err)
+			require.Equal(t, []string{
+				`+ environment production`,
+				`+ environment staging`,
+				`+ flag checkout`,
+				`+ rollout checkout@production`,
+				`+ rollout checkout@staging`,
+			}, planLines(plan))
+			require.Equal(t, 0, countFlags(t))
+		})
+
+		s.When(`pilots are reconciled`, func(s *testcase.Spec) {
+			opts.Let(s, func(t *testcase.T) interface{} { return release.ManifestOptions{Pilots: true} })
+
+			s.Then(`pilot creation is planned as well`, func(t *testcase.T) {
+				plan, err := subject(t)
+				require.Nil(t, err)
+				require.Contains(t, planLines(plan), `+ pilot checkout@staging/alice`)
+			})
+		})
+
+		s.When(`a rollout references an undeclared environment`, func(s *testcase.Spec) {
+			s.Before(func(t *testcase.T) {
+				manifestGet(t).Flags[0].Rollouts[0].Environment = `unknown`
+			})
+
+			s.Then(`it yields an error`, func(t *testcase.T) {
+				_, err := subject(t)
+				require.ErrorIs(t, err, release.ErrManifestUnknownEnvironment)
+			})
+		})
+
+		s.When(`the manifest is already applied`, func(s *testcase.Spec) {
+			s.Before(func(t *testcase.T) {
+				_, err := manager(t).ApplyManifest(sh.ContextGet(t), manifestGet(t), optsGet(t))
+				require.Nil(t, err)
+			})
+
+			s.Then(`there are no changes`, func(t *testcase.T) {
+				plan, err := subject(t)
+				require.Nil(t, err)
+				require.True(t, plan.IsEmpty())
+			})
+
+			s.And(`a rollout plan changed`, func(s *testcase.Spec) {
+				s.Before(func(t *testcase.T) {
+					manifestGet(t).Flags[0].Rollouts[0].Plan.Plan = release.RolloutDecisionByGlobal{State: true}
+				})
+
+				s.Then(`the rollout update is planned`, func(t *testcase.T) {
+					plan, err := subject(t)
+					require.Nil(t, err)
+					require.Contains(t, planLines(plan), `~ rollout checkout@production`)
+				})
+			})
+		})
+
+		s.When(`an undeclared flag exists`, func(s *testcase.Spec) {
+			s.Before(func(t *testcase.T) {
+				require.Nil(t, manager(t).CreateFeatureFlag(sh.ContextGet(t), &release.Flag{Name: `legacy`}))
+			})
+
+			s.Then(`it is kept by default`, func(t *testcase.T) {
+				plan, err := subject(t)
+				require.Nil(t, err)
+				require.NotContains(t, planLines(plan), `- flag legacy`)
+			})
+
+			s.And(`prune is requested`, func(s *testcase.Spec) {
+				opts.Let(s, func(t *testcase.T) interface{} { return release.ManifestOptions{Prune: true} })
+
+				s.Then(`its deletion is planned`, func(t *testcase.T) {
+					plan, err := subject(t)
+					require.Nil(t, err)
+					require.Contains(t, planLines(plan), `- flag legacy`)
+				})
+			})
+		})
+	})
+
+	s.Describe(`ApplyManifest`, func(s *testcase.Spec) {
+		subject := func(t *testcase.T) (release.ManifestPlan, error) {
+			return manager(t).ApplyManifest(sh.ContextGet(t), manifestGet(t), optsGet(t))
+		}
+
+		s.Then(`the exported manifest equals to the applied one`, func(t *testcase.T) {
+			_, err := subject(t)
+			require.Nil(t, err)
+
+			exported, err := manager(t).ExportManifest(sh.ContextGet(t), optsGet(t))
+			require.Nil(t, err)
+
+			expected := manifestGet(t)
+			expected.Flags[0].Pilots = nil
+			expectedJSON, err := json.Marshal(expected)
+			require.Nil(t, err)
+			actualJSON, err := json.Marshal(exported)
+			require.Nil(t, err)
+			require.JSONEq(t, string(expectedJSON), string(actualJSON))
+		})
+	})
+}
+
+func planLines(plan release.ManifestPlan) []string {
+	var lines []string
+	for _, c := range plan.Changes {
+		lines = append(lines, c.String())
+	}
+	return lines
+}
@@ -0,0 +1,90 @@
+package release
+
+import (
+	"context"
+	"time"
+)
+
+// Observer receives the measurements of the release flag evaluations,
+// so they can be exposed to a monitoring system.
+// The methods are called on the evaluation path, so they are expected to return quickly.
+type Observer interface {
+	// ObserveEvaluation is called with the outcome of each release flag evaluation for a pilot.
+	ObserveEvaluation(env Environment, result EvaluationResult)
+	// ObservePlanEvaluation is called with the time it took to evaluate a rollout plan, by the type of the plan.
+	ObservePlanEvaluation(planType string, d time.Duration)
+	// ObserveDecisionByAPI is called with the outcome of each RolloutDecisionByAPI call.
+	ObserveDecisionByAPI(outcome EvaluationResult, d time.Duration)
+}
+
+// EvaluationResult is the outcome of an evaluation from the point of view of the monitoring.
+type EvaluationResult string
+
+const (
+	EvaluationResultEnabled  EvaluationResult = `enabled`
+	EvaluationResultDisabled EvaluationResult = `disabled`
+	EvaluationResultError    EvaluationResult = `error`
+)
+
+func evaluationResultOf(state bool, err error) EvaluationResult {
+	switch {
+	case err != nil:
+		return EvaluationResultError
+	case state:
+		return EvaluationResultEnabled
+	default:
+		return EvaluationResultDisabled
+	}
+}
+
+// PlanTypeOf returns the type name of the rollout plan, as it is used in the JSON representation.
+func PlanTypeOf(plan RolloutPlan) string {
+	switch plan.(type) {
+	case RolloutDecisionByGlobal:
+		return `global`
+	case RolloutDecisionByPercentage:
+		return `percentage`
+	case RolloutDecisionByAPI:
+		return `api`
+	case RolloutDecisionAND:
+		return `and`
+	case RolloutDecisionOR:
+		return `or`
+	case RolloutDecisionNOT:
+		return `not`
+	default:
+		return `unknown`
+	}
+}
+
+type observerContextKey struct{}
+
+// contextWithObserver makes the observer available to the rollout plans,
+// so a RolloutDecisionByAPI can report its call even when it is part of a composite plan.
+func contextWithObserver(ctx context.Context, o Observer) context.Context {
+	if o == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, observerContextKey{}, o)
+}
+
+func observerFromContext(ctx context.Context) (Observer, bool) {
+	o, ok := ctx.Value(observerContextKey{}).(Observer)
+	return o, ok
+}
+
+func (manager *RolloutManager) isParticipating(ctx context.Context, plan RolloutPlan, pilotExternalID string) (bool, error) {
+	if manager.Observer == nil {
+		return plan.IsParticipating(ctx, pilotExternalID)
+	}
+	start := time.Now()
+	defer func() { manager.Observer.ObservePlanEvaluation(PlanTypeOf(plan), time.Since(start)) }()
+	return plan.IsParticipating(contextWithObserver(ctx, manager.Observer), pilotExternalID)
+}
+
+func (manager *RolloutManager) observeEvaluation(env Environment, state bool, err error) {
+	if manager.Observer == nil {
+		return
+	}
+	manager.Observer.ObserveEvaluation(env, evaluationResultOf(state, err))
+}
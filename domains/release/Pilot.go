@@ -15,4 +15,6 @@ type Pilot struct {
 	PublicID string `json:"public_id"`
 	// IsParticipating states that whether the pilot for the given flag in a given environment is enrolled, or blacklisted.
 	IsParticipating bool `json:"is_participating"`
+	// Revision is incremented by the storage with each update of the pilot.
+	Revision int `json:"revision"`
 }
@@ -0,0 +1,220 @@
+package release
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+const (
+	SortByID       = `id`
+	SortByName     = `name`
+	SortByPublicID = `public_id`
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 1000
+)
+
+// Pagination describes a cursor based page request.
+// The entity ID is always used as the tie breaker for the ordering,
+// so a cursor points to an exact position in the result set.
+type Pagination struct {
+	// Cursor is the opaque position token that was returned as the next cursor of the previous page.
+	// Empty cursor means the first page.
+	Cursor string
+	// Limit is the maximum number of entries returned.
+	// Storages treat zero limit as unlimited.
+	Limit int
+	// SortBy is the name of the field the entries ordered by.
+	// Empty value means ordering by ID.
+	SortBy string
+	// Descending reverses the sort order.
+	Descending bool
+}
+
+func (p Pagination) validate(sortable ...string) error {
+	if p.Limit < 0 || MaxPageLimit < p.Limit {
+		return ErrInvalidPageLimit
+	}
+	if p.SortBy != `` && p.SortBy != SortByID {
+		var ok bool
+		for _, field := range sortable {
+			if field == p.SortBy {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return ErrInvalidSortField
+		}
+	}
+	if p.Cursor != `` {
+		if _, err := DecodeCursor(p.Cursor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p Pagination) withDefaults() Pagination {
+	if p.Limit == 0 {
+		p.Limit = DefaultPageLimit
+	}
+	if p.SortBy == `` {
+		p.SortBy = SortByID
+	}
+	return p
+}
+
+// Cursor is the decoded form of a Pagination.Cursor.
+// Key holds the value of the sort field of the last entry in the previous page, and ID holds the entry's ID.
+type Cursor struct {
+	Key string `json:"k"`
+	ID  string `json:"id"`
+}
+
+func EncodeCursor(c Cursor) string {
+	bs, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(bs)
+}
+
+func DecodeCursor(cursor string) (Cursor, error) {
+	bs, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(bs, &c); err != nil || c.ID == `` {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// IsAfterCursor tells if an entry with the given sort key and ID positioned after the cursor in the requested order.
+// Storages without a query language can use it to skip the entries of the previous pages.
+func (p Pagination) IsAfterCursor(key, id string) bool {
+	if p.Cursor == `` {
+		return true
+	}
+	c, err := DecodeCursor(p.Cursor)
+	if err != nil {
+		return false
+	}
+	return p.Less(c.Key, c.ID, key, id)
+}
+
+// Less reports whether the entry (keyA, idA) comes before (keyB, idB) in the requested order.
+func (p Pagination) Less(keyA, idA, keyB, idB string) bool {
+	cmp := strings.Compare(keyA, keyB)
+	if cmp == 0 {
+		cmp = strings.Compare(idA, idB)
+	}
+	if p.Descending {
+		return 0 < cmp
+	}
+	return cmp < 0
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+type FlagQuery struct {
+	NamePrefix string
+	Pagination
+}
+
+func (q FlagQuery) Validate() error {
+	return q.Pagination.validate(SortByName)
+}
+
+// SortKey returns the value of the requested sort field of the flag.
+func (q FlagQuery) SortKey(f Flag) string {
+	if q.SortBy == SortByName {
+		return f.Name
+	}
+	return f.ID
+}
+
+func (q FlagQuery) Match(f Flag) bool {
+	return strings.HasPrefix(f.Name, q.NamePrefix)
+}
+
+type EnvironmentQuery struct {
+	NamePrefix string
+	Pagination
+}
+
+func (q EnvironmentQuery) Validate() error {
+	return q.Pagination.validate(SortByName)
+}
+
+func (q EnvironmentQuery) SortKey(e Environment) string {
+	if q.SortBy == SortByName {
+		return e.Name
+	}
+	return e.ID
+}
+
+func (q EnvironmentQuery) Match(e Environment) bool {
+	return strings.HasPrefix(e.Name, q.NamePrefix)
+}
+
+type PilotQuery struct {
+	FlagID          string
+	EnvironmentID   string
+	PublicID        string
+	IsParticipating *bool
+	Pagination
+}
+
+func (q PilotQuery) Validate() error {
+	return q.Pagination.validate(SortByPublicID)
+}
+
+func (q PilotQuery) SortKey(p Pilot) string {
+	if q.SortBy == SortByPublicID {
+		return p.PublicID
+	}
+	return p.ID
+}
+
+func (q PilotQuery) Match(p Pilot) bool {
+	if q.FlagID != `` && q.FlagID != p.FlagID {
+		return false
+	}
+	if q.EnvironmentID != `` && q.EnvironmentID != p.EnvironmentID {
+		return false
+	}
+	if q.PublicID != `` && q.PublicID != p.PublicID {
+		return false
+	}
+	if q.IsParticipating != nil && *q.IsParticipating != p.IsParticipating {
+		return false
+	}
+	return true
+}
+
+type RolloutQuery struct {
+	FlagID        string
+	EnvironmentID string
+	Pagination
+}
+
+func (q RolloutQuery) Validate() error {
+	return q.Pagination.validate()
+}
+
+func (q RolloutQuery) SortKey(r Rollout) string {
+	return r.ID
+}
+
+func (q RolloutQuery) Match(r Rollout) bool {
+	if q.FlagID != `` && q.FlagID != r.FlagID {
+		return false
+	}
+	if q.EnvironmentID != `` && q.EnvironmentID != r.EnvironmentID {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,23 @@
+package release
+
+// RevisionOf returns the revision field of a release flag, environment, rollout or pilot pointer.
+//
+// The storages implement optimistic concurrency control with the revision:
+// an entity is created with the first revision, and each update increments it.
+// An update is only accepted when it carries the revision of the stored entity,
+// otherwise it fails with ErrRevisionConflict, so a change made in the meantime is not overwritten silently.
+// The zero revision means the caller doesn't know the stored revision, and the entity is updated unconditionally.
+func RevisionOf(ptr interface{}) (*int, bool) {
+	switch e := ptr.(type) {
+	case *Flag:
+		return &e.Revision, true
+	case *Environment:
+		return &e.Revision, true
+	case *Rollout:
+		return &e.Revision, true
+	case *Pilot:
+		return &e.Revision, true
+	default:
+		return nil, false
+	}
+}
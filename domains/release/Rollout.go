@@ -3,6 +3,7 @@ package release
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"hash/fnv"
 	"io/ioutil"
@@ -11,16 +12,23 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Rollout struct {
-	ID string `ext:"ID"`
+	ID string `ext:"ID" json:"id,omitempty"`
 	// FlagID is the release flag id to which the rolloutBase belongs
-	FlagID string
+	FlagID string `json:"flag_id"`
 	// EnvironmentID is the deployment environment id
-	EnvironmentID string
+	EnvironmentID string `json:"env_id"`
 	// Plan holds the composited rule set about the pilot participation decision logic.
-	Plan RolloutPlan
+	Plan RolloutPlan `json:"plan"`
+	// Revision is incremented by the storage with each update of the rollout.
+	Revision int `json:"revision"`
 }
 
 func (r Rollout) Validate() error {
@@ -43,6 +51,8 @@ func (r Rollout) Validate() error {
 // Rollout expects to determines the behavior of the rollout process.
 // the actual behavior implementation is with the RolloutManager,
 // but the configuration data is located here
+//
+// swagger:type object
 type RolloutPlan interface {
 	IsParticipating(ctx context.Context, pilotExternalID string) (bool, error)
 	Validate() error
@@ -60,7 +70,9 @@ type RolloutDecisionByGlobal struct {
 }
 
 func (r RolloutDecisionByGlobal) IsParticipating(ctx context.Context, pilotExternalID string) (bool, error) {
-	return r.State, nil
+	return traceIsParticipating(ctx, `RolloutDecisionByGlobal`, r, func(ctx context.Context) (bool, error) {
+		return r.State, nil
+	})
 }
 
 func (r RolloutDecisionByGlobal) Validate() error {
@@ -95,16 +107,18 @@ type RolloutDecisionByPercentage struct {
 }
 
 func (s RolloutDecisionByPercentage) IsParticipating(ctx context.Context, pilotExternalID string) (bool, error) {
-	if s.Percentage == 0 {
-		return false, nil
-	}
+	return traceIsParticipating(ctx, `RolloutDecisionByPercentage`, s, func(ctx context.Context) (bool, error) {
+		if s.Percentage == 0 {
+			return false, nil
+		}
 
-	diceRollResultPercentage, err := s.pseudoRandPercentage(pilotExternalID)
-	if err != nil {
-		return false, err
-	}
+		diceRollResultPercentage, err := s.pseudoRandPercentage(pilotExternalID)
+		if err != nil {
+			return false, err
+		}
 
-	return diceRollResultPercentage <= s.Percentage, nil
+		return diceRollResultPercentage <= s.Percentage, nil
+	})
 }
 
 func (s RolloutDecisionByPercentage) Validate() error {
@@ -195,11 +209,30 @@ func (s *RolloutDecisionByAPI) UnmarshalJSON(bytes []byte) error {
 var rolloutDecisionByAPIHTTPClient = http.Client{Timeout: 30 * time.Second}
 
 func (s RolloutDecisionByAPI) IsParticipating(ctx context.Context, pilotExternalID string) (bool, error) {
+	return traceIsParticipating(ctx, `RolloutDecisionByAPI`, s, func(ctx context.Context) (bool, error) {
+		o, ok := observerFromContext(ctx)
+		if !ok {
+			return s.requestDecision(ctx, pilotExternalID)
+		}
+		start := time.Now()
+		isParticipating, err := s.requestDecision(ctx, pilotExternalID)
+		o.ObserveDecisionByAPI(evaluationResultOf(isParticipating, err), time.Since(start))
+		return isParticipating, err
+	})
+}
+
+func (s RolloutDecisionByAPI) requestDecision(ctx context.Context, pilotExternalID string) (_ bool, rErr error) {
+	// the url is recorded without the query, so the pilot id is not part of the trace.
+	ctx, span := tracer().Start(ctx, `HTTP GET`, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.HTTPMethodKey.String(`GET`), semconv.HTTPURLKey.String(s.URL.String())))
+	defer func() { endSpan(span, rErr) }()
+
 	req, err := http.NewRequest(`GET`, s.URL.String(), nil)
 	if err != nil {
 		return false, err
 	}
 	req = req.WithContext(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 
 	query := req.URL.Query()
 	query.Set(`pilot-external-id`, pilotExternalID)
@@ -211,13 +244,14 @@ func (s RolloutDecisionByAPI) IsParticipating(ctx context.Context, pilotExternal
 	}
 
 	code := resp.StatusCode
+	span.SetAttributes(semconv.HTTPStatusCodeKey.Int(code))
 
 	if 500 <= code && code < 600 {
 		defer resp.Body.Close()
 		if bs, err := ioutil.ReadAll(resp.Body); err != nil {
 			return false, err
 		} else {
-			return false, fmt.Errorf(string(bs))
+			return false, errors.New(string(bs))
 		}
 	}
 
@@ -254,15 +288,17 @@ type RolloutDecisionAND struct {
 
 // TODO:SPEC
 func (r RolloutDecisionAND) IsParticipating(ctx context.Context, pilotExternalID string) (bool, error) {
-	lp, err := r.Left.IsParticipating(ctx, pilotExternalID)
-	if err != nil {
-		return false, err
-	}
-	rp, err := r.Right.IsParticipating(ctx, pilotExternalID)
-	if err != nil {
-		return false, err
-	}
-	return lp && rp, nil
+	return traceIsParticipating(ctx, `RolloutDecisionAND`, r, func(ctx context.Context) (bool, error) {
+		lp, err := r.Left.IsParticipating(ctx, pilotExternalID)
+		if err != nil {
+			return false, err
+		}
+		rp, err := r.Right.IsParticipating(ctx, pilotExternalID)
+		if err != nil {
+			return false, err
+		}
+		return lp && rp, nil
+	})
 }
 
 // TODO:SPEC
@@ -291,15 +327,17 @@ type RolloutDecisionOR struct {
 
 // TODO:SPEC
 func (r RolloutDecisionOR) IsParticipating(ctx context.Context, pilotExternalID string) (bool, error) {
-	lp, err := r.Left.IsParticipating(ctx, pilotExternalID)
-	if err != nil {
-		return false, err
-	}
-	rp, err := r.Right.IsParticipating(ctx, pilotExternalID)
-	if err != nil {
-		return false, err
-	}
-	return lp || rp, nil
+	return traceIsParticipating(ctx, `RolloutDecisionOR`, r, func(ctx context.Context) (bool, error) {
+		lp, err := r.Left.IsParticipating(ctx, pilotExternalID)
+		if err != nil {
+			return false, err
+		}
+		rp, err := r.Right.IsParticipating(ctx, pilotExternalID)
+		if err != nil {
+			return false, err
+		}
+		return lp || rp, nil
+	})
 }
 
 // TODO:SPEC
@@ -327,11 +365,13 @@ type RolloutDecisionNOT struct {
 
 // TODO:SPEC
 func (r RolloutDecisionNOT) IsParticipating(ctx context.Context, pilotExternalID string) (bool, error) {
-	p, err := r.Definition.IsParticipating(ctx, pilotExternalID)
-	if err != nil {
-		return false, err
-	}
-	return !p, nil
+	return traceIsParticipating(ctx, `RolloutDecisionNOT`, r, func(ctx context.Context) (bool, error) {
+		p, err := r.Definition.IsParticipating(ctx, pilotExternalID)
+		if err != nil {
+			return false, err
+		}
+		return !p, nil
+	})
 }
 
 // TODO:SPEC
@@ -568,6 +608,8 @@ type rolloutView struct {
 	DeploymentEnvironmentID string `json:"env_id"`
 	// Plan holds the composited rule set about the pilot participation decision logic.
 	RolloutPlan RolloutPlanView `json:"plan"`
+	// Revision is incremented by the storage with each update of the rollout.
+	Revision int `json:"revision"`
 }
 
 func (r Rollout) MarshalJSON() ([]byte, error) {
@@ -576,6 +618,7 @@ func (r Rollout) MarshalJSON() ([]byte, error) {
 		FlagID:                  r.FlagID,
 		DeploymentEnvironmentID: r.EnvironmentID,
 		RolloutPlan:             RolloutPlanView{Plan: r.Plan},
+		Revision:                r.Revision,
 	})
 }
 
@@ -590,5 +633,6 @@ func (r *Rollout) UnmarshalJSON(bs []byte) error {
 	r.FlagID = v.FlagID
 	r.EnvironmentID = v.DeploymentEnvironmentID
 	r.Plan = v.RolloutPlan.Plan
+	r.Revision = v.Revision
 	return nil
 }
@@ -15,7 +15,11 @@ func NewRolloutManager(s Storage) *RolloutManager {
 // The manager use storage in a write heavy behavior.
 //
 // SRP: release manager
-type RolloutManager struct{ Storage Storage }
+type RolloutManager struct {
+	Storage Storage
+	// Observer is optional, and when it is set, it receives the measurements of the evaluations.
+	Observer Observer
+}
 
 // GetAllReleaseFlagStatesOfThePilot check the flag states for every requested release flag.
 // If a flag doesn't exist, then it will provide a turned off state to it.
@@ -24,7 +28,10 @@ type RolloutManager struct{ Storage Storage }
 // Also this help if a flag is not cleaned up from the clients, the worst thing will be a disabled feature,
 // instead of a breaking client.
 // This also makes it harder to figure out `private` release flags
-func (manager *RolloutManager) GetAllReleaseFlagStatesOfThePilot(ctx context.Context, pilotExternalID string, env Environment, flagNames ...string) (map[string]bool, error) {
+func (manager *RolloutManager) GetAllReleaseFlagStatesOfThePilot(ctx context.Context, pilotExternalID string, env Environment, flagNames ...string) (_ map[string]bool, rErr error) {
+	ctx, span := startEvaluationSpan(ctx, `GetAllReleaseFlagStatesOfThePilot`, env, flagNames)
+	defer func() { endSpan(span, rErr) }()
+
 	states := make(map[string]bool)
 
 	for _, flagName := range flagNames {
@@ -55,10 +62,12 @@ func (manager *RolloutManager) GetAllReleaseFlagStatesOfThePilot(ctx context.Con
 
 		if p, ok := pilotsIndex[f.ID]; ok {
 			states[f.Name] = p.IsParticipating
+			manager.observeEvaluation(env, p.IsParticipating, nil)
 			continue
 		}
 
 		enrollment, err := manager.checkEnrollment(ctx, env, f, pilotExternalID, pilotsIndex)
+		manager.observeEvaluation(env, enrollment, err)
 		if err != nil {
 			return nil, err
 		}
@@ -83,7 +92,7 @@ func (manager *RolloutManager) checkEnrollment(ctx context.Context, env Environm
 		return false, nil
 	}
 
-	return rollout.Plan.IsParticipating(ctx, pilotExternalID)
+	return manager.isParticipating(ctx, rollout.Plan, pilotExternalID)
 }
 
 func (manager *RolloutManager) CreateFeatureFlag(ctx context.Context, flag *Flag) error {
@@ -105,10 +114,8 @@ func (manager *RolloutManager) CreateFeatureFlag(ctx context.Context, flag *Flag
 	}
 
 	if ff != nil {
-		//TODO: this should be handled in transaction!
-		// as mvp solution, it is acceptable for now,
-		// but spec must be moved to the storage specs as `name is uniq across entries`
-		// or transaction through context with serialization level must be used for this action
+		// the storage enforces the unique flag names as well,
+		// this check only spares a failing create in the common case.
 		return ErrFlagAlreadyExist
 	}
 
@@ -146,7 +153,7 @@ func (manager *RolloutManager) UnsetPilotEnrollmentForFeature(ctx context.Contex
 	}
 
 	if !found {
-		return fmt.Errorf(`ErrNotFound`)
+		return ErrFlagNotFound
 	}
 
 	pilot, err := manager.Storage.ReleasePilot(ctx).FindByFlagEnvPublicID(ctx, ff.ID, envID, pilotExternalID)
@@ -174,7 +181,7 @@ func (manager *RolloutManager) SetPilotEnrollmentForFeature(ctx context.Context,
 	}
 
 	if !found {
-		return fmt.Errorf(`ErrNotFound`)
+		return ErrFlagNotFound
 	}
 
 	pilot, err := manager.Storage.ReleasePilot(ctx).FindByFlagEnvPublicID(ctx, ff.ID, envID, externalPilotID)
@@ -197,7 +204,10 @@ func (manager *RolloutManager) SetPilotEnrollmentForFeature(ctx context.Context,
 
 }
 
-//TODO: make operation atomic between flags and pilots
+// DeleteFeatureFlag deletes the release flag along with its rollouts and pilots.
+// The storage rejects the deletion of a flag which still has rollouts or pilots,
+// so they are deleted one by one first, which keeps their deletion visible for the storage wrappers,
+// like the change history and the protection of the environments.
 // TODO delete ip addr allows as well
 // TODO: rename
 func (manager *RolloutManager) DeleteFeatureFlag(ctx context.Context, id string) (returnErr error) {
@@ -205,13 +215,8 @@ func (manager *RolloutManager) DeleteFeatureFlag(ctx context.Context, id string)
 	if err != nil {
 		return err
 	}
-	var noRollback bool
 	defer func() {
 		if returnErr != nil {
-			if noRollback {
-				return
-			}
-
 			_ = manager.Storage.RollbackTx(ctx)
 			return
 		}
@@ -220,7 +225,6 @@ func (manager *RolloutManager) DeleteFeatureFlag(ctx context.Context, id string)
 	}()
 
 	if id == `` {
-		noRollback = true
 		return fmt.Errorf(`entity id is empty`)
 	}
 
@@ -231,8 +235,7 @@ func (manager *RolloutManager) DeleteFeatureFlag(ctx context.Context, id string)
 		return err
 	}
 	if !found {
-		noRollback = true
-		return fmt.Errorf(`ErrNotFound`)
+		return ErrFlagNotFound
 	}
 
 	var pilots []Pilot
@@ -246,5 +249,118 @@ func (manager *RolloutManager) DeleteFeatureFlag(ctx context.Context, id string)
 		}
 	}
 
+	var rollouts []Rollout
+	if err := iterators.Collect(manager.Storage.ReleaseRollout(ctx).FindByQuery(ctx, RolloutQuery{FlagID: ff.ID}), &rollouts); err != nil {
+		return err
+	}
+
+	for _, rollout := range rollouts {
+		if err := manager.Storage.ReleaseRollout(ctx).DeleteByID(ctx, rollout.ID); err != nil {
+			return err
+		}
+	}
+
 	return manager.Storage.ReleaseFlag(ctx).DeleteByID(ctx, id)
 }
+
+type FlagPage struct {
+	Flags      []Flag
+	NextCursor string
+}
+
+// ListFlags returns a page of the release flags that match the query.
+// When NextCursor is empty, the returned page is the last one.
+func (manager *RolloutManager) ListFlags(ctx context.Context, q FlagQuery) (FlagPage, error) {
+	if err := q.Validate(); err != nil {
+		return FlagPage{}, err
+	}
+	q.Pagination = q.Pagination.withDefaults()
+	limit := q.Limit
+	q.Limit++ // look ahead to know if there is a next page
+
+	page := FlagPage{Flags: make([]Flag, 0)}
+	if err := iterators.Collect(manager.Storage.ReleaseFlag(ctx).FindByQuery(ctx, q), &page.Flags); err != nil {
+		return FlagPage{}, err
+	}
+	if limit < len(page.Flags) {
+		page.Flags = page.Flags[:limit]
+		last := page.Flags[limit-1]
+		page.NextCursor = EncodeCursor(Cursor{Key: q.SortKey(last), ID: last.ID})
+	}
+	return page, nil
+}
+
+type EnvironmentPage struct {
+	Environments []Environment
+	NextCursor   string
+}
+
+func (manager *RolloutManager) ListEnvironments(ctx context.Context, q EnvironmentQuery) (EnvironmentPage, error) {
+	if err := q.Validate(); err != nil {
+		return EnvironmentPage{}, err
+	}
+	q.Pagination = q.Pagination.withDefaults()
+	limit := q.Limit
+	q.Limit++
+
+	page := EnvironmentPage{Environments: make([]Environment, 0)}
+	if err := iterators.Collect(manager.Storage.ReleaseEnvironment(ctx).FindByQuery(ctx, q), &page.Environments); err != nil {
+		return EnvironmentPage{}, err
+	}
+	if limit < len(page.Environments) {
+		page.Environments = page.Environments[:limit]
+		last := page.Environments[limit-1]
+		page.NextCursor = EncodeCursor(Cursor{Key: q.SortKey(last), ID: last.ID})
+	}
+	return page, nil
+}
+
+type PilotPage struct {
+	Pilots     []Pilot
+	NextCursor string
+}
+
+func (manager *RolloutManager) ListPilots(ctx context.Context, q PilotQuery) (PilotPage, error) {
+	if err := q.Validate(); err != nil {
+		return PilotPage{}, err
+	}
+	q.Pagination = q.Pagination.withDefaults()
+	limit := q.Limit
+	q.Limit++
+
+	page := PilotPage{Pilots: make([]Pilot, 0)}
+	if err := iterators.Collect(manager.Storage.ReleasePilot(ctx).FindByQuery(ctx, q), &page.Pilots); err != nil {
+		return PilotPage{}, err
+	}
+	if limit < len(page.Pilots) {
+		page.Pilots = page.Pilots[:limit]
+		last := page.Pilots[limit-1]
+		page.NextCursor = EncodeCursor(Cursor{Key: q.SortKey(last), ID: last.ID})
+	}
+	return page, nil
+}
+
+type RolloutPage struct {
+	Rollouts   []Rollout
+	NextCursor string
+}
+
+func (manager *RolloutManager) ListRollouts(ctx context.Context, q RolloutQuery) (RolloutPage, error) {
+	if err := q.Validate(); err != nil {
+		return RolloutPage{}, err
+	}
+	q.Pagination = q.Pagination.withDefaults()
+	limit := q.Limit
+	q.Limit++
+
+	page := RolloutPage{Rollouts: make([]Rollout, 0)}
+	if err := iterators.Collect(manager.Storage.ReleaseRollout(ctx).FindByQuery(ctx, q), &page.Rollouts); err != nil {
+		return RolloutPage{}, err
+	}
+	if limit < len(page.Rollouts) {
+		page.Rollouts = page.Rollouts[:limit]
+		last := page.Rollouts[limit-1]
+		page.NextCursor = EncodeCursor(Cursor{Key: q.SortKey(last), ID: last.ID})
+	}
+	return page, nil
+}
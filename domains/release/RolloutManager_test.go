@@ -3,6 +3,7 @@ package release_test
 import (
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/adamluzsi/frameless/fixtures"
 	"github.com/adamluzsi/frameless/iterators"
@@ -26,6 +27,7 @@ func TestRolloutManager(t *testing.T) {
 	s.Describe(`UpdateFeatureFlag`, SpecRolloutManagerUpdateFeatureFlag)
 	s.Describe(`DeleteFeatureFlag`, SpecRolloutManagerDeleteFeatureFlag)
 	s.Describe(`ListFeatureFlags`, SpecRolloutManagerListFeatureFlags)
+	s.Describe(`ListFlags`, SpecRolloutManagerListFlags)
 
 	s.Describe(`SetPilotEnrollmentForFeature`, SpecSetPilotEnrollmentForFeature)
 	s.Describe(`UnsetPilotEnrollmentForFeature`, SpecUnsetPilotEnrollmentForFeature)
@@ -186,6 +188,18 @@ func SpecRolloutManagerDeleteFeatureFlag(s *testcase.Spec) {
 			require.False(t, found)
 		})
 
+		s.And(`there is a rollout for the feature`, func(s *testcase.Spec) {
+			s.Before(func(t *testcase.T) { sh.ExampleReleaseRollout(t) })
+
+			s.Then(`it will remove the rollout as well`, func(t *testcase.T) {
+				require.Nil(t, subject(t))
+
+				found, err := manager(t).Storage.ReleaseRollout(sh.ContextGet(t)).FindByID(sh.ContextGet(t), &release.Rollout{}, sh.ExampleReleaseRollout(t).ID)
+				require.Nil(t, err)
+				require.False(t, found)
+			})
+		})
+
 		s.And(`there are pilots manually set for the feature`, func(s *testcase.Spec) {
 			s.Before(func(t *testcase.T) { sh.ExampleReleaseManualPilotEnrollment(t) })
 
@@ -262,6 +276,75 @@ func SpecRolloutManagerListFeatureFlags(s *testcase.Spec) {
 	})
 }
 
+func SpecRolloutManagerListFlags(s *testcase.Spec) {
+	var (
+		query    = s.Let(`query`, func(t *testcase.T) interface{} { return release.FlagQuery{} })
+		queryGet = func(t *testcase.T) release.FlagQuery { return query.Get(t).(release.FlagQuery) }
+		subject  = func(t *testcase.T) (release.FlagPage, error) {
+			return manager(t).ListFlags(sh.ContextGet(t), queryGet(t))
+		}
+	)
+
+	s.Before(func(t *testcase.T) {
+		require.Nil(t, sh.StorageGet(t).ReleaseFlag(sh.ContextGet(t)).DeleteAll(sh.ContextGet(t)))
+		for _, name := range []string{`a`, `b`, `c`} {
+			require.Nil(t, manager(t).CreateFeatureFlag(sh.ContextGet(t), &release.Flag{Name: name}))
+		}
+	})
+
+	s.When(`every flag fits into a page`, func(s *testcase.Spec) {
+		s.Then(`flags are returned without a next cursor`, func(t *testcase.T) {
+			page, err := subject(t)
+			require.Nil(t, err)
+			require.Len(t, page.Flags, 3)
+			require.Empty(t, page.NextCursor)
+		})
+	})
+
+	s.When(`the limit is smaller than the number of flags`, func(s *testcase.Spec) {
+		query.Let(s, func(t *testcase.T) interface{} {
+			return release.FlagQuery{Pagination: release.Pagination{Limit: 2, SortBy: release.SortByName}}
+		})
+
+		s.Then(`the next cursor leads to the remaining flags`, func(t *testcase.T) {
+			page, err := subject(t)
+			require.Nil(t, err)
+			require.Len(t, page.Flags, 2)
+			require.NotEmpty(t, page.NextCursor)
+
+			q := queryGet(t)
+			q.Cursor = page.NextCursor
+			next, err := manager(t).ListFlags(sh.ContextGet(t), q)
+			require.Nil(t, err)
+			require.Len(t, next.Flags, 1)
+			require.Equal(t, `c`, next.Flags[0].Name)
+			require.Empty(t, next.NextCursor)
+		})
+	})
+
+	s.When(`the cursor is malformed`, func(s *testcase.Spec) {
+		query.Let(s, func(t *testcase.T) interface{} {
+			return release.FlagQuery{Pagination: release.Pagination{Cursor: `not a cursor`}}
+		})
+
+		s.Then(`it yields invalid cursor error`, func(t *testcase.T) {
+			_, err := subject(t)
+			require.Equal(t, release.ErrInvalidCursor, err)
+		})
+	})
+
+	s.When(`the sort field is unknown`, func(s *testcase.Spec) {
+		query.Let(s, func(t *testcase.T) interface{} {
+			return release.FlagQuery{Pagination: release.Pagination{SortBy: `plan`}}
+		})
+
+		s.Then(`it yields invalid sort field error`, func(t *testcase.T) {
+			_, err := subject(t)
+			require.Equal(t, release.ErrInvalidSortField, err)
+		})
+	})
+}
+
 func SpecSetPilotEnrollmentForFeature(s *testcase.Spec) {
 	getNewEnrollment := func(t *testcase.T) bool {
 		return t.I(`new enrollment`).(bool)
@@ -320,6 +403,7 @@ func SpecSetPilotEnrollmentForFeature(s *testcase.Spec) {
 
 				actualPilot := *pilot
 				actualPilot.ID = ``
+				actualPilot.Revision = 0
 
 				require.Equal(t, expectedPilot, actualPilot)
 
@@ -468,6 +552,20 @@ func SpecRolloutManagerGetAllReleaseFlagStatesOfThePilot(s *testcase.Spec) {
 					require.True(t, ok)
 				})
 
+				s.And(`an observer is set`, func(s *testcase.Spec) {
+					s.Let(`observer`, func(t *testcase.T) interface{} { return &spyObserver{} })
+					s.Before(func(t *testcase.T) { manager(t).Observer = t.I(`observer`).(*spyObserver) })
+
+					s.Then(`the evaluation and the rollout plan evaluation is reported`, func(t *testcase.T) {
+						_, err := subject(t)
+						require.Nil(t, err)
+
+						o := t.I(`observer`).(*spyObserver)
+						require.Equal(t, []release.EvaluationResult{release.EvaluationResultEnabled}, o.evaluations)
+						require.Equal(t, []string{`percentage`}, o.planTypes)
+					})
+				})
+
 				s.Context(`but manual pilot config prevents participation for the given pilot`, func(s *testcase.Spec) {
 					sh.AndExamplePilotManualParticipatingIsSetTo(s, false)
 
@@ -558,3 +656,18 @@ func SpecRolloutManagerGetAllReleaseFlagStatesOfThePilot(s *testcase.Spec) {
 func manager(t *testcase.T) *release.RolloutManager {
 	return t.I(`manager`).(*release.RolloutManager)
 }
+
+type spyObserver struct {
+	evaluations []release.EvaluationResult
+	planTypes   []string
+}
+
+func (o *spyObserver) ObserveEvaluation(env release.Environment, result release.EvaluationResult) {
+	o.evaluations = append(o.evaluations, result)
+}
+
+func (o *spyObserver) ObservePlanEvaluation(planType string, d time.Duration) {
+	o.planTypes = append(o.planTypes, planType)
+}
+
+func (o *spyObserver) ObserveDecisionByAPI(outcome release.EvaluationResult, d time.Duration) {}
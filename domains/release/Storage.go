@@ -13,12 +13,15 @@ type Storage interface {
 	ReleasePilot(context.Context) PilotStorage
 	ReleaseRollout(context.Context) RolloutStorage
 	ReleaseEnvironment(context.Context) EnvironmentStorage
+	ReleaseVersion(context.Context) VersionStorage
 }
 
 type (
-	PilotEntries   = iterators.Interface
-	FlagEntries    = iterators.Interface
-	RolloutEntries = iterators.Interface
+	PilotEntries       = iterators.Interface
+	FlagEntries        = iterators.Interface
+	RolloutEntries     = iterators.Interface
+	EnvironmentEntries = iterators.Interface
+	VersionEntries     = iterators.Interface
 )
 
 type FlagStorage interface {
@@ -31,6 +34,9 @@ type FlagStorage interface {
 	frameless.DeleterPublisher
 	FindByName(ctx context.Context, name string) (*Flag, error)
 	FindByNames(ctx context.Context, names ...string) FlagEntries
+	// FindByQuery returns the flags that match the query filters,
+	// ordered by the requested sort field and positioned after the query cursor.
+	FindByQuery(ctx context.Context, q FlagQuery) FlagEntries
 }
 
 type PilotStorage interface {
@@ -44,6 +50,7 @@ type PilotStorage interface {
 	FindByFlagEnvPublicID(ctx context.Context, flagID, envID interface{}, publicID string) (*Pilot, error)
 	FindByFlag(ctx context.Context, flag Flag) PilotEntries
 	FindByPublicID(ctx context.Context, publicID string) PilotEntries
+	FindByQuery(ctx context.Context, q PilotQuery) PilotEntries
 }
 
 type RolloutStorage interface {
@@ -55,6 +62,7 @@ type RolloutStorage interface {
 	frameless.UpdaterPublisher
 	frameless.DeleterPublisher
 	FindByFlagEnvironment(context.Context, Flag, Environment, *Rollout) (bool, error)
+	FindByQuery(ctx context.Context, q RolloutQuery) RolloutEntries
 
 	// TODO:
 	//FindReleaseRolloutsByDeploymentEnvironment(context.Context, deployment.Environment, *Rollout) (bool, error)
@@ -69,4 +77,16 @@ type EnvironmentStorage /* Environment */ interface {
 	frameless.UpdaterPublisher
 	frameless.DeleterPublisher
 	FindByAlias(ctx context.Context, idOrName string, env *Environment) (bool, error)
+	FindByQuery(ctx context.Context, q EnvironmentQuery) EnvironmentEntries
+}
+
+// VersionStorage holds the history of the release flags, rollouts and pilots.
+// The versions are only created and never updated.
+type VersionStorage interface {
+	frameless.Creator
+	frameless.Finder
+	frameless.Deleter
+	// FindByQuery returns the versions that match the query,
+	// ordered by entity ID and then by version number.
+	FindByQuery(ctx context.Context, q VersionQuery) VersionEntries
 }
@@ -0,0 +1,153 @@
+package release
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// The kinds of release entities that have versioned history.
+const (
+	VersionKindFlag    = `flag`
+	VersionKindRollout = `rollout`
+	VersionKindPilot   = `pilot`
+)
+
+// The actions that can create a new version of an entity.
+const (
+	VersionActionCreate = `create`
+	VersionActionUpdate = `update`
+	VersionActionDelete = `delete`
+)
+
+// Version is a snapshot of a release flag, rollout or pilot, taken each time the entity is changed.
+// The versions are never changed, so they form the history of the entity.
+type Version struct {
+	ID string `ext:"ID" json:"id"`
+	// Kind tells the type of the entity, e.g. rollout.
+	Kind string `json:"kind"`
+	// EntityID is the ID of the versioned entity.
+	EntityID string `json:"entity_id"`
+	// Number is incremented with each change of the entity, starting from 1.
+	Number int `json:"number"`
+	// Action is the change that made this version.
+	Action string `json:"action"`
+	// Snapshot is the JSON encoded state of the entity after the change.
+	// In case of a delete, it holds the last state of the entity.
+	Snapshot json.RawMessage `json:"snapshot"`
+	// CreatedAt is the time of the change.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// VersionQuery selects the versions of an entity, or every version of an entity kind when EntityID is empty.
+type VersionQuery struct {
+	Kind     string
+	EntityID string
+}
+
+func (q VersionQuery) Match(v Version) bool {
+	if q.Kind != `` && q.Kind != v.Kind {
+		return false
+	}
+	if q.EntityID != `` && q.EntityID != v.EntityID {
+		return false
+	}
+	return true
+}
+
+// Less tells the order of the versions, which is by entity, then by version number.
+func (q VersionQuery) Less(a, b Version) bool {
+	if a.EntityID != b.EntityID {
+		return a.EntityID < b.EntityID
+	}
+	return a.Number < b.Number
+}
+
+// IsDeleted tells if the entity no longer existed after the change of this version.
+func (v Version) IsDeleted() bool {
+	return v.Action == VersionActionDelete
+}
+
+// Decode unmarshal the snapshot of the version into the entity pointer.
+func (v Version) Decode(ptr interface{}) error {
+	return json.Unmarshal(v.Snapshot, ptr)
+}
+
+// VersionChange is a field level difference between two versions of an entity.
+type VersionChange struct {
+	// Path is the dot separated path of the changed field in the entity snapshot, e.g. plan.percentage.
+	Path string      `json:"path"`
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// DiffVersions compares the state of the entity between two of its versions.
+// A deleted version is compared as an entity without any field.
+// The changes are ordered by their path.
+func DiffVersions(from, to Version) ([]VersionChange, error) {
+	if from.Kind != to.Kind || from.EntityID != to.EntityID {
+		return nil, ErrVersionEntityMismatch
+	}
+
+	fromFields, err := from.fields()
+	if err != nil {
+		return nil, err
+	}
+	toFields, err := to.fields()
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]VersionChange, 0)
+	for path, fromValue := range fromFields {
+		toValue, ok := toFields[path]
+		if !ok || !reflect.DeepEqual(fromValue, toValue) {
+			changes = append(changes, VersionChange{Path: path, From: fromValue, To: toValue})
+		}
+	}
+	for path, toValue := range toFields {
+		if _, ok := fromFields[path]; !ok {
+			changes = append(changes, VersionChange{Path: path, To: toValue})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// fields flattens the snapshot into a path to value mapping.
+func (v Version) fields() (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	if v.IsDeleted() {
+		return fields, nil
+	}
+
+	var snapshot interface{}
+	if err := json.Unmarshal(v.Snapshot, &snapshot); err != nil {
+		return nil, err
+	}
+
+	var flatten func(path string, value interface{})
+	flatten = func(path string, value interface{}) {
+		switch value := value.(type) {
+		case map[string]interface{}:
+			for key, sub := range value {
+				if path == `` {
+					flatten(key, sub)
+				} else {
+					flatten(path+`.`+key, sub)
+				}
+			}
+		case []interface{}:
+			for i, sub := range value {
+				flatten(fmt.Sprintf(`%s[%d]`, path, i), sub)
+			}
+		default:
+			fields[path] = value
+		}
+	}
+	flatten(``, snapshot)
+	return fields, nil
+}
@@ -0,0 +1,325 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/extid"
+	"github.com/adamluzsi/frameless/iterators"
+)
+
+// Versioning records a new Version of an entity with each of its changes,
+// in the same transaction as the change itself.
+type Versioning struct {
+	Storage Storage
+}
+
+type versionedEntityStorage interface {
+	frameless.Creator
+	frameless.Finder
+	frameless.Updater
+	frameless.Deleter
+}
+
+func (v Versioning) create(ctx context.Context, kind string, s versionedEntityStorage, ptr interface{}) (rErr error) {
+	ctx, err := v.Storage.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer frameless.FinishOnePhaseCommit(&rErr, v.Storage, ctx)
+
+	if err := s.Create(ctx, ptr); err != nil {
+		return err
+	}
+	return v.record(ctx, kind, VersionActionCreate, ptr)
+}
+
+func (v Versioning) update(ctx context.Context, kind string, s versionedEntityStorage, ptr interface{}) (rErr error) {
+	ctx, err := v.Storage.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer frameless.FinishOnePhaseCommit(&rErr, v.Storage, ctx)
+
+	if err := s.Update(ctx, ptr); err != nil {
+		return err
+	}
+	return v.record(ctx, kind, VersionActionUpdate, ptr)
+}
+
+func (v Versioning) deleteByID(ctx context.Context, kind string, T frameless.T, s versionedEntityStorage, id interface{}) (rErr error) {
+	ctx, err := v.Storage.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer frameless.FinishOnePhaseCommit(&rErr, v.Storage, ctx)
+
+	ptr := reflect.New(reflect.TypeOf(T)).Interface()
+	found, err := s.FindByID(ctx, ptr, id)
+	if err != nil {
+		return err
+	}
+	if err := s.DeleteByID(ctx, id); err != nil {
+		return err
+	}
+	if !found {
+		return nil
+	}
+	return v.record(ctx, kind, VersionActionDelete, ptr)
+}
+
+func (v Versioning) deleteAll(ctx context.Context, kind string, T frameless.T, s versionedEntityStorage) (rErr error) {
+	ctx, err := v.Storage.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer frameless.FinishOnePhaseCommit(&rErr, v.Storage, ctx)
+
+	var ptrs []interface{}
+	iter := s.FindAll(ctx)
+	for iter.Next() {
+		ptr := reflect.New(reflect.TypeOf(T)).Interface()
+		if err := iter.Decode(ptr); err != nil {
+			_ = iter.Close()
+			return err
+		}
+		ptrs = append(ptrs, ptr)
+	}
+	if err := iter.Err(); err != nil {
+		_ = iter.Close()
+		return err
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	if err := s.DeleteAll(ctx); err != nil {
+		return err
+	}
+	for _, ptr := range ptrs {
+		if err := v.record(ctx, kind, VersionActionDelete, ptr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v Versioning) record(ctx context.Context, kind, action string, ptr interface{}) error {
+	id, ok := extid.Lookup(ptr)
+	if !ok {
+		return fmt.Errorf(`%T entity has no ID`, ptr)
+	}
+	entityID := fmt.Sprint(id)
+
+	snapshot, err := json.Marshal(ptr)
+	if err != nil {
+		return err
+	}
+
+	number := 0
+	if err := iterators.ForEach(v.Storage.ReleaseVersion(ctx).FindByQuery(ctx, VersionQuery{Kind: kind, EntityID: entityID}), func(version Version) error {
+		if number < version.Number {
+			number = version.Number
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return v.Storage.ReleaseVersion(ctx).Create(ctx, &Version{
+		Kind:      kind,
+		EntityID:  entityID,
+		Number:    number + 1,
+		Action:    action,
+		Snapshot:  snapshot,
+		CreatedAt: time.Now().UTC(),
+	})
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+// VersionedFlagStorage records the changes of the release flags as versions.
+type VersionedFlagStorage struct {
+	FlagStorage
+	Versioning Versioning
+}
+
+func (s VersionedFlagStorage) Create(ctx context.Context, ptr interface{}) error {
+	return s.Versioning.create(ctx, VersionKindFlag, s.FlagStorage, ptr)
+}
+
+func (s VersionedFlagStorage) Update(ctx context.Context, ptr interface{}) error {
+	return s.Versioning.update(ctx, VersionKindFlag, s.FlagStorage, ptr)
+}
+
+func (s VersionedFlagStorage) DeleteByID(ctx context.Context, id interface{}) error {
+	return s.Versioning.deleteByID(ctx, VersionKindFlag, Flag{}, s.FlagStorage, id)
+}
+
+func (s VersionedFlagStorage) DeleteAll(ctx context.Context) error {
+	return s.Versioning.deleteAll(ctx, VersionKindFlag, Flag{}, s.FlagStorage)
+}
+
+// VersionedRolloutStorage records the changes of the release rollouts as versions.
+type VersionedRolloutStorage struct {
+	RolloutStorage
+	Versioning Versioning
+}
+
+func (s VersionedRolloutStorage) Create(ctx context.Context, ptr interface{}) error {
+	return s.Versioning.create(ctx, VersionKindRollout, s.RolloutStorage, ptr)
+}
+
+func (s VersionedRolloutStorage) Update(ctx context.Context, ptr interface{}) error {
+	return s.Versioning.update(ctx, VersionKindRollout, s.RolloutStorage, ptr)
+}
+
+func (s VersionedRolloutStorage) DeleteByID(ctx context.Context, id interface{}) error {
+	return s.Versioning.deleteByID(ctx, VersionKindRollout, Rollout{}, s.RolloutStorage, id)
+}
+
+func (s VersionedRolloutStorage) DeleteAll(ctx context.Context) error {
+	return s.Versioning.deleteAll(ctx, VersionKindRollout, Rollout{}, s.RolloutStorage)
+}
+
+// VersionedPilotStorage records the changes of the pilots as versions.
+type VersionedPilotStorage struct {
+	PilotStorage
+	Versioning Versioning
+}
+
+func (s VersionedPilotStorage) Create(ctx context.Context, ptr interface{}) error {
+	return s.Versioning.create(ctx, VersionKindPilot, s.PilotStorage, ptr)
+}
+
+func (s VersionedPilotStorage) Update(ctx context.Context, ptr interface{}) error {
+	return s.Versioning.update(ctx, VersionKindPilot, s.PilotStorage, ptr)
+}
+
+func (s VersionedPilotStorage) DeleteByID(ctx context.Context, id interface{}) error {
+	return s.Versioning.deleteByID(ctx, VersionKindPilot, Pilot{}, s.PilotStorage, id)
+}
+
+func (s VersionedPilotStorage) DeleteAll(ctx context.Context) error {
+	return s.Versioning.deleteAll(ctx, VersionKindPilot, Pilot{}, s.PilotStorage)
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+func (manager *RolloutManager) versionedStorage(ctx context.Context, kind string) (versionedEntityStorage, frameless.T, error) {
+	switch kind {
+	case VersionKindFlag:
+		return manager.Storage.ReleaseFlag(ctx), Flag{}, nil
+	case VersionKindRollout:
+		return manager.Storage.ReleaseRollout(ctx), Rollout{}, nil
+	case VersionKindPilot:
+		return manager.Storage.ReleasePilot(ctx), Pilot{}, nil
+	default:
+		return nil, nil, ErrInvalidVersionKind
+	}
+}
+
+// ListVersions returns the history of an entity, ordered by version number.
+func (manager *RolloutManager) ListVersions(ctx context.Context, kind, entityID string) ([]Version, error) {
+	if _, _, err := manager.versionedStorage(ctx, kind); err != nil {
+		return nil, err
+	}
+	versions := make([]Version, 0)
+	err := iterators.Collect(manager.Storage.ReleaseVersion(ctx).FindByQuery(ctx, VersionQuery{Kind: kind, EntityID: entityID}), &versions)
+	return versions, err
+}
+
+// FindVersion looks up a version by its ID.
+func (manager *RolloutManager) FindVersion(ctx context.Context, versionID string) (Version, error) {
+	var version Version
+	found, err := manager.Storage.ReleaseVersion(ctx).FindByID(ctx, &version, versionID)
+	if err != nil {
+		return Version{}, err
+	}
+	if !found {
+		return Version{}, ErrVersionNotFound
+	}
+	return version, nil
+}
+
+// RevertToVersion restores the entity to the state of the given version.
+// The revert is a change itself, so it is recorded as a new version, and the history is kept intact.
+// Reverting to a delete version deletes the entity,
+// while reverting a deleted entity to an earlier version creates it again with its original ID.
+func (manager *RolloutManager) RevertToVersion(ctx context.Context, versionID string) (rErr error) {
+	version, err := manager.FindVersion(ctx, versionID)
+	if err != nil {
+		return err
+	}
+
+	s, T, err := manager.versionedStorage(ctx, version.Kind)
+	if err != nil {
+		return err
+	}
+
+	ctx, err = manager.Storage.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer frameless.FinishOnePhaseCommit(&rErr, manager.Storage, ctx)
+
+	current := reflect.New(reflect.TypeOf(T)).Interface()
+	exists, err := s.FindByID(ctx, current, version.EntityID)
+	if err != nil {
+		return err
+	}
+
+	if version.IsDeleted() {
+		if !exists {
+			return nil
+		}
+		return s.DeleteByID(ctx, version.EntityID)
+	}
+
+	ptr := reflect.New(reflect.TypeOf(T)).Interface()
+	if err := version.Decode(ptr); err != nil {
+		return err
+	}
+	if exists {
+		// the revert overwrites the current state on purpose, so it updates the current revision.
+		revision, _ := RevisionOf(ptr)
+		currentRevision, _ := RevisionOf(current)
+		*revision = *currentRevision
+		return s.Update(ctx, ptr)
+	}
+	return s.Create(ctx, ptr)
+}
+
+// entitiesAsOf reconstructs the state of an entity kind at the given time from the latest version of each entity.
+func (manager *RolloutManager) entitiesAsOf(ctx context.Context, kind string, asOf time.Time, fn func(Version) error) error {
+	latest := make(map[string]Version)
+	var order []string
+	if err := iterators.ForEach(manager.Storage.ReleaseVersion(ctx).FindByQuery(ctx, VersionQuery{Kind: kind}), func(v Version) error {
+		if v.CreatedAt.After(asOf) {
+			return nil
+		}
+		prev, ok := latest[v.EntityID]
+		if !ok {
+			order = append(order, v.EntityID)
+		}
+		if !ok || prev.Number < v.Number {
+			latest[v.EntityID] = v
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, entityID := range order {
+		if v := latest[entityID]; !v.IsDeleted() {
+			if err := fn(v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
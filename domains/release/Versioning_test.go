@@ -0,0 +1,176 @@
+package release_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/toggler"
+	sh "github.com/toggler-io/toggler/spechelper"
+)
+
+func TestRolloutManager_versioning(t *testing.T) {
+	s := sh.NewSpec(t)
+
+	s.Let(`manager`, func(t *testcase.T) interface{} {
+		return &release.RolloutManager{Storage: toggler.NewVersionedStorage(sh.StorageGet(t))}
+	})
+
+	s.Before(func(t *testcase.T) {
+		ctx := sh.ContextGet(t)
+		storage := sh.StorageGet(t)
+		require.Nil(t, storage.ReleaseVersion(ctx).DeleteAll(ctx))
+		require.Nil(t, storage.ReleaseRollout(ctx).DeleteAll(ctx))
+		require.Nil(t, storage.ReleaseFlag(ctx).DeleteAll(ctx))
+	})
+
+	var (
+		flag = s.Let(`flag`, func(t *testcase.T) interface{} {
+			flag := &release.Flag{Name: `new-checkout`}
+			require.Nil(t, manager(t).CreateFeatureFlag(sh.ContextGet(t), flag))
+			return flag
+		})
+		flagGet = func(t *testcase.T) *release.Flag { return flag.Get(t).(*release.Flag) }
+		rollout = s.Let(`rollout`, func(t *testcase.T) interface{} {
+			rollout := &release.Rollout{
+				FlagID:        flagGet(t).ID,
+				EnvironmentID: sh.ExampleDeploymentEnvironment(t).ID,
+				Plan:          release.RolloutDecisionByPercentage{Percentage: 10, PseudoRandPercentageAlgorithm: `FNV1a64`},
+			}
+			require.Nil(t, manager(t).Storage.ReleaseRollout(sh.ContextGet(t)).Create(sh.ContextGet(t), rollout))
+			return rollout
+		})
+		rolloutGet    = func(t *testcase.T) *release.Rollout { return rollout.Get(t).(*release.Rollout) }
+		updateRollout = func(t *testcase.T, percentage int) {
+			r := *rolloutGet(t)
+			r.Plan = release.RolloutDecisionByPercentage{Percentage: percentage, PseudoRandPercentageAlgorithm: `FNV1a64`}
+			require.Nil(t, manager(t).Storage.ReleaseRollout(sh.ContextGet(t)).Update(sh.ContextGet(t), &r))
+		}
+		versions = func(t *testcase.T) []release.Version {
+			vs, err := manager(t).ListVersions(sh.ContextGet(t), release.VersionKindRollout, rolloutGet(t).ID)
+			require.Nil(t, err)
+			return vs
+		}
+	)
+
+	s.Describe(`ListVersions`, func(s *testcase.Spec) {
+		s.Then(`each change of the rollout is a new version`, func(t *testcase.T) {
+			updateRollout(t, 50)
+			require.Nil(t, manager(t).Storage.ReleaseRollout(sh.ContextGet(t)).DeleteByID(sh.ContextGet(t), rolloutGet(t).ID))
+
+			vs := versions(t)
+			require.Len(t, vs, 3)
+			for i, action := range []string{release.VersionActionCreate, release.VersionActionUpdate, release.VersionActionDelete} {
+				require.Equal(t, i+1, vs[i].Number)
+				require.Equal(t, action, vs[i].Action)
+			}
+
+			var r release.Rollout
+			require.Nil(t, vs[1].Decode(&r))
+			require.Equal(t, 50, r.Plan.(release.RolloutDecisionByPercentage).Percentage)
+		})
+
+		s.Then(`the flags are versioned as well`, func(t *testcase.T) {
+			vs, err := manager(t).ListVersions(sh.ContextGet(t), release.VersionKindFlag, flagGet(t).ID)
+			require.Nil(t, err)
+			require.Len(t, vs, 1)
+		})
+
+		s.When(`the kind is unknown`, func(s *testcase.Spec) {
+			s.Then(`it yields error`, func(t *testcase.T) {
+				_, err := manager(t).ListVersions(sh.ContextGet(t), `environment`, rolloutGet(t).ID)
+				require.Equal(t, release.ErrInvalidVersionKind, err)
+			})
+		})
+	})
+
+	s.Describe(`DiffVersions`, func(s *testcase.Spec) {
+		s.Then(`it lists the changed fields`, func(t *testcase.T) {
+			updateRollout(t, 50)
+			vs := versions(t)
+			changes, err := release.DiffVersions(vs[0], vs[1])
+			require.Nil(t, err)
+			require.Equal(t, []release.VersionChange{
+				{Path: `plan.percentage`, From: float64(10), To: float64(50)},
+				{Path: `revision`, From: float64(1), To: float64(2)},
+			}, changes)
+		})
+
+		s.When(`the versions belong to different entities`, func(s *testcase.Spec) {
+			s.Then(`it yields error`, func(t *testcase.T) {
+				_, err := release.DiffVersions(versions(t)[0], release.Version{Kind: release.VersionKindRollout, EntityID: `other`})
+				require.Equal(t, release.ErrVersionEntityMismatch, err)
+			})
+		})
+	})
+
+	s.Describe(`RevertToVersion`, func(s *testcase.Spec) {
+		s.Then(`the rollout is restored, and the revert is recorded as a new version`, func(t *testcase.T) {
+			ctx := sh.ContextGet(t)
+			updateRollout(t, 50)
+			require.Nil(t, manager(t).RevertToVersion(ctx, versions(t)[0].ID))
+
+			var r release.Rollout
+			found, err := manager(t).Storage.ReleaseRollout(ctx).FindByID(ctx, &r, rolloutGet(t).ID)
+			require.Nil(t, err)
+			require.True(t, found)
+			require.Equal(t, 10, r.Plan.(release.RolloutDecisionByPercentage).Percentage)
+			require.Len(t, versions(t), 3)
+		})
+
+		s.When(`the rollout was deleted since`, func(s *testcase.Spec) {
+			s.Then(`it is created again with its original ID`, func(t *testcase.T) {
+				ctx := sh.ContextGet(t)
+				require.Nil(t, manager(t).Storage.ReleaseRollout(ctx).DeleteByID(ctx, rolloutGet(t).ID))
+				require.Nil(t, manager(t).RevertToVersion(ctx, versions(t)[0].ID))
+
+				found, err := manager(t).Storage.ReleaseRollout(ctx).FindByID(ctx, &release.Rollout{}, rolloutGet(t).ID)
+				require.Nil(t, err)
+				require.True(t, found)
+			})
+		})
+
+		s.When(`the version is unknown`, func(s *testcase.Spec) {
+			s.Then(`it yields error`, func(t *testcase.T) {
+				require.Equal(t, release.ErrVersionNotFound, manager(t).RevertToVersion(sh.ContextGet(t), `42`))
+			})
+		})
+	})
+
+	s.Describe(`EvaluateFlagsAsOf`, func(s *testcase.Spec) {
+		s.Then(`the flags are evaluated with the rollouts of the given time`, func(t *testcase.T) {
+			ctx := sh.ContextGet(t)
+			env := *sh.ExampleDeploymentEnvironment(t)
+			r := release.Rollout{FlagID: flagGet(t).ID, EnvironmentID: env.ID, Plan: release.RolloutDecisionByGlobal{State: true}}
+			require.Nil(t, manager(t).Storage.ReleaseRollout(ctx).Create(ctx, &r))
+			asOf := time.Now().UTC()
+			time.Sleep(time.Millisecond)
+			r.Plan = release.RolloutDecisionByGlobal{State: false}
+			require.Nil(t, manager(t).Storage.ReleaseRollout(ctx).Update(ctx, &r))
+
+			evaluations, err := manager(t).EvaluateFlagsAsOf(ctx, asOf, `alice`, env, flagGet(t).Name)
+			require.Nil(t, err)
+			require.Len(t, evaluations, 1)
+			require.True(t, evaluations[0].State)
+			require.Equal(t, release.EvaluationReasonStatic, evaluations[0].Reason)
+
+			evaluations, err = manager(t).EvaluateFlagsAsOf(ctx, time.Now().UTC(), `alice`, env, flagGet(t).Name)
+			require.Nil(t, err)
+			require.Len(t, evaluations, 1)
+			require.False(t, evaluations[0].State)
+		})
+
+		s.Then(`the flags created after the given time are not evaluated`, func(t *testcase.T) {
+			asOf := time.Now().UTC()
+			time.Sleep(time.Millisecond)
+			flagGet(t) // create
+
+			evaluations, err := manager(t).EvaluateFlagsAsOf(sh.ContextGet(t), asOf, `alice`, *sh.ExampleDeploymentEnvironment(t))
+			require.Nil(t, err)
+			require.Empty(t, evaluations)
+		})
+	})
+}
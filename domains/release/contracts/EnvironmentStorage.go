@@ -8,6 +8,7 @@ import (
 
 	"github.com/adamluzsi/frameless"
 	"github.com/adamluzsi/frameless/contracts"
+	"github.com/adamluzsi/frameless/iterators"
 	"github.com/adamluzsi/testcase"
 	"github.com/adamluzsi/testcase/fixtures"
 	"github.com/stretchr/testify/require"
@@ -74,6 +75,13 @@ func (c EnvironmentStorage) Spec(s *testcase.Spec) {
 			Context:        c.Context,
 			FixtureFactory: c.FixtureFactory,
 		},
+		Revision{T: T,
+			Subject: func(tb testing.TB) contracts.UpdaterSubject {
+				return getEnvironmentStorage(tb)
+			},
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
 		contracts.Deleter{T: T,
 			Subject: func(tb testing.TB) contracts.CRD {
 				return getEnvironmentStorage(tb)
@@ -99,6 +107,70 @@ func (c EnvironmentStorage) Spec(s *testcase.Spec) {
 	)
 
 	s.Describe(`.FindDeploymentEnvironmentByAlias`, c.specFindDeploymentEnvironmentByAlias)
+	s.Describe(`.FindByQuery`, c.specFindByQuery)
+}
+
+func (c EnvironmentStorage) specFindByQuery(s *testcase.Spec) {
+	var (
+		query    = s.Let(`query`, func(t *testcase.T) interface{} { return release.EnvironmentQuery{} })
+		queryGet = func(t *testcase.T) release.EnvironmentQuery { return query.Get(t).(release.EnvironmentQuery) }
+		subject  = func(t *testcase.T) []string {
+			var envs []release.Environment
+			iter := c.storageGet(t).ReleaseEnvironment(c.Context(t)).FindByQuery(c.Context(t), queryGet(t))
+			require.Nil(t, iterators.Collect(iter, &envs))
+			var names []string
+			for _, env := range envs {
+				names = append(names, env.Name)
+			}
+			return names
+		}
+	)
+
+	s.Before(func(t *testcase.T) {
+		ctx := c.Context(t)
+		storage := c.storageGet(t).ReleaseEnvironment(ctx)
+		contracts.DeleteAllEntity(t, storage, ctx)
+		for _, name := range []string{`staging`, `production`, `stage-eu`, `development`} {
+			var env = release.Environment{Name: name}
+			contracts.CreateEntity(t, storage, ctx, &env)
+		}
+	})
+
+	s.When(`name prefix is given`, func(s *testcase.Spec) {
+		query.Let(s, func(t *testcase.T) interface{} {
+			return release.EnvironmentQuery{NamePrefix: `stag`}
+		})
+
+		s.Then(`only the environments with the matching name returned`, func(t *testcase.T) {
+			require.ElementsMatch(t, []string{`staging`, `stage-eu`}, subject(t))
+		})
+	})
+
+	s.When(`sorted by name with a limit`, func(s *testcase.Spec) {
+		query.Let(s, func(t *testcase.T) interface{} {
+			return release.EnvironmentQuery{Pagination: release.Pagination{SortBy: release.SortByName, Limit: 3}}
+		})
+
+		s.Then(`the first page returned in name order`, func(t *testcase.T) {
+			require.Equal(t, []string{`development`, `production`, `stage-eu`}, subject(t))
+		})
+
+		s.And(`cursor points to the last entry of the first page`, func(s *testcase.Spec) {
+			query.Let(s, func(t *testcase.T) interface{} {
+				var env release.Environment
+				found, err := c.storageGet(t).ReleaseEnvironment(c.Context(t)).FindByAlias(c.Context(t), `stage-eu`, &env)
+				require.Nil(t, err)
+				require.True(t, found)
+				q := release.EnvironmentQuery{Pagination: release.Pagination{SortBy: release.SortByName, Limit: 3}}
+				q.Cursor = release.EncodeCursor(release.Cursor{Key: q.SortKey(env), ID: env.ID})
+				return q
+			})
+
+			s.Then(`the remaining entries returned`, func(t *testcase.T) {
+				require.Equal(t, []string{`staging`}, subject(t))
+			})
+		})
+	})
 }
 
 func (c EnvironmentStorage) specFindDeploymentEnvironmentByAlias(s *testcase.Spec) {
@@ -85,6 +85,13 @@ func (c FlagStorage) Spec(s *testcase.Spec) {
 			Context:        c.Context,
 			FixtureFactory: c.FixtureFactory,
 		},
+		Revision{T: T,
+			Subject: func(tb testing.TB) contracts.UpdaterSubject {
+				return newStorage(tb)
+			},
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
 		contracts.Deleter{T: T,
 			Subject: func(tb testing.TB) contracts.CRD {
 				return newStorage(tb)
@@ -134,6 +141,19 @@ func (c FlagStorage) specFlagIsUniq(s *testcase.Spec) {
 		s.Then(`saving again will create error`, func(t *testcase.T) {
 			require.Error(t, subject(t))
 		})
+
+		s.Then(`saving an other flag with the same name will create error`, func(t *testcase.T) {
+			other := release.Flag{Name: flag.Get(t).(*release.Flag).Name}
+			require.Equal(t, release.ErrFlagAlreadyExist, c.storageGet(t).Create(c.Context(t), &other))
+		})
+
+		s.Then(`renaming an other flag to the same name will create error`, func(t *testcase.T) {
+			other := release.Flag{Name: `my-other-flag-name`}
+			contracts.CreateEntity(t, c.storageGet(t), c.Context(t), &other)
+
+			other.Name = flag.Get(t).(*release.Flag).Name
+			require.Equal(t, release.ErrFlagAlreadyExist, c.storageGet(t).Update(c.Context(t), &other))
+		})
 	})
 }
 
@@ -180,6 +200,87 @@ func (c FlagFinder) Spec(s *testcase.Spec) {
 	c.cleanup(s)
 	s.Describe(`.FindReleaseFlagByName`, c.specFindReleaseFlagByName)
 	s.Describe(`.FindReleaseFlagsByName`, c.specFindReleaseFlagsByName)
+	s.Describe(`.FindByQuery`, c.specFindByQuery)
+}
+
+func (c FlagFinder) specFindByQuery(s *testcase.Spec) {
+	var (
+		query    = s.Let(`query`, func(t *testcase.T) interface{} { return release.FlagQuery{} })
+		queryGet = func(t *testcase.T) release.FlagQuery { return query.Get(t).(release.FlagQuery) }
+		subject  = func(t *testcase.T) []release.Flag {
+			var flags []release.Flag
+			require.Nil(t, iterators.Collect(c.storageGet(t).FindByQuery(c.Context(t), queryGet(t)), &flags))
+			return flags
+		}
+		namesOf = func(flags []release.Flag) []string {
+			var names []string
+			for _, f := range flags {
+				names = append(names, f.Name)
+			}
+			return names
+		}
+	)
+
+	s.Before(func(t *testcase.T) {
+		contracts.DeleteAllEntity(t, c.storageGet(t), c.Context(t))
+		for _, name := range []string{`beta-b`, `alpha-a`, `beta-a`, `alpha-b`, `gamma`} {
+			var flag = release.Flag{Name: name}
+			contracts.CreateEntity(t, c.storageGet(t), c.Context(t), &flag)
+		}
+	})
+
+	s.Then(`without filters it returns every flag`, func(t *testcase.T) {
+		require.Len(t, subject(t), 5)
+	})
+
+	s.When(`name prefix is given`, func(s *testcase.Spec) {
+		query.Let(s, func(t *testcase.T) interface{} {
+			return release.FlagQuery{NamePrefix: `beta`}
+		})
+
+		s.Then(`only the flags with the matching name returned`, func(t *testcase.T) {
+			require.ElementsMatch(t, []string{`beta-a`, `beta-b`}, namesOf(subject(t)))
+		})
+	})
+
+	s.When(`sorted by name`, func(s *testcase.Spec) {
+		query.Let(s, func(t *testcase.T) interface{} {
+			return release.FlagQuery{Pagination: release.Pagination{SortBy: release.SortByName}}
+		})
+
+		s.Then(`flags are returned in ascending name order`, func(t *testcase.T) {
+			require.Equal(t, []string{`alpha-a`, `alpha-b`, `beta-a`, `beta-b`, `gamma`}, namesOf(subject(t)))
+		})
+
+		s.And(`in descending order`, func(s *testcase.Spec) {
+			query.Let(s, func(t *testcase.T) interface{} {
+				return release.FlagQuery{Pagination: release.Pagination{SortBy: release.SortByName, Descending: true}}
+			})
+
+			s.Then(`flags are returned in descending name order`, func(t *testcase.T) {
+				require.Equal(t, []string{`gamma`, `beta-b`, `beta-a`, `alpha-b`, `alpha-a`}, namesOf(subject(t)))
+			})
+		})
+
+		s.And(`limit and cursor are used to page through the results`, func(s *testcase.Spec) {
+			s.Then(`every flag is visited exactly once in order`, func(t *testcase.T) {
+				q := release.FlagQuery{Pagination: release.Pagination{SortBy: release.SortByName, Limit: 2}}
+				var names []string
+				for {
+					var page []release.Flag
+					require.Nil(t, iterators.Collect(c.storageGet(t).FindByQuery(c.Context(t), q), &page))
+					require.LessOrEqual(t, len(page), 2)
+					names = append(names, namesOf(page)...)
+					if len(page) < q.Limit {
+						break
+					}
+					last := page[len(page)-1]
+					q.Cursor = release.EncodeCursor(release.Cursor{Key: q.SortKey(last), ID: last.ID})
+				}
+				require.Equal(t, []string{`alpha-a`, `alpha-b`, `beta-a`, `beta-b`, `gamma`}, names)
+			})
+		})
+	})
 }
 
 func (c FlagFinder) specFindReleaseFlagsByName(s *testcase.Spec) {
@@ -0,0 +1,274 @@
+package contracts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/contracts"
+	"github.com/adamluzsi/testcase"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/release"
+)
+
+// Integrity describes the invariants the storage keeps between the release entities.
+// The deployment environment names are unique, a release flag has at most one rollout and one pilot entry
+// per deployment environment, and a release flag or a deployment environment can't be deleted
+// while it still has rollouts or pilots, so no entry is left behind with a dangling reference.
+type Integrity struct {
+	Subject        func(testing.TB) release.Storage
+	Context        func(testing.TB) context.Context
+	FixtureFactory func(testing.TB) frameless.FixtureFactory
+}
+
+func (c Integrity) storage() testcase.Var {
+	return testcase.Var{
+		Name: "release integrity storage",
+		Init: func(t *testcase.T) interface{} {
+			return c.Subject(t)
+		},
+	}
+}
+
+func (c Integrity) storageGet(t *testcase.T) release.Storage {
+	return c.storage().Get(t).(release.Storage)
+}
+
+func (c Integrity) String() string {
+	return "Integrity"
+}
+
+func (c Integrity) Test(t *testing.T) {
+	c.Spec(testcase.NewSpec(t))
+}
+
+func (c Integrity) Benchmark(b *testing.B) {
+	c.Spec(testcase.NewSpec(b))
+}
+
+func (c Integrity) Spec(s *testcase.Spec) {
+	s.Describe(`deployment environment name uniqueness`, c.specEnvironmentIsUniq)
+	s.Describe(`release rollout uniqueness per flag and environment`, c.specRolloutIsUniq)
+	s.Describe(`release pilot uniqueness per flag and environment`, c.specPilotIsUniq)
+	s.Describe(`release flag deletion`, c.specFlagDeletion)
+	s.Describe(`deployment environment deletion`, c.specEnvironmentDeletion)
+}
+
+func (c Integrity) flag(s *testcase.Spec, name string) testcase.Var {
+	return s.Let(name, func(t *testcase.T) interface{} {
+		flag := c.FixtureFactory(t).Fixture(release.Flag{}, c.Context(t)).(release.Flag)
+		contracts.CreateEntity(t, c.storageGet(t).ReleaseFlag(c.Context(t)), c.Context(t), &flag)
+		return &flag
+	})
+}
+
+func (c Integrity) env(s *testcase.Spec, name string) testcase.Var {
+	return s.Let(name, func(t *testcase.T) interface{} {
+		env := c.FixtureFactory(t).Fixture(release.Environment{}, c.Context(t)).(release.Environment)
+		contracts.CreateEntity(t, c.storageGet(t).ReleaseEnvironment(c.Context(t)), c.Context(t), &env)
+		return &env
+	})
+}
+
+func (c Integrity) rollout(s *testcase.Spec, name string, flag, env testcase.Var) testcase.Var {
+	return s.Let(name, func(t *testcase.T) interface{} {
+		rollout := release.Rollout{
+			FlagID:        flag.Get(t).(*release.Flag).ID,
+			EnvironmentID: env.Get(t).(*release.Environment).ID,
+			Plan:          release.NewRolloutDecisionByPercentage(),
+		}
+		contracts.CreateEntity(t, c.storageGet(t).ReleaseRollout(c.Context(t)), c.Context(t), &rollout)
+		return &rollout
+	})
+}
+
+func (c Integrity) pilot(s *testcase.Spec, name string, flag, env testcase.Var) testcase.Var {
+	return s.Let(name, func(t *testcase.T) interface{} {
+		pilot := release.Pilot{
+			FlagID:          flag.Get(t).(*release.Flag).ID,
+			EnvironmentID:   env.Get(t).(*release.Environment).ID,
+			PublicID:        uuid.New().String(),
+			IsParticipating: t.Random.Bool(),
+		}
+		contracts.CreateEntity(t, c.storageGet(t).ReleasePilot(c.Context(t)), c.Context(t), &pilot)
+		return &pilot
+	})
+}
+
+func (c Integrity) specEnvironmentIsUniq(s *testcase.Spec) {
+	var (
+		env      = c.env(s, `env`)
+		otherEnv = s.Let(`other env`, func(t *testcase.T) interface{} {
+			return &release.Environment{Name: env.Get(t).(*release.Environment).Name}
+		})
+	)
+
+	s.Then(`creating an environment with a taken name fails`, func(t *testcase.T) {
+		err := c.storageGet(t).ReleaseEnvironment(c.Context(t)).Create(c.Context(t), otherEnv.Get(t))
+		require.Equal(t, release.ErrEnvironmentAlreadyExist, err)
+	})
+
+	s.Then(`renaming an environment to a taken name fails`, func(t *testcase.T) {
+		other := c.FixtureFactory(t).Fixture(release.Environment{}, c.Context(t)).(release.Environment)
+		storage := c.storageGet(t).ReleaseEnvironment(c.Context(t))
+		contracts.CreateEntity(t, storage, c.Context(t), &other)
+
+		other.Name = env.Get(t).(*release.Environment).Name
+		require.Equal(t, release.ErrEnvironmentAlreadyExist, storage.Update(c.Context(t), &other))
+	})
+}
+
+func (c Integrity) specRolloutIsUniq(s *testcase.Spec) {
+	var (
+		flag    = c.flag(s, `flag`)
+		env     = c.env(s, `env`)
+		rollout = c.rollout(s, `rollout`, flag, env)
+	)
+
+	s.Before(func(t *testcase.T) { rollout.Get(t) }) // eager load
+
+	s.Then(`creating an other rollout for the same flag and environment fails`, func(t *testcase.T) {
+		err := c.storageGet(t).ReleaseRollout(c.Context(t)).Create(c.Context(t), &release.Rollout{
+			FlagID:        flag.Get(t).(*release.Flag).ID,
+			EnvironmentID: env.Get(t).(*release.Environment).ID,
+			Plan:          release.NewRolloutDecisionByPercentage(),
+		})
+		require.Equal(t, release.ErrRolloutAlreadyExist, err)
+	})
+
+	s.When(`an other environment has a rollout for the flag`, func(s *testcase.Spec) {
+		otherEnv := c.env(s, `other env`)
+		otherRollout := c.rollout(s, `other rollout`, flag, otherEnv)
+
+		s.Then(`moving it to the environment of the rollout fails`, func(t *testcase.T) {
+			r := *otherRollout.Get(t).(*release.Rollout)
+			r.EnvironmentID = env.Get(t).(*release.Environment).ID
+			require.Equal(t, release.ErrRolloutAlreadyExist, c.storageGet(t).ReleaseRollout(c.Context(t)).Update(c.Context(t), &r))
+		})
+	})
+}
+
+func (c Integrity) specPilotIsUniq(s *testcase.Spec) {
+	var (
+		flag  = c.flag(s, `flag`)
+		env   = c.env(s, `env`)
+		pilot = c.pilot(s, `pilot`, flag, env)
+	)
+
+	s.Then(`creating an other pilot with the same public id for the flag and environment fails`, func(t *testcase.T) {
+		err := c.storageGet(t).ReleasePilot(c.Context(t)).Create(c.Context(t), &release.Pilot{
+			FlagID:        flag.Get(t).(*release.Flag).ID,
+			EnvironmentID: env.Get(t).(*release.Environment).ID,
+			PublicID:      pilot.Get(t).(*release.Pilot).PublicID,
+		})
+		require.Equal(t, release.ErrPilotAlreadyExist, err)
+	})
+}
+
+func (c Integrity) specFlagDeletion(s *testcase.Spec) {
+	var (
+		flag    = c.flag(s, `flag`)
+		env     = c.env(s, `env`)
+		rollout = c.rollout(s, `rollout`, flag, env)
+		pilot   = c.pilot(s, `pilot`, flag, env)
+	)
+
+	thenTheDeletionIsRejected := func(s *testcase.Spec, subject func(t *testcase.T) error) {
+		s.Then(`the deletion is rejected, and the flag is kept`, func(t *testcase.T) {
+			require.Equal(t, release.ErrFlagInUse, subject(t))
+			contracts.IsFindable(t, release.Flag{}, c.storageGet(t).ReleaseFlag(c.Context(t)), c.Context(t), flag.Get(t).(*release.Flag).ID)
+		})
+	}
+
+	s.When(`the flag is deleted by its id`, func(s *testcase.Spec) {
+		subject := func(t *testcase.T) error {
+			return c.storageGet(t).ReleaseFlag(c.Context(t)).DeleteByID(c.Context(t), flag.Get(t).(*release.Flag).ID)
+		}
+
+		s.And(`it still has a rollout`, func(s *testcase.Spec) {
+			s.Before(func(t *testcase.T) { rollout.Get(t) })
+
+			thenTheDeletionIsRejected(s, subject)
+		})
+
+		s.And(`it still has a pilot`, func(s *testcase.Spec) {
+			s.Before(func(t *testcase.T) { pilot.Get(t) })
+
+			thenTheDeletionIsRejected(s, subject)
+		})
+
+		s.And(`it has no rollouts and pilots`, func(s *testcase.Spec) {
+			s.Then(`the flag is deleted`, func(t *testcase.T) {
+				require.Nil(t, subject(t))
+				contracts.IsAbsent(t, release.Flag{}, c.storageGet(t).ReleaseFlag(c.Context(t)), c.Context(t), flag.Get(t).(*release.Flag).ID)
+			})
+		})
+	})
+
+	s.When(`every flag is deleted`, func(s *testcase.Spec) {
+		subject := func(t *testcase.T) error {
+			return c.storageGet(t).ReleaseFlag(c.Context(t)).DeleteAll(c.Context(t))
+		}
+
+		s.And(`a flag still has a rollout`, func(s *testcase.Spec) {
+			s.Before(func(t *testcase.T) { rollout.Get(t) })
+
+			thenTheDeletionIsRejected(s, subject)
+		})
+	})
+}
+
+func (c Integrity) specEnvironmentDeletion(s *testcase.Spec) {
+	var (
+		flag    = c.flag(s, `flag`)
+		env     = c.env(s, `env`)
+		rollout = c.rollout(s, `rollout`, flag, env)
+		pilot   = c.pilot(s, `pilot`, flag, env)
+	)
+
+	thenTheDeletionIsRejected := func(s *testcase.Spec, subject func(t *testcase.T) error) {
+		s.Then(`the deletion is rejected, and the environment is kept`, func(t *testcase.T) {
+			require.Equal(t, release.ErrEnvironmentInUse, subject(t))
+			contracts.IsFindable(t, release.Environment{}, c.storageGet(t).ReleaseEnvironment(c.Context(t)), c.Context(t), env.Get(t).(*release.Environment).ID)
+		})
+	}
+
+	s.When(`the environment is deleted by its id`, func(s *testcase.Spec) {
+		subject := func(t *testcase.T) error {
+			return c.storageGet(t).ReleaseEnvironment(c.Context(t)).DeleteByID(c.Context(t), env.Get(t).(*release.Environment).ID)
+		}
+
+		s.And(`it still has a rollout`, func(s *testcase.Spec) {
+			s.Before(func(t *testcase.T) { rollout.Get(t) })
+
+			thenTheDeletionIsRejected(s, subject)
+		})
+
+		s.And(`it still has a pilot`, func(s *testcase.Spec) {
+			s.Before(func(t *testcase.T) { pilot.Get(t) })
+
+			thenTheDeletionIsRejected(s, subject)
+		})
+
+		s.And(`it has no rollouts and pilots`, func(s *testcase.Spec) {
+			s.Then(`the environment is deleted`, func(t *testcase.T) {
+				require.Nil(t, subject(t))
+				contracts.IsAbsent(t, release.Environment{}, c.storageGet(t).ReleaseEnvironment(c.Context(t)), c.Context(t), env.Get(t).(*release.Environment).ID)
+			})
+		})
+	})
+
+	s.When(`every environment is deleted`, func(s *testcase.Spec) {
+		subject := func(t *testcase.T) error {
+			return c.storageGet(t).ReleaseEnvironment(c.Context(t)).DeleteAll(c.Context(t))
+		}
+
+		s.And(`an environment still has a pilot`, func(s *testcase.Spec) {
+			s.Before(func(t *testcase.T) { pilot.Get(t) })
+
+			thenTheDeletionIsRejected(s, subject)
+		})
+	})
+}
@@ -86,6 +86,13 @@ func (c PilotStorage) Spec(s *testcase.Spec) {
 			Context:        c.Context,
 			FixtureFactory: c.FixtureFactory,
 		},
+		Revision{T: T,
+			Subject: func(tb testing.TB) contracts.UpdaterSubject {
+				return releasePilotStorage(tb)
+			},
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
 		contracts.Deleter{T: T,
 			Subject: func(tb testing.TB) contracts.CRD {
 				return releasePilotStorage(tb)
@@ -325,5 +332,96 @@ func (c PilotStorage) specPilotFinder(s *testcase.Spec) {
 				})
 			})
 		})
+
+		s.Describe(`.FindByQuery`, func(s *testcase.Spec) {
+			var (
+				query    = s.Let(`query`, func(t *testcase.T) interface{} { return release.PilotQuery{} })
+				queryGet = func(t *testcase.T) release.PilotQuery { return query.Get(t).(release.PilotQuery) }
+				subject  = func(t *testcase.T) []release.Pilot {
+					var pilots []release.Pilot
+					require.Nil(t, iterators.Collect(c.storageGet(t).FindByQuery(c.Context(t), queryGet(t)), &pilots))
+					return pilots
+				}
+				otherFlagID = s.Let(`other flag id`, func(t *testcase.T) interface{} {
+					return uuid.New().String()
+				})
+			)
+
+			s.Before(func(t *testcase.T) {
+				for i, flagID := range []string{sh.ExampleReleaseFlag(t).ID, otherFlagID.Get(t).(string)} {
+					for j := 0; j < 3; j++ {
+						contracts.CreateEntity(t, c.storageGet(t), c.Context(t), &release.Pilot{
+							FlagID:          flagID,
+							EnvironmentID:   sh.ExampleDeploymentEnvironment(t).ID,
+							PublicID:        strconv.Itoa(i*3 + j),
+							IsParticipating: j%2 == 0,
+						})
+					}
+				}
+			})
+
+			s.Then(`without filters it returns every pilot`, func(t *testcase.T) {
+				require.Len(t, subject(t), 6)
+			})
+
+			s.When(`flag id filter is given`, func(s *testcase.Spec) {
+				query.Let(s, func(t *testcase.T) interface{} {
+					return release.PilotQuery{FlagID: sh.ExampleReleaseFlag(t).ID}
+				})
+
+				s.Then(`only the pilots of the flag returned`, func(t *testcase.T) {
+					pilots := subject(t)
+					require.Len(t, pilots, 3)
+					for _, p := range pilots {
+						require.Equal(t, sh.ExampleReleaseFlag(t).ID, p.FlagID)
+					}
+				})
+			})
+
+			s.When(`public id filter is given`, func(s *testcase.Spec) {
+				query.Let(s, func(t *testcase.T) interface{} { return release.PilotQuery{PublicID: `4`} })
+
+				s.Then(`only the pilot with the public id returned`, func(t *testcase.T) {
+					pilots := subject(t)
+					require.Len(t, pilots, 1)
+					require.Equal(t, `4`, pilots[0].PublicID)
+				})
+			})
+
+			s.When(`participation filter is given`, func(s *testcase.Spec) {
+				query.Let(s, func(t *testcase.T) interface{} {
+					isParticipating := false
+					return release.PilotQuery{IsParticipating: &isParticipating}
+				})
+
+				s.Then(`only the pilots with the matching participation returned`, func(t *testcase.T) {
+					pilots := subject(t)
+					require.Len(t, pilots, 2)
+					for _, p := range pilots {
+						require.False(t, p.IsParticipating)
+					}
+				})
+			})
+
+			s.When(`sorted by public id with limit and cursor`, func(s *testcase.Spec) {
+				s.Then(`pages cover every pilot in order`, func(t *testcase.T) {
+					q := release.PilotQuery{Pagination: release.Pagination{SortBy: release.SortByPublicID, Limit: 4}}
+					var publicIDs []string
+					for {
+						var page []release.Pilot
+						require.Nil(t, iterators.Collect(c.storageGet(t).FindByQuery(c.Context(t), q), &page))
+						for _, p := range page {
+							publicIDs = append(publicIDs, p.PublicID)
+						}
+						if len(page) < q.Limit {
+							break
+						}
+						last := page[len(page)-1]
+						q.Cursor = release.EncodeCursor(release.Cursor{Key: q.SortKey(last), ID: last.ID})
+					}
+					require.Equal(t, []string{`0`, `1`, `2`, `3`, `4`, `5`}, publicIDs)
+				})
+			})
+		})
 	})
 }
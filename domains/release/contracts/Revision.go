@@ -0,0 +1,127 @@
+package contracts
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/contracts"
+	"github.com/adamluzsi/frameless/extid"
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/release"
+)
+
+// Revision describes the optimistic concurrency control of the entity updates, see release.RevisionOf.
+type Revision struct {
+	T              frameless.T
+	Subject        func(testing.TB) contracts.UpdaterSubject
+	Context        func(testing.TB) context.Context
+	FixtureFactory func(testing.TB) frameless.FixtureFactory
+}
+
+func (c Revision) storage() testcase.Var {
+	return testcase.Var{
+		Name: "revisioned storage",
+		Init: func(t *testcase.T) interface{} {
+			return c.Subject(t)
+		},
+	}
+}
+
+func (c Revision) storageGet(t *testcase.T) contracts.UpdaterSubject {
+	return c.storage().Get(t).(contracts.UpdaterSubject)
+}
+
+func (c Revision) String() string {
+	return "Revision"
+}
+
+func (c Revision) Test(t *testing.T) {
+	c.Spec(testcase.NewSpec(t))
+}
+
+func (c Revision) Benchmark(b *testing.B) {
+	c.Spec(testcase.NewSpec(b))
+}
+
+func (c Revision) Spec(s *testcase.Spec) {
+	var (
+		revisionOf = func(t *testcase.T, ptr interface{}) int {
+			revision, ok := release.RevisionOf(ptr)
+			require.True(t, ok)
+			return *revision
+		}
+		setRevision = func(t *testcase.T, ptr interface{}, n int) {
+			revision, ok := release.RevisionOf(ptr)
+			require.True(t, ok)
+			*revision = n
+		}
+		stored = func(t *testcase.T, ptr interface{}) interface{} {
+			id, ok := extid.Lookup(ptr)
+			require.True(t, ok)
+			return contracts.IsFindable(t, c.T, c.storageGet(t), c.Context(t), id)
+		}
+		entity = s.Let(`entity`, func(t *testcase.T) interface{} {
+			ptr := contracts.CreatePTR(c.FixtureFactory(t), c.T)
+			setRevision(t, ptr, 42)
+			contracts.CreateEntity(t, c.storageGet(t), c.Context(t), ptr)
+			return ptr
+		})
+		// change is the entity as an other client read it before its update.
+		change = s.Let(`change`, func(t *testcase.T) interface{} {
+			ptr := contracts.CreatePTR(c.FixtureFactory(t), c.T)
+			id, _ := extid.Lookup(entity.Get(t))
+			require.Nil(t, extid.Set(ptr, id))
+			setRevision(t, ptr, revisionOf(t, entity.Get(t)))
+			return ptr
+		})
+		subject = func(t *testcase.T) error {
+			return c.storageGet(t).Update(c.Context(t), change.Get(t))
+		}
+	)
+
+	s.Before(func(t *testcase.T) {
+		contracts.DeleteAllEntity(t, c.storageGet(t), c.Context(t))
+	})
+
+	s.Then(`the entity is created with the first revision`, func(t *testcase.T) {
+		require.Equal(t, 1, revisionOf(t, entity.Get(t)))
+		require.Equal(t, 1, revisionOf(t, stored(t, entity.Get(t))))
+	})
+
+	s.When(`the update carries the stored revision`, func(s *testcase.Spec) {
+		s.Then(`the entity is updated with the next revision`, func(t *testcase.T) {
+			require.Nil(t, subject(t))
+			require.Equal(t, 2, revisionOf(t, change.Get(t)))
+			require.Equal(t, change.Get(t), stored(t, change.Get(t)))
+		})
+	})
+
+	s.When(`the entity was updated since its revision was read`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) {
+			other := reflect.New(reflect.TypeOf(c.T)).Interface()
+			reflect.ValueOf(other).Elem().Set(reflect.ValueOf(entity.Get(t)).Elem())
+			require.Nil(t, c.storageGet(t).Update(c.Context(t), other))
+		})
+
+		s.Then(`it yields a revision conflict error, and the entity is left as is`, func(t *testcase.T) {
+			require.Equal(t, release.ErrRevisionConflict, subject(t))
+			require.Equal(t, 1, revisionOf(t, change.Get(t)))
+			require.Equal(t, 2, revisionOf(t, stored(t, entity.Get(t))))
+		})
+	})
+
+	s.When(`the update has no revision`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) {
+			setRevision(t, change.Get(t), 0)
+		})
+
+		s.Then(`the entity is updated unconditionally with the next revision`, func(t *testcase.T) {
+			require.Nil(t, subject(t))
+			require.Equal(t, 2, revisionOf(t, stored(t, entity.Get(t))))
+		})
+	})
+}
@@ -7,7 +7,9 @@ import (
 	"github.com/adamluzsi/frameless"
 
 	"github.com/adamluzsi/frameless/contracts"
+	"github.com/adamluzsi/frameless/iterators"
 	"github.com/adamluzsi/testcase"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 
 	"github.com/toggler-io/toggler/domains/release"
@@ -78,6 +80,13 @@ func (c RolloutStorage) Spec(s *testcase.Spec) {
 			Context:        c.Context,
 			FixtureFactory: c.FixtureFactory,
 		},
+		Revision{T: T,
+			Subject: func(tb testing.TB) contracts.UpdaterSubject {
+				return newRolloutStorage(tb)
+			},
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
 		contracts.Deleter{T: T,
 			Subject: func(tb testing.TB) contracts.CRD {
 				return newRolloutStorage(tb)
@@ -104,6 +113,59 @@ func (c RolloutStorage) Spec(s *testcase.Spec) {
 
 	s.Describe(`.FindReleaseRolloutByReleaseFlagAndDeploymentEnvironment`,
 		c.specFindReleaseRolloutByReleaseFlagAndDeploymentEnvironment)
+	s.Describe(`.FindByQuery`, c.specFindByQuery)
+}
+
+func (c RolloutStorage) specFindByQuery(s *testcase.Spec) {
+	var (
+		query    = s.Let(`query`, func(t *testcase.T) interface{} { return release.RolloutQuery{} })
+		queryGet = func(t *testcase.T) release.RolloutQuery { return query.Get(t).(release.RolloutQuery) }
+		subject  = func(t *testcase.T) []release.Rollout {
+			var rollouts []release.Rollout
+			require.Nil(t, iterators.Collect(c.storageGet(t).FindByQuery(c.Context(t), queryGet(t)), &rollouts))
+			return rollouts
+		}
+		otherEnvID = s.Let(`other env id`, func(t *testcase.T) interface{} {
+			return uuid.New().String()
+		})
+	)
+
+	s.Before(func(t *testcase.T) {
+		contracts.DeleteAllEntity(t, c.storageGet(t), c.Context(t))
+		for _, envID := range []string{sh.ExampleDeploymentEnvironment(t).ID, otherEnvID.Get(t).(string)} {
+			contracts.CreateEntity(t, c.storageGet(t), c.Context(t), &release.Rollout{
+				FlagID:        sh.ExampleReleaseFlag(t).ID,
+				EnvironmentID: envID,
+				Plan:          release.NewRolloutDecisionByPercentage(),
+			})
+		}
+	})
+
+	s.Then(`without filters it returns every rollout ordered by id`, func(t *testcase.T) {
+		rollouts := subject(t)
+		require.Len(t, rollouts, 2)
+		require.Less(t, rollouts[0].ID, rollouts[1].ID)
+	})
+
+	s.When(`environment filter is given`, func(s *testcase.Spec) {
+		query.Let(s, func(t *testcase.T) interface{} {
+			return release.RolloutQuery{EnvironmentID: otherEnvID.Get(t).(string)}
+		})
+
+		s.Then(`only the rollout of the environment returned`, func(t *testcase.T) {
+			rollouts := subject(t)
+			require.Len(t, rollouts, 1)
+			require.Equal(t, otherEnvID.Get(t), rollouts[0].EnvironmentID)
+		})
+	})
+
+	s.When(`limit is given`, func(s *testcase.Spec) {
+		query.Let(s, func(t *testcase.T) interface{} { return release.RolloutQuery{Pagination: release.Pagination{Limit: 1}} })
+
+		s.Then(`the number of rollouts is limited`, func(t *testcase.T) {
+			require.Len(t, subject(t), 1)
+		})
+	})
 }
 
 // TODO replace with FindOne contract
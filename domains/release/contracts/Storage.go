@@ -57,5 +57,17 @@ func (c Storage) Spec(s *testcase.Spec) {
 			Context:        c.Context,
 			FixtureFactory: c.FixtureFactory,
 		},
+		Integrity{
+			Subject:        c.Subject,
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		VersionStorage{
+			Subject: func(tb testing.TB) release.Storage {
+				return c.Subject(tb)
+			},
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
 	)
 }
@@ -0,0 +1,129 @@
+package contracts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/contracts"
+	"github.com/adamluzsi/frameless/iterators"
+	"github.com/adamluzsi/testcase"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/release"
+)
+
+type VersionStorage struct {
+	Subject        func(testing.TB) release.Storage
+	Context        func(testing.TB) context.Context
+	FixtureFactory func(testing.TB) frameless.FixtureFactory
+}
+
+func (c VersionStorage) storage() testcase.Var {
+	return testcase.Var{
+		Name: "release version storage",
+		Init: func(t *testcase.T) interface{} {
+			return c.Subject(t).ReleaseVersion(c.Context(t))
+		},
+	}
+}
+
+func (c VersionStorage) storageGet(t *testcase.T) release.VersionStorage {
+	return c.storage().Get(t).(release.VersionStorage)
+}
+
+func (c VersionStorage) String() string {
+	return "VersionStorage"
+}
+
+func (c VersionStorage) Test(t *testing.T) {
+	c.Spec(testcase.NewSpec(t))
+}
+
+func (c VersionStorage) Benchmark(b *testing.B) {
+	c.Spec(testcase.NewSpec(b))
+}
+
+func (c VersionStorage) Spec(s *testcase.Spec) {
+	T := release.Version{}
+	getVersionStorage := func(tb testing.TB) release.VersionStorage {
+		return c.Subject(tb).ReleaseVersion(c.Context(tb))
+	}
+
+	testcase.RunContract(s,
+		contracts.Creator{T: T,
+			Subject: func(tb testing.TB) contracts.CRD {
+				return getVersionStorage(tb)
+			},
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		contracts.Finder{T: T,
+			Subject: func(tb testing.TB) contracts.CRD {
+				return getVersionStorage(tb)
+			},
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		contracts.Deleter{T: T,
+			Subject: func(tb testing.TB) contracts.CRD {
+				return getVersionStorage(tb)
+			},
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+	)
+
+	s.Describe(`.FindByQuery`, func(s *testcase.Spec) {
+		var (
+			entityID = s.Let(`entity id`, func(t *testcase.T) interface{} { return uuid.New().String() })
+			query    = s.Let(`query`, func(t *testcase.T) interface{} {
+				return release.VersionQuery{Kind: release.VersionKindRollout, EntityID: entityID.Get(t).(string)}
+			})
+			subject = func(t *testcase.T) []release.Version {
+				var versions []release.Version
+				iter := c.storageGet(t).FindByQuery(c.Context(t), query.Get(t).(release.VersionQuery))
+				require.Nil(t, iterators.Collect(iter, &versions))
+				return versions
+			}
+		)
+
+		s.Before(func(t *testcase.T) {
+			storage := c.storageGet(t)
+			contracts.DeleteAllEntity(t, storage, c.Context(t))
+			for _, v := range []release.Version{
+				{Kind: release.VersionKindRollout, EntityID: entityID.Get(t).(string), Number: 2},
+				{Kind: release.VersionKindRollout, EntityID: entityID.Get(t).(string), Number: 1},
+				{Kind: release.VersionKindRollout, EntityID: uuid.New().String(), Number: 1},
+				{Kind: release.VersionKindFlag, EntityID: entityID.Get(t).(string), Number: 1},
+			} {
+				v.Action = release.VersionActionUpdate
+				v.Snapshot = []byte(`{}`)
+				v.CreatedAt = t.Random.Time().UTC()
+				contracts.CreateEntity(t, storage, c.Context(t), &v)
+			}
+		})
+
+		s.Then(`it returns the versions of the entity ordered by number`, func(t *testcase.T) {
+			versions := subject(t)
+			require.Len(t, versions, 2)
+			require.Equal(t, 1, versions[0].Number)
+			require.Equal(t, 2, versions[1].Number)
+			for _, v := range versions {
+				require.Equal(t, release.VersionKindRollout, v.Kind)
+				require.Equal(t, entityID.Get(t), v.EntityID)
+			}
+		})
+
+		s.When(`only the kind is given`, func(s *testcase.Spec) {
+			query.Let(s, func(t *testcase.T) interface{} {
+				return release.VersionQuery{Kind: release.VersionKindRollout}
+			})
+
+			s.Then(`it returns the versions of every entity of the kind`, func(t *testcase.T) {
+				require.Len(t, subject(t), 3)
+			})
+		})
+	})
+}
@@ -1,18 +1,55 @@
 package release
 
-import "github.com/adamluzsi/frameless"
+import "github.com/toggler-io/toggler/domains/errs"
 
-const (
-	ErrNameIsEmpty        frameless.Error = `feature name can't be empty`
-	ErrMissingFlag        frameless.Error = `release flag is not provided`
-	ErrMissingEnv         frameless.Error = `deployment environment is not provided`
-	ErrInvalidAction      frameless.Error = `invalid rollout action`
-	ErrFlagAlreadyExist   frameless.Error = `release flag already exist`
-	ErrInvalidRequestURL  frameless.Error = `value is not a valid request url`
-	ErrInvalidPercentage  frameless.Error = `percentage value not acceptable`
-	ErrMissingRolloutPlan frameless.Error = `release rollout plan is not provided`
+var (
+	ErrNameIsEmpty        = errs.Validation(`flag_name_is_empty`, `name`, `feature name can't be empty`)
+	ErrMissingFlag        = errs.Validation(`flag_is_missing`, `flag_id`, `release flag is not provided`)
+	ErrMissingEnv         = errs.Validation(`environment_is_missing`, `env_id`, `deployment environment is not provided`)
+	ErrInvalidAction      = errs.Validation(`invalid_action`, ``, `invalid rollout action`)
+	ErrFlagAlreadyExist   = errs.Conflict(`flag_already_exist`, `release flag already exist`)
+	ErrInvalidRequestURL  = errs.Validation(`invalid_request_url`, `plan.url`, `value is not a valid request url`)
+	ErrInvalidPercentage  = errs.Validation(`invalid_percentage`, `plan.percentage`, `percentage value not acceptable`)
+	ErrMissingRolloutPlan = errs.Validation(`rollout_plan_is_missing`, `plan`, `release rollout plan is not provided`)
 )
 
-const (
-	ErrEnvironmentNameIsEmpty frameless.Error = `deployment environment name can't be empty`
+var (
+	ErrFlagNotFound        = errs.NotFound(`flag_not_found`, `release flag not found`)
+	ErrEnvironmentNotFound = errs.NotFound(`environment_not_found`, `deployment environment not found`)
+	ErrRolloutNotFound     = errs.NotFound(`rollout_not_found`, `release rollout not found`)
+	ErrPilotNotFound       = errs.NotFound(`pilot_not_found`, `release pilot not found`)
+)
+
+var (
+	ErrInvalidCursor    = errs.Validation(`invalid_cursor`, `cursor`, `invalid pagination cursor`)
+	ErrInvalidSortField = errs.Validation(`invalid_sort_field`, `sort`, `requested field is not sortable`)
+	ErrInvalidPageLimit = errs.Validation(`invalid_page_limit`, `limit`, `page limit is out of the accepted range`)
+)
+
+var (
+	ErrManifestDuplicate          = errs.Validation(`manifest_duplicate`, ``, `manifest declares the same entry more than once`)
+	ErrManifestUnknownEnvironment = errs.Validation(`manifest_unknown_environment`, ``, `manifest references an undeclared deployment environment`)
+)
+
+var (
+	ErrEnvironmentNameIsEmpty   = errs.Validation(`environment_name_is_empty`, `name`, `deployment environment name can't be empty`)
+	ErrInvalidRequiredApprovals = errs.Validation(`invalid_required_approvals`, `required_approvals`, `required approvals can't be negative`)
+)
+
+var (
+	ErrEnvironmentAlreadyExist = errs.Conflict(`environment_already_exist`, `deployment environment already exist`)
+	ErrRolloutAlreadyExist     = errs.Conflict(`rollout_already_exist`, `release rollout already exist for the flag in the deployment environment`)
+	ErrPilotAlreadyExist       = errs.Conflict(`pilot_already_exist`, `release pilot already exist for the flag in the deployment environment`)
+	ErrFlagInUse               = errs.Conflict(`flag_in_use`, `release flag still has rollouts or pilots`)
+	ErrEnvironmentInUse        = errs.Conflict(`environment_in_use`, `deployment environment still has rollouts or pilots`)
+)
+
+var (
+	ErrRevisionConflict = errs.PreconditionFailed(`revision_conflict`, `the entity was changed since its revision was read`)
+)
+
+var (
+	ErrVersionNotFound       = errs.NotFound(`version_not_found`, `release version not found`)
+	ErrInvalidVersionKind    = errs.Validation(`invalid_version_kind`, `kind`, `release version kind is not known`)
+	ErrVersionEntityMismatch = errs.Validation(`version_entity_mismatch`, ``, `the versions belong to different entities`)
 )
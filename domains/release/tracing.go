@@ -0,0 +1,45 @@
+package release
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer records the spans of the evaluations through the global tracer provider,
+// so the spans are only exported when the application configured an exporter.
+func tracer() trace.Tracer {
+	return otel.Tracer(`github.com/toggler-io/toggler/domains/release`)
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// traceIsParticipating records the evaluation of a rollout plan as a span,
+// so the evaluation of the composite plans shows up as a tree.
+func traceIsParticipating(ctx context.Context, name string, plan RolloutPlan, isParticipating func(context.Context) (bool, error)) (bool, error) {
+	ctx, span := tracer().Start(ctx, name+`.IsParticipating`,
+		trace.WithAttributes(attribute.String(`toggler.rollout_plan.type`, PlanTypeOf(plan))))
+	ok, err := isParticipating(ctx)
+	span.SetAttributes(attribute.Bool(`toggler.rollout_plan.participating`, ok))
+	endSpan(span, err)
+	return ok, err
+}
+
+// startEvaluationSpan starts the span of a release flag evaluation request.
+// The pilot external id is left out on purpose, as it may identify a person.
+func startEvaluationSpan(ctx context.Context, name string, env Environment, flagNames []string) (context.Context, trace.Span) {
+	return tracer().Start(ctx, `RolloutManager.`+name, trace.WithAttributes(
+		attribute.String(`toggler.environment.id`, env.ID),
+		attribute.String(`toggler.environment.name`, env.Name),
+		attribute.Int(`toggler.release_flag.count`, len(flagNames)),
+	))
+}
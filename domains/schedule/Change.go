@@ -0,0 +1,103 @@
+package schedule
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/toggler-io/toggler/domains/release"
+)
+
+// The kinds of entities that can be changed at a scheduled time.
+const (
+	KindRollout = release.VersionKindRollout
+	KindPilot   = release.VersionKindPilot
+)
+
+// The actions a scheduled change can make.
+const (
+	ActionCreate = release.VersionActionCreate
+	ActionUpdate = release.VersionActionUpdate
+	ActionDelete = release.VersionActionDelete
+)
+
+// The states of a scheduled change.
+const (
+	StatusScheduled = `scheduled`
+	StatusCancelled = `cancelled`
+	StatusSucceeded = `succeeded`
+	StatusFailed    = `failed`
+	// StatusProposed tells that the change was due in a protected environment,
+	// so it was proposed as a change request instead of being made.
+	StatusProposed = `proposed`
+)
+
+// Change is a change of a rollout or a pilot, which is made by the Scheduler at its run time.
+type Change struct {
+	ID string `ext:"ID" json:"id"`
+	// EnvironmentID is the deployment environment which is affected by the change.
+	EnvironmentID string `json:"env_id"`
+	// Kind tells the type of the changed entity, e.g. rollout.
+	Kind string `json:"kind"`
+	// Action is the scheduled change of the entity.
+	Action string `json:"action"`
+	// EntityID is the ID of the changed entity.
+	// A scheduled create has no entity ID until the change is made.
+	EntityID string `json:"entity_id,omitempty"`
+	// Proposal is the JSON encoded state of the entity after the change.
+	// It is empty when the entity is scheduled to be deleted.
+	Proposal json.RawMessage `json:"proposal,omitempty"`
+	// RunAt is the time when the change is due.
+	RunAt  time.Time `json:"run_at"`
+	Status string    `json:"status"`
+	// ScheduledBy is the token owner who scheduled the change.
+	ScheduledBy string `json:"scheduled_by"`
+	// CancelledBy is the token owner who cancelled the change.
+	CancelledBy string `json:"cancelled_by,omitempty"`
+	// Error is the reason of the failure, when the change couldn't be made.
+	Error string `json:"error,omitempty"`
+	// ChangeRequestID is the change request which was proposed in place of the change of a protected environment.
+	ChangeRequestID string    `json:"change_request_id,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	// UpdatedAt tells when the change was made or cancelled.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IsScheduled tells if the change is still waiting for its run time, so it can be cancelled.
+func (c Change) IsScheduled() bool {
+	return c.Status == StatusScheduled
+}
+
+// DecodeProposal unmarshal the scheduled state of the entity into the entity pointer.
+func (c Change) DecodeProposal(ptr interface{}) error {
+	return json.Unmarshal(c.Proposal, ptr)
+}
+
+// ChangeQuery selects the scheduled changes of an environment, or with a status.
+// The empty fields don't filter the changes.
+type ChangeQuery struct {
+	EnvironmentID string
+	Status        string
+	// DueBy selects the changes whose run time is not after it.
+	DueBy time.Time
+}
+
+func (q ChangeQuery) Match(c Change) bool {
+	if q.EnvironmentID != `` && q.EnvironmentID != c.EnvironmentID {
+		return false
+	}
+	if q.Status != `` && q.Status != c.Status {
+		return false
+	}
+	if !q.DueBy.IsZero() && c.RunAt.After(q.DueBy) {
+		return false
+	}
+	return true
+}
+
+// Less tells the order of the scheduled changes, which is the order of their run time, and then by ID.
+func (q ChangeQuery) Less(a, b Change) bool {
+	if !a.RunAt.Equal(b.RunAt) {
+		return a.RunAt.Before(b.RunAt)
+	}
+	return a.ID < b.ID
+}
@@ -0,0 +1,451 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/extid"
+	"github.com/adamluzsi/frameless/iterators"
+
+	"github.com/toggler-io/toggler/domains/change"
+	"github.com/toggler-io/toggler/domains/errs"
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/security"
+)
+
+// LockName is the name of the storage lock, which is held while the due changes are made.
+const LockName = `scheduled_changes`
+
+// NewScheduler returns a Scheduler which is not yet started.
+func NewScheduler(s Storage) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		Storage:  s,
+		Interval: 10 * time.Second,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Scheduler makes the scheduled changes of the rollouts and the pilots when they are due.
+//
+// The due changes are looked up periodically in the background.
+// When more toggler instances share the storage, only one of them makes the due changes at a time,
+// as they are made while the instance holds the LockName lock of the storage.
+// The storage is expected to be guarded by the change.Protection,
+// so the due changes of the protected environments are proposed as change requests.
+type Scheduler struct {
+	Storage Storage
+	// Interval is the wait between two lookups of the due changes.
+	Interval time.Duration
+	// ErrorHandler is called with the errors that happen in the background,
+	// such as a failed lookup of the due changes.
+	ErrorHandler func(ctx context.Context, err error)
+
+	mutex   sync.Mutex
+	started bool
+	ctx     context.Context
+	cancel  func()
+	wg      sync.WaitGroup
+}
+
+// Start makes the due changes periodically in the background, until the Scheduler is closed.
+func (s *Scheduler) Start(ctx context.Context) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.started {
+		return nil
+	}
+	s.started = true
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.loop(s.ctx)
+	}()
+	return nil
+}
+
+// Close stops the background lookups, and waits for the changes in progress.
+func (s *Scheduler) Close() error {
+	s.cancel()
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Scheduler) loop(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		if err := s.RunDue(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			s.handleError(ctx, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// List returns the scheduled changes that match the query, in the order of their run time.
+func (s *Scheduler) List(ctx context.Context, q ChangeQuery) ([]Change, error) {
+	changes := make([]Change, 0)
+	err := iterators.Collect(s.Storage.ScheduledChange(ctx).FindByQuery(ctx, q), &changes)
+	return changes, err
+}
+
+// Find looks up a scheduled change along with its outcome.
+func (s *Scheduler) Find(ctx context.Context, id string) (Change, error) {
+	var c Change
+	found, err := s.Storage.ScheduledChange(ctx).FindByID(ctx, &c, id)
+	if err != nil {
+		return Change{}, err
+	}
+	if !found {
+		return Change{}, ErrChangeNotFound
+	}
+	return c, nil
+}
+
+// Schedule validates the change against the current state of the entity, and stores it to be made at its run time.
+// The proposal of an update keeps the current value of the fields which are missing from it,
+// so a rollout update may hold only the new plan.
+func (s *Scheduler) Schedule(ctx context.Context, c *Change) error {
+	owner, ok := security.LookupOwner(ctx)
+	if !ok {
+		return change.ErrOwnerUnknown
+	}
+
+	now := time.Now().UTC()
+	if !c.RunAt.After(now) {
+		return ErrInvalidRunAt
+	}
+	if err := s.prepare(ctx, c); err != nil {
+		return err
+	}
+
+	c.ID = ``
+	c.RunAt = c.RunAt.UTC()
+	c.Status = StatusScheduled
+	c.ScheduledBy = owner
+	c.CancelledBy = ``
+	c.Error = ``
+	c.ChangeRequestID = ``
+	c.CreatedAt = now
+	c.UpdatedAt = now
+	return s.Storage.ScheduledChange(ctx).Create(ctx, c)
+}
+
+// Cancel withdraws a scheduled change before its run time.
+func (s *Scheduler) Cancel(ctx context.Context, id string) (rErr error) {
+	owner, ok := security.LookupOwner(ctx)
+	if !ok {
+		return change.ErrOwnerUnknown
+	}
+
+	ctx, err := s.Storage.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer frameless.FinishOnePhaseCommit(&rErr, s.Storage, ctx)
+
+	c, err := s.Find(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !c.IsScheduled() {
+		return ErrChangeNotScheduled
+	}
+
+	c.Status = StatusCancelled
+	c.CancelledBy = owner
+	c.UpdatedAt = time.Now().UTC()
+	return s.Storage.ScheduledChange(ctx).Update(ctx, &c)
+}
+
+// RunDue makes the changes which are due by the given time, in the order of their run time.
+// It does nothing when an other toggler instance is making the due changes.
+// A change that can't be made is recorded as failed with the reason, and the rest of the changes are still made.
+func (s *Scheduler) RunDue(ctx context.Context, now time.Time) (rErr error) {
+	unlock, ok, err := s.Storage.TryLock(ctx, LockName)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	defer func() {
+		if err := unlock(); rErr == nil {
+			rErr = err
+		}
+	}()
+
+	var due []Change
+	if err := iterators.Collect(s.Storage.ScheduledChange(ctx).FindByQuery(ctx, ChangeQuery{Status: StatusScheduled, DueBy: now}), &due); err != nil {
+		return err
+	}
+
+	for _, c := range due {
+		err := s.run(ctx, c.ID)
+		if ctx.Err() != nil {
+			// the interrupted change stays scheduled, and it is made on the next run.
+			return ctx.Err()
+		}
+		if err != nil {
+			if err := s.fail(ctx, c.ID, err); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// run makes the change and records its outcome in the same transaction.
+func (s *Scheduler) run(ctx context.Context, id string) (rErr error) {
+	ctx, err := s.Storage.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer frameless.FinishOnePhaseCommit(&rErr, s.Storage, ctx)
+
+	c, err := s.Find(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !c.IsScheduled() {
+		return nil
+	}
+
+	// the change is made in the name of the token owner who scheduled it,
+	// so the change of a protected environment is proposed by them.
+	changeCtx := change.ContextWithProposals(security.ContextWithOwner(ctx, c.ScheduledBy))
+	entityID, err := s.apply(changeCtx, c)
+	if err != nil {
+		return err
+	}
+
+	if proposed := change.Proposed(changeCtx); 0 < len(proposed) {
+		c.Status = StatusProposed
+		c.ChangeRequestID = proposed[0].ID
+	} else {
+		c.Status = StatusSucceeded
+		c.EntityID = entityID
+	}
+	c.UpdatedAt = time.Now().UTC()
+	return s.Storage.ScheduledChange(ctx).Update(ctx, &c)
+}
+
+// fail records the reason of a change which couldn't be made.
+// The failed change was rolled back, thus its outcome is recorded in a transaction of its own.
+func (s *Scheduler) fail(ctx context.Context, id string, reason error) error {
+	c, err := s.Find(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !c.IsScheduled() {
+		return nil
+	}
+
+	c.Status = StatusFailed
+	c.Error = reason.Error()
+	c.UpdatedAt = time.Now().UTC()
+	return s.Storage.ScheduledChange(ctx).Update(ctx, &c)
+}
+
+func (s *Scheduler) handleError(ctx context.Context, err error) {
+	if s.ErrorHandler != nil {
+		s.ErrorHandler(ctx, err)
+	}
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+type entityStorage interface {
+	frameless.Creator
+	frameless.Finder
+	frameless.Updater
+	frameless.Deleter
+}
+
+// entity returns the storage of the entity kind, and a constructor of its entity pointers.
+func (s *Scheduler) entity(ctx context.Context, kind string) (entityStorage, func() interface{}, error) {
+	switch kind {
+	case KindRollout:
+		return s.Storage.ReleaseRollout(ctx), func() interface{} { return &release.Rollout{} }, nil
+	case KindPilot:
+		return s.Storage.ReleasePilot(ctx), func() interface{} { return &release.Pilot{} }, nil
+	default:
+		return nil, nil, ErrInvalidKind
+	}
+}
+
+// prepare checks the change against the current state of its entity,
+// and completes its proposal and environment.
+func (s *Scheduler) prepare(ctx context.Context, c *Change) error {
+	storage, newEntity, err := s.entity(ctx, c.Kind)
+	if err != nil {
+		return err
+	}
+
+	var current interface{}
+	switch c.Action {
+	case ActionCreate:
+		c.EntityID = ``
+	case ActionUpdate, ActionDelete:
+		current = newEntity()
+		found := false
+		if c.EntityID != `` {
+			found, err = storage.FindByID(ctx, current, c.EntityID)
+			if err != nil {
+				return err
+			}
+		}
+		if !found {
+			return notFound(c.Kind)
+		}
+	default:
+		return ErrInvalidAction
+	}
+
+	if c.Action == ActionDelete {
+		c.Proposal = nil
+		c.EnvironmentID = environmentOf(current)
+		return nil
+	}
+
+	proposal := newEntity()
+	if len(c.Proposal) == 0 {
+		return ErrInvalidProposal
+	}
+	if err := c.DecodeProposal(proposal); err != nil {
+		if _, ok := errs.Lookup(err); ok {
+			return err
+		}
+		return ErrInvalidProposal
+	}
+	complete(proposal, current)
+	if err := validate(proposal); err != nil {
+		return err
+	}
+
+	c.EnvironmentID = environmentOf(proposal)
+	c.Proposal, err = json.Marshal(proposal)
+	return err
+}
+
+// apply makes the change, and returns the ID of the changed entity.
+func (s *Scheduler) apply(ctx context.Context, c Change) (string, error) {
+	storage, newEntity, err := s.entity(ctx, c.Kind)
+	if err != nil {
+		return ``, err
+	}
+
+	if c.Action == ActionDelete {
+		return c.EntityID, storage.DeleteByID(ctx, c.EntityID)
+	}
+
+	ptr := newEntity()
+	if err := c.DecodeProposal(ptr); err != nil {
+		return ``, err
+	}
+
+	switch c.Action {
+	case ActionCreate:
+		if err := storage.Create(ctx, ptr); err != nil {
+			return ``, err
+		}
+		id, _ := extid.Lookup(ptr)
+		return fmt.Sprint(id), nil
+
+	case ActionUpdate:
+		current := newEntity()
+		found, err := storage.FindByID(ctx, current, c.EntityID)
+		if err != nil {
+			return ``, err
+		}
+		if !found {
+			return ``, notFound(c.Kind)
+		}
+		// the scheduled state overwrites the current one on purpose, so it updates the current revision.
+		revision, _ := release.RevisionOf(ptr)
+		currentRevision, _ := release.RevisionOf(current)
+		*revision = *currentRevision
+		return c.EntityID, storage.Update(ctx, ptr)
+
+	default:
+		return ``, ErrInvalidAction
+	}
+}
+
+// complete fills the fields of the proposal which are not given with the current state of the entity.
+// A proposed create has no current state, and it gets its ID from the storage.
+func complete(proposal, current interface{}) {
+	switch p := proposal.(type) {
+	case *release.Rollout:
+		p.ID, p.Revision = ``, 0
+		if c, ok := current.(*release.Rollout); ok {
+			p.ID = c.ID
+			if p.FlagID == `` {
+				p.FlagID = c.FlagID
+			}
+			if p.EnvironmentID == `` {
+				p.EnvironmentID = c.EnvironmentID
+			}
+			if p.Plan == nil {
+				p.Plan = c.Plan
+			}
+		}
+	case *release.Pilot:
+		p.ID, p.Revision = ``, 0
+		if c, ok := current.(*release.Pilot); ok {
+			p.ID = c.ID
+			if p.FlagID == `` {
+				p.FlagID = c.FlagID
+			}
+			if p.EnvironmentID == `` {
+				p.EnvironmentID = c.EnvironmentID
+			}
+			if p.PublicID == `` {
+				p.PublicID = c.PublicID
+			}
+		}
+	}
+}
+
+func validate(ptr interface{}) error {
+	switch e := ptr.(type) {
+	case *release.Rollout:
+		return e.Validate()
+	case *release.Pilot:
+		if e.FlagID == `` {
+			return release.ErrMissingFlag
+		}
+		if e.EnvironmentID == `` {
+			return release.ErrMissingEnv
+		}
+	}
+	return nil
+}
+
+func environmentOf(ptr interface{}) string {
+	switch e := ptr.(type) {
+	case *release.Rollout:
+		return e.EnvironmentID
+	case *release.Pilot:
+		return e.EnvironmentID
+	default:
+		return ``
+	}
+}
+
+func notFound(kind string) error {
+	if kind == KindPilot {
+		return release.ErrPilotNotFound
+	}
+	return release.ErrRolloutNotFound
+}
@@ -0,0 +1,186 @@
+package schedule_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/change"
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/schedule"
+	"github.com/toggler-io/toggler/domains/security"
+	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/external/resource/storages"
+)
+
+func TestScheduler(t *testing.T) {
+	s := testcase.NewSpec(t)
+	defer s.Finish()
+
+	var (
+		ctx          = context.Background()
+		ownerCtx     = security.ContextWithOwner(ctx, `alice`)
+		useCases     = s.Let(`use cases`, func(t *testcase.T) interface{} { return toggler.NewUseCases(storages.NewInMemory()) })
+		storageGet   = func(t *testcase.T) toggler.Storage { return useCases.Get(t).(*toggler.UseCases).Storage }
+		schedulerGet = func(t *testcase.T) *schedule.Scheduler { return useCases.Get(t).(*toggler.UseCases).Scheduler }
+		approvals    = s.LetValue(`required approvals`, 0)
+		rollout      = s.Let(`rollout`, func(t *testcase.T) interface{} {
+			env := release.Environment{Name: `production`}
+			require.Nil(t, storageGet(t).ReleaseEnvironment(ctx).Create(ctx, &env))
+			flag := release.Flag{Name: `checkout-v2`}
+			require.Nil(t, storageGet(t).ReleaseFlag(ctx).Create(ctx, &flag))
+			rollout := &release.Rollout{
+				FlagID:        flag.ID,
+				EnvironmentID: env.ID,
+				Plan:          release.RolloutDecisionByGlobal{State: true},
+			}
+			require.Nil(t, storageGet(t).ReleaseRollout(ctx).Create(ctx, rollout))
+			// the protection is turned on after the rollout is made
+			env.RequiredApprovals = approvals.Get(t).(int)
+			require.Nil(t, storageGet(t).ReleaseEnvironment(ctx).Update(ctx, &env))
+			return rollout
+		})
+		rolloutGet = func(t *testcase.T) *release.Rollout { return rollout.Get(t).(*release.Rollout) }
+		stored     = func(t *testcase.T) release.Rollout {
+			var r release.Rollout
+			found, err := storageGet(t).ReleaseRollout(ctx).FindByID(ctx, &r, rolloutGet(t).ID)
+			require.Nil(t, err)
+			require.True(t, found)
+			return r
+		}
+		runAt       = s.Let(`run at`, func(t *testcase.T) interface{} { return time.Now().Add(time.Hour) })
+		runAtGet    = func(t *testcase.T) time.Time { return runAt.Get(t).(time.Time) }
+		switchOffAt = func(t *testcase.T, runAt time.Time) schedule.Change {
+			c := schedule.Change{
+				Kind:     schedule.KindRollout,
+				Action:   schedule.ActionUpdate,
+				EntityID: rolloutGet(t).ID,
+				Proposal: json.RawMessage(`{"plan":{"type":"global","state":false}}`),
+				RunAt:    runAt,
+			}
+			require.Nil(t, schedulerGet(t).Schedule(ownerCtx, &c))
+			return c
+		}
+		find = func(t *testcase.T, id string) schedule.Change {
+			c, err := schedulerGet(t).Find(ctx, id)
+			require.Nil(t, err)
+			return c
+		}
+	)
+
+	s.Describe(`.Schedule`, func(s *testcase.Spec) {
+		s.Then(`the update keeps the fields of the entity which are not in the proposal`, func(t *testcase.T) {
+			c := switchOffAt(t, runAtGet(t))
+			require.Equal(t, schedule.StatusScheduled, c.Status)
+			require.Equal(t, `alice`, c.ScheduledBy)
+			require.Equal(t, rolloutGet(t).EnvironmentID, c.EnvironmentID)
+
+			var proposal release.Rollout
+			require.Nil(t, c.DecodeProposal(&proposal))
+			require.Equal(t, rolloutGet(t).ID, proposal.ID)
+			require.Equal(t, rolloutGet(t).FlagID, proposal.FlagID)
+			require.Equal(t, release.RolloutDecisionByGlobal{State: false}, proposal.Plan)
+
+			changes, err := schedulerGet(t).List(ctx, schedule.ChangeQuery{EnvironmentID: c.EnvironmentID})
+			require.Nil(t, err)
+			require.Equal(t, []schedule.Change{c}, changes)
+		})
+
+		s.Then(`the run time must be in the future`, func(t *testcase.T) {
+			c := schedule.Change{Kind: schedule.KindRollout, Action: schedule.ActionDelete, EntityID: rolloutGet(t).ID, RunAt: time.Now().Add(-time.Minute)}
+			require.Equal(t, schedule.ErrInvalidRunAt, schedulerGet(t).Schedule(ownerCtx, &c))
+		})
+
+		s.Then(`the changed entity must exist`, func(t *testcase.T) {
+			c := schedule.Change{Kind: schedule.KindRollout, Action: schedule.ActionDelete, EntityID: `unknown`, RunAt: runAtGet(t)}
+			require.Equal(t, release.ErrRolloutNotFound, schedulerGet(t).Schedule(ownerCtx, &c))
+		})
+
+		s.Then(`the token owner must be known`, func(t *testcase.T) {
+			c := schedule.Change{Kind: schedule.KindRollout, Action: schedule.ActionDelete, EntityID: rolloutGet(t).ID, RunAt: runAtGet(t)}
+			require.Equal(t, change.ErrOwnerUnknown, schedulerGet(t).Schedule(ctx, &c))
+		})
+	})
+
+	s.Describe(`.RunDue`, func(s *testcase.Spec) {
+		subject := func(t *testcase.T) error {
+			return schedulerGet(t).RunDue(ctx, runAtGet(t))
+		}
+
+		s.Then(`the due change is made, and its outcome is recorded`, func(t *testcase.T) {
+			c := switchOffAt(t, runAtGet(t))
+			require.Nil(t, subject(t))
+
+			require.Equal(t, release.RolloutDecisionByGlobal{State: false}, stored(t).Plan)
+			c = find(t, c.ID)
+			require.Equal(t, schedule.StatusSucceeded, c.Status)
+			require.Empty(t, c.Error)
+		})
+
+		s.Then(`the change which is not due yet stays scheduled`, func(t *testcase.T) {
+			c := switchOffAt(t, runAtGet(t).Add(time.Minute))
+			require.Nil(t, subject(t))
+
+			require.Equal(t, release.RolloutDecisionByGlobal{State: true}, stored(t).Plan)
+			require.Equal(t, schedule.StatusScheduled, find(t, c.ID).Status)
+		})
+
+		s.Then(`the cancelled change is not made`, func(t *testcase.T) {
+			c := switchOffAt(t, runAtGet(t))
+			require.Nil(t, schedulerGet(t).Cancel(security.ContextWithOwner(ctx, `bob`), c.ID))
+			require.Nil(t, subject(t))
+
+			require.Equal(t, release.RolloutDecisionByGlobal{State: true}, stored(t).Plan)
+			c = find(t, c.ID)
+			require.Equal(t, schedule.StatusCancelled, c.Status)
+			require.Equal(t, `bob`, c.CancelledBy)
+			require.Equal(t, schedule.ErrChangeNotScheduled, schedulerGet(t).Cancel(ownerCtx, c.ID))
+		})
+
+		s.Then(`the change that can't be made is recorded as failed, and the next changes are still made`, func(t *testcase.T) {
+			deletion := schedule.Change{Kind: schedule.KindRollout, Action: schedule.ActionDelete, EntityID: rolloutGet(t).ID, RunAt: runAtGet(t).Add(-time.Minute)}
+			require.Nil(t, schedulerGet(t).Schedule(ownerCtx, &deletion))
+			// the rollout is deleted before the update is due
+			update := switchOffAt(t, runAtGet(t))
+			require.Nil(t, subject(t))
+
+			require.Equal(t, schedule.StatusSucceeded, find(t, deletion.ID).Status)
+			update = find(t, update.ID)
+			require.Equal(t, schedule.StatusFailed, update.Status)
+			require.Equal(t, release.ErrRolloutNotFound.Error(), update.Error)
+		})
+
+		s.Then(`nothing is made while an other instance holds the lock`, func(t *testcase.T) {
+			c := switchOffAt(t, runAtGet(t))
+			unlock, ok, err := storageGet(t).TryLock(ctx, schedule.LockName)
+			require.Nil(t, err)
+			require.True(t, ok)
+			defer unlock()
+
+			require.Nil(t, subject(t))
+			require.Equal(t, schedule.StatusScheduled, find(t, c.ID).Status)
+		})
+
+		s.When(`the environment is protected`, func(s *testcase.Spec) {
+			approvals.LetValue(s, 1)
+
+			s.Then(`the due change is proposed as a change request in the name of its scheduler`, func(t *testcase.T) {
+				c := switchOffAt(t, runAtGet(t))
+				require.Nil(t, subject(t))
+
+				require.Equal(t, release.RolloutDecisionByGlobal{State: true}, stored(t).Plan)
+				c = find(t, c.ID)
+				require.Equal(t, schedule.StatusProposed, c.Status)
+
+				review, err := useCases.Get(t).(*toggler.UseCases).Changes.Review(ctx, c.ChangeRequestID)
+				require.Nil(t, err)
+				require.Equal(t, `alice`, review.Request.ProposedBy)
+				require.Equal(t, change.StatusPending, review.Request.Status)
+			})
+		})
+	})
+}
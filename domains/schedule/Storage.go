@@ -0,0 +1,35 @@
+package schedule
+
+import (
+	"context"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/iterators"
+
+	"github.com/toggler-io/toggler/domains/release"
+)
+
+// Storage holds the scheduled changes, along with the release entities they change.
+type Storage interface {
+	release.Storage
+	Locker
+	ScheduledChange(context.Context) ChangeStorage
+}
+
+type ChangeEntries = iterators.Interface
+
+type ChangeStorage interface {
+	frameless.Creator
+	frameless.Finder
+	frameless.Updater
+	frameless.Deleter
+	// FindByQuery returns the scheduled changes that match the query, in the order of their run time.
+	FindByQuery(ctx context.Context, q ChangeQuery) ChangeEntries
+}
+
+// Locker coordinates the toggler instances which share the storage.
+type Locker interface {
+	// TryLock acquires the named lock, unless an other toggler instance holds it.
+	// The lock is held until the returned unlock func is called.
+	TryLock(ctx context.Context, name string) (unlock func() error, ok bool, err error)
+}
@@ -0,0 +1,135 @@
+package contracts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/contracts"
+	"github.com/adamluzsi/frameless/iterators"
+	"github.com/adamluzsi/testcase"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/schedule"
+)
+
+type ChangeStorage struct {
+	Subject        func(testing.TB) schedule.ChangeStorage
+	Context        func(testing.TB) context.Context
+	FixtureFactory func(testing.TB) frameless.FixtureFactory
+}
+
+func (c ChangeStorage) storage() testcase.Var {
+	return testcase.Var{
+		Name: "scheduled change storage",
+		Init: func(t *testcase.T) interface{} {
+			return c.Subject(t)
+		},
+	}
+}
+
+func (c ChangeStorage) storageGet(t *testcase.T) schedule.ChangeStorage {
+	return c.storage().Get(t).(schedule.ChangeStorage)
+}
+
+func (c ChangeStorage) String() string {
+	return "ChangeStorage"
+}
+
+func (c ChangeStorage) Test(t *testing.T) {
+	c.Spec(testcase.NewSpec(t))
+}
+
+func (c ChangeStorage) Benchmark(b *testing.B) {
+	c.Spec(testcase.NewSpec(b))
+}
+
+func (c ChangeStorage) Spec(s *testcase.Spec) {
+	T := schedule.Change{}
+	testcase.RunContract(s,
+		contracts.Creator{T: T,
+			Subject:        func(tb testing.TB) contracts.CRD { return c.Subject(tb) },
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		contracts.Finder{T: T,
+			Subject:        func(tb testing.TB) contracts.CRD { return c.Subject(tb) },
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		contracts.Updater{T: T,
+			Subject:        func(tb testing.TB) contracts.UpdaterSubject { return c.Subject(tb) },
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		contracts.Deleter{T: T,
+			Subject:        func(tb testing.TB) contracts.CRD { return c.Subject(tb) },
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+	)
+
+	s.Describe(`.FindByQuery`, func(s *testcase.Spec) {
+		var (
+			now       = time.Now().UTC().Truncate(time.Second)
+			envID     = s.Let(`env id`, func(t *testcase.T) interface{} { return uuid.New().String() })
+			newChange = func(t *testcase.T, envID, status string, runAt time.Time) *schedule.Change {
+				sc := c.FixtureFactory(t).Fixture(schedule.Change{}, c.Context(t)).(schedule.Change)
+				sc.EnvironmentID = envID
+				sc.Status = status
+				sc.RunAt = runAt
+				return &sc
+			}
+			query = s.Let(`query`, func(t *testcase.T) interface{} {
+				return schedule.ChangeQuery{EnvironmentID: envID.Get(t).(string)}
+			})
+			subject = func(t *testcase.T) []schedule.Change {
+				var changes []schedule.Change
+				iter := c.storageGet(t).FindByQuery(c.Context(t), query.Get(t).(schedule.ChangeQuery))
+				require.Nil(t, iterators.Collect(iter, &changes))
+				return changes
+			}
+		)
+
+		s.Before(func(t *testcase.T) {
+			contracts.DeleteAllEntity(t, c.storageGet(t), c.Context(t))
+			for _, sc := range []*schedule.Change{
+				newChange(t, envID.Get(t).(string), schedule.StatusScheduled, now.Add(time.Hour)),
+				newChange(t, envID.Get(t).(string), schedule.StatusSucceeded, now.Add(-time.Hour)),
+				newChange(t, uuid.New().String(), schedule.StatusScheduled, now.Add(-time.Minute)),
+			} {
+				contracts.CreateEntity(t, c.storageGet(t), c.Context(t), sc)
+			}
+		})
+
+		s.Then(`it returns the scheduled changes of the environment, in the order of their run time`, func(t *testcase.T) {
+			changes := subject(t)
+			require.Len(t, changes, 2)
+			require.Equal(t, schedule.StatusSucceeded, changes[0].Status)
+			require.Equal(t, schedule.StatusScheduled, changes[1].Status)
+		})
+
+		s.When(`the changes due by a time are selected`, func(s *testcase.Spec) {
+			query.Let(s, func(t *testcase.T) interface{} {
+				return schedule.ChangeQuery{Status: schedule.StatusScheduled, DueBy: now}
+			})
+
+			s.Then(`it returns the changes whose run time is not after it`, func(t *testcase.T) {
+				changes := subject(t)
+				require.Len(t, changes, 1)
+				require.True(t, !changes[0].RunAt.After(now))
+				require.Equal(t, schedule.StatusScheduled, changes[0].Status)
+			})
+		})
+
+		s.When(`the query is empty`, func(s *testcase.Spec) {
+			query.Let(s, func(t *testcase.T) interface{} { return schedule.ChangeQuery{} })
+
+			s.Then(`it returns every scheduled change`, func(t *testcase.T) {
+				require.Len(t, subject(t), 3)
+			})
+		})
+	})
+}
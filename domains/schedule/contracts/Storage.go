@@ -0,0 +1,73 @@
+package contracts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/contracts"
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/schedule"
+)
+
+type Storage struct {
+	Subject        func(testing.TB) schedule.Storage
+	Context        func(testing.TB) context.Context
+	FixtureFactory func(testing.TB) frameless.FixtureFactory
+}
+
+func (c Storage) String() string {
+	return `schedule#Storage`
+}
+
+func (c Storage) Test(t *testing.T) {
+	c.Spec(testcase.NewSpec(t))
+}
+
+func (c Storage) Benchmark(b *testing.B) {
+	c.Spec(testcase.NewSpec(b))
+}
+
+func (c Storage) Spec(s *testcase.Spec) {
+	testcase.RunContract(s,
+		ChangeStorage{
+			Subject: func(tb testing.TB) schedule.ChangeStorage {
+				return c.Subject(tb).ScheduledChange(c.Context(tb))
+			},
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		contracts.OnePhaseCommitProtocol{T: schedule.Change{},
+			Subject: func(tb testing.TB) (frameless.OnePhaseCommitProtocol, contracts.CRD) {
+				storage := c.Subject(tb)
+				return storage, storage.ScheduledChange(c.Context(tb))
+			},
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+	)
+
+	s.Describe(`.TryLock`, func(s *testcase.Spec) {
+		storage := s.Let(`storage`, func(t *testcase.T) interface{} { return c.Subject(t) })
+		subject := func(t *testcase.T) (func() error, bool) {
+			unlock, ok, err := storage.Get(t).(schedule.Storage).TryLock(c.Context(t), `contract`)
+			require.Nil(t, err)
+			return unlock, ok
+		}
+
+		s.Then(`the lock is held by one holder at a time`, func(t *testcase.T) {
+			unlock, ok := subject(t)
+			require.True(t, ok)
+
+			_, ok = subject(t)
+			require.False(t, ok)
+
+			require.Nil(t, unlock())
+			unlock, ok = subject(t)
+			require.True(t, ok)
+			require.Nil(t, unlock())
+		})
+	})
+}
@@ -0,0 +1,11 @@
+package contracts_test
+
+import (
+	c "github.com/adamluzsi/frameless/contracts"
+	"github.com/toggler-io/toggler/domains/schedule/contracts"
+)
+
+var _ = []c.Interface{
+	contracts.Storage{},
+	contracts.ChangeStorage{},
+}
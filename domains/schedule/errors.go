@@ -0,0 +1,18 @@
+package schedule
+
+import "github.com/toggler-io/toggler/domains/errs"
+
+var (
+	ErrInvalidKind     = errs.Validation(`scheduled_change_kind_is_invalid`, `kind`, `scheduled change kind is not known`)
+	ErrInvalidAction   = errs.Validation(`scheduled_change_action_is_invalid`, `action`, `scheduled change action is not known`)
+	ErrInvalidRunAt    = errs.Validation(`scheduled_change_run_at_is_invalid`, `run_at`, `scheduled change run time must be in the future`)
+	ErrInvalidProposal = errs.Validation(`scheduled_change_proposal_is_invalid`, `proposal`, `scheduled change proposal is not a valid state of the entity`)
+)
+
+var (
+	ErrChangeNotFound = errs.NotFound(`scheduled_change_not_found`, `scheduled change not found`)
+)
+
+var (
+	ErrChangeNotScheduled = errs.Conflict(`scheduled_change_not_scheduled`, `scheduled change is already made or cancelled`)
+)
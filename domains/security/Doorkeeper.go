@@ -13,17 +13,24 @@ type Doorkeeper struct {
 }
 
 func (dk *Doorkeeper) VerifyTextToken(ctx context.Context, textToken string) (bool, error) {
+	_, valid, err := dk.LookupTextToken(ctx, textToken)
+	return valid, err
+}
+
+// LookupTextToken verifies the text token the same way as VerifyTextToken,
+// and returns the token as well, so the caller can tell who is the owner of the token.
+func (dk *Doorkeeper) LookupTextToken(ctx context.Context, textToken string) (*Token, bool, error) {
 	sha512hex, err := ToSHA512Hex(textToken)
 
 	if err != nil {
-		return false, err
+		return nil, false, err
 	}
 
 	token, err := dk.Storage.SecurityToken(ctx).FindTokenBySHA512Hex(ctx, sha512hex)
 
 	if token == nil {
-		return false, nil
+		return nil, false, nil
 	}
 
-	return token.IsValid(), err
+	return token, token.IsValid(), err
 }
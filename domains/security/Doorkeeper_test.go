@@ -60,6 +60,13 @@ func SpecDoorkeeperVerifyTextToken(s *testcase.Spec) {
 			s.Then(`it will verify and accept it`, func(t *testcase.T) {
 				require.True(t, onSuccess(t))
 			})
+
+			s.Then(`the token can be looked up with its owner`, func(t *testcase.T) {
+				token, valid, err := doorkeeper(t).LookupTextToken(sh.ContextGet(t), getTextToken(t))
+				require.Nil(t, err)
+				require.True(t, valid)
+				require.Equal(t, getToken(t).OwnerUID, token.OwnerUID)
+			})
 		})
 
 		s.When(`token is unknown`, func(s *testcase.Spec) {
@@ -4,11 +4,18 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/base64"
-	"errors"
 	"strings"
 	"time"
+
+	"github.com/toggler-io/toggler/domains/errs"
 )
 
+// ErrOwnerUIDIsEmpty is returned when a token is requested without an owner.
+var ErrOwnerUIDIsEmpty = errs.Validation(`owner_uid_is_empty`, `owner_uid`, `OwnerUID cannot be empty`)
+
+// ErrEnvironmentIDIsEmpty is returned when an environment scoped token is requested without an environment.
+var ErrEnvironmentIDIsEmpty = errs.Validation(`env_id_is_empty`, `env_id`, `EnvironmentID cannot be empty`)
+
 func NewIssuer(s Storage) *Issuer {
 	return &Issuer{Storage: s}
 }
@@ -18,12 +25,24 @@ type Issuer struct {
 }
 
 func (i *Issuer) CreateNewToken(ctx context.Context, ownerUID string, issueAt *time.Time, duration *time.Duration) (string, *Token, error) {
+	return i.issue(ctx, &Token{OwnerUID: ownerUID}, issueAt, duration)
+}
 
-	if ownerUID == `` {
-		return "", nil, errors.New(`OwnerUID cannot be empty`)
+// CreateNewEnvironmentToken creates a token which is scoped to a deployment environment,
+// so the clients using it don't have to name the environment in their requests.
+func (i *Issuer) CreateNewEnvironmentToken(ctx context.Context, ownerUID, envID string, issueAt *time.Time, duration *time.Duration) (string, *Token, error) {
+	if envID == `` {
+		return "", nil, ErrEnvironmentIDIsEmpty
 	}
 
-	token := &Token{OwnerUID: ownerUID}
+	return i.issue(ctx, &Token{OwnerUID: ownerUID, EnvironmentID: envID}, issueAt, duration)
+}
+
+func (i *Issuer) issue(ctx context.Context, token *Token, issueAt *time.Time, duration *time.Duration) (string, *Token, error) {
+
+	if token.OwnerUID == `` {
+		return "", nil, ErrOwnerUIDIsEmpty
+	}
 
 	if issueAt == nil {
 		token.IssuedAt = time.Now().UTC()
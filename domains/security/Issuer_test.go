@@ -23,6 +23,7 @@ func TestIssuer(t *testing.T) {
 	})
 
 	s.Describe(`CreateNewToken`, SpecIssuerCreateNewToken)
+	s.Describe(`CreateNewEnvironmentToken`, SpecIssuerCreateNewEnvironmentToken)
 	s.Describe(`RevokeToken`, SpecIssuerRevokeToken)
 }
 
@@ -160,3 +161,34 @@ func SpecIssuerCreateNewToken(s *testcase.Spec) {
 		})
 	})
 }
+
+func SpecIssuerCreateNewEnvironmentToken(s *testcase.Spec) {
+	envID := s.Let(`envID`, func(t *testcase.T) interface{} {
+		return fixtures.Random.String()
+	})
+	var subject = func(t *testcase.T) (string, *security.Token, error) {
+		issuer := t.I(`issuer`).(*security.Issuer)
+		return issuer.CreateNewEnvironmentToken(sh.ContextGet(t), sh.ExampleUniqueUserID(t), envID.Get(t).(string), nil, nil)
+	}
+
+	s.Then(`the stored token is scoped to the environment`, func(t *testcase.T) {
+		textToken, token, err := subject(t)
+		require.Nil(t, err)
+		t.Defer(sh.StorageGet(t).SecurityToken(sh.ContextGet(t)).DeleteByID, sh.ContextGet(t), token.ID)
+
+		found, valid, err := security.NewDoorkeeper(sh.StorageGet(t)).LookupTextToken(sh.ContextGet(t), textToken)
+		require.Nil(t, err)
+		require.True(t, valid)
+		require.Equal(t, envID.Get(t).(string), found.EnvironmentID)
+	})
+
+	s.When(`envID is empty`, func(s *testcase.Spec) {
+		envID.LetValue(s, ``)
+
+		s.Then(`it is rejected, as the token would not be scoped`, func(t *testcase.T) {
+			_, token, err := subject(t)
+			require.Equal(t, security.ErrEnvironmentIDIsEmpty, err)
+			require.Nil(t, token)
+		})
+	})
+}
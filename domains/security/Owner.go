@@ -0,0 +1,17 @@
+package security
+
+import "context"
+
+type ownerContextKey struct{}
+
+// ContextWithOwner returns a context which tells who is making the request,
+// so the domain actions can tell apart the token owners, like the approvers of a change request.
+func ContextWithOwner(ctx context.Context, ownerUID string) context.Context {
+	return context.WithValue(ctx, ownerContextKey{}, ownerUID)
+}
+
+// LookupOwner returns the token owner of the request.
+func LookupOwner(ctx context.Context) (ownerUID string, ok bool) {
+	ownerUID, ok = ctx.Value(ownerContextKey{}).(string)
+	return ownerUID, ok && ownerUID != ``
+}
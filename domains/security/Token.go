@@ -8,6 +8,10 @@ type Token struct {
 	OwnerUID string
 	IssuedAt time.Time
 	Duration time.Duration
+	// EnvironmentID scopes the token to a deployment environment.
+	// The client protocols, like OFREP, evaluate the release flags in this environment
+	// for the requests made with the token. It is empty for the tokens which are not scoped.
+	EnvironmentID string
 }
 
 func (token Token) IsValid() bool {
@@ -0,0 +1,37 @@
+package toggler
+
+import (
+	"context"
+
+	"github.com/toggler-io/toggler/domains/change"
+	"github.com/toggler-io/toggler/domains/release"
+)
+
+// NewProtectedStorage wraps the storage,
+// so the protected environments, their rollouts and pilots are only changed through change requests.
+func NewProtectedStorage(s Storage) Storage {
+	if _, ok := s.(protectedStorage); ok {
+		return s
+	}
+	return protectedStorage{Storage: s}
+}
+
+type protectedStorage struct {
+	Storage
+}
+
+func (s protectedStorage) protection() change.Protection {
+	return change.Protection{Storage: s.Storage}
+}
+
+func (s protectedStorage) ReleaseEnvironment(ctx context.Context) release.EnvironmentStorage {
+	return change.ProtectedEnvironmentStorage{EnvironmentStorage: s.Storage.ReleaseEnvironment(ctx), Protection: s.protection()}
+}
+
+func (s protectedStorage) ReleaseRollout(ctx context.Context) release.RolloutStorage {
+	return change.ProtectedRolloutStorage{RolloutStorage: s.Storage.ReleaseRollout(ctx), Protection: s.protection()}
+}
+
+func (s protectedStorage) ReleasePilot(ctx context.Context) release.PilotStorage {
+	return change.ProtectedPilotStorage{PilotStorage: s.Storage.ReleasePilot(ctx), Protection: s.protection()}
+}
@@ -3,12 +3,20 @@ package toggler
 import (
 	"io"
 
+	"github.com/toggler-io/toggler/domains/change"
+	"github.com/toggler-io/toggler/domains/guardrail"
 	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/schedule"
 	"github.com/toggler-io/toggler/domains/security"
+	"github.com/toggler-io/toggler/domains/webhook"
 )
 
 type Storage interface {
 	release.Storage
+	change.Storage
+	schedule.Storage
+	guardrail.Storage
 	security.Storage
+	webhook.Storage
 	io.Closer
 }
@@ -4,18 +4,26 @@
 package toggler
 
 import (
-	"github.com/adamluzsi/frameless"
-
+	"github.com/toggler-io/toggler/domains/change"
+	"github.com/toggler-io/toggler/domains/errs"
+	"github.com/toggler-io/toggler/domains/guardrail"
 	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/schedule"
 	"github.com/toggler-io/toggler/domains/security"
+	"github.com/toggler-io/toggler/domains/webhook"
 )
 
 func NewUseCases(s Storage) *UseCases {
+	s = NewProtectedStorage(NewVersionedStorage(s))
 	return &UseCases{
 		Storage:        s,
 		RolloutManager: release.NewRolloutManager(s),
 		Doorkeeper:     security.NewDoorkeeper(s),
 		Issuer:         security.NewIssuer(s),
+		Webhooks:       webhook.NewDispatcher(s, s),
+		Changes:        change.NewManager(s),
+		Scheduler:      schedule.NewScheduler(s),
+		Guardrails:     guardrail.NewMonitor(s),
 	}
 }
 
@@ -24,7 +32,14 @@ type UseCases struct {
 	*release.RolloutManager
 	*security.Doorkeeper
 	*security.Issuer
+	// Webhooks is started by the server, so the changes are delivered to the webhook subscriptions.
+	Webhooks *webhook.Dispatcher
+	Changes  *change.Manager
+	// Scheduler is started by the server, so the scheduled changes are made when they are due.
+	Scheduler *schedule.Scheduler
+	// Guardrails is started by the server, so the rollouts are rolled back when their guardrail trips.
+	Guardrails *guardrail.Monitor
 }
 
-// TODO: usage of this?
-const ErrInvalidToken frameless.Error = `invalid token error`
+// ErrInvalidToken is returned when the request has no valid security token.
+var ErrInvalidToken = errs.Unauthorized(`invalid_token`, `invalid token error`)
@@ -0,0 +1,36 @@
+package toggler
+
+import (
+	"context"
+
+	"github.com/toggler-io/toggler/domains/release"
+)
+
+// NewVersionedStorage wraps the storage,
+// so every change of the release flags, rollouts and pilots is recorded as a release.Version.
+func NewVersionedStorage(s Storage) Storage {
+	if _, ok := s.(versionedStorage); ok {
+		return s
+	}
+	return versionedStorage{Storage: s}
+}
+
+type versionedStorage struct {
+	Storage
+}
+
+func (s versionedStorage) versioning() release.Versioning {
+	return release.Versioning{Storage: s.Storage}
+}
+
+func (s versionedStorage) ReleaseFlag(ctx context.Context) release.FlagStorage {
+	return release.VersionedFlagStorage{FlagStorage: s.Storage.ReleaseFlag(ctx), Versioning: s.versioning()}
+}
+
+func (s versionedStorage) ReleaseRollout(ctx context.Context) release.RolloutStorage {
+	return release.VersionedRolloutStorage{RolloutStorage: s.Storage.ReleaseRollout(ctx), Versioning: s.versioning()}
+}
+
+func (s versionedStorage) ReleasePilot(ctx context.Context) release.PilotStorage {
+	return release.VersionedPilotStorage{PilotStorage: s.Storage.ReleasePilot(ctx), Versioning: s.versioning()}
+}
@@ -9,11 +9,19 @@ import (
 	"github.com/adamluzsi/frameless"
 	"github.com/adamluzsi/testcase"
 
+	"github.com/toggler-io/toggler/domains/change"
+	"github.com/toggler-io/toggler/domains/guardrail"
 	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/schedule"
 	"github.com/toggler-io/toggler/domains/security"
+	"github.com/toggler-io/toggler/domains/webhook"
 
+	chspecs "github.com/toggler-io/toggler/domains/change/contracts"
+	grspecs "github.com/toggler-io/toggler/domains/guardrail/contracts"
 	relspecs "github.com/toggler-io/toggler/domains/release/contracts"
+	schspecs "github.com/toggler-io/toggler/domains/schedule/contracts"
 	secspecs "github.com/toggler-io/toggler/domains/security/contracts"
+	whspecs "github.com/toggler-io/toggler/domains/webhook/contracts"
 
 	"github.com/toggler-io/toggler/domains/toggler"
 )
@@ -55,5 +63,33 @@ func (c Storage) Spec(s *testcase.Spec) {
 			Context:        c.Context,
 			FixtureFactory: c.FixtureFactory,
 		},
+		whspecs.Storage{
+			Subject: func(tb testing.TB) webhook.Storage {
+				return c.Subject(tb)
+			},
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		chspecs.Storage{
+			Subject: func(tb testing.TB) change.Storage {
+				return c.Subject(tb)
+			},
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		schspecs.Storage{
+			Subject: func(tb testing.TB) schedule.Storage {
+				return c.Subject(tb)
+			},
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		grspecs.Storage{
+			Subject: func(tb testing.TB) guardrail.Storage {
+				return c.Subject(tb)
+			},
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
 	)
 }
@@ -0,0 +1,55 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// The states of a delivery.
+const (
+	// DeliveryStatusPending means the delivery is not yet accepted by the receiver, and it is being retried.
+	DeliveryStatusPending = `pending`
+	// DeliveryStatusSucceeded means the receiver responded with a 2xx status code.
+	DeliveryStatusSucceeded = `succeeded`
+	// DeliveryStatusFailed means every attempt failed, and the delivery is only sent again on request.
+	DeliveryStatusFailed = `failed`
+)
+
+// Delivery is the record of sending an event to a subscription.
+// The deliveries form the delivery log, from which they can be redelivered.
+type Delivery struct {
+	ID             string `ext:"ID" json:"id"`
+	SubscriptionID string `json:"subscription_id"`
+	// EventID is unique per subscription, so an event is delivered only once,
+	// even when more toggler instances receive it.
+	EventID   string `json:"event_id"`
+	EventType string `json:"event_type"`
+	// Payload is the JSON encoded Event, which is sent as the request body.
+	Payload json.RawMessage `json:"payload"`
+	Status  string          `json:"status"`
+	// Attempts counts the requests made to the receiver, including the ones of the redeliveries.
+	Attempts int `json:"attempts"`
+	// ResponseStatus is the status code of the last response, or zero if the receiver was not reachable.
+	ResponseStatus int `json:"response_status"`
+	// Error describes why the last attempt failed.
+	Error     string    `json:"error"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DeliveryQuery selects the deliveries of a subscription, or every delivery when SubscriptionID is empty.
+type DeliveryQuery struct {
+	SubscriptionID string
+}
+
+func (q DeliveryQuery) Match(d Delivery) bool {
+	return q.SubscriptionID == `` || q.SubscriptionID == d.SubscriptionID
+}
+
+// Less tells the order of the deliveries, which is the latest first.
+func (q DeliveryQuery) Less(a, b Delivery) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
@@ -0,0 +1,439 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/iterators"
+
+	"github.com/toggler-io/toggler/domains/release"
+)
+
+// The kinds of entities whose changes are published as events.
+const (
+	KindFlag        = release.VersionKindFlag
+	KindEnvironment = `environment`
+	KindRollout     = release.VersionKindRollout
+	KindPilot       = release.VersionKindPilot
+)
+
+// NewDispatcher returns a Dispatcher which is not yet subscribed to the release storages.
+func NewDispatcher(releases release.Storage, webhooks Storage) *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		Releases:    releases,
+		Webhooks:    webhooks,
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		MaxAttempts: 5,
+		Backoff:     time.Second,
+		MaxBackoff:  time.Minute,
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Dispatcher delivers the changes of the release flags, environments, rollouts and pilots to the webhook subscriptions.
+//
+// Each event is recorded as a delivery for every matching subscription, and then it is sent in the background.
+// When more toggler instances receive the same event, only one of them records and sends the delivery,
+// because a subscription has only one delivery per event.
+// A failed attempt is retried with exponential backoff until MaxAttempts is reached.
+type Dispatcher struct {
+	Releases release.Storage
+	Webhooks Storage
+	Client   *http.Client
+	// MaxAttempts is the number of requests made for a delivery before it is marked as failed.
+	MaxAttempts int
+	// Backoff is the wait before the first retry, and it is doubled with each further retry up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// ErrorHandler is called with the errors that happen in the background,
+	// such as a failed write of the delivery log.
+	ErrorHandler func(ctx context.Context, err error)
+
+	mutex         sync.Mutex
+	closed        bool
+	subscriptions []frameless.Subscription
+	ctx           context.Context
+	cancel        func()
+	wg            sync.WaitGroup
+}
+
+type publisher interface {
+	frameless.CreatorPublisher
+	frameless.UpdaterPublisher
+	frameless.DeleterPublisher
+}
+
+// Start subscribes to the events of the release storages.
+func (d *Dispatcher) Start(ctx context.Context) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for kind, p := range map[string]publisher{
+		KindFlag:        d.Releases.ReleaseFlag(ctx),
+		KindEnvironment: d.Releases.ReleaseEnvironment(ctx),
+		KindRollout:     d.Releases.ReleaseRollout(ctx),
+		KindPilot:       d.Releases.ReleasePilot(ctx),
+	} {
+		h := eventHandler{dispatcher: d, kind: kind}
+		for _, subscribe := range []func() (frameless.Subscription, error){
+			func() (frameless.Subscription, error) { return p.SubscribeToCreatorEvents(d.ctx, h) },
+			func() (frameless.Subscription, error) { return p.SubscribeToUpdaterEvents(d.ctx, h) },
+			func() (frameless.Subscription, error) { return p.SubscribeToDeleterEvents(d.ctx, h) },
+		} {
+			sub, err := subscribe()
+			if err != nil {
+				return err
+			}
+			d.subscriptions = append(d.subscriptions, sub)
+		}
+	}
+	return nil
+}
+
+// Close unsubscribes from the release storages, and stops the deliveries in progress.
+// The interrupted deliveries stay pending, and they can be redelivered.
+func (d *Dispatcher) Close() error {
+	d.mutex.Lock()
+	d.closed = true
+	subscriptions := d.subscriptions
+	d.subscriptions = nil
+	d.mutex.Unlock()
+
+	var errs []error
+	for _, sub := range subscriptions {
+		errs = append(errs, sub.Close())
+	}
+	d.cancel()
+	d.wg.Wait()
+	return errors.Join(errs...)
+}
+
+// CreateSubscription validates and stores the subscription.
+func (d *Dispatcher) CreateSubscription(ctx context.Context, s *Subscription) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	return d.Webhooks.WebhookSubscription(ctx).Create(ctx, s)
+}
+
+// DeleteSubscription deletes the subscription together with its delivery log.
+func (d *Dispatcher) DeleteSubscription(ctx context.Context, id string) (rErr error) {
+	ctx, err := d.Webhooks.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer frameless.FinishOnePhaseCommit(&rErr, d.Webhooks, ctx)
+
+	var s Subscription
+	found, err := d.Webhooks.WebhookSubscription(ctx).FindByID(ctx, &s, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrSubscriptionNotFound
+	}
+
+	deliveries := d.Webhooks.WebhookDelivery(ctx)
+	var ids []string
+	if err := iterators.ForEach(deliveries.FindByQuery(ctx, DeliveryQuery{SubscriptionID: id}), func(delivery Delivery) error {
+		ids = append(ids, delivery.ID)
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, deliveryID := range ids {
+		if err := deliveries.DeleteByID(ctx, deliveryID); err != nil {
+			return err
+		}
+	}
+	return d.Webhooks.WebhookSubscription(ctx).DeleteByID(ctx, id)
+}
+
+// Dispatch records a delivery of the event for each matching subscription, and sends them in the background.
+func (d *Dispatcher) Dispatch(ctx context.Context, e Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	var subscriptions []Subscription
+	if err := iterators.Collect(d.Webhooks.WebhookSubscription(ctx).FindAll(ctx), &subscriptions); err != nil {
+		return err
+	}
+
+	for _, s := range subscriptions {
+		if !s.Match(e) {
+			continue
+		}
+
+		now := time.Now().UTC()
+		delivery := Delivery{
+			SubscriptionID: s.ID,
+			EventID:        e.ID,
+			EventType:      e.Type,
+			Payload:        payload,
+			Status:         DeliveryStatusPending,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		err := d.Webhooks.WebhookDelivery(ctx).Create(ctx, &delivery)
+		if errors.Is(err, ErrDeliveryAlreadyExist) {
+			// an other toggler instance delivers the event.
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		d.send(s, delivery)
+	}
+	return nil
+}
+
+// Redeliver sends a delivery of the log again in the background, with a new series of attempts.
+func (d *Dispatcher) Redeliver(ctx context.Context, deliveryID string) error {
+	var delivery Delivery
+	found, err := d.Webhooks.WebhookDelivery(ctx).FindByID(ctx, &delivery, deliveryID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrDeliveryNotFound
+	}
+
+	var s Subscription
+	found, err = d.Webhooks.WebhookSubscription(ctx).FindByID(ctx, &s, delivery.SubscriptionID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrSubscriptionNotFound
+	}
+
+	delivery.Status = DeliveryStatusPending
+	delivery.UpdatedAt = time.Now().UTC()
+	if err := d.Webhooks.WebhookDelivery(ctx).Update(ctx, &delivery); err != nil {
+		return err
+	}
+	d.send(s, delivery)
+	return nil
+}
+
+func (d *Dispatcher) send(s Subscription, delivery Delivery) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.closed {
+		return
+	}
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.deliver(d.ctx, s, delivery)
+	}()
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, s Subscription, delivery Delivery) {
+	wait := d.Backoff
+	for attempt := 1; ; attempt++ {
+		status, err := d.post(ctx, s, delivery)
+		if ctx.Err() != nil {
+			return
+		}
+
+		delivery.Attempts++
+		delivery.ResponseStatus = status
+		delivery.UpdatedAt = time.Now().UTC()
+		switch {
+		case err == nil:
+			delivery.Status = DeliveryStatusSucceeded
+			delivery.Error = ``
+		case attempt < d.MaxAttempts:
+			delivery.Status = DeliveryStatusPending
+			delivery.Error = err.Error()
+		default:
+			delivery.Status = DeliveryStatusFailed
+			delivery.Error = err.Error()
+		}
+		if err := d.Webhooks.WebhookDelivery(ctx).Update(ctx, &delivery); err != nil {
+			d.handleError(ctx, err)
+		}
+		if delivery.Status != DeliveryStatusPending {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		if wait *= 2; d.MaxBackoff < wait {
+			wait = d.MaxBackoff
+		}
+	}
+}
+
+func (d *Dispatcher) post(ctx context.Context, s Subscription, delivery Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set(`Content-Type`, `application/json`)
+	req.Header.Set(`User-Agent`, `toggler-webhook`)
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(s.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || 299 < resp.StatusCode {
+		return resp.StatusCode, fmt.Errorf(`receiver responded with %s`, resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *Dispatcher) handleError(ctx context.Context, err error) {
+	if d.ErrorHandler != nil {
+		d.ErrorHandler(ctx, err)
+	}
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+// eventHandler turns the storage events of an entity kind into webhook events.
+type eventHandler struct {
+	dispatcher *Dispatcher
+	kind       string
+}
+
+func (h eventHandler) HandleCreateEvent(ctx context.Context, e frameless.CreateEvent) error {
+	event, err := h.entityEvent(`created`, e.Entity)
+	h.dispatch(ctx, event, err)
+	return nil
+}
+
+func (h eventHandler) HandleUpdateEvent(ctx context.Context, e frameless.UpdateEvent) error {
+	event, err := h.entityEvent(`updated`, e.Entity)
+	h.dispatch(ctx, event, err)
+	return nil
+}
+
+func (h eventHandler) HandleDeleteByIDEvent(ctx context.Context, e frameless.DeleteByIDEvent) error {
+	event, err := h.deleteEvent(ctx, fmt.Sprint(e.ID))
+	h.dispatch(ctx, event, err)
+	return nil
+}
+
+// HandleDeleteAllEvent ignores the event, since it doesn't tell which entities were deleted.
+func (h eventHandler) HandleDeleteAllEvent(ctx context.Context, e frameless.DeleteAllEvent) error {
+	return nil
+}
+
+func (h eventHandler) HandleError(ctx context.Context, err error) error {
+	h.dispatcher.handleError(ctx, err)
+	return nil
+}
+
+func (h eventHandler) dispatch(ctx context.Context, e Event, err error) {
+	if err == nil {
+		err = h.dispatcher.Dispatch(ctx, e)
+	}
+	if err != nil {
+		h.dispatcher.handleError(ctx, err)
+	}
+}
+
+func (h eventHandler) entityEvent(action string, entity interface{}) (Event, error) {
+	var (
+		id            string
+		revision      int
+		environmentID string
+	)
+	switch e := entity.(type) {
+	case release.Flag:
+		id, revision = e.ID, e.Revision
+	case release.Environment:
+		id, revision, environmentID = e.ID, e.Revision, e.ID
+	case release.Rollout:
+		id, revision, environmentID = e.ID, e.Revision, e.EnvironmentID
+	case release.Pilot:
+		id, revision, environmentID = e.ID, e.Revision, e.EnvironmentID
+	default:
+		return Event{}, fmt.Errorf(`unexpected %T entity in the %s events`, entity, h.kind)
+	}
+
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		ID:            fmt.Sprintf(`%s/%s/%d`, h.kind, id, revision),
+		Type:          h.kind + `.` + action,
+		EnvironmentID: environmentID,
+		OccurredAt:    time.Now().UTC(),
+		Data:          data,
+	}, nil
+}
+
+// deleteEvent describes the deleted entity with its last version,
+// as the delete event of the storage tells only the ID.
+func (h eventHandler) deleteEvent(ctx context.Context, id string) (Event, error) {
+	e := Event{
+		ID:         fmt.Sprintf(`%s/%s/deleted`, h.kind, id),
+		Type:       h.kind + `.deleted`,
+		OccurredAt: time.Now().UTC(),
+	}
+	data, err := json.Marshal(map[string]string{`id`: id})
+	if err != nil {
+		return Event{}, err
+	}
+	e.Data = data
+
+	if h.kind == KindEnvironment {
+		e.EnvironmentID = id
+		return e, nil
+	}
+
+	var last *release.Version
+	if err := iterators.ForEach(h.dispatcher.Releases.ReleaseVersion(ctx).FindByQuery(ctx, release.VersionQuery{Kind: h.kind, EntityID: id}), func(v release.Version) error {
+		last = &v
+		return nil
+	}); err != nil {
+		return Event{}, err
+	}
+	if last == nil || !last.IsDeleted() {
+		return e, nil
+	}
+
+	e.Data = last.Snapshot
+	switch h.kind {
+	case KindRollout:
+		var rollout release.Rollout
+		if err := last.Decode(&rollout); err == nil {
+			e.EnvironmentID = rollout.EnvironmentID
+		}
+	case KindPilot:
+		var pilot release.Pilot
+		if err := last.Decode(&pilot); err == nil {
+			e.EnvironmentID = pilot.EnvironmentID
+		}
+	}
+	return e, nil
+}
@@ -0,0 +1,301 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adamluzsi/frameless/iterators"
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/domains/webhook"
+	"github.com/toggler-io/toggler/external/resource/storages"
+)
+
+const secret = `5ecret`
+
+// receiver is a webhook endpoint which records the verified requests,
+// and responds with the status codes it is told to.
+type receiver struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	statuses []int
+	requests []receivedRequest
+}
+
+type receivedRequest struct {
+	Header http.Header
+	Event  webhook.Event
+}
+
+func newReceiver(tb testing.TB) *receiver {
+	r := &receiver{}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		payload, err := io.ReadAll(req.Body)
+		require.Nil(tb, err)
+		require.Nil(tb, webhook.Verify(secret, req.Header, payload, time.Minute))
+
+		var event webhook.Event
+		require.Nil(tb, json.Unmarshal(payload, &event))
+
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		r.requests = append(r.requests, receivedRequest{Header: req.Header, Event: event})
+		status := http.StatusNoContent
+		if 0 < len(r.statuses) {
+			status, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	return r
+}
+
+// respond makes the receiver respond with the given status codes, and then with 204.
+func (r *receiver) respond(statuses ...int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.statuses = statuses
+}
+
+func (r *receiver) received() []receivedRequest {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]receivedRequest{}, r.requests...)
+}
+
+func TestDispatcher(t *testing.T) {
+	s := testcase.NewSpec(t)
+	defer s.Finish()
+
+	ctx := context.Background()
+
+	var (
+		storage = s.Let(`storage`, func(t *testcase.T) interface{} {
+			return toggler.NewVersionedStorage(storages.NewInMemory())
+		})
+		storageGet = func(t *testcase.T) toggler.Storage { return storage.Get(t).(toggler.Storage) }
+		recv       = s.Let(`receiver`, func(t *testcase.T) interface{} {
+			r := newReceiver(t)
+			t.Defer(r.Close)
+			return r
+		})
+		recvGet    = func(t *testcase.T) *receiver { return recv.Get(t).(*receiver) }
+		newStarted = func(t *testcase.T) *webhook.Dispatcher {
+			d := webhook.NewDispatcher(storageGet(t), storageGet(t))
+			d.MaxAttempts = 3
+			d.Backoff = time.Millisecond
+			d.MaxBackoff = 5 * time.Millisecond
+			require.Nil(t, d.Start(ctx))
+			t.Defer(d.Close)
+			return d
+		}
+		dispatcher    = s.Let(`dispatcher`, func(t *testcase.T) interface{} { return newStarted(t) })
+		dispatcherGet = func(t *testcase.T) *webhook.Dispatcher { return dispatcher.Get(t).(*webhook.Dispatcher) }
+		env           = s.Let(`env`, func(t *testcase.T) interface{} {
+			env := &release.Environment{Name: `production`}
+			require.Nil(t, storageGet(t).ReleaseEnvironment(ctx).Create(ctx, env))
+			return env
+		})
+		envGet = func(t *testcase.T) *release.Environment { return env.Get(t).(*release.Environment) }
+		flag   = s.Let(`flag`, func(t *testcase.T) interface{} {
+			flag := &release.Flag{Name: `new-checkout`}
+			require.Nil(t, storageGet(t).ReleaseFlag(ctx).Create(ctx, flag))
+			return flag
+		})
+		flagGet    = func(t *testcase.T) *release.Flag { return flag.Get(t).(*release.Flag) }
+		eventTypes = s.Let(`event types`, func(t *testcase.T) interface{} {
+			return []string{webhook.EventRolloutCreated, webhook.EventRolloutUpdated, webhook.EventRolloutDeleted}
+		})
+		envIDs = s.Let(`environment ids`, func(t *testcase.T) interface{} { return []string(nil) })
+		sub    = s.Let(`subscription`, func(t *testcase.T) interface{} {
+			sub := &webhook.Subscription{
+				URL:            recvGet(t).URL,
+				Secret:         secret,
+				EventTypes:     eventTypes.Get(t).([]string),
+				EnvironmentIDs: envIDs.Get(t).([]string),
+			}
+			require.Nil(t, dispatcherGet(t).CreateSubscription(ctx, sub))
+			return sub
+		})
+		subGet     = func(t *testcase.T) *webhook.Subscription { return sub.Get(t).(*webhook.Subscription) }
+		deliveries = func(t *testcase.T) []webhook.Delivery {
+			var ds []webhook.Delivery
+			iter := storageGet(t).WebhookDelivery(ctx).FindByQuery(ctx, webhook.DeliveryQuery{SubscriptionID: subGet(t).ID})
+			require.Nil(t, iterators.Collect(iter, &ds))
+			return ds
+		}
+		awaitDelivery = func(t *testcase.T, status string) webhook.Delivery {
+			var delivery webhook.Delivery
+			require.Eventually(t, func() bool {
+				ds := deliveries(t)
+				if len(ds) == 0 || ds[0].Status != status {
+					return false
+				}
+				delivery = ds[0]
+				return true
+			}, 5*time.Second, time.Millisecond)
+			return delivery
+		}
+		createRollout = func(t *testcase.T) *release.Rollout {
+			rollout := &release.Rollout{
+				FlagID:        flagGet(t).ID,
+				EnvironmentID: envGet(t).ID,
+				Plan:          release.RolloutDecisionByGlobal{State: true},
+			}
+			require.Nil(t, storageGet(t).ReleaseRollout(ctx).Create(ctx, rollout))
+			return rollout
+		}
+	)
+
+	s.Before(func(t *testcase.T) {
+		// the entities are created before the subscription, so only the changes of the test are delivered.
+		envGet(t)
+		flagGet(t)
+		subGet(t)
+	})
+
+	s.Then(`a rollout change is delivered as a signed request`, func(t *testcase.T) {
+		rollout := createRollout(t)
+		delivery := awaitDelivery(t, webhook.DeliveryStatusSucceeded)
+
+		require.Equal(t, 1, delivery.Attempts)
+		require.Equal(t, http.StatusNoContent, delivery.ResponseStatus)
+		require.Equal(t, webhook.EventRolloutCreated, delivery.EventType)
+
+		requests := recvGet(t).received()
+		require.Len(t, requests, 1)
+		require.Equal(t, webhook.EventRolloutCreated, requests[0].Header.Get(webhook.EventHeader))
+		require.Equal(t, delivery.ID, requests[0].Header.Get(webhook.DeliveryHeader))
+
+		event := requests[0].Event
+		require.Equal(t, `rollout/`+rollout.ID+`/1`, event.ID)
+		require.Equal(t, envGet(t).ID, event.EnvironmentID)
+		var data release.Rollout
+		require.Nil(t, json.Unmarshal(event.Data, &data))
+		require.Equal(t, rollout.ID, data.ID)
+		require.Equal(t, rollout.Plan, data.Plan)
+	})
+
+	s.Then(`the deleted rollout is described with its last state`, func(t *testcase.T) {
+		rollout := createRollout(t)
+		awaitDelivery(t, webhook.DeliveryStatusSucceeded)
+		require.Nil(t, storageGet(t).ReleaseRollout(ctx).DeleteByID(ctx, rollout.ID))
+
+		require.Eventually(t, func() bool { return len(recvGet(t).received()) == 2 }, 5*time.Second, time.Millisecond)
+		event := recvGet(t).received()[1].Event
+		require.Equal(t, webhook.EventRolloutDeleted, event.Type)
+		require.Equal(t, envGet(t).ID, event.EnvironmentID)
+		var data release.Rollout
+		require.Nil(t, json.Unmarshal(event.Data, &data))
+		require.Equal(t, rollout.FlagID, data.FlagID)
+	})
+
+	s.Then(`the not subscribed event types are not delivered`, func(t *testcase.T) {
+		require.Nil(t, storageGet(t).ReleaseFlag(ctx).Create(ctx, &release.Flag{Name: `other`}))
+		createRollout(t)
+		awaitDelivery(t, webhook.DeliveryStatusSucceeded)
+
+		for _, r := range recvGet(t).received() {
+			require.Equal(t, webhook.EventRolloutCreated, r.Event.Type)
+		}
+		require.Len(t, deliveries(t), 1)
+	})
+
+	s.When(`the subscription is for an other environment`, func(s *testcase.Spec) {
+		envIDs.Let(s, func(t *testcase.T) interface{} { return []string{`other-env-id`} })
+		eventTypes.Let(s, func(t *testcase.T) interface{} { return []string(nil) })
+
+		s.Then(`only the changes which don't belong to an environment are delivered`, func(t *testcase.T) {
+			createRollout(t)
+			require.Nil(t, storageGet(t).ReleaseFlag(ctx).Create(ctx, &release.Flag{Name: `other`}))
+
+			delivery := awaitDelivery(t, webhook.DeliveryStatusSucceeded)
+			require.Equal(t, webhook.EventFlagCreated, delivery.EventType)
+			require.Len(t, deliveries(t), 1)
+		})
+	})
+
+	s.When(`the receiver fails at first`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { recvGet(t).respond(http.StatusServiceUnavailable) })
+
+		s.Then(`the delivery is retried`, func(t *testcase.T) {
+			createRollout(t)
+			delivery := awaitDelivery(t, webhook.DeliveryStatusSucceeded)
+			require.Equal(t, 2, delivery.Attempts)
+			require.Empty(t, delivery.Error)
+			require.Len(t, recvGet(t).received(), 2)
+		})
+	})
+
+	s.When(`the receiver keeps failing`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) {
+			recvGet(t).respond(http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+		})
+
+		s.Then(`the delivery fails after the last attempt`, func(t *testcase.T) {
+			createRollout(t)
+			delivery := awaitDelivery(t, webhook.DeliveryStatusFailed)
+			require.Equal(t, 3, delivery.Attempts)
+			require.Equal(t, http.StatusInternalServerError, delivery.ResponseStatus)
+			require.Contains(t, delivery.Error, `500`)
+		})
+
+		s.Then(`the failed delivery can be redelivered`, func(t *testcase.T) {
+			createRollout(t)
+			failed := awaitDelivery(t, webhook.DeliveryStatusFailed)
+
+			require.Nil(t, dispatcherGet(t).Redeliver(ctx, failed.ID))
+			delivery := awaitDelivery(t, webhook.DeliveryStatusSucceeded)
+			require.Equal(t, failed.ID, delivery.ID)
+			require.Equal(t, 4, delivery.Attempts)
+
+			requests := recvGet(t).received()
+			require.Equal(t, requests[0].Event, requests[len(requests)-1].Event)
+		})
+	})
+
+	s.When(`more toggler instances receive the same change`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { newStarted(t) })
+
+		s.Then(`the event is delivered only once`, func(t *testcase.T) {
+			createRollout(t)
+			awaitDelivery(t, webhook.DeliveryStatusSucceeded)
+			require.Len(t, deliveries(t), 1)
+			require.Len(t, recvGet(t).received(), 1)
+		})
+	})
+
+	s.Describe(`.Redeliver`, func(s *testcase.Spec) {
+		s.Then(`an unknown delivery is not found`, func(t *testcase.T) {
+			require.Equal(t, webhook.ErrDeliveryNotFound, dispatcherGet(t).Redeliver(ctx, `unknown`))
+		})
+	})
+
+	s.Describe(`.DeleteSubscription`, func(s *testcase.Spec) {
+		s.Then(`the delivery log of the subscription is deleted too`, func(t *testcase.T) {
+			createRollout(t)
+			awaitDelivery(t, webhook.DeliveryStatusSucceeded)
+
+			require.Nil(t, dispatcherGet(t).DeleteSubscription(ctx, subGet(t).ID))
+			require.Empty(t, deliveries(t))
+			found, err := storageGet(t).WebhookSubscription(ctx).FindByID(ctx, &webhook.Subscription{}, subGet(t).ID)
+			require.Nil(t, err)
+			require.False(t, found)
+		})
+	})
+
+	s.Describe(`.CreateSubscription`, func(s *testcase.Spec) {
+		s.Then(`an invalid subscription is rejected`, func(t *testcase.T) {
+			err := dispatcherGet(t).CreateSubscription(ctx, &webhook.Subscription{URL: `ftp://example.com`, Secret: secret})
+			require.Equal(t, webhook.ErrInvalidURL, err)
+		})
+	})
+}
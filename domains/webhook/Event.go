@@ -0,0 +1,23 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event is a change of the release configuration, which is the payload of the deliveries.
+type Event struct {
+	// ID identifies the change, so the receiver can recognise the redelivered events.
+	// It is made from the entity and its revision, e.g. rollout/42/3.
+	ID string `json:"id"`
+	// Type is the kind of the change, e.g. rollout.updated.
+	Type string `json:"type"`
+	// EnvironmentID is the deployment environment of the changed entity,
+	// and it is empty for the entities which don't belong to one, such as flags.
+	EnvironmentID string `json:"environment_id,omitempty"`
+	// OccurredAt is the time when toggler received the change.
+	OccurredAt time.Time `json:"occurred_at"`
+	// Data is the JSON encoded state of the entity after the change.
+	// In case of a delete, it holds the last known state of the entity.
+	Data json.RawMessage `json:"data"`
+}
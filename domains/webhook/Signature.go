@@ -0,0 +1,52 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The headers of the delivery requests.
+const (
+	EventHeader    = `X-Toggler-Event`
+	DeliveryHeader = `X-Toggler-Delivery`
+	// TimestampHeader holds the unix time of the request, which is part of the signed content,
+	// so the receiver can reject the replayed requests.
+	TimestampHeader = `X-Toggler-Timestamp`
+	// SignatureHeader holds the HMAC-SHA256 signature of the request in the sha256=<hex> format.
+	SignatureHeader = `X-Toggler-Signature`
+)
+
+const signaturePrefix = `sha256=`
+
+// Sign returns the signature of the payload sent at the given unix time.
+// The signed content is the timestamp and the payload joined with a dot.
+func Sign(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte(`.`))
+	mac.Write(payload)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a received delivery request.
+// The requests older than the tolerance are rejected, unless the tolerance is zero.
+func Verify(secret string, header http.Header, payload []byte, tolerance time.Duration) error {
+	timestamp, err := strconv.ParseInt(header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if 0 < tolerance && tolerance < time.Since(time.Unix(timestamp, 0)) {
+		return ErrInvalidSignature
+	}
+	signature := header.Get(SignatureHeader)
+	if !strings.HasPrefix(signature, signaturePrefix) ||
+		!hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, payload))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
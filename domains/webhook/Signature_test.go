@@ -0,0 +1,28 @@
+package webhook_test
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/webhook"
+)
+
+func TestVerify(t *testing.T) {
+	payload := []byte(`{"type":"rollout.updated"}`)
+	signed := func(timestamp time.Time, payload []byte) http.Header {
+		header := http.Header{}
+		header.Set(webhook.TimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+		header.Set(webhook.SignatureHeader, webhook.Sign(secret, timestamp.Unix(), payload))
+		return header
+	}
+
+	require.Nil(t, webhook.Verify(secret, signed(time.Now(), payload), payload, time.Minute))
+	require.Equal(t, webhook.ErrInvalidSignature, webhook.Verify(`other`, signed(time.Now(), payload), payload, time.Minute))
+	require.Equal(t, webhook.ErrInvalidSignature, webhook.Verify(secret, signed(time.Now(), payload), []byte(`{}`), time.Minute))
+	require.Equal(t, webhook.ErrInvalidSignature, webhook.Verify(secret, signed(time.Now().Add(-time.Hour), payload), payload, time.Minute))
+	require.Equal(t, webhook.ErrInvalidSignature, webhook.Verify(secret, http.Header{}, payload, time.Minute))
+}
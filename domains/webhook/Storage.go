@@ -0,0 +1,34 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/iterators"
+)
+
+type Storage interface {
+	frameless.OnePhaseCommitProtocol
+	WebhookSubscription(context.Context) SubscriptionStorage
+	WebhookDelivery(context.Context) DeliveryStorage
+}
+
+type DeliveryEntries = iterators.Interface
+
+type SubscriptionStorage interface {
+	frameless.Creator
+	frameless.Finder
+	frameless.Updater
+	frameless.Deleter
+}
+
+// DeliveryStorage holds the delivery log.
+// Its Create fails with ErrDeliveryAlreadyExist when the subscription already has a delivery for the event.
+type DeliveryStorage interface {
+	frameless.Creator
+	frameless.Finder
+	frameless.Updater
+	frameless.Deleter
+	// FindByQuery returns the deliveries that match the query, the latest first.
+	FindByQuery(ctx context.Context, q DeliveryQuery) DeliveryEntries
+}
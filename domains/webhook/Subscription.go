@@ -0,0 +1,82 @@
+package webhook
+
+import (
+	"net/url"
+)
+
+// The event types which can be subscribed to.
+const (
+	EventFlagCreated        = `flag.created`
+	EventFlagUpdated        = `flag.updated`
+	EventFlagDeleted        = `flag.deleted`
+	EventEnvironmentCreated = `environment.created`
+	EventEnvironmentUpdated = `environment.updated`
+	EventEnvironmentDeleted = `environment.deleted`
+	EventRolloutCreated     = `rollout.created`
+	EventRolloutUpdated     = `rollout.updated`
+	EventRolloutDeleted     = `rollout.deleted`
+	EventPilotCreated       = `pilot.created`
+	EventPilotUpdated       = `pilot.updated`
+	EventPilotDeleted       = `pilot.deleted`
+)
+
+// EventTypes lists every event type in the order they are offered to the subscribers.
+var EventTypes = []string{
+	EventFlagCreated, EventFlagUpdated, EventFlagDeleted,
+	EventEnvironmentCreated, EventEnvironmentUpdated, EventEnvironmentDeleted,
+	EventRolloutCreated, EventRolloutUpdated, EventRolloutDeleted,
+	EventPilotCreated, EventPilotUpdated, EventPilotDeleted,
+}
+
+// Subscription tells where the events of the release configuration changes are delivered.
+type Subscription struct {
+	ID string `ext:"ID" json:"id"`
+	// URL is the endpoint of the receiver, which is called with a POST request for each event.
+	URL string `json:"url"`
+	// Secret is the key of the HMAC signature of the deliveries, so the receiver can verify their origin.
+	Secret string `json:"-"`
+	// EventTypes lists the subscribed event types, e.g. rollout.updated.
+	// An empty list subscribes to every event type.
+	EventTypes []string `json:"event_types"`
+	// EnvironmentIDs limits the events to the deployment environments with the given IDs.
+	// An empty list subscribes to every environment.
+	EnvironmentIDs []string `json:"environment_ids"`
+}
+
+func (s Subscription) Validate() error {
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != `http` && u.Scheme != `https`) || u.Host == `` {
+		return ErrInvalidURL
+	}
+	if s.Secret == `` {
+		return ErrSecretIsEmpty
+	}
+	for _, eventType := range s.EventTypes {
+		if !contains(EventTypes, eventType) {
+			return ErrUnknownEventType
+		}
+	}
+	return nil
+}
+
+// Match tells if the event is subscribed.
+// The events which are not bound to an environment, such as the changes of the flags,
+// are delivered regardless of the environment filter.
+func (s Subscription) Match(e Event) bool {
+	if 0 < len(s.EventTypes) && !contains(s.EventTypes, e.Type) {
+		return false
+	}
+	if 0 < len(s.EnvironmentIDs) && e.EnvironmentID != `` && !contains(s.EnvironmentIDs, e.EnvironmentID) {
+		return false
+	}
+	return true
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package webhook_test
+
+import (
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/webhook"
+)
+
+func TestSubscription(t *testing.T) {
+	s := testcase.NewSpec(t)
+	defer s.Finish()
+
+	subscription := s.Let(`subscription`, func(t *testcase.T) interface{} {
+		return webhook.Subscription{
+			URL:            `https://chat.example.com/hooks/toggler`,
+			Secret:         secret,
+			EventTypes:     []string{webhook.EventRolloutUpdated},
+			EnvironmentIDs: []string{`production`},
+		}
+	})
+	subscriptionGet := func(t *testcase.T) webhook.Subscription { return subscription.Get(t).(webhook.Subscription) }
+
+	s.Describe(`.Validate`, func(s *testcase.Spec) {
+		s.Then(`a subscription with url, secret and known event types is valid`, func(t *testcase.T) {
+			require.Nil(t, subscriptionGet(t).Validate())
+		})
+
+		s.Then(`the url has to be an absolute http url`, func(t *testcase.T) {
+			for _, u := range []string{``, `/hooks`, `ftp://example.com`, `https://`} {
+				sub := subscriptionGet(t)
+				sub.URL = u
+				require.Equal(t, webhook.ErrInvalidURL, sub.Validate(), u)
+			}
+		})
+
+		s.Then(`the secret is required`, func(t *testcase.T) {
+			sub := subscriptionGet(t)
+			sub.Secret = ``
+			require.Equal(t, webhook.ErrSecretIsEmpty, sub.Validate())
+		})
+
+		s.Then(`the event types have to be known`, func(t *testcase.T) {
+			sub := subscriptionGet(t)
+			sub.EventTypes = []string{`rollout.renamed`}
+			require.Equal(t, webhook.ErrUnknownEventType, sub.Validate())
+		})
+	})
+
+	s.Describe(`.Match`, func(s *testcase.Spec) {
+		s.Then(`the subscribed event type of the subscribed environment matches`, func(t *testcase.T) {
+			require.True(t, subscriptionGet(t).Match(webhook.Event{Type: webhook.EventRolloutUpdated, EnvironmentID: `production`}))
+		})
+
+		s.Then(`an other event type or environment doesn't match`, func(t *testcase.T) {
+			require.False(t, subscriptionGet(t).Match(webhook.Event{Type: webhook.EventRolloutDeleted, EnvironmentID: `production`}))
+			require.False(t, subscriptionGet(t).Match(webhook.Event{Type: webhook.EventRolloutUpdated, EnvironmentID: `staging`}))
+		})
+
+		s.Then(`an event without environment matches regardless of the environment filter`, func(t *testcase.T) {
+			sub := subscriptionGet(t)
+			sub.EventTypes = nil
+			require.True(t, sub.Match(webhook.Event{Type: webhook.EventFlagCreated}))
+		})
+	})
+}
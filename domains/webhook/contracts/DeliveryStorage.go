@@ -0,0 +1,149 @@
+package contracts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/contracts"
+	"github.com/adamluzsi/frameless/iterators"
+	"github.com/adamluzsi/testcase"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/webhook"
+)
+
+type DeliveryStorage struct {
+	Subject        func(testing.TB) webhook.DeliveryStorage
+	Context        func(testing.TB) context.Context
+	FixtureFactory func(testing.TB) frameless.FixtureFactory
+}
+
+func (c DeliveryStorage) storage() testcase.Var {
+	return testcase.Var{
+		Name: "webhook delivery storage",
+		Init: func(t *testcase.T) interface{} {
+			return c.Subject(t)
+		},
+	}
+}
+
+func (c DeliveryStorage) storageGet(t *testcase.T) webhook.DeliveryStorage {
+	return c.storage().Get(t).(webhook.DeliveryStorage)
+}
+
+func (c DeliveryStorage) String() string {
+	return "DeliveryStorage"
+}
+
+func (c DeliveryStorage) Test(t *testing.T) {
+	c.Spec(testcase.NewSpec(t))
+}
+
+func (c DeliveryStorage) Benchmark(b *testing.B) {
+	c.Spec(testcase.NewSpec(b))
+}
+
+func (c DeliveryStorage) Spec(s *testcase.Spec) {
+	T := webhook.Delivery{}
+	testcase.RunContract(s,
+		contracts.Creator{T: T,
+			Subject:        func(tb testing.TB) contracts.CRD { return c.Subject(tb) },
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		contracts.Finder{T: T,
+			Subject:        func(tb testing.TB) contracts.CRD { return c.Subject(tb) },
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		contracts.Updater{T: T,
+			Subject:        func(tb testing.TB) contracts.UpdaterSubject { return c.Subject(tb) },
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		contracts.Deleter{T: T,
+			Subject:        func(tb testing.TB) contracts.CRD { return c.Subject(tb) },
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+	)
+
+	s.Describe(`delivery log`, func(s *testcase.Spec) {
+		var (
+			subscriptionID = s.Let(`subscription id`, func(t *testcase.T) interface{} { return uuid.New().String() })
+			newDelivery    = func(t *testcase.T, subscriptionID, eventID string, createdAt time.Time) *webhook.Delivery {
+				d := c.FixtureFactory(t).Fixture(webhook.Delivery{}, c.Context(t)).(webhook.Delivery)
+				d.SubscriptionID = subscriptionID
+				d.EventID = eventID
+				d.CreatedAt = createdAt
+				return &d
+			}
+		)
+
+		s.Before(func(t *testcase.T) {
+			contracts.DeleteAllEntity(t, c.storageGet(t), c.Context(t))
+		})
+
+		s.Describe(`.Create`, func(s *testcase.Spec) {
+			s.When(`the subscription already has a delivery for the event`, func(s *testcase.Spec) {
+				s.Before(func(t *testcase.T) {
+					contracts.CreateEntity(t, c.storageGet(t), c.Context(t),
+						newDelivery(t, subscriptionID.Get(t).(string), `rollout/42/1`, time.Now().UTC()))
+				})
+
+				s.Then(`it fails with a conflict`, func(t *testcase.T) {
+					d := newDelivery(t, subscriptionID.Get(t).(string), `rollout/42/1`, time.Now().UTC())
+					require.Equal(t, webhook.ErrDeliveryAlreadyExist, c.storageGet(t).Create(c.Context(t), d))
+				})
+
+				s.Then(`the event can still be delivered to an other subscription`, func(t *testcase.T) {
+					d := newDelivery(t, uuid.New().String(), `rollout/42/1`, time.Now().UTC())
+					contracts.CreateEntity(t, c.storageGet(t), c.Context(t), d)
+				})
+			})
+		})
+
+		s.Describe(`.FindByQuery`, func(s *testcase.Spec) {
+			var (
+				query = s.Let(`query`, func(t *testcase.T) interface{} {
+					return webhook.DeliveryQuery{SubscriptionID: subscriptionID.Get(t).(string)}
+				})
+				subject = func(t *testcase.T) []webhook.Delivery {
+					var deliveries []webhook.Delivery
+					iter := c.storageGet(t).FindByQuery(c.Context(t), query.Get(t).(webhook.DeliveryQuery))
+					require.Nil(t, iterators.Collect(iter, &deliveries))
+					return deliveries
+				}
+			)
+
+			s.Before(func(t *testcase.T) {
+				now := time.Now().UTC().Truncate(time.Second)
+				for _, d := range []*webhook.Delivery{
+					newDelivery(t, subscriptionID.Get(t).(string), `rollout/1/1`, now.Add(-time.Hour)),
+					newDelivery(t, subscriptionID.Get(t).(string), `rollout/1/2`, now),
+					newDelivery(t, uuid.New().String(), `rollout/1/2`, now),
+				} {
+					contracts.CreateEntity(t, c.storageGet(t), c.Context(t), d)
+				}
+			})
+
+			s.Then(`it returns the deliveries of the subscription, the latest first`, func(t *testcase.T) {
+				deliveries := subject(t)
+				require.Len(t, deliveries, 2)
+				require.Equal(t, `rollout/1/2`, deliveries[0].EventID)
+				require.Equal(t, `rollout/1/1`, deliveries[1].EventID)
+			})
+
+			s.When(`the subscription is not given`, func(s *testcase.Spec) {
+				query.Let(s, func(t *testcase.T) interface{} { return webhook.DeliveryQuery{} })
+
+				s.Then(`it returns every delivery`, func(t *testcase.T) {
+					require.Len(t, subject(t), 3)
+				})
+			})
+		})
+	})
+}
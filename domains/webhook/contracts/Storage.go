@@ -0,0 +1,57 @@
+package contracts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/contracts"
+	"github.com/adamluzsi/testcase"
+
+	"github.com/toggler-io/toggler/domains/webhook"
+)
+
+type Storage struct {
+	Subject        func(testing.TB) webhook.Storage
+	Context        func(testing.TB) context.Context
+	FixtureFactory func(testing.TB) frameless.FixtureFactory
+}
+
+func (c Storage) String() string {
+	return `webhook#Storage`
+}
+
+func (c Storage) Test(t *testing.T) {
+	c.Spec(testcase.NewSpec(t))
+}
+
+func (c Storage) Benchmark(b *testing.B) {
+	c.Spec(testcase.NewSpec(b))
+}
+
+func (c Storage) Spec(s *testcase.Spec) {
+	testcase.RunContract(s,
+		SubscriptionStorage{
+			Subject: func(tb testing.TB) webhook.SubscriptionStorage {
+				return c.Subject(tb).WebhookSubscription(c.Context(tb))
+			},
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		DeliveryStorage{
+			Subject: func(tb testing.TB) webhook.DeliveryStorage {
+				return c.Subject(tb).WebhookDelivery(c.Context(tb))
+			},
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+		contracts.OnePhaseCommitProtocol{T: webhook.Subscription{},
+			Subject: func(tb testing.TB) (frameless.OnePhaseCommitProtocol, contracts.CRD) {
+				storage := c.Subject(tb)
+				return storage, storage.WebhookSubscription(c.Context(tb))
+			},
+			Context:        c.Context,
+			FixtureFactory: c.FixtureFactory,
+		},
+	)
+}
@@ -0,0 +1,12 @@
+package contracts_test
+
+import (
+	c "github.com/adamluzsi/frameless/contracts"
+	"github.com/toggler-io/toggler/domains/webhook/contracts"
+)
+
+var _ = []c.Interface{
+	contracts.Storage{},
+	contracts.SubscriptionStorage{},
+	contracts.DeliveryStorage{},
+}
@@ -0,0 +1,22 @@
+package webhook
+
+import "github.com/toggler-io/toggler/domains/errs"
+
+var (
+	ErrInvalidURL       = errs.Validation(`webhook_url_is_invalid`, `url`, `webhook url must be an absolute http or https url`)
+	ErrSecretIsEmpty    = errs.Validation(`webhook_secret_is_empty`, `secret`, `webhook secret can't be empty`)
+	ErrUnknownEventType = errs.Validation(`webhook_event_type_is_unknown`, `event_types`, `webhook event type is not known`)
+)
+
+var (
+	ErrSubscriptionNotFound = errs.NotFound(`webhook_subscription_not_found`, `webhook subscription not found`)
+	ErrDeliveryNotFound     = errs.NotFound(`webhook_delivery_not_found`, `webhook delivery not found`)
+)
+
+var (
+	ErrDeliveryAlreadyExist = errs.Conflict(`webhook_delivery_already_exist`, `webhook delivery already exist for the event`)
+)
+
+var (
+	ErrInvalidSignature = errs.Unauthorized(`webhook_signature_is_invalid`, `webhook signature is invalid`)
+)
@@ -0,0 +1,238 @@
+// Package config holds the configuration of the toggler server.
+//
+// The configuration is assembled from the defaults, the config file, the environment variables and the command line flags,
+// where each source overrides the previous ones.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/toggler-io/toggler/external/interface/httpintf/httptls"
+	"github.com/toggler-io/toggler/external/resource/caches"
+)
+
+type Config struct {
+	Storage   Storage   `json:"storage"`
+	Cache     Cache     `json:"cache"`
+	HTTP      HTTP      `json:"http"`
+	Auth      Auth      `json:"auth"`
+	Logging   Logging   `json:"logging"`
+	Telemetry Telemetry `json:"telemetry"`
+}
+
+type Storage struct {
+	// URL is the connection string of the storage, like "postgres://..." or "sqlite:///var/lib/toggler.db".
+	// When it is empty, the storage is configured from the RDS_* environment variables.
+	URL string `json:"url"`
+}
+
+type Cache struct {
+	// URL selects the cache, like "memory" or "redis://localhost:6379/0".
+	// When it is empty, the storage is used without a cache.
+	URL        string   `json:"url"`
+	TTL        Duration `json:"ttl"`
+	MaxEntries int      `json:"max_entries"`
+	Eviction   string   `json:"eviction"`
+}
+
+type HTTP struct {
+	Port              int      `json:"port"`
+	ReadTimeout       Duration `json:"read_timeout"`
+	ReadHeaderTimeout Duration `json:"read_header_timeout"`
+	WriteTimeout      Duration `json:"write_timeout"`
+	IdleTimeout       Duration `json:"idle_timeout"`
+	ShutdownTimeout   Duration `json:"shutdown_timeout"`
+	// MaxBodyBytes limits the size of the request bodies. Zero means no limit.
+	MaxBodyBytes int64 `json:"max_body_bytes"`
+	TLS          TLS   `json:"tls"`
+	CORS         CORS  `json:"cors"`
+}
+
+type TLS struct {
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
+	// ClientCAFile enables the verification of the client certificates (mTLS) with the given certificate authorities.
+	ClientCAFile string `json:"client_ca_file"`
+	// ClientAuth is either "optional" or "require", the default is "optional" when the ClientCAFile is given.
+	ClientAuth string `json:"client_auth"`
+	// WatchInterval is how often the certificate files are checked for rotation. Zero disables the reload.
+	WatchInterval Duration `json:"watch_interval"`
+	// ClientIdentities maps the subject or the common name of the client certificates to token owners,
+	// so those clients can use the API without a token.
+	ClientIdentities map[string]string `json:"client_identities"`
+}
+
+// Enabled tells if the server should serve HTTPS.
+func (c TLS) Enabled() bool {
+	return c.CertFile != `` || c.KeyFile != ``
+}
+
+// HTTPTLSConfig returns the settings of the certificates.
+func (c TLS) HTTPTLSConfig() httptls.Config {
+	return httptls.Config{
+		CertFile:     c.CertFile,
+		KeyFile:      c.KeyFile,
+		ClientCAFile: c.ClientCAFile,
+		ClientAuth:   c.ClientAuth,
+	}
+}
+
+type CORS struct {
+	// AllowedOrigins are the origins which can call the API from a browser.
+	// An empty list allows every origin.
+	AllowedOrigins []string `json:"allowed_origins"`
+}
+
+type Auth struct {
+	// MetricsToken protects the /metrics endpoint with a bearer token.
+	MetricsToken string `json:"metrics_token"`
+}
+
+type Logging struct {
+	Level  string `json:"level"`
+	Format string `json:"format"`
+}
+
+type Telemetry struct {
+	Tracing Tracing `json:"tracing"`
+}
+
+type Tracing struct {
+	Exporter     string `json:"exporter"`
+	OTLPEndpoint string `json:"otlp_endpoint"`
+	OTLPInsecure bool   `json:"otlp_insecure"`
+}
+
+// Default returns the configuration used when nothing else is given.
+func Default() Config {
+	return Config{
+		Cache: Cache{TTL: Duration(caches.DefaultTTL)},
+		HTTP: HTTP{
+			ReadHeaderTimeout: Duration(10 * time.Second),
+			IdleTimeout:       Duration(2 * time.Minute),
+			ShutdownTimeout:   Duration(25 * time.Second),
+			TLS:               TLS{WatchInterval: Duration(httptls.DefaultWatchInterval)},
+		},
+		Logging:   Logging{Level: `info`, Format: `json`},
+		Telemetry: Telemetry{Tracing: Tracing{Exporter: `none`}},
+	}
+}
+
+// Load assembles the configuration from the defaults, the config file and the environment variables.
+// The path of the config file is optional.
+func Load(path string, lookupEnv func(string) (string, bool)) (Config, error) {
+	c := Default()
+	if path != `` {
+		if err := c.readFile(path); err != nil {
+			return c, err
+		}
+	}
+	return c, c.applyEnv(lookupEnv)
+}
+
+func (c *Config) readFile(path string) error {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	// the YAML is converted to JSON, so a JSON file is accepted as well.
+	js, err := yaml.YAMLToJSON(bs)
+	if err != nil {
+		return fmt.Errorf(`invalid config file %s: %w`, path, err)
+	}
+	dec := json.NewDecoder(strings.NewReader(string(js)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf(`invalid config file %s: %w`, path, err)
+	}
+	return nil
+}
+
+func (c *Config) applyEnv(lookupEnv func(string) (string, bool)) error {
+	str := func(key string, ptr *string) {
+		if v, ok := lookupEnv(key); ok && v != `` {
+			*ptr = v
+		}
+	}
+	var err error
+	parse := func(key string, fn func(string) error) {
+		if v, ok := lookupEnv(key); ok && v != `` && err == nil {
+			if e := fn(v); e != nil {
+				err = fmt.Errorf(`invalid %s: %w`, key, e)
+			}
+		}
+	}
+
+	str(`DATABASE_URL`, &c.Storage.URL)
+	str(`CACHE_URL`, &c.Cache.URL)
+	parse(`CACHE_TTL`, c.Cache.TTL.Set)
+	parse(`PORT`, func(v string) (err error) {
+		c.HTTP.Port, err = strconv.Atoi(v)
+		return err
+	})
+	str(`TLS_CERT_FILE`, &c.HTTP.TLS.CertFile)
+	str(`TLS_KEY_FILE`, &c.HTTP.TLS.KeyFile)
+	str(`TLS_CLIENT_CA_FILE`, &c.HTTP.TLS.ClientCAFile)
+	str(`TLS_CLIENT_AUTH`, &c.HTTP.TLS.ClientAuth)
+	parse(`CORS_ALLOWED_ORIGINS`, func(v string) error {
+		c.HTTP.CORS.AllowedOrigins = SplitList(v)
+		return nil
+	})
+	str(`METRICS_TOKEN`, &c.Auth.MetricsToken)
+	str(`LOG_LEVEL`, &c.Logging.Level)
+	str(`LOG_FORMAT`, &c.Logging.Format)
+	str(`TRACING_EXPORTER`, &c.Telemetry.Tracing.Exporter)
+	str(`OTEL_EXPORTER_OTLP_ENDPOINT`, &c.Telemetry.Tracing.OTLPEndpoint)
+	parse(`OTEL_EXPORTER_OTLP_INSECURE`, func(v string) (err error) {
+		c.Telemetry.Tracing.OTLPInsecure, err = strconv.ParseBool(v)
+		return err
+	})
+	return err
+}
+
+// SplitList splits a comma separated list, like the value of CORS_ALLOWED_ORIGINS.
+func SplitList(v string) []string {
+	var list []string
+	for _, e := range strings.Split(v, `,`) {
+		if e = strings.TrimSpace(e); e != `` {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+// Duration is a time.Duration which is written as "5m" or "30s" in the config file.
+type Duration time.Duration
+
+func (d Duration) Duration() time.Duration { return time.Duration(d) }
+
+func (d Duration) String() string { return time.Duration(d).String() }
+
+// Set parses the duration, so it can be used as a flag.Value as well.
+func (d *Duration) Set(v string) error {
+	pd, err := time.ParseDuration(v)
+	if err != nil {
+		return err
+	}
+	*d = Duration(pd)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(bs []byte) error {
+	var v string
+	if err := json.Unmarshal(bs, &v); err != nil {
+		return fmt.Errorf(`duration must be a string like "30s": %w`, err)
+	}
+	return d.Set(v)
+}
@@ -0,0 +1,134 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/external/config"
+)
+
+func TestLoad(t *testing.T) {
+	s := testcase.NewSpec(t)
+	defer s.Finish()
+
+	content := s.LetValue(`content`, ``)
+	env := s.Let(`env`, func(t *testcase.T) interface{} { return map[string]string{} })
+	path := s.Let(`path`, func(t *testcase.T) interface{} {
+		if content.Get(t).(string) == `` {
+			return ``
+		}
+		p := filepath.Join(t.TempDir(), `toggler.yml`)
+		require.Nil(t, ioutil.WriteFile(p, []byte(content.Get(t).(string)), 0600))
+		return p
+	})
+	subject := func(t *testcase.T) (config.Config, error) {
+		return config.Load(path.Get(t).(string), func(key string) (string, bool) {
+			v, ok := env.Get(t).(map[string]string)[key]
+			return v, ok
+		})
+	}
+
+	s.When(`there is no config file and environment`, func(s *testcase.Spec) {
+		s.Then(`the defaults are used`, func(t *testcase.T) {
+			c, err := subject(t)
+			require.Nil(t, err)
+			require.Equal(t, config.Default(), c)
+			require.Nil(t, c.Validate())
+		})
+	})
+
+	s.When(`the config file is given`, func(s *testcase.Spec) {
+		content.LetValue(s, `
+storage:
+  url: memory
+cache:
+  url: memory
+  ttl: 1m
+  max_entries: 42
+  eviction: lfu
+http:
+  port: 8080
+  read_timeout: 15s
+  max_body_bytes: 1048576
+  cors:
+    allowed_origins: ["https://example.com"]
+logging:
+  level: debug
+`)
+
+		s.Then(`its values override the defaults`, func(t *testcase.T) {
+			c, err := subject(t)
+			require.Nil(t, err)
+			require.Equal(t, `memory`, c.Storage.URL)
+			require.Equal(t, time.Minute, c.Cache.TTL.Duration())
+			require.Equal(t, 42, c.Cache.MaxEntries)
+			require.Equal(t, `lfu`, c.Cache.Eviction)
+			require.Equal(t, 8080, c.HTTP.Port)
+			require.Equal(t, 15*time.Second, c.HTTP.ReadTimeout.Duration())
+			require.Equal(t, int64(1048576), c.HTTP.MaxBodyBytes)
+			require.Equal(t, []string{`https://example.com`}, c.HTTP.CORS.AllowedOrigins)
+			require.Equal(t, `debug`, c.Logging.Level)
+			require.Equal(t, config.Default().Logging.Format, c.Logging.Format)
+			require.Nil(t, c.Validate())
+		})
+
+		s.And(`the environment variables are set as well`, func(s *testcase.Spec) {
+			env.Let(s, func(t *testcase.T) interface{} {
+				return map[string]string{`PORT`: `9090`, `CACHE_TTL`: `30s`}
+			})
+
+			s.Then(`the environment overrides the config file`, func(t *testcase.T) {
+				c, err := subject(t)
+				require.Nil(t, err)
+				require.Equal(t, 9090, c.HTTP.Port)
+				require.Equal(t, 30*time.Second, c.Cache.TTL.Duration())
+				require.Equal(t, `memory`, c.Storage.URL)
+			})
+		})
+
+		s.And(`it has an unknown field`, func(s *testcase.Spec) {
+			content.LetValue(s, "http:\n  prot: 8080\n")
+
+			s.Then(`it is rejected`, func(t *testcase.T) {
+				_, err := subject(t)
+				require.Error(t, err)
+			})
+		})
+	})
+
+	s.When(`the environment has an invalid value`, func(s *testcase.Spec) {
+		env.Let(s, func(t *testcase.T) interface{} { return map[string]string{`CACHE_TTL`: `soon`} })
+
+		s.Then(`an error is returned`, func(t *testcase.T) {
+			_, err := subject(t)
+			require.Error(t, err)
+		})
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	s := testcase.NewSpec(t)
+	defer s.Finish()
+
+	for name, change := range map[string]func(*config.Config){
+		`negative cache ttl`:     func(c *config.Config) { c.Cache.TTL = -1 },
+		`unknown eviction`:       func(c *config.Config) { c.Cache.Eviction = `random` },
+		`invalid port`:           func(c *config.Config) { c.HTTP.Port = 70000 },
+		`half configured tls`:    func(c *config.Config) { c.HTTP.TLS.CertFile = `cert.pem` },
+		`invalid cors origin`:    func(c *config.Config) { c.HTTP.CORS.AllowedOrigins = []string{`example.com`} },
+		`unknown log level`:      func(c *config.Config) { c.Logging.Level = `loud` },
+		`unknown tracing export`: func(c *config.Config) { c.Telemetry.Tracing.Exporter = `zipkin` },
+	} {
+		change := change
+		s.Then(name+` is rejected`, func(t *testcase.T) {
+			c := config.Default()
+			change(&c)
+			require.Error(t, c.Validate())
+		})
+	}
+}
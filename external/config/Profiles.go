@@ -0,0 +1,76 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ghodss/yaml"
+)
+
+// Profiles are the connection settings of the remote toggler servers, which the admin commands can manage.
+//
+//	default: staging
+//	profiles:
+//	  staging:
+//	    url: https://toggler.staging.example.com
+//	    token: ...
+type Profiles struct {
+	// Default is the name of the profile used when no profile is selected.
+	Default  string             `json:"default"`
+	Profiles map[string]Profile `json:"profiles"`
+}
+
+type Profile struct {
+	URL   string `json:"url"`
+	Token string `json:"token"`
+}
+
+// DefaultProfilesPath returns where the profiles are looked for by default,
+// like "~/.config/toggler/profiles.yaml" on linux.
+func DefaultProfilesPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ``, err
+	}
+	return filepath.Join(dir, `toggler`, `profiles.yaml`), nil
+}
+
+// LoadProfiles reads the profiles file.
+// A missing file is not an error, it means that there is no profile.
+func LoadProfiles(path string) (Profiles, error) {
+	var ps Profiles
+	bs, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ps, nil
+	}
+	if err != nil {
+		return ps, err
+	}
+	js, err := yaml.YAMLToJSON(bs)
+	if err != nil {
+		return ps, fmt.Errorf(`invalid profiles file %s: %w`, path, err)
+	}
+	if err := json.Unmarshal(js, &ps); err != nil {
+		return ps, fmt.Errorf(`invalid profiles file %s: %w`, path, err)
+	}
+	return ps, nil
+}
+
+// Lookup returns the profile with the given name, or the default profile when the name is empty.
+func (ps Profiles) Lookup(name string) (Profile, error) {
+	if name == `` {
+		name = ps.Default
+	}
+	if name == `` {
+		return Profile{}, nil
+	}
+	p, ok := ps.Profiles[name]
+	if !ok {
+		return p, fmt.Errorf(`unknown profile: %s`, name)
+	}
+	return p, nil
+}
@@ -0,0 +1,63 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/external/config"
+)
+
+func TestProfiles(t *testing.T) {
+	s := testcase.NewSpec(t)
+	defer s.Finish()
+
+	path := s.Let(`path`, func(t *testcase.T) interface{} {
+		p := filepath.Join(t.TempDir(), `profiles.yaml`)
+		require.Nil(t, ioutil.WriteFile(p, []byte(`
+default: staging
+profiles:
+  staging:
+    url: https://staging.example.com
+    token: staging-token
+  prod:
+    url: https://prod.example.com
+`), 0600))
+		return p
+	})
+	profiles := func(t *testcase.T) config.Profiles {
+		ps, err := config.LoadProfiles(path.Get(t).(string))
+		require.Nil(t, err)
+		return ps
+	}
+
+	s.Then(`the default profile is used when no profile is selected`, func(t *testcase.T) {
+		p, err := profiles(t).Lookup(``)
+		require.Nil(t, err)
+		require.Equal(t, config.Profile{URL: `https://staging.example.com`, Token: `staging-token`}, p)
+	})
+
+	s.Then(`the profile can be selected by its name`, func(t *testcase.T) {
+		p, err := profiles(t).Lookup(`prod`)
+		require.Nil(t, err)
+		require.Equal(t, `https://prod.example.com`, p.URL)
+	})
+
+	s.Then(`an unknown profile is an error`, func(t *testcase.T) {
+		_, err := profiles(t).Lookup(`qa`)
+		require.Error(t, err)
+	})
+
+	s.When(`the profiles file doesn't exist`, func(s *testcase.Spec) {
+		path.LetValue(s, filepath.Join(`testdata`, `missing.yaml`))
+
+		s.Then(`there is no profile`, func(t *testcase.T) {
+			p, err := profiles(t).Lookup(``)
+			require.Nil(t, err)
+			require.Equal(t, config.Profile{}, p)
+		})
+	})
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/toggler-io/toggler/external/interface/tracing"
+	"github.com/toggler-io/toggler/external/logging"
+	"github.com/toggler-io/toggler/external/resource/caches"
+)
+
+// Validate checks the whole configuration, and reports every problem it finds, not just the first one.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.Storage.URL != `` {
+		_, err := url.Parse(c.Storage.URL)
+		check(wrap(`storage.url`, err))
+	}
+
+	check(wrap(`cache`, caches.Config{
+		TTL:        c.Cache.TTL.Duration(),
+		MaxEntries: c.Cache.MaxEntries,
+		Eviction:   c.Cache.Eviction,
+	}.Validate()))
+
+	if c.HTTP.Port < 0 || 65535 < c.HTTP.Port {
+		check(fmt.Errorf(`http.port: %d is not a valid port`, c.HTTP.Port))
+	}
+	for name, d := range map[string]Duration{
+		`http.read_timeout`:        c.HTTP.ReadTimeout,
+		`http.read_header_timeout`: c.HTTP.ReadHeaderTimeout,
+		`http.write_timeout`:       c.HTTP.WriteTimeout,
+		`http.idle_timeout`:        c.HTTP.IdleTimeout,
+		`http.shutdown_timeout`:    c.HTTP.ShutdownTimeout,
+	} {
+		if d < 0 {
+			check(fmt.Errorf(`%s: can't be negative`, name))
+		}
+	}
+	if c.HTTP.ShutdownTimeout == 0 {
+		check(errors.New(`http.shutdown_timeout: must be greater than zero`))
+	}
+	if c.HTTP.MaxBodyBytes < 0 {
+		check(errors.New(`http.max_body_bytes: can't be negative`))
+	}
+	if c.HTTP.TLS.Enabled() {
+		check(wrap(`http.tls`, c.HTTP.TLS.HTTPTLSConfig().Validate()))
+	}
+	if c.HTTP.TLS.WatchInterval < 0 {
+		check(errors.New(`http.tls.watch_interval: can't be negative`))
+	}
+	if len(c.HTTP.TLS.ClientIdentities) != 0 && c.HTTP.TLS.ClientCAFile == `` {
+		check(errors.New(`http.tls.client_identities: requires the client_ca_file to verify the client certificates`))
+	}
+	for _, origin := range c.HTTP.CORS.AllowedOrigins {
+		if origin == `*` {
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || u.Scheme == `` || u.Host == `` {
+			check(fmt.Errorf(`http.cors.allowed_origins: %q is not an origin like "https://example.com"`, origin))
+		}
+	}
+
+	_, err := logging.New(logging.Config{Level: c.Logging.Level, Format: c.Logging.Format})
+	check(wrap(`logging`, err))
+
+	switch c.Telemetry.Tracing.Exporter {
+	case ``, tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP:
+	default:
+		check(fmt.Errorf(`telemetry.tracing.exporter: unknown exporter %q`, c.Telemetry.Tracing.Exporter))
+	}
+
+	return errors.Join(errs...)
+}
+
+func wrap(field string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf(`%s: %w`, field, err)
+}
@@ -2,9 +2,11 @@ package httpintf
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/adamluzsi/gorest"
 
+	"github.com/toggler-io/toggler/external/interface/httpintf/health"
 	"github.com/toggler-io/toggler/external/interface/httpintf/httputils"
 	"github.com/toggler-io/toggler/external/interface/httpintf/swagger"
 
@@ -13,11 +15,25 @@ import (
 	"github.com/toggler-io/toggler/external/interface/httpintf/webgui"
 )
 
-func NewServeMux(uc *toggler.UseCases) (*http.ServeMux, error) {
-	mux := http.NewServeMux()
+// NewServeMux creates the handler of the http interfaces.
+// The checks are the dependencies of the application, which are verified by the readiness health check.
+func NewServeMux(uc *toggler.UseCases, checks ...health.Check) (*ServeMux, error) {
+	mux := &ServeMux{
+		ServeMux:         http.NewServeMux(),
+		CORS:             &httputils.CORSPolicy{},
+		ClientIdentities: &httputils.ClientCertificateIdentities{},
+		api:              httpapi.NewHandler(uc),
+	}
+
+	mux.Handle(`/api/`, mux.CORS.Handler(mux.ClientIdentities.Handler(http.StripPrefix(`/api`, mux.api))))
+	// the websocket API is not served yet, its capacity is checked by the readiness once it is mounted.
+	//wsConnections := &httpws.Connections{Max: httpws.DefaultMaxConnections}
+	//checks = append(checks, health.Check{Name: `websocket`, Optional: true, Check: wsConnections.Check})
+	//mux.Handle(`/ws/`, mux.CORS.Handler(mux.ClientIdentities.Handler(http.StripPrefix(`/ws`, httpws.NewHandler(uc, wsConnections)))))
 
-	mux.Handle(`/api/`, httputils.CORS(http.StripPrefix(`/api`, httpapi.NewHandler(uc))))
-	//mux.Handle(`/ws/`, httputils.CORS(http.StripPrefix(`/ws`, httpws.NewHandler(uc))))
+	healthHandler := http.StripPrefix(`/api/healthcheck`, health.NewHandler(checks...))
+	mux.Handle(`/api/healthcheck`, healthHandler)
+	mux.Handle(`/api/healthcheck/`, healthHandler)
 
 	ui, err := webgui.NewHandler(uc)
 	if err != nil {
@@ -27,7 +43,32 @@ func NewServeMux(uc *toggler.UseCases) (*http.ServeMux, error) {
 	mux.Handle(`/`, ui)
 
 	// TODO: fix the behavior where "/swagger/ui" redirects to "/ui"
-	gorest.Mount(mux, `/swagger`, swagger.NewHandler())
+	gorest.Mount(mux.ServeMux, `/swagger`, swagger.NewHandler())
 
 	return mux, nil
 }
+
+type ServeMux struct {
+	*http.ServeMux
+	// CORS is the policy of the API, by default it allows every origin.
+	CORS *httputils.CORSPolicy
+	// ClientIdentities maps the client certificates to token owners of the API, by default it is empty.
+	ClientIdentities *httputils.ClientCertificateIdentities
+	api              *httpapi.Handler
+}
+
+// Route returns the pattern of the handler which serves the request, like "/api/release-flags/",
+// so the requests of the same endpoint can be grouped together regardless of the IDs in their path.
+func (mux *ServeMux) Route(r *http.Request) string {
+	_, pattern := mux.ServeMux.Handler(r)
+	if pattern != `/api/` {
+		return pattern
+	}
+
+	apiRequest := r.Clone(r.Context())
+	apiRequest.URL.Path = strings.TrimPrefix(r.URL.Path, `/api`)
+	if _, apiPattern := mux.api.Handler(apiRequest); apiPattern != `` {
+		return `/api` + apiPattern
+	}
+	return pattern
+}
@@ -0,0 +1,177 @@
+// Package apiclient is the client of the toggler HTTP API,
+// which is used by the admin commands to manage a remote toggler.
+package apiclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/toggler-io/toggler/domains/change"
+	"github.com/toggler-io/toggler/domains/errs"
+)
+
+// Client calls the API of a toggler server.
+type Client struct {
+	// BaseURL is the address of the server, like "https://toggler.example.com".
+	BaseURL string
+	// Token is the security token the requests are made with.
+	Token string
+	// HTTPClient is used for the requests, by default the http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+func (c Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// do makes a request to the API, and decodes the response body into the out value, when it is given.
+// The error responses of the API are returned as errs.Error values, so they can be inspected with errs.Lookup,
+// and the changes proposed in a protected environment as ProposedError.
+func (c Client) do(ctx context.Context, method, path string, query url.Values, ifMatch int, in, out interface{}) error {
+	u, err := url.Parse(strings.TrimSuffix(c.BaseURL, `/`) + `/api` + path)
+	if err != nil {
+		return err
+	}
+	u.RawQuery = query.Encode()
+
+	var body io.Reader
+	if in != nil {
+		bs, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(bs)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(`Accept`, `application/json`)
+	if in != nil {
+		req.Header.Set(`Content-Type`, `application/json`)
+	}
+	if c.Token != `` {
+		req.Header.Set(`X-App-Token`, c.Token)
+	}
+	if 0 < ifMatch {
+		req.Header.Set(`If-Match`, strconv.Quote(strconv.Itoa(ifMatch)))
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || 300 <= resp.StatusCode {
+		return decodeError(resp)
+	}
+	if resp.StatusCode == http.StatusAccepted {
+		var proposed ProposedError
+		if err := json.NewDecoder(resp.Body).Decode(&proposed); err != nil {
+			return err
+		}
+		return proposed
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// ProposedError tells that the change was not made, because its deployment environment is protected,
+// and it was proposed as a change request instead, which is made once it is approved and applied.
+type ProposedError struct {
+	ChangeRequest change.Request `json:"change_request"`
+}
+
+func (err ProposedError) Error() string {
+	return fmt.Sprintf(`the deployment environment is protected, the change is proposed as the change request %s`, err.ChangeRequest.ID)
+}
+
+// decodeError turns the error response of the API into a domain error.
+func decodeError(resp *http.Response) error {
+	bs, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	var errResp struct {
+		Error struct {
+			Code    int    `json:"code"`
+			Key     string `json:"key"`
+			Kind    string `json:"kind"`
+			Field   string `json:"field"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(bs, &errResp); err != nil || errResp.Error.Key == `` {
+		msg := strings.TrimSpace(string(bs))
+		if msg == `` {
+			msg = http.StatusText(resp.StatusCode)
+		}
+		return fmt.Errorf(`%s %s: %d %s`, resp.Request.Method, resp.Request.URL.Path, resp.StatusCode, msg)
+	}
+
+	kind := errs.Kind(errResp.Error.Kind)
+	if kind == `` {
+		kind = kindOfStatus(resp.StatusCode)
+	}
+	return errs.Error{
+		Kind:    kind,
+		Code:    errResp.Error.Key,
+		Field:   errResp.Error.Field,
+		Message: errResp.Error.Message,
+	}
+}
+
+func kindOfStatus(code int) errs.Kind {
+	switch code {
+	case http.StatusBadRequest:
+		return errs.KindValidation
+	case http.StatusNotFound:
+		return errs.KindNotFound
+	case http.StatusConflict:
+		return errs.KindConflict
+	case http.StatusPreconditionFailed, http.StatusPreconditionRequired:
+		return errs.KindPreconditionFailed
+	case http.StatusUnauthorized:
+		return errs.KindUnauthorized
+	case http.StatusForbidden:
+		return errs.KindForbidden
+	case http.StatusServiceUnavailable:
+		return errs.KindUnavailable
+	default:
+		return ``
+	}
+}
+
+// listAll follows the cursors of a paginated listing until its last page.
+// The page func decodes a page into the out value, and returns its next cursor.
+func (c Client) listAll(ctx context.Context, path string, query url.Values, page func(body []byte) (string, error)) error {
+	if query == nil {
+		query = url.Values{}
+	}
+	for {
+		var raw json.RawMessage
+		if err := c.do(ctx, http.MethodGet, path, query, 0, nil, &raw); err != nil {
+			return err
+		}
+		next, err := page(raw)
+		if err != nil {
+			return err
+		}
+		if next == `` {
+			return nil
+		}
+		query.Set(`cursor`, next)
+	}
+}
@@ -0,0 +1,118 @@
+package apiclient_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/errs"
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/external/interface/httpintf"
+	"github.com/toggler-io/toggler/external/interface/httpintf/apiclient"
+	sh "github.com/toggler-io/toggler/spechelper"
+)
+
+func TestClient(t *testing.T) {
+	s := sh.NewSpec(t)
+	defer s.Finish()
+
+	client := s.Let(`client`, func(t *testcase.T) interface{} {
+		mux, err := httpintf.NewServeMux(sh.ExampleUseCases(t))
+		require.Nil(t, err)
+		server := httptest.NewServer(mux)
+		t.Defer(server.Close)
+		return apiclient.Client{BaseURL: server.URL, Token: sh.ExampleTextToken(t)}
+	})
+	clientGet := func(t *testcase.T) apiclient.Client { return client.Get(t).(apiclient.Client) }
+
+	s.Test(`the flags can be created, listed, found and deleted`, func(t *testcase.T) {
+		c := clientGet(t)
+		flag, err := c.CreateFlag(sh.ContextGet(t), release.Flag{Name: `new-checkout`})
+		require.Nil(t, err)
+		require.NotEmpty(t, flag.ID)
+
+		flags, err := c.ListFlags(sh.ContextGet(t), ``)
+		require.Nil(t, err)
+		require.Contains(t, flags, flag)
+
+		found, err := c.FindFlag(sh.ContextGet(t), `new-checkout`)
+		require.Nil(t, err)
+		require.Equal(t, flag, found)
+
+		require.Nil(t, c.DeleteFlag(sh.ContextGet(t), flag.ID))
+		_, err = c.FindFlag(sh.ContextGet(t), `new-checkout`)
+		require.Equal(t, release.ErrFlagNotFound, err)
+	})
+
+	s.Test(`the rollout plan of a flag is created and then updated`, func(t *testcase.T) {
+		c := clientGet(t)
+		flag, err := c.CreateFlag(sh.ContextGet(t), release.Flag{Name: `new-checkout`})
+		require.Nil(t, err)
+		env, err := c.CreateEnvironment(sh.ContextGet(t), release.Environment{Name: `prod`})
+		require.Nil(t, err)
+
+		found, err := c.FindEnvironment(sh.ContextGet(t), `prod`)
+		require.Nil(t, err)
+		require.Equal(t, env.ID, found.ID)
+
+		global := func(state bool) func(release.RolloutPlan) release.RolloutPlan {
+			return func(release.RolloutPlan) release.RolloutPlan { return release.RolloutDecisionByGlobal{State: state} }
+		}
+		_, err = c.SetRolloutPlan(sh.ContextGet(t), flag.ID, env.ID, global(false))
+		require.Nil(t, err)
+		_, err = c.SetRolloutPlan(sh.ContextGet(t), flag.ID, env.ID, global(true))
+		require.Nil(t, err)
+
+		rollouts, err := c.ListRollouts(sh.ContextGet(t), flag.ID, env.ID)
+		require.Nil(t, err)
+		require.Len(t, rollouts, 1)
+		require.Equal(t, release.RolloutDecisionByGlobal{State: true}, rollouts[0].Plan)
+
+		_, err = c.SetPilotEnrollment(sh.ContextGet(t), flag.ID, env.ID, `pilot-1`, false)
+		require.Nil(t, err)
+		evaluations, err := c.Evaluate(sh.ContextGet(t), `pilot-1`, `prod`, time.Time{}, `new-checkout`)
+		require.Nil(t, err)
+		require.Len(t, evaluations, 1)
+		require.False(t, evaluations[0].State)
+
+		_, err = c.SetPilotEnrollment(sh.ContextGet(t), flag.ID, env.ID, `pilot-1`, true)
+		require.Nil(t, err)
+		pilots, err := c.ListPilots(sh.ContextGet(t), flag.ID, env.ID)
+		require.Nil(t, err)
+		require.Len(t, pilots, 1)
+		require.True(t, pilots[0].IsParticipating)
+	})
+
+	s.Test(`the changes of a protected environment are proposed as change requests`, func(t *testcase.T) {
+		c := clientGet(t)
+		flag, err := c.CreateFlag(sh.ContextGet(t), release.Flag{Name: `new-checkout`})
+		require.Nil(t, err)
+		env, err := c.CreateEnvironment(sh.ContextGet(t), release.Environment{Name: `prod`, RequiredApprovals: 1})
+		require.Nil(t, err)
+
+		_, err = c.SetRolloutPlan(sh.ContextGet(t), flag.ID, env.ID, func(release.RolloutPlan) release.RolloutPlan {
+			return release.RolloutDecisionByGlobal{State: true}
+		})
+		var proposed apiclient.ProposedError
+		require.True(t, errors.As(err, &proposed))
+		require.NotEmpty(t, proposed.ChangeRequest.ID)
+		require.Equal(t, env.ID, proposed.ChangeRequest.EnvironmentID)
+
+		rollouts, err := c.ListRollouts(sh.ContextGet(t), flag.ID, env.ID)
+		require.Nil(t, err)
+		require.Empty(t, rollouts)
+	})
+
+	s.Test(`the error responses are returned as domain errors`, func(t *testcase.T) {
+		c := clientGet(t)
+		c.Token = `invalid`
+		_, err := c.ListEnvironments(sh.ContextGet(t), ``)
+		domainErr, ok := errs.Lookup(err)
+		require.True(t, ok)
+		require.Equal(t, errs.KindUnauthorized, domainErr.Kind)
+	})
+}
@@ -0,0 +1,247 @@
+package apiclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/toggler-io/toggler/domains/errs"
+	"github.com/toggler-io/toggler/domains/release"
+)
+
+func (c Client) ListFlags(ctx context.Context, namePrefix string) ([]release.Flag, error) {
+	var flags []release.Flag
+	query := url.Values{}
+	if namePrefix != `` {
+		query.Set(`name_prefix`, namePrefix)
+	}
+	return flags, c.listAll(ctx, `/release-flags`, query, func(body []byte) (string, error) {
+		var page struct {
+			Flags      []release.Flag `json:"flags"`
+			NextCursor string         `json:"next_cursor"`
+		}
+		err := json.Unmarshal(body, &page)
+		flags = append(flags, page.Flags...)
+		return page.NextCursor, err
+	})
+}
+
+// FindFlag looks up a release flag by its name or by its ID.
+func (c Client) FindFlag(ctx context.Context, nameOrID string) (release.Flag, error) {
+	flags, err := c.ListFlags(ctx, nameOrID)
+	if err != nil {
+		return release.Flag{}, err
+	}
+	for _, f := range flags {
+		if f.Name == nameOrID {
+			return f, nil
+		}
+	}
+
+	var resp struct {
+		Flag release.Flag `json:"flag"`
+	}
+	err = c.do(ctx, http.MethodGet, `/release-flags/`+url.PathEscape(nameOrID), nil, 0, nil, &resp)
+	if isNotFound(err) {
+		return release.Flag{}, release.ErrFlagNotFound
+	}
+	return resp.Flag, err
+}
+
+func (c Client) CreateFlag(ctx context.Context, flag release.Flag) (release.Flag, error) {
+	var req, resp struct {
+		Flag release.Flag `json:"flag"`
+	}
+	req.Flag = flag
+	err := c.do(ctx, http.MethodPost, `/release-flags`, nil, 0, req, &resp)
+	return resp.Flag, err
+}
+
+func (c Client) DeleteFlag(ctx context.Context, flagID string) error {
+	return c.do(ctx, http.MethodDelete, `/release-flags/`+url.PathEscape(flagID), nil, 0, nil, nil)
+}
+
+func (c Client) ListEnvironments(ctx context.Context, namePrefix string) ([]release.Environment, error) {
+	var envs []release.Environment
+	query := url.Values{}
+	if namePrefix != `` {
+		query.Set(`name_prefix`, namePrefix)
+	}
+	return envs, c.listAll(ctx, `/deployment-environments`, query, func(body []byte) (string, error) {
+		var page struct {
+			Environments []release.Environment `json:"environments"`
+			NextCursor   string                `json:"next_cursor"`
+		}
+		err := json.Unmarshal(body, &page)
+		envs = append(envs, page.Environments...)
+		return page.NextCursor, err
+	})
+}
+
+// FindEnvironment looks up a deployment environment by its name or by its ID.
+func (c Client) FindEnvironment(ctx context.Context, nameOrID string) (release.Environment, error) {
+	envs, err := c.ListEnvironments(ctx, nameOrID)
+	if err != nil {
+		return release.Environment{}, err
+	}
+	for _, env := range envs {
+		if env.Name == nameOrID {
+			return env, nil
+		}
+	}
+
+	var resp struct {
+		Environment release.Environment `json:"environment"`
+	}
+	err = c.do(ctx, http.MethodGet, `/deployment-environments/`+url.PathEscape(nameOrID), nil, 0, nil, &resp)
+	if isNotFound(err) {
+		return release.Environment{}, release.ErrEnvironmentNotFound
+	}
+	return resp.Environment, err
+}
+
+func (c Client) CreateEnvironment(ctx context.Context, env release.Environment) (release.Environment, error) {
+	var req, resp struct {
+		Environment release.Environment `json:"environment"`
+	}
+	req.Environment = env
+	err := c.do(ctx, http.MethodPost, `/deployment-environments`, nil, 0, req, &resp)
+	return resp.Environment, err
+}
+
+func (c Client) DeleteEnvironment(ctx context.Context, envID string) error {
+	return c.do(ctx, http.MethodDelete, `/deployment-environments/`+url.PathEscape(envID), nil, 0, nil, nil)
+}
+
+// ListRollouts lists the rollouts, optionally filtered by the flag and the environment IDs.
+func (c Client) ListRollouts(ctx context.Context, flagID, envID string) ([]release.Rollout, error) {
+	var rollouts []release.Rollout
+	query := url.Values{}
+	if flagID != `` {
+		query.Set(`flag_id`, flagID)
+	}
+	if envID != `` {
+		query.Set(`env_id`, envID)
+	}
+	return rollouts, c.listAll(ctx, `/release-rollouts`, query, func(body []byte) (string, error) {
+		var page struct {
+			Rollouts   []release.Rollout `json:"rollouts"`
+			NextCursor string            `json:"next_cursor"`
+		}
+		err := json.Unmarshal(body, &page)
+		rollouts = append(rollouts, page.Rollouts...)
+		return page.NextCursor, err
+	})
+}
+
+// SetRolloutPlan sets the plan of the flag's rollout in the environment.
+// The rollout is created when the flag has no rollout in the environment yet.
+// The plan func receives the current plan, or nil for a new rollout.
+func (c Client) SetRolloutPlan(ctx context.Context, flagID, envID string, plan func(current release.RolloutPlan) release.RolloutPlan) (release.Rollout, error) {
+	rollouts, err := c.ListRollouts(ctx, flagID, envID)
+	if err != nil {
+		return release.Rollout{}, err
+	}
+
+	if len(rollouts) == 0 {
+		var req, resp struct {
+			Rollout release.Rollout `json:"rollout"`
+		}
+		req.Rollout = release.Rollout{FlagID: flagID, EnvironmentID: envID, Plan: plan(nil)}
+		err := c.do(ctx, http.MethodPost, `/release-rollouts`, nil, 0, req, &resp)
+		return resp.Rollout, err
+	}
+
+	rollout := rollouts[0]
+	rollout.Plan = plan(rollout.Plan)
+	var req struct {
+		Rollout release.Rollout `json:"rollout"`
+	}
+	req.Rollout = rollout
+	err = c.do(ctx, http.MethodPut, `/release-rollouts/`+url.PathEscape(rollout.ID), nil, rollout.Revision, req, nil)
+	rollout.Revision++
+	return rollout, err
+}
+
+// DeleteRollout removes the rollout of the flag in the environment.
+func (c Client) DeleteRollout(ctx context.Context, flagID, envID string) error {
+	rollouts, err := c.ListRollouts(ctx, flagID, envID)
+	if err != nil {
+		return err
+	}
+	if len(rollouts) == 0 {
+		return release.ErrRolloutNotFound
+	}
+	return c.do(ctx, http.MethodDelete, `/release-rollouts/`+url.PathEscape(rollouts[0].ID), nil, 0, nil, nil)
+}
+
+// ListPilots lists the pilots, optionally filtered by the flag and the environment IDs.
+func (c Client) ListPilots(ctx context.Context, flagID, envID string) ([]release.Pilot, error) {
+	return c.listPilots(ctx, flagID, envID, ``)
+}
+
+func (c Client) listPilots(ctx context.Context, flagID, envID, publicID string) ([]release.Pilot, error) {
+	var pilots []release.Pilot
+	query := url.Values{}
+	for key, value := range map[string]string{`flag_id`: flagID, `env_id`: envID, `public_id`: publicID} {
+		if value != `` {
+			query.Set(key, value)
+		}
+	}
+	return pilots, c.listAll(ctx, `/release-pilots`, query, func(body []byte) (string, error) {
+		var page struct {
+			Pilots     []release.Pilot `json:"pilots"`
+			NextCursor string          `json:"next_cursor"`
+		}
+		err := json.Unmarshal(body, &page)
+		pilots = append(pilots, page.Pilots...)
+		return page.NextCursor, err
+	})
+}
+
+// SetPilotEnrollment enrolls the pilot to the flag's rollout in the environment, or excludes it from the rollout.
+func (c Client) SetPilotEnrollment(ctx context.Context, flagID, envID, publicID string, isParticipating bool) (release.Pilot, error) {
+	pilots, err := c.listPilots(ctx, flagID, envID, publicID)
+	if err != nil {
+		return release.Pilot{}, err
+	}
+
+	var req, resp struct {
+		Pilot release.Pilot `json:"pilot"`
+	}
+	if len(pilots) == 0 {
+		req.Pilot = release.Pilot{FlagID: flagID, EnvironmentID: envID, PublicID: publicID, IsParticipating: isParticipating}
+		err := c.do(ctx, http.MethodPost, `/release-pilots`, nil, 0, req, &resp)
+		return resp.Pilot, err
+	}
+
+	req.Pilot = pilots[0]
+	if req.Pilot.IsParticipating == isParticipating {
+		return req.Pilot, nil
+	}
+	req.Pilot.IsParticipating = isParticipating
+	err = c.do(ctx, http.MethodPut, `/release-pilots/`+url.PathEscape(req.Pilot.ID), nil, req.Pilot.Revision, req, &resp)
+	return resp.Pilot, err
+}
+
+// Evaluate explains the release flag states of the pilot in the environment.
+// When no flag name is given, every flag is evaluated.
+// A zero asOf evaluates the current state.
+func (c Client) Evaluate(ctx context.Context, pilotID, env string, asOf time.Time, flagNames ...string) ([]release.Evaluation, error) {
+	query := url.Values{`pilot_id`: {pilotID}, `environment`: {env}, `flag`: flagNames}
+	if !asOf.IsZero() {
+		query.Set(`as_of`, asOf.Format(time.RFC3339))
+	}
+	var resp struct {
+		Evaluations []release.Evaluation `json:"evaluations"`
+	}
+	err := c.do(ctx, http.MethodGet, `/release-evaluations`, query, 0, nil, &resp)
+	return resp.Evaluations, err
+}
+
+func isNotFound(err error) bool {
+	domainErr, ok := errs.Lookup(err)
+	return ok && domainErr.Kind == errs.KindNotFound
+}
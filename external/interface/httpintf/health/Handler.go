@@ -0,0 +1,55 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NewHandler serves the liveness and the readiness of the application.
+//
+//	/live  responds as long as the process can serve requests, so it should be used to restart a stuck instance.
+//	/ready checks the dependencies, and it responds with 503 when a required one is unavailable,
+//	       so it should be used to take the instance out of the load balancing.
+//
+// The root path responds with the readiness as well.
+func NewHandler(checks ...Check) http.Handler {
+	return handler{checks: checks, timeout: DefaultTimeout}
+}
+
+type handler struct {
+	checks  []Check
+	timeout time.Duration
+}
+
+func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch strings.TrimSuffix(r.URL.Path, `/`) {
+	case `/live`:
+		h.live(w, r)
+	case ``, `/ready`:
+		h.ready(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h handler) live(w http.ResponseWriter, r *http.Request) {
+	serveReport(w, Report{Status: StatusUp}, http.StatusOK)
+}
+
+func (h handler) ready(w http.ResponseWriter, r *http.Request) {
+	report := Run(r.Context(), h.timeout, h.checks...)
+	code := http.StatusOK
+	if report.Status == StatusDown {
+		code = http.StatusServiceUnavailable
+	}
+	serveReport(w, report, code)
+}
+
+func serveReport(w http.ResponseWriter, report Report, code int) {
+	w.Header().Set(`Content-Type`, `application/json`)
+	w.Header().Set(`Cache-Control`, `no-store`)
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(report)
+}
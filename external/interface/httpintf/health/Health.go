@@ -0,0 +1,121 @@
+// Package health reports whether the application and the services it depends on are able to serve requests.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Status is the state of the application or a dependency of it.
+type Status string
+
+const (
+	// StatusUp means that every dependency is available.
+	StatusUp Status = `up`
+	// StatusDegraded means that an optional dependency, like the cache, is unavailable,
+	// but the requests can be served without it.
+	StatusDegraded Status = `degraded`
+	// StatusDown means that a required dependency, like the storage, is unavailable.
+	StatusDown Status = `down`
+)
+
+// DefaultTimeout is the time a check has to finish before it is considered failed.
+const DefaultTimeout = 2 * time.Second
+
+// Check verifies a single dependency of the application.
+type Check struct {
+	Name string
+	// Optional dependencies only degrade the application when they are unavailable.
+	Optional bool
+	Check    func(ctx context.Context) error
+}
+
+// Informational is implemented by the errors of the checks, which are worth reporting,
+// but don't make the dependency unavailable.
+type Informational interface {
+	Informational() bool
+}
+
+// Pinger is implemented by the resources which can tell whether they are reachable.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Report is the result of the checks.
+type Report struct {
+	Status Status                 `json:"status"`
+	Checks map[string]CheckReport `json:"checks,omitempty"`
+}
+
+// CheckReport is the result of a single check.
+type CheckReport struct {
+	Status   Status `json:"status"`
+	Optional bool   `json:"optional,omitempty"`
+	// Duration is how long the check took, in milliseconds.
+	Duration int64  `json:"duration_ms"`
+	Error    string `json:"error,omitempty"`
+	// Info is the informational finding of a check, which is up.
+	Info string `json:"info,omitempty"`
+}
+
+// Run executes the checks concurrently, and each of them has the timeout to finish.
+func Run(ctx context.Context, timeout time.Duration, checks ...Check) Report {
+	report := Report{Status: StatusUp, Checks: make(map[string]CheckReport, len(checks))}
+
+	var (
+		wg sync.WaitGroup
+		m  sync.Mutex
+	)
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c Check) {
+			defer wg.Done()
+			cr := run(ctx, timeout, c)
+			m.Lock()
+			defer m.Unlock()
+			report.Checks[c.Name] = cr
+		}(c)
+	}
+	wg.Wait()
+
+	for _, cr := range report.Checks {
+		switch {
+		case cr.Status == StatusUp:
+		case cr.Optional && report.Status == StatusUp:
+			report.Status = StatusDegraded
+		case !cr.Optional:
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+func run(ctx context.Context, timeout time.Duration, c Check) CheckReport {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- c.Check(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	cr := CheckReport{Status: StatusUp, Optional: c.Optional, Duration: time.Since(start).Milliseconds()}
+	var info Informational
+	switch {
+	case err == nil:
+	case errors.As(err, &info) && info.Informational():
+		cr.Info = err.Error()
+	default:
+		cr.Status = StatusDown
+		cr.Error = err.Error()
+	}
+	return cr
+}
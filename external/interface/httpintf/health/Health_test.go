@@ -0,0 +1,106 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/external/interface/httpintf/health"
+)
+
+func TestHandler(t *testing.T) {
+	s := testcase.NewSpec(t)
+	defer s.Finish()
+
+	storageErr := s.Let(`storage error`, func(t *testcase.T) interface{} { return nil })
+	cacheErr := s.Let(`cache error`, func(t *testcase.T) interface{} { return nil })
+	errOf := func(t *testcase.T, v testcase.Var) error {
+		err, _ := v.Get(t).(error)
+		return err
+	}
+
+	serve := func(t *testcase.T, path string) (int, health.Report) {
+		h := health.NewHandler(
+			health.Check{Name: `storage`, Check: func(ctx context.Context) error { return errOf(t, storageErr) }},
+			health.Check{Name: `cache`, Optional: true, Check: func(ctx context.Context) error { return errOf(t, cacheErr) }},
+		)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var report health.Report
+		require.Nil(t, json.NewDecoder(w.Body).Decode(&report))
+		return w.Code, report
+	}
+
+	s.Describe(`/ready`, func(s *testcase.Spec) {
+		s.Then(`every dependency is reported up`, func(t *testcase.T) {
+			code, report := serve(t, `/ready`)
+			require.Equal(t, http.StatusOK, code)
+			require.Equal(t, health.StatusUp, report.Status)
+			require.Equal(t, health.StatusUp, report.Checks[`storage`].Status)
+			require.Equal(t, health.StatusUp, report.Checks[`cache`].Status)
+		})
+
+		s.When(`an optional dependency is unavailable`, func(s *testcase.Spec) {
+			cacheErr.Let(s, func(t *testcase.T) interface{} { return errors.New(`connection refused`) })
+
+			s.Then(`the application is degraded, but still ready`, func(t *testcase.T) {
+				code, report := serve(t, `/ready`)
+				require.Equal(t, http.StatusOK, code)
+				require.Equal(t, health.StatusDegraded, report.Status)
+				require.Equal(t, health.StatusDown, report.Checks[`cache`].Status)
+				require.Equal(t, `connection refused`, report.Checks[`cache`].Error)
+			})
+		})
+
+		s.When(`a required dependency has an informational finding`, func(s *testcase.Spec) {
+			storageErr.Let(s, func(t *testcase.T) interface{} { return informationalErr{} })
+
+			s.Then(`the finding is reported, and the application is ready`, func(t *testcase.T) {
+				code, report := serve(t, `/ready`)
+				require.Equal(t, http.StatusOK, code)
+				require.Equal(t, health.StatusUp, report.Status)
+				require.Equal(t, health.StatusUp, report.Checks[`storage`].Status)
+				require.Equal(t, `schema is newer`, report.Checks[`storage`].Info)
+				require.Empty(t, report.Checks[`storage`].Error)
+			})
+		})
+
+		s.When(`a required dependency is unavailable`, func(s *testcase.Spec) {
+			storageErr.Let(s, func(t *testcase.T) interface{} { return errors.New(`connection refused`) })
+
+			s.Then(`the application is not ready`, func(t *testcase.T) {
+				code, report := serve(t, `/ready`)
+				require.Equal(t, http.StatusServiceUnavailable, code)
+				require.Equal(t, health.StatusDown, report.Status)
+			})
+
+			s.Then(`the application is still alive`, func(t *testcase.T) {
+				code, report := serve(t, `/live`)
+				require.Equal(t, http.StatusOK, code)
+				require.Equal(t, health.StatusUp, report.Status)
+			})
+		})
+	})
+}
+
+type informationalErr struct{}
+
+func (informationalErr) Error() string       { return `schema is newer` }
+func (informationalErr) Informational() bool { return true }
+
+func TestRun_timeout(t *testing.T) {
+	report := health.Run(context.Background(), 10*time.Millisecond, health.Check{Name: `slow`, Check: func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(time.Second)
+		return nil
+	}})
+	require.Equal(t, health.StatusDown, report.Status)
+	require.Equal(t, context.DeadlineExceeded.Error(), report.Checks[`slow`].Error)
+}
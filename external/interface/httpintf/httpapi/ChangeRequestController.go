@@ -0,0 +1,336 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/toggler-io/toggler/domains/change"
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/external/interface/httpintf/httputils"
+)
+
+// NewChangeRequestHandler serves the review of the change requests,
+// which are proposed in place of the changes of the protected deployment environments.
+func NewChangeRequestHandler(uc *toggler.UseCases) http.Handler {
+	ctrl := ChangeRequestController{UseCases: uc}
+	m := http.NewServeMux()
+	m.HandleFunc(`/change-requests`, ctrl.List)
+	m.HandleFunc(`/change-requests/`, ctrl.Route)
+	return httputils.AuthMiddleware(m, uc, WriteError)
+}
+
+type ChangeRequestController struct {
+	UseCases *toggler.UseCases
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+// ProposedChangeResponse is the response of a change of a protected deployment environment,
+// which was recorded as a pending change request instead of being made.
+// swagger:response proposedChangeResponse
+type ProposedChangeResponse struct {
+	// in: body
+	Body struct {
+		ChangeRequest change.Request `json:"change_request"`
+	}
+}
+
+// serveProposed responds with 202 Accepted and the change request,
+// when the change made with the context was proposed instead of being made.
+func serveProposed(w http.ResponseWriter, r *http.Request, ctx context.Context) bool {
+	proposed := change.Proposed(ctx)
+	if len(proposed) == 0 {
+		return false
+	}
+	var resp ProposedChangeResponse
+	resp.Body.ChangeRequest = proposed[0]
+	serveJSONWithStatus(w, r, http.StatusAccepted, resp.Body)
+	return true
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+// ListChangeRequestRequest
+// swagger:parameters listChangeRequests
+type ListChangeRequestRequest struct {
+	// EnvironmentID filters the change requests by deployment environment.
+	//
+	// in: query
+	EnvironmentID string `json:"env_id"`
+	// Status filters the change requests by their state.
+	//
+	// in: query
+	// enum: pending,rejected,applied
+	Status string `json:"status"`
+}
+
+// ListChangeRequestResponse
+// swagger:response listChangeRequestResponse
+type ListChangeRequestResponse struct {
+	// in: body
+	Body struct {
+		ChangeRequests []change.Request `json:"change_requests"`
+	}
+}
+
+/*
+List
+swagger:route GET /change-requests change listChangeRequests
+
+List the change requests, the latest first.
+
+	Produces:
+	- application/json
+
+	Schemes: http, https
+
+	Security:
+	  AppToken: []
+
+	Responses:
+	  200: listChangeRequestResponse
+	  401: errorResponse
+	  500: errorResponse
+*/
+func (ctrl ChangeRequestController) List(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		ErrorWriterFunc(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	requests, err := ctrl.UseCases.Changes.List(r.Context(), change.RequestQuery{
+		EnvironmentID: r.URL.Query().Get(`env_id`),
+		Status:        r.URL.Query().Get(`status`),
+	})
+	if handleError(w, r, err, http.StatusInternalServerError) {
+		return
+	}
+
+	var resp ListChangeRequestResponse
+	resp.Body.ChangeRequests = requests
+	serveJSON(w, r, resp.Body)
+}
+
+// Route dispatches the requests of a single change request by their path.
+func (ctrl ChangeRequestController) Route(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, `/change-requests/`), `/`)
+	if parts[0] == `` || 2 < len(parts) {
+		ErrorWriterFunc(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	id, action := parts[0], ``
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+
+	switch {
+	case action == `` && r.Method == http.MethodGet:
+		ctrl.Show(w, r, id)
+	case action == `approve` && r.Method == http.MethodPost:
+		ctrl.Approve(w, r, id)
+	case action == `reject` && r.Method == http.MethodPost:
+		ctrl.Reject(w, r, id)
+	case action == `apply` && r.Method == http.MethodPost:
+		ctrl.Apply(w, r, id)
+	case action == `` || action == `approve` || action == `reject` || action == `apply`:
+		ErrorWriterFunc(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	default:
+		ErrorWriterFunc(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	}
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+// ShowChangeRequestRequest
+// swagger:parameters showChangeRequest approveChangeRequest applyChangeRequest
+type ShowChangeRequestRequest struct {
+	// ChangeRequestID is the change request id.
+	//
+	// in: path
+	// required: true
+	ChangeRequestID string `json:"changeRequestID"`
+}
+
+// ShowChangeRequestResponse
+// swagger:response showChangeRequestResponse
+type ShowChangeRequestResponse struct {
+	// in: body
+	Body ChangeRequestReview
+}
+
+// ChangeRequestReview is a change request along with its approvals and its changes.
+type ChangeRequestReview struct {
+	ChangeRequest change.Request    `json:"change_request"`
+	Approvals     []change.Approval `json:"approvals"`
+	// RequiredApprovals is the number of approvals the deployment environment requires.
+	RequiredApprovals int `json:"required_approvals"`
+	// Approved tells if the change request has the required approvals, so it can be applied.
+	Approved bool `json:"approved"`
+	// Changes is the field level difference between the base and the proposed state of the entity.
+	Changes []release.VersionChange `json:"changes"`
+}
+
+/*
+Show
+swagger:route GET /change-requests/{changeRequestID} change showChangeRequest
+
+Show a change request with its approvals and its changes.
+
+	Produces:
+	- application/json
+
+	Schemes: http, https
+
+	Security:
+	  AppToken: []
+
+	Responses:
+	  200: showChangeRequestResponse
+	  401: errorResponse
+	  404: errorResponse
+	  500: errorResponse
+*/
+func (ctrl ChangeRequestController) Show(w http.ResponseWriter, r *http.Request, id string) {
+	review, err := ctrl.UseCases.Changes.Review(r.Context(), id)
+	if handleError(w, r, err, http.StatusInternalServerError) {
+		return
+	}
+
+	var resp ShowChangeRequestResponse
+	resp.Body = ChangeRequestReview{
+		ChangeRequest:     review.Request,
+		Approvals:         review.Approvals,
+		RequiredApprovals: review.Environment.RequiredApprovals,
+		Approved:          review.IsApproved(),
+		Changes:           review.Changes,
+	}
+	serveJSON(w, r, resp.Body)
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+/*
+Approve
+swagger:route POST /change-requests/{changeRequestID}/approve change approveChangeRequest
+
+Approve a pending change request.
+A token owner can approve a change request only once, and not the one proposed by themselves.
+
+	Produces:
+	- application/json
+
+	Schemes: http, https
+
+	Security:
+	  AppToken: []
+
+	Responses:
+	  200: showChangeRequestResponse
+	  401: errorResponse
+	  403: errorResponse
+	  404: errorResponse
+	  409: errorResponse
+	  500: errorResponse
+*/
+func (ctrl ChangeRequestController) Approve(w http.ResponseWriter, r *http.Request, id string) {
+	if handleError(w, r, ctrl.UseCases.Changes.Approve(r.Context(), id), http.StatusInternalServerError) {
+		return
+	}
+	ctrl.Show(w, r, id)
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+// RejectChangeRequestRequest
+// swagger:parameters rejectChangeRequest
+type RejectChangeRequestRequest struct {
+	// ChangeRequestID is the change request id.
+	//
+	// in: path
+	// required: true
+	ChangeRequestID string `json:"changeRequestID"`
+	// in: body
+	Body struct {
+		// Reason is the explanation of the rejection.
+		Reason string `json:"reason"`
+	}
+}
+
+/*
+Reject
+swagger:route POST /change-requests/{changeRequestID}/reject change rejectChangeRequest
+
+Reject a pending change request, so its change is not made.
+
+	Consumes:
+	- application/json
+
+	Produces:
+	- application/json
+
+	Schemes: http, https
+
+	Security:
+	  AppToken: []
+
+	Responses:
+	  200: showChangeRequestResponse
+	  400: errorResponse
+	  401: errorResponse
+	  404: errorResponse
+	  409: errorResponse
+	  500: errorResponse
+*/
+func (ctrl ChangeRequestController) Reject(w http.ResponseWriter, r *http.Request, id string) {
+	defer r.Body.Close() // ignorable
+
+	var req RejectChangeRequestRequest
+	if r.ContentLength != 0 {
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if handleError(w, r, decoder.Decode(&req.Body), http.StatusBadRequest) {
+			return
+		}
+	}
+
+	if handleError(w, r, ctrl.UseCases.Changes.Reject(r.Context(), id, req.Body.Reason), http.StatusInternalServerError) {
+		return
+	}
+	ctrl.Show(w, r, id)
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+/*
+Apply
+swagger:route POST /change-requests/{changeRequestID}/apply change applyChangeRequest
+
+Make the change of an approved change request.
+The change request stays pending when the entity was changed since the proposal.
+
+	Produces:
+	- application/json
+
+	Schemes: http, https
+
+	Security:
+	  AppToken: []
+
+	Responses:
+	  200: showChangeRequestResponse
+	  401: errorResponse
+	  404: errorResponse
+	  409: errorResponse
+	  412: errorResponse
+	  500: errorResponse
+*/
+func (ctrl ChangeRequestController) Apply(w http.ResponseWriter, r *http.Request, id string) {
+	if handleError(w, r, ctrl.UseCases.Changes.Apply(r.Context(), id), http.StatusInternalServerError) {
+		return
+	}
+	ctrl.Show(w, r, id)
+}
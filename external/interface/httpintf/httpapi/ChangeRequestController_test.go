@@ -0,0 +1,294 @@
+package httpapi_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/adamluzsi/frameless/iterators"
+	"github.com/adamluzsi/testcase"
+	hs "github.com/adamluzsi/testcase/httpspec"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/change"
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/security"
+	"github.com/toggler-io/toggler/external/interface/httpintf/httpapi"
+	sh "github.com/toggler-io/toggler/spechelper"
+)
+
+func TestChangeRequestController(t *testing.T) {
+	s := sh.NewSpec(t)
+	s.Parallel()
+
+	hs.Context.Let(s, func(t *testcase.T) interface{} { return sh.ContextGet(t) })
+	hs.HandlerLet(s, func(t *testcase.T) http.Handler { return httpapi.NewChangeRequestHandler(sh.ExampleUseCases(t)) })
+	hs.ContentTypeIsJSON(s)
+
+	// the change request is proposed by the owner of the example token,
+	// and reviewed by an other token owner by default.
+	reviewerToken := s.Let(`reviewer token`, func(t *testcase.T) interface{} {
+		textToken, _ := sh.CreateToken(t, `reviewer-`+t.Random.StringNWithCharset(8, `abcdef`))
+		return textToken
+	})
+	callerToken := s.Let(`caller token`, func(t *testcase.T) interface{} { return reviewerToken.Get(t) })
+	s.Before(func(t *testcase.T) {
+		hs.HeaderGet(t).Set(`X-App-Token`, callerToken.Get(t).(string))
+	})
+
+	env := s.Let(`protected env`, func(t *testcase.T) interface{} {
+		env := &release.Environment{Name: `protected-` + t.Random.StringNWithCharset(8, `abcdef`), RequiredApprovals: 1}
+		storage := sh.StorageGet(t).ReleaseEnvironment(sh.ContextGet(t))
+		require.Nil(t, storage.Create(sh.ContextGet(t), env))
+		t.Defer(storage.DeleteByID, sh.ContextGet(t), env.ID)
+		return env
+	})
+	request := s.Let(`change request`, func(t *testcase.T) interface{} {
+		ctx := change.ContextWithProposals(security.ContextWithOwner(sh.ContextGet(t), sh.ExampleUniqueUserID(t)))
+		require.Nil(t, sh.ExampleUseCases(t).Storage.ReleaseRollout(ctx).Create(ctx, &release.Rollout{
+			FlagID:        sh.ExampleReleaseFlag(t).ID,
+			EnvironmentID: env.Get(t).(*release.Environment).ID,
+			Plan:          release.RolloutDecisionByGlobal{State: true},
+		}))
+		proposed := change.Proposed(ctx)
+		require.Len(t, proposed, 1)
+		return proposed[0]
+	})
+
+	s.Describe(`GET /change-requests - list`, func(s *testcase.Spec) {
+		hs.Method.LetValue(s, http.MethodGet)
+		hs.Path.LetValue(s, `/change-requests`)
+		s.Before(func(t *testcase.T) { request.Get(t) }) // eager load
+
+		list := func(t *testcase.T) []change.Request {
+			rr := hs.ServeHTTP(t)
+			require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+			var resp httpapi.ListChangeRequestResponse
+			IsJsonResponse(t, rr, &resp.Body)
+			return resp.Body.ChangeRequests
+		}
+
+		s.Then(`the change requests are listed`, func(t *testcase.T) {
+			requests := list(t)
+			require.Len(t, requests, 1)
+			require.Equal(t, request.Get(t).(change.Request).ID, requests[0].ID)
+			require.Equal(t, change.StatusPending, requests[0].Status)
+			require.Equal(t, sh.ExampleUniqueUserID(t), requests[0].ProposedBy)
+		})
+
+		s.When(`they are filtered by an other status`, func(s *testcase.Spec) {
+			hs.Query.Let(s, func(t *testcase.T) interface{} { return url.Values{`status`: {change.StatusApplied}} })
+
+			s.Then(`the pending change request is left out`, func(t *testcase.T) {
+				require.Empty(t, list(t))
+			})
+		})
+	})
+
+	s.Describe(`GET /change-requests/{changeRequestID} - show`, func(s *testcase.Spec) {
+		hs.Method.LetValue(s, http.MethodGet)
+		changeRequestPathLet(s, request, ``)
+
+		s.Then(`the change request is shown with its review`, func(t *testcase.T) {
+			review := thenChangeRequestReview(t)
+			require.Equal(t, request.Get(t).(change.Request).ID, review.ChangeRequest.ID)
+			require.Equal(t, 1, review.RequiredApprovals)
+			require.False(t, review.Approved)
+			require.Empty(t, review.Approvals)
+			require.NotEmpty(t, review.Changes)
+		})
+
+		thenTheChangeRequestIsNotFound(s)
+	})
+
+	s.Describe(`POST /change-requests/{changeRequestID}/approve - approve`, func(s *testcase.Spec) {
+		hs.Method.LetValue(s, http.MethodPost)
+		changeRequestPathLet(s, request, `/approve`)
+
+		s.Then(`an other token owner approves it`, func(t *testcase.T) {
+			review := thenChangeRequestReview(t)
+			require.True(t, review.Approved)
+			require.Len(t, review.Approvals, 1)
+			require.NotEqual(t, sh.ExampleUniqueUserID(t), review.Approvals[0].OwnerUID)
+			require.Equal(t, change.StatusPending, review.ChangeRequest.Status)
+		})
+
+		s.When(`the caller is the proposer`, func(s *testcase.Spec) {
+			callerToken.Let(s, func(t *testcase.T) interface{} { return sh.ExampleTextToken(t) })
+
+			s.Then(`the self approval is forbidden`, func(t *testcase.T) {
+				thenErrorResponse(t, http.StatusForbidden, `change_request_self_approval`)
+				require.False(t, review(t, request).IsApproved())
+			})
+		})
+
+		s.When(`the caller already approved it`, func(s *testcase.Spec) {
+			s.Before(func(t *testcase.T) { thenChangeRequestReview(t) })
+
+			s.Then(`the second approval is a conflict`, func(t *testcase.T) {
+				thenErrorResponse(t, http.StatusConflict, `change_request_already_approved`)
+			})
+		})
+
+		s.When(`the change request is already rejected`, func(s *testcase.Spec) {
+			s.Before(func(t *testcase.T) {
+				require.Nil(t, sh.ExampleUseCases(t).Changes.Reject(ownerContext(t, `someone`), request.Get(t).(change.Request).ID, ``))
+			})
+
+			s.Then(`it is a conflict`, func(t *testcase.T) {
+				thenErrorResponse(t, http.StatusConflict, `change_request_not_pending`)
+			})
+		})
+
+		s.When(`the method is not POST`, func(s *testcase.Spec) {
+			hs.Method.LetValue(s, http.MethodGet)
+
+			s.Then(`the method is not allowed`, func(t *testcase.T) {
+				thenErrorResponse(t, http.StatusMethodNotAllowed, `method_not_allowed`)
+			})
+		})
+
+		thenTheChangeRequestIsNotFound(s)
+		thenTheCallerIsUnauthorizedWithoutToken(s, callerToken, request)
+	})
+
+	s.Describe(`POST /change-requests/{changeRequestID}/reject - reject`, func(s *testcase.Spec) {
+		hs.Method.LetValue(s, http.MethodPost)
+		changeRequestPathLet(s, request, `/reject`)
+		hs.Body.Let(s, func(t *testcase.T) interface{} {
+			var req httpapi.RejectChangeRequestRequest
+			req.Body.Reason = `not during the sale`
+			return req.Body
+		})
+
+		s.Then(`the change request is rejected with the reason`, func(t *testcase.T) {
+			review := thenChangeRequestReview(t)
+			require.Equal(t, change.StatusRejected, review.ChangeRequest.Status)
+			require.Equal(t, `not during the sale`, review.ChangeRequest.Reason)
+			require.Equal(t, reviewerOwner(t, reviewerToken), review.ChangeRequest.ResolvedBy)
+			require.Empty(t, rolloutsOf(t, env))
+		})
+
+		s.When(`the body has an unknown field`, func(s *testcase.Spec) {
+			hs.Body.Let(s, func(t *testcase.T) interface{} { return strings.NewReader(`{"why":"no"}`) })
+
+			s.Then(`it is a bad request, and the change request stays pending`, func(t *testcase.T) {
+				thenErrorResponse(t, http.StatusBadRequest, `bad_request`)
+				require.True(t, review(t, request).Request.IsPending())
+			})
+		})
+
+		thenTheChangeRequestIsNotFound(s)
+		thenTheCallerIsUnauthorizedWithoutToken(s, callerToken, request)
+	})
+
+	s.Describe(`POST /change-requests/{changeRequestID}/apply - apply`, func(s *testcase.Spec) {
+		hs.Method.LetValue(s, http.MethodPost)
+		changeRequestPathLet(s, request, `/apply`)
+
+		s.Then(`a change request without the required approvals can't be applied`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusConflict, `change_request_not_enough_approvals`)
+			require.Empty(t, rolloutsOf(t, env))
+		})
+
+		s.When(`it has the required approvals`, func(s *testcase.Spec) {
+			s.Before(func(t *testcase.T) {
+				require.Nil(t, sh.ExampleUseCases(t).Changes.Approve(ownerContext(t, `approver`), request.Get(t).(change.Request).ID))
+			})
+
+			s.Then(`the change is made`, func(t *testcase.T) {
+				review := thenChangeRequestReview(t)
+				require.Equal(t, change.StatusApplied, review.ChangeRequest.Status)
+				require.Equal(t, reviewerOwner(t, reviewerToken), review.ChangeRequest.ResolvedBy)
+				require.Len(t, rolloutsOf(t, env), 1)
+			})
+
+			s.And(`the caller is the proposer`, func(s *testcase.Spec) {
+				callerToken.Let(s, func(t *testcase.T) interface{} { return sh.ExampleTextToken(t) })
+
+				s.Then(`the proposer can apply the approved change`, func(t *testcase.T) {
+					require.Equal(t, change.StatusApplied, thenChangeRequestReview(t).ChangeRequest.Status)
+				})
+			})
+
+			s.And(`it was already applied`, func(s *testcase.Spec) {
+				s.Before(func(t *testcase.T) { thenChangeRequestReview(t) })
+
+				s.Then(`it is a conflict`, func(t *testcase.T) {
+					thenErrorResponse(t, http.StatusConflict, `change_request_not_pending`)
+					require.Len(t, rolloutsOf(t, env), 1)
+				})
+			})
+		})
+
+		thenTheChangeRequestIsNotFound(s)
+		thenTheCallerIsUnauthorizedWithoutToken(s, callerToken, request)
+	})
+}
+
+func changeRequestPathLet(s *testcase.Spec, request testcase.Var, action string) {
+	id := s.Let(`change request id`, func(t *testcase.T) interface{} {
+		return request.Get(t).(change.Request).ID
+	})
+	hs.Path.Let(s, func(t *testcase.T) interface{} {
+		return `/change-requests/` + id.Get(t).(string) + action
+	})
+}
+
+func thenTheChangeRequestIsNotFound(s *testcase.Spec) {
+	s.When(`the change request is unknown`, func(s *testcase.Spec) {
+		s.LetValue(`change request id`, `unknown`)
+
+		s.Then(`it is not found`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusNotFound, `change_request_not_found`)
+		})
+	})
+}
+
+func thenTheCallerIsUnauthorizedWithoutToken(s *testcase.Spec, callerToken, request testcase.Var) {
+	s.When(`the caller has no valid token`, func(s *testcase.Spec) {
+		callerToken.LetValue(s, `invalid`)
+
+		s.Then(`it is unauthorized, and the change request stays pending`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusUnauthorized, `invalid_token`)
+			r := review(t, request)
+			require.True(t, r.Request.IsPending())
+			require.Empty(t, r.Approvals)
+		})
+	})
+}
+
+func thenChangeRequestReview(t *testcase.T) httpapi.ChangeRequestReview {
+	rr := hs.ServeHTTP(t)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var resp httpapi.ShowChangeRequestResponse
+	IsJsonResponse(t, rr, &resp.Body)
+	return resp.Body
+}
+
+func review(t *testcase.T, request testcase.Var) change.Review {
+	r, err := sh.ExampleUseCases(t).Changes.Review(sh.ContextGet(t), request.Get(t).(change.Request).ID)
+	require.Nil(t, err)
+	return r
+}
+
+func ownerContext(t *testcase.T, owner string) context.Context {
+	return security.ContextWithOwner(sh.ContextGet(t), owner)
+}
+
+func reviewerOwner(t *testcase.T, reviewerToken testcase.Var) string {
+	token, valid, err := sh.ExampleUseCases(t).Doorkeeper.LookupTextToken(sh.ContextGet(t), reviewerToken.Get(t).(string))
+	require.Nil(t, err)
+	require.True(t, valid)
+	return token.OwnerUID
+}
+
+func rolloutsOf(t *testcase.T, env testcase.Var) []release.Rollout {
+	rollouts := make([]release.Rollout, 0)
+	require.Nil(t, iterators.Collect(iterators.Filter(sh.StorageGet(t).ReleaseRollout(sh.ContextGet(t)).FindAll(sh.ContextGet(t)), func(r release.Rollout) bool {
+		return r.EnvironmentID == env.Get(t).(*release.Environment).ID
+	}), &rollouts))
+	return rollouts
+}
@@ -3,10 +3,10 @@ package httpapi
 import (
 	"context"
 	"encoding/json"
+	"github.com/toggler-io/toggler/domains/change"
 	"github.com/toggler-io/toggler/domains/release"
 	"net/http"
 
-	"github.com/adamluzsi/frameless/iterators"
 	"github.com/adamluzsi/gorest"
 
 	"github.com/toggler-io/toggler/domains/toggler"
@@ -16,23 +16,20 @@ import (
 func NewDeploymentEnvironmentHandler(uc *toggler.UseCases) http.Handler {
 	c := DeploymentEnvironmentController{UseCases: uc}
 	h := gorest.NewHandler(c)
-	return httputils.AuthMiddleware(h, uc, ErrorWriterFunc)
+	return httputils.AuthMiddleware(h, uc, WriteError)
 }
 
 type DeploymentEnvironmentController struct {
 	UseCases *toggler.UseCases
 }
 
-//--------------------------------------------------------------------------------------------------------------------//
-
-func (ctrl DeploymentEnvironmentController) handleValidationError(w http.ResponseWriter, err error) bool {
-	switch err {
-	case release.ErrEnvironmentNameIsEmpty:
-		return handleError(w, err, http.StatusBadRequest)
+// NotFound responds when the requested deployment environment doesn't exist.
+func (ctrl DeploymentEnvironmentController) NotFound(w http.ResponseWriter, r *http.Request) {
+	handleError(w, r, release.ErrEnvironmentNotFound, http.StatusNotFound)
+}
 
-	default:
-		return handleError(w, err, http.StatusInternalServerError)
-	}
+func (ctrl DeploymentEnvironmentController) InternalServerError(w http.ResponseWriter, r *http.Request) {
+	ErrorWriterFunc(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 }
 
 //--------------------------------------------------------------------------------------------------------------------//
@@ -88,24 +85,25 @@ func (ctrl DeploymentEnvironmentController) Create(w http.ResponseWriter, r *htt
 
 	var req CreateDeploymentEnvironmentRequest
 
-	if handleError(w, decoder.Decode(&req.Body), http.StatusBadRequest) {
+	if handleError(w, r, decoder.Decode(&req.Body), http.StatusBadRequest) {
 		return
 	}
 
 	req.Body.Environment.ID = `` // ignore id if given
 	env := req.Body.Environment
 
-	if ctrl.handleValidationError(w, env.Validate()) {
+	if handleError(w, r, env.Validate(), http.StatusInternalServerError) {
 		return
 	}
 
-	if ctrl.handleValidationError(w, ctrl.UseCases.Storage.ReleaseEnvironment(r.Context()).Create(r.Context(), &env)) {
+	if handleError(w, r, ctrl.UseCases.Storage.ReleaseEnvironment(r.Context()).Create(r.Context(), &env), http.StatusInternalServerError) {
 		return
 	}
 
 	var resp CreateDeploymentEnvironmentResponse
 	resp.Body.Environment = env
-	serveJSON(w, resp.Body)
+	setETag(w, env.Revision)
+	serveJSON(w, r, resp.Body)
 }
 
 //--------------------------------------------------------------------------------------------------------------------//
@@ -113,6 +111,11 @@ func (ctrl DeploymentEnvironmentController) Create(w http.ResponseWriter, r *htt
 // ListDeploymentEnvironmentRequest
 // swagger:parameters listDeploymentEnvironments
 type ListDeploymentEnvironmentRequest struct {
+	PaginationRequest
+	// NamePrefix filters the environments by the beginning of their name.
+	//
+	// in: query
+	NamePrefix string `json:"name_prefix"`
 }
 
 // ListDeploymentEnvironmentResponse
@@ -121,6 +124,9 @@ type ListDeploymentEnvironmentResponse struct {
 	// in: body
 	Body struct {
 		Environments []release.Environment `json:"environments"`
+		// NextCursor is the cursor of the next page.
+		// It is omitted on the last page.
+		NextCursor string `json:"next_cursor,omitempty"`
 	}
 }
 
@@ -129,7 +135,8 @@ type ListDeploymentEnvironmentResponse struct {
 	List
 	swagger:route GET /deployment-environments deployment listDeploymentEnvironments
 
-	List all the deployment environment that can be used to manage a feature rollout.
+	List the deployment environments that can be used to manage a feature rollout.
+	The result is paginated with a cursor, and can be sorted by id or name.
 
 		Consumes:
 		- application/json
@@ -144,21 +151,29 @@ type ListDeploymentEnvironmentResponse struct {
 
 		Responses:
 		  200: listDeploymentEnvironmentResponse
+		  400: errorResponse
 		  401: errorResponse
 		  500: errorResponse
 
 */
 func (ctrl DeploymentEnvironmentController) List(w http.ResponseWriter, r *http.Request) {
-	var resp ListDeploymentEnvironmentResponse
+	pagination, err := parsePagination(r.URL.Query())
+	if handleError(w, r, err, http.StatusInternalServerError) {
+		return
+	}
 
-	if handleError(w,
-		iterators.Collect(ctrl.UseCases.Storage.ReleaseEnvironment(r.Context()).FindAll(r.Context()), &resp.Body.Environments),
-		http.StatusInternalServerError,
-	) {
+	page, err := ctrl.UseCases.ListEnvironments(r.Context(), release.EnvironmentQuery{
+		NamePrefix: r.URL.Query().Get(`name_prefix`),
+		Pagination: pagination,
+	})
+	if handleError(w, r, err, http.StatusInternalServerError) {
 		return
 	}
 
-	serveJSON(w, resp.Body)
+	var resp ListDeploymentEnvironmentResponse
+	resp.Body.Environments = page.Environments
+	resp.Body.NextCursor = page.NextCursor
+	serveJSON(w, r, resp.Body)
 }
 
 //--------------------------------------------------------------------------------------------------------------------//
@@ -181,6 +196,58 @@ func (ctrl DeploymentEnvironmentController) ContextWithResource(ctx context.Cont
 
 //--------------------------------------------------------------------------------------------------------------------//
 
+// ShowDeploymentEnvironmentRequest
+// swagger:parameters showDeploymentEnvironment
+type ShowDeploymentEnvironmentRequest struct {
+	// EnvironmentID is the deployment environment id.
+	//
+	// in: path
+	// required: true
+	EnvironmentID string `json:"envID"`
+}
+
+// ShowDeploymentEnvironmentResponse
+// swagger:response showDeploymentEnvironmentResponse
+type ShowDeploymentEnvironmentResponse struct {
+	// ETag is the revision of the deployment environment, which is expected in the If-Match header of its update.
+	ETag string
+	// in: body
+	Body struct {
+		Environment release.Environment `json:"environment"`
+	}
+}
+
+/*
+
+	Show
+	swagger:route GET /deployment-environments/{envID} deployment showDeploymentEnvironment
+
+	Show a deployment environment.
+
+		Produces:
+		- application/json
+
+		Schemes: http, https
+
+		Security:
+		  AppToken: []
+
+		Responses:
+		  200: showDeploymentEnvironmentResponse
+		  401: errorResponse
+		  404: errorResponse
+		  500: errorResponse
+
+*/
+func (ctrl DeploymentEnvironmentController) Show(w http.ResponseWriter, r *http.Request) {
+	var resp ShowDeploymentEnvironmentResponse
+	resp.Body.Environment = r.Context().Value(DeploymentEnvironmentContextKey{}).(release.Environment)
+	setETag(w, resp.Body.Environment.Revision)
+	serveJSON(w, r, resp.Body)
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
 // UpdateDeploymentEnvironmentRequest
 // swagger:parameters updateDeploymentEnvironment
 type UpdateDeploymentEnvironmentRequest struct {
@@ -189,6 +256,11 @@ type UpdateDeploymentEnvironmentRequest struct {
 	// in: path
 	// required: true
 	EnvironmentID string `json:"envID"`
+	// IfMatch is the ETag of the deployment environment which the update is based on, or "*" to update it unconditionally.
+	//
+	// in: header
+	// required: true
+	IfMatch string `json:"If-Match"`
 	// in: body
 	Body struct {
 		Environment release.Environment `json:"environment"`
@@ -224,7 +296,10 @@ type UpdateDeploymentEnvironmentResponse struct {
 
 		Responses:
 		  200: updateDeploymentEnvironmentResponse
+		  202: proposedChangeResponse
 		  400: errorResponse
+		  412: errorResponse
+		  428: errorResponse
 		  500: errorResponse
 
 */
@@ -233,26 +308,37 @@ func (ctrl DeploymentEnvironmentController) Update(w http.ResponseWriter, r *htt
 	decoder.DisallowUnknownFields()
 	defer r.Body.Close() // ignorable
 
+	revision, ok := ifMatchRevision(w, r)
+	if !ok {
+		return
+	}
+
 	var req UpdateDeploymentEnvironmentRequest
 
-	if handleError(w, decoder.Decode(&req.Body), http.StatusBadRequest) {
+	if handleError(w, r, decoder.Decode(&req.Body), http.StatusBadRequest) {
 		return
 	}
 
 	env := req.Body.Environment
 	env.ID = r.Context().Value(DeploymentEnvironmentContextKey{}).(release.Environment).ID
+	env.Revision = revision
 
-	if ctrl.handleValidationError(w, env.Validate()) {
+	if handleError(w, r, env.Validate(), http.StatusInternalServerError) {
 		return
 	}
 
-	if ctrl.handleValidationError(w, ctrl.UseCases.Storage.ReleaseEnvironment(r.Context()).Update(r.Context(), &env)) {
+	ctx := change.ContextWithProposals(r.Context())
+	if handleError(w, r, ctrl.UseCases.Storage.ReleaseEnvironment(ctx).Update(ctx, &env), http.StatusInternalServerError) {
+		return
+	}
+	if serveProposed(w, r, ctx) {
 		return
 	}
 
 	var resp UpdateDeploymentEnvironmentResponse
 	resp.Body.Environment = env
-	serveJSON(w, resp.Body)
+	setETag(w, env.Revision)
+	serveJSON(w, r, resp.Body)
 }
 
 //--------------------------------------------------------------------------------------------------------------------//
@@ -278,6 +364,7 @@ type DeleteDeploymentEnvironmentResponse struct {
 	swagger:route DELETE /deployment-environments/{envID} deployment deleteDeploymentEnvironment
 
 	Delete a deployment environment.
+	The deployment environment can't be deleted while it still has rollouts or pilots.
 
 		Consumes:
 		- application/json
@@ -292,7 +379,9 @@ type DeleteDeploymentEnvironmentResponse struct {
 
 		Responses:
 		  200: deleteDeploymentEnvironmentResponse
+		  202: proposedChangeResponse
 		  400: errorResponse
+		  409: errorResponse
 		  500: errorResponse
 
 */
@@ -300,8 +389,12 @@ type DeleteDeploymentEnvironmentResponse struct {
 func (ctrl DeploymentEnvironmentController) Delete(w http.ResponseWriter, r *http.Request) {
 	ID := r.Context().Value(DeploymentEnvironmentContextKey{}).(release.Environment).ID
 
-	err := ctrl.UseCases.Storage.ReleaseEnvironment(r.Context()).DeleteByID(r.Context(), ID)
-	if handleError(w, err, http.StatusBadRequest) {
+	ctx := change.ContextWithProposals(r.Context())
+	err := ctrl.UseCases.Storage.ReleaseEnvironment(ctx).DeleteByID(ctx, ID)
+	if handleError(w, r, err, http.StatusBadRequest) {
+		return
+	}
+	if serveProposed(w, r, ctx) {
 		return
 	}
 
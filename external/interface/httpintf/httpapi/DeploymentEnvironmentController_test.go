@@ -145,6 +145,9 @@ func SpecDeploymentEnvironmentControllerCreate(s *testcase.Spec) {
 			var resp httpapi.ErrorResponse
 			require.Nil(t, json.Unmarshal(rr.Body.Bytes(), &resp.Body), rr.Body.String())
 			require.NotEmpty(t, resp.Body.Error.Message)
+			require.Equal(t, release.ErrEnvironmentNameIsEmpty.Code, resp.Body.Error.Key)
+			require.Equal(t, `validation`, resp.Body.Error.Kind)
+			require.Equal(t, `name`, resp.Body.Error.Field)
 		})
 	})
 
@@ -227,6 +230,10 @@ func SpecDeploymentEnvironmentControllerUpdate(s *testcase.Spec) {
 	sh.GivenHTTPRequestHasAppToken(s)
 	Method.LetValue(s, http.MethodPut)
 
+	s.Before(func(t *testcase.T) {
+		HeaderGet(t).Set(`If-Match`, `"1"`) // the revision of the freshly created entity
+	})
+
 	Path.Let(s, func(t *testcase.T) interface{} {
 		return fmt.Sprintf(`/%s`, t.I(`id`))
 	})
@@ -282,6 +289,27 @@ func SpecDeploymentEnvironmentControllerUpdate(s *testcase.Spec) {
 		})
 	})
 
+	s.And(`the environment was updated since its ETag was read`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) {
+			env := *envGet(t)
+			require.Nil(t, sh.StorageGet(t).ReleaseEnvironment(sh.ContextGet(t)).Update(sh.ContextGet(t), &env))
+		})
+
+		s.Then(`it will return with precondition failed`, func(t *testcase.T) {
+			require.Equal(t, http.StatusPreconditionFailed, ServeHTTP(t).Code)
+		})
+	})
+
+	s.And(`the If-Match header is missing`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) {
+			HeaderGet(t).Del(`If-Match`)
+		})
+
+		s.Then(`it will return with precondition required`, func(t *testcase.T) {
+			require.Equal(t, http.StatusPreconditionRequired, ServeHTTP(t).Code)
+		})
+	})
+
 	s.Context(`E2E`, func(s *testcase.Spec) {
 		s.Tag(sh.TagBlackBox)
 
@@ -297,6 +325,7 @@ func SpecDeploymentEnvironmentControllerUpdate(s *testcase.Spec) {
 			// TODO: ensure validation
 			p := swagger.NewUpdateDeploymentEnvironmentParams()
 			p.EnvironmentID = id
+			p.IfMatch = fmt.Sprintf(`"%d"`, envGet(t).Revision)
 			p.Body.Environment = &models.Environment{
 				ID:   id,
 				Name: fixtures.Random.String(),
@@ -309,7 +338,7 @@ func SpecDeploymentEnvironmentControllerUpdate(s *testcase.Spec) {
 
 			c := client.NewHTTPClientWithConfig(nil, tc)
 
-			resp, err := c.Deployment.UpdateDeploymentEnvironment(p, protectedAuth(t))
+			resp, _, err := c.Deployment.UpdateDeploymentEnvironment(p, protectedAuth(t))
 			require.Nil(t, err)
 			require.NotNil(t, resp)
 			require.NotNil(t, resp.Payload)
@@ -365,7 +394,7 @@ func SpecDeploymentEnvironmentControllerDelete(s *testcase.Spec) {
 
 			c := client.NewHTTPClientWithConfig(nil, tc)
 
-			_, err = c.Deployment.DeleteDeploymentEnvironment(p, protectedAuth(t))
+			_, _, err = c.Deployment.DeleteDeploymentEnvironment(p, protectedAuth(t))
 			require.Nil(t, err)
 		})
 	})
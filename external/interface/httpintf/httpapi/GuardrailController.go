@@ -0,0 +1,468 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/toggler-io/toggler/domains/guardrail"
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/external/interface/httpintf/httputils"
+)
+
+// NewGuardrailHandler serves the guardrails of the rollouts, and the health signals they compare.
+func NewGuardrailHandler(uc *toggler.UseCases) http.Handler {
+	ctrl := GuardrailController{UseCases: uc}
+	m := http.NewServeMux()
+	m.HandleFunc(`/guardrails`, ctrl.Collection)
+	m.HandleFunc(`/guardrails/`, ctrl.Route)
+	m.HandleFunc(`/guardrail-signals`, ctrl.Report)
+	return httputils.AuthMiddleware(m, uc, WriteError)
+}
+
+type GuardrailController struct {
+	UseCases *toggler.UseCases
+}
+
+// Collection dispatches the requests of the guardrail list by their method.
+func (ctrl GuardrailController) Collection(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		ctrl.List(w, r)
+	case http.MethodPost:
+		ctrl.Create(w, r)
+	default:
+		ErrorWriterFunc(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// Route dispatches the requests of a single guardrail by their path.
+func (ctrl GuardrailController) Route(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, `/guardrails/`), `/`)
+	if parts[0] == `` || 2 < len(parts) {
+		ErrorWriterFunc(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	id, action := parts[0], ``
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+
+	switch {
+	case action == `` && r.Method == http.MethodGet:
+		ctrl.Show(w, r, id)
+	case action == `` && r.Method == http.MethodDelete:
+		ctrl.Delete(w, r, id)
+	case action == `rearm` && r.Method == http.MethodPost:
+		ctrl.Rearm(w, r, id)
+	case action == `` || action == `rearm`:
+		ErrorWriterFunc(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	default:
+		ErrorWriterFunc(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	}
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+// ReportGuardrailSignalRequest
+// swagger:parameters reportGuardrailSignals
+type ReportGuardrailSignalRequest struct {
+	// required: true
+	// in: body
+	Body struct {
+		Signals []struct {
+			// Flag is the name of the release flag.
+			//
+			// required: true
+			// example: my-release-flag
+			Flag string `json:"flag"`
+			// Env is the ID or the name of the deployment environment.
+			//
+			// required: true
+			// example: production
+			Env string `json:"env"`
+			// Variant tells if the requests were made with the release flag turned on or off.
+			//
+			// required: true
+			// enum: enrolled,control
+			Variant string `json:"variant"`
+			// Successes is the number of requests which were served successfully since the previous report.
+			Successes int `json:"successes"`
+			// Errors is the number of requests which failed since the previous report.
+			Errors int `json:"errors"`
+		} `json:"signals"`
+	}
+}
+
+// ReportGuardrailSignalResponse
+// swagger:response reportGuardrailSignalResponse
+type ReportGuardrailSignalResponse struct {
+}
+
+/*
+Report
+swagger:route POST /guardrail-signals guardrail reportGuardrailSignals
+
+Report the outcome of the requests of a service by release flag variant,
+so the guardrails can compare the error rate of the enrolled pilots with the error rate of the control group.
+The counters are the requests since the previous report of the service.
+
+	Consumes:
+	- application/json
+
+	Produces:
+	- application/json
+
+	Schemes: http, https
+
+	Security:
+	  AppToken: []
+
+	Responses:
+	  200: reportGuardrailSignalResponse
+	  400: errorResponse
+	  401: errorResponse
+	  404: errorResponse
+	  500: errorResponse
+*/
+func (ctrl GuardrailController) Report(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		ErrorWriterFunc(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	defer r.Body.Close() // ignorable
+
+	var req ReportGuardrailSignalRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if handleError(w, r, decoder.Decode(&req.Body), http.StatusBadRequest) {
+		return
+	}
+
+	ctx := r.Context()
+	var (
+		flagIDs = make(map[string]string)
+		envIDs  = make(map[string]string)
+		signals []*guardrail.Signal
+	)
+	for _, s := range req.Body.Signals {
+		if _, ok := flagIDs[s.Flag]; !ok {
+			flag, err := ctrl.UseCases.Storage.ReleaseFlag(ctx).FindByName(ctx, s.Flag)
+			if handleError(w, r, err, http.StatusInternalServerError) {
+				return
+			}
+			if flag == nil {
+				handleError(w, r, release.ErrFlagNotFound, http.StatusNotFound)
+				return
+			}
+			flagIDs[s.Flag] = flag.ID
+		}
+
+		if _, ok := envIDs[s.Env]; !ok {
+			var env release.Environment
+			found, err := ctrl.UseCases.Storage.ReleaseEnvironment(ctx).FindByAlias(ctx, s.Env, &env)
+			if handleError(w, r, err, http.StatusInternalServerError) {
+				return
+			}
+			if !found {
+				handleError(w, r, release.ErrEnvironmentNotFound, http.StatusNotFound)
+				return
+			}
+			envIDs[s.Env] = env.ID
+		}
+
+		signals = append(signals, &guardrail.Signal{
+			FlagID:        flagIDs[s.Flag],
+			EnvironmentID: envIDs[s.Env],
+			Variant:       s.Variant,
+			Successes:     s.Successes,
+			Errors:        s.Errors,
+		})
+	}
+
+	if handleError(w, r, ctrl.UseCases.Guardrails.Report(ctx, signals...), http.StatusInternalServerError) {
+		return
+	}
+
+	w.WriteHeader(200)
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+// CreateGuardrailRequest
+// swagger:parameters createGuardrail
+type CreateGuardrailRequest struct {
+	// required: true
+	// in: body
+	Body struct {
+		Guardrail struct {
+			// RolloutID is the guarded rollout.
+			//
+			// required: true
+			RolloutID string `json:"rollout_id"`
+			// MaxErrorRateIncrease is how many percentage points the error rate of the enrolled pilots
+			// may be above the error rate of the control group.
+			//
+			// required: true
+			// example: 2
+			MaxErrorRateIncrease float64 `json:"max_error_rate_increase"`
+			// WindowSeconds is the length of the window of the recent health signals, which are compared.
+			//
+			// required: true
+			// example: 600
+			WindowSeconds int `json:"window_seconds"`
+			// MinRequests is the number of requests both variants need in the window, before they are compared.
+			//
+			// required: true
+			// example: 100
+			MinRequests int `json:"min_requests"`
+			// Action is the rollback of the rollout, when the guardrail trips.
+			//
+			// required: true
+			// enum: zero,previous_version
+			Action string `json:"action"`
+		} `json:"guardrail"`
+	}
+}
+
+// GuardrailResponse
+// swagger:response guardrailResponse
+type GuardrailResponse struct {
+	// in: body
+	Body struct {
+		Guardrail guardrail.Guardrail `json:"guardrail"`
+	}
+}
+
+/*
+Create
+swagger:route POST /guardrails guardrail createGuardrail
+
+Arm a guardrail for a rollout, which rolls back the rollout
+when the error rate of its enrolled pilots is too high compared to the control group.
+
+	Consumes:
+	- application/json
+
+	Produces:
+	- application/json
+
+	Schemes: http, https
+
+	Security:
+	  AppToken: []
+
+	Responses:
+	  200: guardrailResponse
+	  400: errorResponse
+	  401: errorResponse
+	  404: errorResponse
+	  409: errorResponse
+	  500: errorResponse
+*/
+func (ctrl GuardrailController) Create(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close() // ignorable
+
+	var req CreateGuardrailRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if handleError(w, r, decoder.Decode(&req.Body), http.StatusBadRequest) {
+		return
+	}
+
+	p := req.Body.Guardrail
+	g := guardrail.Guardrail{
+		RolloutID:            p.RolloutID,
+		MaxErrorRateIncrease: p.MaxErrorRateIncrease,
+		WindowSeconds:        p.WindowSeconds,
+		MinRequests:          p.MinRequests,
+		Action:               p.Action,
+	}
+	if handleError(w, r, ctrl.UseCases.Guardrails.Create(r.Context(), &g), http.StatusInternalServerError) {
+		return
+	}
+
+	var resp GuardrailResponse
+	resp.Body.Guardrail = g
+	serveJSON(w, r, resp.Body)
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+// ListGuardrailRequest
+// swagger:parameters listGuardrails
+type ListGuardrailRequest struct {
+	// RolloutID filters the guardrails by rollout.
+	//
+	// in: query
+	RolloutID string `json:"rollout_id"`
+}
+
+// ListGuardrailResponse
+// swagger:response listGuardrailResponse
+type ListGuardrailResponse struct {
+	// in: body
+	Body struct {
+		Guardrails []guardrail.Guardrail `json:"guardrails"`
+	}
+}
+
+/*
+List
+swagger:route GET /guardrails guardrail listGuardrails
+
+List the guardrails, along with the reason of their last trip.
+
+	Produces:
+	- application/json
+
+	Schemes: http, https
+
+	Security:
+	  AppToken: []
+
+	Responses:
+	  200: listGuardrailResponse
+	  401: errorResponse
+	  500: errorResponse
+*/
+func (ctrl GuardrailController) List(w http.ResponseWriter, r *http.Request) {
+	guardrails, err := ctrl.UseCases.Guardrails.List(r.Context(), r.URL.Query().Get(`rollout_id`))
+	if handleError(w, r, err, http.StatusInternalServerError) {
+		return
+	}
+
+	var resp ListGuardrailResponse
+	resp.Body.Guardrails = guardrails
+	serveJSON(w, r, resp.Body)
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+// ShowGuardrailRequest
+// swagger:parameters showGuardrail deleteGuardrail rearmGuardrail
+type ShowGuardrailRequest struct {
+	// GuardrailID is the guardrail id.
+	//
+	// in: path
+	// required: true
+	GuardrailID string `json:"guardrailID"`
+}
+
+// ShowGuardrailResponse
+// swagger:response showGuardrailResponse
+type ShowGuardrailResponse struct {
+	// in: body
+	Body struct {
+		Guardrail guardrail.Guardrail `json:"guardrail"`
+		// Health is the sum of the health signals in the current window of the guardrail, by variant.
+		Health struct {
+			Enrolled guardrail.Health `json:"enrolled"`
+			Control  guardrail.Health `json:"control"`
+		} `json:"health"`
+	}
+}
+
+/*
+Show
+swagger:route GET /guardrails/{guardrailID} guardrail showGuardrail
+
+Show a guardrail along with the health of its rollout in the current window.
+
+	Produces:
+	- application/json
+
+	Schemes: http, https
+
+	Security:
+	  AppToken: []
+
+	Responses:
+	  200: showGuardrailResponse
+	  401: errorResponse
+	  404: errorResponse
+	  500: errorResponse
+*/
+func (ctrl GuardrailController) Show(w http.ResponseWriter, r *http.Request, id string) {
+	g, err := ctrl.UseCases.Guardrails.Find(r.Context(), id)
+	if handleError(w, r, err, http.StatusInternalServerError) {
+		return
+	}
+
+	var resp ShowGuardrailResponse
+	resp.Body.Guardrail = g
+	resp.Body.Health.Enrolled, resp.Body.Health.Control, err = ctrl.UseCases.Guardrails.Health(r.Context(), g, time.Now())
+	if handleError(w, r, err, http.StatusInternalServerError) {
+		return
+	}
+	serveJSON(w, r, resp.Body)
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+/*
+Rearm
+swagger:route POST /guardrails/{guardrailID}/rearm guardrail rearmGuardrail
+
+Re-arm a tripped guardrail, once its rollout is fixed.
+Only the health signals reported after the re-arm are compared.
+
+	Produces:
+	- application/json
+
+	Schemes: http, https
+
+	Security:
+	  AppToken: []
+
+	Responses:
+	  200: showGuardrailResponse
+	  401: errorResponse
+	  404: errorResponse
+	  409: errorResponse
+	  500: errorResponse
+*/
+func (ctrl GuardrailController) Rearm(w http.ResponseWriter, r *http.Request, id string) {
+	if handleError(w, r, ctrl.UseCases.Guardrails.Rearm(r.Context(), id), http.StatusInternalServerError) {
+		return
+	}
+	ctrl.Show(w, r, id)
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+// DeleteGuardrailResponse
+// swagger:response deleteGuardrailResponse
+type DeleteGuardrailResponse struct {
+}
+
+/*
+Delete
+swagger:route DELETE /guardrails/{guardrailID} guardrail deleteGuardrail
+
+Delete a guardrail, so its rollout is no longer watched.
+
+	Produces:
+	- application/json
+
+	Schemes: http, https
+
+	Security:
+	  AppToken: []
+
+	Responses:
+	  200: deleteGuardrailResponse
+	  401: errorResponse
+	  404: errorResponse
+	  500: errorResponse
+*/
+func (ctrl GuardrailController) Delete(w http.ResponseWriter, r *http.Request, id string) {
+	if handleError(w, r, ctrl.UseCases.Guardrails.Delete(r.Context(), id), http.StatusInternalServerError) {
+		return
+	}
+
+	w.WriteHeader(200)
+}
@@ -0,0 +1,488 @@
+package httpapi_test
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/adamluzsi/frameless/iterators"
+	"github.com/adamluzsi/testcase"
+	hs "github.com/adamluzsi/testcase/httpspec"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/guardrail"
+	"github.com/toggler-io/toggler/external/interface/httpintf/httpapi"
+	sh "github.com/toggler-io/toggler/spechelper"
+)
+
+func TestGuardrailController(t *testing.T) {
+	s := sh.NewSpec(t)
+	s.Parallel()
+
+	hs.Context.Let(s, func(t *testcase.T) interface{} { return sh.ContextGet(t) })
+	hs.HandlerLet(s, func(t *testcase.T) http.Handler { return httpapi.NewGuardrailHandler(sh.ExampleUseCases(t)) })
+	hs.ContentTypeIsJSON(s)
+	sh.GivenHTTPRequestHasAppToken(s)
+
+	s.Describe(`POST /guardrails - create`, SpecGuardrailControllerCreate)
+	s.Describe(`GET /guardrails - list`, SpecGuardrailControllerList)
+	s.Describe(`GET /guardrails/{guardrailID} - show`, SpecGuardrailControllerShow)
+	s.Describe(`POST /guardrails/{guardrailID}/rearm - rearm`, SpecGuardrailControllerRearm)
+	s.Describe(`DELETE /guardrails/{guardrailID} - delete`, SpecGuardrailControllerDelete)
+	s.Describe(`POST /guardrail-signals - report`, SpecGuardrailControllerReport)
+}
+
+// givenWeHaveAGuardrail arms a guardrail for the example rollout.
+func givenWeHaveAGuardrail(s *testcase.Spec) testcase.Var {
+	return s.Let(`guardrail`, func(t *testcase.T) interface{} {
+		g := guardrail.Guardrail{
+			RolloutID:            sh.ExampleReleaseRollout(t).ID,
+			MaxErrorRateIncrease: 2,
+			WindowSeconds:        600,
+			MinRequests:          100,
+			Action:               guardrail.ActionZero,
+		}
+		require.Nil(t, sh.ExampleUseCases(t).Guardrails.Create(sh.ContextGet(t), &g))
+		return g
+	})
+}
+
+func guardrailPathLet(s *testcase.Spec, g testcase.Var, action string) testcase.Var {
+	id := s.Let(`guardrail id`, func(t *testcase.T) interface{} {
+		return g.Get(t).(guardrail.Guardrail).ID
+	})
+	hs.Path.Let(s, func(t *testcase.T) interface{} {
+		return `/guardrails/` + id.Get(t).(string) + action
+	})
+	return id
+}
+
+func thenShowGuardrailResponse(t *testcase.T) httpapi.ShowGuardrailResponse {
+	rr := hs.ServeHTTP(t)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var resp httpapi.ShowGuardrailResponse
+	IsJsonResponse(t, rr, &resp.Body)
+	return resp
+}
+
+func findGuardrail(t *testcase.T, id string) (guardrail.Guardrail, bool) {
+	var g guardrail.Guardrail
+	found, err := sh.StorageGet(t).Guardrail(sh.ContextGet(t)).FindByID(sh.ContextGet(t), &g, id)
+	require.Nil(t, err)
+	return g, found
+}
+
+func SpecGuardrailControllerCreate(s *testcase.Spec) {
+	hs.Method.LetValue(s, http.MethodPost)
+	hs.Path.LetValue(s, `/guardrails`)
+
+	request := s.Let(`request`, func(t *testcase.T) interface{} {
+		var req httpapi.CreateGuardrailRequest
+		req.Body.Guardrail.RolloutID = sh.ExampleReleaseRollout(t).ID
+		req.Body.Guardrail.MaxErrorRateIncrease = 2
+		req.Body.Guardrail.WindowSeconds = 600
+		req.Body.Guardrail.MinRequests = 100
+		req.Body.Guardrail.Action = guardrail.ActionPreviousVersion
+		return &req
+	})
+	requestGet := func(t *testcase.T) *httpapi.CreateGuardrailRequest {
+		return request.Get(t).(*httpapi.CreateGuardrailRequest)
+	}
+	hs.Body.Let(s, func(t *testcase.T) interface{} { return requestGet(t).Body })
+
+	s.Then(`the guardrail is armed for the rollout`, func(t *testcase.T) {
+		rr := hs.ServeHTTP(t)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var resp httpapi.GuardrailResponse
+		IsJsonResponse(t, rr, &resp.Body)
+
+		g := resp.Body.Guardrail
+		require.NotEmpty(t, g.ID)
+		require.Equal(t, sh.ExampleReleaseRollout(t).ID, g.RolloutID)
+		require.Equal(t, 2.0, g.MaxErrorRateIncrease)
+		require.Equal(t, 600, g.WindowSeconds)
+		require.Equal(t, 100, g.MinRequests)
+		require.Equal(t, guardrail.ActionPreviousVersion, g.Action)
+		require.Equal(t, guardrail.StatusArmed, g.Status)
+		require.False(t, g.ArmedAt.IsZero())
+
+		stored, found := findGuardrail(t, g.ID)
+		require.True(t, found)
+		require.Equal(t, g.RolloutID, stored.RolloutID)
+	})
+
+	thenItIsRejected := func(s *testcase.Spec, code int, key, field string) {
+		s.Then(`it is rejected, and no guardrail is armed`, func(t *testcase.T) {
+			resp := thenErrorResponse(t, code, key)
+			require.Equal(t, field, resp.Field)
+
+			guardrails, err := sh.ExampleUseCases(t).Guardrails.List(sh.ContextGet(t), ``)
+			require.Nil(t, err)
+			require.Empty(t, guardrails)
+		})
+	}
+
+	s.When(`the rollout is missing`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { requestGet(t).Body.Guardrail.RolloutID = `` })
+
+		thenItIsRejected(s, http.StatusBadRequest, `guardrail_rollout_is_missing`, `rollout_id`)
+	})
+
+	s.When(`the rollout is unknown`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { requestGet(t).Body.Guardrail.RolloutID = `unknown` })
+
+		thenItIsRejected(s, http.StatusNotFound, `rollout_not_found`, ``)
+	})
+
+	s.When(`the max error rate increase is above 100 percentage points`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { requestGet(t).Body.Guardrail.MaxErrorRateIncrease = 100.5 })
+
+		thenItIsRejected(s, http.StatusBadRequest, `guardrail_max_error_rate_increase_is_invalid`, `max_error_rate_increase`)
+	})
+
+	s.When(`the max error rate increase is negative`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { requestGet(t).Body.Guardrail.MaxErrorRateIncrease = -1 })
+
+		thenItIsRejected(s, http.StatusBadRequest, `guardrail_max_error_rate_increase_is_invalid`, `max_error_rate_increase`)
+	})
+
+	s.When(`the window is not positive`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { requestGet(t).Body.Guardrail.WindowSeconds = 0 })
+
+		thenItIsRejected(s, http.StatusBadRequest, `guardrail_window_is_invalid`, `window_seconds`)
+	})
+
+	s.When(`the min requests is below one`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { requestGet(t).Body.Guardrail.MinRequests = 0 })
+
+		thenItIsRejected(s, http.StatusBadRequest, `guardrail_min_requests_is_invalid`, `min_requests`)
+	})
+
+	s.When(`the action is unknown`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { requestGet(t).Body.Guardrail.Action = `panic` })
+
+		thenItIsRejected(s, http.StatusBadRequest, `guardrail_action_is_invalid`, `action`)
+	})
+
+	s.When(`the body has an unknown field`, func(s *testcase.Spec) {
+		hs.Body.Let(s, func(t *testcase.T) interface{} {
+			return strings.NewReader(`{"guardrail":{"max_error_rate":2}}`)
+		})
+
+		thenItIsRejected(s, http.StatusBadRequest, `bad_request`, ``)
+	})
+
+	s.When(`the request has no valid token`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { hs.HeaderGet(t).Del(`X-App-Token`) })
+
+		thenItIsRejected(s, http.StatusUnauthorized, `invalid_token`, ``)
+	})
+
+	s.When(`the rollout already has a guardrail`, func(s *testcase.Spec) {
+		g := givenWeHaveAGuardrail(s)
+		s.Before(func(t *testcase.T) { g.Get(t) })
+
+		s.Then(`it is a conflict, and the guardrail is left as it is`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusConflict, `guardrail_already_exist`)
+
+			guardrails, err := sh.ExampleUseCases(t).Guardrails.List(sh.ContextGet(t), ``)
+			require.Nil(t, err)
+			require.Len(t, guardrails, 1)
+			require.Equal(t, guardrail.ActionZero, guardrails[0].Action)
+		})
+	})
+
+	s.When(`the method is not allowed`, func(s *testcase.Spec) {
+		hs.Method.LetValue(s, http.MethodPut)
+
+		s.Then(`it is rejected`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusMethodNotAllowed, `method_not_allowed`)
+		})
+	})
+}
+
+func SpecGuardrailControllerList(s *testcase.Spec) {
+	hs.Method.LetValue(s, http.MethodGet)
+	hs.Path.LetValue(s, `/guardrails`)
+
+	g := givenWeHaveAGuardrail(s)
+	s.Before(func(t *testcase.T) { g.Get(t) })
+
+	list := func(t *testcase.T) []guardrail.Guardrail {
+		rr := hs.ServeHTTP(t)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var resp httpapi.ListGuardrailResponse
+		IsJsonResponse(t, rr, &resp.Body)
+		return resp.Body.Guardrails
+	}
+
+	s.Then(`the guardrails are listed`, func(t *testcase.T) {
+		guardrails := list(t)
+		require.Len(t, guardrails, 1)
+		require.Equal(t, g.Get(t).(guardrail.Guardrail).ID, guardrails[0].ID)
+	})
+
+	s.When(`they are filtered by the rollout`, func(s *testcase.Spec) {
+		hs.Query.Let(s, func(t *testcase.T) interface{} {
+			return url.Values{`rollout_id`: {sh.ExampleReleaseRollout(t).ID}}
+		})
+
+		s.Then(`the guardrail of the rollout is listed`, func(t *testcase.T) {
+			require.Len(t, list(t), 1)
+		})
+	})
+
+	s.When(`they are filtered by an other rollout`, func(s *testcase.Spec) {
+		hs.Query.Let(s, func(t *testcase.T) interface{} { return url.Values{`rollout_id`: {`unknown`}} })
+
+		s.Then(`an empty list is returned`, func(t *testcase.T) {
+			rr := hs.ServeHTTP(t)
+			require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+			require.JSONEq(t, `{"guardrails":[]}`, rr.Body.String())
+		})
+	})
+
+	s.When(`the request has no valid token`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { hs.HeaderGet(t).Del(`X-App-Token`) })
+
+		s.Then(`it is unauthorized`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusUnauthorized, `invalid_token`)
+		})
+	})
+}
+
+func SpecGuardrailControllerShow(s *testcase.Spec) {
+	hs.Method.LetValue(s, http.MethodGet)
+	g := givenWeHaveAGuardrail(s)
+	id := guardrailPathLet(s, g, ``)
+
+	s.Then(`the guardrail is shown with an empty health`, func(t *testcase.T) {
+		resp := thenShowGuardrailResponse(t)
+		require.Equal(t, g.Get(t).(guardrail.Guardrail).ID, resp.Body.Guardrail.ID)
+		require.Equal(t, guardrail.Health{}, resp.Body.Health.Enrolled)
+		require.Equal(t, guardrail.Health{}, resp.Body.Health.Control)
+	})
+
+	s.When(`health signals were reported for the rollout`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) {
+			g.Get(t) // armed before the signals
+			rollout := sh.ExampleReleaseRollout(t)
+			require.Nil(t, sh.ExampleUseCases(t).Guardrails.Report(sh.ContextGet(t),
+				&guardrail.Signal{FlagID: rollout.FlagID, EnvironmentID: rollout.EnvironmentID, Variant: guardrail.VariantEnrolled, Successes: 40, Errors: 2},
+				&guardrail.Signal{FlagID: rollout.FlagID, EnvironmentID: rollout.EnvironmentID, Variant: guardrail.VariantControl, Successes: 90, Errors: 1},
+			))
+		})
+
+		s.Then(`the health of the variants is shown`, func(t *testcase.T) {
+			resp := thenShowGuardrailResponse(t)
+			require.Equal(t, guardrail.Health{Successes: 40, Errors: 2}, resp.Body.Health.Enrolled)
+			require.Equal(t, guardrail.Health{Successes: 90, Errors: 1}, resp.Body.Health.Control)
+		})
+	})
+
+	s.When(`the guardrail is unknown`, func(s *testcase.Spec) {
+		id.LetValue(s, `unknown`)
+
+		s.Then(`it is not found`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusNotFound, `guardrail_not_found`)
+		})
+	})
+
+	s.When(`the path is not a guardrail`, func(s *testcase.Spec) {
+		hs.Path.LetValue(s, `/guardrails/`)
+
+		s.Then(`it is not found`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusNotFound, `not_found`)
+		})
+	})
+}
+
+func SpecGuardrailControllerRearm(s *testcase.Spec) {
+	hs.Method.LetValue(s, http.MethodPost)
+	g := givenWeHaveAGuardrail(s)
+	id := guardrailPathLet(s, g, `/rearm`)
+
+	s.When(`the guardrail is tripped`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) {
+			tripped := g.Get(t).(guardrail.Guardrail)
+			tripped.Status = guardrail.StatusTripped
+			tripped.Reason = `error rate is too high`
+			require.Nil(t, sh.StorageGet(t).Guardrail(sh.ContextGet(t)).Update(sh.ContextGet(t), &tripped))
+		})
+
+		s.Then(`it is armed again, and the reason of the trip is cleared`, func(t *testcase.T) {
+			resp := thenShowGuardrailResponse(t)
+			require.Equal(t, guardrail.StatusArmed, resp.Body.Guardrail.Status)
+			require.Empty(t, resp.Body.Guardrail.Reason)
+
+			stored, found := findGuardrail(t, id.Get(t).(string))
+			require.True(t, found)
+			require.True(t, stored.IsArmed())
+		})
+	})
+
+	s.When(`the guardrail is armed`, func(s *testcase.Spec) {
+		s.Then(`it is a conflict`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusConflict, `guardrail_not_tripped`)
+		})
+	})
+
+	s.When(`the guardrail is unknown`, func(s *testcase.Spec) {
+		id.LetValue(s, `unknown`)
+
+		s.Then(`it is not found`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusNotFound, `guardrail_not_found`)
+		})
+	})
+
+	s.When(`the method is not POST`, func(s *testcase.Spec) {
+		hs.Method.LetValue(s, http.MethodGet)
+
+		s.Then(`the method is not allowed`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusMethodNotAllowed, `method_not_allowed`)
+		})
+	})
+}
+
+func SpecGuardrailControllerDelete(s *testcase.Spec) {
+	hs.Method.LetValue(s, http.MethodDelete)
+	g := givenWeHaveAGuardrail(s)
+	id := guardrailPathLet(s, g, ``)
+
+	s.Then(`the guardrail is deleted`, func(t *testcase.T) {
+		rr := hs.ServeHTTP(t)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		_, found := findGuardrail(t, id.Get(t).(string))
+		require.False(t, found)
+	})
+
+	s.When(`the guardrail is unknown`, func(s *testcase.Spec) {
+		id.LetValue(s, `unknown`)
+
+		s.Then(`it is not found`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusNotFound, `guardrail_not_found`)
+		})
+	})
+
+	s.When(`the request has no valid token`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { hs.HeaderGet(t).Del(`X-App-Token`) })
+
+		s.Then(`it is unauthorized, and the guardrail is kept`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusUnauthorized, `invalid_token`)
+			_, found := findGuardrail(t, id.Get(t).(string))
+			require.True(t, found)
+		})
+	})
+}
+
+func SpecGuardrailControllerReport(s *testcase.Spec) {
+	hs.Method.LetValue(s, http.MethodPost)
+	hs.Path.LetValue(s, `/guardrail-signals`)
+
+	flagName := s.Let(`flag name`, func(t *testcase.T) interface{} { return sh.ExampleReleaseFlag(t).Name })
+	envAlias := s.Let(`env alias`, func(t *testcase.T) interface{} { return sh.ExampleDeploymentEnvironment(t).Name })
+	variant := s.LetValue(`variant`, guardrail.VariantEnrolled)
+	errorsCount := s.LetValue(`errors`, 3)
+	hs.Body.Let(s, func(t *testcase.T) interface{} {
+		var req httpapi.ReportGuardrailSignalRequest
+		req.Body.Signals = append(req.Body.Signals, struct {
+			Flag      string `json:"flag"`
+			Env       string `json:"env"`
+			Variant   string `json:"variant"`
+			Successes int    `json:"successes"`
+			Errors    int    `json:"errors"`
+		}{
+			Flag:      flagName.Get(t).(string),
+			Env:       envAlias.Get(t).(string),
+			Variant:   variant.Get(t).(string),
+			Successes: 97,
+			Errors:    errorsCount.Get(t).(int),
+		})
+		return req.Body
+	})
+
+	signals := func(t *testcase.T) []guardrail.Signal {
+		var signals []guardrail.Signal
+		require.Nil(t, iterators.Collect(sh.StorageGet(t).GuardrailSignal(sh.ContextGet(t)).FindByQuery(sh.ContextGet(t), guardrail.SignalQuery{}), &signals))
+		return signals
+	}
+
+	thenItIsRejected := func(s *testcase.Spec, code int, key string) {
+		s.Then(`it is rejected, and no signal is recorded`, func(t *testcase.T) {
+			thenErrorResponse(t, code, key)
+			require.Empty(t, signals(t))
+		})
+	}
+
+	s.Then(`the signal is recorded for the flag in the environment`, func(t *testcase.T) {
+		rr := hs.ServeHTTP(t)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		recorded := signals(t)
+		require.Len(t, recorded, 1)
+		require.Equal(t, sh.ExampleReleaseFlag(t).ID, recorded[0].FlagID)
+		require.Equal(t, sh.ExampleDeploymentEnvironment(t).ID, recorded[0].EnvironmentID)
+		require.Equal(t, guardrail.VariantEnrolled, recorded[0].Variant)
+		require.Equal(t, 97, recorded[0].Successes)
+		require.Equal(t, 3, recorded[0].Errors)
+		require.False(t, recorded[0].ReportedAt.IsZero())
+	})
+
+	s.When(`the environment is given by its id`, func(s *testcase.Spec) {
+		envAlias.Let(s, func(t *testcase.T) interface{} { return sh.ExampleDeploymentEnvironment(t).ID })
+
+		s.Then(`the signal is recorded for the environment`, func(t *testcase.T) {
+			rr := hs.ServeHTTP(t)
+			require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+			recorded := signals(t)
+			require.Len(t, recorded, 1)
+			require.Equal(t, sh.ExampleDeploymentEnvironment(t).ID, recorded[0].EnvironmentID)
+		})
+	})
+
+	s.When(`the flag is unknown`, func(s *testcase.Spec) {
+		flagName.LetValue(s, `unknown`)
+
+		thenItIsRejected(s, http.StatusNotFound, `flag_not_found`)
+	})
+
+	s.When(`the environment is unknown`, func(s *testcase.Spec) {
+		envAlias.LetValue(s, `unknown`)
+
+		thenItIsRejected(s, http.StatusNotFound, `environment_not_found`)
+	})
+
+	s.When(`the variant is unknown`, func(s *testcase.Spec) {
+		variant.LetValue(s, `canary`)
+
+		thenItIsRejected(s, http.StatusBadRequest, `health_signal_variant_is_invalid`)
+	})
+
+	s.When(`a counter is negative`, func(s *testcase.Spec) {
+		errorsCount.LetValue(s, -1)
+
+		thenItIsRejected(s, http.StatusBadRequest, `health_signal_is_invalid`)
+	})
+
+	s.When(`the body has an unknown field`, func(s *testcase.Spec) {
+		hs.Body.Let(s, func(t *testcase.T) interface{} {
+			return strings.NewReader(`{"signals":[{"flag":"my-flag","latency":12}]}`)
+		})
+
+		thenItIsRejected(s, http.StatusBadRequest, `bad_request`)
+	})
+
+	s.When(`the request has no valid token`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { hs.HeaderGet(t).Del(`X-App-Token`) })
+
+		thenItIsRejected(s, http.StatusUnauthorized, `invalid_token`)
+	})
+
+	s.When(`the method is not POST`, func(s *testcase.Spec) {
+		hs.Method.LetValue(s, http.MethodGet)
+
+		thenItIsRejected(s, http.StatusMethodNotAllowed, `method_not_allowed`)
+	})
+}
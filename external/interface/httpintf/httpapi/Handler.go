@@ -3,13 +3,14 @@ package httpapi
 import (
 	"bytes"
 	"encoding/json"
-	"log"
 	"net/http"
 
 	"github.com/adamluzsi/gorest"
 	"github.com/gorilla/websocket"
 
 	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/external/interface/httpintf/unleash"
+	"github.com/toggler-io/toggler/external/logging"
 )
 
 func NewHandler(uc *toggler.UseCases) *Handler {
@@ -24,10 +25,23 @@ func NewHandler(uc *toggler.UseCases) *Handler {
 	gorest.Mount(mux.ServeMux, `/deployment-environments`, NewDeploymentEnvironmentHandler(uc))
 	gorest.Mount(mux.ServeMux, `/release-pilots`, NewReleasePilotHandler(uc))
 	gorest.Mount(mux.ServeMux, `/release-rollouts`, NewReleaseRolloutHandler(uc))
-
-	mux.HandleFunc(`/healthcheck`, func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(200)
-	})
+	mux.Handle(`/manifest`, NewManifestHandler(uc))
+	versions := NewReleaseVersionHandler(uc)
+	mux.Handle(`/release-versions`, versions)
+	mux.Handle(`/release-versions/`, versions)
+	mux.Handle(`/release-evaluations`, versions)
+	changes := NewChangeRequestHandler(uc)
+	mux.Handle(`/change-requests`, changes)
+	mux.Handle(`/change-requests/`, changes)
+	schedules := NewScheduledChangeHandler(uc)
+	mux.Handle(`/scheduled-changes`, schedules)
+	mux.Handle(`/scheduled-changes/`, schedules)
+	guardrails := NewGuardrailHandler(uc)
+	mux.Handle(`/guardrails`, guardrails)
+	mux.Handle(`/guardrails/`, guardrails)
+	mux.Handle(`/guardrail-signals`, guardrails)
+	mux.Handle(`/ofrep/`, NewOFREPHandler(uc))
+	mux.Handle(`/client/`, unleash.NewHandler(uc))
 
 	return mux
 }
@@ -38,19 +52,23 @@ type Handler struct {
 	*websocket.Upgrader
 }
 
-func serveJSON(w http.ResponseWriter, data interface{}) {
+func serveJSON(w http.ResponseWriter, r *http.Request, data interface{}) {
+	serveJSONWithStatus(w, r, http.StatusOK, data)
+}
+
+func serveJSONWithStatus(w http.ResponseWriter, r *http.Request, code int, data interface{}) {
 	buf := bytes.NewBuffer([]byte{})
 
 	if err := json.NewEncoder(buf).Encode(data); err != nil {
-		log.Println(`ERROR`, err.Error())
+		logging.Error(r.Context(), `unable to encode the response`, err)
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set(`Content-Type`, `application/json`)
-	w.WriteHeader(200)
+	w.WriteHeader(code)
 
 	if _, err := w.Write(buf.Bytes()); err != nil {
-		log.Println(err)
+		logging.Error(r.Context(), `unable to write the response`, err)
 	}
 }
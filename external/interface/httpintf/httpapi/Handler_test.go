@@ -16,7 +16,7 @@ import (
 	sh "github.com/toggler-io/toggler/spechelper"
 )
 
-func NewServeMux(t *testcase.T) *http.ServeMux {
+func NewServeMux(t *testcase.T) *httpintf.ServeMux {
 	m, err := httpintf.NewServeMux(sh.ExampleUseCases(t))
 	require.Nil(t, err)
 	return m
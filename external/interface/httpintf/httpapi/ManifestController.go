@@ -0,0 +1,200 @@
+package httpapi
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/external/interface/httpintf/httputils"
+)
+
+func NewManifestHandler(uc *toggler.UseCases) http.Handler {
+	ctrl := ManifestController{UseCases: uc}
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			ctrl.Export(w, r)
+		case http.MethodPost:
+			ctrl.Apply(w, r)
+		default:
+			ErrorWriterFunc(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}
+	})
+	return httputils.AuthMiddleware(h, uc, WriteError)
+}
+
+type ManifestController struct {
+	UseCases *toggler.UseCases
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+// ExportManifestRequest
+// swagger:parameters exportManifest
+type ExportManifestRequest struct {
+	// Pilots includes the manual pilot enrollments in the manifest.
+	//
+	// in: query
+	Pilots bool `json:"pilots"`
+	// Format of the manifest.
+	// When omitted, the Accept header decides, and JSON is used by default.
+	//
+	// in: query
+	// enum: json,yaml
+	Format string `json:"format"`
+}
+
+// ExportManifestResponse
+// swagger:response exportManifestResponse
+type ExportManifestResponse struct {
+	// in: body
+	Body release.Manifest
+}
+
+/*
+
+	Export
+	swagger:route GET /manifest manifest exportManifest
+
+	Export the environments, flags, rollouts and optionally the pilots as a declarative manifest.
+	Entities reference each other by name, so the manifest can be kept in version control.
+
+		Produces:
+		- application/json
+		- application/yaml
+
+		Schemes: http, https
+
+		Security:
+		  AppToken: []
+
+		Responses:
+		  200: exportManifestResponse
+		  401: errorResponse
+		  500: errorResponse
+
+*/
+func (ctrl ManifestController) Export(w http.ResponseWriter, r *http.Request) {
+	m, err := ctrl.UseCases.RolloutManager.ExportManifest(r.Context(), release.ManifestOptions{
+		Pilots: r.URL.Query().Get(`pilots`) == `true`,
+	})
+	if handleError(w, r, err, http.StatusInternalServerError) {
+		return
+	}
+
+	format := r.URL.Query().Get(`format`)
+	if format == `` && strings.Contains(r.Header.Get(`Accept`), `yaml`) {
+		format = `yaml`
+	}
+
+	if format != `yaml` {
+		serveJSON(w, r, m)
+		return
+	}
+
+	bs, err := yaml.Marshal(m)
+	if handleError(w, r, err, http.StatusInternalServerError) {
+		return
+	}
+	w.Header().Set(`Content-Type`, `application/yaml`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(bs)
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+// ApplyManifestRequest
+// swagger:parameters applyManifest
+type ApplyManifestRequest struct {
+	// DryRun only computes the plan, without applying it.
+	//
+	// in: query
+	DryRun bool `json:"dry_run"`
+	// Prune deletes the entities that are not declared in the manifest.
+	//
+	// in: query
+	Prune bool `json:"prune"`
+	// Pilots reconciles the manual pilot enrollments as well.
+	//
+	// in: query
+	Pilots bool `json:"pilots"`
+	// The manifest in either JSON or YAML format.
+	//
+	// in: body
+	Body release.Manifest
+}
+
+// ApplyManifestResponse
+// swagger:response applyManifestResponse
+type ApplyManifestResponse struct {
+	// in: body
+	Body struct {
+		Plan    release.ManifestPlan `json:"plan"`
+		Applied bool                 `json:"applied"`
+	}
+}
+
+/*
+
+	Apply
+	swagger:route POST /manifest manifest applyManifest
+
+	Compute the plan that makes the release configuration match the manifest, and apply it in a single transaction.
+
+		Consumes:
+		- application/json
+		- application/yaml
+
+		Produces:
+		- application/json
+
+		Schemes: http, https
+
+		Security:
+		  AppToken: []
+
+		Responses:
+		  200: applyManifestResponse
+		  400: errorResponse
+		  401: errorResponse
+		  500: errorResponse
+
+*/
+func (ctrl ManifestController) Apply(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	data, err := ioutil.ReadAll(r.Body)
+	if handleError(w, r, err, http.StatusBadRequest) {
+		return
+	}
+
+	var m release.Manifest
+	if handleError(w, r, yaml.Unmarshal(data, &m), http.StatusBadRequest) {
+		return
+	}
+
+	var (
+		q      = r.URL.Query()
+		dryRun = q.Get(`dry_run`) == `true`
+		opts   = release.ManifestOptions{
+			Pilots: q.Get(`pilots`) == `true`,
+			Prune:  q.Get(`prune`) == `true`,
+		}
+		resp ApplyManifestResponse
+	)
+
+	if dryRun {
+		resp.Body.Plan, err = ctrl.UseCases.RolloutManager.PlanManifest(r.Context(), m, opts)
+	} else {
+		resp.Body.Plan, err = ctrl.UseCases.RolloutManager.ApplyManifest(r.Context(), m, opts)
+	}
+	if handleError(w, r, err, http.StatusInternalServerError) {
+		return
+	}
+
+	resp.Body.Applied = !dryRun
+	serveJSON(w, r, resp.Body)
+}
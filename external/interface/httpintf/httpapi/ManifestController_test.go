@@ -0,0 +1,299 @@
+package httpapi_test
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	hs "github.com/adamluzsi/testcase/httpspec"
+	"github.com/ghodss/yaml"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/external/interface/httpintf/httpapi"
+	sh "github.com/toggler-io/toggler/spechelper"
+)
+
+func TestManifestController(t *testing.T) {
+	s := sh.NewSpec(t)
+	s.Parallel()
+
+	hs.Context.Let(s, func(t *testcase.T) interface{} { return sh.ContextGet(t) })
+	hs.HandlerLet(s, func(t *testcase.T) http.Handler { return httpapi.NewManifestHandler(sh.ExampleUseCases(t)) })
+	hs.ContentTypeIsJSON(s)
+	hs.Path.LetValue(s, `/manifest`)
+	sh.GivenHTTPRequestHasAppToken(s)
+
+	s.Describe(`GET /manifest - export`, SpecManifestControllerExport)
+	s.Describe(`POST /manifest - apply`, SpecManifestControllerApply)
+
+	s.Describe(`PUT /manifest`, func(s *testcase.Spec) {
+		hs.Method.LetValue(s, http.MethodPut)
+
+		s.Then(`the method is not allowed`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusMethodNotAllowed, `method_not_allowed`)
+		})
+	})
+}
+
+func SpecManifestControllerExport(s *testcase.Spec) {
+	hs.Method.LetValue(s, http.MethodGet)
+
+	s.Before(func(t *testcase.T) { sh.ExampleReleaseRollout(t) }) // eager load
+
+	findFlag := func(t *testcase.T, m release.Manifest) release.ManifestFlag {
+		for _, f := range m.Flags {
+			if f.Name == sh.ExampleReleaseFlag(t).Name {
+				return f
+			}
+		}
+		t.Fatalf(`the flag %s is missing from the manifest`, sh.ExampleReleaseFlag(t).Name)
+		return release.ManifestFlag{}
+	}
+	onSuccess := func(t *testcase.T) release.Manifest {
+		rr := hs.ServeHTTP(t)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var m release.Manifest
+		IsJsonResponse(t, rr, &m)
+		return m
+	}
+
+	s.Then(`the environments, flags and rollouts are exported, referencing each other by name`, func(t *testcase.T) {
+		m := onSuccess(t)
+		require.Contains(t, m.Environments, release.ManifestEnvironment{Name: sh.ExampleDeploymentEnvironment(t).Name})
+
+		flag := findFlag(t, m)
+		require.Len(t, flag.Rollouts, 1)
+		require.Equal(t, sh.ExampleDeploymentEnvironment(t).Name, flag.Rollouts[0].Environment)
+		require.Equal(t, sh.ExampleReleaseRollout(t).Plan, flag.Rollouts[0].Plan.Plan)
+	})
+
+	s.When(`the flag has a manual pilot enrollment`, func(s *testcase.Spec) {
+		sh.AndExamplePilotManualParticipatingIsSetTo(s, true)
+
+		s.Then(`the pilots are left out by default`, func(t *testcase.T) {
+			require.Empty(t, findFlag(t, onSuccess(t)).Pilots)
+		})
+
+		s.And(`the pilots are requested`, func(s *testcase.Spec) {
+			hs.Query.Let(s, func(t *testcase.T) interface{} { return url.Values{`pilots`: {`true`}} })
+
+			s.Then(`the pilots are exported as well`, func(t *testcase.T) {
+				require.Equal(t, []release.ManifestPilot{{
+					Environment:     sh.ExampleDeploymentEnvironment(t).Name,
+					PublicID:        sh.ExampleExternalPilotID(t),
+					IsParticipating: true,
+				}}, findFlag(t, onSuccess(t)).Pilots)
+			})
+		})
+	})
+
+	thenTheManifestIsYAML := func(s *testcase.Spec) {
+		s.Then(`the manifest is exported as YAML`, func(t *testcase.T) {
+			rr := hs.ServeHTTP(t)
+			require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+			require.Equal(t, `application/yaml`, rr.Header().Get(`Content-Type`))
+			var m release.Manifest
+			require.Nil(t, yaml.Unmarshal(rr.Body.Bytes(), &m))
+			require.Len(t, findFlag(t, m).Rollouts, 1)
+		})
+	}
+
+	s.When(`YAML is requested with the format query parameter`, func(s *testcase.Spec) {
+		hs.Query.Let(s, func(t *testcase.T) interface{} { return url.Values{`format`: {`yaml`}} })
+
+		thenTheManifestIsYAML(s)
+	})
+
+	s.When(`YAML is requested with the Accept header`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { hs.HeaderGet(t).Set(`Accept`, `application/yaml`) })
+
+		thenTheManifestIsYAML(s)
+	})
+
+	s.When(`the request has no valid token`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { hs.HeaderGet(t).Set(`X-App-Token`, `invalid`) })
+
+		s.Then(`it is unauthorized`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusUnauthorized, `invalid_token`)
+		})
+	})
+}
+
+func SpecManifestControllerApply(s *testcase.Spec) {
+	hs.Method.LetValue(s, http.MethodPost)
+
+	envName := s.Let(`manifest env name`, func(t *testcase.T) interface{} {
+		return `env-` + t.Random.StringNWithCharset(8, `abcdef`)
+	})
+	flagName := s.Let(`manifest flag name`, func(t *testcase.T) interface{} {
+		return `flag-` + t.Random.StringNWithCharset(8, `abcdef`)
+	})
+	manifest := s.Let(`manifest`, func(t *testcase.T) interface{} {
+		plan := release.NewRolloutDecisionByPercentage()
+		plan.Percentage = 42
+		return release.Manifest{
+			Environments: []release.ManifestEnvironment{{Name: envName.Get(t).(string)}},
+			Flags: []release.ManifestFlag{{
+				Name: flagName.Get(t).(string),
+				Rollouts: []release.ManifestRollout{{
+					Environment: envName.Get(t).(string),
+					Plan:        release.RolloutPlanView{Plan: plan},
+				}},
+			}},
+		}
+	})
+	hs.Body.Let(s, func(t *testcase.T) interface{} { return manifest.Get(t) })
+
+	onSuccess := func(t *testcase.T) (resp httpapi.ApplyManifestResponse) {
+		rr := hs.ServeHTTP(t)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		IsJsonResponse(t, rr, &resp.Body)
+		return resp
+	}
+	findEnv := func(t *testcase.T) (release.Environment, bool) {
+		var env release.Environment
+		found, err := sh.StorageGet(t).ReleaseEnvironment(sh.ContextGet(t)).FindByAlias(sh.ContextGet(t), envName.Get(t).(string), &env)
+		require.Nil(t, err)
+		return env, found
+	}
+	s.After(func(t *testcase.T) {
+		flag, err := sh.StorageGet(t).ReleaseFlag(sh.ContextGet(t)).FindByName(sh.ContextGet(t), flagName.Get(t).(string))
+		require.Nil(t, err)
+		if flag != nil {
+			require.Nil(t, sh.ExampleRolloutManager(t).DeleteFeatureFlag(sh.ContextGet(t), flag.ID))
+		}
+		if env, found := findEnv(t); found {
+			require.Nil(t, sh.StorageGet(t).ReleaseEnvironment(sh.ContextGet(t)).DeleteByID(sh.ContextGet(t), env.ID))
+		}
+	})
+
+	expectedChanges := func(t *testcase.T) []release.ManifestChange {
+		return []release.ManifestChange{
+			{Action: release.ManifestActionCreate, Kind: release.ManifestKindEnvironment, Environment: envName.Get(t).(string)},
+			{Action: release.ManifestActionCreate, Kind: release.ManifestKindFlag, Flag: flagName.Get(t).(string)},
+			{Action: release.ManifestActionCreate, Kind: release.ManifestKindRollout, Flag: flagName.Get(t).(string), Environment: envName.Get(t).(string)},
+		}
+	}
+	thenTheManifestIsApplied := func(s *testcase.Spec) {
+		s.Then(`the plan is applied`, func(t *testcase.T) {
+			resp := onSuccess(t)
+			require.True(t, resp.Body.Applied)
+			require.ElementsMatch(t, expectedChanges(t), resp.Body.Plan.Changes)
+
+			env, found := findEnv(t)
+			require.True(t, found)
+			flag := sh.FindStoredReleaseFlagByName(t, flagName.Get(t).(string))
+			var rollout release.Rollout
+			found, err := sh.StorageGet(t).ReleaseRollout(sh.ContextGet(t)).FindByFlagEnvironment(sh.ContextGet(t), *flag, env, &rollout)
+			require.Nil(t, err)
+			require.True(t, found)
+			require.Equal(t, 42, rollout.Plan.(release.RolloutDecisionByPercentage).Percentage)
+		})
+	}
+
+	thenTheManifestIsApplied(s)
+
+	s.When(`the manifest is applied a second time`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { onSuccess(t) })
+
+		s.Then(`there is nothing to change`, func(t *testcase.T) {
+			require.Empty(t, onSuccess(t).Body.Plan.Changes)
+		})
+	})
+
+	s.When(`the manifest is in YAML`, func(s *testcase.Spec) {
+		hs.Body.Let(s, func(t *testcase.T) interface{} {
+			bs, err := yaml.Marshal(manifest.Get(t))
+			require.Nil(t, err)
+			return strings.NewReader(string(bs))
+		})
+		s.Before(func(t *testcase.T) { hs.HeaderGet(t).Set(`Content-Type`, `application/yaml`) })
+
+		thenTheManifestIsApplied(s)
+	})
+
+	s.When(`it is a dry run`, func(s *testcase.Spec) {
+		hs.Query.Let(s, func(t *testcase.T) interface{} { return url.Values{`dry_run`: {`true`}} })
+
+		s.Then(`the plan is returned without being applied`, func(t *testcase.T) {
+			resp := onSuccess(t)
+			require.False(t, resp.Body.Applied)
+			require.ElementsMatch(t, expectedChanges(t), resp.Body.Plan.Changes)
+
+			_, found := findEnv(t)
+			require.False(t, found)
+		})
+	})
+
+	s.When(`the body is not a manifest`, func(s *testcase.Spec) {
+		hs.Body.Let(s, func(t *testcase.T) interface{} { return strings.NewReader(`{"flags":`) })
+
+		s.Then(`it responds with 400`, func(t *testcase.T) {
+			rr := hs.ServeHTTP(t)
+			require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+		})
+	})
+
+	s.When(`the manifest declares an environment twice`, func(s *testcase.Spec) {
+		hs.Body.Let(s, func(t *testcase.T) interface{} {
+			m := manifest.Get(t).(release.Manifest)
+			m.Environments = append(m.Environments, m.Environments[0])
+			return m
+		})
+
+		s.Then(`it is rejected as a validation error, and nothing is applied`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusBadRequest, `manifest_duplicate`)
+			_, found := findEnv(t)
+			require.False(t, found)
+		})
+	})
+
+	s.When(`a rollout of the manifest has no environment`, func(s *testcase.Spec) {
+		hs.Body.Let(s, func(t *testcase.T) interface{} {
+			m := manifest.Get(t).(release.Manifest)
+			m.Flags[0].Rollouts[0].Environment = ``
+			return m
+		})
+
+		s.Then(`it is rejected as a validation error of the field`, func(t *testcase.T) {
+			resp := thenErrorResponse(t, http.StatusBadRequest, `environment_is_missing`)
+			require.Equal(t, `validation`, resp.Kind)
+			require.Equal(t, `env_id`, resp.Field)
+		})
+	})
+
+	s.When(`a rollout of the manifest has an invalid plan`, func(s *testcase.Spec) {
+		hs.Body.Let(s, func(t *testcase.T) interface{} {
+			return strings.NewReader(`{"flags":[{"name":"checkout","rollouts":[{"env":"production","plan":{"type":"percentage","percentage":101}}]}]}`)
+		})
+
+		s.Then(`it is rejected as a bad request`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusBadRequest, `bad_request`)
+		})
+	})
+
+	s.When(`the request has no valid token`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { hs.HeaderGet(t).Del(`X-App-Token`) })
+
+		s.Then(`it is unauthorized, and nothing is applied`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusUnauthorized, `invalid_token`)
+			_, found := findEnv(t)
+			require.False(t, found)
+		})
+	})
+}
+
+// thenErrorResponse asserts the status code and the error key of the response, unless the key is empty.
+func thenErrorResponse(t *testcase.T, code int, key string) httpapi.Error {
+	rr := hs.ServeHTTP(t)
+	require.Equal(t, code, rr.Code, rr.Body.String())
+	var resp httpapi.ErrorResponse
+	IsJsonResponse(t, rr, &resp.Body)
+	if key != `` {
+		require.Equal(t, key, resp.Body.Error.Key)
+	}
+	return resp.Body.Error
+}
@@ -0,0 +1,435 @@
+package httpapi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/security"
+	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/external/interface/httpintf/httputils"
+	"github.com/toggler-io/toggler/external/logging"
+)
+
+const (
+	ofrepErrorCodeParseError          = `PARSE_ERROR`
+	ofrepErrorCodeTargetingKeyMissing = `TARGETING_KEY_MISSING`
+	ofrepErrorCodeInvalidContext      = `INVALID_CONTEXT`
+	ofrepErrorCodeFlagNotFound        = `FLAG_NOT_FOUND`
+	ofrepErrorCodeGeneral             = `GENERAL`
+)
+
+// NewOFREPHandler implements the OpenFeature Remote Evaluation Protocol,
+// so OpenFeature providers can evaluate the release flags of a pilot directly.
+// The evaluation doesn't require a token, but a token which is scoped to a deployment environment selects it.
+func NewOFREPHandler(uc *toggler.UseCases) http.Handler {
+	ctrl := OFREPController{UseCases: uc}
+	m := http.NewServeMux()
+	m.HandleFunc(`/ofrep/v1/evaluate/flags`, ctrl.EvaluateAll)
+	m.HandleFunc(`/ofrep/v1/evaluate/flags/`, ctrl.Evaluate)
+	return m
+}
+
+type OFREPController struct {
+	UseCases *toggler.UseCases
+}
+
+// OFREPEvaluationContext is the OpenFeature evaluation context of the caller.
+type OFREPEvaluationContext struct {
+	// TargetingKey is the public ID of the pilot.
+	//
+	// required: true
+	// example: pilot-public-id
+	TargetingKey string `json:"targetingKey"`
+	// Environment is the ID or the name of the deployment environment.
+	// It is only used when neither the token is scoped to an environment, nor the X-Environment header is present.
+	//
+	// example: production
+	Environment string `json:"environment,omitempty"`
+}
+
+// OFREPEvaluationRequest
+// swagger:model
+type OFREPEvaluationRequest struct {
+	Context OFREPEvaluationContext `json:"context"`
+}
+
+// OFREPEvaluation is the result of a successful flag evaluation.
+// swagger:model
+type OFREPEvaluation struct {
+	// Key is the name of the release flag.
+	Key string `json:"key"`
+	// Value is the state of the release flag for the pilot.
+	Value bool `json:"value"`
+	// Reason explains why the flag evaluated to the value.
+	//
+	// enum: DEFAULT,STATIC,SPLIT,TARGETING_MATCH
+	Reason string `json:"reason"`
+	// Variant is either "on" or "off".
+	Variant string `json:"variant"`
+	// Metadata holds additional information about the flag.
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// OFREPEvaluationFailure is the result of an unsuccessful flag evaluation.
+// swagger:model
+type OFREPEvaluationFailure struct {
+	// Key is the name of the release flag.
+	Key string `json:"key,omitempty"`
+	// ErrorCode is the OpenFeature error code.
+	//
+	// enum: PARSE_ERROR,TARGETING_KEY_MISSING,INVALID_CONTEXT,FLAG_NOT_FOUND,GENERAL
+	ErrorCode string `json:"errorCode"`
+	// ErrorDetails is the human readable description of the failure.
+	ErrorDetails string `json:"errorDetails,omitempty"`
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+// OFREPEvaluateRequest
+// swagger:parameters ofrepEvaluate
+type OFREPEvaluateRequest struct {
+	// Key is the name of the release flag.
+	//
+	// in: path
+	// required: true
+	Key string `json:"key"`
+	// Environment is the ID or the name of the deployment environment.
+	//
+	// in: header
+	// name: X-Environment
+	Environment string `json:"X-Environment"`
+	// in: body
+	Body OFREPEvaluationRequest
+}
+
+// OFREPEvaluateResponse
+// swagger:response ofrepEvaluateResponse
+type OFREPEvaluateResponse struct {
+	// in: body
+	Body OFREPEvaluation
+}
+
+// OFREPEvaluateFailureResponse
+// swagger:response ofrepEvaluateFailureResponse
+type OFREPEvaluateFailureResponse struct {
+	// in: body
+	Body OFREPEvaluationFailure
+}
+
+/*
+
+	Evaluate
+	swagger:route POST /ofrep/v1/evaluate/flags/{key} ofrep ofrepEvaluate
+
+	Evaluate a single release flag for the pilot identified by the targeting key of the evaluation context.
+
+		Consumes:
+		- application/json
+
+		Produces:
+		- application/json
+
+		Schemes: http, https
+
+		Security:
+		  AppToken: []
+
+		Responses:
+		  200: ofrepEvaluateResponse
+		  400: ofrepEvaluateFailureResponse
+		  401: ofrepEvaluateFailureResponse
+		  403: ofrepEvaluateFailureResponse
+		  404: ofrepEvaluateFailureResponse
+		  500: ofrepEvaluateFailureResponse
+
+*/
+func (ctrl OFREPController) Evaluate(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, `/ofrep/v1/evaluate/flags/`)
+	if r.Method != http.MethodPost {
+		ErrorWriterFunc(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	evalCtx, env, failure, code := ctrl.parseRequest(r)
+	if failure != nil {
+		failure.Key = key
+		serveOFREPFailure(w, *failure, code)
+		return
+	}
+
+	evaluations, err := ctrl.UseCases.RolloutManager.EvaluateFlags(r.Context(), evalCtx.TargetingKey, env, key)
+	if err != nil {
+		logging.Error(r.Context(), `unable to evaluate the release flag`, err)
+		serveOFREPFailure(w, OFREPEvaluationFailure{Key: key, ErrorCode: ofrepErrorCodeGeneral}, http.StatusInternalServerError)
+		return
+	}
+
+	if len(evaluations) == 0 {
+		serveOFREPFailure(w, OFREPEvaluationFailure{
+			Key:          key,
+			ErrorCode:    ofrepErrorCodeFlagNotFound,
+			ErrorDetails: fmt.Sprintf(`release flag not found: %s`, key),
+		}, http.StatusNotFound)
+		return
+	}
+
+	serveJSON(w, r, newOFREPEvaluation(evaluations[0], env))
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+// OFREPEvaluateAllRequest
+// swagger:parameters ofrepEvaluateAll
+type OFREPEvaluateAllRequest struct {
+	// Environment is the ID or the name of the deployment environment.
+	//
+	// in: header
+	// name: X-Environment
+	Environment string `json:"X-Environment"`
+	// ETag of a previous bulk evaluation.
+	// When nothing changed since, the response is 304 Not Modified.
+	//
+	// in: header
+	// name: If-None-Match
+	IfNoneMatch string `json:"If-None-Match"`
+	// in: body
+	Body OFREPEvaluationRequest
+}
+
+// OFREPEvaluateAllResponse
+// swagger:response ofrepEvaluateAllResponse
+type OFREPEvaluateAllResponse struct {
+	// ETag identifies the evaluation result.
+	ETag string `json:"ETag"`
+	// in: body
+	Body struct {
+		Flags []OFREPEvaluation `json:"flags"`
+	}
+}
+
+/*
+
+	EvaluateAll
+	swagger:route POST /ofrep/v1/evaluate/flags ofrep ofrepEvaluateAll
+
+	Evaluate every release flag for the pilot identified by the targeting key of the evaluation context.
+	The response carries an ETag, that can be used with If-None-Match to poll for changes cheaply.
+
+		Consumes:
+		- application/json
+
+		Produces:
+		- application/json
+
+		Schemes: http, https
+
+		Security:
+		  AppToken: []
+
+		Responses:
+		  200: ofrepEvaluateAllResponse
+		  400: ofrepEvaluateFailureResponse
+		  401: ofrepEvaluateFailureResponse
+		  403: ofrepEvaluateFailureResponse
+		  500: ofrepEvaluateFailureResponse
+
+*/
+func (ctrl OFREPController) EvaluateAll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		ErrorWriterFunc(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	evalCtx, env, failure, code := ctrl.parseRequest(r)
+	if failure != nil {
+		serveOFREPFailure(w, *failure, code)
+		return
+	}
+
+	evaluations, err := ctrl.UseCases.RolloutManager.EvaluateFlags(r.Context(), evalCtx.TargetingKey, env)
+	if err != nil {
+		logging.Error(r.Context(), `unable to evaluate the release flags`, err)
+		serveOFREPFailure(w, OFREPEvaluationFailure{ErrorCode: ofrepErrorCodeGeneral}, http.StatusInternalServerError)
+		return
+	}
+
+	var resp OFREPEvaluateAllResponse
+	resp.Body.Flags = make([]OFREPEvaluation, 0, len(evaluations))
+	for _, e := range evaluations {
+		resp.Body.Flags = append(resp.Body.Flags, newOFREPEvaluation(e, env))
+	}
+
+	body, err := json.Marshal(resp.Body)
+	if handleError(w, r, err, http.StatusInternalServerError) {
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	resp.ETag = `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set(`ETag`, resp.ETag)
+
+	if ifNoneMatch := r.Header.Get(`If-None-Match`); ifNoneMatch != `` && ifNoneMatch == resp.ETag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set(`Content-Type`, `application/json`)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(body); err != nil {
+		logging.Error(r.Context(), `unable to write the response`, err)
+	}
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+func (ctrl OFREPController) parseRequest(r *http.Request) (OFREPEvaluationContext, release.Environment, *OFREPEvaluationFailure, int) {
+	defer r.Body.Close()
+
+	var (
+		req OFREPEvaluationRequest
+		env release.Environment
+	)
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return req.Context, env, &OFREPEvaluationFailure{
+			ErrorCode:    ofrepErrorCodeParseError,
+			ErrorDetails: err.Error(),
+		}, http.StatusBadRequest
+	}
+
+	if req.Context.TargetingKey == `` {
+		return req.Context, env, &OFREPEvaluationFailure{
+			ErrorCode:    ofrepErrorCodeTargetingKeyMissing,
+			ErrorDetails: `targetingKey is required in the evaluation context`,
+		}, http.StatusBadRequest
+	}
+
+	env, failure, code := ctrl.findEnvironment(r, req.Context)
+	return req.Context, env, failure, code
+}
+
+// findEnvironment selects the deployment environment of the evaluation.
+// A token which is scoped to a deployment environment selects it, so the clients using it don't have to name it.
+// Otherwise, the environment is named by the X-Environment header,
+// or when the header is absent, by the "environment" attribute of the evaluation context.
+func (ctrl OFREPController) findEnvironment(r *http.Request, evalCtx OFREPEvaluationContext) (release.Environment, *OFREPEvaluationFailure, int) {
+	var env release.Environment
+
+	alias := httputils.GetEnvironmentAlias(r)
+	if alias == `` {
+		alias = evalCtx.Environment
+	}
+
+	token, failure, code := ctrl.lookupToken(r)
+	if failure != nil {
+		return env, failure, code
+	}
+
+	if token != nil && token.EnvironmentID != `` {
+		found, err := ctrl.UseCases.Storage.ReleaseEnvironment(r.Context()).FindByID(r.Context(), &env, token.EnvironmentID)
+		if err != nil {
+			logging.Error(r.Context(), `unable to find the deployment environment`, err)
+			return env, &OFREPEvaluationFailure{ErrorCode: ofrepErrorCodeGeneral}, http.StatusInternalServerError
+		}
+		if !found {
+			return env, &OFREPEvaluationFailure{
+				ErrorCode:    ofrepErrorCodeInvalidContext,
+				ErrorDetails: `the deployment environment of the token no longer exists`,
+			}, http.StatusBadRequest
+		}
+		if alias != `` && alias != env.ID && alias != env.Name {
+			return env, &OFREPEvaluationFailure{
+				ErrorCode:    ofrepErrorCodeInvalidContext,
+				ErrorDetails: fmt.Sprintf(`the token is not scoped to the deployment environment: %s`, alias),
+			}, http.StatusForbidden
+		}
+		return env, nil, 0
+	}
+
+	if alias == `` {
+		return env, &OFREPEvaluationFailure{
+			ErrorCode:    ofrepErrorCodeInvalidContext,
+			ErrorDetails: `deployment environment is not provided`,
+		}, http.StatusBadRequest
+	}
+
+	found, err := ctrl.UseCases.Storage.ReleaseEnvironment(r.Context()).FindByAlias(r.Context(), alias, &env)
+	if err != nil {
+		logging.Error(r.Context(), `unable to find the deployment environment`, err)
+		return env, &OFREPEvaluationFailure{ErrorCode: ofrepErrorCodeGeneral}, http.StatusInternalServerError
+	}
+	if !found {
+		return env, &OFREPEvaluationFailure{
+			ErrorCode:    ofrepErrorCodeInvalidContext,
+			ErrorDetails: fmt.Sprintf(`deployment environment not found: %s`, alias),
+		}, http.StatusBadRequest
+	}
+
+	return env, nil, 0
+}
+
+// lookupToken returns the security token of the request, or nil when the request has none.
+// The evaluation doesn't require a token, but when one is given, it has to be valid.
+func (ctrl OFREPController) lookupToken(r *http.Request) (*security.Token, *OFREPEvaluationFailure, int) {
+	textToken, err := httputils.GetAppToken(r)
+	if err != nil {
+		logging.Error(r.Context(), `unable to read the security token`, err)
+		return nil, &OFREPEvaluationFailure{ErrorCode: ofrepErrorCodeGeneral}, http.StatusInternalServerError
+	}
+	if textToken == `` {
+		return nil, nil, 0
+	}
+
+	token, valid, err := ctrl.UseCases.Doorkeeper.LookupTextToken(r.Context(), textToken)
+	if err != nil {
+		logging.Error(r.Context(), `unable to look up the security token`, err)
+		return nil, &OFREPEvaluationFailure{ErrorCode: ofrepErrorCodeGeneral}, http.StatusInternalServerError
+	}
+	if !valid {
+		return nil, &OFREPEvaluationFailure{
+			ErrorCode:    ofrepErrorCodeGeneral,
+			ErrorDetails: `invalid token`,
+		}, http.StatusUnauthorized
+	}
+
+	return token, nil, 0
+}
+
+func newOFREPEvaluation(e release.Evaluation, env release.Environment) OFREPEvaluation {
+	variant := `off`
+	if e.State {
+		variant = `on`
+	}
+	return OFREPEvaluation{
+		Key:     e.FlagName,
+		Value:   e.State,
+		Reason:  string(e.Reason),
+		Variant: variant,
+		Metadata: map[string]interface{}{
+			`flag_id`: e.FlagID,
+			`env_id`:  env.ID,
+		},
+	}
+}
+
+func serveOFREPFailure(w http.ResponseWriter, failure OFREPEvaluationFailure, code int) {
+	buf := bytes.NewBuffer([]byte{})
+	if err := json.NewEncoder(buf).Encode(failure); err != nil {
+		slog.Error(`unable to encode the OFREP failure`, slog.String(`error`, err.Error()))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(`Content-Type`, `application/json`)
+	w.WriteHeader(code)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		slog.Error(`unable to write the OFREP failure`, slog.String(`error`, err.Error()))
+	}
+}
@@ -0,0 +1,265 @@
+package httpapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	hs "github.com/adamluzsi/testcase/httpspec"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/external/interface/httpintf/httpapi"
+	sh "github.com/toggler-io/toggler/spechelper"
+)
+
+func TestOFREPController(t *testing.T) {
+	s := sh.NewSpec(t)
+	s.Parallel()
+
+	hs.Context.Let(s, func(t *testcase.T) interface{} { return sh.ContextGet(t) })
+	hs.HandlerLet(s, func(t *testcase.T) http.Handler { return httpapi.NewOFREPHandler(sh.ExampleUseCases(t)) })
+	hs.ContentTypeIsJSON(s)
+	hs.Method.LetValue(s, http.MethodPost)
+
+	hs.Body.Let(s, func(t *testcase.T) interface{} {
+		var req httpapi.OFREPEvaluationRequest
+		req.Context.TargetingKey = sh.ExampleExternalPilotID(t)
+		return req
+	})
+	s.Before(func(t *testcase.T) {
+		hs.HeaderGet(t).Set(`X-Environment`, sh.ExampleDeploymentEnvironment(t).Name)
+	})
+	sh.AndExamplePilotManualParticipatingIsSetTo(s, true)
+
+	s.Describe(`POST /ofrep/v1/evaluate/flags/{key} - evaluate`, SpecOFREPControllerEvaluate)
+	s.Describe(`POST /ofrep/v1/evaluate/flags - evaluate all`, SpecOFREPControllerEvaluateAll)
+}
+
+func SpecOFREPControllerEvaluate(s *testcase.Spec) {
+	key := s.Let(`key`, func(t *testcase.T) interface{} {
+		return sh.ExampleReleaseFlag(t).Name
+	})
+	hs.Path.Let(s, func(t *testcase.T) interface{} {
+		return `/ofrep/v1/evaluate/flags/` + url.PathEscape(key.Get(t).(string))
+	})
+
+	onSuccess := func(t *testcase.T) httpapi.OFREPEvaluation {
+		rr := hs.ServeHTTP(t)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var evaluation httpapi.OFREPEvaluation
+		require.Nil(t, json.Unmarshal(rr.Body.Bytes(), &evaluation))
+		return evaluation
+	}
+
+	s.Then(`the flag is evaluated for the pilot in the environment`, func(t *testcase.T) {
+		evaluation := onSuccess(t)
+		require.Equal(t, sh.ExampleReleaseFlag(t).Name, evaluation.Key)
+		require.True(t, evaluation.Value)
+		require.Equal(t, `on`, evaluation.Variant)
+		require.Equal(t, string(release.EvaluationReasonTargetingMatch), evaluation.Reason)
+		require.Equal(t, sh.ExampleReleaseFlag(t).ID, evaluation.Metadata[`flag_id`])
+		require.Equal(t, sh.ExampleDeploymentEnvironment(t).ID, evaluation.Metadata[`env_id`])
+	})
+
+	s.When(`the flag is unknown`, func(s *testcase.Spec) {
+		key.Let(s, func(t *testcase.T) interface{} { return `unknown-` + t.Random.StringNWithCharset(8, `abcdef`) })
+
+		s.Then(`it responds with FLAG_NOT_FOUND`, func(t *testcase.T) {
+			failure := thenOFREPFailure(t, http.StatusNotFound, `FLAG_NOT_FOUND`)
+			require.Equal(t, key.Get(t).(string), failure.Key)
+		})
+	})
+
+	s.When(`the environment is given in the evaluation context instead of the header`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { hs.HeaderGet(t).Del(`X-Environment`) })
+		hs.Body.Let(s, func(t *testcase.T) interface{} {
+			var req httpapi.OFREPEvaluationRequest
+			req.Context.TargetingKey = sh.ExampleExternalPilotID(t)
+			req.Context.Environment = sh.ExampleDeploymentEnvironment(t).ID
+			return req
+		})
+
+		s.Then(`the flag is evaluated in that environment`, func(t *testcase.T) {
+			require.Equal(t, sh.ExampleDeploymentEnvironment(t).ID, onSuccess(t).Metadata[`env_id`])
+		})
+	})
+
+	specOFREPRequestFailures(s)
+	specOFREPEnvironmentByToken(s, func(t *testcase.T) string { return onSuccess(t).Metadata[`env_id`].(string) })
+}
+
+func SpecOFREPControllerEvaluateAll(s *testcase.Spec) {
+	hs.Path.LetValue(s, `/ofrep/v1/evaluate/flags`)
+
+	onSuccess := func(t *testcase.T) (etag string, flags []httpapi.OFREPEvaluation) {
+		rr := hs.ServeHTTP(t)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var resp httpapi.OFREPEvaluateAllResponse
+		require.Nil(t, json.Unmarshal(rr.Body.Bytes(), &resp.Body))
+		return rr.Header().Get(`ETag`), resp.Body.Flags
+	}
+	findFlag := func(t *testcase.T, flags []httpapi.OFREPEvaluation) httpapi.OFREPEvaluation {
+		for _, f := range flags {
+			if f.Key == sh.ExampleReleaseFlag(t).Name {
+				return f
+			}
+		}
+		t.Fatalf(`the evaluation of %s is missing`, sh.ExampleReleaseFlag(t).Name)
+		return httpapi.OFREPEvaluation{}
+	}
+
+	s.Then(`every flag is evaluated for the pilot in the environment`, func(t *testcase.T) {
+		_, flags := onSuccess(t)
+		evaluation := findFlag(t, flags)
+		require.True(t, evaluation.Value)
+		require.Equal(t, `on`, evaluation.Variant)
+		require.Equal(t, string(release.EvaluationReasonTargetingMatch), evaluation.Reason)
+		require.Equal(t, sh.ExampleDeploymentEnvironment(t).ID, evaluation.Metadata[`env_id`])
+	})
+
+	s.Then(`the response carries an ETag`, func(t *testcase.T) {
+		etag, _ := onSuccess(t)
+		require.NotEmpty(t, etag)
+	})
+
+	s.When(`the If-None-Match header has the ETag of the current evaluations`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) {
+			etag, _ := onSuccess(t)
+			hs.HeaderGet(t).Set(`If-None-Match`, etag)
+		})
+
+		s.Then(`it responds with 304 Not Modified, without a body`, func(t *testcase.T) {
+			rr := hs.ServeHTTP(t)
+			require.Equal(t, http.StatusNotModified, rr.Code)
+			require.Empty(t, rr.Body.String())
+		})
+
+		s.And(`the evaluations changed since`, func(s *testcase.Spec) {
+			s.Before(func(t *testcase.T) {
+				require.Nil(t, sh.ExampleRolloutManager(t).SetPilotEnrollmentForFeature(sh.ContextGet(t),
+					sh.ExampleReleaseFlag(t).ID, sh.ExampleDeploymentEnvironment(t).ID, sh.ExampleExternalPilotID(t), false))
+			})
+
+			s.Then(`the new evaluations are returned with a new ETag`, func(t *testcase.T) {
+				etag, flags := onSuccess(t)
+				require.NotEqual(t, hs.HeaderGet(t).Get(`If-None-Match`), etag)
+				require.False(t, findFlag(t, flags).Value)
+			})
+		})
+	})
+
+	s.When(`the If-None-Match header has a stale ETag`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { hs.HeaderGet(t).Set(`If-None-Match`, `"stale"`) })
+
+		s.Then(`the evaluations are returned`, func(t *testcase.T) {
+			_, flags := onSuccess(t)
+			require.True(t, findFlag(t, flags).Value)
+		})
+	})
+
+	specOFREPRequestFailures(s)
+	specOFREPEnvironmentByToken(s, func(t *testcase.T) string {
+		_, flags := onSuccess(t)
+		return findFlag(t, flags).Metadata[`env_id`].(string)
+	})
+}
+
+func specOFREPRequestFailures(s *testcase.Spec) {
+	s.When(`the body is not a valid JSON`, func(s *testcase.Spec) {
+		hs.Body.Let(s, func(t *testcase.T) interface{} { return strings.NewReader(`{"context":`) })
+
+		s.Then(`it responds with PARSE_ERROR`, func(t *testcase.T) {
+			thenOFREPFailure(t, http.StatusBadRequest, `PARSE_ERROR`)
+		})
+	})
+
+	s.When(`the targeting key is missing from the evaluation context`, func(s *testcase.Spec) {
+		hs.Body.Let(s, func(t *testcase.T) interface{} { return httpapi.OFREPEvaluationRequest{} })
+
+		s.Then(`it responds with TARGETING_KEY_MISSING`, func(t *testcase.T) {
+			thenOFREPFailure(t, http.StatusBadRequest, `TARGETING_KEY_MISSING`)
+		})
+	})
+
+	s.When(`the environment is not provided`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { hs.HeaderGet(t).Del(`X-Environment`) })
+
+		s.Then(`it responds with INVALID_CONTEXT`, func(t *testcase.T) {
+			thenOFREPFailure(t, http.StatusBadRequest, `INVALID_CONTEXT`)
+		})
+	})
+
+	s.When(`the environment is unknown`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { hs.HeaderGet(t).Set(`X-Environment`, `unknown`) })
+
+		s.Then(`it responds with INVALID_CONTEXT`, func(t *testcase.T) {
+			thenOFREPFailure(t, http.StatusBadRequest, `INVALID_CONTEXT`)
+		})
+	})
+}
+
+func specOFREPEnvironmentByToken(s *testcase.Spec, evaluatedEnvID func(t *testcase.T) string) {
+	s.When(`the request has a token which is scoped to a deployment environment`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) {
+			textToken, token, err := sh.ExampleUseCases(t).Issuer.CreateNewEnvironmentToken(sh.ContextGet(t),
+				sh.ExampleUniqueUserID(t), sh.ExampleDeploymentEnvironment(t).ID, nil, nil)
+			require.Nil(t, err)
+			t.Defer(sh.StorageGet(t).SecurityToken(sh.ContextGet(t)).DeleteByID, sh.ContextGet(t), token.ID)
+			hs.HeaderGet(t).Set(`Authorization`, `Bearer `+textToken)
+		})
+
+		s.And(`the environment is not named by the request`, func(s *testcase.Spec) {
+			s.Before(func(t *testcase.T) { hs.HeaderGet(t).Del(`X-Environment`) })
+
+			s.Then(`the flags are evaluated in the environment of the token`, func(t *testcase.T) {
+				require.Equal(t, sh.ExampleDeploymentEnvironment(t).ID, evaluatedEnvID(t))
+			})
+		})
+
+		s.And(`the request names the same environment`, func(s *testcase.Spec) {
+			s.Then(`the flags are evaluated in the environment of the token`, func(t *testcase.T) {
+				require.Equal(t, sh.ExampleDeploymentEnvironment(t).ID, evaluatedEnvID(t))
+			})
+		})
+
+		s.And(`the request names an other environment`, func(s *testcase.Spec) {
+			otherEnv := sh.GivenWeHaveDeploymentEnvironment(s, `other env`)
+			s.Before(func(t *testcase.T) {
+				hs.HeaderGet(t).Set(`X-Environment`, otherEnv.Get(t).(*release.Environment).Name)
+			})
+
+			s.Then(`it is forbidden`, func(t *testcase.T) {
+				thenOFREPFailure(t, http.StatusForbidden, `INVALID_CONTEXT`)
+			})
+		})
+	})
+
+	s.When(`the request has a token which is not scoped`, func(s *testcase.Spec) {
+		sh.GivenHTTPRequestHasAppToken(s)
+
+		s.Then(`the flags are evaluated in the environment named by the request`, func(t *testcase.T) {
+			require.Equal(t, sh.ExampleDeploymentEnvironment(t).ID, evaluatedEnvID(t))
+		})
+	})
+
+	s.When(`the request has an invalid token`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { hs.HeaderGet(t).Set(`Authorization`, `Bearer invalid`) })
+
+		s.Then(`it is unauthorized`, func(t *testcase.T) {
+			thenOFREPFailure(t, http.StatusUnauthorized, `GENERAL`)
+		})
+	})
+}
+
+func thenOFREPFailure(t *testcase.T, code int, errorCode string) httpapi.OFREPEvaluationFailure {
+	rr := hs.ServeHTTP(t)
+	require.Equal(t, code, rr.Code, rr.Body.String())
+	var failure httpapi.OFREPEvaluationFailure
+	require.Nil(t, json.Unmarshal(rr.Body.Bytes(), &failure))
+	require.Equal(t, errorCode, failure.ErrorCode)
+	return failure
+}
@@ -15,7 +15,7 @@ import (
 func NewReleaseFlagHandler(uc *toggler.UseCases) http.Handler {
 	c := ReleaseFlagController{UseCases: uc}
 	h := gorest.NewHandler(c)
-	httputils.AuthMiddleware(h, uc, ErrorWriterFunc)
+	httputils.AuthMiddleware(h, uc, WriteError)
 	return h
 }
 
@@ -23,21 +23,13 @@ type ReleaseFlagController struct {
 	UseCases *toggler.UseCases
 }
 
-//--------------------------------------------------------------------------------------------------------------------//
+// NotFound responds when the requested release flag doesn't exist.
+func (ctrl ReleaseFlagController) NotFound(w http.ResponseWriter, r *http.Request) {
+	handleError(w, r, release.ErrFlagNotFound, http.StatusNotFound)
+}
 
-func (ctrl ReleaseFlagController) handleFlagValidationError(w http.ResponseWriter, err error) bool {
-	switch err {
-	case release.ErrNameIsEmpty,
-		release.ErrMissingFlag,
-		release.ErrInvalidAction,
-		release.ErrFlagAlreadyExist,
-		release.ErrInvalidRequestURL,
-		release.ErrInvalidPercentage:
-		return handleError(w, err, http.StatusBadRequest)
-
-	default:
-		return handleError(w, err, http.StatusInternalServerError)
-	}
+func (ctrl ReleaseFlagController) InternalServerError(w http.ResponseWriter, r *http.Request) {
+	ErrorWriterFunc(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 }
 
 //--------------------------------------------------------------------------------------------------------------------//
@@ -93,20 +85,21 @@ func (ctrl ReleaseFlagController) Create(w http.ResponseWriter, r *http.Request)
 
 	var req CreateReleaseFlagRequest
 
-	if handleError(w, decoder.Decode(&req.Body), http.StatusBadRequest) {
+	if handleError(w, r, decoder.Decode(&req.Body), http.StatusBadRequest) {
 		return
 	}
 
 	req.Body.Flag.ID = `` // ignore id if given
 	flag := req.Body.Flag
 
-	if ctrl.handleFlagValidationError(w, ctrl.UseCases.CreateFeatureFlag(r.Context(), &flag)) {
+	if handleError(w, r, ctrl.UseCases.CreateFeatureFlag(r.Context(), &flag), http.StatusInternalServerError) {
 		return
 	}
 
 	var resp CreateReleaseFlagResponse
 	resp.Body.Flag = flag
-	serveJSON(w, resp.Body)
+	setETag(w, flag.Revision)
+	serveJSON(w, r, resp.Body)
 }
 
 //--------------------------------------------------------------------------------------------------------------------//
@@ -114,6 +107,11 @@ func (ctrl ReleaseFlagController) Create(w http.ResponseWriter, r *http.Request)
 // ListReleaseFlagRequest
 // swagger:parameters listReleaseFlags
 type ListReleaseFlagRequest struct {
+	PaginationRequest
+	// NamePrefix filters the flags by the beginning of their name.
+	//
+	// in: query
+	NamePrefix string `json:"name_prefix"`
 }
 
 // ListReleaseFlagResponse
@@ -122,6 +120,9 @@ type ListReleaseFlagResponse struct {
 	// in: body
 	Body struct {
 		Flags []release.Flag `json:"flags"`
+		// NextCursor is the cursor of the next page.
+		// It is omitted on the last page.
+		NextCursor string `json:"next_cursor,omitempty"`
 	}
 }
 
@@ -130,7 +131,8 @@ type ListReleaseFlagResponse struct {
 	List
 	swagger:route GET /release-flags flag listReleaseFlags
 
-	List all the release flag that can be used to manage a feature rollout.
+	List the release flags that can be used to manage a feature rollout.
+	The result is paginated with a cursor, and can be sorted by id or name.
 
 		Consumes:
 		- application/json
@@ -145,20 +147,29 @@ type ListReleaseFlagResponse struct {
 
 		Responses:
 		  200: listReleaseFlagResponse
+		  400: errorResponse
 		  401: errorResponse
 		  500: errorResponse
 
 */
 func (ctrl ReleaseFlagController) List(w http.ResponseWriter, r *http.Request) {
-	rfs, err := ctrl.UseCases.ListFeatureFlags(r.Context())
+	pagination, err := parsePagination(r.URL.Query())
+	if handleError(w, r, err, http.StatusInternalServerError) {
+		return
+	}
 
-	if handleError(w, err, http.StatusInternalServerError) {
+	page, err := ctrl.UseCases.ListFlags(r.Context(), release.FlagQuery{
+		NamePrefix: r.URL.Query().Get(`name_prefix`),
+		Pagination: pagination,
+	})
+	if handleError(w, r, err, http.StatusInternalServerError) {
 		return
 	}
 
 	var resp ListReleaseFlagResponse
-	resp.Body.Flags = rfs
-	serveJSON(w, resp.Body)
+	resp.Body.Flags = page.Flags
+	resp.Body.NextCursor = page.NextCursor
+	serveJSON(w, r, resp.Body)
 }
 
 //--------------------------------------------------------------------------------------------------------------------//
@@ -188,6 +199,58 @@ func (ctrl ReleaseFlagController) ContextWithResource(ctx context.Context, resou
 
 //--------------------------------------------------------------------------------------------------------------------//
 
+// ShowReleaseFlagRequest
+// swagger:parameters showReleaseFlag
+type ShowReleaseFlagRequest struct {
+	// FlagID is the release flag id.
+	//
+	// in: path
+	// required: true
+	FlagID string `json:"flagID"`
+}
+
+// ShowReleaseFlagResponse
+// swagger:response showReleaseFlagResponse
+type ShowReleaseFlagResponse struct {
+	// ETag is the revision of the release flag, which is expected in the If-Match header of its update.
+	ETag string
+	// in: body
+	Body struct {
+		Flag release.Flag `json:"flag"`
+	}
+}
+
+/*
+
+	Show
+	swagger:route GET /release-flags/{flagID} flag showReleaseFlag
+
+	Show a release flag.
+
+		Produces:
+		- application/json
+
+		Schemes: http, https
+
+		Security:
+		  AppToken: []
+
+		Responses:
+		  200: showReleaseFlagResponse
+		  401: errorResponse
+		  404: errorResponse
+		  500: errorResponse
+
+*/
+func (ctrl ReleaseFlagController) Show(w http.ResponseWriter, r *http.Request) {
+	var resp ShowReleaseFlagResponse
+	resp.Body.Flag = r.Context().Value(ReleaseFlagContextKey{}).(release.Flag)
+	setETag(w, resp.Body.Flag.Revision)
+	serveJSON(w, r, resp.Body)
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
 // UpdateReleaseFlagRequest
 // swagger:parameters updateReleaseFlag
 type UpdateReleaseFlagRequest struct {
@@ -196,6 +259,11 @@ type UpdateReleaseFlagRequest struct {
 	// in: path
 	// required: true
 	FlagID string `json:"flagID"`
+	// IfMatch is the ETag of the release flag which the update is based on, or "*" to update it unconditionally.
+	//
+	// in: header
+	// required: true
+	IfMatch string `json:"If-Match"`
 	// in: body
 	Body struct {
 		Flag release.Flag `json:"flag"`
@@ -232,6 +300,8 @@ type UpdateReleaseFlagResponse struct {
 		Responses:
 		  200: updateReleaseFlagResponse
 		  400: errorResponse
+		  412: errorResponse
+		  428: errorResponse
 		  500: errorResponse
 
 */
@@ -240,22 +310,29 @@ func (ctrl ReleaseFlagController) Update(w http.ResponseWriter, r *http.Request)
 	decoder.DisallowUnknownFields()
 	defer r.Body.Close() // ignorable
 
+	revision, ok := ifMatchRevision(w, r)
+	if !ok {
+		return
+	}
+
 	var req UpdateReleaseFlagRequest
 
-	if handleError(w, decoder.Decode(&req.Body), http.StatusBadRequest) {
+	if handleError(w, r, decoder.Decode(&req.Body), http.StatusBadRequest) {
 		return
 	}
 
 	flag := req.Body.Flag
 	flag.ID = r.Context().Value(ReleaseFlagContextKey{}).(release.Flag).ID
+	flag.Revision = revision
 
-	if ctrl.handleFlagValidationError(w, ctrl.UseCases.UpdateFeatureFlag(r.Context(), &flag)) {
+	if handleError(w, r, ctrl.UseCases.UpdateFeatureFlag(r.Context(), &flag), http.StatusInternalServerError) {
 		return
 	}
 
 	var resp UpdateReleaseFlagResponse
 	resp.Body.Flag = flag
-	serveJSON(w, resp.Body)
+	setETag(w, flag.Revision)
+	serveJSON(w, r, resp.Body)
 }
 
 //--------------------------------------------------------------------------------------------------------------------//
@@ -280,7 +357,7 @@ type DeleteReleaseFlagResponse struct {
 	Delete
 	swagger:route DELETE /release-flags/{flagID} flag deleteReleaseFlag
 
-	Delete a release flag.
+	Delete a release flag along with its rollouts and pilots.
 
 		Consumes:
 		- application/json
@@ -296,14 +373,15 @@ type DeleteReleaseFlagResponse struct {
 		Responses:
 		  200: deleteReleaseFlagResponse
 		  400: errorResponse
+		  409: errorResponse
 		  500: errorResponse
 
 */
 func (ctrl ReleaseFlagController) Delete(w http.ResponseWriter, r *http.Request) {
 	ID := r.Context().Value(ReleaseFlagContextKey{}).(release.Flag).ID
 
-	err := ctrl.UseCases.Storage.ReleaseFlag(r.Context()).DeleteByID(r.Context(), ID)
-	if handleError(w, err, http.StatusBadRequest) {
+	err := ctrl.UseCases.RolloutManager.DeleteFeatureFlag(r.Context(), ID)
+	if handleError(w, r, err, http.StatusBadRequest) {
 		return
 	}
 
@@ -130,6 +130,9 @@ func SpecReleaseFlagControllerCreate(s *testcase.Spec) {
 			var resp httpapi.ErrorResponse
 			require.Nil(t, json.Unmarshal(rr.Body.Bytes(), &resp.Body), rr.Body.String())
 			require.NotEmpty(t, resp.Body.Error.Message)
+			require.Equal(t, release.ErrNameIsEmpty.Code, resp.Body.Error.Key)
+			require.Equal(t, `validation`, resp.Body.Error.Kind)
+			require.Equal(t, `name`, resp.Body.Error.Field)
 		})
 	})
 
@@ -215,6 +218,10 @@ func SpecReleaseFlagControllerList(s *testcase.Spec) {
 func SpecReleaseFlagControllerUpdate(s *testcase.Spec) {
 	sh.GivenHTTPRequestHasAppToken(s)
 	Method.LetValue(s, http.MethodPut)
+
+	s.Before(func(t *testcase.T) {
+		HeaderGet(t).Set(`If-Match`, `"1"`) // the revision of the freshly created entity
+	})
 	Path.Let(s, func(t *testcase.T) interface{} {
 		return fmt.Sprintf(`/%s`, t.I(`id`))
 	})
@@ -278,6 +285,7 @@ func SpecReleaseFlagControllerUpdate(s *testcase.Spec) {
 			// TODO: ensure validation
 			p := swagger.NewUpdateReleaseFlagParams()
 			p.FlagID = id
+			p.IfMatch = fmt.Sprintf(`"%d"`, sh.GetReleaseFlag(t, `release-flag`).Revision)
 			p.Body.Flag = &models.Flag{
 				ID:   id,
 				Name: fixtures.Random.String(),
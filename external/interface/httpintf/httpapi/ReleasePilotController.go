@@ -4,11 +4,11 @@ package httpapi
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"net/http"
 
 	"github.com/adamluzsi/gorest"
 
+	"github.com/toggler-io/toggler/domains/change"
 	"github.com/toggler-io/toggler/domains/release"
 	"github.com/toggler-io/toggler/domains/toggler"
 	"github.com/toggler-io/toggler/external/interface/httpintf/httputils"
@@ -17,7 +17,7 @@ import (
 func NewReleasePilotHandler(uc *toggler.UseCases) http.Handler {
 	c := ReleasePilotController{UseCases: uc}
 	h := gorest.NewHandler(c)
-	httputils.AuthMiddleware(h, uc, ErrorWriterFunc)
+	httputils.AuthMiddleware(h, uc, WriteError)
 	return h
 }
 
@@ -25,6 +25,15 @@ type ReleasePilotController struct {
 	UseCases *toggler.UseCases
 }
 
+// NotFound responds when the requested release pilot doesn't exist.
+func (ctrl ReleasePilotController) NotFound(w http.ResponseWriter, r *http.Request) {
+	handleError(w, r, release.ErrPilotNotFound, http.StatusNotFound)
+}
+
+func (ctrl ReleasePilotController) InternalServerError(w http.ResponseWriter, r *http.Request) {
+	ErrorWriterFunc(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
 //--------------------------------------------------------------------------------------------------------------------//
 
 // CreateReleasePilotRequest
@@ -66,6 +75,7 @@ type CreateReleasePilotResponse struct {
 
 		Responses:
 		  200: createReleasePilotResponse
+		  202: proposedChangeResponse
 		  400: errorResponse
 		  401: errorResponse
 		  500: errorResponse
@@ -79,36 +89,66 @@ func (ctrl ReleasePilotController) Create(w http.ResponseWriter, r *http.Request
 
 	var req CreateReleasePilotRequest
 
-	if handleError(w, decoder.Decode(&req.Body), http.StatusBadRequest) {
+	if handleError(w, r, decoder.Decode(&req.Body), http.StatusBadRequest) {
 		return
 	}
 
 	req.Body.Pilot.ID = `` // ignore id if given
 	pilot := req.Body.Pilot
 
-	if ctrl.validatePilot(w, pilot) {
+	if ctrl.validatePilot(w, r, pilot) {
 		return
 	}
 
+	ctx = change.ContextWithProposals(ctx)
 	rps := ctrl.UseCases.Storage.ReleasePilot(ctx)
 
-	if handleError(w, rps.Create(ctx, &pilot), http.StatusBadRequest) {
+	if handleError(w, r, rps.Create(ctx, &pilot), http.StatusBadRequest) {
+		return
+	}
+	if serveProposed(w, r, ctx) {
 		return
 	}
 
 	var resp CreateReleasePilotResponse
 	resp.Body.Pilot = pilot
-	serveJSON(w, resp.Body)
+	setETag(w, pilot.Revision)
+	serveJSON(w, r, resp.Body)
 }
 
 //--------------------------------------------------------------------------------------------------------------------//
 
+// ListReleasePilotRequest
+// swagger:parameters listReleasePilots
+type ListReleasePilotRequest struct {
+	PaginationRequest
+	// FlagID filters the pilots by release flag.
+	//
+	// in: query
+	FlagID string `json:"flag_id"`
+	// EnvironmentID filters the pilots by deployment environment.
+	//
+	// in: query
+	EnvironmentID string `json:"env_id"`
+	// PublicID filters the pilots by their public id.
+	//
+	// in: query
+	PublicID string `json:"public_id"`
+	// IsParticipating filters the pilots by their participation.
+	//
+	// in: query
+	IsParticipating *bool `json:"is_participating"`
+}
+
 // ListReleasePilotResponse
 // swagger:response listReleasePilotResponse
 type ListReleasePilotResponse struct {
 	// in: body
 	Body struct {
 		Pilots []release.Pilot `json:"pilots"`
+		// NextCursor is the cursor of the next page.
+		// It is omitted on the last page.
+		NextCursor string `json:"next_cursor,omitempty"`
 	}
 }
 
@@ -117,7 +157,8 @@ type ListReleasePilotResponse struct {
 	List
 	swagger:route GET /release-pilots pilot listReleasePilots
 
-	List all the release flag that can be used to manage a feature rollout.
+	List the release pilots.
+	The result is paginated with a cursor, and can be sorted by id or public_id.
 
 		Consumes:
 		- application/json
@@ -132,30 +173,37 @@ type ListReleasePilotResponse struct {
 
 		Responses:
 		  200: listReleasePilotResponse
+		  400: errorResponse
 		  401: errorResponse
 		  500: errorResponse
 
 */
 func (ctrl ReleasePilotController) List(w http.ResponseWriter, r *http.Request) {
-	pilotsIter := ctrl.UseCases.RolloutManager.Storage.ReleasePilot(r.Context()).FindAll(r.Context())
-	defer pilotsIter.Close()
-
-	var resp ListReleasePilotResponse
-	for pilotsIter.Next() {
-		var p release.Pilot
-
-		if handleError(w, pilotsIter.Decode(&p), http.StatusInternalServerError) {
-			return
-		}
+	pagination, err := parsePagination(r.URL.Query())
+	if handleError(w, r, err, http.StatusInternalServerError) {
+		return
+	}
 
-		resp.Body.Pilots = append(resp.Body.Pilots, p)
+	isParticipating, err := parseOptionalBool(r.URL.Query().Get(`is_participating`))
+	if handleError(w, r, err, http.StatusBadRequest) {
+		return
 	}
 
-	if handleError(w, pilotsIter.Err(), http.StatusInternalServerError) {
+	page, err := ctrl.UseCases.ListPilots(r.Context(), release.PilotQuery{
+		FlagID:          r.URL.Query().Get(`flag_id`),
+		EnvironmentID:   r.URL.Query().Get(`env_id`),
+		PublicID:        r.URL.Query().Get(`public_id`),
+		IsParticipating: isParticipating,
+		Pagination:      pagination,
+	})
+	if handleError(w, r, err, http.StatusInternalServerError) {
 		return
 	}
 
-	serveJSON(w, resp.Body)
+	var resp ListReleasePilotResponse
+	resp.Body.Pilots = page.Pilots
+	resp.Body.NextCursor = page.NextCursor
+	serveJSON(w, r, resp.Body)
 }
 
 //--------------------------------------------------------------------------------------------------------------------//
@@ -176,6 +224,58 @@ func (ctrl ReleasePilotController) ContextWithResource(ctx context.Context, pilo
 
 //--------------------------------------------------------------------------------------------------------------------//
 
+// ShowReleasePilotRequest
+// swagger:parameters showReleasePilot
+type ShowReleasePilotRequest struct {
+	// PilotID is the pilot id.
+	//
+	// in: path
+	// required: true
+	PilotID string `json:"pilotID"`
+}
+
+// ShowReleasePilotResponse
+// swagger:response showReleasePilotResponse
+type ShowReleasePilotResponse struct {
+	// ETag is the revision of the pilot, which is expected in the If-Match header of its update.
+	ETag string
+	// in: body
+	Body struct {
+		Pilot release.Pilot `json:"pilot"`
+	}
+}
+
+/*
+
+	Show
+	swagger:route GET /release-pilots/{pilotID} pilot showReleasePilot
+
+	Show a release pilot.
+
+		Produces:
+		- application/json
+
+		Schemes: http, https
+
+		Security:
+		  AppToken: []
+
+		Responses:
+		  200: showReleasePilotResponse
+		  401: errorResponse
+		  404: errorResponse
+		  500: errorResponse
+
+*/
+func (ctrl ReleasePilotController) Show(w http.ResponseWriter, r *http.Request) {
+	var resp ShowReleasePilotResponse
+	resp.Body.Pilot = r.Context().Value(ReleasePilotContextKey{}).(release.Pilot)
+	setETag(w, resp.Body.Pilot.Revision)
+	serveJSON(w, r, resp.Body)
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
 // UpdateReleasePilotRequest
 // swagger:parameters updateReleasePilot
 type UpdateReleasePilotRequest struct {
@@ -184,6 +284,11 @@ type UpdateReleasePilotRequest struct {
 	// in: path
 	// required: true
 	PilotID string `json:"pilotID"`
+	// IfMatch is the ETag of the pilot which the update is based on, or "*" to update it unconditionally.
+	//
+	// in: header
+	// required: true
+	IfMatch string `json:"If-Match"`
 	// in: body
 	Body struct {
 		Pilot release.Pilot `json:"pilot"`
@@ -219,7 +324,10 @@ type UpdateReleasePilotResponse struct {
 
 		Responses:
 		  200: updateReleasePilotResponse
+		  202: proposedChangeResponse
 		  400: errorResponse
+		  412: errorResponse
+		  428: errorResponse
 		  500: errorResponse
 
 */
@@ -229,37 +337,48 @@ func (ctrl ReleasePilotController) Update(w http.ResponseWriter, r *http.Request
 	decoder.DisallowUnknownFields()
 	defer r.Body.Close() // ignorable
 
+	revision, ok := ifMatchRevision(w, r)
+	if !ok {
+		return
+	}
+
 	var req CreateReleasePilotRequest
 
-	if handleError(w, decoder.Decode(&req.Body), http.StatusBadRequest) {
+	if handleError(w, r, decoder.Decode(&req.Body), http.StatusBadRequest) {
 		return
 	}
 
 	req.Body.Pilot.ID = ctx.Value(ReleasePilotContextKey{}).(release.Pilot).ID
+	req.Body.Pilot.Revision = revision
 	pilot := req.Body.Pilot
 
-	if ctrl.validatePilot(w, pilot) {
+	if ctrl.validatePilot(w, r, pilot) {
 		return
 	}
 
+	ctx = change.ContextWithProposals(ctx)
 	rps := ctrl.UseCases.Storage.ReleasePilot(ctx)
 
-	if handleError(w, rps.Update(ctx, &pilot), http.StatusBadRequest) {
+	if handleError(w, r, rps.Update(ctx, &pilot), http.StatusBadRequest) {
+		return
+	}
+	if serveProposed(w, r, ctx) {
 		return
 	}
 
 	var resp CreateReleasePilotResponse
 	resp.Body.Pilot = pilot
-	serveJSON(w, resp.Body)
+	setETag(w, pilot.Revision)
+	serveJSON(w, r, resp.Body)
 }
 
-func (ctrl ReleasePilotController) validatePilot(w http.ResponseWriter, pilot release.Pilot) bool {
+func (ctrl ReleasePilotController) validatePilot(w http.ResponseWriter, r *http.Request, pilot release.Pilot) bool {
 	if pilot.FlagID == "" {
-		handleError(w, fmt.Errorf("missing flag_id"), http.StatusBadRequest)
+		handleError(w, r, release.ErrMissingFlag, http.StatusBadRequest)
 		return true
 	}
 	if pilot.EnvironmentID == "" {
-		handleError(w, fmt.Errorf("missing env_id"), http.StatusBadRequest)
+		handleError(w, r, release.ErrMissingEnv, http.StatusBadRequest)
 		return true
 	}
 	return false
@@ -302,6 +421,7 @@ type DeleteReleasePilotResponse struct {
 
 		Responses:
 		  200: deleteReleasePilotResponse
+		  202: proposedChangeResponse
 		  400: errorResponse
 		  500: errorResponse
 
@@ -309,8 +429,12 @@ type DeleteReleasePilotResponse struct {
 func (ctrl ReleasePilotController) Delete(w http.ResponseWriter, r *http.Request) {
 	ID := r.Context().Value(ReleasePilotContextKey{}).(release.Pilot).ID
 
-	err := ctrl.UseCases.Storage.ReleasePilot(r.Context()).DeleteByID(r.Context(), ID)
-	if handleError(w, err, http.StatusBadRequest) {
+	ctx := change.ContextWithProposals(r.Context())
+	err := ctrl.UseCases.Storage.ReleasePilot(ctx).DeleteByID(ctx, ID)
+	if handleError(w, r, err, http.StatusBadRequest) {
+		return
+	}
+	if serveProposed(w, r, ctx) {
 		return
 	}
 
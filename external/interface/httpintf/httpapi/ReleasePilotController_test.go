@@ -80,14 +80,18 @@ func SpecReleasePilotControllerCreate(s *testcase.Spec) {
 		actual := pilots[0]
 		require.NotEmpty(t, actual.ID)
 		actual.ID = ""
-		require.Equal(t, *v, actual)
+		expected := *v
+		expected.Revision = 1 // the revision of a freshly created pilot
+		require.Equal(t, expected, actual)
 	})
 
 	s.Then(`it returns pilot in the response`, func(t *testcase.T) {
 		resp := onSuccess(t)
 		require.NotEmpty(t, resp.Body.Pilot.ID)
 		resp.Body.Pilot.ID = ""
-		require.Equal(t, *pilotGet(t), resp.Body.Pilot)
+		expected := *pilotGet(t)
+		expected.Revision = 1 // the revision of a freshly created pilot
+		require.Equal(t, expected, resp.Body.Pilot)
 	})
 
 	s.And(`if input contains invalid values`, func(s *testcase.Spec) {
@@ -126,7 +130,7 @@ func SpecReleasePilotControllerCreate(s *testcase.Spec) {
 
 		c := client.NewHTTPClientWithConfig(nil, tc)
 
-		resp, err := c.Pilot.CreateReleasePilot(p, protectedAuth(t))
+		resp, _, err := c.Pilot.CreateReleasePilot(p, protectedAuth(t))
 		if err != nil {
 			t.Fatal(err.Error())
 		}
@@ -183,6 +187,10 @@ func SpecReleasePilotControllerUpdate(s *testcase.Spec) {
 	sh.GivenHTTPRequestHasAppToken(s)
 	hs.Method.LetValue(s, http.MethodPut)
 
+	s.Before(func(t *testcase.T) {
+		hs.HeaderGet(t).Set(`If-Match`, `"1"`) // the revision of the freshly created entity
+	})
+
 	pilot := sh.GivenWeHaveReleasePilot(s, `release-pilot`)
 
 	hs.Path.Let(s, func(t *testcase.T) interface{} {
@@ -219,7 +227,9 @@ func SpecReleasePilotControllerUpdate(s *testcase.Spec) {
 
 		actual := pilots[0]
 		require.NotEmpty(t, actual.ID)
-		require.Equal(t, *sh.ReleasePilotGet(t, updatedPilot), actual)
+		expected := *sh.ReleasePilotGet(t, updatedPilot)
+		expected.Revision = 2 // the update increments the revision of the freshly created pilot
+		require.Equal(t, expected, actual)
 	})
 
 	s.And(`if input contains invalid values`, func(s *testcase.Spec) {
@@ -251,6 +261,7 @@ func SpecReleasePilotControllerUpdate(s *testcase.Spec) {
 
 			p := swagger.NewUpdateReleasePilotParams()
 			p.PilotID = id
+			p.IfMatch = fmt.Sprintf(`"%d"`, sh.ReleasePilotGet(t, pilot).Revision)
 			p.Body.Pilot = &models.Pilot{
 				ID:              id,
 				EnvironmentID:   sh.ExampleDeploymentEnvironment(t).ID,
@@ -266,7 +277,7 @@ func SpecReleasePilotControllerUpdate(s *testcase.Spec) {
 
 			c := client.NewHTTPClientWithConfig(nil, tc)
 
-			resp, err := c.Pilot.UpdateReleasePilot(p, protectedAuth(t))
+			resp, _, err := c.Pilot.UpdateReleasePilot(p, protectedAuth(t))
 			require.Nil(t, err)
 			require.NotNil(t, resp)
 			require.NotNil(t, resp.Payload)
@@ -313,7 +324,7 @@ func SpecReleasePilotControllerDelete(s *testcase.Spec) {
 
 			c := client.NewHTTPClientWithConfig(nil, tc)
 
-			_, err := c.Pilot.DeleteReleasePilot(p, protectedAuth(t))
+			_, _, err := c.Pilot.DeleteReleasePilot(p, protectedAuth(t))
 			require.Nil(t, err)
 		})
 	})
@@ -5,9 +5,9 @@ import (
 	"encoding/json"
 	"net/http"
 
-	"github.com/adamluzsi/frameless/iterators"
 	"github.com/adamluzsi/gorest"
 
+	"github.com/toggler-io/toggler/domains/change"
 	"github.com/toggler-io/toggler/domains/release"
 	"github.com/toggler-io/toggler/domains/toggler"
 	"github.com/toggler-io/toggler/external/interface/httpintf/httputils"
@@ -16,13 +16,22 @@ import (
 func NewReleaseRolloutHandler(uc *toggler.UseCases) http.Handler {
 	c := ReleaseRolloutController{UseCases: uc}
 	h := gorest.NewHandler(c)
-	return httputils.AuthMiddleware(h, uc, ErrorWriterFunc)
+	return httputils.AuthMiddleware(h, uc, WriteError)
 }
 
 type ReleaseRolloutController struct {
 	UseCases *toggler.UseCases
 }
 
+// NotFound responds when the requested release rollout doesn't exist.
+func (ctrl ReleaseRolloutController) NotFound(w http.ResponseWriter, r *http.Request) {
+	handleError(w, r, release.ErrRolloutNotFound, http.StatusNotFound)
+}
+
+func (ctrl ReleaseRolloutController) InternalServerError(w http.ResponseWriter, r *http.Request) {
+	ErrorWriterFunc(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
 //--------------------------------------------------------------------------------------------------------------------//
 
 func (ctrl ReleaseRolloutController) getDeploymentEnvironment(ctx context.Context) release.Environment {
@@ -33,21 +42,6 @@ func (ctrl ReleaseRolloutController) getReleaseFlag(ctx context.Context) release
 	return ctx.Value(ReleaseFlagContextKey{}).(release.Flag)
 }
 
-func (ctrl ReleaseRolloutController) handleFlagValidationError(w http.ResponseWriter, err error) bool {
-	switch err {
-	case release.ErrNameIsEmpty,
-		release.ErrMissingFlag,
-		release.ErrInvalidAction,
-		release.ErrFlagAlreadyExist,
-		release.ErrInvalidRequestURL,
-		release.ErrInvalidPercentage:
-		return handleError(w, err, http.StatusBadRequest)
-
-	default:
-		return handleError(w, err, http.StatusInternalServerError)
-	}
-}
-
 //--------------------------------------------------------------------------------------------------------------------//
 
 // CreateReleaseRolloutRequest
@@ -97,6 +91,7 @@ type CreateReleaseRolloutResponse struct {
 
 		Responses:
 		  200: createReleaseRolloutResponse
+		  202: proposedChangeResponse
 		  400: errorResponse
 		  401: errorResponse
 		  500: errorResponse
@@ -112,32 +107,53 @@ func (ctrl ReleaseRolloutController) Create(w http.ResponseWriter, r *http.Reque
 	}
 	var p Payload
 
-	if handleError(w, decoder.Decode(&p), http.StatusBadRequest) {
+	if handleError(w, r, decoder.Decode(&p), http.StatusBadRequest) {
 		return
 	}
 
 	rr := p.Rollout
-	ctx := r.Context()
+	ctx := change.ContextWithProposals(r.Context())
 	rrs := ctrl.UseCases.Storage.ReleaseRollout(ctx)
 	err := rrs.Create(ctx, &rr)
 
-	if ctrl.handleFlagValidationError(w, err) {
+	if handleError(w, r, err, http.StatusInternalServerError) {
+		return
+	}
+	if serveProposed(w, r, ctx) {
 		return
 	}
 
 	var resp CreateReleaseRolloutResponse
 	resp.Body.Rollout = rr
-	serveJSON(w, resp.Body)
+	setETag(w, rr.Revision)
+	serveJSON(w, r, resp.Body)
 }
 
 //--------------------------------------------------------------------------------------------------------------------//
 
+// ListReleaseRolloutRequest
+// swagger:parameters listReleaseRollouts
+type ListReleaseRolloutRequest struct {
+	PaginationRequest
+	// FlagID filters the rollouts by release flag.
+	//
+	// in: query
+	FlagID string `json:"flag_id"`
+	// EnvironmentID filters the rollouts by deployment environment.
+	//
+	// in: query
+	EnvironmentID string `json:"env_id"`
+}
+
 // ListReleaseRolloutResponse
 // swagger:response listReleaseRolloutResponse
 type ListReleaseRolloutResponse struct {
 	// in: body
 	Body struct {
 		Rollouts []release.Rollout `json:"rollouts"`
+		// NextCursor is the cursor of the next page.
+		// It is omitted on the last page.
+		NextCursor string `json:"next_cursor,omitempty"`
 	}
 }
 
@@ -146,7 +162,8 @@ type ListReleaseRolloutResponse struct {
 	List
 	swagger:route GET /release-rollouts rollout listReleaseRollouts
 
-	List all the release flag that can be used to manage a feature rollout.
+	List the release rollouts.
+	The result is paginated with a cursor, and ordered by id.
 
 		Consumes:
 		- application/json
@@ -161,28 +178,30 @@ type ListReleaseRolloutResponse struct {
 
 		Responses:
 		  200: listReleaseRolloutResponse
+		  400: errorResponse
 		  401: errorResponse
 		  500: errorResponse
 
 */
 func (ctrl ReleaseRolloutController) List(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-
-	var resp ListReleaseRolloutResponse
-
-	err := iterators.ForEach(
-		ctrl.UseCases.Storage.ReleaseRollout(ctx).FindAll(ctx),
-		func(r release.Rollout) error {
-			resp.Body.Rollouts = append(resp.Body.Rollouts, r)
-			return nil
-		},
-	)
+	pagination, err := parsePagination(r.URL.Query())
+	if handleError(w, r, err, http.StatusInternalServerError) {
+		return
+	}
 
-	if handleError(w, err, http.StatusInternalServerError) {
+	page, err := ctrl.UseCases.ListRollouts(r.Context(), release.RolloutQuery{
+		FlagID:        r.URL.Query().Get(`flag_id`),
+		EnvironmentID: r.URL.Query().Get(`env_id`),
+		Pagination:    pagination,
+	})
+	if handleError(w, r, err, http.StatusInternalServerError) {
 		return
 	}
 
-	serveJSON(w, resp.Body)
+	var resp ListReleaseRolloutResponse
+	resp.Body.Rollouts = page.Rollouts
+	resp.Body.NextCursor = page.NextCursor
+	serveJSON(w, r, resp.Body)
 }
 
 //--------------------------------------------------------------------------------------------------------------------//
@@ -204,6 +223,58 @@ func (ctrl ReleaseRolloutController) ContextWithResource(ctx context.Context, re
 
 //--------------------------------------------------------------------------------------------------------------------//
 
+// ShowReleaseRolloutRequest
+// swagger:parameters showReleaseRollout
+type ShowReleaseRolloutRequest struct {
+	// RolloutID is the rollout id
+	//
+	// in: path
+	// required: true
+	RolloutID string `json:"rolloutID"`
+}
+
+// ShowReleaseRolloutResponse
+// swagger:response showReleaseRolloutResponse
+type ShowReleaseRolloutResponse struct {
+	// ETag is the revision of the rollout, which is expected in the If-Match header of its update.
+	ETag string
+	// in: body
+	Body struct {
+		Rollout release.Rollout `json:"rollout"`
+	}
+}
+
+/*
+
+	Show
+	swagger:route GET /release-rollouts/{rolloutID} rollout showReleaseRollout
+
+	Show a release rollout.
+
+		Produces:
+		- application/json
+
+		Schemes: http, https
+
+		Security:
+		  AppToken: []
+
+		Responses:
+		  200: showReleaseRolloutResponse
+		  401: errorResponse
+		  404: errorResponse
+		  500: errorResponse
+
+*/
+func (ctrl ReleaseRolloutController) Show(w http.ResponseWriter, r *http.Request) {
+	var resp ShowReleaseRolloutResponse
+	resp.Body.Rollout = r.Context().Value(ReleaseRolloutContextKey{}).(release.Rollout)
+	setETag(w, resp.Body.Rollout.Revision)
+	serveJSON(w, r, resp.Body)
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
 // UpdateReleaseRolloutRequest
 // swagger:parameters updateReleaseRollout
 type UpdateReleaseRolloutRequest struct {
@@ -212,6 +283,11 @@ type UpdateReleaseRolloutRequest struct {
 	// in: path
 	// required: true
 	RolloutID string `json:"rolloutID"`
+	// IfMatch is the ETag of the rollout which the update is based on, or "*" to update it unconditionally.
+	//
+	// in: header
+	// required: true
+	IfMatch string `json:"If-Match"`
 	// in: body
 	Body struct {
 		Rollout release.Rollout `json:"rollout"`
@@ -249,7 +325,10 @@ type UpdateReleaseRolloutResponse struct {
 
 		Responses:
 		  200: updateReleaseRolloutResponse
+		  202: proposedChangeResponse
 		  400: errorResponse
+		  412: errorResponse
+		  428: errorResponse
 		  500: errorResponse
 
 */
@@ -258,22 +337,32 @@ func (ctrl ReleaseRolloutController) Update(w http.ResponseWriter, r *http.Reque
 	decoder.DisallowUnknownFields()
 	defer r.Body.Close() // ignorable
 
+	revision, ok := ifMatchRevision(w, r)
+	if !ok {
+		return
+	}
+
 	var p UpdateReleaseRolloutRequest
-	if handleError(w, decoder.Decode(&p.Body), http.StatusBadRequest) {
+	if handleError(w, r, decoder.Decode(&p.Body), http.StatusBadRequest) {
 		return
 	}
 
-	ctx := r.Context()
+	ctx := change.ContextWithProposals(r.Context())
 	rollout := ctx.Value(ReleaseRolloutContextKey{}).(release.Rollout)
 	rollout.Plan = p.Body.Rollout.Plan
+	rollout.Revision = revision
 
-	if ctrl.handleFlagValidationError(w, ctrl.UseCases.Storage.ReleaseRollout(r.Context()).Update(r.Context(), &rollout)) {
+	if handleError(w, r, ctrl.UseCases.Storage.ReleaseRollout(ctx).Update(ctx, &rollout), http.StatusInternalServerError) {
+		return
+	}
+	if serveProposed(w, r, ctx) {
 		return
 	}
 
 	var resp UpdateReleaseRolloutResponse
 	resp.Body.Rollout.Plan = release.RolloutPlanView{Plan: rollout.Plan}
-	serveJSON(w, resp.Body)
+	setETag(w, rollout.Revision)
+	serveJSON(w, r, resp.Body)
 }
 
 //--------------------------------------------------------------------------------------------------------------------//
@@ -313,6 +402,7 @@ type DeleteReleaseRolloutResponse struct {
 
 		Responses:
 		  200: deleteReleaseRolloutResponse
+		  202: proposedChangeResponse
 		  400: errorResponse
 		  500: errorResponse
 
@@ -320,8 +410,12 @@ type DeleteReleaseRolloutResponse struct {
 func (ctrl ReleaseRolloutController) Delete(w http.ResponseWriter, r *http.Request) {
 	ID := r.Context().Value(ReleaseRolloutContextKey{}).(release.Rollout).ID
 
-	err := ctrl.UseCases.Storage.ReleaseRollout(r.Context()).DeleteByID(r.Context(), ID)
-	if handleError(w, err, http.StatusBadRequest) {
+	ctx := change.ContextWithProposals(r.Context())
+	err := ctrl.UseCases.Storage.ReleaseRollout(ctx).DeleteByID(ctx, ID)
+	if handleError(w, r, err, http.StatusBadRequest) {
+		return
+	}
+	if serveProposed(w, r, ctx) {
 		return
 	}
 
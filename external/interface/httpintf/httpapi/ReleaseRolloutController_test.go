@@ -99,7 +99,7 @@ func SpecReleaseRolloutControllerDelete(s *testcase.Spec) {
 
 			c := client.NewHTTPClientWithConfig(nil, tc)
 
-			_, err = c.Rollout.DeleteReleaseRollout(p, protectedAuth(t))
+			_, _, err = c.Rollout.DeleteReleaseRollout(p, protectedAuth(t))
 			require.Nil(t, err)
 		})
 	})
@@ -149,6 +149,7 @@ func SpecReleaseRolloutControllerCreate(s *testcase.Spec) {
 	s.Then(`rollout stored in the system`, func(t *testcase.T) {
 		onSuccess(t)
 		rfv := *sh.GetReleaseRollout(t, rollout.Name)
+		rfv.Revision = 1 // the revision of a freshly created rollout
 		actualReleaseRollout := FindStoredReleaseRollout(t)
 		actualReleaseRollout.ID = ``
 		require.Equal(t, rfv, actualReleaseRollout)
@@ -201,7 +202,7 @@ func SpecReleaseRolloutControllerCreate(s *testcase.Spec) {
 				Plan:          release.RolloutPlanView{Plan: release.NewRolloutDecisionByPercentage()},
 			}
 
-			resp, err := c.Rollout.CreateReleaseRollout(p, protectedAuth(t))
+			resp, _, err := c.Rollout.CreateReleaseRollout(p, protectedAuth(t))
 			require.Nil(t, err)
 			require.NotNil(t, resp)
 			require.NotNil(t, resp.Payload)
@@ -247,7 +248,8 @@ func SpecReleaseRolloutControllerList(s *testcase.Spec) {
 		})
 
 		s.And(`even multiple rollout in the system`, func(s *testcase.Spec) {
-			sh.GivenWeHaveReleaseRollout(s, `feature-2`, sh.LetVarExampleReleaseFlag, sh.LetVarExampleDeploymentEnvironment)
+			sh.GivenWeHaveReleaseFlag(s, `flag-2`) // a flag has a single rollout per environment
+			sh.GivenWeHaveReleaseRollout(s, `feature-2`, `flag-2`, sh.LetVarExampleDeploymentEnvironment)
 			s.Before(func(t *testcase.T) { sh.GetReleaseRollout(t, `feature-2`) }) // eager load
 
 			s.Then(`the rollouts will be received back`, func(t *testcase.T) {
@@ -275,6 +277,10 @@ func SpecReleaseRolloutControllerList(s *testcase.Spec) {
 func SpecReleaseRolloutControllerUpdate(s *testcase.Spec) {
 	sh.GivenHTTPRequestHasAppToken(s)
 	Method.LetValue(s, http.MethodPut)
+
+	s.Before(func(t *testcase.T) {
+		HeaderGet(t).Set(`If-Match`, `"1"`) // the revision of the freshly created entity
+	})
 	Path.Let(s, func(t *testcase.T) interface{} {
 		return fmt.Sprintf(`/%s`, t.I(`id`))
 	})
@@ -307,6 +313,7 @@ func SpecReleaseRolloutControllerUpdate(s *testcase.Spec) {
 	s.Then(`rollout is updated in the system`, func(t *testcase.T) {
 		onSuccess(t)
 		updatedReleaseRolloutView := *sh.GetReleaseRollout(t, `updated-rollout`)
+		updatedReleaseRolloutView.Revision = 2 // the update increments the revision of the freshly created rollout
 		stored := FindStoredReleaseRollout(t)
 		require.Equal(t, updatedReleaseRolloutView, stored)
 	})
@@ -349,11 +356,12 @@ func SpecReleaseRolloutControllerUpdate(s *testcase.Spec) {
 
 			p := swagger.NewUpdateReleaseRolloutParams()
 			p.RolloutID = id
+			p.IfMatch = fmt.Sprintf(`"%d"`, sh.GetReleaseRollout(t, rollout.Name).Revision)
 			p.Body.Rollout = &models.Rollout{
 				Plan: release.RolloutPlanView{Plan: release.NewRolloutDecisionByPercentage()},
 			}
 
-			resp, err := c.Rollout.UpdateReleaseRollout(p, protectedAuth(t))
+			resp, _, err := c.Rollout.UpdateReleaseRollout(p, protectedAuth(t))
 			require.Nil(t, err)
 			require.NotNil(t, resp)
 			require.NotNil(t, resp.Payload)
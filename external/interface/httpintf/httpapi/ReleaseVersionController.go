@@ -0,0 +1,360 @@
+package httpapi
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/toggler-io/toggler/domains/errs"
+	"github.com/toggler-io/toggler/domains/change"
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/external/interface/httpintf/httputils"
+)
+
+// NewReleaseVersionHandler serves the history of the release flags, rollouts and pilots,
+// and the evaluation of the release flags at a point in time.
+func NewReleaseVersionHandler(uc *toggler.UseCases) http.Handler {
+	ctrl := ReleaseVersionController{UseCases: uc}
+	m := http.NewServeMux()
+	m.HandleFunc(`/release-versions`, ctrl.List)
+	m.HandleFunc(`/release-versions/diff`, ctrl.Diff)
+	m.HandleFunc(`/release-versions/`, ctrl.Revert)
+	m.HandleFunc(`/release-evaluations`, ctrl.EvaluateAsOf)
+	return httputils.AuthMiddleware(m, uc, WriteError)
+}
+
+var (
+	ErrEntityIDIsMissing = errs.Validation(`entity_id_is_missing`, `entity_id`, `entity_id is required`)
+	ErrPilotIDIsMissing  = errs.Validation(`pilot_id_is_missing`, `pilot_id`, `pilot_id is required`)
+)
+
+type ReleaseVersionController struct {
+	UseCases *toggler.UseCases
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+// ListReleaseVersionRequest
+// swagger:parameters listReleaseVersions
+type ListReleaseVersionRequest struct {
+	// Kind is the type of the versioned entity.
+	//
+	// in: query
+	// required: true
+	// enum: flag,rollout,pilot
+	Kind string `json:"kind"`
+	// EntityID is the ID of the versioned entity.
+	//
+	// in: query
+	// required: true
+	EntityID string `json:"entity_id"`
+}
+
+// ListReleaseVersionResponse
+// swagger:response listReleaseVersionResponse
+type ListReleaseVersionResponse struct {
+	// in: body
+	Body struct {
+		Versions []release.Version `json:"versions"`
+	}
+}
+
+/*
+
+	List
+	swagger:route GET /release-versions version listReleaseVersions
+
+	List the versions of a release flag, rollout or pilot, ordered by version number.
+
+		Produces:
+		- application/json
+
+		Schemes: http, https
+
+		Security:
+		  AppToken: []
+
+		Responses:
+		  200: listReleaseVersionResponse
+		  400: errorResponse
+		  401: errorResponse
+		  500: errorResponse
+
+*/
+func (ctrl ReleaseVersionController) List(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		ErrorWriterFunc(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	entityID := r.URL.Query().Get(`entity_id`)
+	if entityID == `` {
+		handleError(w, r, ErrEntityIDIsMissing, http.StatusBadRequest)
+		return
+	}
+
+	versions, err := ctrl.UseCases.RolloutManager.ListVersions(r.Context(), r.URL.Query().Get(`kind`), entityID)
+	if handleError(w, r, err, http.StatusInternalServerError) {
+		return
+	}
+
+	var resp ListReleaseVersionResponse
+	resp.Body.Versions = versions
+	serveJSON(w, r, resp.Body)
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+// DiffReleaseVersionRequest
+// swagger:parameters diffReleaseVersions
+type DiffReleaseVersionRequest struct {
+	// From is the ID of the earlier version.
+	//
+	// in: query
+	// required: true
+	From string `json:"from"`
+	// To is the ID of the later version.
+	//
+	// in: query
+	// required: true
+	To string `json:"to"`
+}
+
+// DiffReleaseVersionResponse
+// swagger:response diffReleaseVersionResponse
+type DiffReleaseVersionResponse struct {
+	// in: body
+	Body struct {
+		Changes []release.VersionChange `json:"changes"`
+	}
+}
+
+/*
+
+	Diff
+	swagger:route GET /release-versions/diff version diffReleaseVersions
+
+	Compare two versions of the same entity field by field.
+
+		Produces:
+		- application/json
+
+		Schemes: http, https
+
+		Security:
+		  AppToken: []
+
+		Responses:
+		  200: diffReleaseVersionResponse
+		  400: errorResponse
+		  401: errorResponse
+		  404: errorResponse
+		  500: errorResponse
+
+*/
+func (ctrl ReleaseVersionController) Diff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		ErrorWriterFunc(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	from, err := ctrl.UseCases.RolloutManager.FindVersion(r.Context(), r.URL.Query().Get(`from`))
+	if handleError(w, r, err, http.StatusInternalServerError) {
+		return
+	}
+	to, err := ctrl.UseCases.RolloutManager.FindVersion(r.Context(), r.URL.Query().Get(`to`))
+	if handleError(w, r, err, http.StatusInternalServerError) {
+		return
+	}
+
+	changes, err := release.DiffVersions(from, to)
+	if handleError(w, r, err, http.StatusInternalServerError) {
+		return
+	}
+
+	var resp DiffReleaseVersionResponse
+	resp.Body.Changes = changes
+	serveJSON(w, r, resp.Body)
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+// RevertReleaseVersionRequest
+// swagger:parameters revertReleaseVersion
+type RevertReleaseVersionRequest struct {
+	// VersionID is the ID of the version the entity is reverted to.
+	//
+	// in: path
+	// required: true
+	VersionID string `json:"versionID"`
+}
+
+// RevertReleaseVersionResponse
+// swagger:response revertReleaseVersionResponse
+type RevertReleaseVersionResponse struct {
+	// in: body
+	Body struct {
+		Version release.Version `json:"version"`
+	}
+}
+
+/*
+
+	Revert
+	swagger:route POST /release-versions/{versionID}/revert version revertReleaseVersion
+
+	Restore a release flag, rollout or pilot to the state of one of its versions.
+	The revert is recorded as a new version, which is returned.
+
+		Produces:
+		- application/json
+
+		Schemes: http, https
+
+		Security:
+		  AppToken: []
+
+		Responses:
+		  200: revertReleaseVersionResponse
+		  202: proposedChangeResponse
+		  401: errorResponse
+		  404: errorResponse
+		  500: errorResponse
+
+*/
+func (ctrl ReleaseVersionController) Revert(w http.ResponseWriter, r *http.Request) {
+	versionID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, `/release-versions/`), `/revert`)
+	if !strings.HasSuffix(r.URL.Path, `/revert`) || versionID == `` || strings.Contains(versionID, `/`) {
+		ErrorWriterFunc(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		ErrorWriterFunc(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := change.ContextWithProposals(r.Context())
+	manager := ctrl.UseCases.RolloutManager
+	if handleError(w, r, manager.RevertToVersion(ctx, versionID), http.StatusInternalServerError) {
+		return
+	}
+	if serveProposed(w, r, ctx) {
+		return
+	}
+
+	version, err := manager.FindVersion(ctx, versionID)
+	if handleError(w, r, err, http.StatusInternalServerError) {
+		return
+	}
+	versions, err := manager.ListVersions(ctx, version.Kind, version.EntityID)
+	if handleError(w, r, err, http.StatusInternalServerError) {
+		return
+	}
+
+	var resp RevertReleaseVersionResponse
+	resp.Body.Version = versions[len(versions)-1]
+	serveJSON(w, r, resp.Body)
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+// EvaluateAsOfRequest
+// swagger:parameters evaluateReleaseFlagsAsOf
+type EvaluateAsOfRequest struct {
+	// PilotID is the public ID of the pilot.
+	//
+	// in: query
+	// required: true
+	PilotID string `json:"pilot_id"`
+	// Environment is the ID or the name of the deployment environment.
+	//
+	// in: query
+	// required: true
+	Environment string `json:"environment"`
+	// AsOf is the RFC3339 timestamp the flags are evaluated at.
+	// When omitted, the current state is evaluated.
+	//
+	// in: query
+	AsOf string `json:"as_of"`
+	// Flag limits the evaluation to the given release flag names.
+	//
+	// in: query
+	Flag []string `json:"flag"`
+}
+
+// EvaluateAsOfResponse
+// swagger:response evaluateReleaseFlagsAsOfResponse
+type EvaluateAsOfResponse struct {
+	// in: body
+	Body struct {
+		Evaluations []release.Evaluation `json:"evaluations"`
+	}
+}
+
+/*
+
+	EvaluateAsOf
+	swagger:route GET /release-evaluations version evaluateReleaseFlagsAsOf
+
+	Evaluate the release flags of a pilot as they were at a given time, for post-incident analysis.
+	The past state is reconstructed from the versions, so it only covers the changes made since the versioning exists.
+
+		Produces:
+		- application/json
+
+		Schemes: http, https
+
+		Security:
+		  AppToken: []
+
+		Responses:
+		  200: evaluateReleaseFlagsAsOfResponse
+		  400: errorResponse
+		  401: errorResponse
+		  500: errorResponse
+
+*/
+func (ctrl ReleaseVersionController) EvaluateAsOf(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		ErrorWriterFunc(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	pilotID := q.Get(`pilot_id`)
+	if pilotID == `` {
+		handleError(w, r, ErrPilotIDIsMissing, http.StatusBadRequest)
+		return
+	}
+
+	var env release.Environment
+	found, err := ctrl.UseCases.Storage.ReleaseEnvironment(r.Context()).FindByAlias(r.Context(), q.Get(`environment`), &env)
+	if handleError(w, r, err, http.StatusInternalServerError) {
+		return
+	}
+	if !found {
+		handleError(w, r, fmt.Errorf(`%w: %s`, release.ErrEnvironmentNotFound, q.Get(`environment`)), http.StatusNotFound)
+		return
+	}
+
+	manager := ctrl.UseCases.RolloutManager
+	var evaluations []release.Evaluation
+	if asOf := q.Get(`as_of`); asOf == `` {
+		evaluations, err = manager.EvaluateFlags(r.Context(), pilotID, env, q[`flag`]...)
+	} else {
+		t, perr := time.Parse(time.RFC3339, asOf)
+		if handleError(w, r, perr, http.StatusBadRequest) {
+			return
+		}
+		evaluations, err = manager.EvaluateFlagsAsOf(r.Context(), t, pilotID, env, q[`flag`]...)
+	}
+	if handleError(w, r, err, http.StatusInternalServerError) {
+		return
+	}
+
+	var resp EvaluateAsOfResponse
+	resp.Body.Evaluations = evaluations
+	serveJSON(w, r, resp.Body)
+}
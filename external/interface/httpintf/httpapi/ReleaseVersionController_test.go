@@ -0,0 +1,301 @@
+package httpapi_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/adamluzsi/testcase"
+	hs "github.com/adamluzsi/testcase/httpspec"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/external/interface/httpintf/httpapi"
+	sh "github.com/toggler-io/toggler/spechelper"
+)
+
+func TestReleaseVersionController(t *testing.T) {
+	s := sh.NewSpec(t)
+	s.Parallel()
+
+	hs.Context.Let(s, func(t *testcase.T) interface{} { return sh.ContextGet(t) })
+	hs.HandlerLet(s, func(t *testcase.T) http.Handler { return httpapi.NewReleaseVersionHandler(sh.ExampleUseCases(t)) })
+	hs.ContentTypeIsJSON(s)
+	sh.GivenHTTPRequestHasAppToken(s)
+
+	s.Describe(`GET /release-versions - list`, SpecReleaseVersionControllerList)
+	s.Describe(`GET /release-versions/diff - diff`, SpecReleaseVersionControllerDiff)
+	s.Describe(`POST /release-versions/{versionID}/revert - revert`, SpecReleaseVersionControllerRevert)
+	s.Describe(`GET /release-evaluations - evaluate as of`, SpecReleaseVersionControllerEvaluateAsOf)
+}
+
+// givenWeHaveAVersionedFlag creates a release flag and renames it through the use cases,
+// so the flag has a create and an update version.
+func givenWeHaveAVersionedFlag(s *testcase.Spec) testcase.Var {
+	return s.Let(`versioned flag`, func(t *testcase.T) interface{} {
+		manager := sh.ExampleUseCases(t).RolloutManager
+		flag := &release.Flag{Name: `original-` + t.Random.StringNWithCharset(8, `abcdef`)}
+		require.Nil(t, manager.CreateFeatureFlag(sh.ContextGet(t), flag))
+		t.Defer(manager.DeleteFeatureFlag, sh.ContextGet(t), flag.ID)
+		flag.Name = `renamed-` + t.Random.StringNWithCharset(8, `abcdef`)
+		require.Nil(t, manager.UpdateFeatureFlag(sh.ContextGet(t), flag))
+		return flag
+	})
+}
+
+func flagVersions(t *testcase.T, flag testcase.Var) []release.Version {
+	versions, err := sh.ExampleUseCases(t).RolloutManager.ListVersions(sh.ContextGet(t), release.VersionKindFlag, flag.Get(t).(*release.Flag).ID)
+	require.Nil(t, err)
+	return versions
+}
+
+func SpecReleaseVersionControllerList(s *testcase.Spec) {
+	hs.Method.LetValue(s, http.MethodGet)
+	hs.Path.LetValue(s, `/release-versions`)
+
+	flag := givenWeHaveAVersionedFlag(s)
+	hs.Query.Let(s, func(t *testcase.T) interface{} {
+		return url.Values{`kind`: {release.VersionKindFlag}, `entity_id`: {flag.Get(t).(*release.Flag).ID}}
+	})
+
+	s.Then(`the versions of the entity are listed by version number`, func(t *testcase.T) {
+		rr := hs.ServeHTTP(t)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var resp httpapi.ListReleaseVersionResponse
+		IsJsonResponse(t, rr, &resp.Body)
+
+		require.Len(t, resp.Body.Versions, 2)
+		require.Equal(t, 1, resp.Body.Versions[0].Number)
+		require.Equal(t, release.VersionActionCreate, resp.Body.Versions[0].Action)
+		require.Equal(t, 2, resp.Body.Versions[1].Number)
+		require.Equal(t, release.VersionActionUpdate, resp.Body.Versions[1].Action)
+		for _, v := range resp.Body.Versions {
+			require.Equal(t, flag.Get(t).(*release.Flag).ID, v.EntityID)
+		}
+	})
+
+	s.When(`the entity has no versions`, func(s *testcase.Spec) {
+		hs.Query.Let(s, func(t *testcase.T) interface{} {
+			return url.Values{`kind`: {release.VersionKindFlag}, `entity_id`: {`unknown`}}
+		})
+
+		s.Then(`an empty list is returned`, func(t *testcase.T) {
+			rr := hs.ServeHTTP(t)
+			require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+			require.JSONEq(t, `{"versions":[]}`, rr.Body.String())
+		})
+	})
+
+	s.When(`the entity id is missing`, func(s *testcase.Spec) {
+		hs.Query.Let(s, func(t *testcase.T) interface{} { return url.Values{`kind`: {release.VersionKindFlag}} })
+
+		s.Then(`it is rejected as a validation error`, func(t *testcase.T) {
+			resp := thenErrorResponse(t, http.StatusBadRequest, `entity_id_is_missing`)
+			require.Equal(t, `entity_id`, resp.Field)
+		})
+	})
+
+	s.When(`the kind is unknown`, func(s *testcase.Spec) {
+		hs.Query.Let(s, func(t *testcase.T) interface{} {
+			return url.Values{`kind`: {`unknown`}, `entity_id`: {flag.Get(t).(*release.Flag).ID}}
+		})
+
+		s.Then(`it is rejected as a validation error`, func(t *testcase.T) {
+			resp := thenErrorResponse(t, http.StatusBadRequest, `invalid_version_kind`)
+			require.Equal(t, `kind`, resp.Field)
+		})
+	})
+
+	s.When(`the request has no valid token`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { hs.HeaderGet(t).Del(`X-App-Token`) })
+
+		s.Then(`it is unauthorized`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusUnauthorized, `invalid_token`)
+		})
+	})
+}
+
+func SpecReleaseVersionControllerDiff(s *testcase.Spec) {
+	hs.Method.LetValue(s, http.MethodGet)
+	hs.Path.LetValue(s, `/release-versions/diff`)
+
+	flag := givenWeHaveAVersionedFlag(s)
+	hs.Query.Let(s, func(t *testcase.T) interface{} {
+		versions := flagVersions(t, flag)
+		return url.Values{`from`: {versions[0].ID}, `to`: {versions[1].ID}}
+	})
+
+	s.Then(`the changed fields are returned`, func(t *testcase.T) {
+		rr := hs.ServeHTTP(t)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var resp httpapi.DiffReleaseVersionResponse
+		IsJsonResponse(t, rr, &resp.Body)
+		require.NotEmpty(t, resp.Body.Changes)
+	})
+
+	s.When(`a version is unknown`, func(s *testcase.Spec) {
+		hs.Query.Let(s, func(t *testcase.T) interface{} {
+			return url.Values{`from`: {flagVersions(t, flag)[0].ID}, `to`: {`unknown`}}
+		})
+
+		s.Then(`it is not found`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusNotFound, `version_not_found`)
+		})
+	})
+}
+
+func SpecReleaseVersionControllerRevert(s *testcase.Spec) {
+	hs.Method.LetValue(s, http.MethodPost)
+
+	flag := givenWeHaveAVersionedFlag(s)
+	versionID := s.Let(`version id`, func(t *testcase.T) interface{} {
+		return flagVersions(t, flag)[0].ID
+	})
+	hs.Path.Let(s, func(t *testcase.T) interface{} {
+		return `/release-versions/` + versionID.Get(t).(string) + `/revert`
+	})
+
+	s.Then(`the entity is restored, and the revert is returned as its new version`, func(t *testcase.T) {
+		rr := hs.ServeHTTP(t)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var resp httpapi.RevertReleaseVersionResponse
+		IsJsonResponse(t, rr, &resp.Body)
+		require.Equal(t, 3, resp.Body.Version.Number)
+		require.Equal(t, release.VersionActionUpdate, resp.Body.Version.Action)
+
+		var original release.Flag
+		require.Nil(t, flagVersions(t, flag)[0].Decode(&original))
+		var current release.Flag
+		found, err := sh.StorageGet(t).ReleaseFlag(sh.ContextGet(t)).FindByID(sh.ContextGet(t), &current, flag.Get(t).(*release.Flag).ID)
+		require.Nil(t, err)
+		require.True(t, found)
+		require.Equal(t, original.Name, current.Name)
+	})
+
+	s.When(`the version is unknown`, func(s *testcase.Spec) {
+		versionID.LetValue(s, `unknown`)
+
+		s.Then(`it is not found`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusNotFound, `version_not_found`)
+		})
+	})
+
+	s.When(`the path is not a revert`, func(s *testcase.Spec) {
+		hs.Path.Let(s, func(t *testcase.T) interface{} {
+			return `/release-versions/` + versionID.Get(t).(string)
+		})
+
+		s.Then(`it is not found`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusNotFound, `not_found`)
+		})
+	})
+
+	s.When(`the method is not POST`, func(s *testcase.Spec) {
+		hs.Method.LetValue(s, http.MethodGet)
+
+		s.Then(`the method is not allowed`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusMethodNotAllowed, `method_not_allowed`)
+		})
+	})
+
+	s.When(`the request has no valid token`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { hs.HeaderGet(t).Del(`X-App-Token`) })
+
+		s.Then(`it is unauthorized, and the entity is kept as it is`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusUnauthorized, `invalid_token`)
+			require.Len(t, flagVersions(t, flag), 2)
+		})
+	})
+}
+
+func SpecReleaseVersionControllerEvaluateAsOf(s *testcase.Spec) {
+	hs.Method.LetValue(s, http.MethodGet)
+	hs.Path.LetValue(s, `/release-evaluations`)
+
+	// the pilot is enrolled through the use cases after asOf, so the enrollment has a version after it.
+	asOf := s.Let(`as of`, func(t *testcase.T) interface{} {
+		manager := sh.ExampleUseCases(t).RolloutManager
+		flag := sh.ExampleReleaseFlag(t)
+		require.Nil(t, manager.UpdateFeatureFlag(sh.ContextGet(t), flag))
+		asOf := time.Now().UTC()
+		require.Nil(t, manager.SetPilotEnrollmentForFeature(sh.ContextGet(t), flag.ID, sh.ExampleDeploymentEnvironment(t).ID, sh.ExampleExternalPilotID(t), true))
+		t.Defer(manager.UnsetPilotEnrollmentForFeature, sh.ContextGet(t), flag.ID, sh.ExampleDeploymentEnvironment(t).ID, sh.ExampleExternalPilotID(t))
+		return asOf
+	}).EagerLoading(s)
+	query := func(t *testcase.T) url.Values {
+		return url.Values{
+			`pilot_id`:    {sh.ExampleExternalPilotID(t)},
+			`environment`: {sh.ExampleDeploymentEnvironment(t).Name},
+			`flag`:        {sh.ExampleReleaseFlag(t).Name},
+		}
+	}
+	hs.Query.Let(s, func(t *testcase.T) interface{} { return query(t) })
+
+	onSuccess := func(t *testcase.T) release.Evaluation {
+		rr := hs.ServeHTTP(t)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var resp httpapi.EvaluateAsOfResponse
+		IsJsonResponse(t, rr, &resp.Body)
+		require.Len(t, resp.Body.Evaluations, 1)
+		return resp.Body.Evaluations[0]
+	}
+
+	s.Then(`the current state of the flags is evaluated by default`, func(t *testcase.T) {
+		evaluation := onSuccess(t)
+		require.True(t, evaluation.State)
+		require.Equal(t, release.EvaluationReasonTargetingMatch, evaluation.Reason)
+	})
+
+	s.When(`a time before the pilot enrollment is given`, func(s *testcase.Spec) {
+		hs.Query.Let(s, func(t *testcase.T) interface{} {
+			q := query(t)
+			q.Set(`as_of`, asOf.Get(t).(time.Time).Format(time.RFC3339Nano))
+			return q
+		})
+
+		s.Then(`the flags are evaluated as they were at that time`, func(t *testcase.T) {
+			evaluation := onSuccess(t)
+			require.Equal(t, sh.ExampleReleaseFlag(t).ID, evaluation.FlagID)
+			require.Equal(t, release.EvaluationReasonDefault, evaluation.Reason)
+			require.False(t, evaluation.State)
+		})
+	})
+
+	s.When(`the time is not RFC3339`, func(s *testcase.Spec) {
+		hs.Query.Let(s, func(t *testcase.T) interface{} {
+			q := query(t)
+			q.Set(`as_of`, `yesterday`)
+			return q
+		})
+
+		s.Then(`it is a bad request`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusBadRequest, `bad_request`)
+		})
+	})
+
+	s.When(`the pilot id is missing`, func(s *testcase.Spec) {
+		hs.Query.Let(s, func(t *testcase.T) interface{} {
+			q := query(t)
+			q.Del(`pilot_id`)
+			return q
+		})
+
+		s.Then(`it is rejected as a validation error`, func(t *testcase.T) {
+			resp := thenErrorResponse(t, http.StatusBadRequest, `pilot_id_is_missing`)
+			require.Equal(t, `pilot_id`, resp.Field)
+		})
+	})
+
+	s.When(`the environment is unknown`, func(s *testcase.Spec) {
+		hs.Query.Let(s, func(t *testcase.T) interface{} {
+			q := query(t)
+			q.Set(`environment`, `unknown`)
+			return q
+		})
+
+		s.Then(`it is not found`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusNotFound, `environment_not_found`)
+		})
+	})
+}
@@ -0,0 +1,289 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/toggler-io/toggler/domains/schedule"
+	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/external/interface/httpintf/httputils"
+)
+
+// NewScheduledChangeHandler serves the changes of the rollouts and the pilots, which are made at a scheduled time.
+func NewScheduledChangeHandler(uc *toggler.UseCases) http.Handler {
+	ctrl := ScheduledChangeController{UseCases: uc}
+	m := http.NewServeMux()
+	m.HandleFunc(`/scheduled-changes`, ctrl.Collection)
+	m.HandleFunc(`/scheduled-changes/`, ctrl.Route)
+	return httputils.AuthMiddleware(m, uc, WriteError)
+}
+
+type ScheduledChangeController struct {
+	UseCases *toggler.UseCases
+}
+
+// Collection dispatches the requests of the scheduled change list by their method.
+func (ctrl ScheduledChangeController) Collection(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		ctrl.List(w, r)
+	case http.MethodPost:
+		ctrl.Create(w, r)
+	default:
+		ErrorWriterFunc(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// Route dispatches the requests of a single scheduled change by their path.
+func (ctrl ScheduledChangeController) Route(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, `/scheduled-changes/`), `/`)
+	if parts[0] == `` || 2 < len(parts) {
+		ErrorWriterFunc(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	id, action := parts[0], ``
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+
+	switch {
+	case action == `` && r.Method == http.MethodGet:
+		ctrl.Show(w, r, id)
+	case action == `cancel` && r.Method == http.MethodPost:
+		ctrl.Cancel(w, r, id)
+	case action == `` || action == `cancel`:
+		ErrorWriterFunc(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	default:
+		ErrorWriterFunc(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	}
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+// CreateScheduledChangeRequest
+// swagger:parameters createScheduledChange
+type CreateScheduledChangeRequest struct {
+	// required: true
+	// in: body
+	Body struct {
+		ScheduledChange struct {
+			// Kind is the type of the changed entity.
+			//
+			// required: true
+			// enum: rollout,pilot
+			Kind string `json:"kind"`
+			// Action is the change of the entity.
+			//
+			// required: true
+			// enum: create,update,delete
+			Action string `json:"action"`
+			// EntityID is the ID of the updated or deleted entity.
+			EntityID string `json:"entity_id,omitempty"`
+			// RunAt is the time when the change is made.
+			//
+			// required: true
+			// example: 2026-10-26T09:00:00Z
+			RunAt time.Time `json:"run_at"`
+			// Proposal is the state of the entity after the change.
+			// The fields missing from the proposal of an update keep their current value.
+			//
+			// example: {"plan":{"type":"percentage","percentage":50}}
+			Proposal json.RawMessage `json:"proposal,omitempty"`
+		} `json:"scheduled_change"`
+	}
+}
+
+// ScheduledChangeResponse
+// swagger:response scheduledChangeResponse
+type ScheduledChangeResponse struct {
+	// in: body
+	Body struct {
+		ScheduledChange schedule.Change `json:"scheduled_change"`
+	}
+}
+
+/*
+Create
+swagger:route POST /scheduled-changes schedule createScheduledChange
+
+Schedule a change of a rollout or a pilot, which is made at its run time.
+The due change of a protected deployment environment is proposed as a change request.
+
+	Consumes:
+	- application/json
+
+	Produces:
+	- application/json
+
+	Schemes: http, https
+
+	Security:
+	  AppToken: []
+
+	Responses:
+	  200: scheduledChangeResponse
+	  400: errorResponse
+	  401: errorResponse
+	  404: errorResponse
+	  500: errorResponse
+*/
+func (ctrl ScheduledChangeController) Create(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close() // ignorable
+
+	var req CreateScheduledChangeRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if handleError(w, r, decoder.Decode(&req.Body), http.StatusBadRequest) {
+		return
+	}
+
+	p := req.Body.ScheduledChange
+	c := schedule.Change{
+		Kind:     p.Kind,
+		Action:   p.Action,
+		EntityID: p.EntityID,
+		RunAt:    p.RunAt,
+		Proposal: p.Proposal,
+	}
+	if handleError(w, r, ctrl.UseCases.Scheduler.Schedule(r.Context(), &c), http.StatusInternalServerError) {
+		return
+	}
+
+	var resp ScheduledChangeResponse
+	resp.Body.ScheduledChange = c
+	serveJSON(w, r, resp.Body)
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+// ListScheduledChangeRequest
+// swagger:parameters listScheduledChanges
+type ListScheduledChangeRequest struct {
+	// EnvironmentID filters the scheduled changes by deployment environment.
+	//
+	// in: query
+	EnvironmentID string `json:"env_id"`
+	// Status filters the scheduled changes by their outcome.
+	//
+	// in: query
+	// enum: scheduled,cancelled,succeeded,failed,proposed
+	Status string `json:"status"`
+}
+
+// ListScheduledChangeResponse
+// swagger:response listScheduledChangeResponse
+type ListScheduledChangeResponse struct {
+	// in: body
+	Body struct {
+		ScheduledChanges []schedule.Change `json:"scheduled_changes"`
+	}
+}
+
+/*
+List
+swagger:route GET /scheduled-changes schedule listScheduledChanges
+
+List the scheduled changes in the order of their run time, along with their outcome.
+
+	Produces:
+	- application/json
+
+	Schemes: http, https
+
+	Security:
+	  AppToken: []
+
+	Responses:
+	  200: listScheduledChangeResponse
+	  401: errorResponse
+	  500: errorResponse
+*/
+func (ctrl ScheduledChangeController) List(w http.ResponseWriter, r *http.Request) {
+	changes, err := ctrl.UseCases.Scheduler.List(r.Context(), schedule.ChangeQuery{
+		EnvironmentID: r.URL.Query().Get(`env_id`),
+		Status:        r.URL.Query().Get(`status`),
+	})
+	if handleError(w, r, err, http.StatusInternalServerError) {
+		return
+	}
+
+	var resp ListScheduledChangeResponse
+	resp.Body.ScheduledChanges = changes
+	serveJSON(w, r, resp.Body)
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+// ShowScheduledChangeRequest
+// swagger:parameters showScheduledChange cancelScheduledChange
+type ShowScheduledChangeRequest struct {
+	// ScheduledChangeID is the scheduled change id.
+	//
+	// in: path
+	// required: true
+	ScheduledChangeID string `json:"scheduledChangeID"`
+}
+
+/*
+Show
+swagger:route GET /scheduled-changes/{scheduledChangeID} schedule showScheduledChange
+
+Show a scheduled change along with its outcome.
+
+	Produces:
+	- application/json
+
+	Schemes: http, https
+
+	Security:
+	  AppToken: []
+
+	Responses:
+	  200: scheduledChangeResponse
+	  401: errorResponse
+	  404: errorResponse
+	  500: errorResponse
+*/
+func (ctrl ScheduledChangeController) Show(w http.ResponseWriter, r *http.Request, id string) {
+	c, err := ctrl.UseCases.Scheduler.Find(r.Context(), id)
+	if handleError(w, r, err, http.StatusInternalServerError) {
+		return
+	}
+
+	var resp ScheduledChangeResponse
+	resp.Body.ScheduledChange = c
+	serveJSON(w, r, resp.Body)
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+/*
+Cancel
+swagger:route POST /scheduled-changes/{scheduledChangeID}/cancel schedule cancelScheduledChange
+
+Cancel a scheduled change before its run time.
+
+	Produces:
+	- application/json
+
+	Schemes: http, https
+
+	Security:
+	  AppToken: []
+
+	Responses:
+	  200: scheduledChangeResponse
+	  401: errorResponse
+	  404: errorResponse
+	  409: errorResponse
+	  500: errorResponse
+*/
+func (ctrl ScheduledChangeController) Cancel(w http.ResponseWriter, r *http.Request, id string) {
+	if handleError(w, r, ctrl.UseCases.Scheduler.Cancel(r.Context(), id), http.StatusInternalServerError) {
+		return
+	}
+	ctrl.Show(w, r, id)
+}
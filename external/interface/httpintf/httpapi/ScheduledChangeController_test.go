@@ -0,0 +1,303 @@
+package httpapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adamluzsi/testcase"
+	hs "github.com/adamluzsi/testcase/httpspec"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/schedule"
+	"github.com/toggler-io/toggler/external/interface/httpintf/httpapi"
+	sh "github.com/toggler-io/toggler/spechelper"
+)
+
+func TestScheduledChangeController(t *testing.T) {
+	s := sh.NewSpec(t)
+	s.Parallel()
+
+	hs.Context.Let(s, func(t *testcase.T) interface{} { return sh.ContextGet(t) })
+	hs.HandlerLet(s, func(t *testcase.T) http.Handler { return httpapi.NewScheduledChangeHandler(sh.ExampleUseCases(t)) })
+	hs.ContentTypeIsJSON(s)
+	sh.GivenHTTPRequestHasAppToken(s)
+
+	s.Describe(`POST /scheduled-changes - create`, SpecScheduledChangeControllerCreate)
+	s.Describe(`GET /scheduled-changes - list`, SpecScheduledChangeControllerList)
+	s.Describe(`GET /scheduled-changes/{scheduledChangeID} - show`, SpecScheduledChangeControllerShow)
+	s.Describe(`POST /scheduled-changes/{scheduledChangeID}/cancel - cancel`, SpecScheduledChangeControllerCancel)
+}
+
+// givenWeHaveAScheduledChange schedules the rollout of the example flag to 50% for tomorrow.
+func givenWeHaveAScheduledChange(s *testcase.Spec) testcase.Var {
+	return s.Let(`scheduled change`, func(t *testcase.T) interface{} {
+		c := schedule.Change{
+			Kind:     schedule.KindRollout,
+			Action:   schedule.ActionUpdate,
+			EntityID: sh.ExampleReleaseRollout(t).ID,
+			RunAt:    time.Now().Add(24 * time.Hour),
+			Proposal: json.RawMessage(`{"plan":{"type":"percentage","percentage":50}}`),
+		}
+		require.Nil(t, sh.ExampleUseCases(t).Scheduler.Schedule(ownerContext(t, `scheduler`), &c))
+		return c
+	})
+}
+
+func scheduledChangePathLet(s *testcase.Spec, c testcase.Var, action string) testcase.Var {
+	id := s.Let(`scheduled change id`, func(t *testcase.T) interface{} {
+		return c.Get(t).(schedule.Change).ID
+	})
+	hs.Path.Let(s, func(t *testcase.T) interface{} {
+		return `/scheduled-changes/` + id.Get(t).(string) + action
+	})
+	return id
+}
+
+func thenScheduledChange(t *testcase.T) schedule.Change {
+	rr := hs.ServeHTTP(t)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var resp httpapi.ScheduledChangeResponse
+	IsJsonResponse(t, rr, &resp.Body)
+	return resp.Body.ScheduledChange
+}
+
+func SpecScheduledChangeControllerCreate(s *testcase.Spec) {
+	hs.Method.LetValue(s, http.MethodPost)
+	hs.Path.LetValue(s, `/scheduled-changes`)
+
+	runAt := s.Let(`run at`, func(t *testcase.T) interface{} {
+		return time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	})
+	request := s.Let(`request`, func(t *testcase.T) interface{} {
+		var req httpapi.CreateScheduledChangeRequest
+		req.Body.ScheduledChange.Kind = schedule.KindRollout
+		req.Body.ScheduledChange.Action = schedule.ActionUpdate
+		req.Body.ScheduledChange.EntityID = sh.ExampleReleaseRollout(t).ID
+		req.Body.ScheduledChange.RunAt = runAt.Get(t).(time.Time)
+		req.Body.ScheduledChange.Proposal = json.RawMessage(`{"plan":{"type":"percentage","percentage":50}}`)
+		return &req
+	})
+	requestGet := func(t *testcase.T) *httpapi.CreateScheduledChangeRequest {
+		return request.Get(t).(*httpapi.CreateScheduledChangeRequest)
+	}
+	hs.Body.Let(s, func(t *testcase.T) interface{} { return requestGet(t).Body })
+
+	s.Then(`the change is scheduled for its run time, and the rollout is left as it is`, func(t *testcase.T) {
+		c := thenScheduledChange(t)
+		require.NotEmpty(t, c.ID)
+		require.Equal(t, schedule.StatusScheduled, c.Status)
+		require.Equal(t, sh.ExampleUniqueUserID(t), c.ScheduledBy)
+		require.Equal(t, sh.ExampleDeploymentEnvironment(t).ID, c.EnvironmentID)
+		require.True(t, runAt.Get(t).(time.Time).Equal(c.RunAt))
+
+		// the fields missing from the proposal keep their current value.
+		var proposal release.Rollout
+		require.Nil(t, c.DecodeProposal(&proposal))
+		require.Equal(t, sh.ExampleReleaseFlag(t).ID, proposal.FlagID)
+		require.Equal(t, 50, proposal.Plan.(release.RolloutDecisionByPercentage).Percentage)
+
+		var rollout release.Rollout
+		found, err := sh.StorageGet(t).ReleaseRollout(sh.ContextGet(t)).FindByID(sh.ContextGet(t), &rollout, sh.ExampleReleaseRollout(t).ID)
+		require.Nil(t, err)
+		require.True(t, found)
+		require.Equal(t, sh.ExampleReleaseRollout(t).Plan, rollout.Plan)
+	})
+
+	thenItIsRejected := func(s *testcase.Spec, code int, key, field string) {
+		s.Then(`it is rejected, and nothing is scheduled`, func(t *testcase.T) {
+			resp := thenErrorResponse(t, code, key)
+			require.Equal(t, field, resp.Field)
+
+			changes, err := sh.ExampleUseCases(t).Scheduler.List(sh.ContextGet(t), schedule.ChangeQuery{})
+			require.Nil(t, err)
+			require.Empty(t, changes)
+		})
+	}
+
+	s.When(`the run time is in the past`, func(s *testcase.Spec) {
+		runAt.Let(s, func(t *testcase.T) interface{} { return time.Now().Add(-time.Minute).UTC() })
+
+		thenItIsRejected(s, http.StatusBadRequest, `scheduled_change_run_at_is_invalid`, `run_at`)
+	})
+
+	s.When(`the kind is unknown`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { requestGet(t).Body.ScheduledChange.Kind = `flag` })
+
+		thenItIsRejected(s, http.StatusBadRequest, `scheduled_change_kind_is_invalid`, `kind`)
+	})
+
+	s.When(`the action is unknown`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { requestGet(t).Body.ScheduledChange.Action = `toggle` })
+
+		thenItIsRejected(s, http.StatusBadRequest, `scheduled_change_action_is_invalid`, `action`)
+	})
+
+	s.When(`the proposal of the update is missing`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { requestGet(t).Body.ScheduledChange.Proposal = nil })
+
+		thenItIsRejected(s, http.StatusBadRequest, `scheduled_change_proposal_is_invalid`, `proposal`)
+	})
+
+	s.When(`the proposed rollout plan is invalid`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) {
+			requestGet(t).Body.ScheduledChange.Proposal = json.RawMessage(`{"plan":{"type":"percentage","percentage":101}}`)
+		})
+
+		thenItIsRejected(s, http.StatusBadRequest, `invalid_percentage`, `plan.percentage`)
+	})
+
+	s.When(`the rollout is unknown`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { requestGet(t).Body.ScheduledChange.EntityID = `unknown` })
+
+		thenItIsRejected(s, http.StatusNotFound, `rollout_not_found`, ``)
+	})
+
+	s.When(`the body has an unknown field`, func(s *testcase.Spec) {
+		hs.Body.Let(s, func(t *testcase.T) interface{} {
+			return strings.NewReader(`{"scheduled_change":{"kind":"rollout","when":"tomorrow"}}`)
+		})
+
+		thenItIsRejected(s, http.StatusBadRequest, `bad_request`, ``)
+	})
+
+	s.When(`the request has no valid token`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { hs.HeaderGet(t).Del(`X-App-Token`) })
+
+		thenItIsRejected(s, http.StatusUnauthorized, `invalid_token`, ``)
+	})
+}
+
+func SpecScheduledChangeControllerList(s *testcase.Spec) {
+	hs.Method.LetValue(s, http.MethodGet)
+	hs.Path.LetValue(s, `/scheduled-changes`)
+
+	c := givenWeHaveAScheduledChange(s)
+	sooner := s.Let(`sooner scheduled change`, func(t *testcase.T) interface{} {
+		c := schedule.Change{
+			Kind:     schedule.KindRollout,
+			Action:   schedule.ActionDelete,
+			EntityID: sh.ExampleReleaseRollout(t).ID,
+			RunAt:    time.Now().Add(time.Hour),
+		}
+		require.Nil(t, sh.ExampleUseCases(t).Scheduler.Schedule(ownerContext(t, `scheduler`), &c))
+		return c
+	})
+	s.Before(func(t *testcase.T) {
+		c.Get(t)
+		sooner.Get(t)
+	})
+
+	list := func(t *testcase.T) []schedule.Change {
+		rr := hs.ServeHTTP(t)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var resp httpapi.ListScheduledChangeResponse
+		IsJsonResponse(t, rr, &resp.Body)
+		return resp.Body.ScheduledChanges
+	}
+
+	s.Then(`the scheduled changes are listed in the order of their run time`, func(t *testcase.T) {
+		changes := list(t)
+		require.Len(t, changes, 2)
+		require.Equal(t, sooner.Get(t).(schedule.Change).ID, changes[0].ID)
+		require.Equal(t, c.Get(t).(schedule.Change).ID, changes[1].ID)
+	})
+
+	s.When(`they are filtered by status`, func(s *testcase.Spec) {
+		hs.Query.Let(s, func(t *testcase.T) interface{} { return url.Values{`status`: {schedule.StatusCancelled}} })
+		s.Before(func(t *testcase.T) {
+			require.Nil(t, sh.ExampleUseCases(t).Scheduler.Cancel(ownerContext(t, `scheduler`), c.Get(t).(schedule.Change).ID))
+		})
+
+		s.Then(`only the matching changes are listed`, func(t *testcase.T) {
+			changes := list(t)
+			require.Len(t, changes, 1)
+			require.Equal(t, c.Get(t).(schedule.Change).ID, changes[0].ID)
+		})
+	})
+
+	s.When(`they are filtered by an other environment`, func(s *testcase.Spec) {
+		hs.Query.Let(s, func(t *testcase.T) interface{} { return url.Values{`env_id`: {`unknown`}} })
+
+		s.Then(`nothing is listed`, func(t *testcase.T) {
+			require.Empty(t, list(t))
+		})
+	})
+
+	s.When(`the request has no valid token`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { hs.HeaderGet(t).Del(`X-App-Token`) })
+
+		s.Then(`it is unauthorized`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusUnauthorized, `invalid_token`)
+		})
+	})
+}
+
+func SpecScheduledChangeControllerShow(s *testcase.Spec) {
+	hs.Method.LetValue(s, http.MethodGet)
+	c := givenWeHaveAScheduledChange(s)
+	id := scheduledChangePathLet(s, c, ``)
+
+	s.Then(`the scheduled change is shown`, func(t *testcase.T) {
+		require.Equal(t, c.Get(t).(schedule.Change).ID, thenScheduledChange(t).ID)
+	})
+
+	s.When(`the scheduled change is unknown`, func(s *testcase.Spec) {
+		id.LetValue(s, `unknown`)
+
+		s.Then(`it is not found`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusNotFound, `scheduled_change_not_found`)
+		})
+	})
+}
+
+func SpecScheduledChangeControllerCancel(s *testcase.Spec) {
+	hs.Method.LetValue(s, http.MethodPost)
+	c := givenWeHaveAScheduledChange(s)
+	id := scheduledChangePathLet(s, c, `/cancel`)
+
+	s.Then(`the scheduled change is cancelled by the token owner`, func(t *testcase.T) {
+		cancelled := thenScheduledChange(t)
+		require.Equal(t, schedule.StatusCancelled, cancelled.Status)
+		require.Equal(t, sh.ExampleUniqueUserID(t), cancelled.CancelledBy)
+	})
+
+	s.When(`it is already cancelled`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { thenScheduledChange(t) })
+
+		s.Then(`it is a conflict`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusConflict, `scheduled_change_not_scheduled`)
+		})
+	})
+
+	s.When(`the scheduled change is unknown`, func(s *testcase.Spec) {
+		id.LetValue(s, `unknown`)
+
+		s.Then(`it is not found`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusNotFound, `scheduled_change_not_found`)
+		})
+	})
+
+	s.When(`the method is not POST`, func(s *testcase.Spec) {
+		hs.Method.LetValue(s, http.MethodGet)
+
+		s.Then(`the method is not allowed`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusMethodNotAllowed, `method_not_allowed`)
+		})
+	})
+
+	s.When(`the request has no valid token`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) { hs.HeaderGet(t).Del(`X-App-Token`) })
+
+		s.Then(`it is unauthorized, and the change stays scheduled`, func(t *testcase.T) {
+			thenErrorResponse(t, http.StatusUnauthorized, `invalid_token`)
+			found, err := sh.ExampleUseCases(t).Scheduler.Find(sh.ContextGet(t), c.Get(t).(schedule.Change).ID)
+			require.Nil(t, err)
+			require.True(t, found.IsScheduled())
+		})
+	})
+}
@@ -2,10 +2,13 @@ package httpapi
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
+	"strings"
 
-	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/domains/errs"
+	"github.com/toggler-io/toggler/external/interface/httpintf/httputils"
+	"github.com/toggler-io/toggler/external/logging"
 )
 
 // ErrorResponse will contains a response about request that had some kind of problem.
@@ -21,9 +24,18 @@ type ErrorResponse struct {
 
 // Error contains the details of the error
 type Error struct {
-	// The constant code of the error that can be used for localisation
+	// The HTTP status code of the error
 	// Example: 401
 	Code int `json:"code"`
+	// The stable, machine-readable identifier of the error, which can be used for localisation.
+	// Example: invalid_token
+	Key string `json:"key"`
+	// The kind of the error, when it was caused by the request.
+	// Example: validation
+	Kind string `json:"kind,omitempty"`
+	// The path of the invalid field in the request, in the case of a validation error.
+	// Example: plan.percentage
+	Field string `json:"field,omitempty"`
 	// The message that describe the error to the developer who do the integration.
 	// Not meant to be propagated to the end-user.
 	// The Message may change in the future, it it helps readability,
@@ -31,9 +43,39 @@ type Error struct {
 	Message string `json:"message"`
 }
 
+// NewError describes the error for the requester.
+// The domain errors are presented with their own code and message,
+// the rest of the errors with the status text of the fallbackCode,
+// and with their message only when the fallbackCode blames the request.
+func NewError(err error, fallbackCode int) Error {
+	code := httputils.StatusCode(err, fallbackCode)
+	domainErr, ok := errs.Lookup(err)
+	if !ok {
+		e := Error{Code: code, Key: statusKey(code), Message: http.StatusText(code)}
+		if 400 <= code && code < 500 {
+			e.Message = err.Error()
+		}
+		return e
+	}
+	return Error{
+		Code:    code,
+		Key:     domainErr.Code,
+		Kind:    string(domainErr.Kind),
+		Field:   domainErr.Field,
+		Message: err.Error(),
+	}
+}
+
+// statusKey turns the status text into an error key, such as "method_not_allowed".
+func statusKey(code int) string {
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(code)), ` `, `_`)
+}
+
+// ErrorWriterFunc writes an error which is not a domain error, with the given status code.
 func ErrorWriterFunc(w http.ResponseWriter, error string, code int) {
 	var errResp ErrorResponse
 	errResp.Body.Error.Code = code
+	errResp.Body.Error.Key = statusKey(code)
 
 	if 400 <= code && code < 500 {
 		errResp.Body.Error.Message = error
@@ -41,29 +83,33 @@ func ErrorWriterFunc(w http.ResponseWriter, error string, code int) {
 		errResp.Body.Error.Message = http.StatusText(code)
 	}
 
-	w.WriteHeader(code)
+	writeErrorResponse(w, errResp)
+}
 
-	if err := json.NewEncoder(w).Encode(errResp.Body); err != nil {
-		log.Println(`ERROR`, err.Error())
+// WriteError writes the error with the status code of its kind,
+// or with the fallbackCode if it is not a domain error.
+func WriteError(w http.ResponseWriter, r *http.Request, err error, fallbackCode int) {
+	var errResp ErrorResponse
+	errResp.Body.Error = NewError(err, fallbackCode)
+	if 500 <= errResp.Body.Error.Code {
+		logging.Error(r.Context(), `request failed`, err)
 	}
+	writeErrorResponse(w, errResp)
 }
 
-func handleError(w http.ResponseWriter, err error, errCode int) bool {
-	if err == toggler.ErrInvalidToken {
-		const code = http.StatusUnauthorized
-		ErrorWriterFunc(w, http.StatusText(code), code)
-		return true
-	}
+func writeErrorResponse(w http.ResponseWriter, errResp ErrorResponse) {
+	w.Header().Set(`Content-Type`, `application/json`)
+	w.WriteHeader(errResp.Body.Error.Code)
 
-	if err != nil && err.Error() == `ErrNotFound` { // TODO: fix code smell
-		ErrorWriterFunc(w, `not found`, http.StatusBadRequest)
-		return true
+	if err := json.NewEncoder(w).Encode(errResp.Body); err != nil {
+		slog.Error(`unable to write the error response`, slog.String(`error`, err.Error()))
 	}
+}
 
-	if err != nil {
-		ErrorWriterFunc(w, err.Error(), errCode)
-		return true
+func handleError(w http.ResponseWriter, r *http.Request, err error, errCode int) bool {
+	if err == nil {
+		return false
 	}
-
-	return false
+	WriteError(w, r, err, errCode)
+	return true
 }
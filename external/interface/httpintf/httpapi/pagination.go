@@ -0,0 +1,73 @@
+package httpapi
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/toggler-io/toggler/domains/errs"
+	"github.com/toggler-io/toggler/domains/release"
+)
+
+var ErrInvalidSortOrder = errs.Validation(`invalid_sort_order`, `order`, `sort order must be either asc or desc`)
+
+// PaginationRequest holds the query parameters shared across the list endpoints.
+type PaginationRequest struct {
+	// Cursor is the next_cursor value of the previous page.
+	// When omitted the first page is returned.
+	//
+	// in: query
+	Cursor string `json:"cursor"`
+	// Limit is the maximum number of entries in a page.
+	//
+	// in: query
+	// minimum: 1
+	// maximum: 1000
+	// default: 50
+	Limit int `json:"limit"`
+	// Sort is the name of the field the entries ordered by.
+	//
+	// in: query
+	// default: id
+	Sort string `json:"sort"`
+	// Order is the direction of the ordering.
+	//
+	// in: query
+	// enum: asc,desc
+	// default: asc
+	Order string `json:"order"`
+}
+
+func parsePagination(q url.Values) (release.Pagination, error) {
+	var p release.Pagination
+	p.Cursor = q.Get(`cursor`)
+	p.SortBy = q.Get(`sort`)
+
+	if raw := q.Get(`limit`); raw != `` {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return p, release.ErrInvalidPageLimit
+		}
+		p.Limit = limit
+	}
+
+	switch q.Get(`order`) {
+	case ``, `asc`:
+	case `desc`:
+		p.Descending = true
+	default:
+		return p, ErrInvalidSortOrder
+	}
+
+	return p, nil
+}
+
+func parseOptionalBool(raw string) (*bool, error) {
+	if raw == `` {
+		return nil, nil
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
@@ -0,0 +1,35 @@
+package httpapi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/toggler-io/toggler/domains/release"
+)
+
+// setETag exposes the revision of the entity as its entity tag.
+func setETag(w http.ResponseWriter, revision int) {
+	w.Header().Set(`ETag`, strconv.Quote(strconv.Itoa(revision)))
+}
+
+// ifMatchRevision returns the revision which the update of the client is based on, taken from the If-Match header.
+// The header is required for the updates, so a client can't overwrite a change it has not seen by mistake.
+// The "*" entity tag opts out of the check, and updates the entity whatever its revision is.
+func ifMatchRevision(w http.ResponseWriter, r *http.Request) (int, bool) {
+	ifMatch := strings.TrimSpace(r.Header.Get(`If-Match`))
+	if ifMatch == `` {
+		ErrorWriterFunc(w, `the If-Match header with the ETag of the entity is required`, http.StatusPreconditionRequired)
+		return 0, false
+	}
+	if ifMatch == `*` {
+		return 0, true
+	}
+
+	revision, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(ifMatch, `W/`), `"`))
+	if err != nil || revision < 1 {
+		ErrorWriterFunc(w, release.ErrRevisionConflict.Error(), http.StatusPreconditionFailed)
+		return 0, false
+	}
+	return revision, true
+}
@@ -3,7 +3,6 @@ package httpapi
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"github.com/toggler-io/toggler/domains/release"
 	"net/http"
 
@@ -104,22 +103,22 @@ func (ctrl ViewsController) GetPilotConfig(w http.ResponseWriter, r *http.Reques
 
 	var env release.Environment
 	found, err := ctrl.UseCases.Storage.ReleaseEnvironment(ctx).FindByAlias(ctx, request.Body.DeploymentEnvironmentAlias, &env)
-	if handleError(w, err, http.StatusInternalServerError) {
+	if handleError(w, r, err, http.StatusInternalServerError) {
 		return
 	}
 
 	if !found {
-		handleError(w, fmt.Errorf(`not-found`), http.StatusNotFound)
+		handleError(w, r, release.ErrEnvironmentNotFound, http.StatusNotFound)
 		return
 	}
 
 	states, err := ctrl.UseCases.RolloutManager.GetAllReleaseFlagStatesOfThePilot(ctx, request.Body.PilotExtID, env, request.Body.ReleaseFlags...)
 
-	if handleError(w, err, http.StatusInternalServerError) {
+	if handleError(w, r, err, http.StatusInternalServerError) {
 		return
 	}
 
 	var resp GetPilotConfigResponse
 	resp.Body.Release.Flags = states
-	serveJSON(w, resp.Body)
+	serveJSON(w, r, resp.Body)
 }
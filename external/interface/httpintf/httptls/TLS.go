@@ -0,0 +1,223 @@
+// Package httptls serves the http interfaces over TLS,
+// with certificates that are reloaded when they are rotated on the disk,
+// and with an optional verification of the client certificates (mTLS).
+package httptls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/toggler-io/toggler/external/logging"
+)
+
+const (
+	// ClientAuthNone doesn't ask for client certificates.
+	ClientAuthNone = `none`
+	// ClientAuthOptional verifies the client certificates when they are given.
+	ClientAuthOptional = `optional`
+	// ClientAuthRequire rejects the connections without a valid client certificate.
+	ClientAuthRequire = `require`
+)
+
+// DefaultWatchInterval is how often the certificate files are checked for changes by default.
+const DefaultWatchInterval = time.Minute
+
+type Config struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile is the PEM bundle of the certificate authorities which issue the client certificates.
+	// The client certificates are only verified when it is given.
+	ClientCAFile string
+	// ClientAuth is one of ClientAuthNone, ClientAuthOptional or ClientAuthRequire.
+	// By default it is ClientAuthOptional when the ClientCAFile is given, else ClientAuthNone.
+	ClientAuth string
+}
+
+func (c Config) Validate() error {
+	if c.CertFile == `` || c.KeyFile == `` {
+		return errors.New(`both the certificate and the key file are required`)
+	}
+	switch c.ClientAuth {
+	case ``, ClientAuthNone:
+	case ClientAuthOptional, ClientAuthRequire:
+		if c.ClientCAFile == `` {
+			return fmt.Errorf(`client auth %q requires the client CA file`, c.ClientAuth)
+		}
+	default:
+		return fmt.Errorf(`unknown client auth: %s`, c.ClientAuth)
+	}
+	return nil
+}
+
+func (c Config) clientAuth() tls.ClientAuthType {
+	switch c.ClientAuth {
+	case ClientAuthRequire:
+		return tls.RequireAndVerifyClientCert
+	case ClientAuthNone:
+		return tls.NoClientCert
+	}
+	if c.ClientCAFile != `` {
+		return tls.VerifyClientCertIfGiven
+	}
+	return tls.NoClientCert
+}
+
+func (c Config) files() []string {
+	files := []string{c.CertFile, c.KeyFile}
+	if c.ClientCAFile != `` {
+		files = append(files, c.ClientCAFile)
+	}
+	return files
+}
+
+// New loads the certificates, and reloads them when their files change.
+// A zero watch interval disables the reloading.
+func New(c Config, watchInterval time.Duration) (*Certificates, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	certs := &Certificates{Config: c}
+	if err := certs.Reload(); err != nil {
+		return nil, err
+	}
+	if 0 < watchInterval {
+		ctx, signaler := context.WithCancel(context.Background())
+		certs.exit.signaler = signaler
+		certs.exit.wg.Add(1)
+		go certs.watch(ctx, watchInterval)
+	}
+	return certs, nil
+}
+
+// Certificates holds the server certificate and the client certificate authorities loaded from the files.
+type Certificates struct {
+	Config Config
+
+	mutex       sync.RWMutex
+	config      *tls.Config
+	fingerprint string
+
+	exit struct {
+		signaler func()
+		wg       sync.WaitGroup
+	}
+}
+
+// TLSConfig returns the config for the http.Server.
+// Each new connection uses the certificates which are loaded at the time.
+func (c *Certificates) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return c.current(), nil
+		},
+	}
+}
+
+// Reload reads the certificate files again.
+// When the files are invalid, for example in the middle of a rotation, the previous certificates are kept.
+func (c *Certificates) Reload() error {
+	fingerprint, err := fingerprint(c.Config.files())
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.Config.CertFile, c.Config.KeyFile)
+	if err != nil {
+		return err
+	}
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   c.Config.clientAuth(),
+	}
+	if c.Config.ClientCAFile != `` {
+		pem, err := ioutil.ReadFile(c.Config.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf(`%s: no certificate found`, c.Config.ClientCAFile)
+		}
+	}
+
+	c.mutex.Lock()
+	c.config = config
+	c.fingerprint = fingerprint
+	c.mutex.Unlock()
+	return nil
+}
+
+func (c *Certificates) watch(ctx context.Context, interval time.Duration) {
+	defer c.exit.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastSeen = c.currentFingerprint()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		fingerprint, err := fingerprint(c.Config.files())
+		if err != nil {
+			logging.Error(ctx, `unable to read the certificate files`, err)
+			continue
+		}
+		if fingerprint == lastSeen {
+			continue
+		}
+		lastSeen = fingerprint
+		if err := c.Reload(); err != nil {
+			logging.Error(ctx, `unable to reload the certificates`, err)
+			continue
+		}
+		slog.Info(`certificates reloaded`)
+	}
+}
+
+func (c *Certificates) current() *tls.Config {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.config
+}
+
+func (c *Certificates) currentFingerprint() string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.fingerprint
+}
+
+func (c *Certificates) Close() error {
+	if c.exit.signaler != nil {
+		c.exit.signaler()
+		c.exit.wg.Wait()
+	}
+	return nil
+}
+
+// fingerprint describes the current version of the files with their size and modification time.
+// The files are checked with os.Stat, so the symlinks of a mounted secret are followed.
+func fingerprint(files []string) (string, error) {
+	var fingerprint strings.Builder
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return ``, err
+		}
+		_, _ = fmt.Fprintf(&fingerprint, "%s:%d:%d\n", file, info.Size(), info.ModTime().UnixNano())
+	}
+	return fingerprint.String(), nil
+}
@@ -0,0 +1,171 @@
+package httptls_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/external/interface/httpintf/httptls"
+)
+
+func TestCertificates(t *testing.T) {
+	s := testcase.NewSpec(t)
+	defer s.Finish()
+
+	dir := s.Let(`dir`, func(t *testcase.T) interface{} { return t.TempDir() })
+	ca := s.Let(`ca`, func(t *testcase.T) interface{} { return newCA(t) })
+	clientAuth := s.LetValue(`client auth`, ``)
+	config := s.Let(`config`, func(t *testcase.T) interface{} {
+		c := httptls.Config{
+			CertFile:   filepath.Join(dir.Get(t).(string), `tls.crt`),
+			KeyFile:    filepath.Join(dir.Get(t).(string), `tls.key`),
+			ClientAuth: clientAuth.Get(t).(string),
+		}
+		writeKeyPair(t, ca.Get(t).(*authority).issue(t, `server-1`, false), c.CertFile, c.KeyFile)
+		if c.ClientAuth != `` {
+			c.ClientCAFile = filepath.Join(dir.Get(t).(string), `ca.crt`)
+			require.Nil(t, ioutil.WriteFile(c.ClientCAFile, ca.Get(t).(*authority).certPEM(), 0600))
+		}
+		return c
+	})
+	certs := s.Let(`certs`, func(t *testcase.T) interface{} {
+		certs, err := httptls.New(config.Get(t).(httptls.Config), 0)
+		require.Nil(t, err)
+		t.Defer(certs.Close)
+		return certs
+	})
+	server := func(t *testcase.T) *httptest.Server {
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		srv.TLS = certs.Get(t).(*httptls.Certificates).TLSConfig()
+		srv.StartTLS()
+		t.Defer(srv.Close)
+		return srv
+	}
+	client := func(t *testcase.T, cert *tls.Certificate) *http.Client {
+		c := &tls.Config{RootCAs: ca.Get(t).(*authority).pool()}
+		if cert != nil {
+			c.Certificates = []tls.Certificate{*cert}
+		}
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: c}}
+	}
+	servedCommonName := func(t *testcase.T, srv *httptest.Server) string {
+		resp, err := client(t, nil).Get(srv.URL)
+		require.Nil(t, err)
+		defer resp.Body.Close()
+		return resp.TLS.PeerCertificates[0].Subject.CommonName
+	}
+
+	s.Then(`the certificate is served`, func(t *testcase.T) {
+		require.Equal(t, `server-1`, servedCommonName(t, server(t)))
+	})
+
+	s.Then(`the rotated certificate is served after the reload`, func(t *testcase.T) {
+		srv := server(t)
+		c := config.Get(t).(httptls.Config)
+		writeKeyPair(t, ca.Get(t).(*authority).issue(t, `server-2`, false), c.CertFile, c.KeyFile)
+		require.Nil(t, certs.Get(t).(*httptls.Certificates).Reload())
+		require.Equal(t, `server-2`, servedCommonName(t, srv))
+	})
+
+	s.When(`the client certificates are required`, func(s *testcase.Spec) {
+		clientAuth.LetValue(s, httptls.ClientAuthRequire)
+
+		s.Then(`the clients without certificate are rejected`, func(t *testcase.T) {
+			_, err := client(t, nil).Get(server(t).URL)
+			require.Error(t, err)
+		})
+
+		s.Then(`the clients with a certificate of the authority are accepted`, func(t *testcase.T) {
+			cert := ca.Get(t).(*authority).issue(t, `deployer`, true)
+			resp, err := client(t, &cert).Get(server(t).URL)
+			require.Nil(t, err)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			require.Nil(t, resp.Body.Close())
+		})
+	})
+
+	s.Then(`the client auth without client CA is invalid`, func(t *testcase.T) {
+		c := config.Get(t).(httptls.Config)
+		c.ClientAuth = httptls.ClientAuthOptional
+		_, err := httptls.New(c, 0)
+		require.Error(t, err)
+	})
+}
+
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newCA(t *testcase.T) *authority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: `test-ca`},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.Nil(t, err)
+	return &authority{cert: cert, key: key}
+}
+
+func (a *authority) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(a.cert)
+	return pool
+}
+
+func (a *authority) certPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: `CERTIFICATE`, Bytes: a.cert.Raw})
+}
+
+func (a *authority) issue(t *testcase.T, commonName string, client bool) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	usage := x509.ExtKeyUsageServerAuth
+	if client {
+		usage = x509.ExtKeyUsageClientAuth
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{`localhost`},
+		IPAddresses:  []net.IP{net.ParseIP(`127.0.0.1`)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, a.cert, &key.PublicKey, a.key)
+	require.Nil(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func writeKeyPair(t *testcase.T, cert tls.Certificate, certFile, keyFile string) {
+	keyDER, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	require.Nil(t, err)
+	require.Nil(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: `CERTIFICATE`, Bytes: cert.Certificate[0]}), 0600))
+	require.Nil(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: `EC PRIVATE KEY`, Bytes: keyDER}), 0600))
+}
@@ -0,0 +1,31 @@
+package httputils
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// BodyLimit limits the size of the request bodies.
+// The limit can be changed while the handler is serving, like when the configuration is reloaded.
+// The zero value has no limit.
+type BodyLimit struct {
+	maxBytes int64
+}
+
+// SetMaxBytes sets the limit, zero means no limit.
+func (l *BodyLimit) SetMaxBytes(n int64) {
+	atomic.StoreInt64(&l.maxBytes, n)
+}
+
+func (l *BodyLimit) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n := atomic.LoadInt64(&l.maxBytes); 0 < n && r.Body != nil {
+			if n < r.ContentLength {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
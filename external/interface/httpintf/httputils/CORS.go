@@ -1,12 +1,49 @@
 package httputils
 
-import "net/http"
+import (
+	"net/http"
+	"sync/atomic"
+)
 
+// CORS allows every origin to call the handler.
 func CORS(next http.Handler) http.Handler {
+	return (&CORSPolicy{}).Handler(next)
+}
+
+// CORSPolicy controls which origins can call the handlers from a browser.
+// The allowed origins can be changed while the handlers are serving, like when the configuration is reloaded.
+// The zero value allows every origin.
+type CORSPolicy struct {
+	origins atomic.Value // map[string]struct{}
+}
+
+// SetAllowedOrigins replaces the allowed origins, like "https://example.com".
+// An empty list or the "*" origin allows every origin.
+func (p *CORSPolicy) SetAllowedOrigins(origins []string) {
+	allowed := make(map[string]struct{}, len(origins))
+	for _, o := range origins {
+		if o == `*` {
+			allowed = map[string]struct{}{}
+			break
+		}
+		allowed[o] = struct{}{}
+	}
+	p.origins.Store(allowed)
+}
+
+func (p *CORSPolicy) Handler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowed, _ := p.origins.Load().(map[string]struct{})
+		if len(allowed) == 0 {
+			w.Header().Set(`Access-Control-Allow-Origin`, `*`)
+		} else {
+			w.Header().Add(`Vary`, `Origin`)
+			if _, ok := allowed[r.Header.Get(`Origin`)]; ok {
+				w.Header().Set(`Access-Control-Allow-Origin`, r.Header.Get(`Origin`))
+			}
+		}
 		w.Header().Set(`Access-Control-Allow-Methods`, `*`)
 		w.Header().Set(`Access-Control-Allow-Headers`, `*`)
-		w.Header().Set(`Access-Control-Allow-Origin`, `*`)
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(200)
 			return
@@ -0,0 +1,50 @@
+package httputils_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/external/interface/httpintf/httputils"
+)
+
+func TestCORSPolicy(t *testing.T) {
+	s := testcase.NewSpec(t)
+	defer s.Finish()
+
+	policy := s.Let(`policy`, func(t *testcase.T) interface{} { return &httputils.CORSPolicy{} })
+	origin := s.LetValue(`origin`, `https://example.com`)
+	subject := func(t *testcase.T) *httptest.ResponseRecorder {
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		r := httptest.NewRequest(http.MethodGet, `/`, nil)
+		r.Header.Set(`Origin`, origin.Get(t).(string))
+		w := httptest.NewRecorder()
+		policy.Get(t).(*httputils.CORSPolicy).Handler(next).ServeHTTP(w, r)
+		return w
+	}
+
+	s.Then(`every origin is allowed by default`, func(t *testcase.T) {
+		require.Equal(t, `*`, subject(t).Header().Get(`Access-Control-Allow-Origin`))
+	})
+
+	s.When(`the allowed origins are set`, func(s *testcase.Spec) {
+		s.Before(func(t *testcase.T) {
+			policy.Get(t).(*httputils.CORSPolicy).SetAllowedOrigins([]string{`https://example.com`})
+		})
+
+		s.Then(`the allowed origin is reflected`, func(t *testcase.T) {
+			require.Equal(t, `https://example.com`, subject(t).Header().Get(`Access-Control-Allow-Origin`))
+		})
+
+		s.And(`the request comes from an other origin`, func(s *testcase.Spec) {
+			origin.LetValue(s, `https://evil.example.org`)
+
+			s.Then(`the origin is not allowed`, func(t *testcase.T) {
+				require.Empty(t, subject(t).Header().Get(`Access-Control-Allow-Origin`))
+			})
+		})
+	})
+}
@@ -0,0 +1,59 @@
+package httputils
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+)
+
+// ClientCertificateIdentities maps the subjects of the verified client certificates to token owners,
+// so a client with a certificate can call the API without a token, as the owner of the mapped identity.
+// The mapping can be changed while the handlers are serving, like when the configuration is reloaded.
+type ClientCertificateIdentities struct {
+	identities atomic.Value // map[string]string
+}
+
+// SetIdentities replaces the mapping.
+// The keys are either the whole subject, like "CN=deployer,O=Acme", or just the common name, like "deployer".
+func (ids *ClientCertificateIdentities) SetIdentities(identities map[string]string) {
+	m := make(map[string]string, len(identities))
+	for subject, owner := range identities {
+		m[subject] = owner
+	}
+	ids.identities.Store(m)
+}
+
+// Handler adds the identity of the client certificate to the request context.
+// Only the certificates verified during the TLS handshake are considered.
+func (ids *ClientCertificateIdentities) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if owner, ok := ids.lookup(r); ok {
+			r = r.WithContext(context.WithValue(r.Context(), clientIdentityKey{}, owner))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (ids *ClientCertificateIdentities) lookup(r *http.Request) (string, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return ``, false
+	}
+	identities, _ := ids.identities.Load().(map[string]string)
+	subject := r.TLS.VerifiedChains[0][0].Subject
+	if owner, ok := identities[subject.String()]; ok {
+		return owner, true
+	}
+	if subject.CommonName == `` {
+		return ``, false
+	}
+	owner, ok := identities[subject.CommonName]
+	return owner, ok
+}
+
+type clientIdentityKey struct{}
+
+// LookupClientIdentity returns the token owner which is mapped to the client certificate of the request.
+func LookupClientIdentity(ctx context.Context) (ownerUID string, ok bool) {
+	ownerUID, ok = ctx.Value(clientIdentityKey{}).(string)
+	return ownerUID, ok
+}
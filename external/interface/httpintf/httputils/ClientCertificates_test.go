@@ -0,0 +1,77 @@
+package httputils_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/external/interface/httpintf/httputils"
+)
+
+func TestClientCertificateIdentities(t *testing.T) {
+	s := testcase.NewSpec(t)
+	defer s.Finish()
+
+	identities := s.Let(`identities`, func(t *testcase.T) interface{} {
+		return map[string]string{`CN=deployer,O=Acme`: `deployer-bot`, `auditor`: `audit-bot`}
+	})
+	commonName := s.LetValue(`common name`, `deployer`)
+	verified := s.LetValue(`verified`, true)
+	subject := func(t *testcase.T) (string, bool) {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName.Get(t).(string), Organization: []string{`Acme`}}}
+		r := httptest.NewRequest(http.MethodGet, `/`, nil)
+		r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+		if verified.Get(t).(bool) {
+			r.TLS.VerifiedChains = [][]*x509.Certificate{{cert}}
+		}
+
+		var ids httputils.ClientCertificateIdentities
+		ids.SetIdentities(identities.Get(t).(map[string]string))
+		var owner string
+		var ok bool
+		ids.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			owner, ok = httputils.LookupClientIdentity(r.Context())
+		})).ServeHTTP(httptest.NewRecorder(), r)
+		return owner, ok
+	}
+
+	s.Then(`the identity is looked up by the whole subject`, func(t *testcase.T) {
+		owner, ok := subject(t)
+		require.True(t, ok)
+		require.Equal(t, `deployer-bot`, owner)
+	})
+
+	s.When(`only the common name is mapped`, func(s *testcase.Spec) {
+		commonName.LetValue(s, `auditor`)
+
+		s.Then(`the identity is looked up by the common name`, func(t *testcase.T) {
+			owner, ok := subject(t)
+			require.True(t, ok)
+			require.Equal(t, `audit-bot`, owner)
+		})
+	})
+
+	s.When(`the subject is not mapped`, func(s *testcase.Spec) {
+		commonName.LetValue(s, `stranger`)
+
+		s.Then(`the request has no identity`, func(t *testcase.T) {
+			_, ok := subject(t)
+			require.False(t, ok)
+		})
+	})
+
+	s.When(`the certificate is not verified`, func(s *testcase.Spec) {
+		verified.LetValue(s, false)
+
+		s.Then(`the request has no identity`, func(t *testcase.T) {
+			_, ok := subject(t)
+			require.False(t, ok)
+		})
+	})
+}
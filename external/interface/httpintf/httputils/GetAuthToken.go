@@ -1,10 +1,9 @@
 package httputils
 
 import (
-	"log"
 	"net/http"
+	"strings"
 
-	"github.com/toggler-io/toggler/domains/toggler"
 	"github.com/toggler-io/toggler/external/interface/httpintf/webgui/cookies"
 )
 
@@ -12,6 +11,11 @@ func GetAppToken(r *http.Request) (string, error) {
 	var token string
 	token = r.Header.Get(`X-App-Token`)
 
+	if token == `` {
+		// SDKs of other feature flag services send their API token in the Authorization header.
+		token = strings.TrimPrefix(r.Header.Get(`Authorization`), `Bearer `)
+	}
+
 	if token == `` {
 		token = r.URL.Query().Get(`token`)
 	}
@@ -30,17 +34,10 @@ func GetAppToken(r *http.Request) (string, error) {
 	return token, nil
 }
 
-func HandleError(w http.ResponseWriter, err error, errCode int) (errorWasHandled bool) {
-	if err != nil {
-		log.Println("ERROR", err.Error())
-	}
-	if err == toggler.ErrInvalidToken {
-		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
-		return true
-	}
-	if err != nil {
-		http.Error(w, http.StatusText(errCode), errCode)
-		return true
+func HandleError(w http.ResponseWriter, r *http.Request, err error, errCode int) (errorWasHandled bool) {
+	if err == nil {
+		return false
 	}
-	return false
+	WriteError(w, r, err, errCode)
+	return true
 }
@@ -1,38 +1,47 @@
 package httputils
 
 import (
+	"log/slog"
 	"net/http"
 
+	"github.com/toggler-io/toggler/domains/security"
 	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/external/logging"
 )
 
 type ErrorWriterFunc func(w http.ResponseWriter, error string, code int)
 
-func AuthMiddleware(next http.Handler, uc *toggler.UseCases, errorWriterFunc ErrorWriterFunc) http.Handler {
+func AuthMiddleware(next http.Handler, uc *toggler.UseCases, handleError ErrorHandlerFunc) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
+		// a verified client certificate which is mapped to an identity is accepted in place of a token.
+		if owner, ok := LookupClientIdentity(r.Context()); ok {
+			logging.With(r.Context(), slog.String(`token_owner`, owner))
+			next.ServeHTTP(w, r.WithContext(security.ContextWithOwner(r.Context(), owner)))
+			return
+		}
+
 		token, err := GetAppToken(r)
 
 		if err != nil {
-			errorWriterFunc(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			handleError(w, r, toggler.ErrInvalidToken, http.StatusUnauthorized)
 			return
 		}
 
-		valid, err := uc.Doorkeeper.VerifyTextToken(r.Context(), token)
+		t, valid, err := uc.Doorkeeper.LookupTextToken(r.Context(), token)
 
 		if err != nil {
-			code := http.StatusInternalServerError
-			http.Error(w, http.StatusText(code), code)
+			handleError(w, r, err, http.StatusInternalServerError)
 			return
 		}
 
 		if !valid {
-			code := http.StatusUnauthorized
-			http.Error(w, http.StatusText(code), code)
+			handleError(w, r, toggler.ErrInvalidToken, http.StatusUnauthorized)
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		logging.With(r.Context(), slog.String(`token_owner`, t.OwnerUID))
+		next.ServeHTTP(w, r.WithContext(security.ContextWithOwner(r.Context(), t.OwnerUID)))
 
 	})
 }
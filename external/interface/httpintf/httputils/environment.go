@@ -0,0 +1,16 @@
+package httputils
+
+import "net/http"
+
+// EnvironmentHeader is the request header that selects the deployment environment by ID or by name,
+// for the client protocols that don't have a notion of environment on their own.
+const EnvironmentHeader = `X-Environment`
+
+// GetEnvironmentAlias returns the ID or the name of the deployment environment the request is made for.
+// The header takes precedence over the "environment" query parameter.
+func GetEnvironmentAlias(r *http.Request) string {
+	if alias := r.Header.Get(EnvironmentHeader); alias != `` {
+		return alias
+	}
+	return r.URL.Query().Get(`environment`)
+}
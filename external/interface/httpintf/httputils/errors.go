@@ -0,0 +1,54 @@
+package httputils
+
+import (
+	"net/http"
+
+	"github.com/toggler-io/toggler/domains/errs"
+	"github.com/toggler-io/toggler/external/logging"
+)
+
+// ErrorHandlerFunc writes the response of a failed request in the format of the interface.
+type ErrorHandlerFunc func(w http.ResponseWriter, r *http.Request, err error, fallbackCode int)
+
+// StatusCode maps the kind of the domain error to its HTTP status code.
+// The fallbackCode is returned for the errors which are not domain errors.
+func StatusCode(err error, fallbackCode int) int {
+	domainErr, ok := errs.Lookup(err)
+	if !ok {
+		return fallbackCode
+	}
+	switch domainErr.Kind {
+	case errs.KindValidation:
+		return http.StatusBadRequest
+	case errs.KindNotFound:
+		return http.StatusNotFound
+	case errs.KindConflict:
+		return http.StatusConflict
+	case errs.KindPreconditionFailed:
+		return http.StatusPreconditionFailed
+	case errs.KindUnauthorized:
+		return http.StatusUnauthorized
+	case errs.KindForbidden:
+		return http.StatusForbidden
+	case errs.KindUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return fallbackCode
+	}
+}
+
+// WriteError writes the error as plain text.
+// Only the message of the domain errors are shared with the requester,
+// the rest is replaced with the status text.
+// The server errors are logged with the logger of the request.
+func WriteError(w http.ResponseWriter, r *http.Request, err error, fallbackCode int) {
+	code := StatusCode(err, fallbackCode)
+	if 500 <= code {
+		logging.Error(r.Context(), `request failed`, err)
+	}
+	if _, ok := errs.Lookup(err); ok {
+		http.Error(w, err.Error(), code)
+		return
+	}
+	http.Error(w, http.StatusText(code), code)
+}
@@ -0,0 +1,63 @@
+package httputils_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/errs"
+	"github.com/toggler-io/toggler/external/interface/httpintf/httputils"
+)
+
+func TestStatusCode(t *testing.T) {
+	s := testcase.NewSpec(t)
+	defer s.Finish()
+
+	const fallbackCode = http.StatusTeapot
+	var err = testcase.Var{Name: `err`}
+	subject := func(t *testcase.T) int {
+		return httputils.StatusCode(err.Get(t).(error), fallbackCode)
+	}
+
+	s.When(`the error is not a domain error`, func(s *testcase.Spec) {
+		err.Let(s, func(t *testcase.T) interface{} { return errors.New(`boom`) })
+
+		s.Then(`it returns the fallback code`, func(t *testcase.T) {
+			require.Equal(t, fallbackCode, subject(t))
+		})
+	})
+
+	for kind, code := range map[errs.Kind]int{
+		errs.KindValidation:         http.StatusBadRequest,
+		errs.KindNotFound:           http.StatusNotFound,
+		errs.KindConflict:           http.StatusConflict,
+		errs.KindPreconditionFailed: http.StatusPreconditionFailed,
+		errs.KindUnauthorized:       http.StatusUnauthorized,
+		errs.KindForbidden:          http.StatusForbidden,
+		errs.KindUnavailable:        http.StatusServiceUnavailable,
+	} {
+		kind, code := kind, code
+
+		s.When(fmt.Sprintf(`the error is a %s domain error`, kind), func(s *testcase.Spec) {
+			err.Let(s, func(t *testcase.T) interface{} { return errs.Error{Kind: kind, Code: `code`} })
+
+			s.Then(`it returns the status code of the kind`, func(t *testcase.T) {
+				require.Equal(t, code, subject(t))
+			})
+
+			s.And(`it is wrapped`, func(s *testcase.Spec) {
+				err.Let(s, func(t *testcase.T) interface{} {
+					return fmt.Errorf(`details: %w`, errs.Error{Kind: kind, Code: `code`})
+				})
+
+				s.Then(`it still returns the status code of the kind`, func(t *testcase.T) {
+					require.Equal(t, code, subject(t))
+				})
+			})
+		})
+	}
+}
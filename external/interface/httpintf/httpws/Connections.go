@@ -0,0 +1,45 @@
+package httpws
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/toggler-io/toggler/domains/errs"
+)
+
+// ErrNoCapacity is sent back when the server can't take more websocket connections.
+var ErrNoCapacity = errs.Unavailable(`websocket_capacity_reached`, `the server can't take more websocket connections`)
+
+// Connections limits the number of the open websocket connections of the server instance,
+// so the load can be spread across the instances.
+type Connections struct {
+	// Max is the number of the connections the server takes at most.
+	// Zero means no limit.
+	Max    int64
+	active int64
+}
+
+// Active returns the number of the open connections.
+func (c *Connections) Active() int64 {
+	return atomic.LoadInt64(&c.active)
+}
+
+// Check reports the server as unavailable for new websocket connections when it has no capacity left.
+func (c *Connections) Check(ctx context.Context) error {
+	if 0 < c.Max && c.Max <= c.Active() {
+		return ErrNoCapacity
+	}
+	return nil
+}
+
+func (c *Connections) acquire() bool {
+	if atomic.AddInt64(&c.active, 1) <= c.Max || c.Max <= 0 {
+		return true
+	}
+	atomic.AddInt64(&c.active, -1)
+	return false
+}
+
+func (c *Connections) release() {
+	atomic.AddInt64(&c.active, -1)
+}
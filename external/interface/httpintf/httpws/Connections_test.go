@@ -0,0 +1,38 @@
+package httpws_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/external/interface/httpintf/httpws"
+)
+
+func TestConnections(t *testing.T) {
+	connections := &httpws.Connections{Max: 1}
+	ctrl := httpws.Controller{Upgrader: &websocket.Upgrader{}, Connections: connections}
+	server := httptest.NewServer(http.HandlerFunc(ctrl.WebsocketHandler))
+	defer server.Close()
+	url := `ws` + strings.TrimPrefix(server.URL, `http`)
+
+	require.Nil(t, connections.Check(context.Background()))
+
+	c, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.Nil(t, err)
+	require.Equal(t, int64(1), connections.Active())
+	require.Equal(t, httpws.ErrNoCapacity, connections.Check(context.Background()))
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	require.Equal(t, websocket.ErrBadHandshake, err)
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	require.Nil(t, c.Close())
+	require.Eventually(t, func() bool { return connections.Active() == 0 }, time.Second, 10*time.Millisecond)
+	require.Nil(t, connections.Check(context.Background()))
+}
@@ -6,18 +6,26 @@ import (
 	"github.com/gorilla/websocket"
 
 	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/external/interface/httpintf/httpapi"
 	"github.com/toggler-io/toggler/external/interface/httpintf/httputils"
 )
 
-func NewHandler(uc *toggler.UseCases) http.Handler {
+// DefaultMaxConnections is the number of the websocket connections a server instance takes by default.
+const DefaultMaxConnections = 1024
+
+// NewHandler serves the websocket API.
+// The connections limit the number of the open websocket connections,
+// and they can be shared with the health checks to report the capacity of the instance.
+func NewHandler(uc *toggler.UseCases, connections *Connections) http.Handler {
 	mux := http.NewServeMux()
 
 	ctrl := Controller{
-		UseCases: uc,
-		Upgrader: &websocket.Upgrader{},
+		UseCases:    uc,
+		Upgrader:    &websocket.Upgrader{},
+		Connections: connections,
 	}
 
-	mux.Handle(`/`, httputils.AuthMiddleware(http.HandlerFunc(ctrl.WebsocketHandler), uc, http.Error))
+	mux.Handle(`/`, httputils.AuthMiddleware(http.HandlerFunc(ctrl.WebsocketHandler), uc, httpapi.WriteError))
 
 	return mux
 }
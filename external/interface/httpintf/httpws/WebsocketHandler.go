@@ -4,15 +4,19 @@ import (
 	"net/http"
 
 	"github.com/gorilla/websocket"
-	"github.com/pkg/errors"
 
+	"github.com/toggler-io/toggler/domains/errs"
 	"github.com/toggler-io/toggler/domains/toggler"
 	"github.com/toggler-io/toggler/external/interface/httpintf/httpapi"
 )
 
+// ErrUnknownOperation is sent back when the operation of the request is not supported.
+var ErrUnknownOperation = errs.NotFound(`unknown_operation`, `the requested operation is not supported`)
+
 type Controller struct {
-	UseCases *toggler.UseCases
-	Upgrader *websocket.Upgrader
+	UseCases    *toggler.UseCases
+	Upgrader    *websocket.Upgrader
+	Connections *Connections
 }
 
 // WebsocketRequestPayload is the payload that is expected to be received in the websocket connection.
@@ -79,7 +83,11 @@ type WSLoadBalanceErrResp httpapi.ErrorResponse
 
 */
 func (ctrl *Controller) WebsocketHandler(w http.ResponseWriter, r *http.Request) {
-	//TODO: 503 Service Unavailable for rand based load balancing
+	if !ctrl.Connections.acquire() {
+		httpapi.WriteError(w, r, ErrNoCapacity, http.StatusServiceUnavailable)
+		return
+	}
+	defer ctrl.Connections.release()
 
 	c, err := ctrl.Upgrader.Upgrade(w, r, nil)
 
@@ -94,8 +102,7 @@ func (ctrl *Controller) WebsocketHandler(w http.ResponseWriter, r *http.Request)
 			return false
 		}
 		var errResp httpapi.ErrorResponse
-		errResp.Body.Error.Code = code
-		errResp.Body.Error.Message = err.Error()
+		errResp.Body.Error = httpapi.NewError(err, code)
 		return c.WriteJSON(errResp.Body) != nil
 	}
 
@@ -127,7 +134,7 @@ subscription:
 			//}
 
 		default:
-			if handle(errors.New(http.StatusText(http.StatusNotFound)), http.StatusNotFound) {
+			if handle(ErrUnknownOperation, http.StatusNotFound) {
 				break subscription
 			}
 		}
@@ -20,7 +20,7 @@ func TestWebsocket(t *testing.T) {
 
 	server := func(t *testcase.T) *httptest.Server { return t.I(`server`).(*httptest.Server) }
 	s.Let(`server`, func(t *testcase.T) interface{} {
-		return httptest.NewServer(httpws.NewHandler(sh.ExampleUseCases(t)))
+		return httptest.NewServer(httpws.NewHandler(sh.ExampleUseCases(t), &httpws.Connections{Max: httpws.DefaultMaxConnections}))
 	})
 	s.After(func(t *testcase.T) { server(t).Close() })
 
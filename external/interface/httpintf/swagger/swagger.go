@@ -29,7 +29,7 @@ func HandleSwaggerUI() http.Handler {
 		case `/`, `/index.html`:
 			t := template.New(`index.html`)
 			t, err := t.ParseFS(uiFS, "index.html")
-			if httputils.HandleError(w, err, http.StatusInternalServerError) {
+			if httputils.HandleError(w, r, err, http.StatusInternalServerError) {
 				return
 			}
 
@@ -50,7 +50,7 @@ func HandleSwaggerUI() http.Handler {
 			data := struct{ ConfigURL string }{}
 			data.ConfigURL = createURL(r, schema)
 
-			if httputils.HandleError(w, t.Execute(w, data), http.StatusInternalServerError) {
+			if httputils.HandleError(w, r, t.Execute(w, data), http.StatusInternalServerError) {
 				return
 			}
 
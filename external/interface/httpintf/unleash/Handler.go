@@ -0,0 +1,202 @@
+// Package unleash implements the client API of Unleash,
+// so applications that ship an Unleash SDK can use toggler as their feature toggle server.
+//
+// The deployment environment is selected with the X-Environment header or the "environment" query parameter,
+// and the SDK's API token is verified as a toggler app token.
+package unleash
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+
+	"github.com/adamluzsi/frameless/iterators"
+
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/external/interface/httpintf/httputils"
+	"github.com/toggler-io/toggler/external/logging"
+)
+
+func NewHandler(uc *toggler.UseCases) http.Handler {
+	ctrl := Controller{UseCases: uc}
+	m := http.NewServeMux()
+	m.HandleFunc(`/client/features`, ctrl.Features)
+	m.HandleFunc(`/client/register`, ctrl.Register)
+	m.HandleFunc(`/client/metrics`, ctrl.Metrics)
+	return httputils.AuthMiddleware(m, uc, httputils.WriteError)
+}
+
+type Controller struct {
+	UseCases *toggler.UseCases
+}
+
+// Features serves every release flag of the requested environment as an Unleash feature toggle.
+// The response carries an ETag, so the SDKs polling with If-None-Match get 304 when nothing changed.
+func (ctrl Controller) Features(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	alias := httputils.GetEnvironmentAlias(r)
+	if alias == `` {
+		http.Error(w, `deployment environment is not provided`, http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	var env release.Environment
+	found, err := ctrl.UseCases.Storage.ReleaseEnvironment(ctx).FindByAlias(ctx, alias, &env)
+	if handleError(w, r, err) {
+		return
+	}
+	if !found {
+		http.Error(w, `deployment environment not found`, http.StatusBadRequest)
+		return
+	}
+
+	features, err := ctrl.features(ctx, env)
+	if handleError(w, r, err) {
+		return
+	}
+
+	body, err := json.Marshal(features)
+	if handleError(w, r, err) {
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set(`ETag`, etag)
+
+	if r.Header.Get(`If-None-Match`) == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set(`Content-Type`, `application/json`)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(body); err != nil {
+		logging.Error(ctx, `unable to write the response`, err)
+	}
+}
+
+func (ctrl Controller) features(ctx context.Context, env release.Environment) (Features, error) {
+	var flags []release.Flag
+	if err := iterators.Collect(ctrl.UseCases.Storage.ReleaseFlag(ctx).FindAll(ctx), &flags); err != nil {
+		return Features{}, err
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+
+	rollouts := make(map[string]*release.Rollout)
+	if err := iterators.ForEach(ctrl.UseCases.Storage.ReleaseRollout(ctx).FindAll(ctx), func(r release.Rollout) error {
+		if r.EnvironmentID == env.ID {
+			rollouts[r.FlagID] = &r
+		}
+		return nil
+	}); err != nil {
+		return Features{}, err
+	}
+
+	pilots := make(map[string][]release.Pilot)
+	if err := iterators.ForEach(ctrl.UseCases.Storage.ReleasePilot(ctx).FindAll(ctx), func(p release.Pilot) error {
+		if p.EnvironmentID == env.ID {
+			pilots[p.FlagID] = append(pilots[p.FlagID], p)
+		}
+		return nil
+	}); err != nil {
+		return Features{}, err
+	}
+
+	features := Features{Version: 1, Features: make([]Feature, 0, len(flags))}
+	for _, flag := range flags {
+		features.Features = append(features.Features, NewFeature(flag, rollouts[flag.ID], pilots[flag.ID]))
+	}
+	return features, nil
+}
+
+// Registration is sent by the Unleash SDKs when they start.
+type Registration struct {
+	AppName    string   `json:"appName"`
+	InstanceID string   `json:"instanceId"`
+	SDKVersion string   `json:"sdkVersion"`
+	Strategies []string `json:"strategies"`
+	Interval   int      `json:"interval"`
+}
+
+// Register acknowledges the SDK registration.
+// Toggler doesn't keep track of the client applications,
+// but it warns when the SDK can't evaluate every strategy that the features may use.
+func (ctrl Controller) Register(w http.ResponseWriter, r *http.Request) {
+	var reg Registration
+	if !decodeClientRequest(w, r, &reg) {
+		return
+	}
+
+	supported := make(map[string]struct{})
+	for _, name := range reg.Strategies {
+		supported[name] = struct{}{}
+	}
+	for _, name := range []string{StrategyDefault, StrategyFlexibleRollout, StrategyUserWithID} {
+		if _, ok := supported[name]; !ok && 0 < len(reg.Strategies) {
+			logging.FromContext(r.Context()).Warn(`unleash client doesn't support a strategy`,
+				slog.String(`app_name`, reg.AppName),
+				slog.String(`instance_id`, reg.InstanceID),
+				slog.String(`strategy`, name),
+			)
+		}
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// MetricsReport is the usage report periodically sent by the Unleash SDKs.
+type MetricsReport struct {
+	AppName    string `json:"appName"`
+	InstanceID string `json:"instanceId"`
+	Bucket     struct {
+		Toggles map[string]struct {
+			Yes int `json:"yes"`
+			No  int `json:"no"`
+		} `json:"toggles"`
+	} `json:"bucket"`
+}
+
+// Metrics accepts the SDK usage reports.
+// Toggler doesn't store them, the endpoint exists so the SDKs don't report errors about it.
+func (ctrl Controller) Metrics(w http.ResponseWriter, r *http.Request) {
+	var report MetricsReport
+	if !decodeClientRequest(w, r, &report) {
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func decodeClientRequest(w http.ResponseWriter, r *http.Request, ptr interface{}) bool {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return false
+	}
+
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(ptr); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func handleError(w http.ResponseWriter, r *http.Request, err error) bool {
+	if err == nil {
+		return false
+	}
+	logging.Error(r.Context(), `request failed`, err)
+	code := http.StatusInternalServerError
+	http.Error(w, http.StatusText(code), code)
+	return true
+}
@@ -0,0 +1,109 @@
+package unleash_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	. "github.com/adamluzsi/testcase/httpspec"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/external/interface/httpintf/httputils"
+	"github.com/toggler-io/toggler/external/interface/httpintf/unleash"
+	sh "github.com/toggler-io/toggler/spechelper"
+)
+
+func TestHandler(t *testing.T) {
+	s := sh.NewSpec(t)
+
+	HandlerLet(s, func(t *testcase.T) http.Handler { return unleash.NewHandler(sh.ExampleUseCases(t)) })
+	Context.Let(s, func(t *testcase.T) interface{} { return sh.ContextGet(t) })
+	ContentTypeIsJSON(s)
+
+	s.Before(func(t *testcase.T) {
+		HeaderGet(t).Set(`Authorization`, sh.ExampleTextToken(t))
+	})
+
+	s.Describe(`GET /client/features`, func(s *testcase.Spec) {
+		Method.LetValue(s, http.MethodGet)
+		Path.LetValue(s, `/client/features`)
+
+		s.Before(func(t *testcase.T) {
+			HeaderGet(t).Set(httputils.EnvironmentHeader, sh.ExampleDeploymentEnvironment(t).Name)
+			require.Nil(t, sh.StorageGet(t).ReleaseRollout(sh.ContextGet(t)).Create(sh.ContextGet(t), &release.Rollout{
+				FlagID:        sh.ExampleReleaseFlag(t).ID,
+				EnvironmentID: sh.ExampleDeploymentEnvironment(t).ID,
+				Plan:          release.RolloutDecisionByGlobal{State: true},
+			}))
+		})
+
+		s.Then(`the release flags are served as Unleash features`, func(t *testcase.T) {
+			rr := ServeHTTP(t)
+			require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+			var features unleash.Features
+			require.Nil(t, json.Unmarshal(rr.Body.Bytes(), &features))
+
+			var found bool
+			for _, f := range features.Features {
+				if f.Name == sh.ExampleReleaseFlag(t).Name {
+					found = true
+					require.True(t, f.Enabled)
+				}
+			}
+			require.True(t, found)
+		})
+
+		s.When(`the response didn't change since the last poll`, func(s *testcase.Spec) {
+			s.Before(func(t *testcase.T) {
+				HeaderGet(t).Set(`If-None-Match`, ServeHTTP(t).Header().Get(`ETag`))
+			})
+
+			s.Then(`it responds with not modified`, func(t *testcase.T) {
+				require.Equal(t, http.StatusNotModified, ServeHTTP(t).Code)
+			})
+		})
+
+		s.When(`the environment is not provided`, func(s *testcase.Spec) {
+			s.Before(func(t *testcase.T) { HeaderGet(t).Del(httputils.EnvironmentHeader) })
+
+			s.Then(`it responds with bad request`, func(t *testcase.T) {
+				require.Equal(t, http.StatusBadRequest, ServeHTTP(t).Code)
+			})
+		})
+
+		s.When(`the token is invalid`, func(s *testcase.Spec) {
+			s.Before(func(t *testcase.T) { HeaderGet(t).Set(`Authorization`, `invalid`) })
+
+			s.Then(`it responds with unauthorized`, func(t *testcase.T) {
+				require.Equal(t, http.StatusUnauthorized, ServeHTTP(t).Code)
+			})
+		})
+	})
+
+	s.Describe(`POST /client/register`, func(s *testcase.Spec) {
+		Method.LetValue(s, http.MethodPost)
+		Path.LetValue(s, `/client/register`)
+		Body.Let(s, func(t *testcase.T) interface{} {
+			return unleash.Registration{AppName: `legacy-app`, InstanceID: `1`, Strategies: []string{`default`}}
+		})
+
+		s.Then(`the registration is accepted`, func(t *testcase.T) {
+			require.Equal(t, http.StatusAccepted, ServeHTTP(t).Code)
+		})
+	})
+
+	s.Describe(`POST /client/metrics`, func(s *testcase.Spec) {
+		Method.LetValue(s, http.MethodPost)
+		Path.LetValue(s, `/client/metrics`)
+		Body.Let(s, func(t *testcase.T) interface{} {
+			return unleash.MetricsReport{AppName: `legacy-app`, InstanceID: `1`}
+		})
+
+		s.Then(`the report is accepted`, func(t *testcase.T) {
+			require.Equal(t, http.StatusAccepted, ServeHTTP(t).Code)
+		})
+	})
+}
@@ -0,0 +1,158 @@
+package unleash
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/toggler-io/toggler/domains/release"
+)
+
+const (
+	StrategyDefault         = `default`
+	StrategyFlexibleRollout = `flexibleRollout`
+	StrategyUserWithID      = `userWithId`
+	// StrategyUnsupported is used for rollout plans that has no Unleash equivalent.
+	// Unleash SDKs evaluate unknown strategies as disabled and warn about them,
+	// so the pilot overrides keep working while the problem is visible on the client side too.
+	StrategyUnsupported = `toggler-unsupported`
+)
+
+// Features is the payload of the Unleash client API's feature toggle endpoint.
+type Features struct {
+	Version  int       `json:"version"`
+	Features []Feature `json:"features"`
+}
+
+type Feature struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Type        string     `json:"type"`
+	Enabled     bool       `json:"enabled"`
+	Stale       bool       `json:"stale"`
+	Strategies  []Strategy `json:"strategies"`
+	Variants    []Variant  `json:"variants"`
+}
+
+type Strategy struct {
+	Name        string            `json:"name"`
+	Parameters  map[string]string `json:"parameters"`
+	Constraints []Constraint      `json:"constraints"`
+}
+
+type Constraint struct {
+	ContextName string   `json:"contextName"`
+	Operator    string   `json:"operator"`
+	Values      []string `json:"values"`
+}
+
+type Variant struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+}
+
+// NewFeature translates a release flag with its rollout and manual pilot enrollments
+// from a given environment into an Unleash feature toggle.
+// The rollout is nil when the flag has no rollout in the environment.
+//
+// Unleash uses a different hashing for gradual rollouts,
+// so a percentage based rollout enrolls the same ratio of the users, but not necessarily the same users.
+func NewFeature(flag release.Flag, rollout *release.Rollout, pilots []release.Pilot) Feature {
+	feature := Feature{
+		Name:     flag.Name,
+		Type:     `release`,
+		Variants: []Variant{},
+	}
+
+	var strategies []Strategy
+	if rollout != nil {
+		var ok bool
+		strategies, ok = newStrategies(flag, rollout.Plan)
+		if !ok {
+			planType := planTypeOf(rollout.Plan)
+			feature.Description = `toggler: the "` + planType + `" rollout plan can't be expressed as an Unleash strategy`
+			strategies = []Strategy{newStrategy(StrategyUnsupported, map[string]string{`plan`: planType})}
+		}
+	}
+
+	var included, excluded []string
+	for _, p := range pilots {
+		if p.IsParticipating {
+			included = append(included, p.PublicID)
+		} else {
+			excluded = append(excluded, p.PublicID)
+		}
+	}
+	sort.Strings(included)
+	sort.Strings(excluded)
+
+	if 0 < len(excluded) {
+		for i := range strategies {
+			strategies[i].Constraints = append(strategies[i].Constraints, Constraint{
+				ContextName: `userId`,
+				Operator:    `NOT_IN`,
+				Values:      excluded,
+			})
+		}
+	}
+
+	if 0 < len(included) {
+		strategies = append([]Strategy{newStrategy(StrategyUserWithID, map[string]string{
+			`userIds`: strings.Join(included, `,`),
+		})}, strategies...)
+	}
+
+	// an enabled Unleash feature without strategies is turned on for everyone,
+	// so a flag without any strategy must be disabled.
+	feature.Enabled = 0 < len(strategies)
+	if strategies == nil {
+		strategies = []Strategy{}
+	}
+	feature.Strategies = strategies
+	return feature
+}
+
+func newStrategies(flag release.Flag, plan release.RolloutPlan) ([]Strategy, bool) {
+	switch p := plan.(type) {
+	case release.RolloutDecisionByGlobal:
+		if !p.State {
+			return nil, true
+		}
+		return []Strategy{newStrategy(StrategyDefault, map[string]string{})}, true
+
+	case release.RolloutDecisionByPercentage:
+		return []Strategy{newStrategy(StrategyFlexibleRollout, map[string]string{
+			`rollout`:    strconv.Itoa(p.Percentage),
+			`stickiness`: `userId`,
+			`groupId`:    flag.Name,
+		})}, true
+
+	case release.RolloutDecisionOR:
+		// Unleash enables a feature when any of its strategies is satisfied.
+		left, ok := newStrategies(flag, p.Left)
+		if !ok {
+			return nil, false
+		}
+		right, ok := newStrategies(flag, p.Right)
+		if !ok {
+			return nil, false
+		}
+		return append(left, right...), true
+
+	default:
+		return nil, false
+	}
+}
+
+func newStrategy(name string, parameters map[string]string) Strategy {
+	return Strategy{Name: name, Parameters: parameters, Constraints: []Constraint{}}
+}
+
+func planTypeOf(plan release.RolloutPlan) string {
+	m, err := release.RolloutPlanView{}.MarshalMapping(plan)
+	if err != nil || m == nil {
+		return `unknown`
+	}
+	planType, _ := m[`type`].(string)
+	return planType
+}
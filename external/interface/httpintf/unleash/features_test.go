@@ -0,0 +1,123 @@
+package unleash_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/external/interface/httpintf/unleash"
+)
+
+func TestFeatures(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	var (
+		flag    = release.Flag{ID: `42`, Name: `checkout`}
+		rollout = s.Let(`rollout`, func(t *testcase.T) interface{} { return (*release.Rollout)(nil) })
+		pilots  = s.Let(`pilots`, func(t *testcase.T) interface{} { return []release.Pilot{} })
+		subject = func(t *testcase.T) unleash.Feature {
+			return unleash.NewFeature(flag, rollout.Get(t).(*release.Rollout), pilots.Get(t).([]release.Pilot))
+		}
+		planIs = func(s *testcase.Spec, plan release.RolloutPlan) {
+			rollout.Let(s, func(t *testcase.T) interface{} { return &release.Rollout{Plan: plan} })
+		}
+		strategyNames = func(f unleash.Feature) []string {
+			var names []string
+			for _, s := range f.Strategies {
+				names = append(names, s.Name)
+			}
+			return names
+		}
+	)
+
+	s.Describe(`NewFeature`, func(s *testcase.Spec) {
+		s.When(`the flag has no rollout`, func(s *testcase.Spec) {
+			s.Then(`it is disabled`, func(t *testcase.T) {
+				f := subject(t)
+				require.Equal(t, `checkout`, f.Name)
+				require.False(t, f.Enabled)
+				require.Empty(t, f.Strategies)
+			})
+		})
+
+		s.When(`the rollout is globally enabled`, func(s *testcase.Spec) {
+			planIs(s, release.RolloutDecisionByGlobal{State: true})
+
+			s.Then(`it uses the default strategy`, func(t *testcase.T) {
+				f := subject(t)
+				require.True(t, f.Enabled)
+				require.Equal(t, []string{unleash.StrategyDefault}, strategyNames(f))
+			})
+		})
+
+		s.When(`the rollout is globally disabled`, func(s *testcase.Spec) {
+			planIs(s, release.RolloutDecisionByGlobal{State: false})
+
+			s.Then(`it is disabled`, func(t *testcase.T) {
+				require.False(t, subject(t).Enabled)
+			})
+
+			s.And(`a pilot is manually enrolled`, func(s *testcase.Spec) {
+				pilots.Let(s, func(t *testcase.T) interface{} {
+					return []release.Pilot{{PublicID: `bob`, IsParticipating: true}, {PublicID: `alice`, IsParticipating: true}}
+				})
+
+				s.Then(`the pilots are enabled with the userWithId strategy`, func(t *testcase.T) {
+					f := subject(t)
+					require.True(t, f.Enabled)
+					require.Equal(t, []string{unleash.StrategyUserWithID}, strategyNames(f))
+					require.Equal(t, `alice,bob`, f.Strategies[0].Parameters[`userIds`])
+				})
+			})
+		})
+
+		s.When(`the rollout is percentage based`, func(s *testcase.Spec) {
+			planIs(s, release.RolloutDecisionByPercentage{Percentage: 25})
+
+			s.Then(`it uses a gradual rollout`, func(t *testcase.T) {
+				f := subject(t)
+				require.True(t, f.Enabled)
+				require.Equal(t, []string{unleash.StrategyFlexibleRollout}, strategyNames(f))
+				require.Equal(t, `25`, f.Strategies[0].Parameters[`rollout`])
+				require.Equal(t, `userId`, f.Strategies[0].Parameters[`stickiness`])
+			})
+
+			s.And(`a pilot is manually excluded`, func(s *testcase.Spec) {
+				pilots.Let(s, func(t *testcase.T) interface{} {
+					return []release.Pilot{{PublicID: `alice`, IsParticipating: false}}
+				})
+
+				s.Then(`the pilot is excluded from the gradual rollout`, func(t *testcase.T) {
+					f := subject(t)
+					require.Equal(t, []unleash.Constraint{{ContextName: `userId`, Operator: `NOT_IN`, Values: []string{`alice`}}},
+						f.Strategies[0].Constraints)
+				})
+			})
+		})
+
+		s.When(`the rollout is a combination with OR`, func(s *testcase.Spec) {
+			planIs(s, release.RolloutDecisionOR{
+				Left:  release.RolloutDecisionByPercentage{Percentage: 10},
+				Right: release.RolloutDecisionByGlobal{State: true},
+			})
+
+			s.Then(`every branch becomes a strategy`, func(t *testcase.T) {
+				require.Equal(t, []string{unleash.StrategyFlexibleRollout, unleash.StrategyDefault}, strategyNames(subject(t)))
+			})
+		})
+
+		s.When(`the rollout can't be expressed in Unleash`, func(s *testcase.Spec) {
+			planIs(s, release.NewRolloutDecisionByAPI(&url.URL{Scheme: `https`, Host: `example.com`}))
+
+			s.Then(`it is flagged as unsupported`, func(t *testcase.T) {
+				f := subject(t)
+				require.Equal(t, []string{unleash.StrategyUnsupported}, strategyNames(f))
+				require.Equal(t, `api`, f.Strategies[0].Parameters[`plan`])
+				require.Contains(t, f.Description, `can't be expressed`)
+			})
+		})
+	})
+}
@@ -23,6 +23,16 @@ func NewServeMux(uc *toggler.UseCases) (*ServeMux, error) {
 	mux.HandleFunc(`/env/`, ctrl.EnvPage)
 	mux.HandleFunc(`/rollout`, ctrl.RolloutPage)
 	mux.HandleFunc(`/rollout/`, ctrl.RolloutPage)
+	mux.HandleFunc(`/history`, ctrl.HistoryPage)
+	mux.HandleFunc(`/history/`, ctrl.HistoryPage)
+	mux.HandleFunc(`/webhook`, ctrl.WebhookPage)
+	mux.HandleFunc(`/webhook/`, ctrl.WebhookPage)
+	mux.HandleFunc(`/change`, ctrl.ChangePage)
+	mux.HandleFunc(`/change/`, ctrl.ChangePage)
+	mux.HandleFunc(`/schedule`, ctrl.SchedulePage)
+	mux.HandleFunc(`/schedule/`, ctrl.SchedulePage)
+	mux.HandleFunc(`/guardrail`, ctrl.GuardrailPage)
+	mux.HandleFunc(`/guardrail/`, ctrl.GuardrailPage)
 	mux.HandleFunc(`/docs/`, ctrl.DocsPage)
 	mux.HandleFunc(`/docs/assets/`, ctrl.DocsAssets)
 	mux.HandleFunc(`/pilot/`, ctrl.PilotPage)
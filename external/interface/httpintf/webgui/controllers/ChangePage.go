@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/toggler-io/toggler/domains/change"
+	"github.com/toggler-io/toggler/domains/release"
+)
+
+func (ctrl *Controller) ChangePage(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case `/change`:
+		ctrl.changeListAction(w, r)
+	case `/change/show`:
+		ctrl.changeShowAction(w, r)
+	case `/change/approve`:
+		ctrl.changeResolveAction(w, r, ctrl.UseCases.Changes.Approve)
+	case `/change/reject`:
+		ctrl.changeResolveAction(w, r, func(ctx context.Context, id string) error {
+			return ctrl.UseCases.Changes.Reject(ctx, id, r.FormValue(`reason`))
+		})
+	case `/change/apply`:
+		ctrl.changeResolveAction(w, r, ctrl.UseCases.Changes.Apply)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func changeURL(requestID string) string {
+	u, _ := url.Parse(`/change/show`)
+	q := u.Query()
+	q.Set(`id`, requestID)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// proposedRedirect sends the user to the change request,
+// when the change made with the context was proposed instead of being made.
+func (ctrl *Controller) proposedRedirect(w http.ResponseWriter, r *http.Request, ctx context.Context) bool {
+	proposed := change.Proposed(ctx)
+	if len(proposed) == 0 {
+		return false
+	}
+	http.Redirect(w, r, changeURL(proposed[0].ID), http.StatusFound)
+	return true
+}
+
+func (ctrl *Controller) changeListAction(w http.ResponseWriter, r *http.Request) {
+	status := change.StatusPending
+	if _, ok := r.URL.Query()[`status`]; ok {
+		status = r.URL.Query().Get(`status`)
+	}
+
+	type Content struct {
+		Status       string
+		Requests     []change.Request
+		Environments map[string]string
+	}
+
+	content := Content{Status: status, Environments: make(map[string]string)}
+	requests, err := ctrl.UseCases.Changes.List(r.Context(), change.RequestQuery{Status: status})
+	if ctrl.handleError(w, r, err) {
+		return
+	}
+	content.Requests = requests
+
+	for _, request := range requests {
+		if _, ok := content.Environments[request.EnvironmentID]; ok {
+			continue
+		}
+		var env release.Environment
+		if _, err := ctrl.UseCases.Storage.ReleaseEnvironment(r.Context()).FindByID(r.Context(), &env, request.EnvironmentID); ctrl.handleError(w, r, err) {
+			return
+		}
+		content.Environments[request.EnvironmentID] = env.Name
+	}
+
+	ctrl.Render(w, `/change/index.html`, content)
+}
+
+func (ctrl *Controller) changeShowAction(w http.ResponseWriter, r *http.Request) {
+	review, err := ctrl.UseCases.Changes.Review(r.Context(), r.URL.Query().Get(`id`))
+	if ctrl.handleError(w, r, err) {
+		return
+	}
+
+	ctrl.Render(w, `/change/show.html`, review)
+}
+
+func (ctrl *Controller) changeResolveAction(w http.ResponseWriter, r *http.Request, resolve func(ctx context.Context, requestID string) error) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	requestID := r.FormValue(`id`)
+	if ctrl.handleError(w, r, resolve(r.Context(), requestID)) {
+		return
+	}
+
+	http.Redirect(w, r, changeURL(requestID), http.StatusFound)
+}
@@ -1,11 +1,15 @@
 package controllers
 
 import (
-	"log"
 	"net/http"
+	"net/url"
 
+	"github.com/toggler-io/toggler/domains/errs"
+	"github.com/toggler-io/toggler/domains/release"
 	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/external/interface/httpintf/httputils"
 	"github.com/toggler-io/toggler/external/interface/httpintf/webgui/views"
+	"github.com/toggler-io/toggler/external/logging"
 )
 
 func NewController(uc *toggler.UseCases) (*Controller, error) {
@@ -38,7 +42,68 @@ func (ctrl *Controller) handleError(w http.ResponseWriter, r *http.Request, err
 		return false
 	}
 
-	log.Println(err.Error())
+	if domainErr, ok := errs.Lookup(err); ok {
+		code := httputils.StatusCode(err, http.StatusInternalServerError)
+		w.WriteHeader(code)
+		ctrl.Render(w, `/error.html`, errorPageContent{
+			Status:  http.StatusText(code),
+			Code:    domainErr.Code,
+			Field:   domainErr.Field,
+			Message: err.Error(),
+			Back:    r.URL.Path,
+		})
+		return true
+	}
+
+	logging.Error(r.Context(), `request failed`, err)
 	http.Redirect(w, r, r.URL.Path, http.StatusFound)
 	return true
 }
+
+// errorPageContent describes a domain error, which the user can correct, such as a validation error.
+type errorPageContent struct {
+	Status  string
+	Code    string
+	Field   string
+	Message string
+	Back    string
+}
+
+// listQuery holds the filtering and paging state of an index page.
+type listQuery struct {
+	NamePrefix string
+	Sort       string
+	Order      string
+	Cursor     string
+}
+
+func newListQuery(r *http.Request) listQuery {
+	q := listQuery{
+		NamePrefix: r.URL.Query().Get(`name_prefix`),
+		Sort:       r.URL.Query().Get(`sort`),
+		Order:      r.URL.Query().Get(`order`),
+		Cursor:     r.URL.Query().Get(`cursor`),
+	}
+	if q.Sort == `` {
+		q.Sort = release.SortByName
+	}
+	return q
+}
+
+func (q listQuery) Pagination() release.Pagination {
+	return release.Pagination{
+		Cursor:     q.Cursor,
+		SortBy:     q.Sort,
+		Descending: q.Order == `desc`,
+	}
+}
+
+// NextPageURL returns the query string of the page that continues from the given cursor.
+func (q listQuery) NextPageURL(cursor string) string {
+	v := url.Values{}
+	v.Set(`name_prefix`, q.NamePrefix)
+	v.Set(`sort`, q.Sort)
+	v.Set(`order`, q.Order)
+	v.Set(`cursor`, cursor)
+	return `?` + v.Encode()
+}
@@ -1,14 +1,14 @@
 package controllers
 
 import (
-	"fmt"
+	"github.com/toggler-io/toggler/domains/change"
 	"github.com/toggler-io/toggler/domains/release"
-	"log"
+	"github.com/toggler-io/toggler/external/logging"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
-
-	"github.com/adamluzsi/frameless/iterators"
 )
 
 func (ctrl *Controller) EnvPage(w http.ResponseWriter, r *http.Request) {
@@ -25,16 +25,34 @@ func (ctrl *Controller) EnvPage(w http.ResponseWriter, r *http.Request) {
 }
 
 func (ctrl *Controller) envListAction(w http.ResponseWriter, r *http.Request) {
-	envsIter := ctrl.UseCases.Storage.ReleaseEnvironment(r.Context()).FindAll(r.Context())
-
-	var envs []release.Environment
-	if err := iterators.Collect(envsIter, &envs); err != nil {
-		log.Println(`ERROR`, err.Error())
+	query := newListQuery(r)
+	page, err := ctrl.UseCases.RolloutManager.ListEnvironments(r.Context(), release.EnvironmentQuery{
+		NamePrefix: query.NamePrefix,
+		Pagination: query.Pagination(),
+	})
+
+	switch err {
+	case nil:
+	case release.ErrInvalidCursor, release.ErrInvalidSortField:
+		ctrl.handleError(w, r, err)
+		return
+	default:
+		logging.Error(r.Context(), `unable to list the deployment environments`, err)
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
 
-	ctrl.Render(w, `/env/index.html`, envs)
+	type Content struct {
+		Query        listQuery
+		Environments []release.Environment
+		NextCursor   string
+	}
+
+	ctrl.Render(w, `/env/index.html`, Content{
+		Query:        query,
+		Environments: page.Environments,
+		NextCursor:   page.NextCursor,
+	})
 }
 
 func (ctrl *Controller) envAction(w http.ResponseWriter, r *http.Request) {
@@ -47,7 +65,7 @@ func (ctrl *Controller) envAction(w http.ResponseWriter, r *http.Request) {
 		id := r.Form.Get(`id`)
 
 		var env release.Environment
-		found, err := ctrl.UseCases.Storage.ReleaseRollout(r.Context()).FindByID(r.Context(), &env, id)
+		found, err := ctrl.UseCases.Storage.ReleaseEnvironment(r.Context()).FindByID(r.Context(), &env, id)
 
 		if ctrl.handleError(w, r, err) {
 			return
@@ -72,7 +90,11 @@ func (ctrl *Controller) envAction(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
-			if ctrl.handleError(w, r, ctrl.UseCases.Storage.ReleaseEnvironment(r.Context()).Update(r.Context(), &env)) {
+			ctx := change.ContextWithProposals(r.Context())
+			if ctrl.handleError(w, r, ctrl.UseCases.Storage.ReleaseEnvironment(ctx).Update(ctx, &env)) {
+				return
+			}
+			if ctrl.proposedRedirect(w, r, ctx) {
 				return
 			}
 
@@ -109,11 +131,15 @@ func (ctrl *Controller) envAction(w http.ResponseWriter, r *http.Request) {
 
 			envID := r.Form.Get(`env.id`)
 
-			if envID == `` && ctrl.handleError(w, r, fmt.Errorf(`environment id is missing`)) {
+			if envID == `` && ctrl.handleError(w, r, release.ErrMissingEnv) {
 				return
 			}
 
-			if ctrl.handleError(w, r, ctrl.UseCases.Storage.ReleaseEnvironment(r.Context()).DeleteByID(r.Context(), envID)) {
+			ctx := change.ContextWithProposals(r.Context())
+			if ctrl.handleError(w, r, ctrl.UseCases.Storage.ReleaseEnvironment(ctx).DeleteByID(ctx, envID)) {
+				return
+			}
+			if ctrl.proposedRedirect(w, r, ctx) {
 				return
 			}
 
@@ -167,19 +193,19 @@ func (ctrl *Controller) envCreateNewAction(w http.ResponseWriter, r *http.Reques
 		env, err := ParseEnvForm(r)
 
 		if err != nil {
-			log.Println(err)
+			logging.FromContext(r.Context()).Warn(`invalid deployment environment form`, slog.String(`error`, err.Error()))
 			http.Redirect(w, r, `/`, http.StatusFound)
 			return
 		}
 
 		if env.ID != `` {
-			log.Println(`unexpected env id received`)
+			logging.FromContext(r.Context()).Warn(`unexpected env id received`)
 			http.Redirect(w, r, `/`, http.StatusFound)
 			return
 		}
 
 		if env.Name == `` {
-			log.Println(`missing env name`)
+			logging.FromContext(r.Context()).Warn(`missing env name`)
 			http.Redirect(w, r, `/env/create`, http.StatusFound)
 			return
 		}
@@ -187,7 +213,7 @@ func (ctrl *Controller) envCreateNewAction(w http.ResponseWriter, r *http.Reques
 		err = ctrl.UseCases.Storage.ReleaseEnvironment(r.Context()).Create(r.Context(), &env)
 
 		if err != nil {
-			log.Println(err)
+			logging.Error(r.Context(), `unable to create the deployment environment`, err)
 		}
 
 		http.Redirect(w, r, `/`, http.StatusFound)
@@ -205,5 +231,7 @@ func ParseEnvForm(r *http.Request) (release.Environment, error) {
 	var env release.Environment
 	env.ID = r.Form.Get(`env.id`)
 	env.Name = r.Form.Get(`env.name`)
+	env.Revision, _ = strconv.Atoi(r.Form.Get(`env.revision`))
+	env.RequiredApprovals, _ = strconv.Atoi(r.Form.Get(`env.required_approvals`))
 	return env, nil
 }
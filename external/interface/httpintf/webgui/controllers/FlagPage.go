@@ -1,8 +1,7 @@
 package controllers
 
 import (
-	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
@@ -10,6 +9,7 @@ import (
 	"github.com/adamluzsi/frameless/iterators"
 
 	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/external/logging"
 )
 
 func (ctrl *Controller) FlagPage(w http.ResponseWriter, r *http.Request) {
@@ -30,15 +30,34 @@ func (ctrl *Controller) FlagPage(w http.ResponseWriter, r *http.Request) {
 }
 
 func (ctrl *Controller) flagListAction(w http.ResponseWriter, r *http.Request) {
-	flags, err := ctrl.UseCases.RolloutManager.ListFeatureFlags(r.Context())
-
-	if err != nil {
-		log.Println(`ERROR`, err.Error())
+	query := newListQuery(r)
+	page, err := ctrl.UseCases.RolloutManager.ListFlags(r.Context(), release.FlagQuery{
+		NamePrefix: query.NamePrefix,
+		Pagination: query.Pagination(),
+	})
+
+	switch err {
+	case nil:
+	case release.ErrInvalidCursor, release.ErrInvalidSortField:
+		ctrl.handleError(w, r, err)
+		return
+	default:
+		logging.Error(r.Context(), `unable to list the release flags`, err)
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
 
-	ctrl.Render(w, `/flag/index.html`, flags)
+	type Content struct {
+		Query      listQuery
+		Flags      []release.Flag
+		NextCursor string
+	}
+
+	ctrl.Render(w, `/flag/index.html`, Content{
+		Query:      query,
+		Flags:      page.Flags,
+		NextCursor: page.NextCursor,
+	})
 }
 
 func (ctrl *Controller) flagAction(w http.ResponseWriter, r *http.Request) {
@@ -121,7 +140,7 @@ func (ctrl *Controller) flagAction(w http.ResponseWriter, r *http.Request) {
 
 			flagID := r.Form.Get(`flag.id`)
 
-			if flagID == `` && ctrl.handleError(w, r, fmt.Errorf(`release flag id is missing`)) {
+			if flagID == `` && ctrl.handleError(w, r, release.ErrMissingFlag) {
 				return
 			}
 
@@ -197,19 +216,19 @@ func (ctrl *Controller) flagCreateNewAction(w http.ResponseWriter, r *http.Reque
 		ff, err := ParseFlagForm(r)
 
 		if err != nil {
-			log.Println(err)
+			logging.FromContext(r.Context()).Warn(`invalid release flag form`, slog.String(`error`, err.Error()))
 			http.Redirect(w, r, `/`, http.StatusFound)
 			return
 		}
 
 		if ff.ID != `` {
-			log.Println(`unexpected flag id received`)
+			logging.FromContext(r.Context()).Warn(`unexpected flag id received`)
 			http.Redirect(w, r, `/`, http.StatusFound)
 			return
 		}
 
 		if ff.Name == `` {
-			log.Println(`missing flag name`)
+			logging.FromContext(r.Context()).Warn(`missing flag name`)
 			http.Redirect(w, r, `/flag/create`, http.StatusFound)
 			return
 		}
@@ -217,7 +236,7 @@ func (ctrl *Controller) flagCreateNewAction(w http.ResponseWriter, r *http.Reque
 		err = ctrl.UseCases.RolloutManager.CreateFeatureFlag(r.Context(), ff)
 
 		if err != nil {
-			log.Println(err)
+			logging.Error(r.Context(), `unable to create the release flag`, err)
 		}
 
 		http.Redirect(w, r, `/`, http.StatusFound)
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/toggler-io/toggler/domains/guardrail"
+	"github.com/toggler-io/toggler/domains/release"
+)
+
+func (ctrl *Controller) GuardrailPage(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case `/guardrail`:
+		ctrl.guardrailListAction(w, r)
+	case `/guardrail/show`:
+		ctrl.guardrailShowAction(w, r)
+	case `/guardrail/rearm`:
+		ctrl.guardrailRearmAction(w, r)
+	case `/guardrail/delete`:
+		ctrl.guardrailDeleteAction(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func guardrailURL(guardrailID string) string {
+	u, _ := url.Parse(`/guardrail/show`)
+	q := u.Query()
+	q.Set(`id`, guardrailID)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// guardrailTarget is the flag and the environment of the guarded rollout, by name.
+type guardrailTarget struct {
+	Flag        string
+	Environment string
+}
+
+func (ctrl *Controller) guardrailTarget(r *http.Request, g guardrail.Guardrail) (guardrailTarget, error) {
+	ctx := r.Context()
+	var rollout release.Rollout
+	found, err := ctrl.UseCases.Storage.ReleaseRollout(ctx).FindByID(ctx, &rollout, g.RolloutID)
+	if err != nil || !found {
+		return guardrailTarget{Flag: `deleted rollout`}, err
+	}
+
+	var (
+		target guardrailTarget
+		flag   release.Flag
+		env    release.Environment
+	)
+	if _, err := ctrl.UseCases.Storage.ReleaseFlag(ctx).FindByID(ctx, &flag, rollout.FlagID); err != nil {
+		return guardrailTarget{}, err
+	}
+	if _, err := ctrl.UseCases.Storage.ReleaseEnvironment(ctx).FindByID(ctx, &env, rollout.EnvironmentID); err != nil {
+		return guardrailTarget{}, err
+	}
+	target.Flag, target.Environment = flag.Name, env.Name
+	return target, nil
+}
+
+func (ctrl *Controller) guardrailListAction(w http.ResponseWriter, r *http.Request) {
+	type Content struct {
+		Guardrails []guardrail.Guardrail
+		Targets    map[string]guardrailTarget
+	}
+
+	content := Content{Targets: make(map[string]guardrailTarget)}
+	guardrails, err := ctrl.UseCases.Guardrails.List(r.Context(), ``)
+	if ctrl.handleError(w, r, err) {
+		return
+	}
+	content.Guardrails = guardrails
+
+	for _, g := range guardrails {
+		target, err := ctrl.guardrailTarget(r, g)
+		if ctrl.handleError(w, r, err) {
+			return
+		}
+		content.Targets[g.ID] = target
+	}
+
+	ctrl.Render(w, `/guardrail/index.html`, content)
+}
+
+func (ctrl *Controller) guardrailShowAction(w http.ResponseWriter, r *http.Request) {
+	type Content struct {
+		Guardrail guardrail.Guardrail
+		Target    guardrailTarget
+		Enrolled  guardrail.Health
+		Control   guardrail.Health
+	}
+
+	var content Content
+	g, err := ctrl.UseCases.Guardrails.Find(r.Context(), r.URL.Query().Get(`id`))
+	if ctrl.handleError(w, r, err) {
+		return
+	}
+	content.Guardrail = g
+
+	content.Target, err = ctrl.guardrailTarget(r, g)
+	if ctrl.handleError(w, r, err) {
+		return
+	}
+
+	if content.Target.Environment != `` {
+		content.Enrolled, content.Control, err = ctrl.UseCases.Guardrails.Health(r.Context(), g, time.Now())
+		if ctrl.handleError(w, r, err) {
+			return
+		}
+	}
+
+	ctrl.Render(w, `/guardrail/show.html`, content)
+}
+
+func (ctrl *Controller) guardrailRearmAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	guardrailID := r.FormValue(`id`)
+	if ctrl.handleError(w, r, ctrl.UseCases.Guardrails.Rearm(r.Context(), guardrailID)) {
+		return
+	}
+
+	http.Redirect(w, r, guardrailURL(guardrailID), http.StatusFound)
+}
+
+func (ctrl *Controller) guardrailDeleteAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	if ctrl.handleError(w, r, ctrl.UseCases.Guardrails.Delete(r.Context(), r.FormValue(`id`))) {
+		return
+	}
+
+	http.Redirect(w, r, `/guardrail`, http.StatusFound)
+}
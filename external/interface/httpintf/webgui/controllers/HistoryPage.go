@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/adamluzsi/frameless/iterators"
+
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/external/logging"
+)
+
+func (ctrl *Controller) HistoryPage(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case `/history`:
+		ctrl.historyListAction(w, r)
+	case `/history/diff`:
+		ctrl.historyDiffAction(w, r)
+	case `/history/revert`:
+		ctrl.historyRevertAction(w, r)
+	case `/history/evaluate`:
+		ctrl.historyEvaluateAction(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func historyURL(kind, entityID string) string {
+	u, _ := url.Parse(`/history`)
+	q := u.Query()
+	q.Set(`kind`, kind)
+	q.Set(`id`, entityID)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func (ctrl *Controller) historyListAction(w http.ResponseWriter, r *http.Request) {
+	kind := r.URL.Query().Get(`kind`)
+	entityID := r.URL.Query().Get(`id`)
+
+	versions, err := ctrl.UseCases.RolloutManager.ListVersions(r.Context(), kind, entityID)
+	if err != nil {
+		logging.Error(r.Context(), `unable to list the versions`, err)
+		http.Redirect(w, r, `/`, http.StatusFound)
+		return
+	}
+
+	type ContentVersion struct {
+		release.Version
+		PreviousID string
+	}
+
+	type Content struct {
+		Kind     string
+		EntityID string
+		Versions []ContentVersion
+	}
+
+	content := Content{Kind: kind, EntityID: entityID}
+	// the latest version is shown first, as that is what the reader is usually looking for.
+	for i := len(versions) - 1; 0 <= i; i-- {
+		cv := ContentVersion{Version: versions[i]}
+		if 0 < i {
+			cv.PreviousID = versions[i-1].ID
+		}
+		content.Versions = append(content.Versions, cv)
+	}
+
+	ctrl.Render(w, `/history/index.html`, content)
+}
+
+func (ctrl *Controller) historyDiffAction(w http.ResponseWriter, r *http.Request) {
+	manager := ctrl.UseCases.RolloutManager
+
+	from, err := manager.FindVersion(r.Context(), r.URL.Query().Get(`from`))
+	if ctrl.handleError(w, r, err) {
+		return
+	}
+	to, err := manager.FindVersion(r.Context(), r.URL.Query().Get(`to`))
+	if ctrl.handleError(w, r, err) {
+		return
+	}
+	changes, err := release.DiffVersions(from, to)
+	if ctrl.handleError(w, r, err) {
+		return
+	}
+
+	type Content struct {
+		From       release.Version
+		To         release.Version
+		Changes    []release.VersionChange
+		HistoryURL string
+	}
+
+	ctrl.Render(w, `/history/diff.html`, Content{
+		From:       from,
+		To:         to,
+		Changes:    changes,
+		HistoryURL: historyURL(to.Kind, to.EntityID),
+	})
+}
+
+func (ctrl *Controller) historyRevertAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	manager := ctrl.UseCases.RolloutManager
+	version, err := manager.FindVersion(r.Context(), r.FormValue(`version_id`))
+	if ctrl.handleError(w, r, err) {
+		return
+	}
+
+	if err := manager.RevertToVersion(r.Context(), version.ID); err != nil {
+		logging.Error(r.Context(), `unable to revert to the version`, err)
+	}
+
+	http.Redirect(w, r, historyURL(version.Kind, version.EntityID), http.StatusFound)
+}
+
+func (ctrl *Controller) historyEvaluateAction(w http.ResponseWriter, r *http.Request) {
+	type Content struct {
+		Environments  []release.Environment
+		EnvironmentID string
+		PilotID       string
+		AsOf          string
+		Evaluations   []release.Evaluation
+	}
+
+	q := r.URL.Query()
+	content := Content{
+		EnvironmentID: q.Get(`env-id`),
+		PilotID:       q.Get(`pilot-id`),
+		AsOf:          q.Get(`as-of`),
+	}
+
+	if ctrl.handleError(w, r, iterators.Collect(ctrl.UseCases.Storage.ReleaseEnvironment(r.Context()).FindAll(r.Context()), &content.Environments)) {
+		return
+	}
+
+	if content.EnvironmentID != `` && content.PilotID != `` && content.AsOf != `` {
+		// the datetime-local input of the form has no time zone, so it is taken as UTC.
+		asOf, err := time.Parse(`2006-01-02T15:04`, content.AsOf)
+		if ctrl.handleError(w, r, err) {
+			return
+		}
+
+		var env release.Environment
+		found, err := ctrl.UseCases.Storage.ReleaseEnvironment(r.Context()).FindByID(r.Context(), &env, content.EnvironmentID)
+		if ctrl.handleError(w, r, err) {
+			return
+		}
+
+		if found {
+			// the whole minute of the form input is included.
+			content.Evaluations, err = ctrl.UseCases.RolloutManager.EvaluateFlagsAsOf(r.Context(), asOf.Add(time.Minute-time.Nanosecond), content.PilotID, env)
+			if ctrl.handleError(w, r, err) {
+				return
+			}
+		}
+	}
+
+	ctrl.Render(w, `/history/evaluate.html`, content)
+}
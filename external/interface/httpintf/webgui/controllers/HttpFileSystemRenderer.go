@@ -4,7 +4,7 @@ import (
 	"bytes"
 	"html/template"
 	"io/ioutil"
-	"log"
+	"log/slog"
 	"net/http"
 )
 
@@ -43,13 +43,13 @@ func (r *HttpFileSystemRenderer) Render(w http.ResponseWriter, tempName string,
 	tmpl := template.New(``)
 
 	if tmpl, err = tmpl.New(`page`).Parse(r.Layout); err != nil {
-		log.Println(err)
+		slog.Error(`unable to parse the layout template`, slog.String(`template`, tempName), slog.String(`error`, err.Error()))
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
 
 	if tmpl, err = tmpl.New(`content`).Parse(pageRawStr); err != nil {
-		log.Println(err)
+		slog.Error(`unable to parse the page template`, slog.String(`template`, tempName), slog.String(`error`, err.Error()))
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
@@ -57,13 +57,13 @@ func (r *HttpFileSystemRenderer) Render(w http.ResponseWriter, tempName string,
 	buf := bytes.NewBuffer([]byte{})
 
 	if err := tmpl.ExecuteTemplate(buf, `page`, data); err != nil {
-		log.Println(err)
+		slog.Error(`unable to render the page`, slog.String(`template`, tempName), slog.String(`error`, err.Error()))
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
 
 	if _, err := w.Write(buf.Bytes()); err != nil {
-		log.Println(err)
+		slog.Error(`unable to write the page`, slog.String(`template`, tempName), slog.String(`error`, err.Error()))
 	}
 
 	return
@@ -1,10 +1,10 @@
 package controllers
 
 import (
-	"log"
 	"net/http"
 
 	"github.com/toggler-io/toggler/external/interface/httpintf/webgui/cookies"
+	"github.com/toggler-io/toggler/external/logging"
 )
 
 func (ctrl *Controller) LoginPage(w http.ResponseWriter, r *http.Request) {
@@ -23,7 +23,7 @@ func (ctrl *Controller) LoginPage(w http.ResponseWriter, r *http.Request) {
 
 		valid, err := ctrl.UseCases.Doorkeeper.VerifyTextToken(r.Context(), token)
 		if err != nil {
-			log.Println(`ERROR`, err.Error())
+			logging.Error(r.Context(), `unable to verify the token`, err)
 			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 			return
 		}
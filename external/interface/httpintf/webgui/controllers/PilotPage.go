@@ -2,7 +2,7 @@ package controllers
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
@@ -10,8 +10,10 @@ import (
 	"github.com/adamluzsi/frameless/iterators"
 	"github.com/pkg/errors"
 
+	"github.com/toggler-io/toggler/domains/change"
 	"github.com/toggler-io/toggler/domains/release"
 	"github.com/toggler-io/toggler/external/interface/httpintf/httputils"
+	"github.com/toggler-io/toggler/external/logging"
 )
 
 func (ctrl *Controller) PilotPage(w http.ResponseWriter, r *http.Request) {
@@ -75,7 +77,7 @@ func (ctrl *Controller) pilotEditPage(w http.ResponseWriter, r *http.Request) {
 	for pilots.Next() {
 		var p release.Pilot
 
-		if httputils.HandleError(w, pilots.Decode(&p), http.StatusInternalServerError) {
+		if httputils.HandleError(w, r, pilots.Decode(&p), http.StatusInternalServerError) {
 			return
 		}
 
@@ -84,7 +86,7 @@ func (ctrl *Controller) pilotEditPage(w http.ResponseWriter, r *http.Request) {
 
 	ffs, err := ctrl.UseCases.RolloutManager.ListFeatureFlags(r.Context())
 
-	if httputils.HandleError(w, err, http.StatusInternalServerError) {
+	if httputils.HandleError(w, r, err, http.StatusInternalServerError) {
 		return
 	}
 
@@ -133,15 +135,21 @@ func (ctrl *Controller) pilotFlagSetRollout(w http.ResponseWriter, r *http.Reque
 	pilot.PublicID = r.FormValue(`pilot.ext_id`)
 	newEnrollmentStatus := r.FormValue(`pilot.is_participating`)
 
-	log.Println(`flag:`, pilot.FlagID,
-		`env:`, pilot.EnvironmentID,
-		`ext:`, pilot.PublicID,
-		`is_participating`, newEnrollmentStatus)
+	logging.FromContext(r.Context()).Debug(`set pilot enrollment`,
+		slog.String(`flag_id`, pilot.FlagID),
+		slog.String(`env_id`, pilot.EnvironmentID),
+		slog.String(`pilot_external_id`, pilot.PublicID),
+		slog.String(`is_participating`, newEnrollmentStatus),
+	)
 
-	err := ctrl.setPilotManualEnrollmentForFlag(r.Context(), newEnrollmentStatus, pilot.FlagID, pilot.EnvironmentID, pilot.PublicID)
+	ctx := change.ContextWithProposals(r.Context())
+	err := ctrl.setPilotManualEnrollmentForFlag(ctx, newEnrollmentStatus, pilot.FlagID, pilot.EnvironmentID, pilot.PublicID)
 
-	if httputils.HandleError(w, err, http.StatusInternalServerError) {
-		log.Println(err.Error())
+	if httputils.HandleError(w, r, err, http.StatusInternalServerError) {
+		return
+	}
+	if ctrl.proposedRedirect(w, r, ctx) {
+		return
 	}
 
 	u, _ := url.Parse(`/pilot/edit`)
@@ -2,7 +2,7 @@ package controllers
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -10,8 +10,10 @@ import (
 	"github.com/adamluzsi/frameless/iterators"
 	"github.com/pkg/errors"
 
+	"github.com/toggler-io/toggler/domains/change"
 	"github.com/toggler-io/toggler/domains/release"
 	"github.com/toggler-io/toggler/external/interface/httpintf/httputils"
+	"github.com/toggler-io/toggler/external/logging"
 )
 
 func (ctrl *Controller) RolloutPage(w http.ResponseWriter, r *http.Request) {
@@ -74,19 +76,19 @@ func (ctrl *Controller) rolloutIndexPage(w http.ResponseWriter, r *http.Request)
 
 	var env release.Environment
 	found, err := ctrl.UseCases.Storage.ReleaseEnvironment(r.Context()).FindByID(r.Context(), &env, envID)
-	if httputils.HandleError(w, err, http.StatusNotFound) {
-		log.Println(`ERROR`, err.Error())
+	if httputils.HandleError(w, r, err, http.StatusNotFound) {
+		logging.Error(r.Context(), `unable to find the deployment environment`, err)
 		return
 	}
 	if !found {
-		log.Println(`WARNING`, `environment id not found: `, envID)
+		logging.FromContext(r.Context()).Warn(`deployment environment not found`, slog.String(`env_id`, envID))
 		http.Redirect(w, r, `/`, http.StatusFound)
 		return
 	}
 
 	ffs, err := ctrl.UseCases.RolloutManager.ListFeatureFlags(r.Context())
 
-	if httputils.HandleError(w, err, http.StatusInternalServerError) {
+	if httputils.HandleError(w, r, err, http.StatusInternalServerError) {
 		return
 	}
 
@@ -116,7 +118,7 @@ func (ctrl *Controller) rolloutIndexPage(w http.ResponseWriter, r *http.Request)
 			if bp, ok := rollout.Plan.(release.RolloutDecisionByPercentage); ok {
 				byPercentage = bp
 			} else {
-				log.Println(`ERROR`, `webgui is unable to handle the management of a complex rollout plan`)
+				logging.FromContext(r.Context()).Error(`webgui is unable to handle the management of a complex rollout plan`)
 				http.Redirect(w, r, `/`, http.StatusFound)
 				return
 			}
@@ -138,7 +140,7 @@ func (ctrl *Controller) rolloutEditPage(w http.ResponseWriter, r *http.Request)
 	flagID := query.Get(`flag-id`)
 	envID := query.Get(`env-id`)
 
-	log.Println(`flagID:`, flagID, `envID:`, envID)
+	logging.FromContext(r.Context()).Debug(`edit rollout`, slog.String(`flag_id`, flagID), slog.String(`env_id`, envID))
 
 	var env release.Environment
 	if found, err := ctrl.UseCases.Storage.ReleaseEnvironment(r.Context()).FindByID(r.Context(), &env, envID); ctrl.handleError(w, r, err) {
@@ -173,7 +175,7 @@ func (ctrl *Controller) rolloutEditPage(w http.ResponseWriter, r *http.Request)
 		if bp, ok := rollout.Plan.(release.RolloutDecisionByPercentage); ok {
 			byPercentage = bp
 		} else {
-			log.Println(`ERROR`, `webgui is unable to handle the management of a complex rollout plan`)
+			logging.FromContext(r.Context()).Error(`webgui is unable to handle the management of a complex rollout plan`)
 			redirectToIndexPage()
 			return
 		}
@@ -184,6 +186,9 @@ func (ctrl *Controller) rolloutEditPage(w http.ResponseWriter, r *http.Request)
 	}
 
 	type Content struct {
+		RolloutID             string
+		RolloutRevision       int
+		Conflict              bool
 		ReleaseFlagName       string
 		ReleaseFlagID         string
 		DeployEnvironmentID   string
@@ -191,6 +196,9 @@ func (ctrl *Controller) rolloutEditPage(w http.ResponseWriter, r *http.Request)
 		ByPercentage          release.RolloutDecisionByPercentage
 	}
 	content := Content{
+		RolloutID:             rollout.ID,
+		RolloutRevision:       rollout.Revision,
+		Conflict:              query.Get(`conflict`) != ``,
 		ReleaseFlagName:       flag.Name,
 		ReleaseFlagID:         flag.ID,
 		DeployEnvironmentID:   env.ID,
@@ -206,10 +214,20 @@ func (ctrl *Controller) rolloutUpdateAction(w http.ResponseWriter, r *http.Reque
 	rollout.FlagID = r.FormValue(`flag_id`)
 	rollout.EnvironmentID = r.FormValue(`env_id`)
 
+	// the revision of the rollout when the edit page was loaded,
+	// so the changes made since then are not overwritten silently.
+	revision, _ := strconv.Atoi(r.FormValue(`revision`))
+
 	if storedRollout, found, err := ctrl.lookupRollout(r.Context(), rollout.FlagID, rollout.EnvironmentID); ctrl.handleError(w, r, err) {
 		return
 	} else if found {
+		if revision == 0 {
+			// the rollout was created since the edit page was loaded
+			ctrl.rolloutConflictRedirect(w, r, rollout)
+			return
+		}
 		rollout = storedRollout
+		rollout.Revision = revision
 	}
 
 	percentage, err := strconv.Atoi(r.FormValue(`percentage`))
@@ -227,14 +245,23 @@ func (ctrl *Controller) rolloutUpdateAction(w http.ResponseWriter, r *http.Reque
 	byPercentage.Seed = seed
 	rollout.Plan = byPercentage
 
+	ctx := change.ContextWithProposals(r.Context())
 	if rollout.ID == `` {
-		if ctrl.handleError(w, r, ctrl.UseCases.Storage.ReleaseRollout(r.Context()).Create(r.Context(), &rollout)) {
+		if ctrl.handleError(w, r, ctrl.UseCases.Storage.ReleaseRollout(ctx).Create(ctx, &rollout)) {
 			return
 		}
 	} else {
-		if ctrl.handleError(w, r, ctrl.UseCases.Storage.ReleaseRollout(r.Context()).Update(r.Context(), &rollout)) {
+		err := ctrl.UseCases.Storage.ReleaseRollout(ctx).Update(ctx, &rollout)
+		if err == release.ErrRevisionConflict {
+			ctrl.rolloutConflictRedirect(w, r, rollout)
 			return
 		}
+		if ctrl.handleError(w, r, err) {
+			return
+		}
+	}
+	if ctrl.proposedRedirect(w, r, ctx) {
+		return
 	}
 
 	u, _ := url.Parse(`/rollout/index`)
@@ -245,6 +272,18 @@ func (ctrl *Controller) rolloutUpdateAction(w http.ResponseWriter, r *http.Reque
 
 }
 
+// rolloutConflictRedirect sends the user back to the edit page with the current state of the rollout,
+// which tells that the rollout was changed by someone else in the meantime.
+func (ctrl *Controller) rolloutConflictRedirect(w http.ResponseWriter, r *http.Request, rollout release.Rollout) {
+	u, _ := url.Parse(`/rollout/edit`)
+	q := u.Query()
+	q.Set(`flag-id`, rollout.FlagID)
+	q.Set(`env-id`, rollout.EnvironmentID)
+	q.Set(`conflict`, `true`)
+	u.RawQuery = q.Encode()
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
 func (ctrl *Controller) lookupRollout(ctx context.Context, flagID, envID string) (release.Rollout, bool, error) {
 	s := ctrl.UseCases.Storage
 
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/schedule"
+)
+
+func (ctrl *Controller) SchedulePage(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case `/schedule`:
+		ctrl.scheduleListAction(w, r)
+	case `/schedule/show`:
+		ctrl.scheduleShowAction(w, r)
+	case `/schedule/cancel`:
+		ctrl.scheduleCancelAction(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func scheduleURL(changeID string) string {
+	u, _ := url.Parse(`/schedule/show`)
+	q := u.Query()
+	q.Set(`id`, changeID)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func (ctrl *Controller) scheduleListAction(w http.ResponseWriter, r *http.Request) {
+	status := schedule.StatusScheduled
+	if _, ok := r.URL.Query()[`status`]; ok {
+		status = r.URL.Query().Get(`status`)
+	}
+
+	type Content struct {
+		Status       string
+		Changes      []schedule.Change
+		Environments map[string]string
+	}
+
+	content := Content{Status: status, Environments: make(map[string]string)}
+	changes, err := ctrl.UseCases.Scheduler.List(r.Context(), schedule.ChangeQuery{Status: status})
+	if ctrl.handleError(w, r, err) {
+		return
+	}
+	content.Changes = changes
+
+	for _, c := range changes {
+		if _, ok := content.Environments[c.EnvironmentID]; ok {
+			continue
+		}
+		var env release.Environment
+		if _, err := ctrl.UseCases.Storage.ReleaseEnvironment(r.Context()).FindByID(r.Context(), &env, c.EnvironmentID); ctrl.handleError(w, r, err) {
+			return
+		}
+		content.Environments[c.EnvironmentID] = env.Name
+	}
+
+	ctrl.Render(w, `/schedule/index.html`, content)
+}
+
+func (ctrl *Controller) scheduleShowAction(w http.ResponseWriter, r *http.Request) {
+	type Content struct {
+		Change      schedule.Change
+		Environment release.Environment
+	}
+
+	var content Content
+	c, err := ctrl.UseCases.Scheduler.Find(r.Context(), r.URL.Query().Get(`id`))
+	if ctrl.handleError(w, r, err) {
+		return
+	}
+	content.Change = c
+
+	if _, err := ctrl.UseCases.Storage.ReleaseEnvironment(r.Context()).FindByID(r.Context(), &content.Environment, c.EnvironmentID); ctrl.handleError(w, r, err) {
+		return
+	}
+
+	ctrl.Render(w, `/schedule/show.html`, content)
+}
+
+func (ctrl *Controller) scheduleCancelAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	changeID := r.FormValue(`id`)
+	if ctrl.handleError(w, r, ctrl.UseCases.Scheduler.Cancel(r.Context(), changeID)) {
+		return
+	}
+
+	http.Redirect(w, r, scheduleURL(changeID), http.StatusFound)
+}
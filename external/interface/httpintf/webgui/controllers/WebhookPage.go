@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/adamluzsi/frameless/iterators"
+
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/webhook"
+	"github.com/toggler-io/toggler/external/logging"
+)
+
+func (ctrl *Controller) WebhookPage(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case `/webhook`:
+		switch r.Method {
+		case http.MethodGet:
+			ctrl.webhookListAction(w, r)
+		case http.MethodPost:
+			ctrl.webhookCreateAction(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	case `/webhook/delete`:
+		ctrl.webhookDeleteAction(w, r)
+	case `/webhook/deliveries`:
+		ctrl.webhookDeliveriesAction(w, r)
+	case `/webhook/redeliver`:
+		ctrl.webhookRedeliverAction(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func webhookDeliveriesURL(subscriptionID string) string {
+	u, _ := url.Parse(`/webhook/deliveries`)
+	q := u.Query()
+	q.Set(`subscription_id`, subscriptionID)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func (ctrl *Controller) webhookListAction(w http.ResponseWriter, r *http.Request) {
+	type Content struct {
+		Subscriptions []webhook.Subscription
+		Environments  []release.Environment
+		EventTypes    []string
+	}
+
+	content := Content{EventTypes: webhook.EventTypes}
+	if ctrl.handleError(w, r, iterators.Collect(ctrl.UseCases.Storage.WebhookSubscription(r.Context()).FindAll(r.Context()), &content.Subscriptions)) {
+		return
+	}
+	if ctrl.handleError(w, r, iterators.Collect(ctrl.UseCases.Storage.ReleaseEnvironment(r.Context()).FindAll(r.Context()), &content.Environments)) {
+		return
+	}
+
+	ctrl.Render(w, `/webhook/index.html`, content)
+}
+
+func (ctrl *Controller) webhookCreateAction(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); ctrl.handleError(w, r, err) {
+		return
+	}
+
+	s := webhook.Subscription{
+		URL:            r.PostForm.Get(`url`),
+		Secret:         r.PostForm.Get(`secret`),
+		EventTypes:     r.PostForm[`event_types`],
+		EnvironmentIDs: r.PostForm[`environment_ids`],
+	}
+
+	if ctrl.handleError(w, r, ctrl.UseCases.Webhooks.CreateSubscription(r.Context(), &s)) {
+		return
+	}
+
+	http.Redirect(w, r, `/webhook`, http.StatusFound)
+}
+
+func (ctrl *Controller) webhookDeleteAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := ctrl.UseCases.Webhooks.DeleteSubscription(r.Context(), r.FormValue(`subscription_id`)); err != nil {
+		logging.Error(r.Context(), `unable to delete the webhook subscription`, err)
+	}
+
+	http.Redirect(w, r, `/webhook`, http.StatusFound)
+}
+
+func (ctrl *Controller) webhookDeliveriesAction(w http.ResponseWriter, r *http.Request) {
+	type Content struct {
+		Subscription webhook.Subscription
+		Deliveries   []webhook.Delivery
+	}
+
+	var content Content
+	subscriptionID := r.URL.Query().Get(`subscription_id`)
+	found, err := ctrl.UseCases.Storage.WebhookSubscription(r.Context()).FindByID(r.Context(), &content.Subscription, subscriptionID)
+	if ctrl.handleError(w, r, err) {
+		return
+	}
+	if !found {
+		http.Redirect(w, r, `/webhook`, http.StatusFound)
+		return
+	}
+
+	deliveries := ctrl.UseCases.Storage.WebhookDelivery(r.Context()).FindByQuery(r.Context(), webhook.DeliveryQuery{SubscriptionID: subscriptionID})
+	if ctrl.handleError(w, r, iterators.Collect(deliveries, &content.Deliveries)) {
+		return
+	}
+
+	ctrl.Render(w, `/webhook/deliveries.html`, content)
+}
+
+func (ctrl *Controller) webhookRedeliverAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var delivery webhook.Delivery
+	found, err := ctrl.UseCases.Storage.WebhookDelivery(r.Context()).FindByID(r.Context(), &delivery, r.FormValue(`delivery_id`))
+	if ctrl.handleError(w, r, err) {
+		return
+	}
+	if !found {
+		http.Redirect(w, r, `/webhook`, http.StatusFound)
+		return
+	}
+
+	if err := ctrl.UseCases.Webhooks.Redeliver(r.Context(), delivery.ID); err != nil {
+		logging.Error(r.Context(), `unable to redeliver the webhook`, err)
+	}
+
+	http.Redirect(w, r, webhookDeliveriesURL(delivery.SubscriptionID), http.StatusFound)
+}
@@ -2,11 +2,13 @@ package cookies
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
+	"github.com/toggler-io/toggler/domains/security"
 	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/external/logging"
 )
 
 const authTokenCookieName = `auth-token`
@@ -58,15 +60,14 @@ type AuthTokenMiddleware struct {
 	Next       http.Handler
 	RedirectTo string
 	Doorkeeper interface {
-		VerifyTextToken(ctx context.Context, textToken string) (bool, error)
+		LookupTextToken(ctx context.Context, textToken string) (*security.Token, bool, error)
 	}
 }
 
 func (mw *AuthTokenMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	log.Println(`DEBUG`, r.URL.Path)
 	token, ok, err := LookupAuthToken(r)
 	if err != nil {
-		log.Println(`ERROR`, err.Error())
+		logging.Error(r.Context(), `unable to read the auth token cookie`, err)
 		const code = http.StatusInternalServerError
 		http.Error(w, http.StatusText(code), code)
 		return
@@ -76,9 +77,9 @@ func (mw *AuthTokenMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	valid, err := mw.Doorkeeper.VerifyTextToken(r.Context(), string(token))
+	t, valid, err := mw.Doorkeeper.LookupTextToken(r.Context(), string(token))
 	if err != nil {
-		log.Println(`ERROR`, err.Error())
+		logging.Error(r.Context(), `unable to verify the auth token`, err)
 		const code = http.StatusInternalServerError
 		http.Error(w, http.StatusText(code), code)
 		return
@@ -88,5 +89,7 @@ func (mw *AuthTokenMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	mw.Next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), AuthTokenContextKey{}, token)))
+	logging.With(r.Context(), slog.String(`token_owner`, t.OwnerUID))
+	ctx := security.ContextWithOwner(r.Context(), t.OwnerUID)
+	mw.Next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, AuthTokenContextKey{}, token)))
 }
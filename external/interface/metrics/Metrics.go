@@ -0,0 +1,141 @@
+// Package metrics exposes the measurements of toggler in the Prometheus text format.
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/external/resource/caches"
+)
+
+const namespace = `toggler`
+
+func New() *Metrics {
+	m := &Metrics{Registry: prometheus.NewRegistry()}
+
+	m.httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      `http_requests_total`,
+		Help:      `The number of the served HTTP requests, by route, method and status code.`,
+	}, []string{`route`, `method`, `status`})
+	m.httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      `http_request_duration_seconds`,
+		Help:      `The time it took to serve the HTTP requests, by route, method and status code.`,
+		Buckets:   prometheus.DefBuckets,
+	}, []string{`route`, `method`, `status`})
+	m.evaluations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      `release_flag_evaluations_total`,
+		Help:      `The number of the release flag evaluations, by deployment environment and result.`,
+	}, []string{`environment`, `result`})
+	m.planEvaluationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      `rollout_plan_evaluation_duration_seconds`,
+		Help:      `The time it took to evaluate the rollout plans, by plan type.`,
+		Buckets:   prometheus.DefBuckets,
+	}, []string{`plan_type`})
+	m.decisionByAPICalls = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      `rollout_decision_by_api_duration_seconds`,
+		Help:      `The time the calls of the RolloutDecisionByAPI plans took, by outcome.`,
+		Buckets:   prometheus.DefBuckets,
+	}, []string{`outcome`})
+	m.storageOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      `storage_operation_duration_seconds`,
+		Help:      `The time the storage operations took, by entity and operation.`,
+		Buckets:   prometheus.DefBuckets,
+	}, []string{`entity`, `operation`})
+
+	m.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpRequestDuration,
+		m.evaluations,
+		m.planEvaluationDuration,
+		m.decisionByAPICalls,
+		m.storageOperationDuration,
+	)
+	return m
+}
+
+// Metrics collects the measurements of the HTTP interface, the release flag evaluations, the cache and the storage.
+// It implements release.Observer, so it can be used as the observer of the release.RolloutManager.
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	httpRequests             *prometheus.CounterVec
+	httpRequestDuration      *prometheus.HistogramVec
+	evaluations              *prometheus.CounterVec
+	planEvaluationDuration   *prometheus.HistogramVec
+	decisionByAPICalls       *prometheus.HistogramVec
+	storageOperationDuration *prometheus.HistogramVec
+}
+
+// Handler serves the metrics in the Prometheus text format.
+// When the token is not empty, the requests must present it as a bearer token in the Authorization header.
+func (m *Metrics) Handler(token string) http.Handler {
+	h := promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{})
+	if token == `` {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given := strings.TrimPrefix(r.Header.Get(`Authorization`), `Bearer `)
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set(`WWW-Authenticate`, `Bearer`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (m *Metrics) ObserveEvaluation(env release.Environment, result release.EvaluationResult) {
+	m.evaluations.WithLabelValues(env.Name, string(result)).Inc()
+}
+
+func (m *Metrics) ObservePlanEvaluation(planType string, d time.Duration) {
+	m.planEvaluationDuration.WithLabelValues(planType).Observe(d.Seconds())
+}
+
+func (m *Metrics) ObserveDecisionByAPI(outcome release.EvaluationResult, d time.Duration) {
+	m.decisionByAPICalls.WithLabelValues(string(outcome)).Observe(d.Seconds())
+}
+
+// Cache is a cache which keeps usage counters, like caches.Memory.
+type Cache interface {
+	Stats() caches.Stats
+}
+
+// ObserveCache exposes the usage counters of the cache,
+// so the hit ratio can be calculated from the hits and the misses.
+func (m *Metrics) ObserveCache(c Cache) {
+	counter := func(name, help string, value func(caches.Stats) uint64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{Namespace: namespace, Name: name, Help: help},
+			func() float64 { return float64(value(c.Stats())) })
+	}
+	m.Registry.MustRegister(
+		counter(`cache_hits_total`, `The number of the lookups that were served from the cache.`,
+			func(s caches.Stats) uint64 { return s.Hits }),
+		counter(`cache_misses_total`, `The number of the lookups that had to reach the storage.`,
+			func(s caches.Stats) uint64 { return s.Misses }),
+		counter(`cache_evictions_total`, `The number of the entries that were evicted from the cache.`,
+			func(s caches.Stats) uint64 { return s.Evictions }),
+		counter(`cache_expirations_total`, `The number of the entries that were removed from the cache by their TTL.`,
+			func(s caches.Stats) uint64 { return s.Expirations }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      `cache_entries`,
+			Help:      `The number of the entries currently kept in the cache.`,
+		}, func() float64 { return float64(c.Stats().Entries) }),
+	)
+}
@@ -0,0 +1,135 @@
+package metrics_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/external/interface/metrics"
+	"github.com/toggler-io/toggler/external/resource/caches"
+	"github.com/toggler-io/toggler/external/resource/storages"
+)
+
+func TestMetrics(t *testing.T) {
+	s := testcase.NewSpec(t)
+	defer s.Finish()
+
+	m := s.Let(`metrics`, func(t *testcase.T) interface{} { return metrics.New() })
+	mGet := func(t *testcase.T) *metrics.Metrics { return m.Get(t).(*metrics.Metrics) }
+	token := s.Let(`token`, func(t *testcase.T) interface{} { return `` })
+
+	scrape := func(t *testcase.T, header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, `/metrics`, nil)
+		r.Header = header
+		w := httptest.NewRecorder()
+		mGet(t).Handler(token.Get(t).(string)).ServeHTTP(w, r)
+		return w
+	}
+	body := func(t *testcase.T) string {
+		w := scrape(t, http.Header{})
+		require.Equal(t, http.StatusOK, w.Code)
+		bs, err := ioutil.ReadAll(w.Body)
+		require.Nil(t, err)
+		return string(bs)
+	}
+
+	s.Describe(`#Handler`, func(s *testcase.Spec) {
+		s.Then(`it serves the metrics in the Prometheus text format`, func(t *testcase.T) {
+			require.Contains(t, body(t), `go_goroutines`)
+		})
+
+		s.When(`a token is configured`, func(s *testcase.Spec) {
+			token.Let(s, func(t *testcase.T) interface{} { return `secret` })
+
+			s.Then(`the request without the token is rejected`, func(t *testcase.T) {
+				require.Equal(t, http.StatusUnauthorized, scrape(t, http.Header{}).Code)
+			})
+
+			s.Then(`the request with a wrong token is rejected`, func(t *testcase.T) {
+				require.Equal(t, http.StatusUnauthorized, scrape(t, http.Header{`Authorization`: {`Bearer wrong`}}).Code)
+			})
+
+			s.Then(`the request with the token is served`, func(t *testcase.T) {
+				require.Equal(t, http.StatusOK, scrape(t, http.Header{`Authorization`: {`Bearer secret`}}).Code)
+			})
+		})
+	})
+
+	s.Describe(`#Middleware`, func(s *testcase.Spec) {
+		s.Then(`it counts the requests by route, method and status`, func(t *testcase.T) {
+			h := mGet(t).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+			}), func(r *http.Request) string { return `/api/release-flags/` })
+
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, `/api/release-flags/42`, nil))
+
+			out := body(t)
+			require.Contains(t, out, `toggler_http_requests_total{method="GET",route="/api/release-flags/",status="418"} 1`)
+			require.Contains(t, out, `toggler_http_request_duration_seconds_count{method="GET",route="/api/release-flags/",status="418"} 1`)
+		})
+	})
+
+	s.Describe(`as release.Observer`, func(s *testcase.Spec) {
+		s.Then(`it counts the evaluations and measures the rollout plans`, func(t *testcase.T) {
+			var o release.Observer = mGet(t)
+			o.ObserveEvaluation(release.Environment{Name: `production`}, release.EvaluationResultEnabled)
+			o.ObservePlanEvaluation(`percentage`, time.Millisecond)
+			o.ObserveDecisionByAPI(release.EvaluationResultError, time.Millisecond)
+
+			out := body(t)
+			require.Contains(t, out, `toggler_release_flag_evaluations_total{environment="production",result="enabled"} 1`)
+			require.Contains(t, out, `toggler_rollout_plan_evaluation_duration_seconds_count{plan_type="percentage"} 1`)
+			require.Contains(t, out, `toggler_rollout_decision_by_api_duration_seconds_count{outcome="error"} 1`)
+		})
+	})
+
+	s.Describe(`#ObserveCache`, func(s *testcase.Spec) {
+		s.Then(`it exposes the usage counters of the cache`, func(t *testcase.T) {
+			mGet(t).ObserveCache(fakeCache{Hits: 3, Misses: 1, Entries: 2})
+
+			out := body(t)
+			require.Contains(t, out, `toggler_cache_hits_total 3`)
+			require.Contains(t, out, `toggler_cache_misses_total 1`)
+			require.Contains(t, out, `toggler_cache_entries 2`)
+		})
+	})
+
+	s.Describe(`#Storage`, func(s *testcase.Spec) {
+		s.Then(`it measures the storage operations`, func(t *testcase.T) {
+			src := storages.NewInMemory()
+			t.Defer(src.Close)
+			storage := mGet(t).Storage(src)
+			ctx := context.Background()
+
+			flag := release.Flag{Name: `feature`}
+			require.Nil(t, storage.ReleaseFlag(ctx).Create(ctx, &flag))
+			_, err := storage.ReleaseFlag(ctx).FindByName(ctx, flag.Name)
+			require.Nil(t, err)
+			var flags []release.Flag
+			iter := storage.ReleaseFlag(ctx).FindAll(ctx)
+			for iter.Next() {
+				var f release.Flag
+				require.Nil(t, iter.Decode(&f))
+				flags = append(flags, f)
+			}
+			require.Nil(t, iter.Close())
+			require.Len(t, flags, 1)
+
+			out := body(t)
+			require.Contains(t, out, `toggler_storage_operation_duration_seconds_count{entity="flag",operation="create"} 1`)
+			require.Contains(t, out, `toggler_storage_operation_duration_seconds_count{entity="flag",operation="find_by_name"} 1`)
+			require.Contains(t, out, `toggler_storage_operation_duration_seconds_count{entity="flag",operation="find_all"} 1`)
+		})
+	})
+}
+
+type fakeCache caches.Stats
+
+func (c fakeCache) Stats() caches.Stats { return caches.Stats(c) }
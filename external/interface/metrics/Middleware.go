@@ -0,0 +1,22 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/felixge/httpsnoop"
+)
+
+// Middleware measures the requests served by the next handler.
+// The route func groups the requests, and it must return a value from a small set, such as the pattern of the handler,
+// because each distinct route is a new time series.
+func (m *Metrics) Middleware(next http.Handler, route func(*http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// httpsnoop keeps the optional interfaces of the ResponseWriter, like the http.Hijacker of the websocket upgrade.
+		sm := httpsnoop.CaptureMetrics(next, w, r)
+
+		labels := []string{route(r), r.Method, strconv.Itoa(sm.Code)}
+		m.httpRequests.WithLabelValues(labels...).Inc()
+		m.httpRequestDuration.WithLabelValues(labels...).Observe(sm.Duration.Seconds())
+	})
+}
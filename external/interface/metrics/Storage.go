@@ -0,0 +1,20 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/external/resource/storages/instrumented"
+)
+
+// Storage wraps the storage, so the latency of its operations is measured.
+// The operations which return an iterator are measured until the iterator is closed.
+func (m *Metrics) Storage(s toggler.Storage) toggler.Storage {
+	return instrumented.NewStorage(s, func(ctx context.Context, entity, operation string) (context.Context, func(error)) {
+		start := time.Now()
+		return ctx, func(error) {
+			m.storageOperationDuration.WithLabelValues(entity, operation).Observe(time.Since(start).Seconds())
+		}
+	})
+}
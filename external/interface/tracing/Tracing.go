@@ -0,0 +1,123 @@
+// Package tracing exports the OpenTelemetry spans of the application.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/external/resource/storages/instrumented"
+)
+
+const (
+	// ExporterNone disables the export of the spans.
+	ExporterNone = `none`
+	// ExporterStdout writes the spans to the standard output, which is meant for local use.
+	ExporterStdout = `stdout`
+	// ExporterOTLP sends the spans to an OpenTelemetry collector over OTLP/HTTP.
+	ExporterOTLP = `otlp`
+)
+
+const instrumentationName = `github.com/toggler-io/toggler/external/interface/tracing`
+
+type Config struct {
+	// Exporter is one of ExporterNone, ExporterStdout or ExporterOTLP.
+	// When it is empty, the spans are not exported.
+	Exporter string
+	// Endpoint is the host:port of the OTLP collector.
+	// When it is empty, the OTLP exporter uses its default endpoint or the OTEL_EXPORTER_OTLP_ENDPOINT env variable.
+	Endpoint string
+	// Insecure makes the OTLP exporter connect to the collector without TLS.
+	Insecure bool
+	// ServiceName is the name the spans are reported under.
+	ServiceName string
+	// Writer is where the stdout exporter writes the spans, by default to the standard output.
+	Writer io.Writer
+}
+
+// Setup registers the tracer provider and the trace context propagator globally,
+// so the instrumented parts of the application start to export their spans.
+// The returned shutdown func flushes the spans which are not yet exported.
+func Setup(ctx context.Context, c Config) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	switch c.Exporter {
+	case ExporterNone, ``:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		w := c.Writer
+		if w == nil {
+			w = os.Stdout
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if c.Endpoint != `` {
+			opts = append(opts, otlptracehttp.WithEndpoint(c.Endpoint))
+		}
+		if c.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf(`unknown tracing exporter: %s`, c.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	serviceName := c.ServiceName
+	if serviceName == `` {
+		serviceName = `toggler`
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// Middleware starts a server span for each request, continuing the trace of the caller when it is propagated.
+// The span is named after the route, so requests of the same handler are grouped together.
+func Middleware(next http.Handler, route func(*http.Request) string) http.Handler {
+	return otelhttp.NewHandler(next, `http`, otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return r.Method + ` ` + route(r)
+	}))
+}
+
+// Storage wraps the storage, so each of its operations is recorded as a span.
+// The component is the prefix of the span names, so the cache and the underlying storage can be told apart.
+func Storage(s toggler.Storage, component string) toggler.Storage {
+	return instrumented.NewStorage(s, func(ctx context.Context, entity, operation string) (context.Context, func(error)) {
+		ctx, span := otel.Tracer(instrumentationName).Start(ctx, component+`.`+entity+`.`+operation, trace.WithAttributes(
+			attribute.String(`toggler.storage.component`, component),
+			attribute.String(`toggler.storage.entity`, entity),
+			attribute.String(`toggler.storage.operation`, operation),
+		))
+		return ctx, func(err error) {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/external/interface/tracing"
+	"github.com/toggler-io/toggler/external/resource/storages"
+)
+
+func TestTracing(t *testing.T) {
+	s := testcase.NewSpec(t)
+	defer s.Finish()
+
+	recorder := s.Let(`span recorder`, func(t *testcase.T) interface{} {
+		sr := tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+		return sr
+	})
+	spanNames := func(t *testcase.T) []string {
+		var names []string
+		for _, span := range recorder.Get(t).(*tracetest.SpanRecorder).Ended() {
+			names = append(names, span.Name())
+		}
+		return names
+	}
+
+	s.Describe(`.Setup`, func(s *testcase.Spec) {
+		s.Then(`an unknown exporter is rejected`, func(t *testcase.T) {
+			_, err := tracing.Setup(context.Background(), tracing.Config{Exporter: `unknown`})
+			require.Error(t, err)
+		})
+
+		s.Then(`the stdout exporter writes the spans on shutdown`, func(t *testcase.T) {
+			var buf bytes.Buffer
+			shutdown, err := tracing.Setup(context.Background(), tracing.Config{Exporter: tracing.ExporterStdout, Writer: &buf})
+			require.Nil(t, err)
+			_, span := otel.Tracer(`test`).Start(context.Background(), `test-span`)
+			span.End()
+			require.Nil(t, shutdown(context.Background()))
+			require.Contains(t, buf.String(), `test-span`)
+		})
+	})
+
+	s.Describe(`.Middleware`, func(s *testcase.Spec) {
+		s.Then(`the server span is named after the route and continues the propagated trace`, func(t *testcase.T) {
+			recorder.Get(t)
+			h := tracing.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+				func(r *http.Request) string { return `/api/release-flags/` })
+
+			const traceID = `4bf92f3577b34da6a3ce929d0e0e4736`
+			r := httptest.NewRequest(http.MethodGet, `/api/release-flags/42`, nil)
+			r.Header.Set(`traceparent`, `00-`+traceID+`-00f067aa0ba902b7-01`)
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			spans := recorder.Get(t).(*tracetest.SpanRecorder).Ended()
+			require.Len(t, spans, 1)
+			require.Equal(t, `GET /api/release-flags/`, spans[0].Name())
+			require.Equal(t, traceID, spans[0].SpanContext().TraceID().String())
+		})
+	})
+
+	s.Describe(`.Storage`, func(s *testcase.Spec) {
+		s.Then(`each storage operation is recorded as a span`, func(t *testcase.T) {
+			recorder.Get(t)
+			src := storages.NewInMemory()
+			t.Defer(src.Close)
+			storage := tracing.Storage(src, `storage`)
+			ctx := context.Background()
+
+			flag := release.Flag{Name: `feature`}
+			require.Nil(t, storage.ReleaseFlag(ctx).Create(ctx, &flag))
+			_, err := storage.ReleaseFlag(ctx).FindByName(ctx, flag.Name)
+			require.Nil(t, err)
+
+			require.Equal(t, []string{`storage.flag.create`, `storage.flag.find_by_name`}, spanNames(t))
+		})
+	})
+
+	s.Describe(`release.RolloutDecisionByAPI`, func(s *testcase.Spec) {
+		s.Then(`the trace context is propagated to the decision API`, func(t *testcase.T) {
+			recorder.Get(t)
+			var traceparent string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				traceparent = r.Header.Get(`traceparent`)
+			}))
+			t.Defer(srv.Close)
+			u, err := url.Parse(srv.URL)
+			require.Nil(t, err)
+
+			ok, err := release.RolloutDecisionByAPI{URL: u}.IsParticipating(context.Background(), `pilot`)
+			require.Nil(t, err)
+			require.True(t, ok)
+
+			require.Equal(t, []string{`HTTP GET`, `RolloutDecisionByAPI.IsParticipating`}, spanNames(t))
+			spans := recorder.Get(t).(*tracetest.SpanRecorder).Ended()
+			require.Contains(t, traceparent, spans[0].SpanContext().SpanID().String())
+		})
+	})
+}
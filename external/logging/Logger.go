@@ -0,0 +1,107 @@
+// Package logging provides the structured logger of the application.
+// The logger of a request is carried by its context,
+// so each log entry made while the request is served has the request id and the route.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+const (
+	FormatJSON = `json`
+	FormatText = `text`
+)
+
+type Config struct {
+	// Level is the minimum level of the logged entries: debug, info, warn or error.
+	// By default it is info.
+	Level string
+	// Format is the encoding of the entries: json or text.
+	// By default it is json.
+	Format string
+	// Writer is where the entries are written, by default to the standard error.
+	Writer io.Writer
+	// LevelVar is optional, when given, the level is set on it and the logger follows its changes,
+	// so the level can be changed without creating a new logger.
+	LevelVar *slog.LevelVar
+}
+
+// New creates a logger with the given configuration.
+func New(c Config) (*slog.Logger, error) {
+	level, err := ParseLevel(c.Level)
+	if err != nil {
+		return nil, err
+	}
+	var leveler slog.Leveler = level
+	if c.LevelVar != nil {
+		c.LevelVar.Set(level)
+		leveler = c.LevelVar
+	}
+
+	w := c.Writer
+	if w == nil {
+		w = os.Stderr
+	}
+
+	opts := &slog.HandlerOptions{Level: leveler}
+	switch strings.ToLower(c.Format) {
+	case FormatJSON, ``:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case FormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf(`unknown log format: %s`, c.Format)
+	}
+}
+
+// ParseLevel parses the name of a level, like "debug" or "warn".
+// The empty string means the info level.
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if name == `` {
+		return level, nil
+	}
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return level, fmt.Errorf(`unknown log level: %s`, name)
+	}
+	return level, nil
+}
+
+type contextKey struct{}
+
+// entry is shared by the contexts of a request,
+// so attributes added by an inner handler, like the token owner, show up in the request log as well.
+type entry struct {
+	logger *slog.Logger
+}
+
+// ContextWith returns a context which carries the logger.
+func ContextWith(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, &entry{logger: l})
+}
+
+// FromContext returns the logger of the context, or the default logger when the context has none.
+func FromContext(ctx context.Context) *slog.Logger {
+	if e, ok := ctx.Value(contextKey{}).(*entry); ok {
+		return e.logger
+	}
+	return slog.Default()
+}
+
+// With adds the attributes to the logger of the context.
+// The attributes are visible to everyone who shares the logger of the context, including the request log.
+func With(ctx context.Context, args ...interface{}) {
+	if e, ok := ctx.Value(contextKey{}).(*entry); ok {
+		e.logger = e.logger.With(args...)
+	}
+}
+
+// Error logs an unexpected error with the logger of the context.
+func Error(ctx context.Context, msg string, err error, args ...interface{}) {
+	FromContext(ctx).ErrorContext(ctx, msg, append([]interface{}{slog.String(`error`, err.Error())}, args...)...)
+}
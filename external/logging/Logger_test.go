@@ -0,0 +1,122 @@
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/external/logging"
+)
+
+func TestLogging(t *testing.T) {
+	s := testcase.NewSpec(t)
+	defer s.Finish()
+
+	s.Describe(`.New`, func(s *testcase.Spec) {
+		s.Then(`an unknown level is rejected`, func(t *testcase.T) {
+			_, err := logging.New(logging.Config{Level: `loud`})
+			require.Error(t, err)
+		})
+
+		s.Then(`an unknown format is rejected`, func(t *testcase.T) {
+			_, err := logging.New(logging.Config{Format: `xml`})
+			require.Error(t, err)
+		})
+
+		s.Then(`the entries below the level are not written`, func(t *testcase.T) {
+			var buf bytes.Buffer
+			l, err := logging.New(logging.Config{Level: `warn`, Writer: &buf})
+			require.Nil(t, err)
+			l.Info(`hidden`)
+			l.Warn(`visible`)
+			require.NotContains(t, buf.String(), `hidden`)
+			require.Contains(t, buf.String(), `visible`)
+		})
+
+		s.Then(`the level follows the changes of the level var`, func(t *testcase.T) {
+			var buf bytes.Buffer
+			var lv slog.LevelVar
+			l, err := logging.New(logging.Config{Level: `warn`, Writer: &buf, LevelVar: &lv})
+			require.Nil(t, err)
+			require.Equal(t, slog.LevelWarn, lv.Level())
+			lv.Set(slog.LevelDebug)
+			l.Debug(`visible`)
+			require.Contains(t, buf.String(), `visible`)
+		})
+	})
+
+	s.Describe(`.Middleware`, func(s *testcase.Spec) {
+		buf := s.Let(`buf`, func(t *testcase.T) interface{} { return &bytes.Buffer{} })
+		header := s.Let(`header`, func(t *testcase.T) interface{} { return http.Header{} })
+
+		serve := func(t *testcase.T) (*httptest.ResponseRecorder, []map[string]interface{}) {
+			logger, err := logging.New(logging.Config{Level: `debug`, Writer: buf.Get(t).(*bytes.Buffer)})
+			require.Nil(t, err)
+			h := logging.Middleware(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				logging.With(r.Context(), slog.String(`token_owner`, `owner-uid`))
+				logging.FromContext(r.Context()).Debug(`in handler`)
+				w.WriteHeader(http.StatusTeapot)
+			}), func(r *http.Request) string { return `/api/release-flags/` })
+
+			r := httptest.NewRequest(http.MethodGet, `/api/release-flags/42`, nil)
+			r.Header = header.Get(t).(http.Header)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			var entries []map[string]interface{}
+			dec := json.NewDecoder(buf.Get(t).(*bytes.Buffer))
+			for dec.More() {
+				var entry map[string]interface{}
+				require.Nil(t, dec.Decode(&entry))
+				entries = append(entries, entry)
+			}
+			return w, entries
+		}
+
+		s.Then(`each entry of the request has the request id, the route and the token owner`, func(t *testcase.T) {
+			w, entries := serve(t)
+			require.Len(t, entries, 2)
+			requestID := w.Header().Get(logging.RequestIDHeader)
+			require.NotEmpty(t, requestID)
+
+			require.Equal(t, `in handler`, entries[0][`msg`])
+			require.Equal(t, `http request`, entries[1][`msg`])
+			for _, entry := range entries {
+				require.Equal(t, requestID, entry[`request_id`])
+				require.Equal(t, `/api/release-flags/`, entry[`route`])
+				require.Equal(t, `owner-uid`, entry[`token_owner`])
+			}
+			require.Equal(t, float64(http.StatusTeapot), entries[1][`status`])
+		})
+
+		s.When(`the request has a request id`, func(s *testcase.Spec) {
+			header.Let(s, func(t *testcase.T) interface{} {
+				return http.Header{logging.RequestIDHeader: {`incoming-id`}}
+			})
+
+			s.Then(`the incoming request id is kept`, func(t *testcase.T) {
+				w, entries := serve(t)
+				require.Equal(t, `incoming-id`, w.Header().Get(logging.RequestIDHeader))
+				require.Equal(t, `incoming-id`, entries[1][`request_id`])
+			})
+		})
+
+		s.When(`the request id is not acceptable`, func(s *testcase.Spec) {
+			header.Let(s, func(t *testcase.T) interface{} {
+				return http.Header{logging.RequestIDHeader: {"forged\nentry"}}
+			})
+
+			s.Then(`a new request id is generated`, func(t *testcase.T) {
+				w, _ := serve(t)
+				require.NotEqual(t, "forged\nentry", w.Header().Get(logging.RequestIDHeader))
+				require.Len(t, w.Header().Get(logging.RequestIDHeader), 32)
+			})
+		})
+	})
+}
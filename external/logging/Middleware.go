@@ -0,0 +1,67 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+
+	"github.com/felixge/httpsnoop"
+)
+
+// RequestIDHeader is the header of the request id.
+// An incoming request id is kept, so the logs of the services on the path of a request can be correlated.
+const RequestIDHeader = `X-Request-Id`
+
+// Middleware logs each request served by the next handler.
+// The request gets a logger in its context, which carries the request id and the route of the request.
+// The route func should return a value from a small set, such as the pattern of the handler.
+func Middleware(logger *slog.Logger, next http.Handler, route func(*http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !isValidRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		ctx := ContextWith(r.Context(), logger.With(
+			slog.String(`request_id`, requestID),
+			slog.String(`route`, route(r)),
+		))
+		m := httpsnoop.CaptureMetrics(next, w, r.WithContext(ctx))
+
+		level := slog.LevelInfo
+		if 500 <= m.Code {
+			level = slog.LevelError
+		}
+		FromContext(ctx).LogAttrs(ctx, level, `http request`,
+			slog.String(`method`, r.Method),
+			slog.String(`path`, r.URL.Path),
+			slog.Int(`status`, m.Code),
+			slog.Duration(`duration`, m.Duration),
+			slog.String(`remote_addr`, r.RemoteAddr),
+		)
+	})
+}
+
+// isValidRequestID accepts the request ids of a reasonable length with printable ASCII characters only,
+// so a client can't inject arbitrary content into the logs.
+func isValidRequestID(id string) bool {
+	if id == `` || 128 < len(id) {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || '~' < c {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	bs := make([]byte, 16)
+	if _, err := rand.Read(bs); err != nil {
+		return `unknown`
+	}
+	return hex.EncodeToString(bs)
+}
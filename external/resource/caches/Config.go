@@ -0,0 +1,94 @@
+package caches
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// EvictionLRU evicts the least recently used entry when the cache is full.
+	EvictionLRU = `lru`
+	// EvictionLFU evicts the least frequently used entry when the cache is full.
+	EvictionLFU = `lfu`
+)
+
+const (
+	DefaultTTL              = 5 * time.Minute
+	DefaultMemoryMaxEntries = 100000
+)
+
+// Config holds the tuning options of the caches.
+// The zero value is valid, and means no TTL and the default memory bounds.
+type Config struct {
+	// TTL is how long a cached entry can be served before it is fetched again from the source.
+	// Zero means that the entries only invalidated by the storage events.
+	TTL time.Duration
+	// MaxEntries is the number of entries an entity type may keep in the memory cache.
+	// Zero means DefaultMemoryMaxEntries.
+	MaxEntries int
+	// Eviction is the policy that choose the entry to evict when the memory cache is full,
+	// either EvictionLRU or EvictionLFU. The default is EvictionLRU.
+	Eviction string
+}
+
+func (c Config) withDefaults() Config {
+	if c.MaxEntries == 0 {
+		c.MaxEntries = DefaultMemoryMaxEntries
+	}
+	if c.Eviction == `` {
+		c.Eviction = EvictionLRU
+	}
+	return c
+}
+
+func (c Config) Validate() error {
+	if c.TTL < 0 {
+		return fmt.Errorf(`cache ttl can't be negative: %s`, c.TTL)
+	}
+	if c.MaxEntries < 0 {
+		return fmt.Errorf(`cache max entries can't be negative: %d`, c.MaxEntries)
+	}
+	switch c.Eviction {
+	case ``, EvictionLRU, EvictionLFU:
+		return nil
+	default:
+		return fmt.Errorf(`unknown cache eviction policy: %s`, c.Eviction)
+	}
+}
+
+// withQuery overrides the config with the "ttl", "max_entries" and "eviction" query parameters of the cache url,
+// like "memory?max_entries=50000&eviction=lfu".
+func (c Config) withQuery(q url.Values) (Config, error) {
+	if raw := q.Get(`ttl`); raw != `` {
+		ttl, err := time.ParseDuration(raw)
+		if err != nil {
+			return c, err
+		}
+		c.TTL = ttl
+	}
+	if raw := q.Get(`max_entries`); raw != `` {
+		maxEntries, err := strconv.Atoi(raw)
+		if err != nil {
+			return c, err
+		}
+		c.MaxEntries = maxEntries
+	}
+	if raw := q.Get(`eviction`); raw != `` {
+		c.Eviction = raw
+	}
+	return c, c.Validate()
+}
+
+// withoutConfigQuery returns the cache url without the query parameters which are read by withQuery,
+// so the rest of the url can be passed to the driver.
+func withoutConfigQuery(u *url.URL) *url.URL {
+	q := u.Query()
+	q.Del(`ttl`)
+	q.Del(`max_entries`)
+	q.Del(`eviction`)
+	without := *u
+	without.RawQuery = q.Encode()
+	return &without
+}
@@ -2,62 +2,52 @@ package caches
 
 import (
 	"context"
-	"sync"
+	"fmt"
 
 	"github.com/adamluzsi/frameless"
 	"github.com/adamluzsi/frameless/cache"
+	"github.com/adamluzsi/frameless/extid"
 	"github.com/adamluzsi/frameless/inmemory"
 
-	"github.com/toggler-io/toggler/domains/release"
-	"github.com/toggler-io/toggler/domains/security"
 	"github.com/toggler-io/toggler/domains/toggler"
 )
 
-func NewMemory(s toggler.Storage) (*Memory, error) {
-	m := &Memory{Source: s, Memory: inmemory.NewMemory()}
+// NewMemory creates a cache that keeps the storage results in the memory of the process.
+// Each entity type keeps at most Config.MaxEntries entities and query results,
+// and when the limit is reached, the entries are evicted by the Config.Eviction policy.
+func NewMemory(s toggler.Storage, config Config) (*Memory, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	m := &Memory{Source: s, Memory: inmemory.NewMemory(), Config: config}
 	return m, m.Init(context.Background())
 }
 
 type Memory struct {
 	Source toggler.Storage
 	Memory *inmemory.Memory
+	Config Config
 
-	init               sync.Once
-	releaseFlag        *cache.Manager
-	releaseRollout     *cache.Manager
-	releasePilot       *cache.Manager
-	releaseEnvironment *cache.Manager
-	securityToken      *cache.Manager
+	managers
+	storages []*storage
 }
 
 func (m *Memory) Init(ctx context.Context) error {
-	var err error
-	m.init.Do(func() {
-		newManager := func(T frameless.T, s cache.Source) (*cache.Manager, error) {
-			return cache.NewManager(T, newMemoryStorage(T, m.Memory), s)
-		}
-		m.releaseFlag, err = newManager(release.Flag{}, m.Source.ReleaseFlag(ctx))
-		if err != nil {
-			return
-		}
-		m.releaseRollout, err = newManager(release.Rollout{}, m.Source.ReleaseRollout(ctx))
-		if err != nil {
-			return
-		}
-		m.releasePilot, err = newManager(release.Pilot{}, m.Source.ReleasePilot(ctx))
-		if err != nil {
-			return
-		}
-		m.releaseEnvironment, err = newManager(release.Environment{}, m.Source.ReleaseEnvironment(ctx))
-		if err != nil {
-			return
-		}
-		m.securityToken, err = newManager(security.Token{}, m.Source.SecurityToken(ctx))
-		if err != nil {
-			return
-		}
+	return m.managers.Init(ctx, m.Source, func(T frameless.T, src cache.Source) cache.Storage {
+		s := newMemoryStorage(T, m.Memory, src, m.Config, &m.managers.stats)
+		m.storages = append(m.storages, s)
+		return s
 	})
-	return err
+}
+
+// Stats returns the usage counters of the cache, along with the number of the cached entries.
+func (m *Memory) Stats() Stats {
+	stats := m.managers.Stats()
+	for _, s := range m.storages {
+		stats.Entries += s.HitStorage.(*memoryBoundedStorage).index.len()
+		stats.Entries += s.EntityStorage.(*memoryBoundedStorage).index.len()
+	}
+	return stats
 }
 
 func (m *Memory) BeginTx(ctx context.Context) (context.Context, error) {
@@ -82,68 +72,128 @@ func (m *Memory) RollbackTx(ctx context.Context) error {
 	return m.Memory.RollbackTx(ctx)
 }
 
-func (m *Memory) ReleaseFlag(ctx context.Context) release.FlagStorage {
-	return &FlagStorage{
-		Manager: m.releaseFlag,
-		Source:  m.Source,
-	}
+func (m *Memory) Close() error {
+	_ = m.managers.Close()
+	return m.Source.Close()
 }
 
-func (m *Memory) ReleasePilot(ctx context.Context) release.PilotStorage {
-	return &PilotStorage{
-		Manager: m.releasePilot,
-		Source:  m.Source,
+func newMemoryStorage(T frameless.T, m *inmemory.Memory, src cache.Source, config Config, stats *statsCounter) *storage {
+	return &storage{
+		OnePhaseCommitProtocol: m,
+		HitStorage: &memoryBoundedStorage{
+			Storage:    inmemory.NewStorage(cache.Hit{}, m),
+			index:      newMemoryIndex(config),
+			stats:      stats,
+			isQueryHit: true,
+		},
+		EntityStorage: &memoryBoundedStorage{
+			Storage: inmemory.NewStorage(T, m),
+			index:   newMemoryIndex(config),
+			stats:   stats,
+			src:     src,
+		},
 	}
 }
 
-func (m *Memory) ReleaseRollout(ctx context.Context) release.RolloutStorage {
-	return &RolloutStorage{
-		Manager: m.releaseRollout,
-		Source:  m.Source,
+type storage struct {
+	frameless.OnePhaseCommitProtocol
+	HitStorage    cache.HitStorage
+	EntityStorage cache.EntityStorage
+}
+
+func (s *storage) CacheEntity(ctx context.Context) cache.EntityStorage {
+	return s.EntityStorage
+}
+
+func (s *storage) CacheHit(ctx context.Context) cache.HitStorage {
+	return s.HitStorage
+}
+
+// memoryBoundedStorage keeps an inmemory.Storage within the bounds of the cache config.
+// Expired entries are removed lazily, when they are looked up.
+type memoryBoundedStorage struct {
+	*inmemory.Storage
+	index *memoryIndex
+	stats *statsCounter
+	// isQueryHit tells that the storage keeps query hits, where a missing entry means a cache miss.
+	isQueryHit bool
+	// src is used to fetch the entities that a query hit refers to, but no longer cached.
+	src cache.Source
+}
+
+func (s *memoryBoundedStorage) Create(ctx context.Context, ptr interface{}) error {
+	if err := s.Storage.Create(ctx, ptr); err != nil {
+		return err
 	}
+	return s.added(ctx, ptr)
 }
 
-func (m *Memory) ReleaseEnvironment(ctx context.Context) release.EnvironmentStorage {
-	return &EnvironmentStorage{
-		Manager: m.releaseEnvironment,
-		Source:  m.Source,
+func (s *memoryBoundedStorage) Update(ctx context.Context, ptr interface{}) error {
+	if err := s.Storage.Update(ctx, ptr); err != nil {
+		return err
 	}
+	return s.added(ctx, ptr)
 }
 
-func (m *Memory) SecurityToken(ctx context.Context) security.TokenStorage {
-	return &TokenStorage{
-		Manager: m.securityToken,
-		Source:  m.Source,
+func (s *memoryBoundedStorage) Upsert(ctx context.Context, ptrs ...interface{}) error {
+	if err := s.Storage.Upsert(ctx, ptrs...); err != nil {
+		return err
+	}
+	for _, ptr := range ptrs {
+		if err := s.added(ctx, ptr); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func (m *Memory) Close() error {
-	_ = m.releaseFlag.Close()
-	_ = m.releaseRollout.Close()
-	_ = m.releasePilot.Close()
-	_ = m.releaseEnvironment.Close()
-	_ = m.securityToken.Close()
-	return m.Source.Close()
+func (s *memoryBoundedStorage) FindByID(ctx context.Context, ptr, id interface{}) (bool, error) {
+	found, err := s.findByID(ctx, ptr, id)
+	if err != nil {
+		return false, err
+	}
+	if found {
+		s.stats.hit()
+	} else if s.isQueryHit {
+		s.stats.miss()
+	}
+	return found, nil
 }
 
-func newMemoryStorage(T frameless.T, m *inmemory.Memory) *storage {
-	return &storage{
-		OnePhaseCommitProtocol: m,
-		HitStorage:             inmemory.NewStorage(cache.Hit{}, m),
-		EntityStorage:          inmemory.NewStorage(T, m),
+func (s *memoryBoundedStorage) findByID(ctx context.Context, ptr, id interface{}) (bool, error) {
+	if s.index.touch(id) {
+		s.index.remove(id)
+		s.stats.expired()
+		_ = s.Storage.DeleteByID(ctx, id)
+		return false, nil
 	}
+	return s.Storage.FindByID(ctx, ptr, id)
 }
 
-type storage struct {
-	frameless.OnePhaseCommitProtocol
-	HitStorage    cache.HitStorage
-	EntityStorage cache.EntityStorage
+func (s *memoryBoundedStorage) FindByIDs(ctx context.Context, ids ...interface{}) frameless.Iterator {
+	return findByIDs(ctx, s.Storage.T, s.src, ids, s.findByID)
 }
 
-func (s *storage) CacheEntity(ctx context.Context) cache.EntityStorage {
-	return s.EntityStorage
+func (s *memoryBoundedStorage) DeleteByID(ctx context.Context, id interface{}) error {
+	s.index.remove(id)
+	return s.Storage.DeleteByID(ctx, id)
 }
 
-func (s *storage) CacheHit(ctx context.Context) cache.HitStorage {
-	return s.HitStorage
+func (s *memoryBoundedStorage) DeleteAll(ctx context.Context) error {
+	s.index.clear()
+	return s.Storage.DeleteAll(ctx)
+}
+
+func (s *memoryBoundedStorage) added(ctx context.Context, ptr interface{}) error {
+	id, ok := extid.Lookup(ptr)
+	if !ok {
+		return fmt.Errorf(`%T doesn't have an external id`, ptr)
+	}
+	evicted := s.index.add(id)
+	for _, id := range evicted {
+		// the entry might be already removed by an invalidation or a rolled back transaction.
+		_ = s.Storage.DeleteByID(ctx, id)
+	}
+	s.stats.evicted(len(evicted))
+	return nil
 }
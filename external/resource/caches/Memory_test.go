@@ -3,6 +3,7 @@ package caches_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/adamluzsi/frameless"
 	csh "github.com/adamluzsi/frameless/contracts"
@@ -33,7 +34,7 @@ func TestMemory_smoke(t *testing.T) {
 	s.Test(``, func(t *testcase.T) {
 		storage := storages.NewInMemory()
 		storage.EventLog.Options.DisableAsyncSubscriptionHandling = true
-		m, err := caches.NewMemory(storage)
+		m, err := caches.NewMemory(storage, caches.Config{})
 		require.Nil(t, err)
 		t.Cleanup(func() { require.Nil(t, m.Close()) })
 		ff := sh.NewFixtureFactory(t)
@@ -54,7 +55,7 @@ func SpecMemory(tb testing.TB) {
 		Subject: func(tb testing.TB) toggler.Storage {
 			storage := storages.NewInMemory()
 			storage.EventLog.Options.DisableAsyncSubscriptionHandling = true
-			m, err := caches.NewMemory(storage)
+			m, err := caches.NewMemory(storage, caches.Config{})
 			require.Nil(tb, err)
 			tb.Cleanup(func() { require.Nil(tb, m.Close()) })
 			return m
@@ -67,3 +68,103 @@ func SpecMemory(tb testing.TB) {
 		},
 	})
 }
+
+func TestMemory_bounds(t *testing.T) {
+	s := sh.NewSpec(t)
+
+	var (
+		config = s.Let(`config`, func(t *testcase.T) interface{} { return caches.Config{} })
+		source = s.Let(`source`, func(t *testcase.T) interface{} {
+			storage := storages.NewInMemory()
+			storage.EventLog.Options.DisableAsyncSubscriptionHandling = true
+			return storage
+		})
+		subject = s.Let(`memory`, func(t *testcase.T) interface{} {
+			m, err := caches.NewMemory(source.Get(t).(toggler.Storage), config.Get(t).(caches.Config))
+			require.Nil(t, err)
+			t.Cleanup(func() { require.Nil(t, m.Close()) })
+			return m
+		})
+		subjectGet = func(t *testcase.T) *caches.Memory { return subject.Get(t).(*caches.Memory) }
+		flags      = s.Let(`flags`, func(t *testcase.T) interface{} {
+			ctx := context.Background()
+			var flags []release.Flag
+			for i := 0; i < 3; i++ {
+				flag := sh.NewFixtureFactory(t).Fixture(release.Flag{}, ctx).(release.Flag)
+				require.Nil(t, source.Get(t).(toggler.Storage).ReleaseFlag(ctx).Create(ctx, &flag))
+				flags = append(flags, flag)
+			}
+			return flags
+		})
+		findAll = func(t *testcase.T) {
+			ctx := context.Background()
+			for _, flag := range flags.Get(t).([]release.Flag) {
+				var got release.Flag
+				found, err := subjectGet(t).ReleaseFlag(ctx).FindByID(ctx, &got, flag.ID)
+				require.Nil(t, err)
+				require.True(t, found)
+				require.Equal(t, flag, got)
+			}
+		}
+	)
+
+	s.Describe(`Stats`, func(s *testcase.Spec) {
+		s.Then(`repeated lookups are served from the cache`, func(t *testcase.T) {
+			findAll(t)
+			findAll(t)
+			stats := subjectGet(t).Stats()
+			require.Equal(t, uint64(3), stats.Misses)
+			require.NotZero(t, stats.Hits)
+			require.Zero(t, stats.Evictions)
+			require.Equal(t, 6, stats.Entries)
+		})
+
+		s.When(`the cache is full`, func(s *testcase.Spec) {
+			config.Let(s, func(t *testcase.T) interface{} { return caches.Config{MaxEntries: 2} })
+
+			s.Then(`the entries are evicted to stay within the bounds`, func(t *testcase.T) {
+				findAll(t)
+				findAll(t)
+				stats := subjectGet(t).Stats()
+				require.NotZero(t, stats.Evictions)
+				require.Equal(t, 4, stats.Entries)
+			})
+
+			s.And(`the eviction policy is LFU`, func(s *testcase.Spec) {
+				config.Let(s, func(t *testcase.T) interface{} {
+					return caches.Config{MaxEntries: 2, Eviction: caches.EvictionLFU}
+				})
+
+				s.Then(`the entries are evicted to stay within the bounds`, func(t *testcase.T) {
+					findAll(t)
+					findAll(t)
+					require.Equal(t, 4, subjectGet(t).Stats().Entries)
+				})
+			})
+		})
+
+		s.When(`the entries outlive the TTL`, func(s *testcase.Spec) {
+			config.Let(s, func(t *testcase.T) interface{} { return caches.Config{TTL: time.Millisecond} })
+
+			s.Then(`they are fetched again from the source`, func(t *testcase.T) {
+				findAll(t)
+				time.Sleep(5 * time.Millisecond)
+				findAll(t)
+				stats := subjectGet(t).Stats()
+				require.Equal(t, uint64(6), stats.Misses)
+				require.NotZero(t, stats.Expirations)
+			})
+		})
+	})
+
+	s.Describe(`NewMemory`, func(s *testcase.Spec) {
+		s.When(`the config is invalid`, func(s *testcase.Spec) {
+			config.Let(s, func(t *testcase.T) interface{} { return caches.Config{Eviction: `random`} })
+
+			s.Then(`it returns an error`, func(t *testcase.T) {
+				_, err := caches.NewMemory(source.Get(t).(toggler.Storage), config.Get(t).(caches.Config))
+				require.Error(t, err)
+			})
+		})
+	})
+}
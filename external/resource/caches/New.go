@@ -6,7 +6,10 @@ import (
 	"github.com/toggler-io/toggler/domains/toggler"
 )
 
-func New(connstr string, src toggler.Storage) (toggler.Storage, error) {
+// New creates a cache on top of the source storage based on the connection string.
+// The cache options of the config can be overridden with the query parameters of the connection string,
+// like "memory?ttl=1m&max_entries=50000&eviction=lfu".
+func New(connstr string, src toggler.Storage, config Config) (toggler.Storage, error) {
 	var driver string = connstr
 
 	u, err := url.Parse(connstr)
@@ -18,9 +21,22 @@ func New(connstr string, src toggler.Storage) (toggler.Storage, error) {
 		driver = u.Scheme
 	}
 
+	if u.Scheme == `` && u.Path != `` {
+		driver = u.Path
+	}
+
+	config, err = config.withQuery(u.Query())
+	if err != nil {
+		return nil, err
+	}
+
 	switch driver {
 	case "memory":
-		return NewMemory(src)
+		return NewMemory(src, config)
+
+	case "redis", "rediss":
+		// the redis client rejects the unknown options of the url.
+		return NewRedis(withoutConfigQuery(u).String(), src, config)
 
 	default:
 		return src, nil
@@ -1,42 +1,129 @@
 package caches_test
 
-//import (
-//	"os"
-//	"testing"
-//
-//	"github.com/adamluzsi/testcase"
-//	"github.com/stretchr/testify/require"
-//	"github.com/toggler-io/toggler/external/resource/caches"
-//	"github.com/toggler-io/toggler/external/resource/caches/contracts"
-//	sh "github.com/toggler-io/toggler/spechelper"
-//)
-//
-//func TestRedisCacheStorage(t *testing.T) {
-//	SpecRedisCacheStorage(t)
-//}
-//
-//func BenchmarkRedisCacheStorage(b *testing.B) {
-//	SpecRedisCacheStorage(b)
-//}
-//
-//func SpecRedisCacheStorage(tb testing.TB) {
-//	connstr := getTestRedisConnstr(tb)
-//	testcase.RunContract(tb, contracts.Storage{
-//		Subject: func(tb testing.TB) caches.storage {
-//			cs, err := caches.NewRedisCacheStorage(connstr)
-//			require.Nil(tb, err)
-//			return cs
-//		},
-//		FixtureFactory: sh.DefaultFixtureFactory,
-//	})
-//}
-//
-//func getTestRedisConnstr(tb testing.TB) string {
-//	const envKey = `TEST_CACHE_URL_REDIS`
-//	value, isSet := os.LookupEnv(envKey)
-//	if !isSet {
-//		tb.Skipf(`redis url is not index in "%s"`, envKey)
-//	}
-//
-//	return value
-//}
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/testcase"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/domains/toggler/contracts"
+	"github.com/toggler-io/toggler/external/resource/caches"
+	"github.com/toggler-io/toggler/external/resource/storages"
+	sh "github.com/toggler-io/toggler/spechelper"
+)
+
+var _ toggler.Storage = &caches.Redis{}
+
+func TestRedis(t *testing.T)      { SpecRedis(t) }
+func BenchmarkRedis(b *testing.B) { SpecRedis(b) }
+
+func SpecRedis(tb testing.TB) {
+	testcase.RunContract(sh.NewSpec(tb), contracts.Storage{
+		Subject: func(tb testing.TB) toggler.Storage {
+			storage := storages.NewInMemory()
+			storage.EventLog.Options.DisableAsyncSubscriptionHandling = true
+			r, err := caches.NewRedis(getTestRedisConnstr(tb), storage, caches.Config{})
+			require.Nil(tb, err)
+			tb.Cleanup(func() { require.Nil(tb, r.Close()) })
+			return r
+		},
+		FixtureFactory: func(tb testing.TB) frameless.FixtureFactory {
+			return sh.NewFixtureFactory(tb)
+		},
+		Context: func(tb testing.TB) context.Context {
+			return context.Background()
+		},
+	})
+}
+
+func TestNew_redis(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Describe(`New`, func(s *testcase.Spec) {
+		s.When(`the redis url has cache options in its query`, func(s *testcase.Spec) {
+			s.Then(`the options configure the cache, and they are not passed to the redis client`, func(t *testcase.T) {
+				cache, err := caches.New(getTestRedisConnstr(t)+`/0?ttl=1m&max_entries=10&eviction=lfu`, storages.NewInMemory(), caches.Config{})
+				require.Nil(t, err)
+				t.Cleanup(func() { require.Nil(t, cache.Close()) })
+
+				r, ok := cache.(*caches.Redis)
+				require.True(t, ok)
+				require.Equal(t, caches.Config{TTL: time.Minute, MaxEntries: 10, Eviction: caches.EvictionLFU}, r.Config)
+			})
+		})
+	})
+}
+
+func TestRedis_crossInstanceInvalidation(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Describe(`multiple instances`, func(s *testcase.Spec) {
+		var (
+			source = s.Let(`source`, func(t *testcase.T) interface{} {
+				storage := storages.NewInMemory()
+				storage.EventLog.Options.DisableAsyncSubscriptionHandling = true
+				return storage
+			})
+			connstr     = s.Let(`connstr`, func(t *testcase.T) interface{} { return getTestRedisConnstr(t) })
+			newInstance = func(t *testcase.T) *caches.Redis {
+				// the instances share the source, like toggler servers that use the same database
+				r, err := caches.NewRedis(connstr.Get(t).(string), nopCloser{Storage: source.Get(t).(toggler.Storage)}, caches.Config{})
+				require.Nil(t, err)
+				t.Cleanup(func() { _ = r.Close() })
+				return r
+			}
+		)
+
+		s.Then(`an update made through one instance is visible through the other`, func(t *testcase.T) {
+			ctx := context.Background()
+			a, b := newInstance(t), newInstance(t)
+
+			flag := release.Flag{Name: `checkout`}
+			require.Nil(t, a.ReleaseFlag(ctx).Create(ctx, &flag))
+
+			var cached release.Flag
+			found, err := b.ReleaseFlag(ctx).FindByID(ctx, &cached, flag.ID)
+			require.Nil(t, err)
+			require.True(t, found)
+			require.Equal(t, `checkout`, cached.Name)
+
+			flag.Name = `checkout-v2`
+			require.Nil(t, a.ReleaseFlag(ctx).Update(ctx, &flag))
+
+			found, err = b.ReleaseFlag(ctx).FindByID(ctx, &cached, flag.ID)
+			require.Nil(t, err)
+			require.True(t, found)
+			require.Equal(t, `checkout-v2`, cached.Name)
+
+			require.Nil(t, a.ReleaseFlag(ctx).DeleteByID(ctx, flag.ID))
+			found, err = b.ReleaseFlag(ctx).FindByID(ctx, &cached, flag.ID)
+			require.Nil(t, err)
+			require.False(t, found)
+		})
+	})
+}
+
+type nopCloser struct{ toggler.Storage }
+
+func (nopCloser) Close() error { return nil }
+
+// getTestRedisConnstr use the redis from TEST_CACHE_URL_REDIS when it is set,
+// and an in-process redis stand-in otherwise.
+func getTestRedisConnstr(tb testing.TB) string {
+	const envKey = `TEST_CACHE_URL_REDIS`
+	if value, isSet := os.LookupEnv(envKey); isSet {
+		return value
+	}
+
+	srv, err := miniredis.Run()
+	require.Nil(tb, err)
+	tb.Cleanup(srv.Close)
+	return `redis://` + srv.Addr()
+}
@@ -0,0 +1,39 @@
+package caches
+
+import "sync/atomic"
+
+// Stats is a snapshot of the cache usage counters.
+type Stats struct {
+	// Hits is the number of lookups that were served from the cache.
+	Hits uint64
+	// Misses is the number of queries that had to reach the source storage.
+	Misses uint64
+	// Evictions is the number of entries that were removed to keep the cache within its bounds.
+	Evictions uint64
+	// Expirations is the number of entries that were removed because their TTL passed.
+	Expirations uint64
+	// Entries is the number of entries currently kept in the cache,
+	// when the cache backend can tell it.
+	Entries int
+}
+
+type statsCounter struct {
+	hits        uint64
+	misses      uint64
+	evictions   uint64
+	expirations uint64
+}
+
+func (c *statsCounter) hit()          { atomic.AddUint64(&c.hits, 1) }
+func (c *statsCounter) miss()         { atomic.AddUint64(&c.misses, 1) }
+func (c *statsCounter) evicted(n int) { atomic.AddUint64(&c.evictions, uint64(n)) }
+func (c *statsCounter) expired()      { atomic.AddUint64(&c.expirations, 1) }
+
+func (c *statsCounter) snapshot() Stats {
+	return Stats{
+		Hits:        atomic.LoadUint64(&c.hits),
+		Misses:      atomic.LoadUint64(&c.misses),
+		Evictions:   atomic.LoadUint64(&c.evictions),
+		Expirations: atomic.LoadUint64(&c.expirations),
+	}
+}
@@ -0,0 +1,192 @@
+package caches
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/cache"
+	"github.com/adamluzsi/frameless/iterators"
+
+	"github.com/toggler-io/toggler/domains/change"
+	"github.com/toggler-io/toggler/domains/guardrail"
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/schedule"
+	"github.com/toggler-io/toggler/domains/security"
+	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/domains/webhook"
+)
+
+// managers holds a cache.Manager for each entity type of the toggler.Storage,
+// and provide the cached entity storages on top of them.
+type managers struct {
+	source toggler.Storage
+	stats  statsCounter
+
+	init               sync.Once
+	releaseFlag        *cache.Manager
+	releaseRollout     *cache.Manager
+	releasePilot       *cache.Manager
+	releaseEnvironment *cache.Manager
+	securityToken      *cache.Manager
+}
+
+func (ms *managers) Init(ctx context.Context, source toggler.Storage, newStorage func(T frameless.T, src cache.Source) cache.Storage) error {
+	var err error
+	ms.init.Do(func() {
+		ms.source = source
+		newManager := func(T frameless.T, s cache.Source) (*cache.Manager, error) {
+			return cache.NewManager(T, newStorage(T, s), s)
+		}
+		ms.releaseFlag, err = newManager(release.Flag{}, source.ReleaseFlag(ctx))
+		if err != nil {
+			return
+		}
+		ms.releaseRollout, err = newManager(release.Rollout{}, source.ReleaseRollout(ctx))
+		if err != nil {
+			return
+		}
+		ms.releasePilot, err = newManager(release.Pilot{}, source.ReleasePilot(ctx))
+		if err != nil {
+			return
+		}
+		ms.releaseEnvironment, err = newManager(release.Environment{}, source.ReleaseEnvironment(ctx))
+		if err != nil {
+			return
+		}
+		ms.securityToken, err = newManager(security.Token{}, source.SecurityToken(ctx))
+		if err != nil {
+			return
+		}
+	})
+	return err
+}
+
+func (ms *managers) ReleaseFlag(ctx context.Context) release.FlagStorage {
+	return &FlagStorage{
+		Manager: ms.releaseFlag,
+		Source:  ms.source,
+	}
+}
+
+func (ms *managers) ReleasePilot(ctx context.Context) release.PilotStorage {
+	return &PilotStorage{
+		Manager: ms.releasePilot,
+		Source:  ms.source,
+	}
+}
+
+func (ms *managers) ReleaseRollout(ctx context.Context) release.RolloutStorage {
+	return &RolloutStorage{
+		Manager: ms.releaseRollout,
+		Source:  ms.source,
+	}
+}
+
+func (ms *managers) ReleaseEnvironment(ctx context.Context) release.EnvironmentStorage {
+	return &EnvironmentStorage{
+		Manager: ms.releaseEnvironment,
+		Source:  ms.source,
+	}
+}
+
+// ReleaseVersion is served by the source storage,
+// because the history is only read for administration purposes.
+func (ms *managers) ReleaseVersion(ctx context.Context) release.VersionStorage {
+	return ms.source.ReleaseVersion(ctx)
+}
+
+func (ms *managers) SecurityToken(ctx context.Context) security.TokenStorage {
+	return &TokenStorage{
+		Manager: ms.securityToken,
+		Source:  ms.source,
+	}
+}
+
+// WebhookSubscription is served by the source storage,
+// because the subscriptions are only read when a change is dispatched.
+func (ms *managers) WebhookSubscription(ctx context.Context) webhook.SubscriptionStorage {
+	return ms.source.WebhookSubscription(ctx)
+}
+
+// WebhookDelivery is served by the source storage, as the delivery log is written more than it is read.
+func (ms *managers) WebhookDelivery(ctx context.Context) webhook.DeliveryStorage {
+	return ms.source.WebhookDelivery(ctx)
+}
+
+// ChangeRequest is served by the source storage, so the reviews always see the latest state of the change requests.
+func (ms *managers) ChangeRequest(ctx context.Context) change.RequestStorage {
+	return ms.source.ChangeRequest(ctx)
+}
+
+// ChangeApproval is served by the source storage, as the approvals are only read during the review.
+func (ms *managers) ChangeApproval(ctx context.Context) change.ApprovalStorage {
+	return ms.source.ChangeApproval(ctx)
+}
+
+// ScheduledChange is served by the source storage, so the scheduler sees the outcome of the other instances.
+func (ms *managers) ScheduledChange(ctx context.Context) schedule.ChangeStorage {
+	return ms.source.ScheduledChange(ctx)
+}
+
+// Guardrail is served by the source storage, as the guardrails are only read by the monitor.
+func (ms *managers) Guardrail(ctx context.Context) guardrail.GuardrailStorage {
+	return ms.source.Guardrail(ctx)
+}
+
+// GuardrailSignal is served by the source storage, as the health signals are written more than they are read.
+func (ms *managers) GuardrailSignal(ctx context.Context) guardrail.SignalStorage {
+	return ms.source.GuardrailSignal(ctx)
+}
+
+// TryLock is served by the source storage, as the lock is shared by the toggler instances through it.
+func (ms *managers) TryLock(ctx context.Context, name string) (func() error, bool, error) {
+	return ms.source.TryLock(ctx, name)
+}
+
+// Stats returns the usage counters of the cache.
+func (ms *managers) Stats() Stats {
+	return ms.stats.snapshot()
+}
+
+func (ms *managers) Close() error {
+	for _, m := range []*cache.Manager{
+		ms.releaseFlag,
+		ms.releaseRollout,
+		ms.releasePilot,
+		ms.releaseEnvironment,
+		ms.securityToken,
+	} {
+		if m != nil {
+			_ = m.Close()
+		}
+	}
+	return nil
+}
+
+// findByIDs collects the cached entities by their IDs.
+// A cached query may refer to an entity that was evicted from the cache since,
+// in which case the entity is fetched from the source instead.
+func findByIDs(ctx context.Context, T frameless.T, src cache.Source, ids []interface{},
+	find func(ctx context.Context, ptr, id interface{}) (bool, error)) frameless.Iterator {
+	slice := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(T)), 0, len(ids))
+	for _, id := range ids {
+		ptr := reflect.New(reflect.TypeOf(T))
+		found, err := find(ctx, ptr.Interface(), id)
+		if err != nil {
+			return iterators.NewError(err)
+		}
+		if !found {
+			found, err = src.FindByID(ctx, ptr.Interface(), id)
+			if err != nil {
+				return iterators.NewError(err)
+			}
+		}
+		if !found {
+			continue
+		}
+		slice = reflect.Append(slice, ptr.Elem())
+	}
+	return iterators.NewSlice(slice.Interface())
+}
@@ -0,0 +1,157 @@
+package caches
+
+import (
+	"container/heap"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// memoryIndex keeps track of the usage and the expiry of the entries of a memory cache storage.
+// It orders the entries by the eviction policy, so the next victim can be found in logarithmic time.
+type memoryIndex struct {
+	mutex   sync.Mutex
+	config  Config
+	entries map[string]*memoryIndexEntry
+	heap    memoryIndexHeap
+	clock   uint64
+	now     func() time.Time
+}
+
+type memoryIndexEntry struct {
+	id        interface{}
+	key       string
+	uses      uint64
+	lastUsed  uint64
+	expiresAt time.Time
+	position  int
+}
+
+func newMemoryIndex(config Config) *memoryIndex {
+	config = config.withDefaults()
+	return &memoryIndex{
+		config:  config,
+		entries: make(map[string]*memoryIndexEntry),
+		heap:    memoryIndexHeap{lfu: config.Eviction == EvictionLFU},
+		now:     time.Now,
+	}
+}
+
+func (i *memoryIndex) key(id interface{}) string {
+	return fmt.Sprint(id)
+}
+
+// add registers a written entry, and returns the IDs of the entries that must be evicted to stay within the bounds.
+func (i *memoryIndex) add(id interface{}) []interface{} {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.clock++
+
+	key := i.key(id)
+	e, ok := i.entries[key]
+	if !ok {
+		e = &memoryIndexEntry{id: id, key: key}
+		i.entries[key] = e
+		heap.Push(&i.heap, e)
+	}
+	e.uses++
+	e.lastUsed = i.clock
+	if 0 < i.config.TTL {
+		e.expiresAt = i.now().Add(i.config.TTL)
+	}
+	heap.Fix(&i.heap, e.position)
+
+	var evicted []interface{}
+	for i.config.MaxEntries < len(i.entries) {
+		victim := heap.Pop(&i.heap).(*memoryIndexEntry)
+		if victim == e && 0 < i.heap.Len() {
+			// the entry that was just written is kept,
+			// otherwise a new entry could never get into a full LFU cache.
+			victim = heap.Pop(&i.heap).(*memoryIndexEntry)
+			heap.Push(&i.heap, e)
+		}
+		delete(i.entries, victim.key)
+		evicted = append(evicted, victim.id)
+	}
+	return evicted
+}
+
+// touch registers a read, and reports whether the entry is expired.
+func (i *memoryIndex) touch(id interface{}) (expired bool) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	e, ok := i.entries[i.key(id)]
+	if !ok {
+		return false
+	}
+	if !e.expiresAt.IsZero() && !i.now().Before(e.expiresAt) {
+		return true
+	}
+	i.clock++
+	e.uses++
+	e.lastUsed = i.clock
+	heap.Fix(&i.heap, e.position)
+	return false
+}
+
+func (i *memoryIndex) remove(id interface{}) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	key := i.key(id)
+	e, ok := i.entries[key]
+	if !ok {
+		return
+	}
+	heap.Remove(&i.heap, e.position)
+	delete(i.entries, key)
+}
+
+func (i *memoryIndex) clear() {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.entries = make(map[string]*memoryIndexEntry)
+	i.heap.entries = nil
+}
+
+func (i *memoryIndex) len() int {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	return len(i.entries)
+}
+
+// memoryIndexHeap is a min heap where the root is the next entry to evict.
+type memoryIndexHeap struct {
+	lfu     bool
+	entries []*memoryIndexEntry
+}
+
+func (h memoryIndexHeap) Len() int { return len(h.entries) }
+
+func (h memoryIndexHeap) Less(i, j int) bool {
+	a, b := h.entries[i], h.entries[j]
+	if h.lfu && a.uses != b.uses {
+		return a.uses < b.uses
+	}
+	return a.lastUsed < b.lastUsed
+}
+
+func (h memoryIndexHeap) Swap(i, j int) {
+	h.entries[i], h.entries[j] = h.entries[j], h.entries[i]
+	h.entries[i].position = i
+	h.entries[j].position = j
+}
+
+func (h *memoryIndexHeap) Push(x interface{}) {
+	e := x.(*memoryIndexEntry)
+	e.position = len(h.entries)
+	h.entries = append(h.entries, e)
+}
+
+func (h *memoryIndexHeap) Pop() interface{} {
+	last := len(h.entries) - 1
+	e := h.entries[last]
+	h.entries[last] = nil
+	h.entries = h.entries[:last]
+	e.position = -1
+	return e
+}
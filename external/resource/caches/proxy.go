@@ -48,6 +48,12 @@ func (s *FlagStorage) FindByNames(ctx context.Context, names ...string) release.
 	})
 }
 
+// FindByQuery is served by the source storage,
+// because the cached query hits don't preserve the ordering of a page.
+func (s *FlagStorage) FindByQuery(ctx context.Context, q release.FlagQuery) release.FlagEntries {
+	return s.Source.ReleaseFlag(ctx).FindByQuery(ctx, q)
+}
+
 type PilotStorage struct {
 	*cache.Manager
 	Source toggler.Storage
@@ -87,6 +93,10 @@ func (s *PilotStorage) FindByPublicID(ctx context.Context, externalID string) re
 	})
 }
 
+func (s *PilotStorage) FindByQuery(ctx context.Context, q release.PilotQuery) release.PilotEntries {
+	return s.Source.ReleasePilot(ctx).FindByQuery(ctx, q)
+}
+
 type RolloutStorage struct {
 	*cache.Manager
 	Source toggler.Storage
@@ -100,6 +110,10 @@ func (s *RolloutStorage) FindByFlagEnvironment(ctx context.Context, flag release
 	})
 }
 
+func (s *RolloutStorage) FindByQuery(ctx context.Context, q release.RolloutQuery) release.RolloutEntries {
+	return s.Source.ReleaseRollout(ctx).FindByQuery(ctx, q)
+}
+
 type EnvironmentStorage struct {
 	*cache.Manager
 	Source toggler.Storage
@@ -114,6 +128,10 @@ func (s *EnvironmentStorage) FindByAlias(ctx context.Context, idOrName string, e
 	})
 }
 
+func (s *EnvironmentStorage) FindByQuery(ctx context.Context, q release.EnvironmentQuery) release.EnvironmentEntries {
+	return s.Source.ReleaseEnvironment(ctx).FindByQuery(ctx, q)
+}
+
 type TokenStorage struct {
 	*cache.Manager
 	Source toggler.Storage
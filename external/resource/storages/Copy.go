@@ -0,0 +1,169 @@
+package storages
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/extid"
+
+	"github.com/toggler-io/toggler/domains/change"
+	"github.com/toggler-io/toggler/domains/guardrail"
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/schedule"
+	"github.com/toggler-io/toggler/domains/security"
+	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/domains/webhook"
+)
+
+// ErrCopyVerification is returned when some of the source entities are missing from the destination after the copy.
+const ErrCopyVerification frameless.Error = `copied entities are missing from the destination storage`
+
+type CopyOptions struct {
+	// DryRun only reports what would be copied, without writing the destination storage.
+	DryRun bool
+}
+
+// CopyReport tells what happened with each entity type during the copy.
+type CopyReport struct {
+	Kinds []CopyKindReport
+}
+
+type CopyKindReport struct {
+	Kind string
+	// Source is the number of entities in the source storage.
+	Source int
+	// Copied is the number of entities created in the destination storage,
+	// or with dry run, the number of entities that would be created.
+	Copied int
+	// Skipped is the number of entities that were already present in the destination storage,
+	// for example because an earlier copy was interrupted.
+	Skipped int
+	// Verified is the number of source entities found in the destination storage after the copy.
+	Verified int
+}
+
+func (r CopyReport) String() string {
+	var out strings.Builder
+	for _, k := range r.Kinds {
+		_, _ = fmt.Fprintf(&out, "%-12s source: %d copied: %d skipped: %d verified: %d\n",
+			k.Kind, k.Source, k.Copied, k.Skipped, k.Verified)
+	}
+	return out.String()
+}
+
+type copyStorage interface {
+	frameless.Creator
+	frameless.Finder
+}
+
+// copyKinds lists the entity types in an order where the referenced entities are copied before the referencing ones.
+var copyKinds = []struct {
+	Kind    string
+	T       frameless.T
+	Storage func(context.Context, toggler.Storage) copyStorage
+}{
+	{Kind: `environment`, T: release.Environment{}, Storage: func(ctx context.Context, s toggler.Storage) copyStorage { return s.ReleaseEnvironment(ctx) }},
+	{Kind: `flag`, T: release.Flag{}, Storage: func(ctx context.Context, s toggler.Storage) copyStorage { return s.ReleaseFlag(ctx) }},
+	{Kind: `rollout`, T: release.Rollout{}, Storage: func(ctx context.Context, s toggler.Storage) copyStorage { return s.ReleaseRollout(ctx) }},
+	{Kind: `pilot`, T: release.Pilot{}, Storage: func(ctx context.Context, s toggler.Storage) copyStorage { return s.ReleasePilot(ctx) }},
+	{Kind: `version`, T: release.Version{}, Storage: func(ctx context.Context, s toggler.Storage) copyStorage { return s.ReleaseVersion(ctx) }},
+	{Kind: `token`, T: security.Token{}, Storage: func(ctx context.Context, s toggler.Storage) copyStorage { return s.SecurityToken(ctx) }},
+	{Kind: `webhook_subscription`, T: webhook.Subscription{}, Storage: func(ctx context.Context, s toggler.Storage) copyStorage { return s.WebhookSubscription(ctx) }},
+	{Kind: `webhook_delivery`, T: webhook.Delivery{}, Storage: func(ctx context.Context, s toggler.Storage) copyStorage { return s.WebhookDelivery(ctx) }},
+	{Kind: `change_request`, T: change.Request{}, Storage: func(ctx context.Context, s toggler.Storage) copyStorage { return s.ChangeRequest(ctx) }},
+	{Kind: `change_approval`, T: change.Approval{}, Storage: func(ctx context.Context, s toggler.Storage) copyStorage { return s.ChangeApproval(ctx) }},
+	{Kind: `scheduled_change`, T: schedule.Change{}, Storage: func(ctx context.Context, s toggler.Storage) copyStorage { return s.ScheduledChange(ctx) }},
+	{Kind: `guardrail`, T: guardrail.Guardrail{}, Storage: func(ctx context.Context, s toggler.Storage) copyStorage { return s.Guardrail(ctx) }},
+	{Kind: `guardrail_signal`, T: guardrail.Signal{}, Storage: func(ctx context.Context, s toggler.Storage) copyStorage { return s.GuardrailSignal(ctx) }},
+}
+
+// Copy streams every entity from one storage to the other, and keeps their IDs, so the references stay valid.
+// The entities already present in the destination are skipped, so an interrupted copy can be resumed by running it again.
+// After the copy, Copy verifies that every source entity is present in the destination.
+func Copy(ctx context.Context, from, to toggler.Storage, opts CopyOptions) (CopyReport, error) {
+	var report CopyReport
+	for _, kind := range copyKinds {
+		kr := CopyKindReport{Kind: kind.Kind}
+		src, dst := kind.Storage(ctx, from), kind.Storage(ctx, to)
+
+		err := eachEntity(ctx, src, kind.T, func(ptr interface{}, id interface{}) error {
+			kr.Source++
+			found, err := dst.FindByID(ctx, reflect.New(reflect.TypeOf(kind.T)).Interface(), id)
+			if err != nil {
+				return err
+			}
+			if found {
+				kr.Skipped++
+				return nil
+			}
+			if !opts.DryRun {
+				if err := dst.Create(ctx, ptr); err != nil {
+					return err
+				}
+			}
+			kr.Copied++
+			return nil
+		})
+		report.Kinds = append(report.Kinds, kr)
+		if err != nil {
+			return report, fmt.Errorf(`copying %s entities: %w`, kind.Kind, err)
+		}
+	}
+	if opts.DryRun {
+		return report, nil
+	}
+	return report, verifyCopy(ctx, from, to, &report)
+}
+
+func verifyCopy(ctx context.Context, from, to toggler.Storage, report *CopyReport) error {
+	for i, kind := range copyKinds {
+		kr := &report.Kinds[i]
+		src, dst := kind.Storage(ctx, from), kind.Storage(ctx, to)
+
+		err := eachEntity(ctx, src, kind.T, func(ptr interface{}, id interface{}) error {
+			found, err := dst.FindByID(ctx, reflect.New(reflect.TypeOf(kind.T)).Interface(), id)
+			if err != nil {
+				return err
+			}
+			if found {
+				kr.Verified++
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf(`verifying %s entities: %w`, kind.Kind, err)
+		}
+		if kr.Verified != kr.Source {
+			return fmt.Errorf(`%w: %d of %d %s entities`, ErrCopyVerification, kr.Source-kr.Verified, kr.Source, kind.Kind)
+		}
+	}
+	return nil
+}
+
+// eachEntity iterates through the entities of a storage one by one,
+// so the whole storage doesn't have to fit into the memory.
+func eachEntity(ctx context.Context, s frameless.Finder, T frameless.T, fn func(ptr, id interface{}) error) (rErr error) {
+	iter := s.FindAll(ctx)
+	defer func() {
+		if err := iter.Close(); err != nil && rErr == nil {
+			rErr = err
+		}
+	}()
+	for iter.Next() {
+		ptr := reflect.New(reflect.TypeOf(T)).Interface()
+		if err := iter.Decode(ptr); err != nil {
+			return err
+		}
+		id, ok := extid.Lookup(ptr)
+		if !ok {
+			return fmt.Errorf(`%T entity has no ID`, T)
+		}
+		if err := fn(ptr, id); err != nil {
+			return fmt.Errorf(`%v: %w`, id, err)
+		}
+	}
+	return iter.Err()
+}
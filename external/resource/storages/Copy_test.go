@@ -0,0 +1,222 @@
+package storages_test
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/adamluzsi/frameless/iterators"
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/change"
+	"github.com/toggler-io/toggler/domains/guardrail"
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/schedule"
+	"github.com/toggler-io/toggler/domains/security"
+	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/domains/webhook"
+	"github.com/toggler-io/toggler/external/resource/storages"
+)
+
+func TestCopy(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	var (
+		ctx  = context.Background()
+		from = s.Let(`from`, func(t *testcase.T) interface{} {
+			storage := storages.NewInMemory()
+			env := release.Environment{Name: `production`}
+			require.Nil(t, storage.ReleaseEnvironment(ctx).Create(ctx, &env))
+			flag := release.Flag{Name: `new-checkout`}
+			require.Nil(t, storage.ReleaseFlag(ctx).Create(ctx, &flag))
+			require.Nil(t, storage.ReleaseRollout(ctx).Create(ctx, &release.Rollout{
+				FlagID:        flag.ID,
+				EnvironmentID: env.ID,
+				Plan:          release.RolloutDecisionByGlobal{State: true},
+			}))
+			require.Nil(t, storage.ReleasePilot(ctx).Create(ctx, &release.Pilot{
+				FlagID:          flag.ID,
+				EnvironmentID:   env.ID,
+				PublicID:        `pilot-42`,
+				IsParticipating: true,
+			}))
+			require.Nil(t, storage.ReleaseVersion(ctx).Create(ctx, &release.Version{
+				Kind:      release.VersionKindFlag,
+				EntityID:  flag.ID,
+				Number:    1,
+				Action:    release.VersionActionCreate,
+				Snapshot:  []byte(`{"name":"new-checkout"}`),
+				CreatedAt: time.Now().UTC(),
+			}))
+			require.Nil(t, storage.SecurityToken(ctx).Create(ctx, &security.Token{
+				SHA512:   `sha512`,
+				OwnerUID: `owner`,
+				IssuedAt: time.Now().UTC(),
+			}))
+			subscription := webhook.Subscription{URL: `https://chat.example.com/hooks/toggler`, Secret: `secret`}
+			require.Nil(t, storage.WebhookSubscription(ctx).Create(ctx, &subscription))
+			require.Nil(t, storage.WebhookDelivery(ctx).Create(ctx, &webhook.Delivery{
+				SubscriptionID: subscription.ID,
+				EventID:        `flag/` + flag.ID + `/1`,
+				EventType:      webhook.EventFlagCreated,
+				Payload:        []byte(`{}`),
+				Status:         webhook.DeliveryStatusSucceeded,
+				CreatedAt:      time.Now().UTC(),
+				UpdatedAt:      time.Now().UTC(),
+			}))
+			request := change.Request{
+				EnvironmentID: env.ID,
+				Kind:          change.KindEnvironment,
+				Action:        change.ActionUpdate,
+				EntityID:      env.ID,
+				Base:          []byte(`{"name":"production"}`),
+				Proposal:      []byte(`{"name":"production","required_approvals":2}`),
+				Status:        change.StatusPending,
+				ProposedBy:    `alice`,
+				CreatedAt:     time.Now().UTC(),
+				UpdatedAt:     time.Now().UTC(),
+			}
+			require.Nil(t, storage.ChangeRequest(ctx).Create(ctx, &request))
+			require.Nil(t, storage.ChangeApproval(ctx).Create(ctx, &change.Approval{
+				RequestID: request.ID,
+				OwnerUID:  `bob`,
+				CreatedAt: time.Now().UTC(),
+			}))
+			require.Nil(t, storage.ScheduledChange(ctx).Create(ctx, &schedule.Change{
+				EnvironmentID: env.ID,
+				Kind:          schedule.KindRollout,
+				Action:        schedule.ActionUpdate,
+				EntityID:      `rollout-id`,
+				Proposal:      []byte(`{"plan":{"type":"global","state":false}}`),
+				RunAt:         time.Now().Add(time.Hour).UTC(),
+				Status:        schedule.StatusScheduled,
+				ScheduledBy:   `alice`,
+				CreatedAt:     time.Now().UTC(),
+				UpdatedAt:     time.Now().UTC(),
+			}))
+			require.Nil(t, storage.Guardrail(ctx).Create(ctx, &guardrail.Guardrail{
+				RolloutID:            `rollout-id`,
+				MaxErrorRateIncrease: 2,
+				WindowSeconds:        600,
+				MinRequests:          100,
+				Action:               guardrail.ActionZero,
+				Status:               guardrail.StatusArmed,
+				ArmedAt:              time.Now().UTC(),
+			}))
+			require.Nil(t, storage.GuardrailSignal(ctx).Create(ctx, &guardrail.Signal{
+				FlagID:        flag.ID,
+				EnvironmentID: env.ID,
+				Variant:       guardrail.VariantEnrolled,
+				Successes:     98,
+				Errors:        2,
+				ReportedAt:    time.Now().UTC(),
+			}))
+			return storage
+		})
+		to = s.Let(`to`, func(t *testcase.T) interface{} {
+			return storages.NewInMemory()
+		})
+		dryRun = s.LetValue(`dry run`, false)
+	)
+	fromGet := func(t *testcase.T) toggler.Storage { return from.Get(t).(toggler.Storage) }
+	toGet := func(t *testcase.T) toggler.Storage { return to.Get(t).(toggler.Storage) }
+	subject := func(t *testcase.T) (storages.CopyReport, error) {
+		return storages.Copy(ctx, fromGet(t), toGet(t), storages.CopyOptions{DryRun: dryRun.Get(t).(bool)})
+	}
+
+	allOf := func(t *testcase.T, s toggler.Storage) map[string][]interface{} {
+		all := make(map[string][]interface{})
+		collect := func(kind string, iter iterators.Interface, T interface{}) {
+			var vs []interface{}
+			for iter.Next() {
+				ptr := reflect.New(reflect.TypeOf(T)).Interface()
+				require.Nil(t, iter.Decode(ptr))
+				vs = append(vs, ptr)
+			}
+			require.Nil(t, iter.Err())
+			all[kind] = vs
+		}
+		collect(`environment`, s.ReleaseEnvironment(ctx).FindAll(ctx), release.Environment{})
+		collect(`flag`, s.ReleaseFlag(ctx).FindAll(ctx), release.Flag{})
+		collect(`rollout`, s.ReleaseRollout(ctx).FindAll(ctx), release.Rollout{})
+		collect(`pilot`, s.ReleasePilot(ctx).FindAll(ctx), release.Pilot{})
+		collect(`version`, s.ReleaseVersion(ctx).FindAll(ctx), release.Version{})
+		collect(`token`, s.SecurityToken(ctx).FindAll(ctx), security.Token{})
+		collect(`webhook_subscription`, s.WebhookSubscription(ctx).FindAll(ctx), webhook.Subscription{})
+		collect(`webhook_delivery`, s.WebhookDelivery(ctx).FindAll(ctx), webhook.Delivery{})
+		collect(`change_request`, s.ChangeRequest(ctx).FindAll(ctx), change.Request{})
+		collect(`change_approval`, s.ChangeApproval(ctx).FindAll(ctx), change.Approval{})
+		collect(`scheduled_change`, s.ScheduledChange(ctx).FindAll(ctx), schedule.Change{})
+		collect(`guardrail`, s.Guardrail(ctx).FindAll(ctx), guardrail.Guardrail{})
+		collect(`guardrail_signal`, s.GuardrailSignal(ctx).FindAll(ctx), guardrail.Signal{})
+		return all
+	}
+
+	s.Describe(`Copy`, func(s *testcase.Spec) {
+		s.Then(`every entity is copied with its ID`, func(t *testcase.T) {
+			report, err := subject(t)
+			require.Nil(t, err)
+			require.Equal(t, allOf(t, fromGet(t)), allOf(t, toGet(t)))
+			for _, kind := range report.Kinds {
+				require.Equal(t, 1, kind.Source, kind.Kind)
+				require.Equal(t, 1, kind.Copied, kind.Kind)
+				require.Equal(t, 1, kind.Verified, kind.Kind)
+			}
+		})
+
+		s.When(`an earlier copy was interrupted`, func(s *testcase.Spec) {
+			s.Before(func(t *testcase.T) {
+				var env release.Environment
+				iter := fromGet(t).ReleaseEnvironment(ctx).FindAll(ctx)
+				require.True(t, iter.Next())
+				require.Nil(t, iter.Decode(&env))
+				require.Nil(t, iter.Close())
+				require.Nil(t, toGet(t).ReleaseEnvironment(ctx).Create(ctx, &env))
+			})
+
+			s.Then(`the already copied entities are skipped`, func(t *testcase.T) {
+				report, err := subject(t)
+				require.Nil(t, err)
+				require.Equal(t, allOf(t, fromGet(t)), allOf(t, toGet(t)))
+				require.Equal(t, `environment`, report.Kinds[0].Kind)
+				require.Equal(t, 1, report.Kinds[0].Skipped)
+				require.Equal(t, 0, report.Kinds[0].Copied)
+				require.Equal(t, 1, report.Kinds[0].Verified)
+			})
+		})
+
+		s.When(`it is a dry run`, func(s *testcase.Spec) {
+			dryRun.LetValue(s, true)
+
+			s.Then(`it reports what would be copied without writing the destination`, func(t *testcase.T) {
+				report, err := subject(t)
+				require.Nil(t, err)
+				for _, kind := range report.Kinds {
+					require.Equal(t, 1, kind.Copied, kind.Kind)
+				}
+				for kind, entities := range allOf(t, toGet(t)) {
+					require.Empty(t, entities, kind)
+				}
+			})
+		})
+
+		s.When(`the destination rejects the writes`, func(s *testcase.Spec) {
+			to.Let(s, func(t *testcase.T) interface{} {
+				path := filepath.Join(t.TempDir(), `release.yaml`)
+				writeManifest(t, path, release.Manifest{})
+				storage, err := storages.NewFile(path, 0)
+				require.Nil(t, err)
+				t.Defer(storage.Close)
+				return storage
+			})
+
+			s.Then(`it yields the error`, func(t *testcase.T) {
+				_, err := subject(t)
+				require.ErrorIs(t, err, storages.ErrReadOnly)
+			})
+		})
+	})
+}
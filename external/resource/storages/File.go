@@ -0,0 +1,652 @@
+package storages
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/ghodss/yaml"
+
+	"github.com/toggler-io/toggler/domains/change"
+	"github.com/toggler-io/toggler/domains/errs"
+	"github.com/toggler-io/toggler/domains/guardrail"
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/schedule"
+	"github.com/toggler-io/toggler/domains/security"
+	"github.com/toggler-io/toggler/domains/webhook"
+	"github.com/toggler-io/toggler/external/logging"
+)
+
+// ErrReadOnly is returned by the write operations of the File storage.
+// Its content can be only changed by editing the manifest files.
+var ErrReadOnly = errs.Forbidden(`read_only_storage`, `file storage is read-only, edit the manifest files instead`)
+
+// DefaultFileWatchInterval is how often the File storage checks the manifest files for changes by default.
+const DefaultFileWatchInterval = 5 * time.Second
+
+// NewFile loads the release manifests from the file or directory on the given path,
+// and reloads them when they change.
+//
+// The manifests use the same YAML/JSON format as the export command's output.
+// The manifests of a directory are merged together, and the hidden files are ignored.
+// The entity IDs are derived from the names, so they don't change between the reloads.
+func NewFile(path string, watchInterval time.Duration) (*File, error) {
+	s := &File{Path: path}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	if 0 < watchInterval {
+		ctx, signaler := context.WithCancel(context.Background())
+		s.exit.signaler = signaler
+		s.exit.wg.Add(1)
+		go s.watch(ctx, watchInterval)
+	}
+	return s, nil
+}
+
+// File is a read-only storage, which serves the release configuration from manifest files.
+// It allows running toggler without a database, for example on the edge with a mounted config directory.
+type File struct {
+	Path string
+
+	mutex       sync.RWMutex
+	dataset     *InMemory
+	fingerprint string
+	reloaded    chan struct{}
+
+	subscribers struct {
+		ReleaseFlag         subscribers
+		ReleasePilot        subscribers
+		ReleaseRollout      subscribers
+		ReleaseEnvironment  subscribers
+		ReleaseVersion      subscribers
+		SecurityToken       subscribers
+		WebhookSubscription subscribers
+		WebhookDelivery     subscribers
+		ChangeRequest       subscribers
+		ChangeApproval      subscribers
+		ScheduledChange     subscribers
+		Guardrail           subscribers
+		GuardrailSignal     subscribers
+	}
+	locks processLocks
+	exit  struct {
+		signaler func()
+		wg       sync.WaitGroup
+	}
+}
+
+// Reload reads the manifest files, and replaces the served dataset with their content.
+// When the manifests are invalid, the previous dataset is kept.
+func (s *File) Reload() error {
+	fingerprint, err := fileFingerprint(s.Path)
+	if err != nil {
+		return err
+	}
+	m, err := readManifests(s.Path)
+	if err != nil {
+		return err
+	}
+	dataset, err := newFileDataset(context.Background(), m)
+	if err != nil {
+		return fmt.Errorf(`%s: %w`, s.Path, err)
+	}
+
+	s.mutex.Lock()
+	s.dataset = dataset
+	s.fingerprint = fingerprint
+	if s.reloaded != nil {
+		close(s.reloaded)
+		s.reloaded = nil
+	}
+	s.mutex.Unlock()
+
+	// the subscribers, like the caches, have to drop what they know about the previous dataset.
+	ctx := context.Background()
+	for _, subs := range []*subscribers{
+		&s.subscribers.ReleaseFlag,
+		&s.subscribers.ReleasePilot,
+		&s.subscribers.ReleaseRollout,
+		&s.subscribers.ReleaseEnvironment,
+		&s.subscribers.SecurityToken,
+	} {
+		subs.handleEvent(ctx, frameless.DeleteAllEvent{})
+	}
+	return nil
+}
+
+// Reloaded returns a channel, which is closed when the manifest files are reloaded the next time,
+// either by the watcher or by calling Reload.
+// The reloads which fail because of invalid manifests don't close it.
+func (s *File) Reloaded() <-chan struct{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.reloaded == nil {
+		s.reloaded = make(chan struct{})
+	}
+	return s.reloaded
+}
+
+func (s *File) watch(ctx context.Context, interval time.Duration) {
+	defer s.exit.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastSeen = s.currentFingerprint()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		fingerprint, err := fileFingerprint(s.Path)
+		if err != nil {
+			logging.Error(ctx, `unable to read the storage files`, err, slog.String(`path`, s.Path))
+			continue
+		}
+		if fingerprint == lastSeen {
+			continue
+		}
+		// an invalid change is reported only once, and retried when the files change again.
+		lastSeen = fingerprint
+		if err := s.Reload(); err != nil {
+			logging.Error(ctx, `unable to reload the storage files`, err, slog.String(`path`, s.Path))
+		}
+	}
+}
+
+func (s *File) current() *InMemory {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.dataset
+}
+
+func (s *File) currentFingerprint() string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.fingerprint
+}
+
+func (s *File) Close() error {
+	if s.exit.signaler != nil {
+		s.exit.signaler()
+		s.exit.wg.Wait()
+	}
+	return nil
+}
+
+// BeginTx is a no-op, since the File storage can't be changed through its interface.
+func (s *File) BeginTx(ctx context.Context) (context.Context, error) {
+	return ctx, ctx.Err()
+}
+
+func (s *File) CommitTx(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (s *File) RollbackTx(ctx context.Context) error {
+	return ctx.Err()
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// manifestFileExtensions are the file extensions which are read from a manifest directory.
+var manifestFileExtensions = map[string]struct{}{`.yaml`: {}, `.yml`: {}, `.json`: {}}
+
+func manifestFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), `.`) {
+			continue
+		}
+		if _, ok := manifestFileExtensions[filepath.Ext(entry.Name())]; !ok {
+			continue
+		}
+		files = append(files, filepath.Join(path, entry.Name()))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// fileFingerprint describes the current version of the manifest files with their size and modification time.
+// The files are checked with os.Stat, so the symlinks of a mounted config directory are followed.
+func fileFingerprint(path string) (string, error) {
+	files, err := manifestFiles(path)
+	if err != nil {
+		return ``, err
+	}
+	var fingerprint strings.Builder
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return ``, err
+		}
+		_, _ = fmt.Fprintf(&fingerprint, "%s:%d:%d\n", file, info.Size(), info.ModTime().UnixNano())
+	}
+	return fingerprint.String(), nil
+}
+
+func readManifests(path string) (release.Manifest, error) {
+	files, err := manifestFiles(path)
+	if err != nil {
+		return release.Manifest{}, err
+	}
+	var m release.Manifest
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return release.Manifest{}, err
+		}
+		var fm release.Manifest
+		if err := yaml.Unmarshal(data, &fm); err != nil {
+			return release.Manifest{}, fmt.Errorf(`%s: %w`, file, err)
+		}
+		m.Environments = append(m.Environments, fm.Environments...)
+		m.Flags = append(m.Flags, fm.Flags...)
+	}
+	return m, m.Validate()
+}
+
+// newFileDataset builds the entities of the manifest in a new in-memory storage.
+func newFileDataset(ctx context.Context, m release.Manifest) (*InMemory, error) {
+	dataset := NewInMemory()
+
+	envIDByName := make(map[string]string)
+	for _, me := range m.Environments {
+		env := release.Environment{ID: me.Name, Name: me.Name}
+		if err := dataset.ReleaseEnvironment(ctx).Create(ctx, &env); err != nil {
+			return nil, err
+		}
+		envIDByName[me.Name] = env.ID
+	}
+
+	envID := func(name string) (string, error) {
+		id, ok := envIDByName[name]
+		if !ok {
+			return ``, fmt.Errorf(`%w: %s`, release.ErrManifestUnknownEnvironment, name)
+		}
+		return id, nil
+	}
+
+	for _, mf := range m.Flags {
+		flag := release.Flag{ID: mf.Name, Name: mf.Name}
+		if err := dataset.ReleaseFlag(ctx).Create(ctx, &flag); err != nil {
+			return nil, err
+		}
+
+		for _, mr := range mf.Rollouts {
+			eid, err := envID(mr.Environment)
+			if err != nil {
+				return nil, err
+			}
+			rollout := release.Rollout{
+				ID:            flag.ID + `@` + eid,
+				FlagID:        flag.ID,
+				EnvironmentID: eid,
+				Plan:          mr.Plan.Plan,
+			}
+			if err := dataset.ReleaseRollout(ctx).Create(ctx, &rollout); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, mp := range mf.Pilots {
+			eid, err := envID(mp.Environment)
+			if err != nil {
+				return nil, err
+			}
+			pilot := release.Pilot{
+				ID:              flag.ID + `@` + eid + `/` + mp.PublicID,
+				FlagID:          flag.ID,
+				EnvironmentID:   eid,
+				PublicID:        mp.PublicID,
+				IsParticipating: mp.IsParticipating,
+			}
+			if err := dataset.ReleasePilot(ctx).Create(ctx, &pilot); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return dataset, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// fileEntityStorage is the read-only part shared by the entity storages of File.
+// It reads the dataset that is current at the time of the call,
+// so the storages can be held onto between the reloads.
+type fileEntityStorage struct {
+	*subscribers
+	finder func(ctx context.Context) frameless.Finder
+}
+
+func (s fileEntityStorage) Create(ctx context.Context, ptr interface{}) error {
+	return ErrReadOnly
+}
+
+func (s fileEntityStorage) Update(ctx context.Context, ptr interface{}) error {
+	return ErrReadOnly
+}
+
+func (s fileEntityStorage) DeleteByID(ctx context.Context, id interface{}) error {
+	return ErrReadOnly
+}
+
+func (s fileEntityStorage) DeleteAll(ctx context.Context) error {
+	return ErrReadOnly
+}
+
+func (s fileEntityStorage) FindByID(ctx context.Context, ptr, id interface{}) (bool, error) {
+	return s.finder(ctx).FindByID(ctx, ptr, id)
+}
+
+func (s fileEntityStorage) FindAll(ctx context.Context) frameless.Iterator {
+	return s.finder(ctx).FindAll(ctx)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (s *File) ReleaseFlag(ctx context.Context) release.FlagStorage {
+	return FileReleaseFlagStorage{
+		fileEntityStorage: fileEntityStorage{
+			subscribers: &s.subscribers.ReleaseFlag,
+			finder:      func(ctx context.Context) frameless.Finder { return s.current().ReleaseFlag(ctx) },
+		},
+		file: s,
+	}
+}
+
+type FileReleaseFlagStorage struct {
+	fileEntityStorage
+	file *File
+}
+
+func (s FileReleaseFlagStorage) FindByName(ctx context.Context, name string) (*release.Flag, error) {
+	return s.file.current().ReleaseFlag(ctx).FindByName(ctx, name)
+}
+
+func (s FileReleaseFlagStorage) FindByNames(ctx context.Context, names ...string) release.FlagEntries {
+	return s.file.current().ReleaseFlag(ctx).FindByNames(ctx, names...)
+}
+
+func (s FileReleaseFlagStorage) FindByQuery(ctx context.Context, q release.FlagQuery) release.FlagEntries {
+	return s.file.current().ReleaseFlag(ctx).FindByQuery(ctx, q)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (s *File) ReleasePilot(ctx context.Context) release.PilotStorage {
+	return FileReleasePilotStorage{
+		fileEntityStorage: fileEntityStorage{
+			subscribers: &s.subscribers.ReleasePilot,
+			finder:      func(ctx context.Context) frameless.Finder { return s.current().ReleasePilot(ctx) },
+		},
+		file: s,
+	}
+}
+
+type FileReleasePilotStorage struct {
+	fileEntityStorage
+	file *File
+}
+
+func (s FileReleasePilotStorage) FindByFlagEnvPublicID(ctx context.Context, flagID, envID interface{}, publicID string) (*release.Pilot, error) {
+	return s.file.current().ReleasePilot(ctx).FindByFlagEnvPublicID(ctx, flagID, envID, publicID)
+}
+
+func (s FileReleasePilotStorage) FindByFlag(ctx context.Context, flag release.Flag) release.PilotEntries {
+	return s.file.current().ReleasePilot(ctx).FindByFlag(ctx, flag)
+}
+
+func (s FileReleasePilotStorage) FindByPublicID(ctx context.Context, publicID string) release.PilotEntries {
+	return s.file.current().ReleasePilot(ctx).FindByPublicID(ctx, publicID)
+}
+
+func (s FileReleasePilotStorage) FindByQuery(ctx context.Context, q release.PilotQuery) release.PilotEntries {
+	return s.file.current().ReleasePilot(ctx).FindByQuery(ctx, q)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (s *File) ReleaseRollout(ctx context.Context) release.RolloutStorage {
+	return FileReleaseRolloutStorage{
+		fileEntityStorage: fileEntityStorage{
+			subscribers: &s.subscribers.ReleaseRollout,
+			finder:      func(ctx context.Context) frameless.Finder { return s.current().ReleaseRollout(ctx) },
+		},
+		file: s,
+	}
+}
+
+type FileReleaseRolloutStorage struct {
+	fileEntityStorage
+	file *File
+}
+
+func (s FileReleaseRolloutStorage) FindByFlagEnvironment(ctx context.Context, flag release.Flag, env release.Environment, ptr *release.Rollout) (bool, error) {
+	return s.file.current().ReleaseRollout(ctx).FindByFlagEnvironment(ctx, flag, env, ptr)
+}
+
+func (s FileReleaseRolloutStorage) FindByQuery(ctx context.Context, q release.RolloutQuery) release.RolloutEntries {
+	return s.file.current().ReleaseRollout(ctx).FindByQuery(ctx, q)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (s *File) ReleaseEnvironment(ctx context.Context) release.EnvironmentStorage {
+	return FileReleaseEnvironmentStorage{
+		fileEntityStorage: fileEntityStorage{
+			subscribers: &s.subscribers.ReleaseEnvironment,
+			finder:      func(ctx context.Context) frameless.Finder { return s.current().ReleaseEnvironment(ctx) },
+		},
+		file: s,
+	}
+}
+
+type FileReleaseEnvironmentStorage struct {
+	fileEntityStorage
+	file *File
+}
+
+func (s FileReleaseEnvironmentStorage) FindByAlias(ctx context.Context, idOrName string, env *release.Environment) (bool, error) {
+	return s.file.current().ReleaseEnvironment(ctx).FindByAlias(ctx, idOrName, env)
+}
+
+func (s FileReleaseEnvironmentStorage) FindByQuery(ctx context.Context, q release.EnvironmentQuery) release.EnvironmentEntries {
+	return s.file.current().ReleaseEnvironment(ctx).FindByQuery(ctx, q)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// ReleaseVersion has no entries, since the history of the manifests is kept by their version control.
+func (s *File) ReleaseVersion(ctx context.Context) release.VersionStorage {
+	return FileReleaseVersionStorage{
+		fileEntityStorage: fileEntityStorage{
+			subscribers: &s.subscribers.ReleaseVersion,
+			finder:      func(ctx context.Context) frameless.Finder { return s.current().ReleaseVersion(ctx) },
+		},
+		file: s,
+	}
+}
+
+type FileReleaseVersionStorage struct {
+	fileEntityStorage
+	file *File
+}
+
+func (s FileReleaseVersionStorage) FindByQuery(ctx context.Context, q release.VersionQuery) release.VersionEntries {
+	return s.file.current().ReleaseVersion(ctx).FindByQuery(ctx, q)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// SecurityToken has no entries, since the manifests don't describe tokens,
+// so the File storage serves only the endpoints which don't require a token.
+func (s *File) SecurityToken(ctx context.Context) security.TokenStorage {
+	return FileSecurityTokenStorage{
+		fileEntityStorage: fileEntityStorage{
+			subscribers: &s.subscribers.SecurityToken,
+			finder:      func(ctx context.Context) frameless.Finder { return s.current().SecurityToken(ctx) },
+		},
+		file: s,
+	}
+}
+
+type FileSecurityTokenStorage struct {
+	fileEntityStorage
+	file *File
+}
+
+func (s FileSecurityTokenStorage) FindTokenBySHA512Hex(ctx context.Context, sha512hex string) (*security.Token, error) {
+	return s.file.current().SecurityToken(ctx).FindTokenBySHA512Hex(ctx, sha512hex)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// WebhookSubscription has no entries, since the manifests don't describe webhooks.
+// The changes of the manifest files can be followed in their version control instead.
+func (s *File) WebhookSubscription(ctx context.Context) webhook.SubscriptionStorage {
+	return fileEntityStorage{
+		subscribers: &s.subscribers.WebhookSubscription,
+		finder:      func(ctx context.Context) frameless.Finder { return s.current().WebhookSubscription(ctx) },
+	}
+}
+
+// WebhookDelivery has no entries, as there are no webhook subscriptions.
+func (s *File) WebhookDelivery(ctx context.Context) webhook.DeliveryStorage {
+	return FileWebhookDeliveryStorage{
+		fileEntityStorage: fileEntityStorage{
+			subscribers: &s.subscribers.WebhookDelivery,
+			finder:      func(ctx context.Context) frameless.Finder { return s.current().WebhookDelivery(ctx) },
+		},
+		file: s,
+	}
+}
+
+type FileWebhookDeliveryStorage struct {
+	fileEntityStorage
+	file *File
+}
+
+func (s FileWebhookDeliveryStorage) FindByQuery(ctx context.Context, q webhook.DeliveryQuery) webhook.DeliveryEntries {
+	return s.file.current().WebhookDelivery(ctx).FindByQuery(ctx, q)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// ChangeRequest has no entries, since the manifests are read-only,
+// and their changes are reviewed in their version control instead.
+func (s *File) ChangeRequest(ctx context.Context) change.RequestStorage {
+	return FileChangeRequestStorage{
+		fileEntityStorage: fileEntityStorage{
+			subscribers: &s.subscribers.ChangeRequest,
+			finder:      func(ctx context.Context) frameless.Finder { return s.current().ChangeRequest(ctx) },
+		},
+		file: s,
+	}
+}
+
+type FileChangeRequestStorage struct {
+	fileEntityStorage
+	file *File
+}
+
+func (s FileChangeRequestStorage) FindByQuery(ctx context.Context, q change.RequestQuery) change.RequestEntries {
+	return s.file.current().ChangeRequest(ctx).FindByQuery(ctx, q)
+}
+
+// ChangeApproval has no entries, as there are no change requests.
+func (s *File) ChangeApproval(ctx context.Context) change.ApprovalStorage {
+	return FileChangeApprovalStorage{
+		fileEntityStorage: fileEntityStorage{
+			subscribers: &s.subscribers.ChangeApproval,
+			finder:      func(ctx context.Context) frameless.Finder { return s.current().ChangeApproval(ctx) },
+		},
+		file: s,
+	}
+}
+
+type FileChangeApprovalStorage struct {
+	fileEntityStorage
+	file *File
+}
+
+func (s FileChangeApprovalStorage) FindByQuery(ctx context.Context, q change.ApprovalQuery) change.ApprovalEntries {
+	return s.file.current().ChangeApproval(ctx).FindByQuery(ctx, q)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// ScheduledChange has no entries, since the manifests are read-only.
+func (s *File) ScheduledChange(ctx context.Context) schedule.ChangeStorage {
+	return FileScheduledChangeStorage{
+		fileEntityStorage: fileEntityStorage{
+			subscribers: &s.subscribers.ScheduledChange,
+			finder:      func(ctx context.Context) frameless.Finder { return s.current().ScheduledChange(ctx) },
+		},
+		file: s,
+	}
+}
+
+type FileScheduledChangeStorage struct {
+	fileEntityStorage
+	file *File
+}
+
+func (s FileScheduledChangeStorage) FindByQuery(ctx context.Context, q schedule.ChangeQuery) schedule.ChangeEntries {
+	return s.file.current().ScheduledChange(ctx).FindByQuery(ctx, q)
+}
+
+// Guardrail has no entries, since the manifests are read-only.
+func (s *File) Guardrail(ctx context.Context) guardrail.GuardrailStorage {
+	return fileEntityStorage{
+		subscribers: &s.subscribers.Guardrail,
+		finder:      func(ctx context.Context) frameless.Finder { return s.current().Guardrail(ctx) },
+	}
+}
+
+// GuardrailSignal has no entries, as there are no guardrails to report the health signals for.
+func (s *File) GuardrailSignal(ctx context.Context) guardrail.SignalStorage {
+	return FileGuardrailSignalStorage{
+		fileEntityStorage: fileEntityStorage{
+			subscribers: &s.subscribers.GuardrailSignal,
+			finder:      func(ctx context.Context) frameless.Finder { return s.current().GuardrailSignal(ctx) },
+		},
+		file: s,
+	}
+}
+
+type FileGuardrailSignalStorage struct {
+	fileEntityStorage
+	file *File
+}
+
+func (s FileGuardrailSignalStorage) FindByQuery(ctx context.Context, q guardrail.SignalQuery) guardrail.SignalEntries {
+	return s.file.current().GuardrailSignal(ctx).FindByQuery(ctx, q)
+}
+
+func (s *File) TryLock(ctx context.Context, name string) (func() error, bool, error) {
+	return s.locks.TryLock(ctx, name)
+}
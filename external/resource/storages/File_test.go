@@ -0,0 +1,254 @@
+package storages_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/doubles"
+	"github.com/adamluzsi/frameless/iterators"
+	"github.com/adamluzsi/testcase"
+	"github.com/ghodss/yaml"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/security"
+	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/external/resource/storages"
+)
+
+var (
+	_ toggler.Storage            = &storages.File{}
+	_ release.EnvironmentStorage = storages.FileReleaseEnvironmentStorage{}
+	_ release.FlagStorage        = storages.FileReleaseFlagStorage{}
+	_ release.RolloutStorage     = storages.FileReleaseRolloutStorage{}
+	_ release.PilotStorage       = storages.FileReleasePilotStorage{}
+	_ security.TokenStorage      = storages.FileSecurityTokenStorage{}
+)
+
+func writeManifest(tb testing.TB, path string, m release.Manifest) {
+	data, err := yaml.Marshal(m)
+	require.Nil(tb, err)
+	// the manifest is renamed into place, so the watcher never reloads a half written file.
+	tmp := filepath.Join(filepath.Dir(path), `.`+filepath.Base(path)+`.tmp`)
+	require.Nil(tb, ioutil.WriteFile(tmp, data, 0644))
+	require.Nil(tb, os.Rename(tmp, path))
+}
+
+func TestFile(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	dir := s.Let(`manifest directory`, func(t *testcase.T) interface{} {
+		return t.TempDir()
+	})
+	dirGet := func(t *testcase.T) string { return dir.Get(t).(string) }
+	manifest := s.Let(`manifest`, func(t *testcase.T) interface{} {
+		return release.Manifest{
+			Environments: []release.ManifestEnvironment{{Name: `development`}, {Name: `production`}},
+			Flags: []release.ManifestFlag{{
+				Name: `new-checkout`,
+				Rollouts: []release.ManifestRollout{{
+					Environment: `production`,
+					Plan:        release.RolloutPlanView{Plan: release.RolloutDecisionByGlobal{State: true}},
+				}},
+				Pilots: []release.ManifestPilot{{
+					Environment:     `development`,
+					PublicID:        `pilot-42`,
+					IsParticipating: true,
+				}},
+			}},
+		}
+	})
+	manifestGet := func(t *testcase.T) release.Manifest { return manifest.Get(t).(release.Manifest) }
+	s.Before(func(t *testcase.T) {
+		writeManifest(t, filepath.Join(dirGet(t), `release.yaml`), manifestGet(t))
+	})
+
+	subject := func(t *testcase.T) (*storages.File, error) {
+		return storages.NewFile(dirGet(t), 0)
+	}
+	storage := s.Let(`storage`, func(t *testcase.T) interface{} {
+		storage, err := subject(t)
+		require.Nil(t, err)
+		t.Defer(storage.Close)
+		return storage
+	})
+	storageGet := func(t *testcase.T) *storages.File { return storage.Get(t).(*storages.File) }
+
+	s.Describe(`NewFile`, func(s *testcase.Spec) {
+		s.Then(`it serves the entities of the manifests`, func(t *testcase.T) {
+			ctx := context.Background()
+
+			var env release.Environment
+			found, err := storageGet(t).ReleaseEnvironment(ctx).FindByAlias(ctx, `production`, &env)
+			require.Nil(t, err)
+			require.True(t, found)
+
+			flag, err := storageGet(t).ReleaseFlag(ctx).FindByName(ctx, `new-checkout`)
+			require.Nil(t, err)
+			require.NotNil(t, flag)
+
+			var rollout release.Rollout
+			found, err = storageGet(t).ReleaseRollout(ctx).FindByFlagEnvironment(ctx, *flag, env, &rollout)
+			require.Nil(t, err)
+			require.True(t, found)
+			require.Equal(t, release.RolloutDecisionByGlobal{State: true}, rollout.Plan)
+
+			var pilots []release.Pilot
+			require.Nil(t, iterators.Collect(storageGet(t).ReleasePilot(ctx).FindByFlag(ctx, *flag), &pilots))
+			require.Len(t, pilots, 1)
+			require.Equal(t, `pilot-42`, pilots[0].PublicID)
+			require.True(t, pilots[0].IsParticipating)
+		})
+
+		s.Then(`the entity IDs are derived from the names`, func(t *testcase.T) {
+			ctx := context.Background()
+			var flag release.Flag
+			found, err := storageGet(t).ReleaseFlag(ctx).FindByID(ctx, &flag, `new-checkout`)
+			require.Nil(t, err)
+			require.True(t, found)
+		})
+
+		s.When(`a rollout references an undeclared environment`, func(s *testcase.Spec) {
+			manifest.Let(s, func(t *testcase.T) interface{} {
+				m := manifest.Init(t).(release.Manifest)
+				m.Flags[0].Rollouts[0].Environment = `staging`
+				return m
+			})
+
+			s.Then(`it yields error`, func(t *testcase.T) {
+				_, err := subject(t)
+				require.ErrorIs(t, err, release.ErrManifestUnknownEnvironment)
+			})
+		})
+
+		s.When(`an entity is declared in more than one manifest file`, func(s *testcase.Spec) {
+			s.Before(func(t *testcase.T) {
+				writeManifest(t, filepath.Join(dirGet(t), `other.json`), release.Manifest{
+					Flags: []release.ManifestFlag{{Name: `new-checkout`}},
+				})
+			})
+
+			s.Then(`it yields error`, func(t *testcase.T) {
+				_, err := subject(t)
+				require.ErrorIs(t, err, release.ErrManifestDuplicate)
+			})
+		})
+	})
+
+	s.Describe(`write operations`, func(s *testcase.Spec) {
+		s.Then(`they are rejected`, func(t *testcase.T) {
+			ctx := context.Background()
+			flags := storageGet(t).ReleaseFlag(ctx)
+			require.ErrorIs(t, flags.Create(ctx, &release.Flag{Name: `other`}), storages.ErrReadOnly)
+			require.ErrorIs(t, flags.Update(ctx, &release.Flag{ID: `new-checkout`, Name: `other`}), storages.ErrReadOnly)
+			require.ErrorIs(t, flags.DeleteByID(ctx, `new-checkout`), storages.ErrReadOnly)
+			require.ErrorIs(t, flags.DeleteAll(ctx), storages.ErrReadOnly)
+			require.ErrorIs(t, storageGet(t).SecurityToken(ctx).Create(ctx, &security.Token{}), storages.ErrReadOnly)
+		})
+	})
+
+	s.Describe(`Reload`, func(s *testcase.Spec) {
+		changed := s.Let(`changed manifest`, func(t *testcase.T) interface{} {
+			m := manifestGet(t)
+			m.Flags = append(m.Flags, release.ManifestFlag{Name: `dark-mode`})
+			return m
+		})
+
+		s.When(`the manifests are changed`, func(s *testcase.Spec) {
+			s.Before(func(t *testcase.T) {
+				storageGet(t) // load the original
+				writeManifest(t, filepath.Join(dirGet(t), `release.yaml`), changed.Get(t).(release.Manifest))
+			})
+
+			s.Then(`the new dataset is served`, func(t *testcase.T) {
+				ctx := context.Background()
+				flags := storageGet(t).ReleaseFlag(ctx)
+				require.Nil(t, storageGet(t).Reload())
+				flag, err := flags.FindByName(ctx, `dark-mode`)
+				require.Nil(t, err)
+				require.NotNil(t, flag)
+			})
+
+			s.Then(`the subscribers receive a delete all event`, func(t *testcase.T) {
+				ctx := context.Background()
+				var events []interface{}
+				sub, err := storageGet(t).ReleaseFlag(ctx).SubscribeToDeleterEvents(ctx, doubles.StubSubscriber{
+					HandleFunc: func(ctx context.Context, event interface{}) error {
+						events = append(events, event)
+						return nil
+					},
+				})
+				require.Nil(t, err)
+				t.Defer(sub.Close)
+
+				require.Nil(t, storageGet(t).Reload())
+				require.Equal(t, []interface{}{frameless.DeleteAllEvent{}}, events)
+			})
+		})
+
+		s.When(`the changed manifests are invalid`, func(s *testcase.Spec) {
+			s.Before(func(t *testcase.T) {
+				storageGet(t) // load the original
+				require.Nil(t, ioutil.WriteFile(filepath.Join(dirGet(t), `release.yaml`), []byte(`flags: [{`), 0644))
+			})
+
+			s.Then(`it yields error and the previous dataset is kept`, func(t *testcase.T) {
+				ctx := context.Background()
+				require.Error(t, storageGet(t).Reload())
+				flag, err := storageGet(t).ReleaseFlag(ctx).FindByName(ctx, `new-checkout`)
+				require.Nil(t, err)
+				require.NotNil(t, flag)
+			})
+		})
+	})
+
+	s.Describe(`watching`, func(s *testcase.Spec) {
+		storage.Let(s, func(t *testcase.T) interface{} {
+			storage, err := storages.NewFile(dirGet(t), 10*time.Millisecond)
+			require.Nil(t, err)
+			t.Defer(storage.Close)
+			return storage
+		})
+
+		s.Then(`the changes of the manifest files are loaded`, func(t *testcase.T) {
+			ctx := context.Background()
+			reloaded := storageGet(t).Reloaded()
+			writeManifest(t, filepath.Join(dirGet(t), `environments.yml`), release.Manifest{
+				Environments: []release.ManifestEnvironment{{Name: `staging`}},
+			})
+
+			select {
+			case <-reloaded:
+			case <-time.After(time.Minute):
+				t.Fatal(`the manifest files were not reloaded`)
+			}
+			found, err := storageGet(t).ReleaseEnvironment(ctx).FindByAlias(ctx, `staging`, &release.Environment{})
+			require.Nil(t, err)
+			require.True(t, found)
+		})
+	})
+}
+
+func TestNew_file(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Describe(`New`, func(s *testcase.Spec) {
+		s.When(`the connection string use the file scheme`, func(s *testcase.Spec) {
+			s.Then(`it serves the manifests on the path`, func(t *testcase.T) {
+				dir := t.TempDir()
+				writeManifest(t, filepath.Join(dir, `release.yaml`), release.Manifest{
+					Flags: []release.ManifestFlag{{Name: `new-checkout`}},
+				})
+				storage, err := storages.New(`file://` + dir + `?watch_interval=1m`)
+				require.Nil(t, err)
+				t.Cleanup(func() { require.Nil(t, storage.Close()) })
+				require.Equal(t, dir, storage.(*storages.File).Path)
+			})
+		})
+	})
+}
@@ -3,6 +3,8 @@ package storages
 import (
 	"fmt"
 	"net/url"
+	"strings"
+	"time"
 
 	"github.com/toggler-io/toggler/domains/toggler"
 )
@@ -26,6 +28,20 @@ func New(connstr string) (toggler.Storage, error) {
 	case `memory`:
 		return NewInMemory(), nil
 
+	case `sqlite`:
+		return NewSQLite(strings.TrimPrefix(connstr, `sqlite://`))
+
+	case `file`:
+		// the host part is kept, so relative paths can be given as well, like file://./manifests
+		watchInterval := DefaultFileWatchInterval
+		if v := u.Query().Get(`watch_interval`); v != `` {
+			watchInterval, err = time.ParseDuration(v)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return NewFile(u.Host+u.Path, watchInterval)
+
 	default:
 		return nil, fmt.Errorf(`ErrNotImplemented`)
 	}
@@ -14,9 +14,12 @@ import (
 	"github.com/adamluzsi/frameless/lazyloading"
 	"github.com/adamluzsi/frameless/postgresql"
 	"github.com/adamluzsi/frameless/reflects"
-	"github.com/lib/pq"
+	"github.com/toggler-io/toggler/domains/change"
+	"github.com/toggler-io/toggler/domains/guardrail"
 	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/schedule"
 	"github.com/toggler-io/toggler/domains/security"
+	"github.com/toggler-io/toggler/domains/webhook"
 
 	"github.com/toggler-io/toggler/external/resource/storages/migrations"
 )
@@ -40,94 +43,102 @@ type Postgres struct {
 	DSN string
 	postgresql.ConnectionManager
 
-	init sync.Once
+	init   sync.Once
+	events *pgEvents
 
-	subs struct {
-		listener  *pq.Listener
-		lock      sync.Mutex
-		callbacks []pq.EventCallbackType
-	}
 	storage struct {
-		ReleaseFlag        lazyloading.Var
-		ReleasePilot       lazyloading.Var
-		ReleaseRollout     lazyloading.Var
-		ReleaseEnvironment lazyloading.Var
-		SecurityToken      lazyloading.Var
+		ReleaseFlag         lazyloading.Var
+		ReleasePilot        lazyloading.Var
+		ReleaseRollout      lazyloading.Var
+		ReleaseEnvironment  lazyloading.Var
+		ReleaseVersion      lazyloading.Var
+		SecurityToken       lazyloading.Var
+		WebhookSubscription lazyloading.Var
+		WebhookDelivery     lazyloading.Var
+		ChangeRequest       lazyloading.Var
+		ChangeApproval      lazyloading.Var
+		ScheduledChange     lazyloading.Var
+		Guardrail           lazyloading.Var
+		GuardrailSignal     lazyloading.Var
 	}
 }
 
 func (p *Postgres) Init() (rErr error) {
 	p.init.Do(func() {
-		// toggler#Storage
-		// releases#Storage
-		// FlagStorage
-		// Publisher
-		// CreatorPublisher
-		// describe .Subscribe/Create
-		// and events made
-		// and then new subscriberGet registered
-		// and further events made
-		// then new subscriberGet will receive new events
-		// and it fails because in some case old events are not received when a new subscriber is made.
-		//
-		//const reconnectMinInterval = 10 * time.Second
-		//const reconnectMaxInterval = time.Minute
-		//p.subs.listener = pq.NewListener(p.DSN, reconnectMinInterval, reconnectMaxInterval, func(event pq.ListenerEventType, err error) {
-		//	for _, cb := range p.subs.callbacks {
-		//		cb(event, err)
-		//	}
-		//})
+		p.events = newPgEvents(p.DSN, p.ConnectionManager)
 	})
 	return
 }
 
 func (p *Postgres) Close() error {
+	if p.events != nil {
+		if err := p.events.Close(); err != nil {
+			return err
+		}
+	}
 	if p.ConnectionManager == nil {
 		return nil
 	}
 	return p.ConnectionManager.Close()
 }
 
+// mkPostgresqlStorage creates a storage that publish its events through NOTIFY,
+// thus the subscribers of every toggler process receive the changes, not just the one which made them.
 func (p *Postgres) mkPostgresqlStorage(T interface{}, m postgresql.Mapping) *postgresql.Storage {
-	listenNotifySM := postgresql.NewListenNotifySubscriptionManager(T, m, p.DSN, p.ConnectionManager)
-	//listenNotifySM.Listener = p.subs.listener
-	//p.subs.lock.Lock()
-	//p.subs.callbacks = append(p.subs.callbacks, listenNotifySM.ListenerEventCallback)
-	//p.subs.lock.Unlock()
+	_ = p.Init()
 	return &postgresql.Storage{
 		T:                   T,
 		Mapping:             m,
 		ConnectionManager:   p.ConnectionManager,
-		SubscriptionManager: listenNotifySM,
+		SubscriptionManager: p.events.SubscriptionManager(T, m),
 	}
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
+var releaseFlagMapping = postgresql.Mapper{
+	Table:   "release_flags",
+	ID:      "id",
+	NewIDFn: newIDFn,
+	Columns: []string{"id", "name", "revision"},
+	ToArgsFn: func(ptr interface{}) ([]interface{}, error) {
+		e := ptr.(*release.Flag)
+		return []interface{}{e.ID, e.Name, e.Revision}, nil
+	},
+	MapFn: func(s iterators.SQLRowScanner, ptr interface{}) error {
+		e := ptr.(*release.Flag)
+		return s.Scan(&e.ID, &e.Name, &e.Revision)
+	},
+}
+
 func (p *Postgres) ReleaseFlag(ctx context.Context) release.FlagStorage {
 	return p.storage.ReleaseFlag.Do(func() interface{} {
 		return ReleaseFlagPgStorage{
-			Storage: p.mkPostgresqlStorage(release.Flag{},
-				postgresql.Mapper{
-					Table:   "release_flags",
-					ID:      "id",
-					NewIDFn: newIDFn,
-					Columns: []string{"id", "name"},
-					ToArgsFn: func(ptr interface{}) ([]interface{}, error) {
-						e := ptr.(*release.Flag)
-						return []interface{}{e.ID, e.Name}, nil
-					},
-					MapFn: func(s iterators.SQLRowScanner, ptr interface{}) error {
-						e := ptr.(*release.Flag)
-						return s.Scan(&e.ID, &e.Name)
-					},
-				}),
+			Storage:  p.mkPostgresqlStorage(release.Flag{}, releaseFlagMapping),
+			releases: p,
 		}
 	}).(ReleaseFlagPgStorage)
 }
 
 type ReleaseFlagPgStorage struct {
 	*postgresql.Storage
+	releases release.Storage
+}
+
+func (s ReleaseFlagPgStorage) Create(ctx context.Context, ptr interface{}) error {
+	return uniqueViolation(createRevision(ctx, s.Storage, ptr), release.ErrFlagAlreadyExist)
+}
+
+func (s ReleaseFlagPgStorage) Update(ctx context.Context, ptr interface{}) error {
+	return uniqueViolation(updatePgRevision(ctx, s.Storage, ptr), release.ErrFlagAlreadyExist)
+}
+
+func (s ReleaseFlagPgStorage) DeleteByID(ctx context.Context, id interface{}) error {
+	return deleteByIDRestrict(ctx, s.releases, s.Storage, flagReferences, release.ErrFlagInUse, id)
+}
+
+func (s ReleaseFlagPgStorage) DeleteAll(ctx context.Context) error {
+	return deleteAllRestrict(ctx, s.releases, s.Storage, release.Flag{}, flagReferences, release.ErrFlagInUse)
 }
 
 func (s ReleaseFlagPgStorage) FindByName(ctx context.Context, name string) (*release.Flag, error) {
@@ -182,43 +193,61 @@ func (s ReleaseFlagPgStorage) FindByNames(ctx context.Context, names ...string)
 	return iterators.NewSQLRows(flags, m)
 }
 
+func (s ReleaseFlagPgStorage) FindByQuery(ctx context.Context, q release.FlagQuery) release.FlagEntries {
+	return s.findByQuery(ctx, q, newPgQueryBuilder())
+}
+
+func (s ReleaseFlagPgStorage) findByQuery(ctx context.Context, q release.FlagQuery, qb *sqlQueryBuilder) release.FlagEntries {
+	if q.NamePrefix != `` {
+		qb.WherePrefix(`"name"`, q.NamePrefix)
+	}
+	return qb.FindByQuery(ctx, s.Storage, q.Pagination, map[string]string{
+		release.SortByName: `"name"`,
+	})
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
+var releasePilotMapping = postgresql.Mapper{
+	Table:   "release_pilots",
+	ID:      "id",
+	NewIDFn: newIDFn,
+	Columns: []string{
+		`id`,
+		`flag_id`,
+		`env_id`,
+		`public_id`,
+		`is_participating`,
+		`revision`,
+	},
+	ToArgsFn: func(ptr interface{}) ([]interface{}, error) {
+		e := ptr.(*release.Pilot)
+		return []interface{}{
+			e.ID,
+			e.FlagID,
+			e.EnvironmentID,
+			e.PublicID,
+			e.IsParticipating,
+			e.Revision,
+		}, nil
+	},
+	MapFn: func(s iterators.SQLRowScanner, ptr interface{}) error {
+		e := ptr.(*release.Pilot)
+		return s.Scan(
+			&e.ID,
+			&e.FlagID,
+			&e.EnvironmentID,
+			&e.PublicID,
+			&e.IsParticipating,
+			&e.Revision,
+		)
+	},
+}
+
 func (p *Postgres) ReleasePilot(ctx context.Context) release.PilotStorage {
 	return p.storage.ReleasePilot.Do(func() interface{} {
 		return ReleasePilotPgStorage{
-			Storage: p.mkPostgresqlStorage(release.Pilot{}, postgresql.Mapper{
-				Table:   "release_pilots",
-				ID:      "id",
-				NewIDFn: newIDFn,
-				Columns: []string{
-					`id`,
-					`flag_id`,
-					`env_id`,
-					`public_id`,
-					`is_participating`,
-				},
-				ToArgsFn: func(ptr interface{}) ([]interface{}, error) {
-					e := ptr.(*release.Pilot)
-					return []interface{}{
-						e.ID,
-						e.FlagID,
-						e.EnvironmentID,
-						e.PublicID,
-						e.IsParticipating,
-					}, nil
-				},
-				MapFn: func(s iterators.SQLRowScanner, ptr interface{}) error {
-					e := ptr.(*release.Pilot)
-					return s.Scan(
-						&e.ID,
-						&e.FlagID,
-						&e.EnvironmentID,
-						&e.PublicID,
-						&e.IsParticipating,
-					)
-				},
-			}),
+			Storage: p.mkPostgresqlStorage(release.Pilot{}, releasePilotMapping),
 		}
 	}).(ReleasePilotPgStorage)
 }
@@ -227,6 +256,14 @@ type ReleasePilotPgStorage struct {
 	*postgresql.Storage
 }
 
+func (s ReleasePilotPgStorage) Create(ctx context.Context, ptr interface{}) error {
+	return uniqueViolation(createRevision(ctx, s.Storage, ptr), release.ErrPilotAlreadyExist)
+}
+
+func (s ReleasePilotPgStorage) Update(ctx context.Context, ptr interface{}) error {
+	return uniqueViolation(updatePgRevision(ctx, s.Storage, ptr), release.ErrPilotAlreadyExist)
+}
+
 func (s ReleasePilotPgStorage) FindByFlagEnvPublicID(ctx context.Context, flagID, envID interface{}, pilotExtID string) (*release.Pilot, error) {
 	if !isUUIDValid(flagID) {
 		return nil, nil
@@ -302,41 +339,73 @@ func (s ReleasePilotPgStorage) FindByPublicID(ctx context.Context, externalID st
 	return iterators.NewSQLRows(rows, m)
 }
 
+func (s ReleasePilotPgStorage) FindByQuery(ctx context.Context, q release.PilotQuery) release.PilotEntries {
+	return s.findByQuery(ctx, q, newPgQueryBuilder())
+}
+
+func (s ReleasePilotPgStorage) findByQuery(ctx context.Context, q release.PilotQuery, qb *sqlQueryBuilder) release.PilotEntries {
+	if q.FlagID != `` {
+		if !isUUIDValid(q.FlagID) {
+			return iterators.NewEmpty()
+		}
+		qb.Where(`"flag_id" = %s`, q.FlagID)
+	}
+	if q.EnvironmentID != `` {
+		if !isUUIDValid(q.EnvironmentID) {
+			return iterators.NewEmpty()
+		}
+		qb.Where(`"env_id" = %s`, q.EnvironmentID)
+	}
+	if q.PublicID != `` {
+		qb.Where(`"public_id" = %s`, q.PublicID)
+	}
+	if q.IsParticipating != nil {
+		qb.Where(`"is_participating" = %s`, *q.IsParticipating)
+	}
+	return qb.FindByQuery(ctx, s.Storage, q.Pagination, map[string]string{
+		release.SortByPublicID: `"public_id"`,
+	})
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
+var releaseRolloutMapping = postgresql.Mapper{
+	Table:   "release_rollouts",
+	ID:      "id",
+	NewIDFn: newIDFn,
+	Columns: []string{`id`, `flag_id`, `env_id`, `plan`, `revision`},
+	ToArgsFn: func(ptr interface{}) ([]interface{}, error) {
+		e := ptr.(*release.Rollout)
+		return []interface{}{
+			e.ID,
+			e.FlagID,
+			e.EnvironmentID,
+			releaseRolloutPlanValue{RolloutPlan: e.Plan},
+			e.Revision,
+		}, nil
+	},
+	MapFn: func(s iterators.SQLRowScanner, ptr interface{}) error {
+		var rollout release.Rollout
+		var rolloutPlanValue releaseRolloutPlanValue
+		if err := s.Scan(
+			&rollout.ID,
+			&rollout.FlagID,
+			&rollout.EnvironmentID,
+			&rolloutPlanValue,
+			&rollout.Revision,
+		); err != nil {
+			return err
+		}
+
+		rollout.Plan = rolloutPlanValue.RolloutPlan
+		return reflects.Link(rollout, ptr)
+	},
+}
+
 func (p *Postgres) ReleaseRollout(ctx context.Context) release.RolloutStorage {
 	return p.storage.ReleaseRollout.Do(func() interface{} {
 		return ReleaseRolloutPgStorage{
-			Storage: p.mkPostgresqlStorage(release.Rollout{}, postgresql.Mapper{
-				Table:   "release_rollouts",
-				ID:      "id",
-				NewIDFn: newIDFn,
-				Columns: []string{`id`, `flag_id`, `env_id`, `plan`},
-				ToArgsFn: func(ptr interface{}) ([]interface{}, error) {
-					e := ptr.(*release.Rollout)
-					return []interface{}{
-						e.ID,
-						e.FlagID,
-						e.EnvironmentID,
-						releaseRolloutPlanValue{RolloutPlan: e.Plan},
-					}, nil
-				},
-				MapFn: func(s iterators.SQLRowScanner, ptr interface{}) error {
-					var rollout release.Rollout
-					var rolloutPlanValue releaseRolloutPlanValue
-					if err := s.Scan(
-						&rollout.ID,
-						&rollout.FlagID,
-						&rollout.EnvironmentID,
-						&rolloutPlanValue,
-					); err != nil {
-						return err
-					}
-
-					rollout.Plan = rolloutPlanValue.RolloutPlan
-					return reflects.Link(rollout, ptr)
-				},
-			}),
+			Storage: p.mkPostgresqlStorage(release.Rollout{}, releaseRolloutMapping),
 		}
 	}).(ReleaseRolloutPgStorage)
 }
@@ -345,6 +414,14 @@ type ReleaseRolloutPgStorage struct {
 	*postgresql.Storage
 }
 
+func (s ReleaseRolloutPgStorage) Create(ctx context.Context, ptr interface{}) error {
+	return uniqueViolation(createRevision(ctx, s.Storage, ptr), release.ErrRolloutAlreadyExist)
+}
+
+func (s ReleaseRolloutPgStorage) Update(ctx context.Context, ptr interface{}) error {
+	return uniqueViolation(updatePgRevision(ctx, s.Storage, ptr), release.ErrRolloutAlreadyExist)
+}
+
 type releaseRolloutPlanValue struct {
 	release.RolloutPlan
 }
@@ -390,31 +467,68 @@ func (s ReleaseRolloutPgStorage) FindByFlagEnvironment(ctx context.Context, flag
 	return true, nil
 }
 
+func (s ReleaseRolloutPgStorage) FindByQuery(ctx context.Context, q release.RolloutQuery) release.RolloutEntries {
+	qb := newPgQueryBuilder()
+	if q.FlagID != `` {
+		if !isUUIDValid(q.FlagID) {
+			return iterators.NewEmpty()
+		}
+		qb.Where(`"flag_id" = %s`, q.FlagID)
+	}
+	if q.EnvironmentID != `` {
+		if !isUUIDValid(q.EnvironmentID) {
+			return iterators.NewEmpty()
+		}
+		qb.Where(`"env_id" = %s`, q.EnvironmentID)
+	}
+	return qb.FindByQuery(ctx, s.Storage, q.Pagination, nil)
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
+var releaseEnvironmentMapping = postgresql.Mapper{
+	Table:   "release_environments",
+	ID:      "id",
+	NewIDFn: newIDFn,
+	Columns: []string{`id`, `name`, `required_approvals`, `revision`},
+	ToArgsFn: func(ptr interface{}) ([]interface{}, error) {
+		e := ptr.(*release.Environment)
+		return []interface{}{e.ID, e.Name, e.RequiredApprovals, e.Revision}, nil
+	},
+	MapFn: func(s iterators.SQLRowScanner, ptr interface{}) error {
+		e := ptr.(*release.Environment)
+		return s.Scan(&e.ID, &e.Name, &e.RequiredApprovals, &e.Revision)
+	},
+}
+
 func (p *Postgres) ReleaseEnvironment(ctx context.Context) release.EnvironmentStorage {
 	return p.storage.ReleaseEnvironment.Do(func() interface{} {
 		return ReleaseEnvironmentPgStorage{
-			Storage: p.mkPostgresqlStorage(release.Environment{}, postgresql.Mapper{
-				Table:   "release_environments",
-				ID:      "id",
-				NewIDFn: newIDFn,
-				Columns: []string{`id`, `name`},
-				ToArgsFn: func(ptr interface{}) ([]interface{}, error) {
-					e := ptr.(*release.Environment)
-					return []interface{}{e.ID, e.Name}, nil
-				},
-				MapFn: func(s iterators.SQLRowScanner, ptr interface{}) error {
-					e := ptr.(*release.Environment)
-					return s.Scan(&e.ID, &e.Name)
-				},
-			}),
+			Storage:  p.mkPostgresqlStorage(release.Environment{}, releaseEnvironmentMapping),
+			releases: p,
 		}
 	}).(ReleaseEnvironmentPgStorage)
 }
 
 type ReleaseEnvironmentPgStorage struct {
 	*postgresql.Storage
+	releases release.Storage
+}
+
+func (s ReleaseEnvironmentPgStorage) Create(ctx context.Context, ptr interface{}) error {
+	return uniqueViolation(createRevision(ctx, s.Storage, ptr), release.ErrEnvironmentAlreadyExist)
+}
+
+func (s ReleaseEnvironmentPgStorage) Update(ctx context.Context, ptr interface{}) error {
+	return uniqueViolation(updatePgRevision(ctx, s.Storage, ptr), release.ErrEnvironmentAlreadyExist)
+}
+
+func (s ReleaseEnvironmentPgStorage) DeleteByID(ctx context.Context, id interface{}) error {
+	return deleteByIDRestrict(ctx, s.releases, s.Storage, environmentReferences, release.ErrEnvironmentInUse, id)
+}
+
+func (s ReleaseEnvironmentPgStorage) DeleteAll(ctx context.Context) error {
+	return deleteAllRestrict(ctx, s.releases, s.Storage, release.Environment{}, environmentReferences, release.ErrEnvironmentInUse)
 }
 
 func (s ReleaseEnvironmentPgStorage) FindByAlias(ctx context.Context, idOrName string, env *release.Environment) (bool, error) {
@@ -442,41 +556,722 @@ func (s ReleaseEnvironmentPgStorage) FindByAlias(ctx context.Context, idOrName s
 	return err == nil, err
 }
 
+func (s ReleaseEnvironmentPgStorage) FindByQuery(ctx context.Context, q release.EnvironmentQuery) release.EnvironmentEntries {
+	return s.findByQuery(ctx, q, newPgQueryBuilder())
+}
+
+func (s ReleaseEnvironmentPgStorage) findByQuery(ctx context.Context, q release.EnvironmentQuery, qb *sqlQueryBuilder) release.EnvironmentEntries {
+	if q.NamePrefix != `` {
+		qb.WherePrefix(`"name"`, q.NamePrefix)
+	}
+	return qb.FindByQuery(ctx, s.Storage, q.Pagination, map[string]string{
+		release.SortByName: `"name"`,
+	})
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+var releaseVersionMapping = postgresql.Mapper{
+	Table:   "release_versions",
+	ID:      "id",
+	NewIDFn: newIDFn,
+	Columns: []string{`id`, `kind`, `entity_id`, `number`, `action`, `snapshot`, `created_at`},
+	ToArgsFn: func(ptr interface{}) ([]interface{}, error) {
+		e := ptr.(*release.Version)
+		return []interface{}{
+			e.ID,
+			e.Kind,
+			e.EntityID,
+			e.Number,
+			e.Action,
+			[]byte(e.Snapshot),
+			e.CreatedAt.UTC(),
+		}, nil
+	},
+	MapFn: func(s iterators.SQLRowScanner, ptr interface{}) error {
+		var (
+			version  release.Version
+			snapshot []byte
+		)
+		if err := s.Scan(
+			&version.ID,
+			&version.Kind,
+			&version.EntityID,
+			&version.Number,
+			&version.Action,
+			&snapshot,
+			&version.CreatedAt,
+		); err != nil {
+			return err
+		}
+		version.Snapshot = snapshot
+		version.CreatedAt = version.CreatedAt.UTC()
+		return reflects.Link(version, ptr)
+	},
+}
+
+func (p *Postgres) ReleaseVersion(ctx context.Context) release.VersionStorage {
+	return p.storage.ReleaseVersion.Do(func() interface{} {
+		return ReleaseVersionPgStorage{
+			Storage: p.mkPostgresqlStorage(release.Version{}, releaseVersionMapping),
+		}
+	}).(ReleaseVersionPgStorage)
+}
+
+type ReleaseVersionPgStorage struct {
+	*postgresql.Storage
+}
+
+func (s ReleaseVersionPgStorage) FindByQuery(ctx context.Context, q release.VersionQuery) release.VersionEntries {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	if q.Kind != `` {
+		args = append(args, q.Kind)
+		conditions = append(conditions, fmt.Sprintf(`"kind" = $%d`, len(args)))
+	}
+	if q.EntityID != `` {
+		args = append(args, q.EntityID)
+		conditions = append(conditions, fmt.Sprintf(`"entity_id" = $%d`, len(args)))
+	}
+
+	m := s.Mapping
+	query := fmt.Sprintf(`SELECT %s FROM %s`, toSelectClause(m), m.TableRef())
+	if 0 < len(conditions) {
+		query += ` WHERE ` + strings.Join(conditions, ` AND `)
+	}
+	query += ` ORDER BY "entity_id", "number"`
+
+	c, err := s.ConnectionManager.Connection(ctx)
+	if err != nil {
+		return iterators.NewError(err)
+	}
+
+	rows, err := c.QueryContext(ctx, query, args...)
+	if err != nil {
+		return iterators.NewError(err)
+	}
+	return iterators.NewSQLRows(rows, m)
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
+var webhookSubscriptionMapping = postgresql.Mapper{
+	Table:   "webhook_subscriptions",
+	ID:      "id",
+	NewIDFn: newIDFn,
+	Columns: []string{`id`, `url`, `secret`, `event_types`, `environment_ids`},
+	ToArgsFn: func(ptr interface{}) ([]interface{}, error) {
+		e := ptr.(*webhook.Subscription)
+		eventTypes, err := json.Marshal(e.EventTypes)
+		if err != nil {
+			return nil, err
+		}
+		environmentIDs, err := json.Marshal(e.EnvironmentIDs)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{
+			e.ID,
+			e.URL,
+			e.Secret,
+			eventTypes,
+			environmentIDs,
+		}, nil
+	},
+	MapFn: func(s iterators.SQLRowScanner, ptr interface{}) error {
+		var (
+			subscription   webhook.Subscription
+			eventTypes     []byte
+			environmentIDs []byte
+		)
+		if err := s.Scan(
+			&subscription.ID,
+			&subscription.URL,
+			&subscription.Secret,
+			&eventTypes,
+			&environmentIDs,
+		); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(eventTypes, &subscription.EventTypes); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(environmentIDs, &subscription.EnvironmentIDs); err != nil {
+			return err
+		}
+		return reflects.Link(subscription, ptr)
+	},
+}
+
+func (p *Postgres) WebhookSubscription(ctx context.Context) webhook.SubscriptionStorage {
+	return p.storage.WebhookSubscription.Do(func() interface{} {
+		return WebhookSubscriptionPgStorage{
+			Storage: p.mkPostgresqlStorage(webhook.Subscription{}, webhookSubscriptionMapping),
+		}
+	}).(WebhookSubscriptionPgStorage)
+}
+
+type WebhookSubscriptionPgStorage struct {
+	*postgresql.Storage
+}
+
+var webhookDeliveryMapping = postgresql.Mapper{
+	Table:   "webhook_deliveries",
+	ID:      "id",
+	NewIDFn: newIDFn,
+	Columns: []string{`id`, `subscription_id`, `event_id`, `event_type`, `payload`, `status`, `attempts`, `response_status`, `error`, `created_at`, `updated_at`},
+	ToArgsFn: func(ptr interface{}) ([]interface{}, error) {
+		e := ptr.(*webhook.Delivery)
+		return []interface{}{
+			e.ID,
+			e.SubscriptionID,
+			e.EventID,
+			e.EventType,
+			[]byte(e.Payload),
+			e.Status,
+			e.Attempts,
+			e.ResponseStatus,
+			e.Error,
+			e.CreatedAt.UTC(),
+			e.UpdatedAt.UTC(),
+		}, nil
+	},
+	MapFn: func(s iterators.SQLRowScanner, ptr interface{}) error {
+		var (
+			delivery webhook.Delivery
+			payload  []byte
+		)
+		if err := s.Scan(
+			&delivery.ID,
+			&delivery.SubscriptionID,
+			&delivery.EventID,
+			&delivery.EventType,
+			&payload,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.ResponseStatus,
+			&delivery.Error,
+			&delivery.CreatedAt,
+			&delivery.UpdatedAt,
+		); err != nil {
+			return err
+		}
+		delivery.Payload = payload
+		delivery.CreatedAt = delivery.CreatedAt.UTC()
+		delivery.UpdatedAt = delivery.UpdatedAt.UTC()
+		return reflects.Link(delivery, ptr)
+	},
+}
+
+func (p *Postgres) WebhookDelivery(ctx context.Context) webhook.DeliveryStorage {
+	return p.storage.WebhookDelivery.Do(func() interface{} {
+		return WebhookDeliveryPgStorage{
+			Storage: p.mkPostgresqlStorage(webhook.Delivery{}, webhookDeliveryMapping),
+		}
+	}).(WebhookDeliveryPgStorage)
+}
+
+type WebhookDeliveryPgStorage struct {
+	*postgresql.Storage
+}
+
+func (s WebhookDeliveryPgStorage) Create(ctx context.Context, ptr interface{}) error {
+	return uniqueViolation(s.Storage.Create(ctx, ptr), webhook.ErrDeliveryAlreadyExist)
+}
+
+func (s WebhookDeliveryPgStorage) FindByQuery(ctx context.Context, q webhook.DeliveryQuery) webhook.DeliveryEntries {
+	var args []interface{}
+	m := s.Mapping
+	query := fmt.Sprintf(`SELECT %s FROM %s`, toSelectClause(m), m.TableRef())
+	if q.SubscriptionID != `` {
+		args = append(args, q.SubscriptionID)
+		query += ` WHERE "subscription_id" = $1`
+	}
+	query += ` ORDER BY "created_at" DESC, "id"`
+
+	c, err := s.ConnectionManager.Connection(ctx)
+	if err != nil {
+		return iterators.NewError(err)
+	}
+
+	rows, err := c.QueryContext(ctx, query, args...)
+	if err != nil {
+		return iterators.NewError(err)
+	}
+	return iterators.NewSQLRows(rows, m)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+var changeRequestMapping = postgresql.Mapper{
+	Table:   "change_requests",
+	ID:      "id",
+	NewIDFn: newIDFn,
+	Columns: []string{`id`, `env_id`, `kind`, `action`, `entity_id`, `base`, `proposal`, `status`, `proposed_by`, `resolved_by`, `reason`, `created_at`, `updated_at`},
+	ToArgsFn: func(ptr interface{}) ([]interface{}, error) {
+		e := ptr.(*change.Request)
+		return []interface{}{
+			e.ID,
+			e.EnvironmentID,
+			e.Kind,
+			e.Action,
+			e.EntityID,
+			nullableJSON(e.Base),
+			nullableJSON(e.Proposal),
+			e.Status,
+			e.ProposedBy,
+			e.ResolvedBy,
+			e.Reason,
+			e.CreatedAt.UTC(),
+			e.UpdatedAt.UTC(),
+		}, nil
+	},
+	MapFn: func(s iterators.SQLRowScanner, ptr interface{}) error {
+		var (
+			request  change.Request
+			base     []byte
+			proposal []byte
+		)
+		if err := s.Scan(
+			&request.ID,
+			&request.EnvironmentID,
+			&request.Kind,
+			&request.Action,
+			&request.EntityID,
+			&base,
+			&proposal,
+			&request.Status,
+			&request.ProposedBy,
+			&request.ResolvedBy,
+			&request.Reason,
+			&request.CreatedAt,
+			&request.UpdatedAt,
+		); err != nil {
+			return err
+		}
+		request.Base = base
+		request.Proposal = proposal
+		request.CreatedAt = request.CreatedAt.UTC()
+		request.UpdatedAt = request.UpdatedAt.UTC()
+		return reflects.Link(request, ptr)
+	},
+}
+
+// nullableJSON stores the missing JSON document as NULL, as an empty value is not a valid JSON.
+func nullableJSON(doc json.RawMessage) interface{} {
+	if len(doc) == 0 {
+		return nil
+	}
+	return []byte(doc)
+}
+
+func (p *Postgres) ChangeRequest(ctx context.Context) change.RequestStorage {
+	return p.storage.ChangeRequest.Do(func() interface{} {
+		return ChangeRequestPgStorage{
+			Storage: p.mkPostgresqlStorage(change.Request{}, changeRequestMapping),
+		}
+	}).(ChangeRequestPgStorage)
+}
+
+type ChangeRequestPgStorage struct {
+	*postgresql.Storage
+}
+
+func (s ChangeRequestPgStorage) FindByQuery(ctx context.Context, q change.RequestQuery) change.RequestEntries {
+	var (
+		args  []interface{}
+		where []string
+	)
+	if q.EnvironmentID != `` {
+		args = append(args, q.EnvironmentID)
+		where = append(where, fmt.Sprintf(`"env_id" = $%d`, len(args)))
+	}
+	if q.Status != `` {
+		args = append(args, q.Status)
+		where = append(where, fmt.Sprintf(`"status" = $%d`, len(args)))
+	}
+
+	m := s.Mapping
+	query := fmt.Sprintf(`SELECT %s FROM %s`, toSelectClause(m), m.TableRef())
+	if 0 < len(where) {
+		query += ` WHERE ` + strings.Join(where, ` AND `)
+	}
+	query += ` ORDER BY "created_at" DESC, "id"`
+
+	c, err := s.ConnectionManager.Connection(ctx)
+	if err != nil {
+		return iterators.NewError(err)
+	}
+
+	rows, err := c.QueryContext(ctx, query, args...)
+	if err != nil {
+		return iterators.NewError(err)
+	}
+	return iterators.NewSQLRows(rows, m)
+}
+
+var changeApprovalMapping = postgresql.Mapper{
+	Table:   "change_approvals",
+	ID:      "id",
+	NewIDFn: newIDFn,
+	Columns: []string{`id`, `change_request_id`, `owner_uid`, `created_at`},
+	ToArgsFn: func(ptr interface{}) ([]interface{}, error) {
+		e := ptr.(*change.Approval)
+		return []interface{}{e.ID, e.RequestID, e.OwnerUID, e.CreatedAt.UTC()}, nil
+	},
+	MapFn: func(s iterators.SQLRowScanner, ptr interface{}) error {
+		var approval change.Approval
+		if err := s.Scan(&approval.ID, &approval.RequestID, &approval.OwnerUID, &approval.CreatedAt); err != nil {
+			return err
+		}
+		approval.CreatedAt = approval.CreatedAt.UTC()
+		return reflects.Link(approval, ptr)
+	},
+}
+
+func (p *Postgres) ChangeApproval(ctx context.Context) change.ApprovalStorage {
+	return p.storage.ChangeApproval.Do(func() interface{} {
+		return ChangeApprovalPgStorage{
+			Storage: p.mkPostgresqlStorage(change.Approval{}, changeApprovalMapping),
+		}
+	}).(ChangeApprovalPgStorage)
+}
+
+type ChangeApprovalPgStorage struct {
+	*postgresql.Storage
+}
+
+func (s ChangeApprovalPgStorage) Create(ctx context.Context, ptr interface{}) error {
+	return uniqueViolation(s.Storage.Create(ctx, ptr), change.ErrAlreadyApproved)
+}
+
+func (s ChangeApprovalPgStorage) FindByQuery(ctx context.Context, q change.ApprovalQuery) change.ApprovalEntries {
+	var args []interface{}
+	m := s.Mapping
+	query := fmt.Sprintf(`SELECT %s FROM %s`, toSelectClause(m), m.TableRef())
+	if q.RequestID != `` {
+		args = append(args, q.RequestID)
+		query += ` WHERE "change_request_id" = $1`
+	}
+	query += ` ORDER BY "created_at", "id"`
+
+	c, err := s.ConnectionManager.Connection(ctx)
+	if err != nil {
+		return iterators.NewError(err)
+	}
+
+	rows, err := c.QueryContext(ctx, query, args...)
+	if err != nil {
+		return iterators.NewError(err)
+	}
+	return iterators.NewSQLRows(rows, m)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+var scheduledChangeMapping = postgresql.Mapper{
+	Table:   "scheduled_changes",
+	ID:      "id",
+	NewIDFn: newIDFn,
+	Columns: []string{`id`, `env_id`, `kind`, `action`, `entity_id`, `proposal`, `run_at`, `status`, `scheduled_by`, `cancelled_by`, `error`, `change_request_id`, `created_at`, `updated_at`},
+	ToArgsFn: func(ptr interface{}) ([]interface{}, error) {
+		e := ptr.(*schedule.Change)
+		return []interface{}{
+			e.ID,
+			e.EnvironmentID,
+			e.Kind,
+			e.Action,
+			e.EntityID,
+			nullableJSON(e.Proposal),
+			e.RunAt.UTC(),
+			e.Status,
+			e.ScheduledBy,
+			e.CancelledBy,
+			e.Error,
+			e.ChangeRequestID,
+			e.CreatedAt.UTC(),
+			e.UpdatedAt.UTC(),
+		}, nil
+	},
+	MapFn: func(s iterators.SQLRowScanner, ptr interface{}) error {
+		var (
+			c        schedule.Change
+			proposal []byte
+		)
+		if err := s.Scan(
+			&c.ID,
+			&c.EnvironmentID,
+			&c.Kind,
+			&c.Action,
+			&c.EntityID,
+			&proposal,
+			&c.RunAt,
+			&c.Status,
+			&c.ScheduledBy,
+			&c.CancelledBy,
+			&c.Error,
+			&c.ChangeRequestID,
+			&c.CreatedAt,
+			&c.UpdatedAt,
+		); err != nil {
+			return err
+		}
+		c.Proposal = proposal
+		c.RunAt = c.RunAt.UTC()
+		c.CreatedAt = c.CreatedAt.UTC()
+		c.UpdatedAt = c.UpdatedAt.UTC()
+		return reflects.Link(c, ptr)
+	},
+}
+
+func (p *Postgres) ScheduledChange(ctx context.Context) schedule.ChangeStorage {
+	return p.storage.ScheduledChange.Do(func() interface{} {
+		return ScheduledChangePgStorage{
+			Storage: p.mkPostgresqlStorage(schedule.Change{}, scheduledChangeMapping),
+		}
+	}).(ScheduledChangePgStorage)
+}
+
+type ScheduledChangePgStorage struct {
+	*postgresql.Storage
+}
+
+func (s ScheduledChangePgStorage) FindByQuery(ctx context.Context, q schedule.ChangeQuery) schedule.ChangeEntries {
+	var (
+		args  []interface{}
+		where []string
+	)
+	if q.EnvironmentID != `` {
+		args = append(args, q.EnvironmentID)
+		where = append(where, fmt.Sprintf(`"env_id" = $%d`, len(args)))
+	}
+	if q.Status != `` {
+		args = append(args, q.Status)
+		where = append(where, fmt.Sprintf(`"status" = $%d`, len(args)))
+	}
+
+	m := s.Mapping
+	query := fmt.Sprintf(`SELECT %s FROM %s`, toSelectClause(m), m.TableRef())
+	if 0 < len(where) {
+		query += ` WHERE ` + strings.Join(where, ` AND `)
+	}
+	query += ` ORDER BY "run_at", "id"`
+
+	c, err := s.ConnectionManager.Connection(ctx)
+	if err != nil {
+		return iterators.NewError(err)
+	}
+
+	rows, err := c.QueryContext(ctx, query, args...)
+	if err != nil {
+		return iterators.NewError(err)
+	}
+	// the run time is matched after the scan,
+	// because the sqlite storage keeps the timestamps as text, which can't be compared reliably.
+	return iterators.Filter(iterators.NewSQLRows(rows, m), q.Match)
+}
+
+// TryLock acquires a transaction level advisory lock in a transaction of its own,
+// so the lock is shared by every toggler instance connected to the database,
+// and it is released even when the instance disconnects while holding it.
+func (p *Postgres) TryLock(ctx context.Context, name string) (func() error, bool, error) {
+	ctx, err := p.ConnectionManager.BeginTx(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	c, err := p.ConnectionManager.Connection(ctx)
+	if err != nil {
+		_ = p.ConnectionManager.RollbackTx(ctx)
+		return nil, false, err
+	}
+
+	var ok bool
+	if err := c.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock(hashtext($1))`, name).Scan(&ok); err != nil || !ok {
+		_ = p.ConnectionManager.RollbackTx(ctx)
+		return nil, false, err
+	}
+	return func() error { return p.ConnectionManager.RollbackTx(ctx) }, true, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+var guardrailMapping = postgresql.Mapper{
+	Table:   "guardrails",
+	ID:      "id",
+	NewIDFn: newIDFn,
+	Columns: []string{`id`, `rollout_id`, `max_error_rate_increase`, `window_seconds`, `min_requests`, `action`, `status`, `armed_at`, `tripped_at`, `reason`},
+	ToArgsFn: func(ptr interface{}) ([]interface{}, error) {
+		e := ptr.(*guardrail.Guardrail)
+		return []interface{}{
+			e.ID,
+			e.RolloutID,
+			e.MaxErrorRateIncrease,
+			e.WindowSeconds,
+			e.MinRequests,
+			e.Action,
+			e.Status,
+			e.ArmedAt.UTC(),
+			e.TrippedAt.UTC(),
+			e.Reason,
+		}, nil
+	},
+	MapFn: func(s iterators.SQLRowScanner, ptr interface{}) error {
+		var g guardrail.Guardrail
+		if err := s.Scan(
+			&g.ID,
+			&g.RolloutID,
+			&g.MaxErrorRateIncrease,
+			&g.WindowSeconds,
+			&g.MinRequests,
+			&g.Action,
+			&g.Status,
+			&g.ArmedAt,
+			&g.TrippedAt,
+			&g.Reason,
+		); err != nil {
+			return err
+		}
+		g.ArmedAt = g.ArmedAt.UTC()
+		g.TrippedAt = g.TrippedAt.UTC()
+		return reflects.Link(g, ptr)
+	},
+}
+
+func (p *Postgres) Guardrail(ctx context.Context) guardrail.GuardrailStorage {
+	return p.storage.Guardrail.Do(func() interface{} {
+		return GuardrailPgStorage{
+			Storage: p.mkPostgresqlStorage(guardrail.Guardrail{}, guardrailMapping),
+		}
+	}).(GuardrailPgStorage)
+}
+
+type GuardrailPgStorage struct {
+	*postgresql.Storage
+}
+
+var guardrailSignalMapping = postgresql.Mapper{
+	Table:   "guardrail_signals",
+	ID:      "id",
+	NewIDFn: newIDFn,
+	Columns: []string{`id`, `flag_id`, `env_id`, `variant`, `successes`, `errors`, `reported_at`},
+	ToArgsFn: func(ptr interface{}) ([]interface{}, error) {
+		e := ptr.(*guardrail.Signal)
+		return []interface{}{
+			e.ID,
+			e.FlagID,
+			e.EnvironmentID,
+			e.Variant,
+			e.Successes,
+			e.Errors,
+			e.ReportedAt.UTC(),
+		}, nil
+	},
+	MapFn: func(s iterators.SQLRowScanner, ptr interface{}) error {
+		var signal guardrail.Signal
+		if err := s.Scan(
+			&signal.ID,
+			&signal.FlagID,
+			&signal.EnvironmentID,
+			&signal.Variant,
+			&signal.Successes,
+			&signal.Errors,
+			&signal.ReportedAt,
+		); err != nil {
+			return err
+		}
+		signal.ReportedAt = signal.ReportedAt.UTC()
+		return reflects.Link(signal, ptr)
+	},
+}
+
+func (p *Postgres) GuardrailSignal(ctx context.Context) guardrail.SignalStorage {
+	return p.storage.GuardrailSignal.Do(func() interface{} {
+		return GuardrailSignalPgStorage{
+			Storage: p.mkPostgresqlStorage(guardrail.Signal{}, guardrailSignalMapping),
+		}
+	}).(GuardrailSignalPgStorage)
+}
+
+type GuardrailSignalPgStorage struct {
+	*postgresql.Storage
+}
+
+func (s GuardrailSignalPgStorage) FindByQuery(ctx context.Context, q guardrail.SignalQuery) guardrail.SignalEntries {
+	var (
+		args  []interface{}
+		where []string
+	)
+	if q.FlagID != `` {
+		args = append(args, q.FlagID)
+		where = append(where, fmt.Sprintf(`"flag_id" = $%d`, len(args)))
+	}
+	if q.EnvironmentID != `` {
+		args = append(args, q.EnvironmentID)
+		where = append(where, fmt.Sprintf(`"env_id" = $%d`, len(args)))
+	}
+
+	m := s.Mapping
+	query := fmt.Sprintf(`SELECT %s FROM %s`, toSelectClause(m), m.TableRef())
+	if 0 < len(where) {
+		query += ` WHERE ` + strings.Join(where, ` AND `)
+	}
+	query += ` ORDER BY "reported_at", "id"`
+
+	c, err := s.ConnectionManager.Connection(ctx)
+	if err != nil {
+		return iterators.NewError(err)
+	}
+
+	rows, err := c.QueryContext(ctx, query, args...)
+	if err != nil {
+		return iterators.NewError(err)
+	}
+	// the report time is matched after the scan, like the run time of the scheduled changes.
+	return iterators.Filter(iterators.NewSQLRows(rows, m), q.Match)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+var securityTokenMapping = postgresql.Mapper{
+	Table:   "tokens", // TODO: change it to security_tokens
+	ID:      "id",
+	NewIDFn: newIDFn,
+	Columns: []string{`id`, `sha512`, `duration`, `issued_at`, `owner_uid`, `env_id`},
+	ToArgsFn: func(ptr interface{}) ([]interface{}, error) {
+		e := ptr.(*security.Token)
+		return []interface{}{
+			e.ID,
+			e.SHA512,
+			e.Duration,
+			e.IssuedAt.UTC(),
+			e.OwnerUID,
+			e.EnvironmentID,
+		}, nil
+	},
+	MapFn: func(s iterators.SQLRowScanner, ptr interface{}) error {
+		var src security.Token
+		if err := s.Scan(
+			&src.ID,
+			&src.SHA512,
+			&src.Duration,
+			&src.IssuedAt,
+			&src.OwnerUID,
+			&src.EnvironmentID,
+		); err != nil {
+			return err
+		}
+		src.IssuedAt = src.IssuedAt.UTC()
+		return reflects.Link(src, ptr)
+	},
+}
+
 func (p *Postgres) SecurityToken(ctx context.Context) security.TokenStorage {
 	return p.storage.SecurityToken.Do(func() interface{} {
 		return SecurityTokenPgStorage{
-			Storage: p.mkPostgresqlStorage(security.Token{}, postgresql.Mapper{
-				Table:   "tokens", // TODO: change it to security_tokens
-				ID:      "id",
-				NewIDFn: newIDFn,
-				Columns: []string{`id`, `sha512`, `duration`, `issued_at`, `owner_uid`},
-				ToArgsFn: func(ptr interface{}) ([]interface{}, error) {
-					e := ptr.(*security.Token)
-					return []interface{}{
-						e.ID,
-						e.SHA512,
-						e.Duration,
-						e.IssuedAt.UTC(),
-						e.OwnerUID,
-					}, nil
-				},
-				MapFn: func(s iterators.SQLRowScanner, ptr interface{}) error {
-					var src security.Token
-					if err := s.Scan(
-						&src.ID,
-						&src.SHA512,
-						&src.Duration,
-						&src.IssuedAt,
-						&src.OwnerUID,
-					); err != nil {
-						return err
-					}
-					src.IssuedAt = src.IssuedAt.UTC()
-					return reflects.Link(src, ptr)
-				},
-			}),
+			Storage: p.mkPostgresqlStorage(security.Token{}, securityTokenMapping),
 		}
 	}).(SecurityTokenPgStorage)
 }
@@ -514,3 +1309,87 @@ func (s SecurityTokenPgStorage) FindTokenBySHA512Hex(ctx context.Context, sha512
 func toSelectClause(m postgresql.Mapping) string {
 	return strings.Join(m.ColumnRefs(), `,`)
 }
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// sqlQueryBuilder assembles the filtering, cursor and ordering parts of a list query.
+type sqlQueryBuilder struct {
+	conditions []string
+	args       []interface{}
+	// textCollation is applied on the text sort columns,
+	// so the database orders them byte-wise, the same way as release.Pagination compares the cursor keys.
+	textCollation string
+}
+
+func newPgQueryBuilder() *sqlQueryBuilder {
+	return &sqlQueryBuilder{textCollation: `COLLATE "C"`}
+}
+
+// Where adds a condition, where the %s verb in the format is replaced with the placeholder of the argument.
+func (qb *sqlQueryBuilder) Where(format string, arg interface{}) {
+	qb.args = append(qb.args, arg)
+	qb.conditions = append(qb.conditions, fmt.Sprintf(format, fmt.Sprintf(`$%d`, len(qb.args))))
+}
+
+// WherePrefix adds a condition that matches the text column values that start with the prefix.
+func (qb *sqlQueryBuilder) WherePrefix(column string, prefix string) {
+	qb.Where(`substr(`+column+`, 1, length(CAST(%s AS TEXT))) = CAST(%[1]s AS TEXT)`, prefix)
+}
+
+func (qb *sqlQueryBuilder) FindByQuery(ctx context.Context, s *postgresql.Storage, p release.Pagination, sortColumns map[string]string) iterators.Interface {
+	sortColumn := `"id"`
+	if column, ok := sortColumns[p.SortBy]; ok {
+		sortColumn = strings.TrimSpace(column + ` ` + qb.textCollation)
+	}
+
+	if p.Cursor != `` {
+		cursor, err := release.DecodeCursor(p.Cursor)
+		if err != nil {
+			return iterators.NewError(err)
+		}
+		if !isUUIDValid(cursor.ID) {
+			return iterators.NewError(release.ErrInvalidCursor)
+		}
+
+		operator := `>`
+		if p.Descending {
+			operator = `<`
+		}
+
+		if sortColumn == `"id"` {
+			qb.Where(`"id" `+operator+` %s`, cursor.ID)
+		} else {
+			qb.args = append(qb.args, cursor.Key, cursor.ID)
+			qb.conditions = append(qb.conditions, fmt.Sprintf(`(%s, "id") %s ($%d, $%d)`,
+				sortColumn, operator, len(qb.args)-1, len(qb.args)))
+		}
+	}
+
+	m := s.Mapping
+	query := fmt.Sprintf(`SELECT %s FROM %s`, toSelectClause(m), m.TableRef())
+	if 0 < len(qb.conditions) {
+		query += ` WHERE ` + strings.Join(qb.conditions, ` AND `)
+	}
+
+	direction := `ASC`
+	if p.Descending {
+		direction = `DESC`
+	}
+	query += fmt.Sprintf(` ORDER BY %s %s, "id" %[2]s`, sortColumn, direction)
+
+	if 0 < p.Limit {
+		query += fmt.Sprintf(` LIMIT %d`, p.Limit)
+	}
+
+	c, err := s.ConnectionManager.Connection(ctx)
+	if err != nil {
+		return iterators.NewError(err)
+	}
+
+	rows, err := c.QueryContext(ctx, query, qb.args...)
+	if err != nil {
+		return iterators.NewError(err)
+	}
+
+	return iterators.NewSQLRows(rows, m)
+}
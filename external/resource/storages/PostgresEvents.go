@@ -0,0 +1,244 @@
+package storages
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/extid"
+	"github.com/adamluzsi/frameless/postgresql"
+	"github.com/lib/pq"
+)
+
+// ErrEventsMissed is received by the subscribers when the connection to postgres was lost,
+// and the events published by the other processes in the meantime can't be delivered.
+// The caches drop their content when they receive it.
+const ErrEventsMissed frameless.Error = `postgres connection was lost, some storage events might be missed`
+
+// pgEvents publishes the storage events with NOTIFY, so every toggler process connected to the same database receives them.
+// A process use a single LISTEN connection for all entity types,
+// which is opened with the first subscription.
+type pgEvents struct {
+	DSN               string
+	ConnectionManager postgresql.ConnectionManager
+
+	ReconnectMinInterval time.Duration
+	ReconnectMaxInterval time.Duration
+	PingInterval         time.Duration
+
+	mutex    sync.Mutex
+	listener *pq.Listener
+	managers map[string]*pgSubscriptionManager
+	exit     struct {
+		signaler func()
+		wg       sync.WaitGroup
+	}
+}
+
+func newPgEvents(dsn string, cm postgresql.ConnectionManager) *pgEvents {
+	return &pgEvents{
+		DSN:                  dsn,
+		ConnectionManager:    cm,
+		ReconnectMinInterval: time.Second,
+		ReconnectMaxInterval: time.Minute,
+		PingInterval:         time.Minute,
+		managers:             make(map[string]*pgSubscriptionManager),
+	}
+}
+
+// SubscriptionManager returns the subscription manager of an entity type stored in the mapped table.
+func (e *pgEvents) SubscriptionManager(T frameless.T, m postgresql.Mapping) *pgSubscriptionManager {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	// the notifications are published by the frameless subscription manager,
+	// so the channel and the payload format stay compatible with it.
+	publisher := postgresql.NewListenNotifySubscriptionManager(T, m, e.DSN, e.ConnectionManager)
+	sm := &pgSubscriptionManager{
+		ListenNotifySubscriptionManager: publisher,
+		events:                          e,
+		channel:                         m.TableRef() + `=>cud_events`,
+		entityType:                      reflect.TypeOf(T),
+	}
+	if _, field, ok := extid.LookupStructField(T); ok {
+		sm.idType = field.Type()
+	}
+	e.managers[sm.channel] = sm
+	return sm
+}
+
+func (e *pgEvents) listen(channel string) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.listener == nil {
+		e.listener = pq.NewListener(e.DSN, e.ReconnectMinInterval, e.ReconnectMaxInterval, e.handleListenerEvent)
+		ctx, signaler := context.WithCancel(context.Background())
+		e.exit.signaler = signaler
+		e.exit.wg.Add(1)
+		go e.worker(ctx, e.listener)
+	}
+
+	if err := e.listener.Listen(channel); err != nil && err != pq.ErrChannelAlreadyOpen {
+		return err
+	}
+	return nil
+}
+
+func (e *pgEvents) worker(ctx context.Context, listener *pq.Listener) {
+	defer e.exit.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case n, ok := <-listener.Notify:
+			if !ok {
+				return
+			}
+			if n == nil {
+				// pq sends nil after the connection is re-established,
+				// since the notifications of the disconnected period are lost.
+				e.broadcastError(ctx, ErrEventsMissed)
+				continue
+			}
+			e.dispatch(ctx, n)
+
+		case <-time.After(e.PingInterval):
+			if err := listener.Ping(); err != nil {
+				e.broadcastError(ctx, err)
+			}
+		}
+	}
+}
+
+func (e *pgEvents) handleListenerEvent(event pq.ListenerEventType, err error) {
+	if err != nil {
+		e.broadcastError(context.Background(), err)
+	}
+}
+
+func (e *pgEvents) dispatch(ctx context.Context, n *pq.Notification) {
+	e.mutex.Lock()
+	sm, ok := e.managers[n.Channel]
+	e.mutex.Unlock()
+	if !ok {
+		return
+	}
+	if err := sm.handleNotification(ctx, []byte(n.Extra)); err != nil {
+		sm.handleError(ctx, err)
+	}
+}
+
+func (e *pgEvents) broadcastError(ctx context.Context, err error) {
+	e.mutex.Lock()
+	managers := make([]*pgSubscriptionManager, 0, len(e.managers))
+	for _, sm := range e.managers {
+		managers = append(managers, sm)
+	}
+	e.mutex.Unlock()
+	for _, sm := range managers {
+		sm.handleError(ctx, err)
+	}
+}
+
+func (e *pgEvents) Close() error {
+	e.mutex.Lock()
+	listener := e.listener
+	e.listener = nil
+	signaler := e.exit.signaler
+	e.exit.signaler = nil
+	e.mutex.Unlock()
+
+	if listener == nil {
+		return nil
+	}
+	signaler()
+	err := listener.Close()
+	e.exit.wg.Wait()
+	return err
+}
+
+// pgSubscriptionManager implements the postgresql.SubscriptionManager of a single entity type,
+// on top of the shared LISTEN connection of pgEvents.
+type pgSubscriptionManager struct {
+	*postgresql.ListenNotifySubscriptionManager
+	subscribers
+
+	events     *pgEvents
+	channel    string
+	entityType reflect.Type
+	idType     reflect.Type
+}
+
+// pgNotification is the payload of the NOTIFY made by postgresql.ListenNotifySubscriptionManager.
+type pgNotification struct {
+	Name string          `json:"name"`
+	Data json.RawMessage `json:"data"`
+}
+
+func (sm *pgSubscriptionManager) handleNotification(ctx context.Context, payload []byte) error {
+	var n pgNotification
+	if err := json.Unmarshal(payload, &n); err != nil {
+		return err
+	}
+
+	switch n.Name {
+	case `create`:
+		ptr := reflect.New(sm.entityType)
+		if err := json.Unmarshal(n.Data, ptr.Interface()); err != nil {
+			return err
+		}
+		sm.handleEvent(ctx, frameless.CreateEvent{Entity: ptr.Elem().Interface()})
+
+	case `update`:
+		ptr := reflect.New(sm.entityType)
+		if err := json.Unmarshal(n.Data, ptr.Interface()); err != nil {
+			return err
+		}
+		sm.handleEvent(ctx, frameless.UpdateEvent{Entity: ptr.Elem().Interface()})
+
+	case `delete_by_id`:
+		ptr := reflect.New(sm.idType)
+		if err := json.Unmarshal(n.Data, ptr.Interface()); err != nil {
+			return err
+		}
+		sm.handleEvent(ctx, frameless.DeleteByIDEvent{ID: ptr.Elem().Interface()})
+
+	case `delete_all`:
+		sm.handleEvent(ctx, frameless.DeleteAllEvent{})
+
+	default:
+		return fmt.Errorf(`unknown storage event: %s`, n.Name)
+	}
+	return nil
+}
+
+func (sm *pgSubscriptionManager) SubscribeToCreatorEvents(ctx context.Context, s frameless.CreatorSubscriber) (frameless.Subscription, error) {
+	if err := sm.events.listen(sm.channel); err != nil {
+		return nil, err
+	}
+	return sm.subscribers.SubscribeToCreatorEvents(ctx, s)
+}
+
+func (sm *pgSubscriptionManager) SubscribeToUpdaterEvents(ctx context.Context, s frameless.UpdaterSubscriber) (frameless.Subscription, error) {
+	if err := sm.events.listen(sm.channel); err != nil {
+		return nil, err
+	}
+	return sm.subscribers.SubscribeToUpdaterEvents(ctx, s)
+}
+
+func (sm *pgSubscriptionManager) SubscribeToDeleterEvents(ctx context.Context, s frameless.DeleterSubscriber) (frameless.Subscription, error) {
+	if err := sm.events.listen(sm.channel); err != nil {
+		return nil, err
+	}
+	return sm.subscribers.SubscribeToDeleterEvents(ctx, s)
+}
+
+// Close is a no-op, the shared LISTEN connection is closed together with the Postgres storage.
+func (sm *pgSubscriptionManager) Close() error {
+	return nil
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/doubles"
+	"github.com/adamluzsi/frameless/fixtures"
 	"github.com/adamluzsi/testcase"
 	"github.com/toggler-io/toggler/domains/release"
 	"github.com/toggler-io/toggler/domains/security"
@@ -102,3 +105,53 @@ func TestPostgres_Close(t *testing.T) {
 func TestPostgres_migration(t *testing.T) {
 	require.Nil(t, migrations.MigratePostgres(getDatabaseConnectionString(t)))
 }
+
+func TestPostgres_crossProcessEvents(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	s := testcase.NewSpec(t)
+
+	newPostgres := func(t *testcase.T) *storages.Postgres {
+		pg, err := storages.NewPostgres(getDatabaseConnectionString(t))
+		require.Nil(t, err)
+		t.Cleanup(func() { require.Nil(t, pg.Close()) })
+		return pg
+	}
+
+	s.Describe(`SubscribeToUpdaterEvents`, func(s *testcase.Spec) {
+		s.Then(`the subscriber receives the changes made by an other process`, func(t *testcase.T) {
+			var (
+				ctx       = context.Background()
+				publisher = newPostgres(t)
+				listener  = newPostgres(t)
+				flag      = &release.Flag{Name: fixtures.Random.String()}
+				events    = make(chan release.Flag, 1)
+			)
+
+			sub, err := listener.ReleaseFlag(ctx).SubscribeToUpdaterEvents(ctx, doubles.StubSubscriber{
+				HandleFunc: func(ctx context.Context, event interface{}) error {
+					if event, ok := event.(frameless.UpdateEvent); ok {
+						events <- event.Entity.(release.Flag)
+					}
+					return nil
+				},
+			})
+			require.Nil(t, err)
+			t.Cleanup(func() { require.Nil(t, sub.Close()) })
+
+			require.Nil(t, publisher.ReleaseFlag(ctx).Create(ctx, flag))
+			t.Cleanup(func() { _ = publisher.ReleaseFlag(ctx).DeleteByID(ctx, flag.ID) })
+			flag.Name = fixtures.Random.String()
+			require.Nil(t, publisher.ReleaseFlag(ctx).Update(ctx, flag))
+
+			select {
+			case got := <-events:
+				require.Equal(t, *flag, got)
+			case <-time.After(5 * time.Second):
+				t.Fatal(`update event was not received`)
+			}
+		})
+	})
+}
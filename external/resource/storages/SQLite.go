@@ -0,0 +1,360 @@
+package storages
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/lazyloading"
+	"github.com/adamluzsi/frameless/postgresql"
+	"modernc.org/sqlite"
+
+	"github.com/toggler-io/toggler/domains/change"
+	"github.com/toggler-io/toggler/domains/guardrail"
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/schedule"
+	"github.com/toggler-io/toggler/domains/security"
+	"github.com/toggler-io/toggler/domains/webhook"
+	"github.com/toggler-io/toggler/external/resource/storages/migrations"
+)
+
+// NewSQLite opens the sqlite database file at the given path, and migrates it to the latest schema.
+// The database file is created when it doesn't exist yet.
+//
+// The storage events are delivered to the subscribers of the same process only,
+// so more than one toggler process should not share the same database file when caching is used.
+func NewSQLite(path string) (*SQLite, error) {
+	db := sql.OpenDB(sqliteConnector{path: path})
+	if err := migrations.MigrateSQLite(db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &SQLite{Path: path, DB: db}, nil
+}
+
+type SQLite struct {
+	Path string
+	DB   *sql.DB
+
+	storage struct {
+		ReleaseFlag         lazyloading.Var
+		ReleasePilot        lazyloading.Var
+		ReleaseRollout      lazyloading.Var
+		ReleaseEnvironment  lazyloading.Var
+		ReleaseVersion      lazyloading.Var
+		SecurityToken       lazyloading.Var
+		WebhookSubscription lazyloading.Var
+		WebhookDelivery     lazyloading.Var
+		ChangeRequest       lazyloading.Var
+		ChangeApproval      lazyloading.Var
+		ScheduledChange     lazyloading.Var
+		Guardrail           lazyloading.Var
+		GuardrailSignal     lazyloading.Var
+	}
+	locks processLocks
+}
+
+func (s *SQLite) Close() error {
+	return s.DB.Close()
+}
+
+// sqliteBusyTimeout is how long a write waits for an other connection's transaction before it fails.
+const sqliteBusyTimeout = 5 * time.Second
+
+// sqliteConnector opens the sqlite connections with the pragmas that allow concurrent use of the database file.
+type sqliteConnector struct {
+	path string
+}
+
+func (c sqliteConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Driver().Open(c.path)
+	if err != nil {
+		return nil, err
+	}
+	for _, pragma := range []string{
+		`PRAGMA journal_mode = WAL`,
+		fmt.Sprintf(`PRAGMA busy_timeout = %d`, sqliteBusyTimeout.Milliseconds()),
+	} {
+		if _, err := conn.(driver.Execer).Exec(pragma, nil); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (c sqliteConnector) Driver() driver.Driver {
+	return &sqlite.Driver{}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+type (
+	sqliteTxKey   struct{ db *sql.DB }
+	sqliteTxValue struct {
+		*sql.Conn
+		depth  int
+		events []func()
+	}
+)
+
+// Connection returns the connection of the transaction in the context, or the connection pool.
+// It makes the SQLite usable as the postgresql.ConnectionManager of the frameless postgresql.Storage,
+// which only use standard SQL.
+func (s *SQLite) Connection(ctx context.Context) (postgresql.Connection, error) {
+	if tx, ok := s.lookupTx(ctx); ok {
+		return tx, nil
+	}
+	return s.DB, nil
+}
+
+// BeginTx starts a deferred transaction, which takes the write lock of the database file only with its first write.
+// Until then, the other connections can write the database, the same way as they would with postgres.
+func (s *SQLite) BeginTx(ctx context.Context) (context.Context, error) {
+	if tx, ok := s.lookupTx(ctx); ok {
+		tx.depth++
+		return ctx, nil
+	}
+
+	conn, err := s.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, `BEGIN`); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return context.WithValue(ctx, sqliteTxKey{db: s.DB}, &sqliteTxValue{Conn: conn}), nil
+}
+
+func (s *SQLite) CommitTx(ctx context.Context) error {
+	tx, ok := s.lookupTx(ctx)
+	if !ok {
+		return errNoSQLiteTx
+	}
+	if 0 < tx.depth {
+		tx.depth--
+		return nil
+	}
+	if err := s.finishTx(tx, `COMMIT`); err != nil {
+		return err
+	}
+	// the events are published after the commit, so the subscribers never see uncommitted changes.
+	for _, publish := range tx.events {
+		publish()
+	}
+	return nil
+}
+
+func (s *SQLite) RollbackTx(ctx context.Context) error {
+	tx, ok := s.lookupTx(ctx)
+	if !ok {
+		return errNoSQLiteTx
+	}
+	return s.finishTx(tx, `ROLLBACK`)
+}
+
+// finishTx releases the connection of the transaction,
+// so the later use of the finished transaction's context yields sql.ErrConnDone.
+func (s *SQLite) finishTx(tx *sqliteTxValue, statement string) error {
+	defer tx.Conn.Close()
+	_, err := tx.Conn.ExecContext(context.Background(), statement)
+	return err
+}
+
+func (s *SQLite) lookupTx(ctx context.Context) (*sqliteTxValue, bool) {
+	tx, ok := ctx.Value(sqliteTxKey{db: s.DB}).(*sqliteTxValue)
+	return tx, ok && tx != nil
+}
+
+const errNoSQLiteTx frameless.Error = `no sqlite transaction found in the given context`
+
+func (s *SQLite) mkSQLiteStorage(T interface{}, m postgresql.Mapping) *postgresql.Storage {
+	return &postgresql.Storage{
+		T:                   T,
+		Mapping:             m,
+		ConnectionManager:   s,
+		SubscriptionManager: &sqliteSubscriptionManager{sqlite: s},
+	}
+}
+
+// sqliteSubscriptionManager delivers the storage events to the subscribers of the current process.
+type sqliteSubscriptionManager struct {
+	subscribers
+	sqlite *SQLite
+}
+
+func (sm *sqliteSubscriptionManager) publish(ctx context.Context, event interface{}) error {
+	if tx, ok := sm.sqlite.lookupTx(ctx); ok {
+		// the subscribers get the context without the finished transaction,
+		// so they can use the storage while they handle the event.
+		ctx := context.WithValue(ctx, sqliteTxKey{db: sm.sqlite.DB}, (*sqliteTxValue)(nil))
+		tx.events = append(tx.events, func() { sm.handleEvent(ctx, event) })
+		return nil
+	}
+	sm.handleEvent(ctx, event)
+	return nil
+}
+
+func (sm *sqliteSubscriptionManager) PublishCreateEvent(ctx context.Context, e frameless.CreateEvent) error {
+	return sm.publish(ctx, e)
+}
+
+func (sm *sqliteSubscriptionManager) PublishUpdateEvent(ctx context.Context, e frameless.UpdateEvent) error {
+	return sm.publish(ctx, e)
+}
+
+func (sm *sqliteSubscriptionManager) PublishDeleteByIDEvent(ctx context.Context, e frameless.DeleteByIDEvent) error {
+	return sm.publish(ctx, e)
+}
+
+func (sm *sqliteSubscriptionManager) PublishDeleteAllEvent(ctx context.Context, e frameless.DeleteAllEvent) error {
+	return sm.publish(ctx, e)
+}
+
+func (sm *sqliteSubscriptionManager) Close() error {
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// The sqlite entity storages use the same mapping and queries as the postgres ones,
+// except the list queries, which don't need collation for byte-wise ordering.
+
+func (s *SQLite) ReleaseFlag(ctx context.Context) release.FlagStorage {
+	return s.storage.ReleaseFlag.Do(func() interface{} {
+		return ReleaseFlagSQLiteStorage{ReleaseFlagPgStorage{
+			Storage:  s.mkSQLiteStorage(release.Flag{}, releaseFlagMapping),
+			releases: s,
+		}}
+	}).(ReleaseFlagSQLiteStorage)
+}
+
+type ReleaseFlagSQLiteStorage struct {
+	ReleaseFlagPgStorage
+}
+
+func (s ReleaseFlagSQLiteStorage) FindByQuery(ctx context.Context, q release.FlagQuery) release.FlagEntries {
+	return s.findByQuery(ctx, q, &sqlQueryBuilder{})
+}
+
+func (s *SQLite) ReleasePilot(ctx context.Context) release.PilotStorage {
+	return s.storage.ReleasePilot.Do(func() interface{} {
+		return ReleasePilotSQLiteStorage{ReleasePilotPgStorage{
+			Storage: s.mkSQLiteStorage(release.Pilot{}, releasePilotMapping),
+		}}
+	}).(ReleasePilotSQLiteStorage)
+}
+
+type ReleasePilotSQLiteStorage struct {
+	ReleasePilotPgStorage
+}
+
+func (s ReleasePilotSQLiteStorage) FindByQuery(ctx context.Context, q release.PilotQuery) release.PilotEntries {
+	return s.findByQuery(ctx, q, &sqlQueryBuilder{})
+}
+
+func (s *SQLite) ReleaseRollout(ctx context.Context) release.RolloutStorage {
+	return s.storage.ReleaseRollout.Do(func() interface{} {
+		return ReleaseRolloutPgStorage{
+			Storage: s.mkSQLiteStorage(release.Rollout{}, releaseRolloutMapping),
+		}
+	}).(ReleaseRolloutPgStorage)
+}
+
+func (s *SQLite) ReleaseEnvironment(ctx context.Context) release.EnvironmentStorage {
+	return s.storage.ReleaseEnvironment.Do(func() interface{} {
+		return ReleaseEnvironmentSQLiteStorage{ReleaseEnvironmentPgStorage{
+			Storage:  s.mkSQLiteStorage(release.Environment{}, releaseEnvironmentMapping),
+			releases: s,
+		}}
+	}).(ReleaseEnvironmentSQLiteStorage)
+}
+
+type ReleaseEnvironmentSQLiteStorage struct {
+	ReleaseEnvironmentPgStorage
+}
+
+func (s ReleaseEnvironmentSQLiteStorage) FindByQuery(ctx context.Context, q release.EnvironmentQuery) release.EnvironmentEntries {
+	return s.findByQuery(ctx, q, &sqlQueryBuilder{})
+}
+
+func (s *SQLite) ReleaseVersion(ctx context.Context) release.VersionStorage {
+	return s.storage.ReleaseVersion.Do(func() interface{} {
+		return ReleaseVersionPgStorage{
+			Storage: s.mkSQLiteStorage(release.Version{}, releaseVersionMapping),
+		}
+	}).(ReleaseVersionPgStorage)
+}
+
+func (s *SQLite) SecurityToken(ctx context.Context) security.TokenStorage {
+	return s.storage.SecurityToken.Do(func() interface{} {
+		return SecurityTokenPgStorage{
+			Storage: s.mkSQLiteStorage(security.Token{}, securityTokenMapping),
+		}
+	}).(SecurityTokenPgStorage)
+}
+
+func (s *SQLite) WebhookSubscription(ctx context.Context) webhook.SubscriptionStorage {
+	return s.storage.WebhookSubscription.Do(func() interface{} {
+		return WebhookSubscriptionPgStorage{
+			Storage: s.mkSQLiteStorage(webhook.Subscription{}, webhookSubscriptionMapping),
+		}
+	}).(WebhookSubscriptionPgStorage)
+}
+
+func (s *SQLite) WebhookDelivery(ctx context.Context) webhook.DeliveryStorage {
+	return s.storage.WebhookDelivery.Do(func() interface{} {
+		return WebhookDeliveryPgStorage{
+			Storage: s.mkSQLiteStorage(webhook.Delivery{}, webhookDeliveryMapping),
+		}
+	}).(WebhookDeliveryPgStorage)
+}
+
+func (s *SQLite) ChangeRequest(ctx context.Context) change.RequestStorage {
+	return s.storage.ChangeRequest.Do(func() interface{} {
+		return ChangeRequestPgStorage{
+			Storage: s.mkSQLiteStorage(change.Request{}, changeRequestMapping),
+		}
+	}).(ChangeRequestPgStorage)
+}
+
+func (s *SQLite) ChangeApproval(ctx context.Context) change.ApprovalStorage {
+	return s.storage.ChangeApproval.Do(func() interface{} {
+		return ChangeApprovalPgStorage{
+			Storage: s.mkSQLiteStorage(change.Approval{}, changeApprovalMapping),
+		}
+	}).(ChangeApprovalPgStorage)
+}
+
+func (s *SQLite) ScheduledChange(ctx context.Context) schedule.ChangeStorage {
+	return s.storage.ScheduledChange.Do(func() interface{} {
+		return ScheduledChangePgStorage{
+			Storage: s.mkSQLiteStorage(schedule.Change{}, scheduledChangeMapping),
+		}
+	}).(ScheduledChangePgStorage)
+}
+
+func (s *SQLite) Guardrail(ctx context.Context) guardrail.GuardrailStorage {
+	return s.storage.Guardrail.Do(func() interface{} {
+		return GuardrailPgStorage{
+			Storage: s.mkSQLiteStorage(guardrail.Guardrail{}, guardrailMapping),
+		}
+	}).(GuardrailPgStorage)
+}
+
+func (s *SQLite) GuardrailSignal(ctx context.Context) guardrail.SignalStorage {
+	return s.storage.GuardrailSignal.Do(func() interface{} {
+		return GuardrailSignalPgStorage{
+			Storage: s.mkSQLiteStorage(guardrail.Signal{}, guardrailSignalMapping),
+		}
+	}).(GuardrailSignalPgStorage)
+}
+
+// TryLock holds the lock within the process, as the database file is not shared by more toggler processes.
+func (s *SQLite) TryLock(ctx context.Context, name string) (func() error, bool, error) {
+	return s.locks.TryLock(ctx, name)
+}
@@ -0,0 +1,137 @@
+package storages_test
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/testcase"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/security"
+	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/domains/toggler/contracts"
+	"github.com/toggler-io/toggler/external/resource/storages"
+	"github.com/toggler-io/toggler/external/resource/storages/migrations"
+	sh "github.com/toggler-io/toggler/spechelper"
+)
+
+var (
+	_ toggler.Storage            = &storages.SQLite{}
+	_ release.Storage            = &storages.SQLite{}
+	_ security.Storage           = &storages.SQLite{}
+	_ release.EnvironmentStorage = storages.ReleaseEnvironmentSQLiteStorage{}
+	_ release.FlagStorage        = storages.ReleaseFlagSQLiteStorage{}
+	_ release.PilotStorage       = storages.ReleasePilotSQLiteStorage{}
+)
+
+func TestSQLite(t *testing.T)      { SpecSQLite(t) }
+func BenchmarkSQLite(b *testing.B) { SpecSQLite(b) }
+
+func SpecSQLite(tb testing.TB) {
+	// Every test has its own database file.
+	// A sqlite database has a single writer at a time,
+	// so the fixture teardowns, which run outside of the test's transaction, might fail to clean up after the test.
+	var dbs sync.Map
+	testcase.RunContract(sh.NewSpec(tb), contracts.Storage{
+		Subject: func(tb testing.TB) toggler.Storage {
+			if storage, ok := dbs.Load(tb); ok {
+				return storage.(*storages.SQLite)
+			}
+			storage, err := storages.NewSQLite(filepath.Join(tb.TempDir(), `toggler.db`))
+			require.Nil(tb, err)
+			tb.Cleanup(func() {
+				dbs.Delete(tb)
+				require.NoError(tb, storage.Close())
+			})
+			dbs.Store(tb, storage)
+			return storage
+		},
+		FixtureFactory: func(tb testing.TB) frameless.FixtureFactory {
+			return sh.NewFixtureFactory(tb)
+		},
+		Context: func(tb testing.TB) context.Context {
+			return context.Background()
+		},
+	})
+}
+
+func TestNew_sqlite(t *testing.T) {
+	s := testcase.NewSpec(t)
+
+	s.Describe(`New`, func(s *testcase.Spec) {
+		s.When(`the connection string use the sqlite scheme`, func(s *testcase.Spec) {
+			s.Then(`it opens the database file on the path`, func(t *testcase.T) {
+				path := filepath.Join(t.TempDir(), `toggler.db`)
+				storage, err := storages.New(`sqlite://` + path)
+				require.Nil(t, err)
+				t.Cleanup(func() { require.Nil(t, storage.Close()) })
+				require.Equal(t, path, storage.(*storages.SQLite).Path)
+			})
+		})
+	})
+}
+
+func TestSQLite_health(t *testing.T) {
+	storage, err := storages.NewSQLite(filepath.Join(t.TempDir(), `toggler.db`))
+	require.Nil(t, err)
+	defer storage.Close()
+	ctx := context.Background()
+
+	require.Nil(t, storage.Ping(ctx))
+	require.Nil(t, storage.CheckMigrations(ctx))
+
+	_, err = storage.DB.ExecContext(ctx, `UPDATE schema_migrations SET version = version - 1`)
+	require.Nil(t, err)
+	require.Error(t, storage.CheckMigrations(ctx))
+
+	_, err = storage.DB.ExecContext(ctx, `UPDATE schema_migrations SET version = version + 1, dirty = true`)
+	require.Nil(t, err)
+	require.Error(t, storage.CheckMigrations(ctx))
+
+	// a newer toggler migrated the schema during a rolling deploy.
+	_, err = storage.DB.ExecContext(ctx, `UPDATE schema_migrations SET version = version + 1, dirty = false`)
+	require.Nil(t, err)
+	err = storage.CheckMigrations(ctx)
+	var newer migrations.SchemaIsNewerError
+	require.ErrorAs(t, err, &newer)
+	require.True(t, newer.Informational())
+	require.Equal(t, newer.Expected+1, newer.Version)
+}
+
+func TestSQLite_eventsAfterCommit(t *testing.T) {
+	storage, err := storages.NewSQLite(filepath.Join(t.TempDir(), `toggler.db`))
+	require.Nil(t, err)
+	defer storage.Close()
+	ctx := context.Background()
+
+	var found bool
+	var findErr error
+	sub, err := storage.ReleaseFlag(ctx).SubscribeToCreatorEvents(ctx, flagCreatedSubscriber(func(ctx context.Context, e frameless.CreateEvent) {
+		flag := e.Entity.(release.Flag)
+		// the subscriber reads the storage with the context of the event, after the transaction is finished.
+		found, findErr = storage.ReleaseFlag(ctx).FindByID(ctx, &release.Flag{}, flag.ID)
+	}))
+	require.Nil(t, err)
+	defer sub.Close()
+
+	tx, err := storage.BeginTx(ctx)
+	require.Nil(t, err)
+	require.Nil(t, storage.ReleaseFlag(tx).Create(tx, &release.Flag{Name: `flag`}))
+	require.Nil(t, storage.CommitTx(tx))
+
+	require.Nil(t, findErr)
+	require.True(t, found)
+}
+
+type flagCreatedSubscriber func(context.Context, frameless.CreateEvent)
+
+func (fn flagCreatedSubscriber) HandleCreateEvent(ctx context.Context, e frameless.CreateEvent) error {
+	fn(ctx, e)
+	return nil
+}
+
+func (fn flagCreatedSubscriber) HandleError(ctx context.Context, err error) error { return nil }
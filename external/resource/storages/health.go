@@ -0,0 +1,44 @@
+package storages
+
+import (
+	"context"
+
+	"github.com/adamluzsi/frameless/postgresql"
+
+	"github.com/toggler-io/toggler/external/resource/storages/migrations"
+)
+
+// Ping checks that the database can be reached.
+func (p *Postgres) Ping(ctx context.Context) error {
+	return ping(ctx, p.ConnectionManager)
+}
+
+// CheckMigrations checks that every migration is applied to the database.
+func (p *Postgres) CheckMigrations(ctx context.Context) error {
+	c, err := p.ConnectionManager.Connection(ctx)
+	if err != nil {
+		return err
+	}
+	return migrations.CheckPostgres(ctx, c)
+}
+
+// Ping checks that the database file can be read.
+func (s *SQLite) Ping(ctx context.Context) error {
+	return ping(ctx, s)
+}
+
+// CheckMigrations checks that every migration is applied to the database.
+func (s *SQLite) CheckMigrations(ctx context.Context) error {
+	return migrations.CheckSQLite(ctx, s.DB)
+}
+
+func ping(ctx context.Context, cm interface {
+	Connection(ctx context.Context) (postgresql.Connection, error)
+}) error {
+	c, err := cm.Connection(ctx)
+	if err != nil {
+		return err
+	}
+	var one int
+	return c.QueryRowContext(ctx, `SELECT 1`).Scan(&one)
+}
@@ -0,0 +1,810 @@
+// Package instrumented wraps a storage, so its operations can be measured or traced.
+package instrumented
+
+import (
+	"context"
+	"sync"
+
+	"github.com/adamluzsi/frameless/iterators"
+
+	"github.com/toggler-io/toggler/domains/change"
+	"github.com/toggler-io/toggler/domains/guardrail"
+	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/schedule"
+	"github.com/toggler-io/toggler/domains/security"
+	"github.com/toggler-io/toggler/domains/toggler"
+	"github.com/toggler-io/toggler/domains/webhook"
+)
+
+// Hook is called when a storage operation starts, and the returned func is called with its error when it finishes.
+// The operation is made with the context returned by the Hook.
+type Hook func(ctx context.Context, entity, operation string) (context.Context, func(err error))
+
+// NewStorage wraps the storage, so the Hook is called around each of its entity operations.
+// The operations which return an iterator are finished when the iterator is closed.
+func NewStorage(s toggler.Storage, hook Hook) toggler.Storage {
+	return storage{Storage: s, hook: hook}
+}
+
+type storage struct {
+	toggler.Storage
+	hook Hook
+}
+
+func (s storage) operations(entity string) operations {
+	return operations{hook: s.hook, entity: entity}
+}
+
+func (s storage) ReleaseFlag(ctx context.Context) release.FlagStorage {
+	return flagStorage{FlagStorage: s.Storage.ReleaseFlag(ctx), operations: s.operations(`flag`)}
+}
+
+func (s storage) ReleasePilot(ctx context.Context) release.PilotStorage {
+	return pilotStorage{PilotStorage: s.Storage.ReleasePilot(ctx), operations: s.operations(`pilot`)}
+}
+
+func (s storage) ReleaseRollout(ctx context.Context) release.RolloutStorage {
+	return rolloutStorage{RolloutStorage: s.Storage.ReleaseRollout(ctx), operations: s.operations(`rollout`)}
+}
+
+func (s storage) ReleaseEnvironment(ctx context.Context) release.EnvironmentStorage {
+	return environmentStorage{EnvironmentStorage: s.Storage.ReleaseEnvironment(ctx), operations: s.operations(`environment`)}
+}
+
+func (s storage) ReleaseVersion(ctx context.Context) release.VersionStorage {
+	return versionStorage{VersionStorage: s.Storage.ReleaseVersion(ctx), operations: s.operations(`version`)}
+}
+
+func (s storage) SecurityToken(ctx context.Context) security.TokenStorage {
+	return tokenStorage{TokenStorage: s.Storage.SecurityToken(ctx), operations: s.operations(`token`)}
+}
+
+func (s storage) WebhookSubscription(ctx context.Context) webhook.SubscriptionStorage {
+	return webhookSubscriptionStorage{SubscriptionStorage: s.Storage.WebhookSubscription(ctx), operations: s.operations(`webhook_subscription`)}
+}
+
+func (s storage) WebhookDelivery(ctx context.Context) webhook.DeliveryStorage {
+	return webhookDeliveryStorage{DeliveryStorage: s.Storage.WebhookDelivery(ctx), operations: s.operations(`webhook_delivery`)}
+}
+
+func (s storage) ChangeRequest(ctx context.Context) change.RequestStorage {
+	return changeRequestStorage{RequestStorage: s.Storage.ChangeRequest(ctx), operations: s.operations(`change_request`)}
+}
+
+func (s storage) ChangeApproval(ctx context.Context) change.ApprovalStorage {
+	return changeApprovalStorage{ApprovalStorage: s.Storage.ChangeApproval(ctx), operations: s.operations(`change_approval`)}
+}
+
+func (s storage) ScheduledChange(ctx context.Context) schedule.ChangeStorage {
+	return scheduledChangeStorage{ChangeStorage: s.Storage.ScheduledChange(ctx), operations: s.operations(`scheduled_change`)}
+}
+
+func (s storage) Guardrail(ctx context.Context) guardrail.GuardrailStorage {
+	return guardrailStorage{GuardrailStorage: s.Storage.Guardrail(ctx), operations: s.operations(`guardrail`)}
+}
+
+func (s storage) GuardrailSignal(ctx context.Context) guardrail.SignalStorage {
+	return guardrailSignalStorage{SignalStorage: s.Storage.GuardrailSignal(ctx), operations: s.operations(`guardrail_signal`)}
+}
+
+type operations struct {
+	hook   Hook
+	entity string
+}
+
+func (o operations) start(ctx context.Context, operation string) (context.Context, func(error)) {
+	return o.hook(ctx, o.entity, operation)
+}
+
+func (o operations) iterator(ctx context.Context, operation string, find func(context.Context) iterators.Interface) iterators.Interface {
+	ctx, finish := o.start(ctx, operation)
+	return &iterator{Interface: find(ctx), finish: finish}
+}
+
+type iterator struct {
+	iterators.Interface
+	finish func(error)
+	once   sync.Once
+}
+
+func (i *iterator) Close() error {
+	err := i.Interface.Close()
+	i.once.Do(func() {
+		if iterErr := i.Interface.Err(); iterErr != nil {
+			i.finish(iterErr)
+			return
+		}
+		i.finish(err)
+	})
+	return err
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+type flagStorage struct {
+	release.FlagStorage
+	operations
+}
+
+func (s flagStorage) Create(ctx context.Context, ptr interface{}) error {
+	ctx, finish := s.start(ctx, `create`)
+	err := s.FlagStorage.Create(ctx, ptr)
+	finish(err)
+	return err
+}
+
+func (s flagStorage) FindByID(ctx context.Context, ptr, id interface{}) (bool, error) {
+	ctx, finish := s.start(ctx, `find_by_id`)
+	found, err := s.FlagStorage.FindByID(ctx, ptr, id)
+	finish(err)
+	return found, err
+}
+
+func (s flagStorage) FindAll(ctx context.Context) iterators.Interface {
+	return s.iterator(ctx, `find_all`, s.FlagStorage.FindAll)
+}
+
+func (s flagStorage) Update(ctx context.Context, ptr interface{}) error {
+	ctx, finish := s.start(ctx, `update`)
+	err := s.FlagStorage.Update(ctx, ptr)
+	finish(err)
+	return err
+}
+
+func (s flagStorage) DeleteByID(ctx context.Context, id interface{}) error {
+	ctx, finish := s.start(ctx, `delete_by_id`)
+	err := s.FlagStorage.DeleteByID(ctx, id)
+	finish(err)
+	return err
+}
+
+func (s flagStorage) DeleteAll(ctx context.Context) error {
+	ctx, finish := s.start(ctx, `delete_all`)
+	err := s.FlagStorage.DeleteAll(ctx)
+	finish(err)
+	return err
+}
+
+func (s flagStorage) FindByName(ctx context.Context, name string) (*release.Flag, error) {
+	ctx, finish := s.start(ctx, `find_by_name`)
+	ent, err := s.FlagStorage.FindByName(ctx, name)
+	finish(err)
+	return ent, err
+}
+
+func (s flagStorage) FindByNames(ctx context.Context, names ...string) release.FlagEntries {
+	return s.iterator(ctx, `find_by_names`, func(ctx context.Context) iterators.Interface {
+		return s.FlagStorage.FindByNames(ctx, names...)
+	})
+}
+
+func (s flagStorage) FindByQuery(ctx context.Context, q release.FlagQuery) release.FlagEntries {
+	return s.iterator(ctx, `find_by_query`, func(ctx context.Context) iterators.Interface {
+		return s.FlagStorage.FindByQuery(ctx, q)
+	})
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+type pilotStorage struct {
+	release.PilotStorage
+	operations
+}
+
+func (s pilotStorage) Create(ctx context.Context, ptr interface{}) error {
+	ctx, finish := s.start(ctx, `create`)
+	err := s.PilotStorage.Create(ctx, ptr)
+	finish(err)
+	return err
+}
+
+func (s pilotStorage) FindByID(ctx context.Context, ptr, id interface{}) (bool, error) {
+	ctx, finish := s.start(ctx, `find_by_id`)
+	found, err := s.PilotStorage.FindByID(ctx, ptr, id)
+	finish(err)
+	return found, err
+}
+
+func (s pilotStorage) FindAll(ctx context.Context) iterators.Interface {
+	return s.iterator(ctx, `find_all`, s.PilotStorage.FindAll)
+}
+
+func (s pilotStorage) Update(ctx context.Context, ptr interface{}) error {
+	ctx, finish := s.start(ctx, `update`)
+	err := s.PilotStorage.Update(ctx, ptr)
+	finish(err)
+	return err
+}
+
+func (s pilotStorage) DeleteByID(ctx context.Context, id interface{}) error {
+	ctx, finish := s.start(ctx, `delete_by_id`)
+	err := s.PilotStorage.DeleteByID(ctx, id)
+	finish(err)
+	return err
+}
+
+func (s pilotStorage) DeleteAll(ctx context.Context) error {
+	ctx, finish := s.start(ctx, `delete_all`)
+	err := s.PilotStorage.DeleteAll(ctx)
+	finish(err)
+	return err
+}
+
+func (s pilotStorage) FindByFlagEnvPublicID(ctx context.Context, flagID, envID interface{}, publicID string) (*release.Pilot, error) {
+	ctx, finish := s.start(ctx, `find_by_flag_env_public_id`)
+	ent, err := s.PilotStorage.FindByFlagEnvPublicID(ctx, flagID, envID, publicID)
+	finish(err)
+	return ent, err
+}
+
+func (s pilotStorage) FindByFlag(ctx context.Context, flag release.Flag) release.PilotEntries {
+	return s.iterator(ctx, `find_by_flag`, func(ctx context.Context) iterators.Interface {
+		return s.PilotStorage.FindByFlag(ctx, flag)
+	})
+}
+
+func (s pilotStorage) FindByPublicID(ctx context.Context, publicID string) release.PilotEntries {
+	return s.iterator(ctx, `find_by_public_id`, func(ctx context.Context) iterators.Interface {
+		return s.PilotStorage.FindByPublicID(ctx, publicID)
+	})
+}
+
+func (s pilotStorage) FindByQuery(ctx context.Context, q release.PilotQuery) release.PilotEntries {
+	return s.iterator(ctx, `find_by_query`, func(ctx context.Context) iterators.Interface {
+		return s.PilotStorage.FindByQuery(ctx, q)
+	})
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+type rolloutStorage struct {
+	release.RolloutStorage
+	operations
+}
+
+func (s rolloutStorage) Create(ctx context.Context, ptr interface{}) error {
+	ctx, finish := s.start(ctx, `create`)
+	err := s.RolloutStorage.Create(ctx, ptr)
+	finish(err)
+	return err
+}
+
+func (s rolloutStorage) FindByID(ctx context.Context, ptr, id interface{}) (bool, error) {
+	ctx, finish := s.start(ctx, `find_by_id`)
+	found, err := s.RolloutStorage.FindByID(ctx, ptr, id)
+	finish(err)
+	return found, err
+}
+
+func (s rolloutStorage) FindAll(ctx context.Context) iterators.Interface {
+	return s.iterator(ctx, `find_all`, s.RolloutStorage.FindAll)
+}
+
+func (s rolloutStorage) Update(ctx context.Context, ptr interface{}) error {
+	ctx, finish := s.start(ctx, `update`)
+	err := s.RolloutStorage.Update(ctx, ptr)
+	finish(err)
+	return err
+}
+
+func (s rolloutStorage) DeleteByID(ctx context.Context, id interface{}) error {
+	ctx, finish := s.start(ctx, `delete_by_id`)
+	err := s.RolloutStorage.DeleteByID(ctx, id)
+	finish(err)
+	return err
+}
+
+func (s rolloutStorage) DeleteAll(ctx context.Context) error {
+	ctx, finish := s.start(ctx, `delete_all`)
+	err := s.RolloutStorage.DeleteAll(ctx)
+	finish(err)
+	return err
+}
+
+func (s rolloutStorage) FindByFlagEnvironment(ctx context.Context, flag release.Flag, env release.Environment, ptr *release.Rollout) (bool, error) {
+	ctx, finish := s.start(ctx, `find_by_flag_environment`)
+	found, err := s.RolloutStorage.FindByFlagEnvironment(ctx, flag, env, ptr)
+	finish(err)
+	return found, err
+}
+
+func (s rolloutStorage) FindByQuery(ctx context.Context, q release.RolloutQuery) release.RolloutEntries {
+	return s.iterator(ctx, `find_by_query`, func(ctx context.Context) iterators.Interface {
+		return s.RolloutStorage.FindByQuery(ctx, q)
+	})
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+type environmentStorage struct {
+	release.EnvironmentStorage
+	operations
+}
+
+func (s environmentStorage) Create(ctx context.Context, ptr interface{}) error {
+	ctx, finish := s.start(ctx, `create`)
+	err := s.EnvironmentStorage.Create(ctx, ptr)
+	finish(err)
+	return err
+}
+
+func (s environmentStorage) FindByID(ctx context.Context, ptr, id interface{}) (bool, error) {
+	ctx, finish := s.start(ctx, `find_by_id`)
+	found, err := s.EnvironmentStorage.FindByID(ctx, ptr, id)
+	finish(err)
+	return found, err
+}
+
+func (s environmentStorage) FindAll(ctx context.Context) iterators.Interface {
+	return s.iterator(ctx, `find_all`, s.EnvironmentStorage.FindAll)
+}
+
+func (s environmentStorage) Update(ctx context.Context, ptr interface{}) error {
+	ctx, finish := s.start(ctx, `update`)
+	err := s.EnvironmentStorage.Update(ctx, ptr)
+	finish(err)
+	return err
+}
+
+func (s environmentStorage) DeleteByID(ctx context.Context, id interface{}) error {
+	ctx, finish := s.start(ctx, `delete_by_id`)
+	err := s.EnvironmentStorage.DeleteByID(ctx, id)
+	finish(err)
+	return err
+}
+
+func (s environmentStorage) DeleteAll(ctx context.Context) error {
+	ctx, finish := s.start(ctx, `delete_all`)
+	err := s.EnvironmentStorage.DeleteAll(ctx)
+	finish(err)
+	return err
+}
+
+func (s environmentStorage) FindByAlias(ctx context.Context, idOrName string, env *release.Environment) (bool, error) {
+	ctx, finish := s.start(ctx, `find_by_alias`)
+	found, err := s.EnvironmentStorage.FindByAlias(ctx, idOrName, env)
+	finish(err)
+	return found, err
+}
+
+func (s environmentStorage) FindByQuery(ctx context.Context, q release.EnvironmentQuery) release.EnvironmentEntries {
+	return s.iterator(ctx, `find_by_query`, func(ctx context.Context) iterators.Interface {
+		return s.EnvironmentStorage.FindByQuery(ctx, q)
+	})
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+type versionStorage struct {
+	release.VersionStorage
+	operations
+}
+
+func (s versionStorage) Create(ctx context.Context, ptr interface{}) error {
+	ctx, finish := s.start(ctx, `create`)
+	err := s.VersionStorage.Create(ctx, ptr)
+	finish(err)
+	return err
+}
+
+func (s versionStorage) FindByID(ctx context.Context, ptr, id interface{}) (bool, error) {
+	ctx, finish := s.start(ctx, `find_by_id`)
+	found, err := s.VersionStorage.FindByID(ctx, ptr, id)
+	finish(err)
+	return found, err
+}
+
+func (s versionStorage) FindAll(ctx context.Context) iterators.Interface {
+	return s.iterator(ctx, `find_all`, s.VersionStorage.FindAll)
+}
+
+func (s versionStorage) DeleteByID(ctx context.Context, id interface{}) error {
+	ctx, finish := s.start(ctx, `delete_by_id`)
+	err := s.VersionStorage.DeleteByID(ctx, id)
+	finish(err)
+	return err
+}
+
+func (s versionStorage) DeleteAll(ctx context.Context) error {
+	ctx, finish := s.start(ctx, `delete_all`)
+	err := s.VersionStorage.DeleteAll(ctx)
+	finish(err)
+	return err
+}
+
+func (s versionStorage) FindByQuery(ctx context.Context, q release.VersionQuery) release.VersionEntries {
+	return s.iterator(ctx, `find_by_query`, func(ctx context.Context) iterators.Interface {
+		return s.VersionStorage.FindByQuery(ctx, q)
+	})
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+type tokenStorage struct {
+	security.TokenStorage
+	operations
+}
+
+func (s tokenStorage) Create(ctx context.Context, ptr interface{}) error {
+	ctx, finish := s.start(ctx, `create`)
+	err := s.TokenStorage.Create(ctx, ptr)
+	finish(err)
+	return err
+}
+
+func (s tokenStorage) FindByID(ctx context.Context, ptr, id interface{}) (bool, error) {
+	ctx, finish := s.start(ctx, `find_by_id`)
+	found, err := s.TokenStorage.FindByID(ctx, ptr, id)
+	finish(err)
+	return found, err
+}
+
+func (s tokenStorage) FindAll(ctx context.Context) iterators.Interface {
+	return s.iterator(ctx, `find_all`, s.TokenStorage.FindAll)
+}
+
+func (s tokenStorage) Update(ctx context.Context, ptr interface{}) error {
+	ctx, finish := s.start(ctx, `update`)
+	err := s.TokenStorage.Update(ctx, ptr)
+	finish(err)
+	return err
+}
+
+func (s tokenStorage) DeleteByID(ctx context.Context, id interface{}) error {
+	ctx, finish := s.start(ctx, `delete_by_id`)
+	err := s.TokenStorage.DeleteByID(ctx, id)
+	finish(err)
+	return err
+}
+
+func (s tokenStorage) DeleteAll(ctx context.Context) error {
+	ctx, finish := s.start(ctx, `delete_all`)
+	err := s.TokenStorage.DeleteAll(ctx)
+	finish(err)
+	return err
+}
+
+func (s tokenStorage) FindTokenBySHA512Hex(ctx context.Context, sha512hex string) (*security.Token, error) {
+	ctx, finish := s.start(ctx, `find_token_by_sha512_hex`)
+	ent, err := s.TokenStorage.FindTokenBySHA512Hex(ctx, sha512hex)
+	finish(err)
+	return ent, err
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+type webhookSubscriptionStorage struct {
+	webhook.SubscriptionStorage
+	operations
+}
+
+func (s webhookSubscriptionStorage) Create(ctx context.Context, ptr interface{}) error {
+	ctx, finish := s.start(ctx, `create`)
+	err := s.SubscriptionStorage.Create(ctx, ptr)
+	finish(err)
+	return err
+}
+
+func (s webhookSubscriptionStorage) FindByID(ctx context.Context, ptr, id interface{}) (bool, error) {
+	ctx, finish := s.start(ctx, `find_by_id`)
+	found, err := s.SubscriptionStorage.FindByID(ctx, ptr, id)
+	finish(err)
+	return found, err
+}
+
+func (s webhookSubscriptionStorage) FindAll(ctx context.Context) iterators.Interface {
+	return s.iterator(ctx, `find_all`, s.SubscriptionStorage.FindAll)
+}
+
+func (s webhookSubscriptionStorage) Update(ctx context.Context, ptr interface{}) error {
+	ctx, finish := s.start(ctx, `update`)
+	err := s.SubscriptionStorage.Update(ctx, ptr)
+	finish(err)
+	return err
+}
+
+func (s webhookSubscriptionStorage) DeleteByID(ctx context.Context, id interface{}) error {
+	ctx, finish := s.start(ctx, `delete_by_id`)
+	err := s.SubscriptionStorage.DeleteByID(ctx, id)
+	finish(err)
+	return err
+}
+
+func (s webhookSubscriptionStorage) DeleteAll(ctx context.Context) error {
+	ctx, finish := s.start(ctx, `delete_all`)
+	err := s.SubscriptionStorage.DeleteAll(ctx)
+	finish(err)
+	return err
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+type webhookDeliveryStorage struct {
+	webhook.DeliveryStorage
+	operations
+}
+
+func (s webhookDeliveryStorage) Create(ctx context.Context, ptr interface{}) error {
+	ctx, finish := s.start(ctx, `create`)
+	err := s.DeliveryStorage.Create(ctx, ptr)
+	finish(err)
+	return err
+}
+
+func (s webhookDeliveryStorage) FindByID(ctx context.Context, ptr, id interface{}) (bool, error) {
+	ctx, finish := s.start(ctx, `find_by_id`)
+	found, err := s.DeliveryStorage.FindByID(ctx, ptr, id)
+	finish(err)
+	return found, err
+}
+
+func (s webhookDeliveryStorage) FindAll(ctx context.Context) iterators.Interface {
+	return s.iterator(ctx, `find_all`, s.DeliveryStorage.FindAll)
+}
+
+func (s webhookDeliveryStorage) Update(ctx context.Context, ptr interface{}) error {
+	ctx, finish := s.start(ctx, `update`)
+	err := s.DeliveryStorage.Update(ctx, ptr)
+	finish(err)
+	return err
+}
+
+func (s webhookDeliveryStorage) DeleteByID(ctx context.Context, id interface{}) error {
+	ctx, finish := s.start(ctx, `delete_by_id`)
+	err := s.DeliveryStorage.DeleteByID(ctx, id)
+	finish(err)
+	return err
+}
+
+func (s webhookDeliveryStorage) DeleteAll(ctx context.Context) error {
+	ctx, finish := s.start(ctx, `delete_all`)
+	err := s.DeliveryStorage.DeleteAll(ctx)
+	finish(err)
+	return err
+}
+
+func (s webhookDeliveryStorage) FindByQuery(ctx context.Context, q webhook.DeliveryQuery) webhook.DeliveryEntries {
+	return s.iterator(ctx, `find_by_query`, func(ctx context.Context) iterators.Interface {
+		return s.DeliveryStorage.FindByQuery(ctx, q)
+	})
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+type changeRequestStorage struct {
+	change.RequestStorage
+	operations
+}
+
+func (s changeRequestStorage) Create(ctx context.Context, ptr interface{}) error {
+	ctx, finish := s.start(ctx, `create`)
+	err := s.RequestStorage.Create(ctx, ptr)
+	finish(err)
+	return err
+}
+
+func (s changeRequestStorage) FindByID(ctx context.Context, ptr, id interface{}) (bool, error) {
+	ctx, finish := s.start(ctx, `find_by_id`)
+	found, err := s.RequestStorage.FindByID(ctx, ptr, id)
+	finish(err)
+	return found, err
+}
+
+func (s changeRequestStorage) FindAll(ctx context.Context) iterators.Interface {
+	return s.iterator(ctx, `find_all`, s.RequestStorage.FindAll)
+}
+
+func (s changeRequestStorage) Update(ctx context.Context, ptr interface{}) error {
+	ctx, finish := s.start(ctx, `update`)
+	err := s.RequestStorage.Update(ctx, ptr)
+	finish(err)
+	return err
+}
+
+func (s changeRequestStorage) DeleteByID(ctx context.Context, id interface{}) error {
+	ctx, finish := s.start(ctx, `delete_by_id`)
+	err := s.RequestStorage.DeleteByID(ctx, id)
+	finish(err)
+	return err
+}
+
+func (s changeRequestStorage) DeleteAll(ctx context.Context) error {
+	ctx, finish := s.start(ctx, `delete_all`)
+	err := s.RequestStorage.DeleteAll(ctx)
+	finish(err)
+	return err
+}
+
+func (s changeRequestStorage) FindByQuery(ctx context.Context, q change.RequestQuery) change.RequestEntries {
+	return s.iterator(ctx, `find_by_query`, func(ctx context.Context) iterators.Interface {
+		return s.RequestStorage.FindByQuery(ctx, q)
+	})
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+type changeApprovalStorage struct {
+	change.ApprovalStorage
+	operations
+}
+
+func (s changeApprovalStorage) Create(ctx context.Context, ptr interface{}) error {
+	ctx, finish := s.start(ctx, `create`)
+	err := s.ApprovalStorage.Create(ctx, ptr)
+	finish(err)
+	return err
+}
+
+func (s changeApprovalStorage) FindByID(ctx context.Context, ptr, id interface{}) (bool, error) {
+	ctx, finish := s.start(ctx, `find_by_id`)
+	found, err := s.ApprovalStorage.FindByID(ctx, ptr, id)
+	finish(err)
+	return found, err
+}
+
+func (s changeApprovalStorage) FindAll(ctx context.Context) iterators.Interface {
+	return s.iterator(ctx, `find_all`, s.ApprovalStorage.FindAll)
+}
+
+func (s changeApprovalStorage) DeleteByID(ctx context.Context, id interface{}) error {
+	ctx, finish := s.start(ctx, `delete_by_id`)
+	err := s.ApprovalStorage.DeleteByID(ctx, id)
+	finish(err)
+	return err
+}
+
+func (s changeApprovalStorage) DeleteAll(ctx context.Context) error {
+	ctx, finish := s.start(ctx, `delete_all`)
+	err := s.ApprovalStorage.DeleteAll(ctx)
+	finish(err)
+	return err
+}
+
+func (s changeApprovalStorage) FindByQuery(ctx context.Context, q change.ApprovalQuery) change.ApprovalEntries {
+	return s.iterator(ctx, `find_by_query`, func(ctx context.Context) iterators.Interface {
+		return s.ApprovalStorage.FindByQuery(ctx, q)
+	})
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+type scheduledChangeStorage struct {
+	schedule.ChangeStorage
+	operations
+}
+
+func (s scheduledChangeStorage) Create(ctx context.Context, ptr interface{}) error {
+	ctx, finish := s.start(ctx, `create`)
+	err := s.ChangeStorage.Create(ctx, ptr)
+	finish(err)
+	return err
+}
+
+func (s scheduledChangeStorage) FindByID(ctx context.Context, ptr, id interface{}) (bool, error) {
+	ctx, finish := s.start(ctx, `find_by_id`)
+	found, err := s.ChangeStorage.FindByID(ctx, ptr, id)
+	finish(err)
+	return found, err
+}
+
+func (s scheduledChangeStorage) FindAll(ctx context.Context) iterators.Interface {
+	return s.iterator(ctx, `find_all`, s.ChangeStorage.FindAll)
+}
+
+func (s scheduledChangeStorage) Update(ctx context.Context, ptr interface{}) error {
+	ctx, finish := s.start(ctx, `update`)
+	err := s.ChangeStorage.Update(ctx, ptr)
+	finish(err)
+	return err
+}
+
+func (s scheduledChangeStorage) DeleteByID(ctx context.Context, id interface{}) error {
+	ctx, finish := s.start(ctx, `delete_by_id`)
+	err := s.ChangeStorage.DeleteByID(ctx, id)
+	finish(err)
+	return err
+}
+
+func (s scheduledChangeStorage) DeleteAll(ctx context.Context) error {
+	ctx, finish := s.start(ctx, `delete_all`)
+	err := s.ChangeStorage.DeleteAll(ctx)
+	finish(err)
+	return err
+}
+
+func (s scheduledChangeStorage) FindByQuery(ctx context.Context, q schedule.ChangeQuery) schedule.ChangeEntries {
+	return s.iterator(ctx, `find_by_query`, func(ctx context.Context) iterators.Interface {
+		return s.ChangeStorage.FindByQuery(ctx, q)
+	})
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+type guardrailStorage struct {
+	guardrail.GuardrailStorage
+	operations
+}
+
+func (s guardrailStorage) Create(ctx context.Context, ptr interface{}) error {
+	ctx, finish := s.start(ctx, `create`)
+	err := s.GuardrailStorage.Create(ctx, ptr)
+	finish(err)
+	return err
+}
+
+func (s guardrailStorage) FindByID(ctx context.Context, ptr, id interface{}) (bool, error) {
+	ctx, finish := s.start(ctx, `find_by_id`)
+	found, err := s.GuardrailStorage.FindByID(ctx, ptr, id)
+	finish(err)
+	return found, err
+}
+
+func (s guardrailStorage) FindAll(ctx context.Context) iterators.Interface {
+	return s.iterator(ctx, `find_all`, s.GuardrailStorage.FindAll)
+}
+
+func (s guardrailStorage) Update(ctx context.Context, ptr interface{}) error {
+	ctx, finish := s.start(ctx, `update`)
+	err := s.GuardrailStorage.Update(ctx, ptr)
+	finish(err)
+	return err
+}
+
+func (s guardrailStorage) DeleteByID(ctx context.Context, id interface{}) error {
+	ctx, finish := s.start(ctx, `delete_by_id`)
+	err := s.GuardrailStorage.DeleteByID(ctx, id)
+	finish(err)
+	return err
+}
+
+func (s guardrailStorage) DeleteAll(ctx context.Context) error {
+	ctx, finish := s.start(ctx, `delete_all`)
+	err := s.GuardrailStorage.DeleteAll(ctx)
+	finish(err)
+	return err
+}
+
+//--------------------------------------------------------------------------------------------------------------------//
+
+type guardrailSignalStorage struct {
+	guardrail.SignalStorage
+	operations
+}
+
+func (s guardrailSignalStorage) Create(ctx context.Context, ptr interface{}) error {
+	ctx, finish := s.start(ctx, `create`)
+	err := s.SignalStorage.Create(ctx, ptr)
+	finish(err)
+	return err
+}
+
+func (s guardrailSignalStorage) FindByID(ctx context.Context, ptr, id interface{}) (bool, error) {
+	ctx, finish := s.start(ctx, `find_by_id`)
+	found, err := s.SignalStorage.FindByID(ctx, ptr, id)
+	finish(err)
+	return found, err
+}
+
+func (s guardrailSignalStorage) FindAll(ctx context.Context) iterators.Interface {
+	return s.iterator(ctx, `find_all`, s.SignalStorage.FindAll)
+}
+
+func (s guardrailSignalStorage) DeleteByID(ctx context.Context, id interface{}) error {
+	ctx, finish := s.start(ctx, `delete_by_id`)
+	err := s.SignalStorage.DeleteByID(ctx, id)
+	finish(err)
+	return err
+}
+
+func (s guardrailSignalStorage) DeleteAll(ctx context.Context) error {
+	ctx, finish := s.start(ctx, `delete_all`)
+	err := s.SignalStorage.DeleteAll(ctx)
+	finish(err)
+	return err
+}
+
+func (s guardrailSignalStorage) FindByQuery(ctx context.Context, q guardrail.SignalQuery) guardrail.SignalEntries {
+	return s.iterator(ctx, `find_by_query`, func(ctx context.Context) iterators.Interface {
+		return s.SignalStorage.FindByQuery(ctx, q)
+	})
+}
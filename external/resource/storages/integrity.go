@@ -0,0 +1,148 @@
+package storages
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/extid"
+	"github.com/adamluzsi/frameless/inmemory"
+	"github.com/adamluzsi/frameless/iterators"
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"github.com/toggler-io/toggler/domains/release"
+)
+
+// uniqueViolation replaces the unique constraint violation error of the database with the given domain error.
+func uniqueViolation(err error, domainErr error) error {
+	var pgErr *pq.Error
+	if errors.As(err, &pgErr) && pgErr.Code == `23505` {
+		return domainErr
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return domainErr
+	}
+	return err
+}
+
+// memoryUniqueWrite makes the write only when no stored entity is taken by the isTaken func,
+// so the in-memory storages keep the same unique constraints as the sql schema.
+// The check and the write are made in the same transaction.
+func memoryUniqueWrite(ctx context.Context, s *inmemory.EventLogStorage, isTaken func(stored interface{}) bool, domainErr error, write func(context.Context) error) (rErr error) {
+	ctx, err := s.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer frameless.FinishOnePhaseCommit(&rErr, s, ctx)
+
+	for _, v := range s.View(ctx) {
+		if isTaken(v) {
+			return domainErr
+		}
+	}
+	return write(ctx)
+}
+
+// references returns the queries of the rollouts and pilots which refer to the entity with the given id.
+type references func(id string) (release.RolloutQuery, release.PilotQuery)
+
+func flagReferences(id string) (release.RolloutQuery, release.PilotQuery) {
+	return release.RolloutQuery{FlagID: id}, release.PilotQuery{FlagID: id}
+}
+
+func environmentReferences(id string) (release.RolloutQuery, release.PilotQuery) {
+	return release.RolloutQuery{EnvironmentID: id}, release.PilotQuery{EnvironmentID: id}
+}
+
+// deleteByIDRestrict deletes the entity, unless rollouts or pilots still refer to it,
+// in which case the inUse error is returned.
+// The referring entries are not deleted here, as the storage wrappers of the use cases,
+// like the change history and the protection of the environments, wouldn't see their deletion.
+// The check and the deletion are made in the same transaction.
+func deleteByIDRestrict(ctx context.Context, releases release.Storage, s frameless.Deleter, refs references, inUse error, id interface{}) (rErr error) {
+	ctx, err := releases.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer frameless.FinishOnePhaseCommit(&rErr, releases, ctx)
+
+	referenced, err := isReferenced(ctx, releases, refs, id)
+	if err != nil {
+		return err
+	}
+	if referenced {
+		return inUse
+	}
+	return s.DeleteByID(ctx, id)
+}
+
+// deleteAllRestrict is the DeleteAll version of deleteByIDRestrict.
+func deleteAllRestrict(ctx context.Context, releases release.Storage, s interface {
+	frameless.Finder
+	frameless.Deleter
+}, T interface{}, refs references, inUse error) (rErr error) {
+	ctx, err := releases.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer frameless.FinishOnePhaseCommit(&rErr, releases, ctx)
+
+	// the ids are collected before the checks,
+	// so the sql storages don't query while the result rows are still open.
+	var ids []interface{}
+	iter := s.FindAll(ctx)
+	for iter.Next() {
+		ptr := reflect.New(reflect.TypeOf(T)).Interface()
+		if err := iter.Decode(ptr); err != nil {
+			_ = iter.Close()
+			return err
+		}
+		if id, ok := extid.Lookup(ptr); ok {
+			ids = append(ids, id)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		_ = iter.Close()
+		return err
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		referenced, err := isReferenced(ctx, releases, refs, id)
+		if err != nil {
+			return err
+		}
+		if referenced {
+			return inUse
+		}
+	}
+	return s.DeleteAll(ctx)
+}
+
+func isReferenced(ctx context.Context, releases release.Storage, refs references, id interface{}) (bool, error) {
+	sid, ok := id.(string)
+	if !ok || sid == `` {
+		return false, nil
+	}
+	rolloutQuery, pilotQuery := refs(sid)
+
+	rollouts, err := iterators.Count(releases.ReleaseRollout(ctx).FindByQuery(ctx, rolloutQuery))
+	if err != nil {
+		return false, err
+	}
+	if 0 < rollouts {
+		return true, nil
+	}
+
+	pilots, err := iterators.Count(releases.ReleasePilot(ctx).FindByQuery(ctx, pilotQuery))
+	if err != nil {
+		return false, err
+	}
+	return 0 < pilots, nil
+}
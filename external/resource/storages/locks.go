@@ -0,0 +1,36 @@
+package storages
+
+import (
+	"context"
+	"sync"
+)
+
+// processLocks are the named locks of a storage which is not shared by more toggler processes,
+// so it is enough to hold the locks within the process.
+type processLocks struct {
+	mutex sync.Mutex
+	held  map[string]struct{}
+}
+
+func (l *processLocks) TryLock(ctx context.Context, name string) (func() error, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if _, ok := l.held[name]; ok {
+		return nil, false, nil
+	}
+	if l.held == nil {
+		l.held = make(map[string]struct{})
+	}
+	l.held[name] = struct{}{}
+
+	return func() error {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+		delete(l.held, name)
+		return nil
+	}, true, nil
+}
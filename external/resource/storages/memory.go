@@ -3,14 +3,20 @@ package storages
 import (
 	"context"
 	"fmt"
+	"sort"
+	"sync"
 
 	"github.com/adamluzsi/frameless/reflects"
 
 	"github.com/adamluzsi/frameless/inmemory"
 	"github.com/adamluzsi/frameless/iterators"
 
+	"github.com/toggler-io/toggler/domains/change"
+	"github.com/toggler-io/toggler/domains/guardrail"
 	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/schedule"
 	"github.com/toggler-io/toggler/domains/security"
+	"github.com/toggler-io/toggler/domains/webhook"
 )
 
 func NewInMemory() *InMemory {
@@ -21,6 +27,12 @@ type InMemory struct {
 	EventLog  *inmemory.EventLog
 	Namespace string
 	closed    bool
+	// deliveries serialise the webhook delivery writes,
+	// since the event log transactions don't isolate the uniqueness check from the concurrent writes.
+	deliveries sync.Mutex
+	// approvals serialise the change approval writes for the same reason.
+	approvals sync.Mutex
+	locks     processLocks
 }
 
 func (s *InMemory) storageFor(T interface{}) *inmemory.EventLogStorage {
@@ -41,14 +53,47 @@ func (s *InMemory) RollbackTx(ctx context.Context) error {
 	return s.EventLog.RollbackTx(ctx)
 }
 
+func (s *InMemory) TryLock(ctx context.Context, name string) (func() error, bool, error) {
+	return s.locks.TryLock(ctx, name)
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 func (s *InMemory) ReleaseFlag(ctx context.Context) release.FlagStorage {
-	return &MemoryReleaseFlagStorage{EventLogStorage: s.storageFor(release.Flag{})}
+	return &MemoryReleaseFlagStorage{EventLogStorage: s.storageFor(release.Flag{}), releases: s}
 }
 
 type MemoryReleaseFlagStorage struct {
 	*inmemory.EventLogStorage
+	releases release.Storage
+}
+
+func (s *MemoryReleaseFlagStorage) Create(ctx context.Context, ptr interface{}) error {
+	return memoryUniqueWrite(ctx, s.EventLogStorage, s.isNameTaken(ptr), release.ErrFlagAlreadyExist, func(ctx context.Context) error {
+		return createRevision(ctx, s.EventLogStorage, ptr)
+	})
+}
+
+func (s *MemoryReleaseFlagStorage) Update(ctx context.Context, ptr interface{}) error {
+	return memoryUniqueWrite(ctx, s.EventLogStorage, s.isNameTaken(ptr), release.ErrFlagAlreadyExist, func(ctx context.Context) error {
+		return updateRevision(ctx, s.EventLogStorage, ptr)
+	})
+}
+
+func (s *MemoryReleaseFlagStorage) isNameTaken(ptr interface{}) func(interface{}) bool {
+	flag := ptr.(*release.Flag)
+	return func(v interface{}) bool {
+		stored := v.(release.Flag)
+		return stored.ID != flag.ID && stored.Name == flag.Name
+	}
+}
+
+func (s *MemoryReleaseFlagStorage) DeleteByID(ctx context.Context, id interface{}) error {
+	return deleteByIDRestrict(ctx, s.releases, s.EventLogStorage, flagReferences, release.ErrFlagInUse, id)
+}
+
+func (s *MemoryReleaseFlagStorage) DeleteAll(ctx context.Context) error {
+	return deleteAllRestrict(ctx, s.releases, s.EventLogStorage, release.Flag{}, flagReferences, release.ErrFlagInUse)
 }
 
 func (s *MemoryReleaseFlagStorage) FindByName(ctx context.Context, name string) (*release.Flag, error) {
@@ -94,6 +139,31 @@ func (s *MemoryReleaseFlagStorage) FindByNames(ctx context.Context, names ...str
 	return iterators.NewSlice(flags)
 }
 
+func (s *MemoryReleaseFlagStorage) FindByQuery(ctx context.Context, q release.FlagQuery) release.FlagEntries {
+	if err := ctx.Err(); err != nil {
+		return iterators.NewError(err)
+	}
+
+	var flags []release.Flag
+	for _, v := range s.View(ctx) {
+		flag := v.(release.Flag)
+
+		if q.Match(flag) && q.IsAfterCursor(q.SortKey(flag), flag.ID) {
+			flags = append(flags, flag)
+		}
+	}
+
+	sort.Slice(flags, func(i, j int) bool {
+		return q.Less(q.SortKey(flags[i]), flags[i].ID, q.SortKey(flags[j]), flags[j].ID)
+	})
+
+	if 0 < q.Limit && q.Limit < len(flags) {
+		flags = flags[:q.Limit]
+	}
+
+	return iterators.NewSlice(flags)
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 func (s *InMemory) ReleasePilot(ctx context.Context) release.PilotStorage {
@@ -104,6 +174,29 @@ type MemoryReleasePilotStorage struct {
 	*inmemory.EventLogStorage
 }
 
+func (s *MemoryReleasePilotStorage) Create(ctx context.Context, ptr interface{}) error {
+	return memoryUniqueWrite(ctx, s.EventLogStorage, s.isTaken(ptr), release.ErrPilotAlreadyExist, func(ctx context.Context) error {
+		return createRevision(ctx, s.EventLogStorage, ptr)
+	})
+}
+
+func (s *MemoryReleasePilotStorage) Update(ctx context.Context, ptr interface{}) error {
+	return memoryUniqueWrite(ctx, s.EventLogStorage, s.isTaken(ptr), release.ErrPilotAlreadyExist, func(ctx context.Context) error {
+		return updateRevision(ctx, s.EventLogStorage, ptr)
+	})
+}
+
+func (s *MemoryReleasePilotStorage) isTaken(ptr interface{}) func(interface{}) bool {
+	pilot := ptr.(*release.Pilot)
+	return func(v interface{}) bool {
+		stored := v.(release.Pilot)
+		return stored.ID != pilot.ID &&
+			stored.FlagID == pilot.FlagID &&
+			stored.EnvironmentID == pilot.EnvironmentID &&
+			stored.PublicID == pilot.PublicID
+	}
+}
+
 func (s *MemoryReleasePilotStorage) FindByFlagEnvPublicID(ctx context.Context, flagID, envID interface{}, pilotExtID string) (*release.Pilot, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
@@ -156,6 +249,31 @@ func (s *MemoryReleasePilotStorage) FindByPublicID(ctx context.Context, pilotPub
 	return iterators.NewSlice(pilots)
 }
 
+func (s *MemoryReleasePilotStorage) FindByQuery(ctx context.Context, q release.PilotQuery) release.PilotEntries {
+	if err := ctx.Err(); err != nil {
+		return iterators.NewError(err)
+	}
+
+	var pilots []release.Pilot
+	for _, v := range s.View(ctx) {
+		pilot := v.(release.Pilot)
+
+		if q.Match(pilot) && q.IsAfterCursor(q.SortKey(pilot), pilot.ID) {
+			pilots = append(pilots, pilot)
+		}
+	}
+
+	sort.Slice(pilots, func(i, j int) bool {
+		return q.Less(q.SortKey(pilots[i]), pilots[i].ID, q.SortKey(pilots[j]), pilots[j].ID)
+	})
+
+	if 0 < q.Limit && q.Limit < len(pilots) {
+		pilots = pilots[:q.Limit]
+	}
+
+	return iterators.NewSlice(pilots)
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 func (s *InMemory) ReleaseRollout(ctx context.Context) release.RolloutStorage {
@@ -166,6 +284,28 @@ type MemoryReleaseRolloutStorage struct {
 	*inmemory.EventLogStorage
 }
 
+func (s *MemoryReleaseRolloutStorage) Create(ctx context.Context, ptr interface{}) error {
+	return memoryUniqueWrite(ctx, s.EventLogStorage, s.isTaken(ptr), release.ErrRolloutAlreadyExist, func(ctx context.Context) error {
+		return createRevision(ctx, s.EventLogStorage, ptr)
+	})
+}
+
+func (s *MemoryReleaseRolloutStorage) Update(ctx context.Context, ptr interface{}) error {
+	return memoryUniqueWrite(ctx, s.EventLogStorage, s.isTaken(ptr), release.ErrRolloutAlreadyExist, func(ctx context.Context) error {
+		return updateRevision(ctx, s.EventLogStorage, ptr)
+	})
+}
+
+func (s *MemoryReleaseRolloutStorage) isTaken(ptr interface{}) func(interface{}) bool {
+	rollout := ptr.(*release.Rollout)
+	return func(v interface{}) bool {
+		stored := v.(release.Rollout)
+		return stored.ID != rollout.ID &&
+			stored.FlagID == rollout.FlagID &&
+			stored.EnvironmentID == rollout.EnvironmentID
+	}
+}
+
 func (s *MemoryReleaseRolloutStorage) FindByFlagEnvironment(ctx context.Context, flag release.Flag, env release.Environment, ptr *release.Rollout) (bool, error) {
 	if err := ctx.Err(); err != nil {
 		return false, err
@@ -185,14 +325,68 @@ func (s *MemoryReleaseRolloutStorage) FindByFlagEnvironment(ctx context.Context,
 	return found, nil
 }
 
+func (s *MemoryReleaseRolloutStorage) FindByQuery(ctx context.Context, q release.RolloutQuery) release.RolloutEntries {
+	if err := ctx.Err(); err != nil {
+		return iterators.NewError(err)
+	}
+
+	var rollouts []release.Rollout
+	for _, v := range s.View(ctx) {
+		rollout := v.(release.Rollout)
+
+		if q.Match(rollout) && q.IsAfterCursor(q.SortKey(rollout), rollout.ID) {
+			rollouts = append(rollouts, rollout)
+		}
+	}
+
+	sort.Slice(rollouts, func(i, j int) bool {
+		return q.Less(q.SortKey(rollouts[i]), rollouts[i].ID, q.SortKey(rollouts[j]), rollouts[j].ID)
+	})
+
+	if 0 < q.Limit && q.Limit < len(rollouts) {
+		rollouts = rollouts[:q.Limit]
+	}
+
+	return iterators.NewSlice(rollouts)
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 func (s *InMemory) ReleaseEnvironment(ctx context.Context) release.EnvironmentStorage {
-	return &MemoryReleaseEnvironmentStorage{EventLogStorage: s.storageFor(release.Environment{})}
+	return &MemoryReleaseEnvironmentStorage{EventLogStorage: s.storageFor(release.Environment{}), releases: s}
 }
 
 type MemoryReleaseEnvironmentStorage struct {
 	*inmemory.EventLogStorage
+	releases release.Storage
+}
+
+func (s *MemoryReleaseEnvironmentStorage) Create(ctx context.Context, ptr interface{}) error {
+	return memoryUniqueWrite(ctx, s.EventLogStorage, s.isNameTaken(ptr), release.ErrEnvironmentAlreadyExist, func(ctx context.Context) error {
+		return createRevision(ctx, s.EventLogStorage, ptr)
+	})
+}
+
+func (s *MemoryReleaseEnvironmentStorage) Update(ctx context.Context, ptr interface{}) error {
+	return memoryUniqueWrite(ctx, s.EventLogStorage, s.isNameTaken(ptr), release.ErrEnvironmentAlreadyExist, func(ctx context.Context) error {
+		return updateRevision(ctx, s.EventLogStorage, ptr)
+	})
+}
+
+func (s *MemoryReleaseEnvironmentStorage) isNameTaken(ptr interface{}) func(interface{}) bool {
+	env := ptr.(*release.Environment)
+	return func(v interface{}) bool {
+		stored := v.(release.Environment)
+		return stored.ID != env.ID && stored.Name == env.Name
+	}
+}
+
+func (s *MemoryReleaseEnvironmentStorage) DeleteByID(ctx context.Context, id interface{}) error {
+	return deleteByIDRestrict(ctx, s.releases, s.EventLogStorage, environmentReferences, release.ErrEnvironmentInUse, id)
+}
+
+func (s *MemoryReleaseEnvironmentStorage) DeleteAll(ctx context.Context) error {
+	return deleteAllRestrict(ctx, s.releases, s.EventLogStorage, release.Environment{}, environmentReferences, release.ErrEnvironmentInUse)
 }
 
 func (s *MemoryReleaseEnvironmentStorage) FindByAlias(ctx context.Context, idOrName string, env *release.Environment) (bool, error) {
@@ -214,6 +408,62 @@ func (s *MemoryReleaseEnvironmentStorage) FindByAlias(ctx context.Context, idOrN
 	return found, nil
 }
 
+func (s *MemoryReleaseEnvironmentStorage) FindByQuery(ctx context.Context, q release.EnvironmentQuery) release.EnvironmentEntries {
+	if err := ctx.Err(); err != nil {
+		return iterators.NewError(err)
+	}
+
+	var envs []release.Environment
+	for _, v := range s.View(ctx) {
+		env := v.(release.Environment)
+
+		if q.Match(env) && q.IsAfterCursor(q.SortKey(env), env.ID) {
+			envs = append(envs, env)
+		}
+	}
+
+	sort.Slice(envs, func(i, j int) bool {
+		return q.Less(q.SortKey(envs[i]), envs[i].ID, q.SortKey(envs[j]), envs[j].ID)
+	})
+
+	if 0 < q.Limit && q.Limit < len(envs) {
+		envs = envs[:q.Limit]
+	}
+
+	return iterators.NewSlice(envs)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (s *InMemory) ReleaseVersion(ctx context.Context) release.VersionStorage {
+	return &MemoryReleaseVersionStorage{EventLogStorage: s.storageFor(release.Version{})}
+}
+
+type MemoryReleaseVersionStorage struct {
+	*inmemory.EventLogStorage
+}
+
+func (s *MemoryReleaseVersionStorage) FindByQuery(ctx context.Context, q release.VersionQuery) release.VersionEntries {
+	if err := ctx.Err(); err != nil {
+		return iterators.NewError(err)
+	}
+
+	var versions []release.Version
+	for _, v := range s.View(ctx) {
+		version := v.(release.Version)
+
+		if q.Match(version) {
+			versions = append(versions, version)
+		}
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return q.Less(versions[i], versions[j])
+	})
+
+	return iterators.NewSlice(versions)
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 func (s *InMemory) SecurityToken(ctx context.Context) security.TokenStorage {
@@ -246,6 +496,203 @@ func (s *MemorySecurityTokenStorage) FindTokenBySHA512Hex(ctx context.Context, t
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
+func (s *InMemory) WebhookSubscription(ctx context.Context) webhook.SubscriptionStorage {
+	return &MemoryWebhookSubscriptionStorage{EventLogStorage: s.storageFor(webhook.Subscription{})}
+}
+
+type MemoryWebhookSubscriptionStorage struct {
+	*inmemory.EventLogStorage
+}
+
+func (s *InMemory) WebhookDelivery(ctx context.Context) webhook.DeliveryStorage {
+	return &MemoryWebhookDeliveryStorage{EventLogStorage: s.storageFor(webhook.Delivery{}), mutex: &s.deliveries}
+}
+
+type MemoryWebhookDeliveryStorage struct {
+	*inmemory.EventLogStorage
+	mutex *sync.Mutex
+}
+
+func (s *MemoryWebhookDeliveryStorage) Create(ctx context.Context, ptr interface{}) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delivery := ptr.(*webhook.Delivery)
+	isTaken := func(v interface{}) bool {
+		stored := v.(webhook.Delivery)
+		return stored.SubscriptionID == delivery.SubscriptionID && stored.EventID == delivery.EventID
+	}
+	return memoryUniqueWrite(ctx, s.EventLogStorage, isTaken, webhook.ErrDeliveryAlreadyExist, func(ctx context.Context) error {
+		return s.EventLogStorage.Create(ctx, ptr)
+	})
+}
+
+func (s *MemoryWebhookDeliveryStorage) FindByQuery(ctx context.Context, q webhook.DeliveryQuery) webhook.DeliveryEntries {
+	if err := ctx.Err(); err != nil {
+		return iterators.NewError(err)
+	}
+
+	var deliveries []webhook.Delivery
+	for _, v := range s.View(ctx) {
+		delivery := v.(webhook.Delivery)
+
+		if q.Match(delivery) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		return q.Less(deliveries[i], deliveries[j])
+	})
+
+	return iterators.NewSlice(deliveries)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (s *InMemory) ChangeRequest(ctx context.Context) change.RequestStorage {
+	return &MemoryChangeRequestStorage{EventLogStorage: s.storageFor(change.Request{})}
+}
+
+type MemoryChangeRequestStorage struct {
+	*inmemory.EventLogStorage
+}
+
+func (s *MemoryChangeRequestStorage) FindByQuery(ctx context.Context, q change.RequestQuery) change.RequestEntries {
+	if err := ctx.Err(); err != nil {
+		return iterators.NewError(err)
+	}
+
+	var requests []change.Request
+	for _, v := range s.View(ctx) {
+		request := v.(change.Request)
+
+		if q.Match(request) {
+			requests = append(requests, request)
+		}
+	}
+
+	sort.Slice(requests, func(i, j int) bool {
+		return q.Less(requests[i], requests[j])
+	})
+
+	return iterators.NewSlice(requests)
+}
+
+func (s *InMemory) ChangeApproval(ctx context.Context) change.ApprovalStorage {
+	return &MemoryChangeApprovalStorage{EventLogStorage: s.storageFor(change.Approval{}), mutex: &s.approvals}
+}
+
+type MemoryChangeApprovalStorage struct {
+	*inmemory.EventLogStorage
+	mutex *sync.Mutex
+}
+
+func (s *MemoryChangeApprovalStorage) Create(ctx context.Context, ptr interface{}) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	approval := ptr.(*change.Approval)
+	isTaken := func(v interface{}) bool {
+		stored := v.(change.Approval)
+		return stored.RequestID == approval.RequestID && stored.OwnerUID == approval.OwnerUID
+	}
+	return memoryUniqueWrite(ctx, s.EventLogStorage, isTaken, change.ErrAlreadyApproved, func(ctx context.Context) error {
+		return s.EventLogStorage.Create(ctx, ptr)
+	})
+}
+
+func (s *MemoryChangeApprovalStorage) FindByQuery(ctx context.Context, q change.ApprovalQuery) change.ApprovalEntries {
+	if err := ctx.Err(); err != nil {
+		return iterators.NewError(err)
+	}
+
+	var approvals []change.Approval
+	for _, v := range s.View(ctx) {
+		approval := v.(change.Approval)
+
+		if q.Match(approval) {
+			approvals = append(approvals, approval)
+		}
+	}
+
+	sort.Slice(approvals, func(i, j int) bool {
+		return q.Less(approvals[i], approvals[j])
+	})
+
+	return iterators.NewSlice(approvals)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (s *InMemory) ScheduledChange(ctx context.Context) schedule.ChangeStorage {
+	return &MemoryScheduledChangeStorage{EventLogStorage: s.storageFor(schedule.Change{})}
+}
+
+type MemoryScheduledChangeStorage struct {
+	*inmemory.EventLogStorage
+}
+
+func (s *MemoryScheduledChangeStorage) FindByQuery(ctx context.Context, q schedule.ChangeQuery) schedule.ChangeEntries {
+	if err := ctx.Err(); err != nil {
+		return iterators.NewError(err)
+	}
+
+	var changes []schedule.Change
+	for _, v := range s.View(ctx) {
+		c := v.(schedule.Change)
+
+		if q.Match(c) {
+			changes = append(changes, c)
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return q.Less(changes[i], changes[j])
+	})
+
+	return iterators.NewSlice(changes)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (s *InMemory) Guardrail(ctx context.Context) guardrail.GuardrailStorage {
+	return &MemoryGuardrailStorage{EventLogStorage: s.storageFor(guardrail.Guardrail{})}
+}
+
+type MemoryGuardrailStorage struct {
+	*inmemory.EventLogStorage
+}
+
+func (s *InMemory) GuardrailSignal(ctx context.Context) guardrail.SignalStorage {
+	return &MemoryGuardrailSignalStorage{EventLogStorage: s.storageFor(guardrail.Signal{})}
+}
+
+type MemoryGuardrailSignalStorage struct {
+	*inmemory.EventLogStorage
+}
+
+func (s *MemoryGuardrailSignalStorage) FindByQuery(ctx context.Context, q guardrail.SignalQuery) guardrail.SignalEntries {
+	if err := ctx.Err(); err != nil {
+		return iterators.NewError(err)
+	}
+
+	var signals []guardrail.Signal
+	for _, v := range s.View(ctx) {
+		signal := v.(guardrail.Signal)
+
+		if q.Match(signal) {
+			signals = append(signals, signal)
+		}
+	}
+
+	sort.Slice(signals, func(i, j int) bool {
+		return q.Less(signals[i], signals[j])
+	})
+
+	return iterators.NewSlice(signals)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
 func (s *InMemory) Close() error {
 	if s.closed {
 		return fmt.Errorf(`dev storage already closed`)
@@ -0,0 +1,91 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Queryer is the part of a database connection which is needed to read the schema version.
+type Queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// CheckPostgres verifies that every postgres migration is applied to the database.
+func CheckPostgres(ctx context.Context, db Queryer) error {
+	return check(ctx, db, `postgres`)
+}
+
+// CheckSQLite verifies that every sqlite migration is applied to the database.
+func CheckSQLite(ctx context.Context, db Queryer) error {
+	return check(ctx, db, `sqlite`)
+}
+
+func check(ctx context.Context, db Queryer, dirName string) error {
+	expected, err := latestVersion(dirName)
+	if err != nil {
+		return err
+	}
+
+	var (
+		version int64
+		dirty   bool
+	)
+	err = db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf(`database schema is not migrated, version %d is expected`, expected)
+	}
+	if err != nil {
+		return fmt.Errorf(`unable to read the database schema version: %w`, err)
+	}
+	if dirty {
+		return fmt.Errorf(`database schema is dirty at version %d, a migration failed midway`, version)
+	}
+	if uint(version) < expected {
+		return fmt.Errorf(`database schema is at version %d, but version %d is expected`, version, expected)
+	}
+	if expected < uint(version) {
+		return SchemaIsNewerError{Version: uint(version), Expected: expected}
+	}
+	return nil
+}
+
+// SchemaIsNewerError tells that the database schema was migrated by a newer toggler,
+// like during a rolling deploy, when the first new instance already applied its migrations.
+// The migrations are backward compatible, so the older instances can still serve the requests.
+type SchemaIsNewerError struct {
+	Version  uint
+	Expected uint
+}
+
+func (err SchemaIsNewerError) Error() string {
+	return fmt.Sprintf(`database schema is at version %d, which is newer than the expected version %d`, err.Version, err.Expected)
+}
+
+// Informational tells the health checks to report the newer schema without making the storage unavailable.
+func (err SchemaIsNewerError) Informational() bool { return true }
+
+func latestVersion(dirName string) (uint, error) {
+	src, err := newBindataSourceDriver(dirName)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		version = next
+	}
+}
@@ -54,11 +54,14 @@ func NewPostgresMigrate(db *sql.DB) (*migrate.Migrate, error) {
 
 }
 
-//go:embed postgres/*
+//go:embed postgres/* sqlite/*
 var fs embed.FS
 
 func NewPostgresBindataSourceDriver() (source.Driver, error) {
-	const dirName = `postgres`
+	return newBindataSourceDriver(`postgres`)
+}
+
+func newBindataSourceDriver(dirName string) (source.Driver, error) {
 	dirEntries, err := fs.ReadDir(dirName)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,12 @@
+CREATE TABLE "release_versions"
+(
+    "id"         UUID        NOT NULL PRIMARY KEY,
+    "kind"       TEXT        NOT NULL,
+    "entity_id"  TEXT        NOT NULL,
+    "number"     INTEGER     NOT NULL,
+    "action"     TEXT        NOT NULL,
+    "snapshot"   JSON        NOT NULL,
+    "created_at" TIMESTAMPTZ NOT NULL,
+
+    CONSTRAINT "release_versions_number_is_uniq" UNIQUE ("kind", "entity_id", "number")
+);
@@ -0,0 +1,5 @@
+ALTER TABLE "release_rollouts"
+    DROP CONSTRAINT "release_rollouts_flag_env_is_uniq";
+
+CREATE INDEX "find_release_rollout_by_release_flag_and_deployment_environment"
+    ON "release_rollouts" USING btree ("flag_id", "env_id");
@@ -0,0 +1,31 @@
+-- A release flag has only one rollout per deployment environment.
+-- Which of the duplicated rollouts was used by the evaluation was not defined,
+-- so they are not removed here, the migration fails and lists them instead,
+-- and the unwanted rollouts have to be deleted before the migration is run again.
+DO
+$$
+    DECLARE
+        duplicates TEXT;
+    BEGIN
+        SELECT string_agg(format('rollout %s (flag %s, environment %s)', r."id", r."flag_id", r."env_id"), ', '
+                          ORDER BY r."flag_id", r."env_id", r."id")
+        INTO duplicates
+        FROM "release_rollouts" AS r
+        WHERE EXISTS(
+                      SELECT 1
+                      FROM "release_rollouts" AS d
+                      WHERE d."flag_id" = r."flag_id"
+                        AND d."env_id" = r."env_id"
+                        AND d."id" <> r."id"
+                  );
+
+        IF duplicates IS NOT NULL THEN
+            RAISE EXCEPTION 'release flags have more than one rollout in a deployment environment: %', duplicates;
+        END IF;
+    END
+$$;
+
+DROP INDEX "find_release_rollout_by_release_flag_and_deployment_environment";
+
+ALTER TABLE "release_rollouts"
+    ADD CONSTRAINT "release_rollouts_flag_env_is_uniq" UNIQUE ("flag_id", "env_id");
@@ -0,0 +1,5 @@
+DROP TABLE "change_approvals";
+DROP TABLE "change_requests";
+
+ALTER TABLE "release_environments"
+    DROP COLUMN "required_approvals";
@@ -0,0 +1,29 @@
+ALTER TABLE "release_environments"
+    ADD COLUMN "required_approvals" INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE "change_requests"
+(
+    "id"          UUID NOT NULL PRIMARY KEY,
+    "env_id"      TEXT NOT NULL,
+    "kind"        TEXT NOT NULL,
+    "action"      TEXT NOT NULL,
+    "entity_id"   TEXT NOT NULL,
+    "base"        JSON,
+    "proposal"    JSON,
+    "status"      TEXT NOT NULL,
+    "proposed_by" TEXT NOT NULL,
+    "resolved_by" TEXT NOT NULL,
+    "reason"      TEXT NOT NULL,
+    "created_at"  TIMESTAMPTZ NOT NULL,
+    "updated_at"  TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE "change_approvals"
+(
+    "id"                UUID NOT NULL PRIMARY KEY,
+    "change_request_id" TEXT NOT NULL,
+    "owner_uid"         TEXT NOT NULL,
+    "created_at"        TIMESTAMPTZ NOT NULL,
+
+    CONSTRAINT "change_approvals_owner_is_uniq" UNIQUE ("change_request_id", "owner_uid")
+);
@@ -0,0 +1,2 @@
+DROP TABLE "guardrail_signals";
+DROP TABLE "guardrails";
@@ -0,0 +1,26 @@
+CREATE TABLE "guardrails"
+(
+    "id"                      UUID             NOT NULL PRIMARY KEY,
+    "rollout_id"              TEXT             NOT NULL,
+    "max_error_rate_increase" DOUBLE PRECISION NOT NULL,
+    "window_seconds"          INTEGER          NOT NULL,
+    "min_requests"            INTEGER          NOT NULL,
+    "action"                  TEXT             NOT NULL,
+    "status"                  TEXT             NOT NULL,
+    "armed_at"                TIMESTAMPTZ      NOT NULL,
+    "tripped_at"              TIMESTAMPTZ      NOT NULL,
+    "reason"                  TEXT             NOT NULL
+);
+
+CREATE TABLE "guardrail_signals"
+(
+    "id"          UUID        NOT NULL PRIMARY KEY,
+    "flag_id"     TEXT        NOT NULL,
+    "env_id"      TEXT        NOT NULL,
+    "variant"     TEXT        NOT NULL,
+    "successes"   INTEGER     NOT NULL,
+    "errors"      INTEGER     NOT NULL,
+    "reported_at" TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX "guardrail_signals_flag_id_env_id_reported_at_idx" ON "guardrail_signals" ("flag_id", "env_id", "reported_at");
@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/golang-migrate/migrate/v4"
+	sqlitemigr "github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+func MigrateSQLite(db *sql.DB) error {
+	m, err := NewSQLiteMigrate(db)
+	if err != nil {
+		return err
+	}
+
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+		return err
+	}
+
+	return nil
+}
+
+func NewSQLiteMigrate(db *sql.DB) (*migrate.Migrate, error) {
+	srcDriver, err := NewSQLiteBindataSourceDriver()
+	if err != nil {
+		return nil, err
+	}
+
+	dbDriver, err := sqlitemigr.WithInstance(db, &sqlitemigr.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.NewWithInstance(`embed`, srcDriver, `sqlite`, dbDriver)
+}
+
+func NewSQLiteBindataSourceDriver() (source.Driver, error) {
+	return newBindataSourceDriver(`sqlite`)
+}
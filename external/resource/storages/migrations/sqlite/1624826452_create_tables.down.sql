@@ -0,0 +1,5 @@
+DROP TABLE "tokens";
+DROP TABLE "release_rollouts";
+DROP TABLE "release_pilots";
+DROP TABLE "release_environments";
+DROP TABLE "release_flags";
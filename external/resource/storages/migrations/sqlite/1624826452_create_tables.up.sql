@@ -0,0 +1,51 @@
+-- The sqlite schema starts at the latest postgres schema version,
+-- so the two storages can be compared by their migration version.
+
+CREATE TABLE "release_flags"
+(
+    "id"   TEXT NOT NULL PRIMARY KEY,
+    "name" TEXT NOT NULL,
+
+    CONSTRAINT "release_flags_name_is_uniq" UNIQUE ("name")
+);
+
+CREATE TABLE "release_environments"
+(
+    "id"   TEXT NOT NULL PRIMARY KEY,
+    "name" TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE "release_pilots"
+(
+    "id"               TEXT    NOT NULL PRIMARY KEY,
+    "flag_id"          TEXT    NOT NULL,
+    "env_id"           TEXT    NOT NULL,
+    "public_id"        TEXT    NOT NULL,
+    "is_participating" BOOLEAN NOT NULL,
+
+    CONSTRAINT "pilot_uniq_combination" UNIQUE ("flag_id", "env_id", "public_id")
+);
+
+CREATE INDEX "lookup_pilots_by_public_id" ON "release_pilots" ("public_id");
+
+CREATE TABLE "release_rollouts"
+(
+    "id"      TEXT NOT NULL PRIMARY KEY,
+    "flag_id" TEXT NOT NULL,
+    "env_id"  TEXT NOT NULL,
+    "plan"    BLOB NOT NULL
+);
+
+CREATE INDEX "find_release_rollout_by_release_flag_and_deployment_environment"
+    ON "release_rollouts" ("flag_id", "env_id");
+
+CREATE TABLE "tokens"
+(
+    "id"        TEXT      NOT NULL PRIMARY KEY,
+    "sha512"    TEXT      NOT NULL,
+    "owner_uid" TEXT      NOT NULL,
+    "issued_at" TIMESTAMP NOT NULL,
+    "duration"  BIGINT    NOT NULL,
+
+    CONSTRAINT "tokens_token_is_uniq" UNIQUE ("sha512")
+);
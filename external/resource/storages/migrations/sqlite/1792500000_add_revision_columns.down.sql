@@ -0,0 +1,11 @@
+ALTER TABLE "release_pilots"
+    DROP COLUMN "revision";
+
+ALTER TABLE "release_rollouts"
+    DROP COLUMN "revision";
+
+ALTER TABLE "release_environments"
+    DROP COLUMN "revision";
+
+ALTER TABLE "release_flags"
+    DROP COLUMN "revision";
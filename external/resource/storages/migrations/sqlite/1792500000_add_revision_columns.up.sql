@@ -0,0 +1,11 @@
+ALTER TABLE "release_flags"
+    ADD COLUMN "revision" INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE "release_environments"
+    ADD COLUMN "revision" INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE "release_rollouts"
+    ADD COLUMN "revision" INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE "release_pilots"
+    ADD COLUMN "revision" INTEGER NOT NULL DEFAULT 1;
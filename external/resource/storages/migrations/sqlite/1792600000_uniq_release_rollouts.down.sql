@@ -0,0 +1,4 @@
+DROP INDEX "release_rollouts_flag_env_is_uniq";
+
+CREATE INDEX "find_release_rollout_by_release_flag_and_deployment_environment"
+    ON "release_rollouts" ("flag_id", "env_id");
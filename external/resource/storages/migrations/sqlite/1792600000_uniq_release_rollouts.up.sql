@@ -0,0 +1,11 @@
+-- A release flag has only one rollout per deployment environment.
+-- Which of the duplicated rollouts was used by the evaluation was not defined,
+-- so they are not removed here, and the creation of the unique index fails while there are duplicates.
+-- The duplicates can be listed with:
+--   SELECT "id", "flag_id", "env_id" FROM "release_rollouts" AS r
+--   WHERE EXISTS(SELECT 1 FROM "release_rollouts" AS d
+--                WHERE d."flag_id" = r."flag_id" AND d."env_id" = r."env_id" AND d."id" <> r."id");
+CREATE UNIQUE INDEX "release_rollouts_flag_env_is_uniq"
+    ON "release_rollouts" ("flag_id", "env_id");
+
+DROP INDEX "find_release_rollout_by_release_flag_and_deployment_environment";
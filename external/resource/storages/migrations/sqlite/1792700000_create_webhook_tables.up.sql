@@ -0,0 +1,25 @@
+CREATE TABLE "webhook_subscriptions"
+(
+    "id"              TEXT NOT NULL PRIMARY KEY,
+    "url"             TEXT NOT NULL,
+    "secret"          TEXT NOT NULL,
+    "event_types"     BLOB NOT NULL,
+    "environment_ids" BLOB NOT NULL
+);
+
+CREATE TABLE "webhook_deliveries"
+(
+    "id"              TEXT      NOT NULL PRIMARY KEY,
+    "subscription_id" TEXT      NOT NULL,
+    "event_id"        TEXT      NOT NULL,
+    "event_type"      TEXT      NOT NULL,
+    "payload"         BLOB      NOT NULL,
+    "status"          TEXT      NOT NULL,
+    "attempts"        INTEGER   NOT NULL,
+    "response_status" INTEGER   NOT NULL,
+    "error"           TEXT      NOT NULL,
+    "created_at"      TIMESTAMP NOT NULL,
+    "updated_at"      TIMESTAMP NOT NULL,
+
+    CONSTRAINT "webhook_deliveries_event_is_uniq" UNIQUE ("subscription_id", "event_id")
+);
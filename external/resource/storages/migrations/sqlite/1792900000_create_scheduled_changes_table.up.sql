@@ -0,0 +1,19 @@
+CREATE TABLE "scheduled_changes"
+(
+    "id"                TEXT NOT NULL PRIMARY KEY,
+    "env_id"            TEXT NOT NULL,
+    "kind"              TEXT NOT NULL,
+    "action"            TEXT NOT NULL,
+    "entity_id"         TEXT NOT NULL,
+    "proposal"          BLOB,
+    "run_at"            TIMESTAMP NOT NULL,
+    "status"            TEXT NOT NULL,
+    "scheduled_by"      TEXT NOT NULL,
+    "cancelled_by"      TEXT NOT NULL,
+    "error"             TEXT NOT NULL,
+    "change_request_id" TEXT NOT NULL,
+    "created_at"        TIMESTAMP NOT NULL,
+    "updated_at"        TIMESTAMP NOT NULL
+);
+
+CREATE INDEX "scheduled_changes_status_run_at_idx" ON "scheduled_changes" ("status", "run_at");
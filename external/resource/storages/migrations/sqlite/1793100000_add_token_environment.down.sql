@@ -0,0 +1,2 @@
+ALTER TABLE "tokens"
+    DROP COLUMN "env_id";
@@ -0,0 +1,2 @@
+ALTER TABLE "tokens"
+    ADD COLUMN "env_id" TEXT NOT NULL DEFAULT '';
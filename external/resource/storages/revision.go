@@ -0,0 +1,142 @@
+package storages
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+
+	"github.com/adamluzsi/frameless"
+	"github.com/adamluzsi/frameless/extid"
+	"github.com/adamluzsi/frameless/postgresql"
+
+	"github.com/toggler-io/toggler/domains/release"
+)
+
+// createRevision creates the entity with its first revision, regardless of the revision it was given with.
+func createRevision(ctx context.Context, s frameless.Creator, ptr interface{}) error {
+	revision, ok := release.RevisionOf(ptr)
+	if !ok {
+		return s.Create(ctx, ptr)
+	}
+
+	previous := *revision
+	*revision = 1
+	if err := s.Create(ctx, ptr); err != nil {
+		*revision = previous
+		return err
+	}
+	return nil
+}
+
+type revisionedStorage interface {
+	frameless.Finder
+	frameless.Updater
+	frameless.OnePhaseCommitProtocol
+}
+
+// updateRevision updates the entity only when its revision matches the stored one, and increments the revision.
+// The revision check and the update are made in the same transaction.
+func updateRevision(ctx context.Context, s revisionedStorage, ptr interface{}) (rErr error) {
+	revision, ok := release.RevisionOf(ptr)
+	if !ok {
+		return s.Update(ctx, ptr)
+	}
+	id, ok := extid.Lookup(ptr)
+	if !ok {
+		return s.Update(ctx, ptr)
+	}
+
+	ctx, err := s.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer frameless.FinishOnePhaseCommit(&rErr, s, ctx)
+
+	stored := reflect.New(reflect.TypeOf(ptr).Elem()).Interface()
+	found, err := s.FindByID(ctx, stored, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		// the storage yields its own not found error
+		return s.Update(ctx, ptr)
+	}
+
+	storedRevision, _ := release.RevisionOf(stored)
+	if *revision != 0 && *revision != *storedRevision {
+		return release.ErrRevisionConflict
+	}
+
+	previous := *revision
+	*revision = *storedRevision + 1
+	if err := s.Update(ctx, ptr); err != nil {
+		*revision = previous
+		return err
+	}
+	return nil
+}
+
+// updatePgRevision is the sql version of updateRevision.
+// It increments the revision with a compare and swap, so a concurrent update of an other process can't slip in
+// between the revision check and the update.
+func updatePgRevision(ctx context.Context, s *postgresql.Storage, ptr interface{}) (rErr error) {
+	revision, ok := release.RevisionOf(ptr)
+	if !ok {
+		return s.Update(ctx, ptr)
+	}
+	id, ok := extid.Lookup(ptr)
+	if !ok {
+		return s.Update(ctx, ptr)
+	}
+
+	ctx, err := s.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer frameless.FinishOnePhaseCommit(&rErr, s, ctx)
+
+	c, err := s.ConnectionManager.Connection(ctx)
+	if err != nil {
+		return err
+	}
+
+	m := s.Mapping
+	var storedRevision int
+	query := fmt.Sprintf(`SELECT "revision" FROM %s WHERE %q = $1`, m.TableRef(), m.IDRef())
+	if err := c.QueryRowContext(ctx, query, id).Scan(&storedRevision); err == sql.ErrNoRows {
+		// the storage yields its own not found error
+		return s.Update(ctx, ptr)
+	} else if err != nil {
+		return err
+	}
+
+	expected := *revision
+	if expected == 0 {
+		expected = storedRevision
+	}
+	if expected != storedRevision {
+		return release.ErrRevisionConflict
+	}
+
+	query = fmt.Sprintf(`UPDATE %s SET "revision" = $1 WHERE %q = $2 AND "revision" = $3`, m.TableRef(), m.IDRef())
+	res, err := c.ExecContext(ctx, query, expected+1, id, expected)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return release.ErrRevisionConflict
+	}
+
+	previous := *revision
+	*revision = expected + 1
+	if err := s.Update(ctx, ptr); err != nil {
+		*revision = previous
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package storages
+
+import (
+	"context"
+	"sync"
+
+	"github.com/adamluzsi/frameless"
+)
+
+// subscribers keeps the subscribers of an entity type's storage events,
+// for the storages that deliver the events themselves.
+type subscribers struct {
+	lock    sync.RWMutex
+	serial  int64
+	creator map[int64]frameless.CreatorSubscriber
+	updater map[int64]frameless.UpdaterSubscriber
+	deleter map[int64]frameless.DeleterSubscriber
+}
+
+func (subs *subscribers) SubscribeToCreatorEvents(ctx context.Context, s frameless.CreatorSubscriber) (frameless.Subscription, error) {
+	return subs.subscribe(func(id int64) {
+		if subs.creator == nil {
+			subs.creator = make(map[int64]frameless.CreatorSubscriber)
+		}
+		subs.creator[id] = s
+	}), nil
+}
+
+func (subs *subscribers) SubscribeToUpdaterEvents(ctx context.Context, s frameless.UpdaterSubscriber) (frameless.Subscription, error) {
+	return subs.subscribe(func(id int64) {
+		if subs.updater == nil {
+			subs.updater = make(map[int64]frameless.UpdaterSubscriber)
+		}
+		subs.updater[id] = s
+	}), nil
+}
+
+func (subs *subscribers) SubscribeToDeleterEvents(ctx context.Context, s frameless.DeleterSubscriber) (frameless.Subscription, error) {
+	return subs.subscribe(func(id int64) {
+		if subs.deleter == nil {
+			subs.deleter = make(map[int64]frameless.DeleterSubscriber)
+		}
+		subs.deleter[id] = s
+	}), nil
+}
+
+func (subs *subscribers) subscribe(register func(id int64)) frameless.Subscription {
+	subs.lock.Lock()
+	defer subs.lock.Unlock()
+	subs.serial++
+	id := subs.serial
+	register(id)
+	return &subscription{close: func() {
+		subs.lock.Lock()
+		defer subs.lock.Unlock()
+		delete(subs.creator, id)
+		delete(subs.updater, id)
+		delete(subs.deleter, id)
+	}}
+}
+
+func (subs *subscribers) handleEvent(ctx context.Context, event interface{}) {
+	subs.lock.RLock()
+	defer subs.lock.RUnlock()
+	switch event := event.(type) {
+	case frameless.CreateEvent:
+		for _, sub := range subs.creator {
+			_ = sub.HandleCreateEvent(ctx, event)
+		}
+	case frameless.UpdateEvent:
+		for _, sub := range subs.updater {
+			_ = sub.HandleUpdateEvent(ctx, event)
+		}
+	case frameless.DeleteByIDEvent:
+		for _, sub := range subs.deleter {
+			_ = sub.HandleDeleteByIDEvent(ctx, event)
+		}
+	case frameless.DeleteAllEvent:
+		for _, sub := range subs.deleter {
+			_ = sub.HandleDeleteAllEvent(ctx, event)
+		}
+	}
+}
+
+func (subs *subscribers) handleError(ctx context.Context, err error) {
+	subs.lock.RLock()
+	defer subs.lock.RUnlock()
+	for _, sub := range subs.creator {
+		_ = sub.HandleError(ctx, err)
+	}
+	for _, sub := range subs.updater {
+		_ = sub.HandleError(ctx, err)
+	}
+	for _, sub := range subs.deleter {
+		_ = sub.HandleError(ctx, err)
+	}
+}
+
+type subscription struct {
+	once  sync.Once
+	close func()
+}
+
+func (s *subscription) Close() error {
+	s.once.Do(s.close)
+	return nil
+}
@@ -15,8 +15,12 @@ import (
 	"github.com/adamluzsi/testcase/fixtures"
 	"github.com/google/uuid"
 
+	"github.com/toggler-io/toggler/domains/change"
+	"github.com/toggler-io/toggler/domains/guardrail"
 	"github.com/toggler-io/toggler/domains/release"
+	"github.com/toggler-io/toggler/domains/schedule"
 	"github.com/toggler-io/toggler/domains/security"
+	"github.com/toggler-io/toggler/domains/webhook"
 )
 
 var FixtureFactory = testcase.Var{Name: "contracts.FixtureFactory"}
@@ -43,6 +47,11 @@ func NewFixtureFactory(tb testing.TB) frameless.FixtureFactory {
 			Name: fmt.Sprintf(`%s - %s`, t.Random.StringN(4), uuid.New().String()),
 		}
 	})
+	factory.RegisterType(release.Environment{}, func(ctx context.Context) interface{} {
+		return release.Environment{
+			Name: fmt.Sprintf(`%s - %s`, t.Random.StringN(4), uuid.New().String()),
+		}
+	})
 	factory.RegisterType(release.RolloutDecisionByAPI{}, func(ctx context.Context) interface{} {
 		var byAPI release.RolloutDecisionByAPI
 		byAPI = release.NewRolloutDecisionByAPIDeprecated()
@@ -69,6 +78,99 @@ func NewFixtureFactory(tb testing.TB) frameless.FixtureFactory {
 			OwnerUID: uuid.New().String(),
 			IssuedAt: t.Random.Time().UTC(),
 			Duration: time.Duration(t.Random.IntBetween(int(time.Second), int(time.Hour))),
+			// a scoped token is generated half of the time, so both kinds are covered.
+			EnvironmentID: t.Random.ElementFromSlice([]string{``, uuid.New().String()}).(string),
+		}
+	})
+	factory.RegisterType(release.Version{}, func(ctx context.Context) interface{} {
+		return release.Version{
+			Kind:      t.Random.ElementFromSlice([]string{release.VersionKindFlag, release.VersionKindRollout, release.VersionKindPilot}).(string),
+			EntityID:  uuid.New().String(),
+			Number:    t.Random.IntBetween(1, 1024),
+			Action:    t.Random.ElementFromSlice([]string{release.VersionActionCreate, release.VersionActionUpdate, release.VersionActionDelete}).(string),
+			Snapshot:  []byte(fmt.Sprintf(`{"name":%q}`, t.Random.StringN(8))),
+			CreatedAt: t.Random.Time().UTC(),
+		}
+	})
+	factory.RegisterType(webhook.Subscription{}, func(ctx context.Context) interface{} {
+		return webhook.Subscription{
+			URL:            fmt.Sprintf(`https://example.com/hooks/%s`, url.PathEscape(t.Random.StringN(8))),
+			Secret:         t.Random.StringN(32),
+			EventTypes:     []string{t.Random.ElementFromSlice(webhook.EventTypes).(string)},
+			EnvironmentIDs: []string{uuid.New().String()},
+		}
+	})
+	factory.RegisterType(webhook.Delivery{}, func(ctx context.Context) interface{} {
+		eventType := t.Random.ElementFromSlice(webhook.EventTypes).(string)
+		return webhook.Delivery{
+			SubscriptionID: uuid.New().String(),
+			EventID:        uuid.New().String(),
+			EventType:      eventType,
+			Payload:        []byte(fmt.Sprintf(`{"type":%q}`, eventType)),
+			Status:         t.Random.ElementFromSlice([]string{webhook.DeliveryStatusPending, webhook.DeliveryStatusSucceeded, webhook.DeliveryStatusFailed}).(string),
+			Attempts:       t.Random.IntBetween(1, 5),
+			ResponseStatus: t.Random.ElementFromSlice([]int{0, 200, 500}).(int),
+			Error:          t.Random.StringN(8),
+			CreatedAt:      t.Random.Time().UTC(),
+			UpdatedAt:      t.Random.Time().UTC(),
+		}
+	})
+	factory.RegisterType(change.Request{}, func(ctx context.Context) interface{} {
+		return change.Request{
+			EnvironmentID: uuid.New().String(),
+			Kind:          t.Random.ElementFromSlice([]string{change.KindEnvironment, change.KindRollout, change.KindPilot}).(string),
+			Action:        change.ActionUpdate,
+			EntityID:      uuid.New().String(),
+			Base:          []byte(fmt.Sprintf(`{"name":%q}`, t.Random.StringN(8))),
+			Proposal:      []byte(fmt.Sprintf(`{"name":%q}`, t.Random.StringN(8))),
+			Status:        t.Random.ElementFromSlice([]string{change.StatusPending, change.StatusRejected, change.StatusApplied}).(string),
+			ProposedBy:    t.Random.StringN(8),
+			CreatedAt:     t.Random.Time().UTC(),
+			UpdatedAt:     t.Random.Time().UTC(),
+		}
+	})
+	factory.RegisterType(change.Approval{}, func(ctx context.Context) interface{} {
+		return change.Approval{
+			RequestID: uuid.New().String(),
+			OwnerUID:  t.Random.StringN(8),
+			CreatedAt: t.Random.Time().UTC(),
+		}
+	})
+	factory.RegisterType(schedule.Change{}, func(ctx context.Context) interface{} {
+		return schedule.Change{
+			EnvironmentID: uuid.New().String(),
+			Kind:          t.Random.ElementFromSlice([]string{schedule.KindRollout, schedule.KindPilot}).(string),
+			Action:        schedule.ActionUpdate,
+			EntityID:      uuid.New().String(),
+			Proposal:      []byte(fmt.Sprintf(`{"flag_id":%q}`, t.Random.StringN(8))),
+			RunAt:         t.Random.Time().UTC(),
+			Status:        t.Random.ElementFromSlice([]string{schedule.StatusScheduled, schedule.StatusSucceeded, schedule.StatusFailed}).(string),
+			ScheduledBy:   t.Random.StringN(8),
+			CreatedAt:     t.Random.Time().UTC(),
+			UpdatedAt:     t.Random.Time().UTC(),
+		}
+	})
+	factory.RegisterType(guardrail.Guardrail{}, func(ctx context.Context) interface{} {
+		return guardrail.Guardrail{
+			RolloutID:            uuid.New().String(),
+			MaxErrorRateIncrease: float64(t.Random.IntN(200)) / 2,
+			WindowSeconds:        t.Random.IntBetween(60, 3600),
+			MinRequests:          t.Random.IntBetween(1, 1000),
+			Action:               t.Random.ElementFromSlice([]string{guardrail.ActionZero, guardrail.ActionPreviousVersion}).(string),
+			Status:               t.Random.ElementFromSlice([]string{guardrail.StatusArmed, guardrail.StatusTripped}).(string),
+			ArmedAt:              t.Random.Time().UTC(),
+			TrippedAt:            t.Random.Time().UTC(),
+			Reason:               t.Random.StringN(8),
+		}
+	})
+	factory.RegisterType(guardrail.Signal{}, func(ctx context.Context) interface{} {
+		return guardrail.Signal{
+			FlagID:        uuid.New().String(),
+			EnvironmentID: uuid.New().String(),
+			Variant:       t.Random.ElementFromSlice([]string{guardrail.VariantEnrolled, guardrail.VariantControl}).(string),
+			Successes:     t.Random.IntN(1000),
+			Errors:        t.Random.IntN(1000),
+			ReportedAt:    t.Random.Time().UTC(),
 		}
 	})
 	factory.RegisterType(release.Pilot{}, func(ctx context.Context) interface{} {
@@ -82,7 +184,9 @@ func NewFixtureFactory(tb testing.TB) frameless.FixtureFactory {
 	factory.RegisterType(release.Rollout{}, func(ctx context.Context) interface{} {
 		t.Helper()
 		return release.Rollout{
-			FlagID:        ExampleReleaseFlag(t).ID,
+			// a flag has only one rollout per environment,
+			// so the rollouts made from the fixture refer to a flag of their own.
+			FlagID:        uuid.New().String(),
 			EnvironmentID: ExampleDeploymentEnvironment(t).ID,
 			Plan: func() release.RolloutPlan {
 				switch t.Random.IntN(3) {